@@ -0,0 +1,137 @@
+// Package artwork validates cover images and renders their thumbnails and mosaics.
+package artwork
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	_ "image/png"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+var (
+	// ErrUnsupported is returned for data that is not a JPEG, PNG or WebP image
+	ErrUnsupported = errors.New("unsupported image format")
+	// ErrTooLarge is returned for images wider or taller than MaxDimension
+	ErrTooLarge = errors.New("image dimensions are too large")
+)
+
+// MaxDimension caps the width and height of decoded images so small files can't expand into huge bitmaps
+const MaxDimension = 8000
+
+// thumbnailQuality is the JPEG quality of rendered thumbnails and mosaics
+const thumbnailQuality = 85
+
+// Sizes are the edge lengths in pixels of the thumbnails rendered for every cover
+var Sizes = []int{64, 128, 256, 512}
+
+// Format describes a supported image format
+type Format struct {
+	ContentType string
+	Extension   string
+}
+
+// JPEG is the format thumbnails and mosaics are encoded in
+var JPEG = Format{"image/jpeg", ".jpg"}
+
+// Detect identifies an image format from its magic bytes
+func Detect(header []byte) (Format, bool) {
+	switch {
+	case bytes.HasPrefix(header, []byte{0xFF, 0xD8, 0xFF}):
+		return JPEG, true
+	case bytes.HasPrefix(header, []byte("\x89PNG\r\n\x1a\n")):
+		return Format{"image/png", ".png"}, true
+	case len(header) >= 12 && bytes.Equal(header[0:4], []byte("RIFF")) && bytes.Equal(header[8:12], []byte("WEBP")):
+		return Format{"image/webp", ".webp"}, true
+	}
+	return Format{}, false
+}
+
+// Decode checks the format and dimensions of an image before decoding all of it
+func Decode(data []byte) (image.Image, Format, error) {
+	format, ok := Detect(data)
+	if !ok {
+		return nil, Format{}, ErrUnsupported
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, format, fmt.Errorf("%w: %v", ErrUnsupported, err)
+	}
+	if config.Width <= 0 || config.Height <= 0 {
+		return nil, format, ErrUnsupported
+	}
+	if config.Width > MaxDimension || config.Height > MaxDimension {
+		return nil, format, ErrTooLarge
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, format, fmt.Errorf("%w: %v", ErrUnsupported, err)
+	}
+	return img, format, nil
+}
+
+// Thumbnail scales an image to fit within size by size pixels, keeping its aspect ratio; smaller images are not enlarged
+func Thumbnail(img image.Image, size int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width > size || height > size {
+		if width >= height {
+			width, height = size, max(1, height*size/width)
+		} else {
+			width, height = max(1, width*size/height), size
+		}
+	}
+
+	dst := canvas(width, height)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Over, nil)
+	return dst
+}
+
+// Mosaic lays out four images two by two in a size by size square, cropping each to a square around its centre
+func Mosaic(tiles []image.Image, size int) image.Image {
+	dst := canvas(size, size)
+	half := size / 2
+	for i, tile := range tiles[:min(len(tiles), 4)] {
+		x, y := i%2*half, i/2*half
+		// The right column and bottom row take the odd pixel
+		target := image.Rect(x, y, x+half, y+half)
+		if i%2 == 1 {
+			target.Max.X = size
+		}
+		if i >= 2 {
+			target.Max.Y = size
+		}
+		draw.CatmullRom.Scale(dst, target, tile, centreSquare(tile.Bounds()), draw.Over, nil)
+	}
+	return dst
+}
+
+// Encode writes an image as JPEG
+func Encode(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: thumbnailQuality}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// canvas returns a white image, so transparent areas don't turn black in JPEG
+func canvas(width, height int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	return dst
+}
+
+func centreSquare(bounds image.Rectangle) image.Rectangle {
+	side := min(bounds.Dx(), bounds.Dy())
+	x := bounds.Min.X + (bounds.Dx()-side)/2
+	y := bounds.Min.Y + (bounds.Dy()-side)/2
+	return image.Rect(x, y, x+side, y+side)
+}
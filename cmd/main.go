@@ -16,15 +16,27 @@
 // @securityDefinitions.apikey BearerAuth
 // @in header
 // @name Authorization
+
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-API-Key
 package main
 
 import (
 	"log"
+	"os"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/tushar27x/music-lib-api/config"
 	_ "github.com/tushar27x/music-lib-api/docs"
+	"github.com/tushar27x/music-lib-api/mailer"
+	"github.com/tushar27x/music-lib-api/migrations"
+	"github.com/tushar27x/music-lib-api/ratelimit"
+	"github.com/tushar27x/music-lib-api/repositories"
 	"github.com/tushar27x/music-lib-api/routes"
+	"github.com/tushar27x/music-lib-api/services"
+	"github.com/tushar27x/music-lib-api/storage"
 )
 
 // @Summary     Health check endpoint
@@ -37,6 +49,18 @@ func main() {
 	// Load environment variables
 	config.LoadEnv()
 
+	// `music-api migrate ...` manages the schema instead of serving
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrate(os.Args[2:])
+		return
+	}
+
+	// `music-api set-role EMAIL ROLE` appoints the first admin
+	if len(os.Args) > 1 && os.Args[1] == "set-role" {
+		runSetRole(os.Args[2:])
+		return
+	}
+
 	// Check for required environment variables
 	requiredEnvVars := []string{"PORT", "DB_URI", "JWT_SECRET"}
 	for _, envVar := range requiredEnvVars {
@@ -48,9 +72,48 @@ func main() {
 	// Connect to database
 	config.ConnectDB()
 
+	// Refuse to serve on a schema this release doesn't expect
+	migrator, err := migrations.New(config.DB)
+	if err != nil {
+		log.Fatalf("Error loading migrations: %v", err)
+	}
+	if config.GetEnv("AUTO_MIGRATE") == "true" {
+		if _, err := migrator.Up(0); err != nil {
+			log.Fatalf("Error applying migrations: %v", err)
+		}
+	}
+	if err := migrator.Check(); err != nil {
+		log.Fatalf("Database schema is not ready: %v (run `music-api migrate up`)", err)
+	}
+
+	searcher := services.NewSearchService(config.DB)
+
+	// Configure blob storage for uploaded audio
+	storage.InitStorage()
+
+	// Configure the mailer for verification and password reset emails
+	mailer.InitMailer()
+
+	// Configure where request counts for rate limiting are kept
+	ratelimit.InitRateLimiter()
+
+	// Everything below main only sees the repositories and storage it is handed
+	store := repositories.NewGormStore(config.DB)
+
 	r := gin.Default()
 
-	routes.RegisterRoutes(r)
+	// Failed logins and rate limits are counted per client IP, so X-Forwarded-For is only believed from TRUSTED_PROXIES
+	var proxies []string
+	for _, proxy := range strings.Split(config.GetEnv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	if err := r.SetTrustedProxies(proxies); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
+
+	routes.RegisterRoutes(r, routes.NewHandlers(store, searcher, storage.Store, mailer.Mail, ratelimit.Counters))
 
 	port := config.GetEnv("PORT")
 	if port == "" {
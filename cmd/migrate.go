@@ -0,0 +1,114 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/tushar27x/music-lib-api/config"
+	"github.com/tushar27x/music-lib-api/migrations"
+)
+
+const migrateUsage = `Usage: music-api migrate <command>
+
+Commands:
+  up [N]         apply all pending migrations, or the next N
+  down [N]       roll back the last migration, or the last N
+  status         list migrations and whether they are applied
+  create NAME    add empty up and down files for every database to ` + migrations.Dir
+
+// runMigrate handles the migrate subcommand and exits the process when done
+func runMigrate(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		os.Exit(2)
+	}
+
+	command, rest := args[0], args[1:]
+	if command == "create" {
+		if len(rest) == 0 {
+			log.Fatal("migrate create needs a name, e.g. migrate create add_song_lyrics")
+		}
+		created, err := migrations.Create(migrations.Dir, strings.Join(rest, "_"))
+		if err != nil {
+			log.Fatalf("Error creating migration: %v", err)
+		}
+		for _, file := range created {
+			fmt.Println("Created", file)
+		}
+		return
+	}
+
+	if command != "up" && command != "down" && command != "status" {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		os.Exit(2)
+	}
+
+	steps := 0
+	if len(rest) > 0 {
+		parsed, err := strconv.Atoi(rest[0])
+		if err != nil || parsed <= 0 {
+			log.Fatalf("Number of migrations must be a positive integer, got %q", rest[0])
+		}
+		steps = parsed
+	}
+
+	if config.GetEnv("DB_URI") == "" {
+		log.Fatal("Required environment variable DB_URI is not set")
+	}
+	config.ConnectDB()
+
+	migrator, err := migrations.New(config.DB)
+	if err != nil {
+		log.Fatalf("Error loading migrations: %v", err)
+	}
+
+	switch command {
+	case "up":
+		applied, err := migrator.Up(steps)
+		for _, migration := range applied {
+			fmt.Printf("Applied %04d_%s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			log.Fatalf("Error applying migrations: %v", err)
+		}
+		if len(applied) == 0 {
+			fmt.Println("Schema is up to date")
+		}
+	case "down":
+		reverted, err := migrator.Down(steps)
+		for _, migration := range reverted {
+			fmt.Printf("Rolled back %04d_%s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			log.Fatalf("Error rolling back migrations: %v", err)
+		}
+		if len(reverted) == 0 {
+			fmt.Println("No migrations to roll back")
+		}
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			log.Fatalf("Error reading migration status: %v", err)
+		}
+		for _, status := range statuses {
+			state := "pending"
+			switch {
+			case status.Dirty:
+				state = "DIRTY"
+			case status.Applied:
+				state = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			if status.Unknown {
+				state += " (not in this release)"
+			}
+			fmt.Printf("%04d_%-40s %s\n", status.Version, status.Name, state)
+		}
+		if err := migrator.Check(); errors.Is(err, migrations.ErrPending) || errors.Is(err, migrations.ErrDirty) {
+			fmt.Println(err)
+		}
+	}
+}
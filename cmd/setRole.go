@@ -0,0 +1,46 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/tushar27x/music-lib-api/config"
+	"github.com/tushar27x/music-lib-api/models"
+	"github.com/tushar27x/music-lib-api/repositories"
+	"github.com/tushar27x/music-lib-api/services"
+)
+
+var setRoleUsage = `Usage: music-api set-role EMAIL ROLE
+
+Gives an existing account one of the roles: ` + strings.Join(models.Roles, ", ") + `.
+Use it to appoint the first admin; after that admins assign roles through the API.`
+
+// runSetRole handles the set-role subcommand and exits the process when done
+func runSetRole(args []string) {
+	if len(args) != 2 {
+		fmt.Fprintln(os.Stderr, setRoleUsage)
+		os.Exit(2)
+	}
+	email, role := args[0], args[1]
+	if !services.IsRole(role) {
+		log.Fatalf("Unknown role %q, expected one of %s", role, strings.Join(models.Roles, ", "))
+	}
+
+	if config.GetEnv("DB_URI") == "" {
+		log.Fatal("Required environment variable DB_URI is not set")
+	}
+	config.ConnectDB()
+
+	users := services.NewUserService(repositories.NewGormStore(config.DB))
+	user, err := users.SetRoleByEmail(email, role)
+	if errors.Is(err, services.ErrUserNotFound) {
+		log.Fatalf("No account with email %s", email)
+	}
+	if err != nil {
+		log.Fatalf("Error changing role: %v", err)
+	}
+	fmt.Printf("%s is now %s\n", user.Email, user.Role)
+}
@@ -3,9 +3,10 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
-	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
@@ -30,19 +31,46 @@ func GetEnv(key string) string {
 	return os.Getenv(key)
 }
 
+// GetEnvDuration parses a duration such as "15m" from the environment,
+// falling back to the given default when the variable is unset or invalid
+func GetEnvDuration(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		log.Printf("Warning: invalid duration %q for %s, using %s", value, key, fallback)
+		return fallback
+	}
+	return duration
+}
+
+// GetEnvInt64 parses an integer from the environment, falling back to the
+// given default when the variable is unset or invalid
+func GetEnvInt64(key string, fallback int64) int64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	parsed, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || parsed <= 0 {
+		log.Printf("Warning: invalid number %q for %s, using %d", value, key, fallback)
+		return fallback
+	}
+	return parsed
+}
+
 func ConnectDB() {
 	var err error
-	dsn := GetEnv("DB_URI")
-
-	DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	DB, err = OpenDB(GetEnv("DB_URI"))
 	if err != nil {
 		log.Fatalf("❌ Error connecting to DB:%s", err)
 	}
 
-	// err = DB.AutoMigrate(&models.User{}, &models.Album{}, &models.Song{}, &models.Playlist{})
-	if err != nil {
-		log.Fatalf("Error migrating models:%s", err)
-	}
+	// The schema is managed by the SQL files in migrations/sql, see `music-api migrate`
 
 	log.Println("Connected to DB successfully 🎉🎉")
 }
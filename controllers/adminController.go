@@ -0,0 +1,655 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tushar27x/music-lib-api/models"
+	"github.com/tushar27x/music-lib-api/repositories"
+	"github.com/tushar27x/music-lib-api/services"
+)
+
+// AdminController serves the admin endpoints
+type AdminController struct {
+	users      *services.UserService
+	moderation *services.ModerationService
+	audit      *services.AuditService
+	apiKeys    *services.APIKeyService
+	throttle   *services.LoginThrottleService
+}
+
+// NewAdminController returns an AdminController backed by the given services
+func NewAdminController(users *services.UserService, moderation *services.ModerationService, audit *services.AuditService, apiKeys *services.APIKeyService, throttle *services.LoginThrottleService) *AdminController {
+	return &AdminController{users: users, moderation: moderation, audit: audit, apiKeys: apiKeys, throttle: throttle}
+}
+
+// @Summary     List users
+// @Description List and search user accounts (admins only)
+// @Tags        admin
+// @Produce     json
+// @Param       q query string false "Part of the name or email"
+// @Param       role query string false "Only users with this role"
+// @Param       suspended query bool false "Only suspended (true) or active (false) users"
+// @Param       limit query int false "Limit results (default: 20, max: 100)"
+// @Param       offset query int false "Offset for pagination (default: 0)"
+// @Success     200 {object} map[string]interface{}
+// @Failure     400 {object} map[string]interface{}
+// @Failure     403 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Security    BearerAuth
+// @Router      /admin/users [get]
+func (ctl *AdminController) ListUsers(c *gin.Context) {
+	filter := repositories.UserFilter{Query: c.Query("q"), Role: c.Query("role")}
+	if filter.Role != "" && !services.IsRole(filter.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role"})
+		return
+	}
+	if value := c.Query("suspended"); value != "" {
+		suspended, err := strconv.ParseBool(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "suspended must be true or false"})
+			return
+		}
+		filter.Suspended = &suspended
+	}
+	limit, offset := pageParams(c)
+
+	users, total, err := ctl.users.List(filter, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	responses := make([]models.UserResponse, 0, len(users))
+	for _, user := range users {
+		responses = append(responses, userResponse(user))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"users":      responses,
+		"pagination": pagination(total, limit, offset),
+	})
+}
+
+// @Summary     Get user by ID
+// @Description Retrieve any user account (admins only)
+// @Tags        admin
+// @Produce     json
+// @Param       id path int true "User ID"
+// @Success     200 {object} models.UserResponse
+// @Failure     400 {object} map[string]interface{}
+// @Failure     403 {object} map[string]interface{}
+// @Failure     404 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Security    BearerAuth
+// @Router      /admin/users/{id} [get]
+func (ctl *AdminController) GetUser(c *gin.Context) {
+	userId, ok := idParam(c, "id", "user")
+	if !ok {
+		return
+	}
+
+	user, err := ctl.users.Get(userId)
+	if err != nil {
+		userError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, userResponse(user))
+}
+
+// @Summary     Change a user's role
+// @Description Assign listener, artist, moderator or admin to a user (admins only). The new role applies to the user's next request.
+// @Tags        admin
+// @Accept      json
+// @Produce     json
+// @Param       id path int true "User ID"
+// @Param       role body models.UserRoleUpdateRequest true "New role"
+// @Success     200 {object} models.UserResponse
+// @Failure     400 {object} map[string]interface{}
+// @Failure     403 {object} map[string]interface{}
+// @Failure     404 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Security    BearerAuth
+// @Router      /admin/users/{id}/role [put]
+func (ctl *AdminController) UpdateUserRole(c *gin.Context) {
+	userId, ok := idParam(c, "id", "user")
+	if !ok {
+		return
+	}
+
+	var input models.UserRoleUpdateRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	actorId, ok := c.MustGet("userId").(uint)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	user, err := ctl.users.ChangeRole(actorId, userId, input.Role)
+	if err != nil {
+		userError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, userResponse(user))
+}
+
+// @Summary     Suspend a user
+// @Description Lock a user out until unsuspended: login and refresh are refused, existing access tokens stop working and every refresh token is revoked (admins only)
+// @Tags        admin
+// @Accept      json
+// @Produce     json
+// @Param       id path int true "User ID"
+// @Param       body body models.UserSuspendRequest true "Reason for the suspension"
+// @Success     200 {object} models.UserResponse
+// @Failure     400 {object} map[string]interface{}
+// @Failure     403 {object} map[string]interface{}
+// @Failure     404 {object} map[string]interface{}
+// @Failure     409 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Security    BearerAuth
+// @Router      /admin/users/{id}/suspend [post]
+func (ctl *AdminController) SuspendUser(c *gin.Context) {
+	userId, ok := idParam(c, "id", "user")
+	if !ok {
+		return
+	}
+
+	var input models.UserSuspendRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	actorId, ok := c.MustGet("userId").(uint)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	user, err := ctl.users.Suspend(actorId, userId, input.Reason)
+	if err != nil {
+		userError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, userResponse(user))
+}
+
+// @Summary     Unsuspend a user
+// @Description Let a suspended user log in again (admins only)
+// @Tags        admin
+// @Produce     json
+// @Param       id path int true "User ID"
+// @Success     200 {object} models.UserResponse
+// @Failure     400 {object} map[string]interface{}
+// @Failure     403 {object} map[string]interface{}
+// @Failure     404 {object} map[string]interface{}
+// @Failure     409 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Security    BearerAuth
+// @Router      /admin/users/{id}/unsuspend [post]
+func (ctl *AdminController) UnsuspendUser(c *gin.Context) {
+	userId, ok := idParam(c, "id", "user")
+	if !ok {
+		return
+	}
+
+	actorId, ok := c.MustGet("userId").(uint)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	user, err := ctl.users.Unsuspend(actorId, userId)
+	if err != nil {
+		userError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, userResponse(user))
+}
+
+// @Summary     Force a password reset
+// @Description Revoke every refresh token of a user and refuse their requests until they change their password with PUT /auth/password (admins only)
+// @Tags        admin
+// @Produce     json
+// @Param       id path int true "User ID"
+// @Success     200 {object} models.UserResponse
+// @Failure     400 {object} map[string]interface{}
+// @Failure     403 {object} map[string]interface{}
+// @Failure     404 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Security    BearerAuth
+// @Router      /admin/users/{id}/password-reset [post]
+func (ctl *AdminController) ForcePasswordReset(c *gin.Context) {
+	userId, ok := idParam(c, "id", "user")
+	if !ok {
+		return
+	}
+
+	actorId, ok := c.MustGet("userId").(uint)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	user, err := ctl.users.ForcePasswordReset(actorId, userId)
+	if err != nil {
+		userError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, userResponse(user))
+}
+
+// @Summary     Reset a user's two-factor authentication
+// @Description Turn off two-factor authentication for a user who lost their authenticator and recovery codes (admins only)
+// @Tags        admin
+// @Produce     json
+// @Param       id path int true "User ID"
+// @Success     200 {object} models.UserResponse
+// @Failure     400 {object} map[string]interface{}
+// @Failure     403 {object} map[string]interface{}
+// @Failure     404 {object} map[string]interface{}
+// @Failure     409 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Security    BearerAuth
+// @Router      /admin/users/{id}/2fa/reset [post]
+func (ctl *AdminController) ResetTwoFactor(c *gin.Context) {
+	userId, ok := idParam(c, "id", "user")
+	if !ok {
+		return
+	}
+
+	actorId, ok := c.MustGet("userId").(uint)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	user, err := ctl.users.ResetTwoFactor(actorId, userId)
+	if err != nil {
+		userError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, userResponse(user))
+}
+
+// @Summary     List a user's API keys
+// @Description List the API keys of any user (admins only)
+// @Tags        admin
+// @Produce     json
+// @Param       id path int true "User ID"
+// @Success     200 {array} models.APIKeyResponse
+// @Failure     400 {object} map[string]interface{}
+// @Failure     403 {object} map[string]interface{}
+// @Failure     404 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Security    BearerAuth
+// @Router      /admin/users/{id}/api-keys [get]
+func (ctl *AdminController) ListUserAPIKeys(c *gin.Context) {
+	userId, ok := idParam(c, "id", "user")
+	if !ok {
+		return
+	}
+
+	if _, err := ctl.users.Get(userId); err != nil {
+		userError(c, err)
+		return
+	}
+	keys, err := ctl.apiKeys.List(userId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, apiKeyResponses(keys))
+}
+
+// @Summary     Revoke a user's API key
+// @Description Revoke an API key of any user (admins only). The revocation is recorded in the audit trail.
+// @Tags        admin
+// @Produce     json
+// @Param       id path int true "User ID"
+// @Param       keyId path int true "API key ID"
+// @Success     200 {object} models.APIKeyResponse
+// @Failure     400 {object} map[string]interface{}
+// @Failure     403 {object} map[string]interface{}
+// @Failure     404 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Security    BearerAuth
+// @Router      /admin/users/{id}/api-keys/{keyId} [delete]
+func (ctl *AdminController) RevokeUserAPIKey(c *gin.Context) {
+	userId, ok := idParam(c, "id", "user")
+	if !ok {
+		return
+	}
+	keyId, ok := idParam(c, "keyId", "API key")
+	if !ok {
+		return
+	}
+
+	actorId, ok := c.MustGet("userId").(uint)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	key, err := ctl.apiKeys.RevokeForUser(actorId, userId, keyId)
+	if err != nil {
+		apiKeyError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, apiKeyResponse(key))
+}
+
+// @Summary     Get a user's library
+// @Description Retrieve the songs, albums and playlists of any user (moderators and admins). Every view is recorded in the audit trail.
+// @Tags        admin
+// @Produce     json
+// @Param       id path int true "User ID"
+// @Success     200 {object} models.UserLibraryResponse
+// @Failure     400 {object} map[string]interface{}
+// @Failure     403 {object} map[string]interface{}
+// @Failure     404 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Security    BearerAuth
+// @Router      /admin/users/{id}/library [get]
+func (ctl *AdminController) GetUserLibrary(c *gin.Context) {
+	userId, ok := idParam(c, "id", "user")
+	if !ok {
+		return
+	}
+
+	actorId, ok := c.MustGet("userId").(uint)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	library, err := ctl.moderation.Library(actorId, userId)
+	if err != nil {
+		userError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.UserLibraryResponse{
+		User:      userResponse(library.User),
+		Songs:     library.Songs,
+		Albums:    library.Albums,
+		Playlists: library.Playlists,
+	})
+}
+
+// @Summary     Remove a record
+// @Description Delete a song, album or playlist of any user the way its owner would (moderators and admins): songs leave every playlist, albums take their songs with them and playlists lose their entries. The reason is recorded in the audit trail, and the record can be restored.
+// @Tags        admin
+// @Accept      json
+// @Produce     json
+// @Param       type path string true "Record type" Enums(songs, albums, playlists)
+// @Param       id path int true "Record ID"
+// @Param       body body models.RecordRemoveRequest true "Reason for the removal"
+// @Success     200 {object} map[string]interface{}
+// @Failure     400 {object} map[string]interface{}
+// @Failure     403 {object} map[string]interface{}
+// @Failure     404 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Security    BearerAuth
+// @Router      /admin/content/{type}/{id} [delete]
+func (ctl *AdminController) RemoveRecord(c *gin.Context) {
+	id, ok := idParam(c, "id", "record")
+	if !ok {
+		return
+	}
+
+	var input models.RecordRemoveRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	actorId, ok := c.MustGet("userId").(uint)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	if err := ctl.moderation.Remove(actorId, c.Param("type"), id, input.Reason); err != nil {
+		switch {
+		case errors.Is(err, services.ErrUnknownRecordType):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Record type must be songs, albums or playlists"})
+		case errors.Is(err, services.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "No record with this ID"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Record removed successfully"})
+}
+
+// @Summary     List deleted records
+// @Description List deleted songs, albums or playlists, most recently deleted first (moderators and admins)
+// @Tags        admin
+// @Produce     json
+// @Param       type path string true "Record type" Enums(songs, albums, playlists)
+// @Param       user_id query int false "Only records of this user"
+// @Param       limit query int false "Limit results (default: 20, max: 100)"
+// @Param       offset query int false "Offset for pagination (default: 0)"
+// @Success     200 {object} map[string]interface{}
+// @Failure     400 {object} map[string]interface{}
+// @Failure     403 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Security    BearerAuth
+// @Router      /admin/deleted/{type} [get]
+func (ctl *AdminController) ListDeleted(c *gin.Context) {
+	userId, ok := optionalIdQuery(c, "user_id")
+	if !ok {
+		return
+	}
+	var owner uint
+	if userId != nil {
+		owner = *userId
+	}
+	limit, offset := pageParams(c)
+
+	records, total, err := ctl.moderation.ListDeleted(c.Param("type"), owner, limit, offset)
+	if err != nil {
+		if errors.Is(err, services.ErrUnknownRecordType) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Record type must be songs, albums or playlists"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		c.Param("type"): records,
+		"pagination":    pagination(total, limit, offset),
+	})
+}
+
+// @Summary     Restore a deleted record
+// @Description Undelete a song, album or playlist (moderators and admins). Albums come back without their songs, which are restored separately, and playlists come back empty. An album cannot be restored while its owner has another album with the same artist, title and year, nor a song while another song has its disc and track number on the album.
+// @Tags        admin
+// @Produce     json
+// @Param       type path string true "Record type" Enums(songs, albums, playlists)
+// @Param       id path int true "Record ID"
+// @Success     200 {object} map[string]interface{}
+// @Failure     400 {object} map[string]interface{}
+// @Failure     403 {object} map[string]interface{}
+// @Failure     404 {object} map[string]interface{}
+// @Failure     409 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Security    BearerAuth
+// @Router      /admin/deleted/{type}/{id}/restore [post]
+func (ctl *AdminController) RestoreDeleted(c *gin.Context) {
+	id, ok := idParam(c, "id", "record")
+	if !ok {
+		return
+	}
+
+	actorId, ok := c.MustGet("userId").(uint)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	restored, err := ctl.moderation.Restore(actorId, c.Param("type"), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrUnknownRecordType):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Record type must be songs, albums or playlists"})
+		case errors.Is(err, services.ErrRecordNotDeleted):
+			c.JSON(http.StatusNotFound, gin.H{"error": "No deleted record with this ID"})
+		case errors.Is(err, services.ErrRestoreConflict):
+			c.JSON(http.StatusConflict, gin.H{"error": "The record conflicts with a live one: an album with the same artist, title and year, or a song at the same place on its album"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, restored)
+}
+
+// @Summary     List the audit trail
+// @Description List administrative and moderation actions, newest first (moderators and admins)
+// @Tags        admin
+// @Produce     json
+// @Param       actor_id query int false "Only actions taken by this user"
+// @Param       action query string false "Only this action, e.g. user.suspended"
+// @Param       target_type query string false "Only actions on this kind of record" Enums(user, song, album, playlist, api_key, login_attempt)
+// @Param       target_id query int false "Only actions on the record with this ID"
+// @Param       limit query int false "Limit results (default: 20, max: 100)"
+// @Param       offset query int false "Offset for pagination (default: 0)"
+// @Success     200 {object} map[string]interface{}
+// @Failure     400 {object} map[string]interface{}
+// @Failure     403 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Security    BearerAuth
+// @Router      /admin/audit [get]
+func (ctl *AdminController) ListAudit(c *gin.Context) {
+	filter := repositories.AuditFilter{Action: c.Query("action"), TargetType: c.Query("target_type")}
+	var ok bool
+	if filter.ActorId, ok = optionalIdQuery(c, "actor_id"); !ok {
+		return
+	}
+	if filter.TargetId, ok = optionalIdQuery(c, "target_id"); !ok {
+		return
+	}
+	limit, offset := pageParams(c)
+
+	entries, total, err := ctl.audit.List(filter, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"entries":    entries,
+		"pagination": pagination(total, limit, offset),
+	})
+}
+
+// @Summary     List login lockouts
+// @Description List the email addresses and IP addresses that are locked out after failed logins right now, longest lockouts first (admins only)
+// @Tags        admin
+// @Produce     json
+// @Param       kind query string false "Only accounts or only IP addresses" Enums(account, ip)
+// @Param       limit query int false "Limit results (default: 20, max: 100)"
+// @Param       offset query int false "Offset for pagination (default: 0)"
+// @Success     200 {object} map[string]interface{}
+// @Failure     400 {object} map[string]interface{}
+// @Failure     403 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Security    BearerAuth
+// @Router      /admin/lockouts [get]
+func (ctl *AdminController) ListLockouts(c *gin.Context) {
+	kind := c.Query("kind")
+	if kind != "" && kind != models.LoginAttemptAccount && kind != models.LoginAttemptIP {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "kind must be account or ip"})
+		return
+	}
+	limit, offset := pageParams(c)
+
+	lockouts, total, err := ctl.throttle.ListLocked(kind, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"lockouts":   lockouts,
+		"pagination": pagination(total, limit, offset),
+	})
+}
+
+// @Summary     Lift a login lockout
+// @Description Clear a lockout and the failed logins behind it, so the account or IP address can log in again right away (admins only). Recorded in the audit trail.
+// @Tags        admin
+// @Produce     json
+// @Param       id path int true "Lockout ID"
+// @Success     200 {object} models.LoginAttempt
+// @Failure     400 {object} map[string]interface{}
+// @Failure     403 {object} map[string]interface{}
+// @Failure     404 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Security    BearerAuth
+// @Router      /admin/lockouts/{id} [delete]
+func (ctl *AdminController) Unlock(c *gin.Context) {
+	id, ok := idParam(c, "id", "lockout")
+	if !ok {
+		return
+	}
+
+	actorId, ok := c.MustGet("userId").(uint)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	lockout, err := ctl.throttle.Unlock(actorId, id)
+	if err != nil {
+		if errors.Is(err, services.ErrLockoutNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Lockout not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, lockout)
+}
+
+// userError responds to the errors of the admin account actions
+func userError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	case errors.Is(err, services.ErrInvalidRole):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role"})
+	case errors.Is(err, services.ErrOwnRole):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot change your own role"})
+	case errors.Is(err, services.ErrOwnAccount):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot suspend your own account"})
+	case errors.Is(err, services.ErrAlreadySuspended):
+		c.JSON(http.StatusConflict, gin.H{"error": "Account is already suspended"})
+	case errors.Is(err, services.ErrNotSuspended):
+		c.JSON(http.StatusConflict, gin.H{"error": "Account is not suspended"})
+	case errors.Is(err, services.ErrTwoFactorNotEnabled):
+		c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is not enabled"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
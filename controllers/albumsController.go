@@ -1,17 +1,30 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
-	"github.com/tushar27x/music-lib-api/config"
 	"github.com/tushar27x/music-lib-api/models"
-	"gorm.io/gorm"
+	"github.com/tushar27x/music-lib-api/search"
+	"github.com/tushar27x/music-lib-api/services"
 )
 
+// AlbumController serves the album endpoints
+type AlbumController struct {
+	albums *services.AlbumService
+	covers *services.CoverService
+	search *services.SearchService
+}
+
+// NewAlbumController returns an AlbumController backed by the given services
+func NewAlbumController(albums *services.AlbumService, covers *services.CoverService, search *services.SearchService) *AlbumController {
+	return &AlbumController{albums: albums, covers: covers, search: search}
+}
+
 // @Summary     Create a new album
-// @Description Create a new album (artists only)
+// @Description Create a new album (artists and admins only)
 // @Tags        albums
 // @Accept      json
 // @Produce     json
@@ -19,10 +32,12 @@ import (
 // @Success     201 {object} models.AlbumResponse
 // @Failure     400 {object} map[string]interface{}
 // @Failure     403 {object} map[string]interface{}
+// @Failure     409 {object} map[string]interface{}
 // @Failure     500 {object} map[string]interface{}
 // @Security    BearerAuth
+// @Security    ApiKeyAuth
 // @Router      /albums/ [post]
-func CreateAlbum(c *gin.Context) {
+func (ctl *AlbumController) CreateAlbum(c *gin.Context) {
 	var album models.Album
 
 	if err := c.ShouldBindJSON(&album); err != nil {
@@ -41,13 +56,18 @@ func CreateAlbum(c *gin.Context) {
 		return
 	}
 
-	if role != "artist" {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Only artists can create albums"})
-		return
-	}
-	album.UserId = userId
-
-	if err := config.DB.Create(&album).Error; err != nil {
+	if err := ctl.albums.Create(userId, role, &album); err != nil {
+		if creditError(c, err) || categoryError(c, err) {
+			return
+		}
+		if errors.Is(err, services.ErrPermissionDenied) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Your role cannot create albums"})
+			return
+		}
+		if errors.Is(err, services.ErrAlbumExists) {
+			c.JSON(http.StatusConflict, gin.H{"error": "You already have an album with this artist, title and year"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -63,18 +83,17 @@ func CreateAlbum(c *gin.Context) {
 // @Failure     400 {object} map[string]interface{}
 // @Failure     500 {object} map[string]interface{}
 // @Security    BearerAuth
+// @Security    ApiKeyAuth
 // @Router      /albums/ [get]
-func GetAlbums(c *gin.Context) {
+func (ctl *AlbumController) GetAlbums(c *gin.Context) {
 	userId, ok := c.MustGet("userId").(uint)
 	if !ok {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
 		return
 	}
 
-	var albums []models.Album
-	if err := config.DB.Preload("Songs", func(db *gorm.DB) *gorm.DB {
-		return db.Where("user_id = ?", userId)
-	}).Where("user_id = ?", userId).Find(&albums).Error; err != nil {
+	albums, err := ctl.albums.List(userId)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -92,8 +111,9 @@ func GetAlbums(c *gin.Context) {
 // @Failure     404 {object} map[string]interface{}
 // @Failure     500 {object} map[string]interface{}
 // @Security    BearerAuth
+// @Security    ApiKeyAuth
 // @Router      /albums/{id} [get]
-func GetAlbumByID(c *gin.Context) {
+func (ctl *AlbumController) GetAlbumByID(c *gin.Context) {
 	userId, ok := c.MustGet("userId").(uint)
 	if !ok {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid User ID"})
@@ -101,18 +121,14 @@ func GetAlbumByID(c *gin.Context) {
 	}
 
 	// Get album ID from URL parameter
-	albumId := c.Param("id")
-	if albumId == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Album ID is required"})
+	albumId, ok := idParam(c, "id", "album")
+	if !ok {
 		return
 	}
 
-	var album models.Album
-
-	if err := config.DB.Preload("Songs", func(db *gorm.DB) *gorm.DB {
-		return db.Where("user_id = ?", userId)
-	}).Where("id = ? AND user_id = ?", albumId, userId).First(&album).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
+	album, err := ctl.albums.Get(userId, albumId)
+	if err != nil {
+		if errors.Is(err, services.ErrAlbumNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Album not found"})
 			return
 		}
@@ -124,7 +140,7 @@ func GetAlbumByID(c *gin.Context) {
 }
 
 // @Summary     Update album by ID
-// @Description Update a specific album by its ID (artists only)
+// @Description Update a specific album by its ID (artists and admins only)
 // @Tags        albums
 // @Accept      json
 // @Produce     json
@@ -134,10 +150,12 @@ func GetAlbumByID(c *gin.Context) {
 // @Failure     400 {object} map[string]interface{}
 // @Failure     403 {object} map[string]interface{}
 // @Failure     404 {object} map[string]interface{}
+// @Failure     409 {object} map[string]interface{}
 // @Failure     500 {object} map[string]interface{}
 // @Security    BearerAuth
+// @Security    ApiKeyAuth
 // @Router      /albums/{id} [put]
-func UpdateAlbum(c *gin.Context) {
+func (ctl *AlbumController) UpdateAlbum(c *gin.Context) {
 	userId, ok := c.MustGet("userId").(uint)
 	if !ok {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
@@ -150,64 +168,160 @@ func UpdateAlbum(c *gin.Context) {
 		return
 	}
 
-	if role != "artist" {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Only artists can update albums"})
+	albumId, ok := idParam(c, "id", "album")
+	if !ok {
 		return
 	}
 
-	albumId := c.Param("id")
-	if albumId == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Album ID is required"})
+	// Parse the update data
+	var updateData models.AlbumCreateRequest
+	if err := c.ShouldBindJSON(&updateData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Check if album exists and belongs to the user
-	var existingAlbum models.Album
-	if err := config.DB.Where("id = ? AND user_id = ?", albumId, userId).First(&existingAlbum).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Album not found"})
+	existingAlbum, err := ctl.albums.Update(userId, role, albumId, updateData)
+	if err != nil {
+		if creditError(c, err) || categoryError(c, err) {
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		switch {
+		case errors.Is(err, services.ErrPermissionDenied):
+			c.JSON(http.StatusForbidden, gin.H{"error": "Your role cannot update albums"})
+		case errors.Is(err, services.ErrAlbumNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Album not found"})
+		case errors.Is(err, services.ErrAlbumExists):
+			c.JSON(http.StatusConflict, gin.H{"error": "You already have an album with this artist, title and year"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
 		return
 	}
 
-	// Parse the update data
-	var updateData models.AlbumCreateRequest
-	if err := c.ShouldBindJSON(&updateData); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	// Return the updated album
+	c.JSON(http.StatusOK, existingAlbum)
+}
+
+// @Summary     Delete album by ID
+// @Description Delete a specific album by its ID and all its songs (artists and admins only)
+// @Tags        albums
+// @Produce     json
+// @Param       id path int true "Album ID"
+// @Success     200 {object} map[string]interface{}
+// @Failure     400 {object} map[string]interface{}
+// @Failure     403 {object} map[string]interface{}
+// @Failure     404 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Security    BearerAuth
+// @Security    ApiKeyAuth
+// @Router      /albums/{id} [delete]
+func (ctl *AlbumController) DeleteAlbum(c *gin.Context) {
+	userId, ok := c.MustGet("userId").(uint)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
 		return
 	}
 
-	// Update the album
-	updates := map[string]interface{}{
-		"title":  updateData.Title,
-		"artist": updateData.Artist,
-		"year":   updateData.Year,
+	role, ok := c.MustGet("role").(string)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user role"})
+		return
 	}
 
-	if err := config.DB.Model(&existingAlbum).Updates(updates).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	albumId, ok := idParam(c, "id", "album")
+	if !ok {
 		return
 	}
 
-	// Return the updated album
-	c.JSON(http.StatusOK, existingAlbum)
+	// Songs on the album are deleted together with it
+	if err := ctl.albums.Delete(userId, role, albumId); err != nil {
+		switch {
+		case errors.Is(err, services.ErrPermissionDenied):
+			c.JSON(http.StatusForbidden, gin.H{"error": "Your role cannot delete albums"})
+		case errors.Is(err, services.ErrAlbumNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Album not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Album and all its songs deleted successfully"})
 }
 
-// @Summary     Delete album by ID
-// @Description Delete a specific album by its ID and all its songs (artists only)
+// @Summary     Merge a duplicate album
+// @Description Move the songs of an album into another album of the user, add the credits and tags the target lacks, and its genre and cover when the target has none, and delete the merged album and a cover the target did not take, all at once (artists and admins only). Moved songs whose disc and track number is taken on the target lose their track number.
 // @Tags        albums
+// @Accept      json
+// @Produce     json
+// @Param       id path int true "ID of the album to merge"
+// @Param       merge body models.AlbumMergeRequest true "Target album"
+// @Success     200 {object} models.AlbumResponse
+// @Failure     400 {object} map[string]interface{}
+// @Failure     403 {object} map[string]interface{}
+// @Failure     404 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Security    BearerAuth
+// @Security    ApiKeyAuth
+// @Router      /albums/{id}/merge [post]
+func (ctl *AlbumController) MergeAlbum(c *gin.Context) {
+	userId, ok := c.MustGet("userId").(uint)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	role, ok := c.MustGet("role").(string)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user role"})
+		return
+	}
+
+	albumId, ok := idParam(c, "id", "album")
+	if !ok {
+		return
+	}
+
+	var input models.AlbumMergeRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	target, err := ctl.albums.Merge(c.Request.Context(), userId, role, albumId, input.TargetAlbumId)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrPermissionDenied):
+			c.JSON(http.StatusForbidden, gin.H{"error": "Your role cannot merge albums"})
+		case errors.Is(err, services.ErrMergeSameAlbum):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "An album cannot be merged into itself"})
+		case errors.Is(err, services.ErrAlbumNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Album not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, target)
+}
+
+// @Summary     Set an album's tracklist
+// @Description Renumber and reorder every song on an album at once (artists and admins only). Each song is listed once, in order; entries without a disc number stay on the previous entry's disc and entries without a track number follow the previous entry on their disc.
+// @Tags        albums
+// @Accept      json
 // @Produce     json
 // @Param       id path int true "Album ID"
-// @Success     200 {object} map[string]interface{}
+// @Param       tracklist body models.TracklistRequest true "Songs in tracklist order"
+// @Success     200 {object} models.AlbumResponse
 // @Failure     400 {object} map[string]interface{}
 // @Failure     403 {object} map[string]interface{}
 // @Failure     404 {object} map[string]interface{}
 // @Failure     500 {object} map[string]interface{}
 // @Security    BearerAuth
-// @Router      /albums/{id} [delete]
-func DeleteAlbum(c *gin.Context) {
+// @Security    ApiKeyAuth
+// @Router      /albums/{id}/tracklist [put]
+func (ctl *AlbumController) UpdateTracklist(c *gin.Context) {
 	userId, ok := c.MustGet("userId").(uint)
 	if !ok {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
@@ -220,70 +334,270 @@ func DeleteAlbum(c *gin.Context) {
 		return
 	}
 
-	if role != "artist" {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Only artists can delete albums"})
+	albumId, ok := idParam(c, "id", "album")
+	if !ok {
 		return
 	}
 
-	albumId := c.Param("id")
-	if albumId == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Album ID is required"})
+	var input models.TracklistRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Check if album exists and belongs to the user
-	var album models.Album
-	if err := config.DB.Where("id = ? AND user_id = ?", albumId, userId).First(&album).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
+	album, err := ctl.albums.Tracklist(userId, role, albumId, input)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrPermissionDenied):
+			c.JSON(http.StatusForbidden, gin.H{"error": "Your role cannot update albums"})
+		case errors.Is(err, services.ErrAlbumNotFound):
 			c.JSON(http.StatusNotFound, gin.H{"error": "Album not found"})
-			return
+		case errors.Is(err, services.ErrTracklistSongs), errors.Is(err, services.ErrTracklistPositions):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Start a transaction
-	tx := config.DB.Begin()
+	c.JSON(http.StatusOK, album)
+}
 
-	// Delete all songs associated with this album
-	if err := tx.Where("album_id = ? AND user_id = ?", albumId, userId).Delete(&models.Song{}).Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete songs: " + err.Error()})
+// @Summary     Get an album's cover
+// @Description Retrieve the cover image of an album, or a JPEG thumbnail of it. Supports ETag and Last-Modified so clients can cache covers.
+// @Tags        albums
+// @Produce     image/jpeg,image/png,image/webp
+// @Param       id path int true "Album ID"
+// @Param       size query int false "Thumbnail size in pixels; the original image when omitted" Enums(64, 128, 256, 512)
+// @Success     200 {file} file
+// @Failure     304 {object} map[string]interface{}
+// @Failure     400 {object} map[string]interface{}
+// @Failure     404 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Security    BearerAuth
+// @Security    ApiKeyAuth
+// @Router      /albums/{id}/cover [get]
+func (ctl *AlbumController) GetAlbumCover(c *gin.Context) {
+	userId, ok := c.MustGet("userId").(uint)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
 		return
 	}
 
-	// Delete the album
-	if err := tx.Delete(&album).Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	albumId, ok := idParam(c, "id", "album")
+	if !ok {
 		return
 	}
 
-	// Commit the transaction
-	if err := tx.Commit().Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction: " + err.Error()})
+	size, ok := coverSize(c)
+	if !ok {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Album and all its songs deleted successfully"})
+	cover, err := ctl.covers.AlbumCover(c.Request.Context(), userId, albumId, size)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrAlbumNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Album not found"})
+		case errors.Is(err, services.ErrNoCover):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Album has no cover"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	serveCover(c, cover)
+}
+
+// @Summary     Upload an album cover
+// @Description Upload a JPEG, PNG or WebP cover image for an album as multipart form data (artists and admins only), replacing the current cover. Thumbnails are rendered on upload.
+// @Tags        albums
+// @Accept      multipart/form-data
+// @Produce     json
+// @Param       id path int true "Album ID"
+// @Param       file formData file true "Cover image (JPEG, PNG, WebP)"
+// @Success     200 {object} models.AlbumResponse
+// @Failure     400 {object} map[string]interface{}
+// @Failure     403 {object} map[string]interface{}
+// @Failure     404 {object} map[string]interface{}
+// @Failure     413 {object} map[string]interface{}
+// @Failure     415 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Security    BearerAuth
+// @Security    ApiKeyAuth
+// @Router      /albums/{id}/cover [post]
+func (ctl *AlbumController) UploadAlbumCover(c *gin.Context) {
+	userId, ok := c.MustGet("userId").(uint)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	role, ok := c.MustGet("role").(string)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user role"})
+		return
+	}
+
+	albumId, ok := idParam(c, "id", "album")
+	if !ok {
+		return
+	}
+
+	data, ok := coverUpload(c)
+	if !ok {
+		return
+	}
+
+	if err := ctl.covers.SetAlbumCover(c.Request.Context(), userId, role, albumId, data); err != nil {
+		ctl.albumCoverError(c, err)
+		return
+	}
+
+	ctl.respondAlbum(c, userId, albumId)
+}
+
+// @Summary     Delete an album cover
+// @Description Remove the cover image of an album and its thumbnails (artists and admins only)
+// @Tags        albums
+// @Produce     json
+// @Param       id path int true "Album ID"
+// @Success     200 {object} models.AlbumResponse
+// @Failure     400 {object} map[string]interface{}
+// @Failure     403 {object} map[string]interface{}
+// @Failure     404 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Security    BearerAuth
+// @Security    ApiKeyAuth
+// @Router      /albums/{id}/cover [delete]
+func (ctl *AlbumController) DeleteAlbumCover(c *gin.Context) {
+	userId, ok := c.MustGet("userId").(uint)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	role, ok := c.MustGet("role").(string)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user role"})
+		return
+	}
+
+	albumId, ok := idParam(c, "id", "album")
+	if !ok {
+		return
+	}
+
+	if err := ctl.covers.DeleteAlbumCover(c.Request.Context(), userId, role, albumId); err != nil {
+		ctl.albumCoverError(c, err)
+		return
+	}
+
+	ctl.respondAlbum(c, userId, albumId)
+}
+
+// @Summary     Use embedded cover art
+// @Description Make the cover art embedded in a song's audio file the album cover (artists and admins only), replacing the current cover. Without song_id the album's songs are tried in tracklist order.
+// @Tags        albums
+// @Produce     json
+// @Param       id path int true "Album ID"
+// @Param       song_id query int false "Song on the album to take the cover art from"
+// @Success     200 {object} models.AlbumResponse
+// @Failure     400 {object} map[string]interface{}
+// @Failure     403 {object} map[string]interface{}
+// @Failure     404 {object} map[string]interface{}
+// @Failure     415 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Security    BearerAuth
+// @Security    ApiKeyAuth
+// @Router      /albums/{id}/cover/extract [post]
+func (ctl *AlbumController) ExtractAlbumCover(c *gin.Context) {
+	userId, ok := c.MustGet("userId").(uint)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	role, ok := c.MustGet("role").(string)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user role"})
+		return
+	}
+
+	albumId, ok := idParam(c, "id", "album")
+	if !ok {
+		return
+	}
+
+	songId, ok := optionalIdQuery(c, "song_id")
+	if !ok {
+		return
+	}
+	var fromSong uint
+	if songId != nil {
+		fromSong = *songId
+	}
+
+	if err := ctl.covers.ExtractAlbumCover(c.Request.Context(), userId, role, albumId, fromSong); err != nil {
+		ctl.albumCoverError(c, err)
+		return
+	}
+
+	ctl.respondAlbum(c, userId, albumId)
+}
+
+// albumCoverError maps the errors of changing an album cover to responses
+func (ctl *AlbumController) albumCoverError(c *gin.Context, err error) {
+	if coverError(c, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, services.ErrPermissionDenied):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Your role cannot update albums"})
+	case errors.Is(err, services.ErrAlbumNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Album not found"})
+	case errors.Is(err, services.ErrNoCover):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Album has no cover"})
+	case errors.Is(err, services.ErrNoEmbeddedCover):
+		c.JSON(http.StatusNotFound, gin.H{"error": "No song on the album has embedded cover art"})
+	case errors.Is(err, services.ErrSongNotOnAlbum):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// respondAlbum answers with an album of the user, its songs and credits
+func (ctl *AlbumController) respondAlbum(c *gin.Context, userId uint, albumId uint) {
+	album, err := ctl.albums.Get(userId, albumId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, album)
 }
 
 // @Summary     Search albums
-// @Description Search albums by title, artist, or year with fuzzy matching
+// @Description Full-text search over album titles, artists and years, ranked by relevance and tolerant of typos. The query supports "phrases", prefix* matching, -exclusion and OR. Results can be narrowed to genres and tags.
 // @Tags        albums
 // @Produce     json
 // @Param       q query string false "Search query (searches title, artist, year)"
 // @Param       title query string false "Search by title"
 // @Param       artist query string false "Search by artist"
 // @Param       year query int false "Search by year"
+// @Param       genre query []string false "Genre name, also matching its subgenres; repeat for several" collectionFormat(multi)
+// @Param       tag query []string false "Tag name; repeat for several" collectionFormat(multi)
+// @Param       match query string false "Whether albums must match all genres and tags or any of them (default: all)" Enums(all, any)
 // @Param       limit query int false "Limit results (default: 20, max: 100)"
 // @Param       offset query int false "Offset for pagination (default: 0)"
 // @Success     200 {object} map[string]interface{}
 // @Failure     400 {object} map[string]interface{}
 // @Failure     500 {object} map[string]interface{}
 // @Security    BearerAuth
+// @Security    ApiKeyAuth
 // @Router      /albums/search [get]
-func SearchAlbums(c *gin.Context) {
+func (ctl *AlbumController) SearchAlbums(c *gin.Context) {
 	userId, ok := c.MustGet("userId").(uint)
 	if !ok {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
@@ -320,45 +634,20 @@ func SearchAlbums(c *gin.Context) {
 		}
 	}
 
-	// Build the query
-	dbQuery := config.DB.Where("user_id = ?", userId)
-
-	// Apply search filters
-	if query != "" {
-		// General search across title, artist, and year
-		dbQuery = dbQuery.Where(
-			"LOWER(title) LIKE LOWER(?) OR LOWER(artist) LIKE LOWER(?) OR CAST(year AS TEXT) LIKE ?",
-			"%"+query+"%", "%"+query+"%", "%"+query+"%",
-		)
-	} else {
-		// Specific field searches
-		if title != "" {
-			dbQuery = dbQuery.Where("LOWER(title) LIKE LOWER(?)", "%"+title+"%")
-		}
-		if artist != "" {
-			dbQuery = dbQuery.Where("LOWER(artist) LIKE LOWER(?)", "%"+artist+"%")
-		}
-		if yearStr != "" {
-			if year, err := strconv.Atoi(yearStr); err == nil {
-				dbQuery = dbQuery.Where("year = ?", year)
-			}
-		}
-	}
-
-	// Preload songs and apply pagination
-	var albums []models.Album
-	var total int64
-
-	// Count total results
-	if err := dbQuery.Model(&models.Album{}).Count(&total).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	// Field filters apply on top of the text query
+	categories, ok := categoryParams(c)
+	if !ok {
 		return
 	}
+	filters := services.AlbumSearchFilters{Title: title, Artist: artist, Categories: categories}
+	if yearStr != "" {
+		if year, err := strconv.Atoi(yearStr); err == nil {
+			filters.Year = &year
+		}
+	}
 
-	// Get paginated results with songs
-	if err := dbQuery.Preload("Songs", func(db *gorm.DB) *gorm.DB {
-		return db.Where("user_id = ?", userId)
-	}).Limit(limit).Offset(offset).Find(&albums).Error; err != nil {
+	albums, total, err := ctl.search.SearchAlbums(userId, search.Parse(query), filters, limit, offset)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -0,0 +1,149 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tushar27x/music-lib-api/models"
+	"github.com/tushar27x/music-lib-api/services"
+)
+
+// APIKeyController serves the endpoints for managing one's own API keys
+type APIKeyController struct {
+	apiKeys *services.APIKeyService
+}
+
+// NewAPIKeyController returns an APIKeyController backed by the given service
+func NewAPIKeyController(apiKeys *services.APIKeyService) *APIKeyController {
+	return &APIKeyController{apiKeys: apiKeys}
+}
+
+// @Summary     List API keys
+// @Description List the API keys of the authenticated user, including revoked and expired ones. Keys themselves are never returned again after creation.
+// @Tags        api-keys
+// @Produce     json
+// @Success     200 {array} models.APIKeyResponse
+// @Failure     400 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Security    BearerAuth
+// @Router      /auth/api-keys [get]
+func (ctl *APIKeyController) ListAPIKeys(c *gin.Context) {
+	userId, ok := c.MustGet("userId").(uint)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	keys, err := ctl.apiKeys.List(userId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, apiKeyResponses(keys))
+}
+
+// @Summary     Create an API key
+// @Description Create a named API key limited to the given scopes, optionally expiring. The key is only shown in this response. Send it in the X-API-Key header or as a Bearer token.
+// @Tags        api-keys
+// @Accept      json
+// @Produce     json
+// @Param       key body models.APIKeyCreateRequest true "API key data"
+// @Success     201 {object} models.APIKeyCreateResponse
+// @Failure     400 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Security    BearerAuth
+// @Router      /auth/api-keys [post]
+func (ctl *APIKeyController) CreateAPIKey(c *gin.Context) {
+	userId, ok := c.MustGet("userId").(uint)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var input models.APIKeyCreateRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	key, rawKey, err := ctl.apiKeys.Create(userId, input)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidScope):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown scope"})
+		case errors.Is(err, services.ErrAPIKeyExpired):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "expires_at must be in the future"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.APIKeyCreateResponse{APIKeyResponse: apiKeyResponse(key), Key: rawKey})
+}
+
+// @Summary     Revoke an API key
+// @Description Revoke one of the authenticated user's API keys; it stops working immediately
+// @Tags        api-keys
+// @Produce     json
+// @Param       id path int true "API key ID"
+// @Success     200 {object} models.APIKeyResponse
+// @Failure     400 {object} map[string]interface{}
+// @Failure     404 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Security    BearerAuth
+// @Router      /auth/api-keys/{id} [delete]
+func (ctl *APIKeyController) RevokeAPIKey(c *gin.Context) {
+	userId, ok := c.MustGet("userId").(uint)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	keyId, ok := idParam(c, "id", "API key")
+	if !ok {
+		return
+	}
+
+	key, err := ctl.apiKeys.Revoke(userId, keyId)
+	if err != nil {
+		apiKeyError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, apiKeyResponse(key))
+}
+
+// apiKeyError maps the errors of revoking an API key to responses
+func apiKeyError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrAPIKeyNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
+// apiKeyResponse turns the stored scopes of a key into a list
+func apiKeyResponse(key models.APIKey) models.APIKeyResponse {
+	return models.APIKeyResponse{
+		ID:         key.ID,
+		CreatedAt:  key.CreatedAt,
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Scopes:     strings.Fields(key.Scopes),
+		ExpiresAt:  key.ExpiresAt,
+		LastUsedAt: key.LastUsedAt,
+		RevokedAt:  key.RevokedAt,
+	}
+}
+
+func apiKeyResponses(keys []models.APIKey) []models.APIKeyResponse {
+	responses := make([]models.APIKeyResponse, 0, len(keys))
+	for _, key := range keys {
+		responses = append(responses, apiKeyResponse(key))
+	}
+	return responses
+}
@@ -0,0 +1,323 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tushar27x/music-lib-api/models"
+	"github.com/tushar27x/music-lib-api/search"
+	"github.com/tushar27x/music-lib-api/services"
+)
+
+// ArtistController serves the artist endpoints
+type ArtistController struct {
+	artists *services.ArtistService
+	search  *services.SearchService
+}
+
+// NewArtistController returns an ArtistController backed by the given services
+func NewArtistController(artists *services.ArtistService, search *services.SearchService) *ArtistController {
+	return &ArtistController{artists: artists, search: search}
+}
+
+// @Summary     Create an artist
+// @Description Add an artist to the user's library. Names are unique per user, ignoring case.
+// @Tags        artists
+// @Accept      json
+// @Produce     json
+// @Param       artist body models.ArtistCreateRequest true "Artist data"
+// @Success     201 {object} models.Artist
+// @Failure     400 {object} map[string]interface{}
+// @Failure     409 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Security    BearerAuth
+// @Security    ApiKeyAuth
+// @Router      /artists/ [post]
+func (ctl *ArtistController) CreateArtist(c *gin.Context) {
+	var input models.ArtistCreateRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userId, ok := c.MustGet("userId").(uint)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	artist, err := ctl.artists.Create(userId, input)
+	if err != nil {
+		artistError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, artist)
+}
+
+// @Summary     Get all artists
+// @Description Retrieve all artists of the authenticated user in name order
+// @Tags        artists
+// @Produce     json
+// @Success     200 {array} models.Artist
+// @Failure     400 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Security    BearerAuth
+// @Security    ApiKeyAuth
+// @Router      /artists/ [get]
+func (ctl *ArtistController) GetArtists(c *gin.Context) {
+	userId, ok := c.MustGet("userId").(uint)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	artists, err := ctl.artists.List(userId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, artists)
+}
+
+// @Summary     Get artist by ID
+// @Description Retrieve a specific artist of the authenticated user
+// @Tags        artists
+// @Produce     json
+// @Param       id path int true "Artist ID"
+// @Success     200 {object} models.Artist
+// @Failure     400 {object} map[string]interface{}
+// @Failure     404 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Security    BearerAuth
+// @Security    ApiKeyAuth
+// @Router      /artists/{id} [get]
+func (ctl *ArtistController) GetArtistByID(c *gin.Context) {
+	userId, ok := c.MustGet("userId").(uint)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	artistId, ok := idParam(c, "id", "artist")
+	if !ok {
+		return
+	}
+
+	artist, err := ctl.artists.Get(userId, artistId)
+	if err != nil {
+		artistError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, artist)
+}
+
+// @Summary     Rename an artist
+// @Description Rename an artist of the authenticated user. Albums and songs crediting the artist as primary artist show the new name.
+// @Tags        artists
+// @Accept      json
+// @Produce     json
+// @Param       id path int true "Artist ID"
+// @Param       artist body models.ArtistCreateRequest true "Updated artist data"
+// @Success     200 {object} models.Artist
+// @Failure     400 {object} map[string]interface{}
+// @Failure     404 {object} map[string]interface{}
+// @Failure     409 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Security    BearerAuth
+// @Security    ApiKeyAuth
+// @Router      /artists/{id} [put]
+func (ctl *ArtistController) UpdateArtist(c *gin.Context) {
+	userId, ok := c.MustGet("userId").(uint)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	artistId, ok := idParam(c, "id", "artist")
+	if !ok {
+		return
+	}
+
+	var input models.ArtistCreateRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	artist, err := ctl.artists.Update(userId, artistId, input)
+	if err != nil {
+		artistError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, artist)
+}
+
+// @Summary     Delete an artist
+// @Description Delete an artist of the authenticated user. Artists still credited on albums or songs cannot be deleted.
+// @Tags        artists
+// @Produce     json
+// @Param       id path int true "Artist ID"
+// @Success     200 {object} map[string]interface{}
+// @Failure     400 {object} map[string]interface{}
+// @Failure     404 {object} map[string]interface{}
+// @Failure     409 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Security    BearerAuth
+// @Security    ApiKeyAuth
+// @Router      /artists/{id} [delete]
+func (ctl *ArtistController) DeleteArtist(c *gin.Context) {
+	userId, ok := c.MustGet("userId").(uint)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	artistId, ok := idParam(c, "id", "artist")
+	if !ok {
+		return
+	}
+
+	if err := ctl.artists.Delete(userId, artistId); err != nil {
+		artistError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Artist deleted successfully"})
+}
+
+// @Summary     List an artist's albums
+// @Description Retrieve the albums crediting an artist, oldest release first, optionally only those crediting them in one role
+// @Tags        artists
+// @Produce     json
+// @Param       id path int true "Artist ID"
+// @Param       role query string false "Credit role" Enums(primary, featured, composer, producer)
+// @Success     200 {array} models.AlbumResponse
+// @Failure     400 {object} map[string]interface{}
+// @Failure     404 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Security    BearerAuth
+// @Security    ApiKeyAuth
+// @Router      /artists/{id}/albums [get]
+func (ctl *ArtistController) GetArtistAlbums(c *gin.Context) {
+	userId, ok := c.MustGet("userId").(uint)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	artistId, ok := idParam(c, "id", "artist")
+	if !ok {
+		return
+	}
+
+	albums, err := ctl.artists.Albums(userId, artistId, c.Query("role"))
+	if err != nil {
+		artistError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, albums)
+}
+
+// @Summary     List an artist's songs
+// @Description Retrieve the songs crediting an artist, optionally only those crediting them in one role
+// @Tags        artists
+// @Produce     json
+// @Param       id path int true "Artist ID"
+// @Param       role query string false "Credit role" Enums(primary, featured, composer, producer)
+// @Success     200 {object} map[string]interface{}
+// @Failure     400 {object} map[string]interface{}
+// @Failure     404 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Security    BearerAuth
+// @Security    ApiKeyAuth
+// @Router      /artists/{id}/songs [get]
+func (ctl *ArtistController) GetArtistSongs(c *gin.Context) {
+	userId, ok := c.MustGet("userId").(uint)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	artistId, ok := idParam(c, "id", "artist")
+	if !ok {
+		return
+	}
+
+	songs, err := ctl.artists.Songs(userId, artistId, c.Query("role"))
+	if err != nil {
+		artistError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"songs": songs})
+}
+
+// @Summary     Search artists
+// @Description Full-text search over artist names, ranked by relevance and tolerant of typos. The query supports "phrases", prefix* matching, -exclusion and OR.
+// @Tags        artists
+// @Produce     json
+// @Param       q query string false "Search query"
+// @Param       limit query int false "Limit results (default: 20, max: 100)"
+// @Param       offset query int false "Offset for pagination (default: 0)"
+// @Success     200 {object} map[string]interface{}
+// @Failure     400 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Security    BearerAuth
+// @Security    ApiKeyAuth
+// @Router      /artists/search [get]
+func (ctl *ArtistController) SearchArtists(c *gin.Context) {
+	userId, ok := c.MustGet("userId").(uint)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	limit, offset := pageParams(c)
+	artists, total, err := ctl.search.SearchArtists(userId, search.Parse(c.Query("q")), limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"artists":    artists,
+		"pagination": pagination(total, limit, offset),
+	})
+}
+
+// artistError maps artist errors to responses
+func artistError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrArtistNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Artist not found"})
+	case errors.Is(err, services.ErrArtistNameTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": "An artist with this name already exists"})
+	case errors.Is(err, services.ErrArtistInUse):
+		c.JSON(http.StatusConflict, gin.H{"error": "Artist is still credited on albums or songs"})
+	case errors.Is(err, services.ErrArtistNameRequired), errors.Is(err, services.ErrInvalidCreditRole):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// creditError answers requests whose credits name unknown artists or roles and reports whether it did
+func creditError(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, services.ErrArtistNotFound):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Credits name an unknown artist_id"})
+	case errors.Is(err, services.ErrInvalidCredit), errors.Is(err, services.ErrInvalidCreditRole):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrArtistNameTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": "An artist named in the credits was created at the same time, please try again"})
+	default:
+		return false
+	}
+	return true
+}
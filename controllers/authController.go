@@ -1,56 +1,82 @@
 package controllers
 
 import (
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt"
-	"github.com/tushar27x/music-lib-api/config"
 	"github.com/tushar27x/music-lib-api/models"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/tushar27x/music-lib-api/services"
 )
 
-var jwtSecret = []byte(config.GetEnv("JWT_SECRET"))
+// AuthController serves registration, login and the session endpoints
+type AuthController struct {
+	auth     *services.AuthService
+	tokens   *services.TokenService
+	accounts *services.AccountService
+}
+
+// NewAuthController returns an AuthController backed by the given services
+func NewAuthController(auth *services.AuthService, tokens *services.TokenService, accounts *services.AccountService) *AuthController {
+	return &AuthController{auth: auth, tokens: tokens, accounts: accounts}
+}
 
 // @Summary     Register a new user
-// @Description Create a new user account
+// @Description Create a new user account and email a link to verify the address
 // @Tags        auth
 // @Accept      json
 // @Produce     json
 // @Param       user body models.UserRegisterRequest true "User registration data"
 // @Success     201 {object} models.UserResponse
 // @Failure     400 {object} map[string]interface{}
+// @Failure     409 {object} map[string]interface{}
 // @Failure     500 {object} map[string]interface{}
 // @Router      /auth/register [post]
-func Register(c *gin.Context) {
-	var user models.User
+func (ctl *AuthController) Register(c *gin.Context) {
+	var input models.UserRegisterRequest
 
-	if err := c.ShouldBindJSON(&user); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err})
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	if err := config.DB.Create(&user).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	user, err := ctl.auth.Register(input)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrEmailTaken):
+			c.JSON(http.StatusConflict, gin.H{"error": "Email is already registered"})
+		case errors.Is(err, services.ErrRoleNotAllowed):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Only listener or artist can be chosen at registration"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
 		return
 	}
 
-	c.JSON(http.StatusCreated, user)
+	// The account exists either way; the user can ask for the email again
+	ctl.accounts.SendVerification(user)
+
+	c.JSON(http.StatusCreated, userResponse(user))
 }
 
 // @Summary     User login
-// @Description Authenticate user and get JWT token
+// @Description Authenticate user and get a short-lived access token plus a refresh token. Accounts with two-factor authentication get 202 with a challenge token instead, which is exchanged at POST /auth/2fa/verify together with a code. Unknown emails and wrong passwords get the same 401. Repeated failures for an email or from an IP address make further attempts wait, growing into a temporary lockout; those attempts get 429 with a Retry-After header.
 // @Tags        auth
 // @Accept      json
 // @Produce     json
 // @Param       credentials body models.UserLoginRequest true "Login credentials"
-// @Success     200 {object} map[string]interface{}
+// @Success     200 {object} models.TokenResponse
+// @Success     202 {object} models.MFAChallengeResponse
 // @Failure     400 {object} map[string]interface{}
 // @Failure     401 {object} map[string]interface{}
-// @Failure     404 {object} map[string]interface{}
+// @Failure     403 {object} map[string]interface{}
+// @Failure     429 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
 // @Router      /auth/login [post]
-func Login(c *gin.Context) {
+func (ctl *AuthController) Login(c *gin.Context) {
 	var input struct {
 		Email    string `json:"email"`
 		Password string `json:"password"`
@@ -61,23 +87,301 @@ func Login(c *gin.Context) {
 		return
 	}
 
-	var user models.User
+	tokens, challenge, err := ctl.auth.Login(input.Email, input.Password, c.ClientIP())
+	if err != nil {
+		if loginLocked(c, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, services.ErrInvalidCredentials):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
+		case errors.Is(err, services.ErrAccountSuspended):
+			c.JSON(http.StatusForbidden, gin.H{"error": "Account is suspended"})
+		case errors.Is(err, services.ErrEmailNotVerified):
+			c.JSON(http.StatusForbidden, gin.H{"error": "Email address is not verified"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to issue tokens"})
+		}
+		return
+	}
+
+	if challenge != nil {
+		c.JSON(http.StatusAccepted, challenge)
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// @Summary     Refresh tokens
+// @Description Exchange a refresh token for a new access token; the refresh token is rotated and reusing an old one revokes the whole token family
+// @Tags        auth
+// @Accept      json
+// @Produce     json
+// @Param       body body models.RefreshTokenRequest true "Refresh token"
+// @Success     200 {object} models.TokenResponse
+// @Failure     400 {object} map[string]interface{}
+// @Failure     401 {object} map[string]interface{}
+// @Failure     403 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Router      /auth/refresh [post]
+func (ctl *AuthController) Refresh(c *gin.Context) {
+	var input models.RefreshTokenRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tokens, err := ctl.tokens.RefreshTokens(input.RefreshToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrRefreshTokenReused):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token reuse detected, please log in again"})
+		case errors.Is(err, services.ErrInvalidRefreshToken):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired refresh token"})
+		case errors.Is(err, services.ErrAccountSuspended):
+			c.JSON(http.StatusForbidden, gin.H{"error": "Account is suspended"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// @Summary     User logout
+// @Description Revoke the current access token and the given refresh token (or every refresh token of the user when none is given)
+// @Tags        auth
+// @Accept      json
+// @Produce     json
+// @Param       body body models.LogoutRequest false "Refresh token to revoke"
+// @Success     200 {object} map[string]interface{}
+// @Failure     400 {object} map[string]interface{}
+// @Failure     401 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Security    BearerAuth
+// @Router      /auth/logout [post]
+func (ctl *AuthController) Logout(c *gin.Context) {
+	userId, ok := c.MustGet("userId").(uint)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var input models.LogoutRequest
+	// The body is optional, so only reject it when something was sent and it is malformed
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	// Revoke the access token used for this request
+	jti := c.GetString("jti")
+	expiresAt, _ := c.Get("tokenExpiresAt")
+	if exp, ok := expiresAt.(time.Time); ok && jti != "" {
+		if err := ctl.tokens.RevokeAccessToken(jti, exp); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
 
-	if err := config.DB.Where("email = ?", input.Email).First(&user).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	// Revoke the refresh token family, or all of the user's sessions
+	if input.RefreshToken != "" {
+		if err := ctl.tokens.RevokeRefreshToken(userId, input.RefreshToken); err != nil && !errors.Is(err, services.ErrInvalidRefreshToken) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	} else if err := ctl.tokens.RevokeUserRefreshTokens(userId); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(input.Password)); err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid Credentials"})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
+}
+
+// @Summary     Change password
+// @Description Change the password of the authenticated user. This is the only request accepted while an admin requires a password change. Every session is ended and a new token pair is returned.
+// @Tags        auth
+// @Accept      json
+// @Produce     json
+// @Param       body body models.PasswordChangeRequest true "Current and new password"
+// @Success     200 {object} models.TokenResponse
+// @Failure     400 {object} map[string]interface{}
+// @Failure     401 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Security    BearerAuth
+// @Router      /auth/password [put]
+func (ctl *AuthController) ChangePassword(c *gin.Context) {
+	userId, ok := c.MustGet("userId").(uint)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var input models.PasswordChangeRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tokens, err := ctl.auth.ChangePassword(userId, input.CurrentPassword, input.NewPassword)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidCredentials):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Current password is incorrect"})
+		case errors.Is(err, services.ErrUserNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
 		return
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id": user.ID,
-		"role":    user.Role,
-		"exp":     time.Now().Add(time.Hour * 24).Unix(),
-	})
+	// The access token used for this request belongs to the old password
+	jti := c.GetString("jti")
+	expiresAt, _ := c.Get("tokenExpiresAt")
+	if exp, ok := expiresAt.(time.Time); ok && jti != "" {
+		if err := ctl.tokens.RevokeAccessToken(jti, exp); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// @Summary     Verify email address
+// @Description Verify the email address with the token from the verification email. Tokens are single use.
+// @Tags        auth
+// @Accept      json
+// @Produce     json
+// @Param       body body models.EmailVerificationRequest true "Verification token"
+// @Success     200 {object} models.UserResponse
+// @Failure     400 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Router      /auth/verify-email [post]
+func (ctl *AuthController) VerifyEmail(c *gin.Context) {
+	var input models.EmailVerificationRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := ctl.accounts.VerifyEmail(input.Token)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidAccountToken) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Verification link is invalid or has expired"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, userResponse(user))
+}
+
+// @Summary     Resend verification email
+// @Description Email a new verification link, which replaces earlier ones. The response is the same whether or not the address has an unverified account.
+// @Tags        auth
+// @Accept      json
+// @Produce     json
+// @Param       body body models.EmailRequest true "Email address of the account"
+// @Success     200 {object} map[string]interface{}
+// @Failure     400 {object} map[string]interface{}
+// @Router      /auth/verify-email/resend [post]
+func (ctl *AuthController) ResendVerification(c *gin.Context) {
+	var input models.EmailRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Failures are logged rather than returned, which would reveal that the account exists
+	ctl.accounts.ResendVerification(input.Email)
+
+	c.JSON(http.StatusOK, gin.H{"message": "If the address belongs to an unverified account, a verification email has been sent"})
+}
+
+// @Summary     Forgot password
+// @Description Email a single-use link to reset the password. The response is the same whether or not the address has an account.
+// @Tags        auth
+// @Accept      json
+// @Produce     json
+// @Param       body body models.EmailRequest true "Email address of the account"
+// @Success     200 {object} map[string]interface{}
+// @Failure     400 {object} map[string]interface{}
+// @Router      /auth/forgot-password [post]
+func (ctl *AuthController) ForgotPassword(c *gin.Context) {
+	var input models.EmailRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Failures are logged rather than returned, which would reveal that the account exists
+	ctl.accounts.ForgotPassword(input.Email)
+
+	c.JSON(http.StatusOK, gin.H{"message": "If the address belongs to an account, a password reset email has been sent"})
+}
+
+// @Summary     Reset password
+// @Description Set a new password with the token from the password reset email. Tokens are single use, and every session of the user is ended.
+// @Tags        auth
+// @Accept      json
+// @Produce     json
+// @Param       body body models.PasswordResetRequest true "Reset token and new password"
+// @Success     200 {object} map[string]interface{}
+// @Failure     400 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Router      /auth/reset-password [post]
+func (ctl *AuthController) ResetPassword(c *gin.Context) {
+	var input models.PasswordResetRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if _, err := ctl.accounts.ResetPassword(input.Token, input.NewPassword); err != nil {
+		if errors.Is(err, services.ErrInvalidAccountToken) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Reset link is invalid or has expired"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password has been reset, log in with the new password"})
+}
+
+// userResponse strips the password hash and relations from a user
+func userResponse(user models.User) models.UserResponse {
+	return models.UserResponse{
+		ID:                    user.ID,
+		CreatedAt:             user.CreatedAt,
+		UpdatedAt:             user.UpdatedAt,
+		Name:                  user.Name,
+		Email:                 user.Email,
+		Role:                  user.Role,
+		SuspendedAt:           user.SuspendedAt,
+		SuspensionReason:      user.SuspensionReason,
+		PasswordResetRequired: user.PasswordResetRequired,
+		EmailVerifiedAt:       user.EmailVerifiedAt,
+		TwoFactorEnabled:      user.TOTPEnabledAt != nil,
+	}
+}
+
+// loginLocked responds with 429 and when to try again if err is a login lockout
+func loginLocked(c *gin.Context, err error) bool {
+	var locked *services.LoginLockedError
+	if !errors.As(err, &locked) {
+		return false
+	}
 
-	tokenString, _ := token.SignedString(jwtSecret)
-	c.JSON(http.StatusOK, gin.H{"token": tokenString})
+	seconds := int64(math.Ceil(time.Until(locked.Until).Seconds()))
+	c.Header("Retry-After", strconv.FormatInt(max(seconds, 1), 10))
+	c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many failed login attempts, try again later"})
+	return true
 }
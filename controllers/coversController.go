@@ -0,0 +1,104 @@
+package controllers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tushar27x/music-lib-api/artwork"
+	"github.com/tushar27x/music-lib-api/services"
+)
+
+// coverSize reads the size query parameter, 0 for the original image; on failure it responds with 400 and returns false
+func coverSize(c *gin.Context) (int, bool) {
+	value := c.Query("size")
+	if value == "" {
+		return 0, true
+	}
+	size, err := strconv.Atoi(value)
+	if err != nil || !slices.Contains(artwork.Sizes, size) {
+		sizes := make([]string, len(artwork.Sizes))
+		for i, size := range artwork.Sizes {
+			sizes[i] = strconv.Itoa(size)
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "size must be one of " + strings.Join(sizes, ", ")})
+		return 0, false
+	}
+	return size, true
+}
+
+// coverUpload reads the image in the file field of a multipart upload; on failure it responds and returns false
+func coverUpload(c *gin.Context) ([]byte, bool) {
+	// Cap the request body so oversized uploads fail early
+	maxSize := services.MaxCoverSize()
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize+(1<<20))
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Image file is too large"})
+			return nil, false
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Image file is required in the 'file' field"})
+		return nil, false
+	}
+	if fileHeader.Size > maxSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Image file is too large"})
+		return nil, false
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return data, true
+}
+
+// serveCover writes a cover image, answering conditional requests before the image is loaded
+func serveCover(c *gin.Context, cover services.Cover) {
+	etag := `"` + cover.ETag + `"`
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "private, max-age=0, must-revalidate")
+	if match := c.GetHeader("If-None-Match"); match != "" && (match == "*" || strings.Contains(match, etag)) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	data, err := cover.Read()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to read cover: %v", err)})
+		return
+	}
+
+	// ServeContent handles If-Modified-Since and Range for us
+	c.Header("Content-Type", cover.ContentType)
+	http.ServeContent(c.Writer, c.Request, "", cover.ModTime, bytes.NewReader(data))
+}
+
+// coverError answers requests failing on the cover image itself and reports whether it did
+func coverError(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, services.ErrNotImage):
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrImageTooLarge):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,337 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tushar27x/music-lib-api/models"
+	"github.com/tushar27x/music-lib-api/services"
+)
+
+// GenreController serves the genre endpoints
+type GenreController struct {
+	genres *services.GenreService
+}
+
+// NewGenreController returns a GenreController backed by the given service
+func NewGenreController(genres *services.GenreService) *GenreController {
+	return &GenreController{genres: genres}
+}
+
+// @Summary     Create a genre
+// @Description Add a genre to the user's taxonomy, optionally under a parent genre. Names are unique per user, ignoring case.
+// @Tags        genres
+// @Accept      json
+// @Produce     json
+// @Param       genre body models.GenreCreateRequest true "Genre data"
+// @Success     201 {object} models.Genre
+// @Failure     400 {object} map[string]interface{}
+// @Failure     409 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Security    BearerAuth
+// @Security    ApiKeyAuth
+// @Router      /genres/ [post]
+func (ctl *GenreController) CreateGenre(c *gin.Context) {
+	var input models.GenreCreateRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userId, ok := c.MustGet("userId").(uint)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	genre, err := ctl.genres.Create(userId, input)
+	if err != nil {
+		genreError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, genre)
+}
+
+// @Summary     Get all genres
+// @Description Retrieve all genres of the authenticated user in name order
+// @Tags        genres
+// @Produce     json
+// @Success     200 {array} models.Genre
+// @Failure     400 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Security    BearerAuth
+// @Security    ApiKeyAuth
+// @Router      /genres/ [get]
+func (ctl *GenreController) GetGenres(c *gin.Context) {
+	userId, ok := c.MustGet("userId").(uint)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	genres, err := ctl.genres.List(userId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, genres)
+}
+
+// @Summary     Get the genre tree
+// @Description Retrieve the top-level genres of the authenticated user with their subgenres nested under them, each level in name order
+// @Tags        genres
+// @Produce     json
+// @Success     200 {array} models.GenreNode
+// @Failure     400 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Security    BearerAuth
+// @Security    ApiKeyAuth
+// @Router      /genres/tree [get]
+func (ctl *GenreController) GetGenreTree(c *gin.Context) {
+	userId, ok := c.MustGet("userId").(uint)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	tree, err := ctl.genres.Tree(userId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, tree)
+}
+
+// @Summary     Get genre by ID
+// @Description Retrieve a specific genre of the authenticated user
+// @Tags        genres
+// @Produce     json
+// @Param       id path int true "Genre ID"
+// @Success     200 {object} models.Genre
+// @Failure     400 {object} map[string]interface{}
+// @Failure     404 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Security    BearerAuth
+// @Security    ApiKeyAuth
+// @Router      /genres/{id} [get]
+func (ctl *GenreController) GetGenreByID(c *gin.Context) {
+	userId, ok := c.MustGet("userId").(uint)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	genreId, ok := idParam(c, "id", "genre")
+	if !ok {
+		return
+	}
+
+	genre, err := ctl.genres.Get(userId, genreId)
+	if err != nil {
+		genreError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, genre)
+}
+
+// @Summary     Update a genre
+// @Description Rename a genre of the authenticated user and move it under another parent, or to the top level when parent_id is omitted. Albums and songs in the genre show the new name.
+// @Tags        genres
+// @Accept      json
+// @Produce     json
+// @Param       id path int true "Genre ID"
+// @Param       genre body models.GenreCreateRequest true "Updated genre data"
+// @Success     200 {object} models.Genre
+// @Failure     400 {object} map[string]interface{}
+// @Failure     404 {object} map[string]interface{}
+// @Failure     409 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Security    BearerAuth
+// @Security    ApiKeyAuth
+// @Router      /genres/{id} [put]
+func (ctl *GenreController) UpdateGenre(c *gin.Context) {
+	userId, ok := c.MustGet("userId").(uint)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	genreId, ok := idParam(c, "id", "genre")
+	if !ok {
+		return
+	}
+
+	var input models.GenreCreateRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	genre, err := ctl.genres.Update(userId, genreId, input)
+	if err != nil {
+		genreError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, genre)
+}
+
+// @Summary     Delete a genre
+// @Description Delete a genre of the authenticated user. Genres with subgenres or that albums or songs are still in cannot be deleted.
+// @Tags        genres
+// @Produce     json
+// @Param       id path int true "Genre ID"
+// @Success     200 {object} map[string]interface{}
+// @Failure     400 {object} map[string]interface{}
+// @Failure     404 {object} map[string]interface{}
+// @Failure     409 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Security    BearerAuth
+// @Security    ApiKeyAuth
+// @Router      /genres/{id} [delete]
+func (ctl *GenreController) DeleteGenre(c *gin.Context) {
+	userId, ok := c.MustGet("userId").(uint)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	genreId, ok := idParam(c, "id", "genre")
+	if !ok {
+		return
+	}
+
+	if err := ctl.genres.Delete(userId, genreId); err != nil {
+		genreError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Genre deleted successfully"})
+}
+
+// @Summary     Put songs and albums in a genre
+// @Description Put songs and albums of the authenticated user in a genre, taking them out of the genre they were in. Changing albums needs a role that manages albums.
+// @Tags        genres
+// @Accept      json
+// @Produce     json
+// @Param       id path int true "Genre ID"
+// @Param       items body models.GenreAssignRequest true "Songs and albums"
+// @Success     200 {object} map[string]interface{}
+// @Failure     400 {object} map[string]interface{}
+// @Failure     403 {object} map[string]interface{}
+// @Failure     404 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Security    BearerAuth
+// @Security    ApiKeyAuth
+// @Router      /genres/{id}/assign [post]
+func (ctl *GenreController) AssignGenre(c *gin.Context) {
+	ctl.changeGenre(c, ctl.genres.Assign, "Genre assigned successfully")
+}
+
+// @Summary     Take songs and albums out of a genre
+// @Description Take songs and albums of the authenticated user out of a genre; those in another genre are left alone. Changing albums needs a role that manages albums.
+// @Tags        genres
+// @Accept      json
+// @Produce     json
+// @Param       id path int true "Genre ID"
+// @Param       items body models.GenreAssignRequest true "Songs and albums"
+// @Success     200 {object} map[string]interface{}
+// @Failure     400 {object} map[string]interface{}
+// @Failure     403 {object} map[string]interface{}
+// @Failure     404 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Security    BearerAuth
+// @Security    ApiKeyAuth
+// @Router      /genres/{id}/unassign [post]
+func (ctl *GenreController) UnassignGenre(c *gin.Context) {
+	ctl.changeGenre(c, ctl.genres.Unassign, "Genre unassigned successfully")
+}
+
+// changeGenre runs a bulk genre change on the songs and albums in the request body
+func (ctl *GenreController) changeGenre(c *gin.Context, change func(userId uint, role string, genreId uint, input models.GenreAssignRequest) error, message string) {
+	userId, ok := c.MustGet("userId").(uint)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	role, ok := c.MustGet("role").(string)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user role"})
+		return
+	}
+
+	genreId, ok := idParam(c, "id", "genre")
+	if !ok {
+		return
+	}
+
+	var input models.GenreAssignRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := change(userId, role, genreId, input); err != nil {
+		if libraryItemsError(c, err) {
+			return
+		}
+		genreError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": message})
+}
+
+// genreError maps genre errors to responses
+func genreError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrGenreNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Genre not found"})
+	case errors.Is(err, services.ErrGenreNameTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": "A genre with this name already exists"})
+	case errors.Is(err, services.ErrGenreInUse):
+		c.JSON(http.StatusConflict, gin.H{"error": "Genre still has subgenres, albums or songs"})
+	case errors.Is(err, services.ErrParentGenreNotFound):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid parent_id"})
+	case errors.Is(err, services.ErrGenreNameRequired), errors.Is(err, services.ErrGenreCycle):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// libraryItemsError answers bulk changes naming no, unknown or forbidden songs and albums and reports whether it did
+func libraryItemsError(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, services.ErrNoLibraryItems):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrSongNotFound):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "song_ids name a song you don't have"})
+	case errors.Is(err, services.ErrAlbumNotFound):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "album_ids name an album you don't have"})
+	case errors.Is(err, services.ErrPermissionDenied):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Your role cannot update albums"})
+	default:
+		return false
+	}
+	return true
+}
+
+// categoryError answers requests naming an unknown genre_id, or a genre or tag created at the same
+// time by another request, and reports whether it did
+func categoryError(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, services.ErrGenreNotFound):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid genre_id"})
+	case errors.Is(err, services.ErrGenreNameTaken), errors.Is(err, services.ErrTagNameTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": "A genre or tag named in the request was created at the same time, please try again"})
+	default:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,139 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tushar27x/music-lib-api/models"
+	"github.com/tushar27x/music-lib-api/services"
+)
+
+// OIDCController serves logins through external OpenID Connect providers
+type OIDCController struct {
+	oidc *services.OIDCService
+}
+
+// NewOIDCController returns an OIDCController backed by the given service
+func NewOIDCController(oidc *services.OIDCService) *OIDCController {
+	return &OIDCController{oidc: oidc}
+}
+
+// @Summary     List identity providers
+// @Description List the OpenID Connect providers users can log in with
+// @Tags        auth
+// @Produce     json
+// @Success     200 {array} models.OIDCProviderResponse
+// @Router      /auth/oidc/providers [get]
+func (ctl *OIDCController) Providers(c *gin.Context) {
+	c.JSON(http.StatusOK, ctl.oidc.Providers())
+}
+
+// @Summary     Start an identity provider login
+// @Description Start logging in at an OpenID Connect provider. Send the browser to authorization_url; the provider redirects back to the configured redirect URL with a code and the state, which are posted to the callback endpoint.
+// @Tags        auth
+// @Produce     json
+// @Param       provider path string true "Provider name"
+// @Success     200 {object} models.OIDCAuthorizeResponse
+// @Failure     404 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Failure     502 {object} map[string]interface{}
+// @Router      /auth/oidc/{provider}/authorize [post]
+func (ctl *OIDCController) Authorize(c *gin.Context) {
+	response, err := ctl.oidc.Authorize(c.Request.Context(), c.Param("provider"))
+	if err != nil {
+		oidcError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// @Summary     Finish an identity provider login
+// @Description Exchange the code and state the provider sent back for a token pair. The identity is linked to the account with the same verified email address, or a new account is created. Accounts with two-factor authentication get 202 with a challenge token instead, like POST /auth/login.
+// @Tags        auth
+// @Accept      json
+// @Produce     json
+// @Param       provider path string true "Provider name"
+// @Param       body body models.OIDCCallbackRequest true "Code and state from the provider"
+// @Success     200 {object} models.TokenResponse
+// @Success     202 {object} models.MFAChallengeResponse
+// @Failure     400 {object} map[string]interface{}
+// @Failure     401 {object} map[string]interface{}
+// @Failure     403 {object} map[string]interface{}
+// @Failure     404 {object} map[string]interface{}
+// @Failure     409 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Failure     502 {object} map[string]interface{}
+// @Router      /auth/oidc/{provider}/callback [post]
+func (ctl *OIDCController) Callback(c *gin.Context) {
+	var input models.OIDCCallbackRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tokens, challenge, err := ctl.oidc.Callback(c.Request.Context(), c.Param("provider"), input.Code, input.State)
+	if err != nil {
+		oidcError(c, err)
+		return
+	}
+
+	if challenge != nil {
+		c.JSON(http.StatusAccepted, challenge)
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// @Summary     List linked identities
+// @Description List the identity provider accounts linked to the authenticated user
+// @Tags        auth
+// @Produce     json
+// @Success     200 {array} models.UserIdentity
+// @Failure     400 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Security    BearerAuth
+// @Router      /auth/identities [get]
+func (ctl *OIDCController) Identities(c *gin.Context) {
+	userId, ok := c.MustGet("userId").(uint)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	identities, err := ctl.oidc.Identities(userId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, identities)
+}
+
+// oidcError maps identity provider login errors to responses. Provider failures are logged
+// since the response does not include what the provider said.
+func oidcError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrOIDCProviderNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Identity provider not found"})
+	case errors.Is(err, services.ErrOIDCProviderUnavailable):
+		log.Printf("OIDC login failed: %v", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Identity provider is unavailable"})
+	case errors.Is(err, services.ErrInvalidOIDCState):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired login state, please start again"})
+	case errors.Is(err, services.ErrOIDCLoginFailed):
+		log.Printf("OIDC login failed: %v", err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Login at the identity provider failed"})
+	case errors.Is(err, services.ErrOIDCEmailNotVerified):
+		c.JSON(http.StatusForbidden, gin.H{"error": "The identity provider did not confirm your email address"})
+	case errors.Is(err, services.ErrOIDCAccountNotVerified):
+		c.JSON(http.StatusConflict, gin.H{"error": "An account with this email already exists; verify its address before logging in with this provider"})
+	case errors.Is(err, services.ErrAccountSuspended):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Account is suspended"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,83 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tushar27x/music-lib-api/services"
+)
+
+// idParam parses a numeric path parameter; on failure it responds with 400 and returns false
+func idParam(c *gin.Context, name string, label string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(name), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + label + " ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// pageParams reads the limit (default 20, at most 100) and offset query parameters, ignoring invalid values
+func pageParams(c *gin.Context) (int, int) {
+	limit, offset := 20, 0
+	if parsed, err := strconv.Atoi(c.Query("limit")); err == nil && parsed > 0 {
+		limit = min(parsed, 100)
+	}
+	if parsed, err := strconv.Atoi(c.Query("offset")); err == nil && parsed >= 0 {
+		offset = parsed
+	}
+	return limit, offset
+}
+
+// pagination describes a page of a listing the way every paginated endpoint does
+func pagination(total int64, limit int, offset int) gin.H {
+	return gin.H{
+		"total":    total,
+		"limit":    limit,
+		"offset":   offset,
+		"has_more": offset+limit < int(total),
+	}
+}
+
+// optionalIdQuery parses an optional numeric query parameter; on failure it responds with 400 and returns false
+func optionalIdQuery(c *gin.Context, name string) (*uint, bool) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, true
+	}
+	id, err := strconv.ParseUint(value, 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + name})
+		return nil, false
+	}
+	parsed := uint(id)
+	return &parsed, true
+}
+
+// categoryParams reads the repeatable genre and tag query parameters and match, all (the default)
+// or any; on failure it responds with 400 and returns false
+func categoryParams(c *gin.Context) (services.CategoryFilters, bool) {
+	var filters services.CategoryFilters
+	for _, genre := range c.QueryArray("genre") {
+		if genre = strings.TrimSpace(genre); genre != "" {
+			filters.Genres = append(filters.Genres, genre)
+		}
+	}
+	for _, tag := range c.QueryArray("tag") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			filters.Tags = append(filters.Tags, tag)
+		}
+	}
+
+	switch c.DefaultQuery("match", "all") {
+	case "all":
+	case "any":
+		filters.MatchAny = true
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "match must be all or any"})
+		return filters, false
+	}
+	return filters, true
+}
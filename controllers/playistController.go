@@ -1,17 +1,36 @@
 package controllers
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"path/filepath"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
-	"github.com/tushar27x/music-lib-api/config"
 	"github.com/tushar27x/music-lib-api/models"
-	"gorm.io/gorm"
+	"github.com/tushar27x/music-lib-api/playlistfile"
+	"github.com/tushar27x/music-lib-api/search"
+	"github.com/tushar27x/music-lib-api/services"
 )
 
+// PlaylistController serves the playlist endpoints
+type PlaylistController struct {
+	playlists *services.PlaylistService
+	covers    *services.CoverService
+	search    *services.SearchService
+}
+
+// NewPlaylistController returns a PlaylistController backed by the given services
+func NewPlaylistController(playlists *services.PlaylistService, covers *services.CoverService, search *services.SearchService) *PlaylistController {
+	return &PlaylistController{playlists: playlists, covers: covers, search: search}
+}
+
 // @Summary     Add a new playlist
-// @Description Create a new playlist with songs
+// @Description Create a manual playlist with songs, or a smart playlist whose songs are chosen by rules
 // @Tags        playlists
 // @Accept      json
 // @Produce     json
@@ -20,9 +39,14 @@ import (
 // @Failure     400 {object} map[string]interface{}
 // @Failure     500 {object} map[string]interface{}
 // @Security    BearerAuth
+// @Security    ApiKeyAuth
 // @Router      /playlists/ [post]
-func AddPlaylist(c *gin.Context) {
-	var input models.Playlist
+func (ctl *PlaylistController) AddPlaylist(c *gin.Context) {
+	var input struct {
+		models.PlaylistCreateRequest
+		// Older clients send the songs as objects
+		Songs []models.Song `json:"songs,omitempty"`
+	}
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -30,44 +54,19 @@ func AddPlaylist(c *gin.Context) {
 
 	userId := c.MustGet("userId").(uint)
 
-	// Extract song IDs
-	songIDs := make([]uint, 0, len(input.Songs))
+	// Merge the song IDs of older clients
 	for _, song := range input.Songs {
-		songIDs = append(songIDs, song.ID)
+		input.SongIds = append(input.SongIds, song.ID)
 	}
 
-	// Fetch only songs that belong to the user
-	var songs []models.Song
-	if len(songIDs) > 0 {
-		if err := config.DB.
-			Where("id IN ?", songIDs).
-			Find(&songs).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	// Create the playlist and return it with its ordered tracks
+	playlist, err := ctl.playlists.Create(userId, input.PlaylistCreateRequest)
+	if err != nil {
+		if errors.Is(err, services.ErrSmartPlaylist) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Smart playlists cannot have songs added by hand"})
 			return
 		}
-	}
-
-	// Create playlist
-	playlist := models.Playlist{
-		Name:   input.Name,
-		UserId: userId,
-	}
-
-	if err := config.DB.Create(&playlist).Error; err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-
-	if len(songs) > 0 {
-		if err := config.DB.Model(&playlist).Association("Songs").Append(&songs); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
-		}
-	}
-
-	// Optionally return playlist with preloaded songs
-	if err := config.DB.Preload("Songs").First(&playlist, playlist.ID).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondPlaylistError(c, err)
 		return
 	}
 
@@ -82,19 +81,17 @@ func AddPlaylist(c *gin.Context) {
 // @Failure     400 {object} map[string]interface{}
 // @Failure     500 {object} map[string]interface{}
 // @Security    BearerAuth
+// @Security    ApiKeyAuth
 // @Router      /playlists/ [get]
-func GetPlayList(c *gin.Context) {
-	var playlists []models.Playlist
+func (ctl *PlaylistController) GetPlayList(c *gin.Context) {
 	userId, ok := c.MustGet("userId").(uint)
 	if !ok {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
 		return
 	}
 
-	if err := config.DB.
-		Preload("Songs").
-		Where("user_id = ?", userId).
-		Find(&playlists).Error; err != nil {
+	playlists, err := ctl.playlists.List(userId)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -103,7 +100,7 @@ func GetPlayList(c *gin.Context) {
 }
 
 // @Summary     Get playlist by ID
-// @Description Retrieve a specific playlist by its ID for the authenticated user
+// @Description Retrieve a specific playlist by its ID for the authenticated user; smart playlists are re-evaluated on every request
 // @Tags        playlists
 // @Produce     json
 // @Param       id path int true "Playlist ID"
@@ -112,37 +109,25 @@ func GetPlayList(c *gin.Context) {
 // @Failure     404 {object} map[string]interface{}
 // @Failure     500 {object} map[string]interface{}
 // @Security    BearerAuth
+// @Security    ApiKeyAuth
 // @Router      /playlists/{id} [get]
-func GetPlayListById(c *gin.Context) {
+func (ctl *PlaylistController) GetPlayListById(c *gin.Context) {
 	userId, ok := c.MustGet("userId").(uint)
 	if !ok {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
 		return
 	}
 
-	playlistId := c.Param("id")
-	if playlistId == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Playlist ID is required"})
-		return
-	}
-
-	var playlist models.Playlist
-	if err := config.DB.Preload("Songs").
-		Where("id = ? AND user_id = ?", playlistId, userId).
-		First(&playlist).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Playlist not found"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	playlistId, ok := idParam(c, "id", "playlist")
+	if !ok {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"playlist": &playlist})
+	respondWithPlaylist(c, ctl.playlists, userId, playlistId)
 }
 
 // @Summary     Update playlist by ID
-// @Description Update a specific playlist by its ID for the authenticated user
+// @Description Update a specific playlist by its ID for the authenticated user; the playlist type cannot be changed
 // @Tags        playlists
 // @Accept      json
 // @Produce     json
@@ -153,28 +138,17 @@ func GetPlayListById(c *gin.Context) {
 // @Failure     404 {object} map[string]interface{}
 // @Failure     500 {object} map[string]interface{}
 // @Security    BearerAuth
+// @Security    ApiKeyAuth
 // @Router      /playlists/{id} [put]
-func UpdatePlaylist(c *gin.Context) {
+func (ctl *PlaylistController) UpdatePlaylist(c *gin.Context) {
 	userId, ok := c.MustGet("userId").(uint)
 	if !ok {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
 		return
 	}
 
-	playlistId := c.Param("id")
-	if playlistId == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Playlist ID is required"})
-		return
-	}
-
-	// Check if playlist exists and belongs to the user
-	var existingPlaylist models.Playlist
-	if err := config.DB.Where("id = ? AND user_id = ?", playlistId, userId).First(&existingPlaylist).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Playlist not found"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	playlistId, ok := idParam(c, "id", "playlist")
+	if !ok {
 		return
 	}
 
@@ -185,49 +159,17 @@ func UpdatePlaylist(c *gin.Context) {
 		return
 	}
 
-	// Start a transaction
-	tx := config.DB.Begin()
-
-	// Update the playlist name
-	if err := tx.Model(&existingPlaylist).Update("name", updateData.Name).Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	// If song IDs are provided, update the playlist's songs
-	if len(updateData.SongIds) > 0 {
-		// Verify that all songs belong to the user
-		var songs []models.Song
-		if err := tx.Where("id IN ? AND user_id = ?", updateData.SongIds, userId).Find(&songs).Error; err != nil {
-			tx.Rollback()
+	// Song IDs, when given, replace the playlist's tracks in the given order
+	existingPlaylist, err := ctl.playlists.Update(userId, playlistId, updateData)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrSongsNotOwned):
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid song IDs provided"})
-			return
-		}
-
-		// Clear existing songs and add new ones
-		if err := tx.Model(&existingPlaylist).Association("Songs").Clear(); err != nil {
-			tx.Rollback()
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clear existing songs"})
-			return
+		case errors.Is(err, services.ErrSmartPlaylist):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Smart playlists cannot have songs added by hand"})
+		default:
+			respondPlaylistError(c, err)
 		}
-
-		if err := tx.Model(&existingPlaylist).Association("Songs").Append(&songs); err != nil {
-			tx.Rollback()
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add songs to playlist"})
-			return
-		}
-	}
-
-	// Commit the transaction
-	if err := tx.Commit().Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
-		return
-	}
-
-	// Return the updated playlist with songs
-	if err := config.DB.Preload("Songs").First(&existingPlaylist, existingPlaylist.ID).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load updated playlist"})
 		return
 	}
 
@@ -244,51 +186,23 @@ func UpdatePlaylist(c *gin.Context) {
 // @Failure     404 {object} map[string]interface{}
 // @Failure     500 {object} map[string]interface{}
 // @Security    BearerAuth
+// @Security    ApiKeyAuth
 // @Router      /playlists/{id} [delete]
-func DeletePlaylist(c *gin.Context) {
+func (ctl *PlaylistController) DeletePlaylist(c *gin.Context) {
 	userId, ok := c.MustGet("userId").(uint)
 	if !ok {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
 		return
 	}
 
-	playlistId := c.Param("id")
-	if playlistId == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Playlist ID is required"})
-		return
-	}
-
-	// Check if playlist exists and belongs to the user
-	var playlist models.Playlist
-	if err := config.DB.Where("id = ? AND user_id = ?", playlistId, userId).First(&playlist).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Playlist not found"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	// Start a transaction
-	tx := config.DB.Begin()
-
-	// Clear the association with songs (but don't delete the songs)
-	if err := tx.Model(&playlist).Association("Songs").Clear(); err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clear song associations"})
-		return
-	}
-
-	// Soft delete the playlist
-	if err := tx.Delete(&playlist).Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	playlistId, ok := idParam(c, "id", "playlist")
+	if !ok {
 		return
 	}
 
-	// Commit the transaction
-	if err := tx.Commit().Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+	// Soft delete the playlist and its entries (but don't delete the songs)
+	if err := ctl.playlists.Delete(userId, playlistId); err != nil {
+		respondPlaylistError(c, err)
 		return
 	}
 
@@ -296,7 +210,7 @@ func DeletePlaylist(c *gin.Context) {
 }
 
 // @Summary     Search playlists
-// @Description Search playlists by name with fuzzy matching
+// @Description Full-text search over playlist names, ranked by relevance and tolerant of typos. The query supports "phrases", prefix* matching, -exclusion and OR.
 // @Tags        playlists
 // @Produce     json
 // @Param       q query string false "Search query (searches playlist name)"
@@ -307,8 +221,9 @@ func DeletePlaylist(c *gin.Context) {
 // @Failure     400 {object} map[string]interface{}
 // @Failure     500 {object} map[string]interface{}
 // @Security    BearerAuth
+// @Security    ApiKeyAuth
 // @Router      /playlists/search [get]
-func SearchPlaylists(c *gin.Context) {
+func (ctl *PlaylistController) SearchPlaylists(c *gin.Context) {
 	userId, ok := c.MustGet("userId").(uint)
 	if !ok {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
@@ -343,30 +258,8 @@ func SearchPlaylists(c *gin.Context) {
 		}
 	}
 
-	// Build the query
-	dbQuery := config.DB.Where("user_id = ?", userId)
-
-	// Apply search filters
-	if query != "" {
-		// General search across playlist name
-		dbQuery = dbQuery.Where("LOWER(name) LIKE LOWER(?)", "%"+query+"%")
-	} else if name != "" {
-		// Specific name search
-		dbQuery = dbQuery.Where("LOWER(name) LIKE LOWER(?)", "%"+name+"%")
-	}
-
-	// Preload songs and apply pagination
-	var playlists []models.Playlist
-	var total int64
-
-	// Count total results
-	if err := dbQuery.Model(&models.Playlist{}).Count(&total).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	// Get paginated results with songs
-	if err := dbQuery.Preload("Songs").Limit(limit).Offset(offset).Find(&playlists).Error; err != nil {
+	playlists, total, err := ctl.search.SearchPlaylists(userId, search.Parse(query), name, limit, offset)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -381,3 +274,443 @@ func SearchPlaylists(c *gin.Context) {
 		},
 	})
 }
+
+// @Summary     Add tracks to a playlist
+// @Description Insert songs at a position in the playlist (or append them); the same song may be added more than once
+// @Tags        playlists
+// @Accept      json
+// @Produce     json
+// @Param       id path int true "Playlist ID"
+// @Param       tracks body models.PlaylistTracksAddRequest true "Songs to insert"
+// @Success     200 {object} models.PlaylistResponse
+// @Failure     400 {object} map[string]interface{}
+// @Failure     404 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Security    BearerAuth
+// @Security    ApiKeyAuth
+// @Router      /playlists/{id}/tracks [post]
+func (ctl *PlaylistController) AddPlaylistTracks(c *gin.Context) {
+	userId, ok := c.MustGet("userId").(uint)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	playlistId, ok := idParam(c, "id", "playlist")
+	if !ok {
+		return
+	}
+
+	var input models.PlaylistTracksAddRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := ctl.playlists.InsertTracks(userId, playlistId, input.SongIds, input.Position); err != nil {
+		respondPlaylistError(c, err)
+		return
+	}
+
+	respondWithPlaylist(c, ctl.playlists, userId, playlistId)
+}
+
+// @Summary     Remove a track from a playlist
+// @Description Remove a single playlist entry; the following entries move up one position
+// @Tags        playlists
+// @Produce     json
+// @Param       id path int true "Playlist ID"
+// @Param       entryId path int true "Playlist entry ID"
+// @Success     200 {object} models.PlaylistResponse
+// @Failure     400 {object} map[string]interface{}
+// @Failure     404 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Security    BearerAuth
+// @Security    ApiKeyAuth
+// @Router      /playlists/{id}/tracks/{entryId} [delete]
+func (ctl *PlaylistController) RemovePlaylistTrack(c *gin.Context) {
+	userId, ok := c.MustGet("userId").(uint)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	playlistId, ok := idParam(c, "id", "playlist")
+	if !ok {
+		return
+	}
+	entryId, ok := idParam(c, "entryId", "entry")
+	if !ok {
+		return
+	}
+
+	if err := ctl.playlists.RemoveTrack(userId, playlistId, entryId); err != nil {
+		respondPlaylistError(c, err)
+		return
+	}
+
+	respondWithPlaylist(c, ctl.playlists, userId, playlistId)
+}
+
+// @Summary     Move a track within a playlist
+// @Description Move a playlist entry to a new position; entries in between shift by one
+// @Tags        playlists
+// @Accept      json
+// @Produce     json
+// @Param       id path int true "Playlist ID"
+// @Param       move body models.PlaylistTrackMoveRequest true "Entry and target position"
+// @Success     200 {object} models.PlaylistResponse
+// @Failure     400 {object} map[string]interface{}
+// @Failure     404 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Security    BearerAuth
+// @Security    ApiKeyAuth
+// @Router      /playlists/{id}/tracks/move [patch]
+func (ctl *PlaylistController) MovePlaylistTrack(c *gin.Context) {
+	userId, ok := c.MustGet("userId").(uint)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	playlistId, ok := idParam(c, "id", "playlist")
+	if !ok {
+		return
+	}
+
+	var input models.PlaylistTrackMoveRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := ctl.playlists.MoveTrack(userId, playlistId, input.EntryId, *input.Position); err != nil {
+		respondPlaylistError(c, err)
+		return
+	}
+
+	respondWithPlaylist(c, ctl.playlists, userId, playlistId)
+}
+
+// @Summary     Get a playlist's cover
+// @Description Retrieve the cover image of a playlist, or a JPEG thumbnail of it. Playlists without an uploaded cover show a 2x2 mosaic of the covers of their first four albums, or the cover of their first album when fewer albums have one. Supports ETag so clients can cache covers.
+// @Tags        playlists
+// @Produce     image/jpeg,image/png,image/webp
+// @Param       id path int true "Playlist ID"
+// @Param       size query int false "Thumbnail size in pixels; the original image, or a 512 pixel mosaic, when omitted" Enums(64, 128, 256, 512)
+// @Success     200 {file} file
+// @Failure     304 {object} map[string]interface{}
+// @Failure     400 {object} map[string]interface{}
+// @Failure     404 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Security    BearerAuth
+// @Security    ApiKeyAuth
+// @Router      /playlists/{id}/cover [get]
+func (ctl *PlaylistController) GetPlaylistCover(c *gin.Context) {
+	userId, ok := c.MustGet("userId").(uint)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	playlistId, ok := idParam(c, "id", "playlist")
+	if !ok {
+		return
+	}
+
+	size, ok := coverSize(c)
+	if !ok {
+		return
+	}
+
+	playlist, err := ctl.playlists.Load(userId, playlistId)
+	if err != nil {
+		respondPlaylistError(c, err)
+		return
+	}
+
+	cover, err := ctl.covers.PlaylistCover(c.Request.Context(), playlist, size)
+	if err != nil {
+		if errors.Is(err, services.ErrNoCover) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Playlist has no cover and none of its albums has one"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	serveCover(c, cover)
+}
+
+// @Summary     Upload a playlist cover
+// @Description Upload a JPEG, PNG or WebP cover image for a playlist as multipart form data, replacing the current cover or mosaic. Thumbnails are rendered on upload.
+// @Tags        playlists
+// @Accept      multipart/form-data
+// @Produce     json
+// @Param       id path int true "Playlist ID"
+// @Param       file formData file true "Cover image (JPEG, PNG, WebP)"
+// @Success     200 {object} models.PlaylistResponse
+// @Failure     400 {object} map[string]interface{}
+// @Failure     404 {object} map[string]interface{}
+// @Failure     413 {object} map[string]interface{}
+// @Failure     415 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Security    BearerAuth
+// @Security    ApiKeyAuth
+// @Router      /playlists/{id}/cover [post]
+func (ctl *PlaylistController) UploadPlaylistCover(c *gin.Context) {
+	userId, ok := c.MustGet("userId").(uint)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	playlistId, ok := idParam(c, "id", "playlist")
+	if !ok {
+		return
+	}
+
+	data, ok := coverUpload(c)
+	if !ok {
+		return
+	}
+
+	if err := ctl.covers.SetPlaylistCover(c.Request.Context(), userId, playlistId, data); err != nil {
+		if !coverError(c, err) {
+			respondPlaylistError(c, err)
+		}
+		return
+	}
+
+	respondWithPlaylist(c, ctl.playlists, userId, playlistId)
+}
+
+// @Summary     Delete a playlist cover
+// @Description Remove the uploaded cover image of a playlist, which then shows a mosaic of its album covers again
+// @Tags        playlists
+// @Produce     json
+// @Param       id path int true "Playlist ID"
+// @Success     200 {object} models.PlaylistResponse
+// @Failure     400 {object} map[string]interface{}
+// @Failure     404 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Security    BearerAuth
+// @Security    ApiKeyAuth
+// @Router      /playlists/{id}/cover [delete]
+func (ctl *PlaylistController) DeletePlaylistCover(c *gin.Context) {
+	userId, ok := c.MustGet("userId").(uint)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	playlistId, ok := idParam(c, "id", "playlist")
+	if !ok {
+		return
+	}
+
+	if err := ctl.covers.DeletePlaylistCover(c.Request.Context(), userId, playlistId); err != nil {
+		if errors.Is(err, services.ErrNoCover) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Playlist has no uploaded cover"})
+			return
+		}
+		respondPlaylistError(c, err)
+		return
+	}
+
+	respondWithPlaylist(c, ctl.playlists, userId, playlistId)
+}
+
+func respondWithPlaylist(c *gin.Context, playlists *services.PlaylistService, userId uint, playlistId uint) {
+	playlist, err := playlists.Load(userId, playlistId)
+	if err != nil {
+		respondPlaylistError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"playlist": &playlist})
+}
+
+func respondPlaylistError(c *gin.Context, err error) {
+	var rulesErr *services.SmartRulesError
+	switch {
+	case errors.Is(err, services.ErrPlaylistNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Playlist not found"})
+	case errors.Is(err, services.ErrEntryNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Playlist entry not found"})
+	case errors.Is(err, services.ErrSongsNotOwned), errors.Is(err, services.ErrInvalidPosition), errors.Is(err, services.ErrSmartPlaylist):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrRulesNotAllowed):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Rules are only allowed on smart playlists"})
+	case errors.Is(err, services.ErrPlaylistTypeChanged):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Playlist type cannot be changed"})
+	case errors.As(err, &rulesErr):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid smart playlist rules", "fields": rulesErr.Fields})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// maxPlaylistFileSize caps uploaded playlist files; real playlists are a few kilobytes
+const maxPlaylistFileSize = 5 << 20
+
+// @Summary     Export a playlist
+// @Description Download a playlist as an M3U8, PLS or XSPF file. Tracks link to the stream endpoint below PUBLIC_BASE_URL; songs with audio use signed stream URLs valid for EXPORT_URL_TTL, with which anyone holding the file can play them until then.
+// @Tags        playlists
+// @Produce     octet-stream
+// @Param       id path int true "Playlist ID"
+// @Param       format query string false "File format: m3u8 (default), m3u, pls or xspf"
+// @Success     200 {file} file
+// @Failure     400 {object} map[string]interface{}
+// @Failure     404 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Security    BearerAuth
+// @Security    ApiKeyAuth
+// @Router      /playlists/{id}/export [get]
+func (ctl *PlaylistController) ExportPlaylist(c *gin.Context) {
+	userId, ok := c.MustGet("userId").(uint)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	playlistId, ok := idParam(c, "id", "playlist")
+	if !ok {
+		return
+	}
+
+	format, err := playlistfile.NormalizeFormat(c.DefaultQuery("format", playlistfile.FormatM3U8))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Format must be one of m3u8, m3u, pls or xspf"})
+		return
+	}
+
+	playlist, err := ctl.playlists.Load(userId, playlistId)
+	if err != nil {
+		respondPlaylistError(c, err)
+		return
+	}
+
+	var body bytes.Buffer
+	if err := playlistfile.Write(&body, format, services.ExportPlaylist(playlist, services.PublicBaseURL())); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, exportFileName(playlist.Name), format))
+	c.Data(http.StatusOK, playlistfile.ContentType(format), body.Bytes())
+}
+
+// @Summary     Import a playlist
+// @Description Upload an M3U/M3U8, PLS or XSPF file. Entries are matched to your songs by stream URL, stored file name, or title, artist and duration, and a playlist is created from the matches. The report lists matched, ambiguous and unmatched entries.
+// @Tags        playlists
+// @Accept      multipart/form-data
+// @Produce     json
+// @Param       file formData file true "Playlist file"
+// @Param       name formData string false "Playlist name (defaults to the title in the file, then the file name)"
+// @Param       format formData string false "File format when it can't be detected from the file name: m3u8, m3u, pls or xspf"
+// @Param       ambiguous formData string false "skip (default) leaves ambiguous entries out, best adds the best candidate"
+// @Success     201 {object} models.PlaylistImportReport
+// @Failure     400 {object} map[string]interface{}
+// @Failure     413 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Security    BearerAuth
+// @Security    ApiKeyAuth
+// @Router      /playlists/import [post]
+func (ctl *PlaylistController) ImportPlaylist(c *gin.Context) {
+	userId, ok := c.MustGet("userId").(uint)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxPlaylistFileSize+(1<<20))
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Playlist file is too large"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Playlist file is required in the 'file' field"})
+		return
+	}
+	if fileHeader.Size > maxPlaylistFileSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Playlist file is too large"})
+		return
+	}
+
+	ambiguous := c.DefaultPostForm("ambiguous", "skip")
+	if ambiguous != "skip" && ambiguous != "best" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ambiguous must be skip or best"})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var format string
+	if requested := c.PostForm("format"); requested != "" {
+		format, err = playlistfile.NormalizeFormat(requested)
+	} else {
+		format, err = playlistfile.Detect(fileHeader.Filename, data)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Format must be one of m3u8, m3u, pls or xspf"})
+		return
+	}
+
+	parsed, err := playlistfile.Parse(format, data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Could not read playlist file: " + err.Error()})
+		return
+	}
+	if len(parsed.Entries) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Playlist file has no entries"})
+		return
+	}
+
+	name := strings.TrimSpace(c.PostForm("name"))
+	if name == "" {
+		name = parsed.Title
+	}
+	if name == "" {
+		name = strings.TrimSuffix(filepath.Base(fileHeader.Filename), filepath.Ext(fileHeader.Filename))
+	}
+	if name == "" {
+		name = "Imported playlist"
+	}
+
+	playlist, report, err := ctl.playlists.Import(userId, name, parsed, ambiguous == "best")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"playlist": &playlist, "report": report})
+}
+
+// exportFileName keeps a playlist name safe to use in a Content-Disposition header
+func exportFileName(name string) string {
+	safe := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == ' ':
+			return r
+		}
+		return -1
+	}, name)
+	safe = strings.TrimSpace(safe)
+	if safe == "" {
+		return "playlist"
+	}
+	return safe
+}
@@ -0,0 +1,110 @@
+package controllers
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tushar27x/music-lib-api/search"
+	"github.com/tushar27x/music-lib-api/services"
+)
+
+// SearchController serves the unified search endpoint
+type SearchController struct {
+	search *services.SearchService
+}
+
+// NewSearchController returns a SearchController backed by search
+func NewSearchController(search *services.SearchService) *SearchController {
+	return &SearchController{search: search}
+}
+
+// @Summary     Search everything
+// @Description Search songs, albums and playlists in one call. Hits are grouped by type and ranked by relevance, with the total per type. Facets by year, album and duration are computed over the matching songs; pass a facet value back as a filter to narrow the results.
+// @Tags        search
+// @Produce     json
+// @Param       q query string false "Search query; supports \"phrases\", prefix*, -exclusion and OR"
+// @Param       types query string false "Comma separated types to search (default: songs,albums,playlists)"
+// @Param       limit query int false "Hits per type (default: 5, max: 50)"
+// @Param       year query int false "Only songs and albums from this year"
+// @Param       album_id query int false "Only songs on this album (and the album itself)"
+// @Param       duration query string false "Only songs in this duration bucket: 0-2, 2-4, 4-6, 6-10 or 10-plus (minutes)"
+// @Param       facets query bool false "Include facets (default: true)"
+// @Success     200 {object} models.SearchResponse
+// @Failure     400 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Security    BearerAuth
+// @Security    ApiKeyAuth
+// @Router      /search [get]
+func (ctl *SearchController) Search(c *gin.Context) {
+	userId, ok := c.MustGet("userId").(uint)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	query := c.Query("q")
+	options := services.UnifiedSearchOptions{
+		Types:  services.SearchTypes,
+		Limit:  5,
+		Facets: c.DefaultQuery("facets", "true") != "false",
+	}
+
+	// Parse types
+	if typesStr := c.Query("types"); typesStr != "" {
+		options.Types = nil
+		for _, searchType := range strings.Split(typesStr, ",") {
+			searchType = strings.TrimSpace(searchType)
+			if !slices.Contains(services.SearchTypes, searchType) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown search type: " + searchType})
+				return
+			}
+			if !slices.Contains(options.Types, searchType) {
+				options.Types = append(options.Types, searchType)
+			}
+		}
+	}
+
+	// Parse limit
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 {
+			options.Limit = min(parsedLimit, 50)
+		}
+	}
+
+	// Parse filters
+	if yearStr := c.Query("year"); yearStr != "" {
+		year, err := strconv.Atoi(yearStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid year"})
+			return
+		}
+		options.Year = &year
+	}
+	if albumIdStr := c.Query("album_id"); albumIdStr != "" {
+		albumId, err := strconv.Atoi(albumIdStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid album ID"})
+			return
+		}
+		options.AlbumId = &albumId
+	}
+	if durationStr := c.Query("duration"); durationStr != "" {
+		bucket, found := services.FindDurationBucket(durationStr)
+		if !found {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid duration bucket"})
+			return
+		}
+		options.Duration = &bucket
+	}
+
+	response, err := ctl.search.UnifiedSearch(userId, search.Parse(query), query, options)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
@@ -1,15 +1,33 @@
 package controllers
 
 import (
+	"errors"
+	"log"
 	"net/http"
+	"slices"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/tushar27x/music-lib-api/config"
+	"github.com/tushar27x/music-lib-api/metadata"
 	"github.com/tushar27x/music-lib-api/models"
-	"gorm.io/gorm"
+	"github.com/tushar27x/music-lib-api/search"
+	"github.com/tushar27x/music-lib-api/services"
 )
 
+// SongController serves the song, audio and streaming endpoints
+type SongController struct {
+	songs  *services.SongService
+	covers *services.CoverService
+	search *services.SearchService
+}
+
+// NewSongController returns a SongController backed by the given services
+func NewSongController(songs *services.SongService, covers *services.CoverService, search *services.SearchService) *SongController {
+	return &SongController{songs: songs, covers: covers, search: search}
+}
+
 // @Summary     Add a new song
 // @Description Add a new song to the user's library
 // @Tags        songs
@@ -19,10 +37,12 @@ import (
 // @Success     200 {object} map[string]interface{}
 // @Failure     400 {object} map[string]interface{}
 // @Failure     403 {object} map[string]interface{}
+// @Failure     409 {object} map[string]interface{}
 // @Failure     500 {object} map[string]interface{}
 // @Security    BearerAuth
+// @Security    ApiKeyAuth
 // @Router      /songs/ [post]
-func AddSong(c *gin.Context) {
+func (ctl *SongController) AddSong(c *gin.Context) {
 	var song models.Song
 
 	if err := c.ShouldBindJSON(&song); err != nil {
@@ -36,23 +56,21 @@ func AddSong(c *gin.Context) {
 		return
 	}
 
-	// Optional album ownership check if AlbumId provided
-	if song.AlbumId != nil {
-		var album models.Album
-		if err := config.DB.First(&album, *song.AlbumId).Error; err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid album_id"})
+	// The album, when given, must belong to the user
+	if err := ctl.songs.Create(userId, &song); err != nil {
+		if creditError(c, err) || categoryError(c, err) {
 			return
 		}
-		if album.UserId != userId {
+		switch {
+		case errors.Is(err, services.ErrAlbumNotFound):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid album_id"})
+		case errors.Is(err, services.ErrAlbumNotOwned):
 			c.JSON(http.StatusForbidden, gin.H{"error": "You don't own this album"})
-			return
+		case errors.Is(err, services.ErrTrackPositionTaken):
+			c.JSON(http.StatusConflict, gin.H{"error": "Another song on the album has this disc and track number"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		}
-	}
-
-	song.UserId = userId
-
-	if err := config.DB.Create(&song).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
@@ -67,16 +85,17 @@ func AddSong(c *gin.Context) {
 // @Failure     400 {object} map[string]interface{}
 // @Failure     500 {object} map[string]interface{}
 // @Security    BearerAuth
+// @Security    ApiKeyAuth
 // @Router      /songs/ [get]
-func GetSongs(c *gin.Context) {
+func (ctl *SongController) GetSongs(c *gin.Context) {
 	userId, ok := c.MustGet("userId").(uint)
 	if !ok {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
 		return
 	}
 
-	var songs []models.Song
-	if err := config.DB.Where("user_id = ?", userId).Find(&songs).Error; err != nil {
+	songs, err := ctl.songs.List(userId)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -94,30 +113,18 @@ func GetSongs(c *gin.Context) {
 // @Failure     404 {object} map[string]interface{}
 // @Failure     500 {object} map[string]interface{}
 // @Security    BearerAuth
+// @Security    ApiKeyAuth
 // @Router      /songs/{id} [get]
-func GetSongByID(c *gin.Context) {
+func (ctl *SongController) GetSongByID(c *gin.Context) {
 	userId, ok := c.MustGet("userId").(uint)
 	if !ok {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
 		return
 	}
 
-	// Get song ID from URL parameter
-	songId := c.Param("id")
-	if songId == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Song ID is required"})
-		return
-	}
-
-	var song models.Song
-
 	// Query the song, ensuring it belongs to the authenticated user
-	if err := config.DB.Where("id = ? AND user_id = ?", songId, userId).First(&song).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Song not found"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	song, ok := ctl.ownedSong(c, userId)
+	if !ok {
 		return
 	}
 
@@ -134,30 +141,20 @@ func GetSongByID(c *gin.Context) {
 // @Success     200 {object} models.SongResponse
 // @Failure     400 {object} map[string]interface{}
 // @Failure     404 {object} map[string]interface{}
+// @Failure     409 {object} map[string]interface{}
 // @Failure     500 {object} map[string]interface{}
 // @Security    BearerAuth
+// @Security    ApiKeyAuth
 // @Router      /songs/{id} [put]
-func UpdateSong(c *gin.Context) {
+func (ctl *SongController) UpdateSong(c *gin.Context) {
 	userId, ok := c.MustGet("userId").(uint)
 	if !ok {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
 		return
 	}
 
-	songId := c.Param("id")
-	if songId == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Song ID is required"})
-		return
-	}
-
-	// Check if song exists and belongs to the user
-	var existingSong models.Song
-	if err := config.DB.Where("id = ? AND user_id = ?", songId, userId).First(&existingSong).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Song not found"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	songId, ok := idParam(c, "id", "song")
+	if !ok {
 		return
 	}
 
@@ -168,24 +165,22 @@ func UpdateSong(c *gin.Context) {
 		return
 	}
 
-	// Validate album ownership if album_id is provided
-	if updateData.AlbumId != nil {
-		var album models.Album
-		if err := config.DB.Where("id = ? AND user_id = ?", *updateData.AlbumId, userId).First(&album).Error; err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid album_id or you don't own this album"})
+	// The song and, when given, its new album must belong to the user
+	existingSong, err := ctl.songs.Update(userId, songId, updateData)
+	if err != nil {
+		if creditError(c, err) || categoryError(c, err) {
 			return
 		}
-	}
-
-	// Update the song
-	updates := map[string]interface{}{
-		"title":    updateData.Title,
-		"duration": updateData.Duration,
-		"album_id": updateData.AlbumId,
-	}
-
-	if err := config.DB.Model(&existingSong).Updates(updates).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		switch {
+		case errors.Is(err, services.ErrSongNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Song not found"})
+		case errors.Is(err, services.ErrAlbumNotOwned):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid album_id or you don't own this album"})
+		case errors.Is(err, services.ErrTrackPositionTaken):
+			c.JSON(http.StatusConflict, gin.H{"error": "Another song on the album has this disc and track number"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
 		return
 	}
 
@@ -203,24 +198,23 @@ func UpdateSong(c *gin.Context) {
 // @Failure     404 {object} map[string]interface{}
 // @Failure     500 {object} map[string]interface{}
 // @Security    BearerAuth
+// @Security    ApiKeyAuth
 // @Router      /songs/{id} [delete]
-func DeleteSong(c *gin.Context) {
+func (ctl *SongController) DeleteSong(c *gin.Context) {
 	userId, ok := c.MustGet("userId").(uint)
 	if !ok {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
 		return
 	}
 
-	songId := c.Param("id")
-	if songId == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Song ID is required"})
+	songId, ok := idParam(c, "id", "song")
+	if !ok {
 		return
 	}
 
-	// Check if song exists and belongs to the user
-	var song models.Song
-	if err := config.DB.Where("id = ? AND user_id = ?", songId, userId).First(&song).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
+	// Soft delete the song and remove it from all playlists (but don't delete the playlists)
+	if err := ctl.songs.Delete(userId, songId); err != nil {
+		if errors.Is(err, services.ErrSongNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Song not found"})
 			return
 		}
@@ -228,58 +222,30 @@ func DeleteSong(c *gin.Context) {
 		return
 	}
 
-	// Start a transaction
-	tx := config.DB.Begin()
-
-	// Remove song from all playlists (but don't delete the playlists)
-	var playlists []models.Playlist
-	if err := tx.Model(&song).Association("Playlists").Find(&playlists); err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find associated playlists"})
-		return
-	}
-
-	for _, playlist := range playlists {
-		if err := tx.Model(&playlist).Association("Songs").Delete(&song); err != nil {
-			tx.Rollback()
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove song from playlists"})
-			return
-		}
-	}
-
-	// Soft delete the song
-	if err := tx.Delete(&song).Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	// Commit the transaction
-	if err := tx.Commit().Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
-		return
-	}
-
 	c.JSON(http.StatusOK, gin.H{"message": "Song deleted successfully"})
 }
 
 // @Summary     Search songs
-// @Description Search songs by title, duration, or album with fuzzy matching
+// @Description Full-text search over song titles, artists and genres, ranked by relevance and tolerant of typos. The query supports "phrases", prefix* matching, -exclusion and OR. Results can be narrowed to genres and tags.
 // @Tags        songs
 // @Produce     json
-// @Param       q query string false "Search query (searches title, duration)"
+// @Param       q query string false "Search query (searches title, artist, genre)"
 // @Param       title query string false "Search by title"
 // @Param       album_id query int false "Search by album ID"
 // @Param       min_duration query int false "Minimum duration in milliseconds"
 // @Param       max_duration query int false "Maximum duration in milliseconds"
+// @Param       genre query []string false "Genre name, also matching its subgenres; repeat for several" collectionFormat(multi)
+// @Param       tag query []string false "Tag name; repeat for several" collectionFormat(multi)
+// @Param       match query string false "Whether songs must match all genres and tags or any of them (default: all)" Enums(all, any)
 // @Param       limit query int false "Limit results (default: 20, max: 100)"
 // @Param       offset query int false "Offset for pagination (default: 0)"
 // @Success     200 {object} map[string]interface{}
 // @Failure     400 {object} map[string]interface{}
 // @Failure     500 {object} map[string]interface{}
 // @Security    BearerAuth
+// @Security    ApiKeyAuth
 // @Router      /songs/search [get]
-func SearchSongs(c *gin.Context) {
+func (ctl *SongController) SearchSongs(c *gin.Context) {
 	userId, ok := c.MustGet("userId").(uint)
 	if !ok {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
@@ -317,61 +283,397 @@ func SearchSongs(c *gin.Context) {
 		}
 	}
 
-	// Build the query
-	dbQuery := config.DB.Where("user_id = ?", userId)
+	// Field filters apply on top of the text query
+	categories, ok := categoryParams(c)
+	if !ok {
+		return
+	}
+	filters := services.SongSearchFilters{Title: title, Categories: categories}
+	if albumIdStr != "" {
+		if albumId, err := strconv.Atoi(albumIdStr); err == nil {
+			filters.AlbumId = &albumId
+		}
+	}
+	if minDurationStr != "" {
+		if minDuration, err := strconv.Atoi(minDurationStr); err == nil {
+			filters.MinDuration = &minDuration
+		}
+	}
+	if maxDurationStr != "" {
+		if maxDuration, err := strconv.Atoi(maxDurationStr); err == nil {
+			filters.MaxDuration = &maxDuration
+		}
+	}
+
+	songs, total, err := ctl.search.SearchSongs(userId, search.Parse(query), filters, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 
-	// Apply search filters
-	if query != "" {
-		// General search across title and duration
-		dbQuery = dbQuery.Where(
-			"LOWER(title) LIKE LOWER(?) OR CAST(duration AS TEXT) LIKE ?",
-			"%"+query+"%", "%"+query+"%",
-		)
-	} else {
-		// Specific field searches
-		if title != "" {
-			dbQuery = dbQuery.Where("LOWER(title) LIKE LOWER(?)", "%"+title+"%")
+	c.JSON(http.StatusOK, gin.H{
+		"songs": songs,
+		"pagination": gin.H{
+			"total":    total,
+			"limit":    limit,
+			"offset":   offset,
+			"has_more": offset+limit < int(total),
+		},
+	})
+}
+
+// @Summary     Upload song audio
+// @Description Upload the audio file for a song as multipart form data; the format is detected from the file contents. When the song's album has no cover yet, cover art embedded in the file becomes the album cover.
+// @Tags        songs
+// @Accept      multipart/form-data
+// @Produce     json
+// @Param       id path int true "Song ID"
+// @Param       file formData file true "Audio file (MP3, FLAC, Ogg, Opus, WAV, AIFF, M4A, AAC, WebM)"
+// @Success     200 {object} models.SongResponse
+// @Failure     400 {object} map[string]interface{}
+// @Failure     404 {object} map[string]interface{}
+// @Failure     409 {object} map[string]interface{}
+// @Failure     413 {object} map[string]interface{}
+// @Failure     415 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Security    BearerAuth
+// @Security    ApiKeyAuth
+// @Router      /songs/{id}/audio [post]
+func (ctl *SongController) UploadSongAudio(c *gin.Context) {
+	userId, ok := c.MustGet("userId").(uint)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	// Check if song exists and belongs to the user
+	song, ok := ctl.ownedSong(c, userId)
+	if !ok {
+		return
+	}
+
+	// Cap the request body so oversized uploads fail early
+	maxSize := config.GetEnvInt64("MAX_UPLOAD_SIZE", 100<<20)
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize+(1<<20))
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Audio file is too large"})
+			return
 		}
-		if albumIdStr != "" {
-			if albumId, err := strconv.Atoi(albumIdStr); err == nil {
-				dbQuery = dbQuery.Where("album_id = ?", albumId)
-			}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Audio file is required in the 'file' field"})
+		return
+	}
+	if fileHeader.Size > maxSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Audio file is too large"})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	if err := ctl.songs.StoreAudio(c.Request.Context(), &song, file, fileHeader.Size); err != nil {
+		if errors.Is(err, services.ErrNotAudio) {
+			c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+			return
 		}
-		if minDurationStr != "" {
-			if minDuration, err := strconv.Atoi(minDurationStr); err == nil {
-				dbQuery = dbQuery.Where("duration >= ?", minDuration)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store audio file: " + err.Error()})
+		return
+	}
+
+	// Tags are only previewed unless the client asked for them to be applied
+	tags, err := ctl.songs.ExtractTags(c.Request.Context(), song)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"song": song, "tags_error": err.Error()})
+		return
+	}
+
+	role, _ := c.MustGet("role").(string)
+	if c.Query("apply_tags") == "true" {
+		input := models.SongTagsApplyRequest{Album: c.DefaultQuery("album", "link")}
+		if err := ctl.songs.ApplyTags(userId, role, &song, tags, input); err != nil {
+			if errors.Is(err, services.ErrAlbumCreateForbidden) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Your role cannot create albums", "song": song})
+				return
 			}
-		}
-		if maxDurationStr != "" {
-			if maxDuration, err := strconv.Atoi(maxDurationStr); err == nil {
-				dbQuery = dbQuery.Where("duration <= ?", maxDuration)
+			if errors.Is(err, services.ErrTrackPositionTaken) {
+				c.JSON(http.StatusConflict, gin.H{"error": "Another song on the album has the tagged disc and track number", "song": song})
+				return
 			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to apply tags: " + err.Error()})
+			return
+		}
+	}
+
+	preview, err := ctl.songs.PreviewTags(userId, song, tags)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// An album without a cover takes the cover art embedded in the file
+	coverExtracted, err := ctl.covers.CoverFromAudio(c.Request.Context(), userId, role, song)
+	if err != nil {
+		log.Printf("Failed to take the cover of album %d from song %d: %v", *song.AlbumId, song.ID, err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"song": song, "tags": preview, "album_cover_extracted": coverExtracted})
+}
+
+// @Summary     Preview song tags
+// @Description Extract ID3, Vorbis comment or MP4 tags from the song's audio file and show what applying them would change
+// @Tags        songs
+// @Produce     json
+// @Param       id path int true "Song ID"
+// @Success     200 {object} models.SongTagsPreview
+// @Failure     400 {object} map[string]interface{}
+// @Failure     404 {object} map[string]interface{}
+// @Failure     422 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Security    BearerAuth
+// @Security    ApiKeyAuth
+// @Router      /songs/{id}/tags [get]
+func (ctl *SongController) GetSongTags(c *gin.Context) {
+	userId, ok := c.MustGet("userId").(uint)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	song, ok := ctl.ownedSong(c, userId)
+	if !ok {
+		return
+	}
+
+	tags, err := ctl.songs.ExtractTags(c.Request.Context(), song)
+	if err != nil {
+		respondTagError(c, err)
+		return
+	}
+
+	preview, err := ctl.songs.PreviewTags(userId, song, tags)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, preview)
+}
+
+// @Summary     Apply song tags
+// @Description Fill the song's fields from the tags of its audio file, optionally linking or creating the matching album
+// @Tags        songs
+// @Accept      json
+// @Produce     json
+// @Param       id path int true "Song ID"
+// @Param       body body models.SongTagsApplyRequest false "Fields to apply and album handling"
+// @Success     200 {object} models.SongResponse
+// @Failure     400 {object} map[string]interface{}
+// @Failure     403 {object} map[string]interface{}
+// @Failure     404 {object} map[string]interface{}
+// @Failure     409 {object} map[string]interface{}
+// @Failure     422 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Security    BearerAuth
+// @Security    ApiKeyAuth
+// @Router      /songs/{id}/tags [post]
+func (ctl *SongController) ApplySongTags(c *gin.Context) {
+	userId, ok := c.MustGet("userId").(uint)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	role, ok := c.MustGet("role").(string)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user role"})
+		return
+	}
+
+	input := models.SongTagsApplyRequest{Album: "link"}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
 		}
 	}
+	for _, field := range input.Fields {
+		if !slices.Contains(services.TagFields, field) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown tag field: " + field})
+			return
+		}
+	}
+
+	song, ok := ctl.ownedSong(c, userId)
+	if !ok {
+		return
+	}
 
-	// Preload album information and apply pagination
-	var songs []models.Song
-	var total int64
+	tags, err := ctl.songs.ExtractTags(c.Request.Context(), song)
+	if err != nil {
+		respondTagError(c, err)
+		return
+	}
 
-	// Count total results
-	if err := dbQuery.Model(&models.Song{}).Count(&total).Error; err != nil {
+	if err := ctl.songs.ApplyTags(userId, role, &song, tags, input); err != nil {
+		if errors.Is(err, services.ErrAlbumCreateForbidden) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Your role cannot create albums"})
+			return
+		}
+		if errors.Is(err, services.ErrTrackPositionTaken) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Another song on the album has the tagged disc and track number"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Get paginated results with album info
-	if err := dbQuery.Preload("Album").Limit(limit).Offset(offset).Find(&songs).Error; err != nil {
+	c.JSON(http.StatusOK, gin.H{"song": song})
+}
+
+func respondTagError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrNoAudio):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Song has no audio file"})
+	case errors.Is(err, services.ErrNoTags), errors.Is(err, metadata.ErrMalformed):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	default:
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// @Summary     Get a signed stream URL
+// @Description Create a short-lived signed URL for streaming a song without an Authorization header (e.g. in an <audio> tag)
+// @Tags        songs
+// @Produce     json
+// @Param       id path int true "Song ID"
+// @Success     200 {object} map[string]interface{}
+// @Failure     400 {object} map[string]interface{}
+// @Failure     404 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Security    BearerAuth
+// @Security    ApiKeyAuth
+// @Router      /songs/{id}/stream-url [get]
+func (ctl *SongController) GetSongStreamURL(c *gin.Context) {
+	userId, ok := c.MustGet("userId").(uint)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	// Only the owner may mint a stream URL for the song
+	song, ok := ctl.ownedSong(c, userId)
+	if !ok {
 		return
 	}
 
+	if song.AudioKey == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Song has no audio file"})
+		return
+	}
+
+	expiresAt := time.Now().Add(services.StreamURLTTL())
 	c.JSON(http.StatusOK, gin.H{
-		"songs": songs,
-		"pagination": gin.H{
-			"total":    total,
-			"limit":    limit,
-			"offset":   offset,
-			"has_more": offset+limit < int(total),
-		},
+		"url":        services.SignStreamURL(song.ID, expiresAt),
+		"expires_at": expiresAt,
 	})
 }
+
+// @Summary     Stream song audio
+// @Description Stream a song's audio file. Supports Range, If-Range, ETag and Last-Modified so clients can seek. Authenticate with a bearer token or a signed URL from /songs/{id}/stream-url.
+// @Tags        songs
+// @Produce     octet-stream
+// @Param       id path int true "Song ID"
+// @Param       Range header string false "Byte range, e.g. bytes=0-1023"
+// @Param       expires query int false "Signed URL expiry (unix seconds)"
+// @Param       signature query string false "Signed URL signature"
+// @Success     200 {file} file
+// @Success     206 {file} file
+// @Failure     304 {object} map[string]interface{}
+// @Failure     401 {object} map[string]interface{}
+// @Failure     404 {object} map[string]interface{}
+// @Failure     416 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Security    BearerAuth
+// @Security    ApiKeyAuth
+// @Router      /songs/{id}/stream [get]
+func (ctl *SongController) StreamSong(c *gin.Context) {
+	songId, ok := idParam(c, "id", "song")
+	if !ok {
+		return
+	}
+
+	// A valid signed URL already proves the owner granted access to this song
+	var song models.Song
+	var err error
+	if _, signed := c.Get("streamSongId"); signed {
+		song, err = ctl.songs.GetByID(songId)
+	} else {
+		userId, ok := c.MustGet("userId").(uint)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+			return
+		}
+		song, err = ctl.songs.Get(userId, songId)
+	}
+	if err != nil {
+		if errors.Is(err, services.ErrSongNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Song not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if song.AudioKey == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Song has no audio file"})
+		return
+	}
+
+	reader := ctl.songs.OpenAudio(c.Request.Context(), song)
+	defer reader.Close()
+
+	// ServeContent handles Range, If-Range, If-None-Match and If-Modified-Since for us
+	c.Header("Content-Type", song.AudioContentType)
+	c.Header("ETag", `"`+song.AudioChecksum+`"`)
+	c.Header("Cache-Control", "private, max-age=0, must-revalidate")
+	var modTime time.Time
+	if song.AudioUploadedAt != nil {
+		modTime = *song.AudioUploadedAt
+	}
+	http.ServeContent(c.Writer, c.Request, "", modTime, reader)
+
+	// Count a play once per playback: seeks and HEAD requests don't count
+	status := c.Writer.Status()
+	if c.Request.Method == http.MethodGet && (status == http.StatusOK || status == http.StatusPartialContent) && services.IsPlaybackStart(c.GetHeader("Range")) {
+		if err := ctl.songs.RecordPlay(song.ID); err != nil {
+			log.Printf("Failed to record play of song %d: %v", song.ID, err)
+		}
+	}
+}
+
+// ownedSong loads the song named by the id path parameter, making sure it belongs to the user;
+// it responds with an error and returns false when it can't
+func (ctl *SongController) ownedSong(c *gin.Context, userId uint) (models.Song, bool) {
+	songId, ok := idParam(c, "id", "song")
+	if !ok {
+		return models.Song{}, false
+	}
+
+	song, err := ctl.songs.Get(userId, songId)
+	if err != nil {
+		if errors.Is(err, services.ErrSongNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Song not found"})
+			return song, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return song, false
+	}
+	return song, true
+}
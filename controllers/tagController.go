@@ -0,0 +1,293 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tushar27x/music-lib-api/models"
+	"github.com/tushar27x/music-lib-api/services"
+)
+
+// TagController serves the endpoints of the tags users put on songs and albums
+type TagController struct {
+	tags *services.TagService
+}
+
+// NewTagController returns a TagController backed by the given service
+func NewTagController(tags *services.TagService) *TagController {
+	return &TagController{tags: tags}
+}
+
+// @Summary     Create a tag
+// @Description Add a tag to the user's library. Names are unique per user, ignoring case.
+// @Tags        tags
+// @Accept      json
+// @Produce     json
+// @Param       tag body models.TagCreateRequest true "Tag data"
+// @Success     201 {object} models.Tag
+// @Failure     400 {object} map[string]interface{}
+// @Failure     409 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Security    BearerAuth
+// @Security    ApiKeyAuth
+// @Router      /tags/ [post]
+func (ctl *TagController) CreateTag(c *gin.Context) {
+	var input models.TagCreateRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userId, ok := c.MustGet("userId").(uint)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	tag, err := ctl.tags.Create(userId, input)
+	if err != nil {
+		tagError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, tag)
+}
+
+// @Summary     Get all tags
+// @Description Retrieve all tags of the authenticated user in name order with how many songs and albums carry each
+// @Tags        tags
+// @Produce     json
+// @Success     200 {array} models.TagUsage
+// @Failure     400 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Security    BearerAuth
+// @Security    ApiKeyAuth
+// @Router      /tags/ [get]
+func (ctl *TagController) GetTags(c *gin.Context) {
+	userId, ok := c.MustGet("userId").(uint)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	tags, err := ctl.tags.List(userId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, tags)
+}
+
+// @Summary     Rename a tag
+// @Description Rename a tag of the authenticated user. To give a tag the name of another tag, merge them instead.
+// @Tags        tags
+// @Accept      json
+// @Produce     json
+// @Param       id path int true "Tag ID"
+// @Param       tag body models.TagCreateRequest true "Updated tag data"
+// @Success     200 {object} models.Tag
+// @Failure     400 {object} map[string]interface{}
+// @Failure     404 {object} map[string]interface{}
+// @Failure     409 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Security    BearerAuth
+// @Security    ApiKeyAuth
+// @Router      /tags/{id} [put]
+func (ctl *TagController) UpdateTag(c *gin.Context) {
+	userId, ok := c.MustGet("userId").(uint)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	tagId, ok := idParam(c, "id", "tag")
+	if !ok {
+		return
+	}
+
+	var input models.TagCreateRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tag, err := ctl.tags.Update(userId, tagId, input)
+	if err != nil {
+		tagError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tag)
+}
+
+// @Summary     Delete a tag
+// @Description Take a tag of the authenticated user off every song and album and delete it
+// @Tags        tags
+// @Produce     json
+// @Param       id path int true "Tag ID"
+// @Success     200 {object} map[string]interface{}
+// @Failure     400 {object} map[string]interface{}
+// @Failure     404 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Security    BearerAuth
+// @Security    ApiKeyAuth
+// @Router      /tags/{id} [delete]
+func (ctl *TagController) DeleteTag(c *gin.Context) {
+	userId, ok := c.MustGet("userId").(uint)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	tagId, ok := idParam(c, "id", "tag")
+	if !ok {
+		return
+	}
+
+	if err := ctl.tags.Delete(userId, tagId); err != nil {
+		tagError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Tag deleted successfully"})
+}
+
+// @Summary     Merge a tag into another
+// @Description Put the target tag on every song and album carrying the tag and delete the tag, all at once
+// @Tags        tags
+// @Accept      json
+// @Produce     json
+// @Param       id path int true "ID of the tag to merge"
+// @Param       merge body models.TagMergeRequest true "Target tag"
+// @Success     200 {object} models.Tag
+// @Failure     400 {object} map[string]interface{}
+// @Failure     404 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Security    BearerAuth
+// @Security    ApiKeyAuth
+// @Router      /tags/{id}/merge [post]
+func (ctl *TagController) MergeTag(c *gin.Context) {
+	userId, ok := c.MustGet("userId").(uint)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	tagId, ok := idParam(c, "id", "tag")
+	if !ok {
+		return
+	}
+
+	var input models.TagMergeRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tag, err := ctl.tags.Merge(userId, tagId, input.TargetTagId)
+	if err != nil {
+		tagError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tag)
+}
+
+// @Summary     Tag songs and albums
+// @Description Put tags on songs and albums of the authenticated user, creating the tags the user doesn't have yet. Tagging albums needs a role that manages albums.
+// @Tags        tags
+// @Accept      json
+// @Produce     json
+// @Param       tagging body models.TagApplyRequest true "Tags, songs and albums"
+// @Success     200 {object} map[string]interface{}
+// @Failure     400 {object} map[string]interface{}
+// @Failure     403 {object} map[string]interface{}
+// @Failure     409 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Security    BearerAuth
+// @Security    ApiKeyAuth
+// @Router      /tags/apply [post]
+func (ctl *TagController) ApplyTags(c *gin.Context) {
+	userId, role, input, ok := tagApplyParams(c)
+	if !ok {
+		return
+	}
+
+	tags, err := ctl.tags.Tag(userId, role, input)
+	if err != nil {
+		if libraryItemsError(c, err) {
+			return
+		}
+		tagError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tags": tags})
+}
+
+// @Summary     Untag songs and albums
+// @Description Take tags off songs and albums of the authenticated user; names of tags the user doesn't have are ignored. Untagging albums needs a role that manages albums.
+// @Tags        tags
+// @Accept      json
+// @Produce     json
+// @Param       tagging body models.TagApplyRequest true "Tags, songs and albums"
+// @Success     200 {object} map[string]interface{}
+// @Failure     400 {object} map[string]interface{}
+// @Failure     403 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Security    BearerAuth
+// @Security    ApiKeyAuth
+// @Router      /tags/remove [post]
+func (ctl *TagController) RemoveTags(c *gin.Context) {
+	userId, role, input, ok := tagApplyParams(c)
+	if !ok {
+		return
+	}
+
+	if err := ctl.tags.Untag(userId, role, input); err != nil {
+		if libraryItemsError(c, err) {
+			return
+		}
+		tagError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Tags removed successfully"})
+}
+
+// tagApplyParams reads the user, role and body of a bulk tag change; on failure it responds and returns false
+func tagApplyParams(c *gin.Context) (uint, string, models.TagApplyRequest, bool) {
+	var input models.TagApplyRequest
+	userId, ok := c.MustGet("userId").(uint)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return 0, "", input, false
+	}
+	role, ok := c.MustGet("role").(string)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user role"})
+		return 0, "", input, false
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return 0, "", input, false
+	}
+	return userId, role, input, true
+}
+
+// tagError maps tag errors to responses
+func tagError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrTagNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tag not found"})
+	case errors.Is(err, services.ErrTagNameTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": "A tag with this name already exists"})
+	case errors.Is(err, services.ErrTagNameRequired), errors.Is(err, services.ErrMergeSameTag):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
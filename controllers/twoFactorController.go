@@ -0,0 +1,234 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tushar27x/music-lib-api/models"
+	"github.com/tushar27x/music-lib-api/services"
+)
+
+// TwoFactorController serves TOTP enrolment and the second step of logging in
+type TwoFactorController struct {
+	twoFactor *services.TwoFactorService
+}
+
+// NewTwoFactorController returns a TwoFactorController backed by the given service
+func NewTwoFactorController(twoFactor *services.TwoFactorService) *TwoFactorController {
+	return &TwoFactorController{twoFactor: twoFactor}
+}
+
+// @Summary     Two-factor status
+// @Description Report whether two-factor authentication is on and how many recovery codes are left
+// @Tags        auth
+// @Produce     json
+// @Success     200 {object} models.TwoFactorStatusResponse
+// @Failure     400 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Security    BearerAuth
+// @Router      /auth/2fa [get]
+func (ctl *TwoFactorController) Status(c *gin.Context) {
+	userId, ok := c.MustGet("userId").(uint)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	status, err := ctl.twoFactor.Status(userId)
+	if err != nil {
+		twoFactorError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// @Summary     Set up two-factor authentication
+// @Description Generate a TOTP secret with its otpauth:// URI and a QR code to scan with an authenticator app. Nothing changes until a code is confirmed; calling this again replaces the secret.
+// @Tags        auth
+// @Produce     json
+// @Success     200 {object} models.TwoFactorSetupResponse
+// @Failure     400 {object} map[string]interface{}
+// @Failure     409 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Security    BearerAuth
+// @Router      /auth/2fa/setup [post]
+func (ctl *TwoFactorController) Setup(c *gin.Context) {
+	userId, ok := c.MustGet("userId").(uint)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	setup, err := ctl.twoFactor.Setup(userId)
+	if err != nil {
+		twoFactorError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, setup)
+}
+
+// @Summary     Confirm two-factor authentication
+// @Description Turn two-factor authentication on with a code from the authenticator app. The recovery codes in the response are only shown once.
+// @Tags        auth
+// @Accept      json
+// @Produce     json
+// @Param       body body models.TwoFactorCodeRequest true "TOTP code"
+// @Success     200 {object} models.RecoveryCodesResponse
+// @Failure     400 {object} map[string]interface{}
+// @Failure     401 {object} map[string]interface{}
+// @Failure     409 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Security    BearerAuth
+// @Router      /auth/2fa/confirm [post]
+func (ctl *TwoFactorController) Confirm(c *gin.Context) {
+	userId, ok := c.MustGet("userId").(uint)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var input models.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	codes, err := ctl.twoFactor.Confirm(userId, input.Code)
+	if err != nil {
+		twoFactorError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// @Summary     Disable two-factor authentication
+// @Description Turn two-factor authentication off with the password and a TOTP or recovery code
+// @Tags        auth
+// @Accept      json
+// @Produce     json
+// @Param       body body models.TwoFactorDisableRequest true "Password and code"
+// @Success     200 {object} map[string]interface{}
+// @Failure     400 {object} map[string]interface{}
+// @Failure     401 {object} map[string]interface{}
+// @Failure     409 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Security    BearerAuth
+// @Router      /auth/2fa/disable [post]
+func (ctl *TwoFactorController) Disable(c *gin.Context) {
+	userId, ok := c.MustGet("userId").(uint)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var input models.TwoFactorDisableRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := ctl.twoFactor.Disable(userId, input.Password, input.Code); err != nil {
+		twoFactorError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+}
+
+// @Summary     Regenerate recovery codes
+// @Description Replace every recovery code with new ones after checking a TOTP code. The codes are only shown once.
+// @Tags        auth
+// @Accept      json
+// @Produce     json
+// @Param       body body models.TwoFactorCodeRequest true "TOTP code"
+// @Success     200 {object} models.RecoveryCodesResponse
+// @Failure     400 {object} map[string]interface{}
+// @Failure     401 {object} map[string]interface{}
+// @Failure     409 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Security    BearerAuth
+// @Router      /auth/2fa/recovery-codes [post]
+func (ctl *TwoFactorController) RegenerateRecoveryCodes(c *gin.Context) {
+	userId, ok := c.MustGet("userId").(uint)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var input models.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	codes, err := ctl.twoFactor.RegenerateRecoveryCodes(userId, input.Code)
+	if err != nil {
+		twoFactorError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// @Summary     Verify two-factor login
+// @Description Finish logging in to an account with two-factor authentication by exchanging the challenge token from login and a TOTP or recovery code for a token pair. Each challenge token works once. Wrong codes count as failed logins, like wrong passwords.
+// @Tags        auth
+// @Accept      json
+// @Produce     json
+// @Param       body body models.MFAVerifyRequest true "Challenge token and code"
+// @Success     200 {object} models.TokenResponse
+// @Failure     400 {object} map[string]interface{}
+// @Failure     401 {object} map[string]interface{}
+// @Failure     403 {object} map[string]interface{}
+// @Failure     429 {object} map[string]interface{}
+// @Failure     500 {object} map[string]interface{}
+// @Router      /auth/2fa/verify [post]
+func (ctl *TwoFactorController) Verify(c *gin.Context) {
+	var input models.MFAVerifyRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tokens, err := ctl.twoFactor.Verify(input.MFAToken, input.Code, c.ClientIP())
+	if err != nil {
+		if loginLocked(c, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, services.ErrInvalidMFAToken):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Login challenge is invalid or has expired, log in again"})
+		case errors.Is(err, services.ErrAccountSuspended):
+			c.JSON(http.StatusForbidden, gin.H{"error": "Account is suspended"})
+		default:
+			twoFactorError(c, err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// twoFactorError maps the errors of the two-factor service to responses
+func twoFactorError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidTwoFactorCode):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid two-factor code"})
+	case errors.Is(err, services.ErrInvalidCredentials):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid Credentials"})
+	case errors.Is(err, services.ErrTwoFactorEnabled):
+		c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+	case errors.Is(err, services.ErrTwoFactorNotEnabled):
+		c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is not enabled"})
+	case errors.Is(err, services.ErrTwoFactorNotSetUp):
+		c.JSON(http.StatusConflict, gin.H{"error": "Set up two-factor authentication first"})
+	case errors.Is(err, services.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
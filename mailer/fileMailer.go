@@ -0,0 +1,45 @@
+package mailer
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FileMailer writes every message as an .eml file into a directory, where it can be opened
+// with a mail client or read by tests
+type FileMailer struct {
+	dir  string
+	from string
+}
+
+// NewFileMailer creates the directory if needed and returns a file driver
+func NewFileMailer(dir string, from string) (*FileMailer, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(absDir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileMailer{dir: absDir, from: from}, nil
+}
+
+func (m *FileMailer) Send(ctx context.Context, msg Message) error {
+	now := time.Now()
+	content, err := encode(m.from, msg, now)
+	if err != nil {
+		return err
+	}
+
+	// Names sort by the time the message was sent
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+	name := now.UTC().Format("20060102T150405.000000000") + "-" + hex.EncodeToString(suffix) + ".eml"
+	return os.WriteFile(filepath.Join(m.dir, name), content, 0o644)
+}
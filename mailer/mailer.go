@@ -0,0 +1,131 @@
+// Package mailer sends the emails of the API, such as address verification and password resets.
+//
+// Messages are rendered from the templates in templates/, each with a text and an HTML body, and
+// handed to the driver selected by MAIL_DRIVER: SMTP in production, or the log or .eml files
+// for development and tests.
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/tushar27x/music-lib-api/config"
+)
+
+// Message is one email
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Mailer is implemented by every mail driver
+type Mailer interface {
+	// Send delivers msg; From is filled in by the driver
+	Send(ctx context.Context, msg Message) error
+}
+
+// Mail is the mail driver selected by MAIL_DRIVER
+var Mail Mailer
+
+// InitMailer configures Mail from the environment
+func InitMailer() {
+	var err error
+	driver := strings.ToLower(config.GetEnv("MAIL_DRIVER"))
+	from := config.GetEnv("MAIL_FROM")
+	if from == "" {
+		from = "Music Library <no-reply@localhost>"
+	}
+
+	switch driver {
+	case "", "log":
+		driver = "log"
+		Mail = NewLogMailer(from)
+	case "file":
+		dir := config.GetEnv("MAIL_FILE_PATH")
+		if dir == "" {
+			dir = "mail"
+		}
+		Mail, err = NewFileMailer(dir, from)
+	case "smtp":
+		Mail, err = NewSMTPMailer(SMTPConfig{
+			Host:        config.GetEnv("SMTP_HOST"),
+			Port:        config.GetEnv("SMTP_PORT"),
+			Username:    config.GetEnv("SMTP_USERNAME"),
+			Password:    config.GetEnv("SMTP_PASSWORD"),
+			ImplicitTLS: config.GetEnv("SMTP_IMPLICIT_TLS") == "true",
+			From:        from,
+		})
+	default:
+		log.Fatalf("❌ Unknown MAIL_DRIVER %q (expected log, file or smtp)", driver)
+	}
+
+	if err != nil {
+		log.Fatalf("❌ Error configuring %s mailer: %s", driver, err)
+	}
+
+	log.Printf("Using %s mailer", driver)
+}
+
+// encode builds a multipart/alternative message with the text and HTML bodies, ready for SMTP
+func encode(from string, msg Message, date time.Time) ([]byte, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	domain := "localhost"
+	if at := strings.LastIndex(from, "@"); at >= 0 {
+		domain = strings.Trim(from[at+1:], "> ")
+	}
+
+	var buf bytes.Buffer
+	body := multipart.NewWriter(&buf)
+	header := textproto.MIMEHeader{}
+	header.Set("From", from)
+	header.Set("To", msg.To)
+	header.Set("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	header.Set("Date", date.Format(time.RFC1123Z))
+	header.Set("Message-ID", fmt.Sprintf("<%s@%s>", hex.EncodeToString(id), domain))
+	header.Set("MIME-Version", "1.0")
+	header.Set("Content-Type", "multipart/alternative; boundary="+body.Boundary())
+	for _, key := range []string{"From", "To", "Subject", "Date", "Message-ID", "MIME-Version", "Content-Type"} {
+		fmt.Fprintf(&buf, "%s: %s\r\n", key, header.Get(key))
+	}
+	buf.WriteString("\r\n")
+
+	// Mail clients show the last alternative they understand, so HTML goes last
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=utf-8", msg.Text},
+		{"text/html; charset=utf-8", msg.HTML},
+	} {
+		writer, err := body.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		encoder := quotedprintable.NewWriter(writer)
+		if _, err := encoder.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+		if err := encoder.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := body.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
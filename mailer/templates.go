@@ -0,0 +1,65 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+)
+
+// Names of the email templates
+const (
+	TemplateVerifyEmail   = "verifyEmail"
+	TemplateResetPassword = "resetPassword"
+)
+
+// Every email has NAME.txt.tmpl, which also defines the "subject" template, and NAME.html.tmpl,
+// which defines the "content" of layout.html.tmpl
+//
+//go:embed templates/*.tmpl
+var templateFiles embed.FS
+
+// LinkData is what the templates of emails carrying a link are rendered with
+type LinkData struct {
+	Name  string
+	Email string
+	Link  string
+	// ExpiresIn is how long the link stays valid, in words
+	ExpiresIn string
+}
+
+// Render builds the message for the template called name, addressed to to
+func Render(name string, to string, data interface{}) (Message, error) {
+	text, err := texttemplate.ParseFS(templateFiles, "templates/"+name+".txt.tmpl")
+	if err != nil {
+		return Message{}, err
+	}
+	var subject, textBody bytes.Buffer
+	if err := text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Message{}, err
+	}
+	if err := text.Execute(&textBody, data); err != nil {
+		return Message{}, err
+	}
+
+	// The layout's title shows the subject rendered above
+	title := strings.TrimSpace(subject.String())
+	html, err := htmltemplate.New("layout.html.tmpl").
+		Funcs(htmltemplate.FuncMap{"subject": func() string { return title }}).
+		ParseFS(templateFiles, "templates/layout.html.tmpl", "templates/"+name+".html.tmpl")
+	if err != nil {
+		return Message{}, err
+	}
+	var htmlBody bytes.Buffer
+	if err := html.ExecuteTemplate(&htmlBody, "layout.html.tmpl", data); err != nil {
+		return Message{}, err
+	}
+
+	return Message{
+		To:      to,
+		Subject: title,
+		Text:    textBody.String(),
+		HTML:    htmlBody.String(),
+	}, nil
+}
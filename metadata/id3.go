@@ -0,0 +1,383 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// id3v2TagSize returns the full size of an ID3v2 tag, including header and footer
+func id3v2TagSize(header []byte) (int64, error) {
+	if len(header) < 10 || !bytes.HasPrefix(header, []byte("ID3")) {
+		return 0, ErrMalformed
+	}
+	size, ok := syncsafe(header[6:10])
+	if !ok {
+		return 0, ErrMalformed
+	}
+	total := int64(10 + size)
+	if header[3] == 4 && header[5]&0x10 != 0 {
+		total += 10
+	}
+	return total, nil
+}
+
+// readID3v2 parses the ID3v2 tag at the start of the file
+func readID3v2(r io.ReadSeeker) (Tags, int64, error) {
+	header, err := readAt(r, 0, 10)
+	if err != nil {
+		return Tags{}, 0, err
+	}
+	tagSize, err := id3v2TagSize(header)
+	if err != nil {
+		return Tags{}, 0, err
+	}
+
+	version := header[3]
+	flags := header[5]
+	body, err := readAt(r, 10, int(tagSize-10))
+	if err != nil {
+		return Tags{}, tagSize, err
+	}
+	if version == 4 && flags&0x10 != 0 {
+		body = body[:len(body)-10]
+	}
+
+	// Before v2.4 unsynchronisation applies to the whole tag
+	if version < 4 && flags&0x80 != 0 {
+		body = removeUnsync(body)
+	}
+
+	// Skip the extended header
+	if flags&0x40 != 0 && version >= 3 && len(body) >= 4 {
+		var extSize int
+		if version == 4 {
+			size, _ := syncsafe(body[:4])
+			extSize = size
+		} else {
+			extSize = int(binary.BigEndian.Uint32(body[:4])) + 4
+		}
+		if extSize > len(body) {
+			return Tags{}, tagSize, ErrMalformed
+		}
+		body = body[extSize:]
+	}
+
+	var tags Tags
+	for len(body) > 0 {
+		id, data, rest, ok := nextID3Frame(body, version)
+		if !ok {
+			break
+		}
+		body = rest
+		applyID3Frame(&tags, id, data)
+	}
+
+	return tags, tagSize, nil
+}
+
+// nextID3Frame splits the next frame off the tag body
+func nextID3Frame(body []byte, version byte) (string, []byte, []byte, bool) {
+	headerSize := 10
+	if version == 2 {
+		headerSize = 6
+	}
+	if len(body) < headerSize || body[0] == 0 {
+		// Padding
+		return "", nil, nil, false
+	}
+
+	var id string
+	var size int
+	var formatFlags byte
+	switch version {
+	case 2:
+		id = string(body[0:3])
+		size = int(body[3])<<16 | int(body[4])<<8 | int(body[5])
+	case 3:
+		id = string(body[0:4])
+		size = int(binary.BigEndian.Uint32(body[4:8]))
+		if body[9]&0xC0 != 0 {
+			// Compressed or encrypted
+			formatFlags = 0xFF
+		}
+	default:
+		id = string(body[0:4])
+		var ok bool
+		if size, ok = syncsafe(body[4:8]); !ok {
+			// Some writers use plain sizes in v2.4
+			size = int(binary.BigEndian.Uint32(body[4:8]))
+		}
+		formatFlags = body[9]
+	}
+
+	if size < 0 || headerSize+size > len(body) {
+		return "", nil, nil, false
+	}
+	data := body[headerSize : headerSize+size]
+	rest := body[headerSize+size:]
+
+	if version == 4 {
+		if formatFlags&0x0C != 0 {
+			// Compressed or encrypted frames are skipped
+			data = nil
+		} else {
+			if formatFlags&0x01 != 0 && len(data) >= 4 {
+				data = data[4:]
+			}
+			if formatFlags&0x02 != 0 {
+				data = removeUnsync(data)
+			}
+		}
+	} else if formatFlags == 0xFF {
+		data = nil
+	}
+
+	return id, data, rest, true
+}
+
+func applyID3Frame(tags *Tags, id string, data []byte) {
+	if len(data) == 0 {
+		return
+	}
+
+	switch id {
+	case "TIT2", "TT2":
+		tags.Title = decodeID3Text(data)
+	case "TPE1", "TP1":
+		tags.Artist = decodeID3Text(data)
+	case "TALB", "TAL":
+		tags.Album = decodeID3Text(data)
+	case "TPE2", "TP2":
+		tags.AlbumArtist = decodeID3Text(data)
+	case "TCON", "TCO":
+		tags.Genre = resolveID3Genre(decodeID3Text(data))
+	case "TRCK", "TRK":
+		tags.TrackNumber, tags.TrackTotal = parsePosition(decodeID3Text(data))
+	case "TPOS", "TPA":
+		tags.DiscNumber, tags.DiscTotal = parsePosition(decodeID3Text(data))
+	case "TDRC", "TYER", "TYE", "TDOR", "TORY":
+		if tags.Year == 0 {
+			tags.Year = parseYear(decodeID3Text(data))
+		}
+	case "TLEN", "TLE":
+		if ms, err := strconv.Atoi(decodeID3Text(data)); err == nil && ms > 0 {
+			tags.Duration = time.Duration(ms) * time.Millisecond
+		}
+	case "APIC", "PIC":
+		if picture, ok := parseID3Picture(id, data); ok {
+			tags.addPicture(picture)
+		}
+	}
+}
+
+// parseID3Picture decodes an APIC frame, or a PIC frame of ID3v2.2 which names a three letter image format instead of a MIME type
+func parseID3Picture(id string, data []byte) (Picture, bool) {
+	encoding := data[0]
+	data = data[1:]
+
+	var mimeType string
+	if id == "PIC" {
+		if len(data) < 3 {
+			return Picture{}, false
+		}
+		mimeType = "image/" + strings.ToLower(string(data[:3]))
+		data = data[3:]
+	} else {
+		end := bytes.IndexByte(data, 0)
+		if end < 0 {
+			return Picture{}, false
+		}
+		mimeType = string(data[:end])
+		data = data[end+1:]
+	}
+	if len(data) < 1 {
+		return Picture{}, false
+	}
+	pictureType := int(data[0])
+	data = data[1:]
+
+	// The description ends with a NUL, or two in UTF-16
+	if encoding == 1 || encoding == 2 {
+		end := -1
+		for i := 0; i+1 < len(data); i += 2 {
+			if data[i] == 0 && data[i+1] == 0 {
+				end = i + 2
+				break
+			}
+		}
+		if end < 0 {
+			return Picture{}, false
+		}
+		data = data[end:]
+	} else {
+		end := bytes.IndexByte(data, 0)
+		if end < 0 {
+			return Picture{}, false
+		}
+		data = data[end+1:]
+	}
+
+	return Picture{MIMEType: mimeType, Type: pictureType, Data: data}, len(data) > 0
+}
+
+// decodeID3Text decodes a text frame and returns its first value
+func decodeID3Text(data []byte) string {
+	if len(data) == 0 {
+		return ""
+	}
+	text := decodeID3String(data[0], data[1:])
+	// v2.4 separates multiple values with NUL
+	if first, _, found := strings.Cut(text, "\x00"); found {
+		text = first
+	}
+	return trimText(text)
+}
+
+func decodeID3String(encoding byte, data []byte) string {
+	switch encoding {
+	case 1, 2:
+		return decodeUTF16(data, encoding == 2)
+	case 3:
+		return string(data)
+	default:
+		return decodeLatin1(data)
+	}
+}
+
+// decodeUTF16 decodes UTF-16 with an optional BOM; bigEndian is used when there is none
+func decodeUTF16(data []byte, bigEndian bool) string {
+	var units []uint16
+	for len(data) >= 2 {
+		if data[0] == 0xFF && data[1] == 0xFE {
+			bigEndian = false
+			data = data[2:]
+			continue
+		}
+		if data[0] == 0xFE && data[1] == 0xFF {
+			bigEndian = true
+			data = data[2:]
+			continue
+		}
+		if bigEndian {
+			units = append(units, uint16(data[0])<<8|uint16(data[1]))
+		} else {
+			units = append(units, uint16(data[1])<<8|uint16(data[0]))
+		}
+		data = data[2:]
+	}
+	return string(utf16.Decode(units))
+}
+
+func decodeLatin1(data []byte) string {
+	runes := make([]rune, len(data))
+	for i, b := range data {
+		runes[i] = rune(b)
+	}
+	return string(runes)
+}
+
+// resolveID3Genre turns "(17)", "17" or "(17)Rock" into a genre name
+func resolveID3Genre(value string) string {
+	if strings.HasPrefix(value, "(") {
+		end := strings.Index(value, ")")
+		if end > 0 {
+			if refined := strings.TrimSpace(value[end+1:]); refined != "" {
+				return refined
+			}
+			value = value[1:end]
+		}
+	}
+	if index, err := strconv.Atoi(value); err == nil {
+		return id3v1Genre(index)
+	}
+	return value
+}
+
+// readID3v1 parses the 128 byte ID3v1 tag at the end of the file, if any
+func readID3v1(r io.ReadSeeker, size int64) (Tags, bool) {
+	if size < 128 {
+		return Tags{}, false
+	}
+	data, err := readAt(r, size-128, 128)
+	if err != nil || !bytes.HasPrefix(data, []byte("TAG")) {
+		return Tags{}, false
+	}
+
+	tags := Tags{
+		Title:  trimText(decodeLatin1(data[3:33])),
+		Artist: trimText(decodeLatin1(data[33:63])),
+		Album:  trimText(decodeLatin1(data[63:93])),
+		Year:   parseYear(string(data[93:97])),
+	}
+	// ID3v1.1 stores the track number in the last byte of the comment
+	if data[125] == 0 && data[126] != 0 {
+		tags.TrackNumber = int(data[126])
+	}
+	if data[127] != 0xFF {
+		tags.Genre = id3v1Genre(int(data[127]))
+	}
+	return tags, true
+}
+
+func syncsafe(b []byte) (int, bool) {
+	size := 0
+	for _, v := range b {
+		if v&0x80 != 0 {
+			return 0, false
+		}
+		size = size<<7 | int(v)
+	}
+	return size, true
+}
+
+// removeUnsync reverses ID3 unsynchronisation (0xFF 0x00 -> 0xFF)
+func removeUnsync(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	for i := 0; i < len(data); i++ {
+		out = append(out, data[i])
+		if data[i] == 0xFF && i+1 < len(data) && data[i+1] == 0x00 {
+			i++
+		}
+	}
+	return out
+}
+
+func id3v1Genre(index int) string {
+	if index < 0 || index >= len(id3v1Genres) {
+		return ""
+	}
+	return id3v1Genres[index]
+}
+
+// id3v1Genres is the ID3v1 genre list including the Winamp extensions
+var id3v1Genres = []string{
+	"Blues", "Classic Rock", "Country", "Dance", "Disco", "Funk", "Grunge", "Hip-Hop",
+	"Jazz", "Metal", "New Age", "Oldies", "Other", "Pop", "R&B", "Rap",
+	"Reggae", "Rock", "Techno", "Industrial", "Alternative", "Ska", "Death Metal", "Pranks",
+	"Soundtrack", "Euro-Techno", "Ambient", "Trip-Hop", "Vocal", "Jazz+Funk", "Fusion", "Trance",
+	"Classical", "Instrumental", "Acid", "House", "Game", "Sound Clip", "Gospel", "Noise",
+	"AlternRock", "Bass", "Soul", "Punk", "Space", "Meditative", "Instrumental Pop", "Instrumental Rock",
+	"Ethnic", "Gothic", "Darkwave", "Techno-Industrial", "Electronic", "Pop-Folk", "Eurodance", "Dream",
+	"Southern Rock", "Comedy", "Cult", "Gangsta", "Top 40", "Christian Rap", "Pop/Funk", "Jungle",
+	"Native American", "Cabaret", "New Wave", "Psychadelic", "Rave", "Showtunes", "Trailer", "Lo-Fi",
+	"Tribal", "Acid Punk", "Acid Jazz", "Polka", "Retro", "Musical", "Rock & Roll", "Hard Rock",
+	"Folk", "Folk-Rock", "National Folk", "Swing", "Fast Fusion", "Bebob", "Latin", "Revival",
+	"Celtic", "Bluegrass", "Avantgarde", "Gothic Rock", "Progressive Rock", "Psychedelic Rock", "Symphonic Rock", "Slow Rock",
+	"Big Band", "Chorus", "Easy Listening", "Acoustic", "Humour", "Speech", "Chanson", "Opera",
+	"Chamber Music", "Sonata", "Symphony", "Booty Bass", "Primus", "Porn Groove", "Satire", "Slow Jam",
+	"Club", "Tango", "Samba", "Folklore", "Ballad", "Power Ballad", "Rhythmic Soul", "Freestyle",
+	"Duet", "Punk Rock", "Drum Solo", "A capella", "Euro-House", "Dance Hall", "Goa", "Drum & Bass",
+	"Club-House", "Hardcore", "Terror", "Indie", "BritPop", "Negerpunk", "Polsk Punk", "Beat",
+	"Christian Gangsta Rap", "Heavy Metal", "Black Metal", "Crossover", "Contemporary Christian", "Christian Rock", "Merengue", "Salsa",
+	"Thrash Metal", "Anime", "JPop", "Synthpop", "Abstract", "Art Rock", "Baroque", "Bhangra",
+	"Big Beat", "Breakbeat", "Chillout", "Downtempo", "Dub", "EBM", "Eclectic", "Electro",
+	"Electroclash", "Emo", "Experimental", "Garage", "Global", "IDM", "Illbient", "Industro-Goth",
+	"Jam Band", "Krautrock", "Leftfield", "Lounge", "Math Rock", "New Romantic", "Nu-Breakz", "Post-Punk",
+	"Post-Rock", "Psytrance", "Shoegaze", "Space Rock", "Trop Rock", "World Music", "Neoclassical", "Audiobook",
+	"Audio Theatre", "Neue Deutsche Welle", "Podcast", "Indie Rock", "G-Funk", "Dubstep", "Garage Rock", "Psybient",
+}
@@ -0,0 +1,185 @@
+package metadata_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf16"
+
+	"github.com/tushar27x/music-lib-api/metadata"
+)
+
+func syncsafe32(n int) []byte {
+	return []byte{byte(n >> 21 & 0x7F), byte(n >> 14 & 0x7F), byte(n >> 7 & 0x7F), byte(n & 0x7F)}
+}
+
+// id3Tag builds an ID3v2 tag of the given version around body
+func id3Tag(version, flags byte, body []byte) []byte {
+	return join("ID3", version, byte(0), flags, syncsafe32(len(body)), body)
+}
+
+// id3Frame builds a frame of an ID3v2 tag, with the size encoding of its version
+func id3Frame(version byte, id string, data ...any) []byte {
+	payload := join(data...)
+	switch version {
+	case 2:
+		n := len(payload)
+		return join(id, []byte{byte(n >> 16), byte(n >> 8), byte(n)}, payload)
+	case 3:
+		return join(id, be32(len(payload)), []byte{0, 0}, payload)
+	default:
+		return join(id, syncsafe32(len(payload)), []byte{0, 0}, payload)
+	}
+}
+
+func utf16LE(s string) []byte {
+	var out []byte
+	for _, unit := range utf16.Encode([]rune(s)) {
+		out = append(out, byte(unit), byte(unit>>8))
+	}
+	return out
+}
+
+func utf16BE(s string) []byte {
+	var out []byte
+	for _, unit := range utf16.Encode([]rune(s)) {
+		out = append(out, byte(unit>>8), byte(unit))
+	}
+	return out
+}
+
+// id3v1 builds an ID3v1.1 tag
+func id3v1(title, artist, album, year string, track, genre byte) []byte {
+	field := func(value string, n int) []byte {
+		return join(value, make([]byte, n-len(value)))
+	}
+	return join("TAG", field(title, 30), field(artist, 30), field(album, 30), field(year, 4), make([]byte, 29), track, genre)
+}
+
+func id3Fixtures() []fixture {
+	return []fixture{
+		{
+			name: "v2.3",
+			data: join(id3Tag(3, 0, join(
+				id3Frame(3, "TIT2", byte(0), "Hey Jude"),
+				id3Frame(3, "TPE1", byte(1), []byte{0xFF, 0xFE}, utf16LE("The Beatles")),
+				id3Frame(3, "TRCK", byte(0), "7/13"),
+				id3Frame(3, "TCON", byte(0), "(17)"),
+				id3Frame(3, "TYER", byte(0), "1968"),
+				// The stream is measured rather than trusting TLEN
+				id3Frame(3, "TLEN", byte(0), "5000"),
+				// A later front cover wins over other pictures
+				id3Frame(3, "APIC", byte(0), "image/jpeg\x00", byte(4), "back\x00", "back-data"),
+				id3Frame(3, "APIC", byte(1), "image/png\x00", byte(3), utf16LE("front"), []byte{0, 0}, "png-data"),
+				make([]byte, 20),
+			)), cbrAudio()),
+			want: metadata.Tags{
+				Format: "mp3", Title: "Hey Jude", Artist: "The Beatles", Genre: "Rock", Year: 1968,
+				TrackNumber: 7, TrackTotal: 13, Duration: time.Second,
+				Picture: &metadata.Picture{MIMEType: "image/png", Type: metadata.PictureFrontCover, Data: []byte("png-data")},
+			},
+		},
+		{
+			name: "v2.4 with a footer",
+			data: join(id3Tag(4, 0x10, join(
+				id3Frame(4, "TIT2", byte(3), "Señorita"),
+				id3Frame(4, "TPE2", byte(2), utf16BE("Various")),
+				// Multiple values are separated by NUL and the first one is kept
+				id3Frame(4, "TCON", byte(3), "Pop\x00Rock"),
+				id3Frame(4, "TDRC", byte(3), "2001-05-01"),
+				id3Frame(4, "TPOS", byte(3), "1/2"),
+			)), "3DI", make([]byte, 7), cbrAudio()),
+			want: metadata.Tags{
+				Format: "mp3", Title: "Señorita", AlbumArtist: "Various", Genre: "Pop", Year: 2001,
+				DiscNumber: 1, DiscTotal: 2, Duration: time.Second,
+			},
+		},
+		{
+			name: "v2.4 frame flags and plain sizes",
+			data: join(id3Tag(4, 0, join(
+				// A data length indicator precedes the frame data
+				"TALB", syncsafe32(10), []byte{0, 0x01}, syncsafe32(6), byte(0), "Abbey",
+				// 200 is not syncsafe, so it is read as a plain size
+				"TIT2", be32(200), []byte{0, 0}, byte(0), strings.Repeat("a", 199),
+				// Compressed frames are skipped
+				"TPE1", syncsafe32(5), []byte{0, 0x08}, byte(0), "Nope",
+			)), cbrAudio()),
+			want: metadata.Tags{Format: "mp3", Title: strings.Repeat("a", 199), Album: "Abbey", Duration: time.Second},
+		},
+		{
+			name: "v2.2",
+			data: join(id3Tag(2, 0, join(
+				id3Frame(2, "TT2", byte(0), "Yesterday"),
+				id3Frame(2, "TP1", byte(0), "The Beatles"),
+				id3Frame(2, "TRK", byte(0), "13"),
+				id3Frame(2, "TCO", byte(0), "(17)Britpop"),
+				id3Frame(2, "PIC", byte(0), "JPG", byte(3), "\x00", "jpeg-data"),
+			)), cbrAudio()),
+			want: metadata.Tags{
+				Format: "mp3", Title: "Yesterday", Artist: "The Beatles", Genre: "Britpop", TrackNumber: 13, Duration: time.Second,
+				Picture: &metadata.Picture{MIMEType: "image/jpg", Type: metadata.PictureFrontCover, Data: []byte("jpeg-data")},
+			},
+		},
+		{
+			name: "v2.3 unsynchronised",
+			data: join(id3Tag(3, 0x80, join(
+				// The frame size counts the data after unsynchronisation is removed
+				"TIT2", be32(4), []byte{0, 0}, byte(0), "A", []byte{0xFF, 0x00}, "B",
+			)), cbrAudio()),
+			want: metadata.Tags{Format: "mp3", Title: "AÿB", Duration: time.Second},
+		},
+		{
+			name: "v1.1 only",
+			data: join(cbrAudio(), id3v1("Imagine", "John Lennon", "Imagine", "1971", 1, 17)),
+			want: metadata.Tags{
+				Format: "mp3", Title: "Imagine", Artist: "John Lennon", Album: "Imagine", Year: 1971,
+				TrackNumber: 1, Genre: "Rock", Duration: time.Second,
+			},
+		},
+		{
+			name: "v2 fields win over v1",
+			data: join(
+				id3Tag(3, 0, id3Frame(3, "TIT2", byte(0), "From v2")),
+				cbrAudio(),
+				id3v1("From v1", "Artist from v1", "", "", 0, 0xFF),
+			),
+			want: metadata.Tags{Format: "mp3", Title: "From v2", Artist: "Artist from v1", Duration: time.Second},
+		},
+		{
+			name: "tag longer than the file",
+			data: join("ID3", byte(3), byte(0), byte(0), syncsafe32(1000), id3Frame(3, "TIT2", byte(0), "Cut")),
+			err:  metadata.ErrMalformed,
+		},
+		{
+			name: "tag size not syncsafe",
+			data: join("ID3", byte(3), byte(0), byte(0), []byte{0, 0, 0x80, 0}, cbrAudio()),
+			err:  metadata.ErrMalformed,
+		},
+		{
+			name: "frame longer than the tag",
+			data: join(id3Tag(3, 0, join(
+				id3Frame(3, "TIT2", byte(0), "Kept"),
+				"TPE1", be32(0x7FFFFFF0), []byte{0, 0}, byte(0), "Lost",
+			)), cbrAudio()),
+			want: metadata.Tags{Format: "mp3", Title: "Kept", Duration: time.Second},
+		},
+		{
+			name: "extended header longer than the tag",
+			data: join(id3Tag(3, 0x40, join(be32(1000), id3Frame(3, "TIT2", byte(0), "Lost"))), cbrAudio()),
+			err:  metadata.ErrMalformed,
+		},
+		{
+			name: "empty and truncated pictures",
+			data: join(id3Tag(3, 0, join(
+				id3Frame(3, "APIC", byte(0)),
+				id3Frame(3, "APIC", byte(0), "image/png"),
+				id3Frame(3, "APIC", byte(1), "image/png\x00", byte(3), "no terminator"),
+			)), cbrAudio()),
+			want: metadata.Tags{Format: "mp3", Duration: time.Second},
+		},
+	}
+}
+
+func TestReadID3(t *testing.T) {
+	checkFixtures(t, id3Fixtures())
+}
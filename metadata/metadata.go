@@ -0,0 +1,183 @@
+// Package metadata extracts tags and durations from audio files.
+package metadata
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrUnsupported is returned for formats that carry no tags we can read
+	ErrUnsupported = errors.New("unsupported audio format")
+	// ErrMalformed is returned when a file claims a format but its structure is broken
+	ErrMalformed = errors.New("malformed audio metadata")
+)
+
+// maxBlockSize caps how much of a single tag structure is read into memory
+const maxBlockSize = 64 << 20
+
+// Tags holds the metadata extracted from an audio file
+type Tags struct {
+	Format      string
+	Title       string
+	Artist      string
+	Album       string
+	AlbumArtist string
+	Genre       string
+	Year        int
+	TrackNumber int
+	TrackTotal  int
+	DiscNumber  int
+	DiscTotal   int
+	Duration    time.Duration
+	// Picture is the embedded cover art, preferring the front cover
+	Picture *Picture
+}
+
+// PictureFrontCover is the picture type ID3 and FLAC use for the front cover
+const PictureFrontCover = 3
+
+// Picture is an image embedded in the tags
+type Picture struct {
+	// MIMEType is the type given by the tag, which is not always accurate
+	MIMEType string
+	// Type is the ID3 picture type, e.g. PictureFrontCover
+	Type int
+	Data []byte
+}
+
+// Read detects the container format and extracts its tags and duration
+func Read(r io.ReadSeeker, size int64) (Tags, error) {
+	header, err := readAt(r, 0, 12)
+	if err != nil {
+		return Tags{}, ErrUnsupported
+	}
+
+	switch {
+	case bytes.HasPrefix(header, []byte("ID3")):
+		// ID3v2 is mostly found on MP3, but some encoders also prepend it to FLAC
+		tagSize, err := id3v2TagSize(header)
+		if err != nil {
+			return Tags{}, err
+		}
+		next, err := readAt(r, tagSize, 4)
+		if err == nil && bytes.Equal(next, []byte("fLaC")) {
+			return readFLAC(r, tagSize)
+		}
+		return readMP3(r, size)
+	case bytes.HasPrefix(header, []byte("fLaC")):
+		return readFLAC(r, 0)
+	case bytes.HasPrefix(header, []byte("OggS")):
+		return readOgg(r, size)
+	case bytes.Equal(header[4:8], []byte("ftyp")):
+		return readMP4(r, size)
+	case bytes.Equal(header[0:4], []byte("RIFF")) && bytes.Equal(header[8:12], []byte("WAVE")):
+		return readWAV(r, size)
+	case header[0] == 0xFF && header[1]&0xE0 == 0xE0 && header[1]&0x06 != 0:
+		return readMP3(r, size)
+	}
+
+	return Tags{}, ErrUnsupported
+}
+
+// merge fills the empty fields of t from other
+func (t *Tags) merge(other Tags) {
+	if t.Title == "" {
+		t.Title = other.Title
+	}
+	if t.Artist == "" {
+		t.Artist = other.Artist
+	}
+	if t.Album == "" {
+		t.Album = other.Album
+	}
+	if t.AlbumArtist == "" {
+		t.AlbumArtist = other.AlbumArtist
+	}
+	if t.Genre == "" {
+		t.Genre = other.Genre
+	}
+	if t.Year == 0 {
+		t.Year = other.Year
+	}
+	if t.TrackNumber == 0 {
+		t.TrackNumber = other.TrackNumber
+	}
+	if t.TrackTotal == 0 {
+		t.TrackTotal = other.TrackTotal
+	}
+	if t.DiscNumber == 0 {
+		t.DiscNumber = other.DiscNumber
+	}
+	if t.DiscTotal == 0 {
+		t.DiscTotal = other.DiscTotal
+	}
+	if t.Picture == nil {
+		t.Picture = other.Picture
+	}
+}
+
+// addPicture keeps the first picture found unless a later one is the front cover
+func (t *Tags) addPicture(picture Picture) {
+	if len(picture.Data) == 0 {
+		return
+	}
+	if t.Picture == nil || (t.Picture.Type != PictureFrontCover && picture.Type == PictureFrontCover) {
+		t.Picture = &picture
+	}
+}
+
+// parsePosition parses "3" or "3/12" style track and disc numbers
+func parsePosition(value string) (int, int) {
+	value = strings.TrimSpace(value)
+	number, total, _ := strings.Cut(value, "/")
+	n, _ := strconv.Atoi(strings.TrimSpace(number))
+	t, _ := strconv.Atoi(strings.TrimSpace(total))
+	if n < 0 {
+		n = 0
+	}
+	if t < 0 {
+		t = 0
+	}
+	return n, t
+}
+
+// parseYear takes the year from values like "1973", "1973-03-01" or "1973-03-01T00:00:00Z"
+func parseYear(value string) int {
+	value = strings.TrimSpace(value)
+	if len(value) < 4 {
+		return 0
+	}
+	year, err := strconv.Atoi(value[:4])
+	if err != nil || year < 1000 {
+		return 0
+	}
+	return year
+}
+
+// readAt reads exactly n bytes at offset; structures that run past the end of the file are malformed
+func readAt(r io.ReadSeeker, offset int64, n int) ([]byte, error) {
+	if offset < 0 || n < 0 || n > maxBlockSize {
+		return nil, ErrMalformed
+	}
+	if _, err := r.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, ErrMalformed
+		}
+		return nil, err
+	}
+	return buf, nil
+}
+
+// trimText strips the padding and terminators tag formats leave around strings
+func trimText(value string) string {
+	return strings.TrimSpace(strings.TrimRight(value, "\x00 "))
+}
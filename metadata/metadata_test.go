@@ -0,0 +1,82 @@
+package metadata_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/tushar27x/music-lib-api/metadata"
+)
+
+// fixture is a hand-built file and what Read makes of it
+type fixture struct {
+	name string
+	data []byte
+	want metadata.Tags
+	err  error
+}
+
+func checkFixtures(t *testing.T, fixtures []fixture) {
+	t.Helper()
+	for _, tt := range fixtures {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := metadata.Read(bytes.NewReader(tt.data), int64(len(tt.data)))
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("error = %v, want %v", err, tt.err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tags = %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func FuzzParse(f *testing.F) {
+	for _, fixtures := range [][]fixture{id3Fixtures(), flacFixtures(), oggFixtures(), mp4Fixtures(), wavFixtures()} {
+		for _, tt := range fixtures {
+			f.Add(tt.data)
+		}
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// Anything may come back, as long as it doesn't panic
+		metadata.Read(bytes.NewReader(data), int64(len(data)))
+	})
+}
+
+func TestReadUnsupported(t *testing.T) {
+	checkFixtures(t, []fixture{
+		{name: "empty", data: nil, err: metadata.ErrUnsupported},
+		{name: "shorter than a header", data: []byte("fLaC"), err: metadata.ErrUnsupported},
+		{name: "text", data: []byte("just some text, not audio"), err: metadata.ErrUnsupported},
+	})
+}
+
+func be16(n int) []byte { return binary.BigEndian.AppendUint16(nil, uint16(n)) }
+func be32(n int) []byte { return binary.BigEndian.AppendUint32(nil, uint32(n)) }
+func le32(n int) []byte { return binary.LittleEndian.AppendUint32(nil, uint32(n)) }
+
+// join concatenates byte slices and strings
+func join(parts ...any) []byte {
+	var out []byte
+	for _, part := range parts {
+		switch part := part.(type) {
+		case string:
+			out = append(out, part...)
+		case []byte:
+			out = append(out, part...)
+		case byte:
+			out = append(out, part)
+		default:
+			panic("join: unexpected part")
+		}
+	}
+	return out
+}
+
+// cbrAudio is one second of 128 kbit/s MPEG-1 Layer III: a frame header followed by silence
+func cbrAudio() []byte {
+	return slices.Concat([]byte{0xFF, 0xFB, 0x90, 0x00}, make([]byte, 16000-4))
+}
@@ -0,0 +1,195 @@
+package metadata
+
+import (
+	"encoding/binary"
+	"io"
+	"strings"
+	"time"
+)
+
+type mp4Atom struct {
+	kind string
+	data []byte
+}
+
+// readMP4 locates the moov atom (which may follow the media data) and reads
+// the movie header and iTunes-style ilst metadata
+func readMP4(r io.ReadSeeker, size int64) (Tags, error) {
+	tags := Tags{Format: "m4a"}
+
+	var position int64
+	for position+8 <= size {
+		header, err := readAt(r, position, 8)
+		if err != nil {
+			return Tags{}, ErrMalformed
+		}
+		atomSize := int64(binary.BigEndian.Uint32(header[0:4]))
+		kind := string(header[4:8])
+		headerSize := int64(8)
+
+		switch atomSize {
+		case 0:
+			atomSize = size - position
+		case 1:
+			large, err := readAt(r, position+8, 8)
+			if err != nil {
+				return Tags{}, ErrMalformed
+			}
+			atomSize = int64(binary.BigEndian.Uint64(large))
+			headerSize = 16
+		}
+		if atomSize < headerSize {
+			return Tags{}, ErrMalformed
+		}
+
+		if kind == "moov" {
+			moov, err := readAt(r, position+headerSize, int(atomSize-headerSize))
+			if err != nil {
+				return Tags{}, ErrMalformed
+			}
+			parseMoov(&tags, moov)
+			return tags, nil
+		}
+		position += atomSize
+	}
+
+	return Tags{}, ErrMalformed
+}
+
+func parseMoov(tags *Tags, moov []byte) {
+	for _, atom := range mp4Children(moov) {
+		switch atom.kind {
+		case "mvhd":
+			tags.Duration = mvhdDuration(atom.data)
+		case "udta":
+			for _, child := range mp4Children(atom.data) {
+				if child.kind == "meta" {
+					parseMeta(tags, child.data)
+				}
+			}
+		case "meta":
+			parseMeta(tags, atom.data)
+		}
+	}
+}
+
+func parseMeta(tags *Tags, meta []byte) {
+	// meta is a full box in iTunes files but a plain container in QuickTime ones
+	if len(meta) >= 4 && binary.BigEndian.Uint32(meta[0:4]) == 0 {
+		meta = meta[4:]
+	}
+	for _, child := range mp4Children(meta) {
+		if child.kind != "ilst" {
+			continue
+		}
+		for _, item := range mp4Children(child.data) {
+			applyMP4Item(tags, item)
+		}
+	}
+}
+
+func applyMP4Item(tags *Tags, item mp4Atom) {
+	var value []byte
+	var dataType uint32
+	for _, child := range mp4Children(item.data) {
+		// data atoms carry a 4 byte type indicator and a 4 byte locale before the value
+		if child.kind == "data" && len(child.data) >= 8 {
+			value = child.data[8:]
+			dataType = binary.BigEndian.Uint32(child.data[0:4]) & 0xFFFFFF
+			break
+		}
+	}
+	if value == nil {
+		return
+	}
+
+	text := func() string { return trimText(string(value)) }
+
+	switch item.kind {
+	case "\xa9nam":
+		tags.Title = text()
+	case "\xa9ART":
+		tags.Artist = text()
+	case "aART":
+		tags.AlbumArtist = text()
+	case "\xa9alb":
+		tags.Album = text()
+	case "\xa9day":
+		tags.Year = parseYear(text())
+	case "\xa9gen":
+		tags.Genre = text()
+	case "gnre":
+		// Legacy numeric genre, stored as ID3v1 index + 1
+		if len(value) >= 2 {
+			tags.Genre = id3v1Genre(int(binary.BigEndian.Uint16(value[0:2])) - 1)
+		}
+	case "trkn":
+		if len(value) >= 6 {
+			tags.TrackNumber = int(binary.BigEndian.Uint16(value[2:4]))
+			tags.TrackTotal = int(binary.BigEndian.Uint16(value[4:6]))
+		}
+	case "disk":
+		if len(value) >= 6 {
+			tags.DiscNumber = int(binary.BigEndian.Uint16(value[2:4]))
+			tags.DiscTotal = int(binary.BigEndian.Uint16(value[4:6]))
+		}
+	case "covr":
+		// iTunes has no picture types, so the first image is the cover
+		picture := Picture{Type: PictureFrontCover, Data: value}
+		switch dataType {
+		case 13:
+			picture.MIMEType = "image/jpeg"
+		case 14:
+			picture.MIMEType = "image/png"
+		}
+		tags.addPicture(picture)
+	}
+}
+
+func mvhdDuration(mvhd []byte) time.Duration {
+	if len(mvhd) < 20 {
+		return 0
+	}
+
+	var timescale, duration uint64
+	if mvhd[0] == 1 {
+		if len(mvhd) < 32 {
+			return 0
+		}
+		timescale = uint64(binary.BigEndian.Uint32(mvhd[20:24]))
+		duration = binary.BigEndian.Uint64(mvhd[24:32])
+	} else {
+		timescale = uint64(binary.BigEndian.Uint32(mvhd[12:16]))
+		duration = uint64(binary.BigEndian.Uint32(mvhd[16:20]))
+	}
+	if timescale == 0 {
+		return 0
+	}
+	return time.Duration(duration) * time.Second / time.Duration(timescale)
+}
+
+// mp4Children splits a container payload into its child atoms
+func mp4Children(data []byte) []mp4Atom {
+	var atoms []mp4Atom
+	for len(data) >= 8 {
+		size := int(binary.BigEndian.Uint32(data[0:4]))
+		kind := string(data[4:8])
+		headerSize := 8
+		switch size {
+		case 0:
+			size = len(data)
+		case 1:
+			if len(data) < 16 {
+				return atoms
+			}
+			size = int(binary.BigEndian.Uint64(data[8:16]))
+			headerSize = 16
+		}
+		if size < headerSize || size > len(data) {
+			return atoms
+		}
+		atoms = append(atoms, mp4Atom{kind: strings.Clone(kind), data: data[headerSize:size]})
+		data = data[size:]
+	}
+	return atoms
+}
@@ -0,0 +1,109 @@
+package metadata_test
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/tushar27x/music-lib-api/metadata"
+)
+
+func atom(kind string, payload ...any) []byte {
+	data := join(payload...)
+	return join(be32(8+len(data)), kind, data)
+}
+
+// ilstItem builds a metadata item holding a single data atom
+func ilstItem(kind string, dataType int, value ...any) []byte {
+	return atom(kind, atom("data", be32(dataType), be32(0), join(value...)))
+}
+
+// mvhd builds a version 0 movie header
+func mvhd(timescale, duration int) []byte {
+	return atom("mvhd", make([]byte, 12), be32(timescale), be32(duration), make([]byte, 80))
+}
+
+func mp4Fixtures() []fixture {
+	ftyp := atom("ftyp", "M4A ", be32(0), "isomM4A ")
+	return []fixture{
+		{
+			name: "moov after the media data",
+			data: join(ftyp, atom("mdat", make([]byte, 100)), atom("moov",
+				mvhd(1000, 5000),
+				atom("udta", atom("meta", be32(0),
+					atom("hdlr", make([]byte, 25)),
+					atom("ilst",
+						ilstItem("\xa9nam", 1, "Blackbird"),
+						ilstItem("\xa9ART", 1, "The Beatles"),
+						ilstItem("aART", 1, "The Beatles"),
+						ilstItem("\xa9alb", 1, "The White Album"),
+						ilstItem("\xa9day", 1, "1968-11-22T00:00:00Z"),
+						// Numeric genres count from 1
+						ilstItem("gnre", 0, be16(18)),
+						ilstItem("trkn", 0, be16(0), be16(11), be16(30), be16(0)),
+						ilstItem("disk", 0, be16(0), be16(1), be16(2)),
+						ilstItem("covr", 14, "png-data"),
+					),
+				)),
+			)),
+			want: metadata.Tags{
+				Format: "m4a", Title: "Blackbird", Artist: "The Beatles", AlbumArtist: "The Beatles", Album: "The White Album",
+				Year: 1968, Genre: "Rock", TrackNumber: 11, TrackTotal: 30, DiscNumber: 1, DiscTotal: 2, Duration: 5 * time.Second,
+				Picture: &metadata.Picture{MIMEType: "image/png", Type: metadata.PictureFrontCover, Data: []byte("png-data")},
+			},
+		},
+		{
+			name: "64-bit sizes and a QuickTime meta",
+			data: join(ftyp,
+				be32(1), "mdat", binary.BigEndian.AppendUint64(nil, 16+50), make([]byte, 50),
+				atom("moov",
+					// Version 1 has 64-bit times and duration
+					atom("mvhd", byte(1), make([]byte, 19), be32(44100), binary.BigEndian.AppendUint64(nil, 441000), make([]byte, 80)),
+					atom("meta", atom("ilst", ilstItem("\xa9nam", 1, "Julia"), ilstItem("\xa9gen", 1, "Folk"))),
+				),
+			),
+			want: metadata.Tags{Format: "m4a", Title: "Julia", Genre: "Folk", Duration: 10 * time.Second},
+		},
+		{
+			name: "item longer than its list",
+			data: join(ftyp, atom("moov", atom("meta", be32(0), atom("ilst",
+				ilstItem("\xa9nam", 1, "Kept"),
+				be32(1000), "\xa9ART", ilstItem("data", 1, "Lost"),
+			)))),
+			want: metadata.Tags{Format: "m4a", Title: "Kept"},
+		},
+		{
+			name: "short values",
+			data: join(ftyp, atom("moov", atom("meta", be32(0), atom("ilst",
+				atom("\xa9nam", atom("data", be32(1))),
+				ilstItem("trkn", 0, be16(0), be16(3)),
+				ilstItem("gnre", 0, byte(1)),
+			)))),
+			want: metadata.Tags{Format: "m4a"},
+		},
+		{
+			name: "moov longer than the file",
+			data: join(ftyp, be32(1000), "moov", mvhd(1000, 5000)),
+			err:  metadata.ErrMalformed,
+		},
+		{
+			name: "atom smaller than its header",
+			data: join(ftyp, be32(4), "free", make([]byte, 20)),
+			err:  metadata.ErrMalformed,
+		},
+		{
+			name: "64-bit size smaller than its header",
+			data: join(ftyp, be32(1), "mdat", binary.BigEndian.AppendUint64(nil, 1<<63), make([]byte, 20)),
+			err:  metadata.ErrMalformed,
+		},
+		{
+			name: "no moov",
+			data: join(ftyp, atom("mdat", make([]byte, 100))),
+			err:  metadata.ErrMalformed,
+		},
+	}
+}
+
+func TestReadMP4(t *testing.T) {
+	checkFixtures(t, mp4Fixtures())
+}
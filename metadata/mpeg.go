@@ -0,0 +1,161 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"time"
+)
+
+// frameSearchWindow is how far past the tag we look for the first MPEG frame
+const frameSearchWindow = 64 << 10
+
+var mpegBitrates = map[[2]int][]int{
+	// {version, layer}: kbit/s by bitrate index; version 1 = MPEG-1, 2 = MPEG-2/2.5
+	{1, 1}: {0, 32, 64, 96, 128, 160, 192, 224, 256, 288, 320, 352, 384, 416, 448},
+	{1, 2}: {0, 32, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384},
+	{1, 3}: {0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320},
+	{2, 1}: {0, 32, 48, 56, 64, 80, 96, 112, 128, 144, 160, 176, 192, 224, 256},
+	{2, 2}: {0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+	{2, 3}: {0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+}
+
+var mpegSampleRates = map[byte][]int{
+	3: {44100, 48000, 32000}, // MPEG-1
+	2: {22050, 24000, 16000}, // MPEG-2
+	0: {11025, 12000, 8000},  // MPEG-2.5
+}
+
+type mpegFrame struct {
+	versionBits     byte
+	layer           int
+	bitrate         int
+	sampleRate      int
+	samplesPerFrame int
+	mono            bool
+}
+
+func readMP3(r io.ReadSeeker, size int64) (Tags, error) {
+	tags := Tags{Format: "mp3"}
+
+	var audioStart int64
+	header, err := readAt(r, 0, 3)
+	if err == nil && bytes.Equal(header, []byte("ID3")) {
+		id3Tags, tagSize, err := readID3v2(r)
+		if err != nil {
+			return Tags{}, err
+		}
+		tags = id3Tags
+		tags.Format = "mp3"
+		audioStart = tagSize
+	}
+
+	audioEnd := size
+	if v1, ok := readID3v1(r, size); ok {
+		tags.merge(v1)
+		audioEnd -= 128
+	}
+
+	// Prefer the real stream length over TLEN, which is often missing or stale
+	if duration, ok := mp3Duration(r, audioStart, audioEnd); ok {
+		tags.Duration = duration
+	}
+
+	return tags, nil
+}
+
+// mp3Duration finds the first frame and derives the duration from a Xing/VBRI
+// header, or from the bitrate for constant bitrate files
+func mp3Duration(r io.ReadSeeker, audioStart, audioEnd int64) (time.Duration, bool) {
+	window := int64(frameSearchWindow)
+	if audioEnd-audioStart < window {
+		window = audioEnd - audioStart
+	}
+	if window < 4 {
+		return 0, false
+	}
+	data, err := readAt(r, audioStart, int(window))
+	if err != nil {
+		return 0, false
+	}
+
+	for i := 0; i+4 <= len(data); i++ {
+		frame, ok := parseMPEGHeader(data[i : i+4])
+		if !ok {
+			continue
+		}
+
+		// Xing/Info header sits right after the side information
+		var sideInfo int
+		switch {
+		case frame.versionBits == 3 && !frame.mono:
+			sideInfo = 32
+		case frame.versionBits == 3, !frame.mono:
+			sideInfo = 17
+		default:
+			sideInfo = 9
+		}
+		if xing := i + 4 + sideInfo; xing+12 <= len(data) {
+			tag := string(data[xing : xing+4])
+			if (tag == "Xing" || tag == "Info") && data[xing+7]&0x01 != 0 {
+				frames := binary.BigEndian.Uint32(data[xing+8 : xing+12])
+				return framesToDuration(frames, frame), true
+			}
+		}
+		if vbri := i + 36; vbri+18 <= len(data) && string(data[vbri:vbri+4]) == "VBRI" {
+			frames := binary.BigEndian.Uint32(data[vbri+14 : vbri+18])
+			return framesToDuration(frames, frame), true
+		}
+
+		if frame.bitrate == 0 {
+			return 0, false
+		}
+		audioBytes := audioEnd - audioStart - int64(i)
+		seconds := float64(audioBytes*8) / float64(frame.bitrate*1000)
+		return time.Duration(seconds * float64(time.Second)), true
+	}
+
+	return 0, false
+}
+
+func parseMPEGHeader(h []byte) (mpegFrame, bool) {
+	if h[0] != 0xFF || h[1]&0xE0 != 0xE0 {
+		return mpegFrame{}, false
+	}
+
+	versionBits := (h[1] >> 3) & 0x03
+	layerBits := (h[1] >> 1) & 0x03
+	bitrateIndex := int(h[2] >> 4)
+	sampleRateIndex := int((h[2] >> 2) & 0x03)
+	if versionBits == 1 || layerBits == 0 || bitrateIndex == 15 || sampleRateIndex == 3 {
+		return mpegFrame{}, false
+	}
+
+	layer := 4 - int(layerBits)
+	tableVersion := 1
+	if versionBits != 3 {
+		tableVersion = 2
+	}
+
+	samples := 1152
+	switch {
+	case layer == 1:
+		samples = 384
+	case layer == 3 && versionBits != 3:
+		samples = 576
+	}
+
+	return mpegFrame{
+		versionBits:     versionBits,
+		layer:           layer,
+		bitrate:         mpegBitrates[[2]int{tableVersion, layer}][bitrateIndex],
+		sampleRate:      mpegSampleRates[versionBits][sampleRateIndex],
+		samplesPerFrame: samples,
+		mono:            h[3]>>6 == 3,
+	}, true
+}
+
+func framesToDuration(frames uint32, frame mpegFrame) time.Duration {
+	seconds := float64(frames) * float64(frame.samplesPerFrame) / float64(frame.sampleRate)
+	return time.Duration(seconds * float64(time.Second))
+}
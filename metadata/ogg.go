@@ -0,0 +1,144 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"time"
+)
+
+// oggTailWindow is how much of the end of the file is scanned for the last page
+const oggTailWindow = 64 << 10
+
+type oggPacketReader struct {
+	r        io.ReadSeeker
+	position int64
+	serial   uint32
+	started  bool
+	pending  [][]byte
+	partial  []byte
+}
+
+// next returns the next complete packet of the first logical stream
+func (p *oggPacketReader) next() ([]byte, error) {
+	for len(p.pending) == 0 {
+		header, err := readAt(p.r, p.position, 27)
+		if err != nil || !bytes.HasPrefix(header, []byte("OggS")) {
+			return nil, ErrMalformed
+		}
+		serial := binary.LittleEndian.Uint32(header[14:18])
+		segmentCount := int(header[26])
+		table, err := readAt(p.r, p.position+27, segmentCount)
+		if err != nil {
+			return nil, ErrMalformed
+		}
+
+		bodySize := 0
+		for _, length := range table {
+			bodySize += int(length)
+		}
+		body, err := readAt(p.r, p.position+27+int64(segmentCount), bodySize)
+		if err != nil {
+			return nil, ErrMalformed
+		}
+		p.position += 27 + int64(segmentCount) + int64(bodySize)
+
+		if !p.started {
+			p.serial = serial
+			p.started = true
+		}
+		if serial != p.serial {
+			continue
+		}
+
+		// A segment shorter than 255 bytes terminates the packet
+		offset := 0
+		for _, length := range table {
+			p.partial = append(p.partial, body[offset:offset+int(length)]...)
+			offset += int(length)
+			if length < 255 {
+				p.pending = append(p.pending, p.partial)
+				p.partial = nil
+			}
+			if len(p.partial) > maxBlockSize {
+				return nil, ErrMalformed
+			}
+		}
+	}
+
+	packet := p.pending[0]
+	p.pending = p.pending[1:]
+	return packet, nil
+}
+
+func readOgg(r io.ReadSeeker, size int64) (Tags, error) {
+	packets := &oggPacketReader{r: r}
+
+	identification, err := packets.next()
+	if err != nil {
+		return Tags{}, err
+	}
+
+	var tags Tags
+	var sampleRate int64
+	var preSkip int64
+
+	switch {
+	case len(identification) >= 16 && bytes.Equal(identification[:7], []byte("\x01vorbis")):
+		sampleRate = int64(binary.LittleEndian.Uint32(identification[12:16]))
+		comment, err := packets.next()
+		if err != nil || !bytes.HasPrefix(comment, []byte("\x03vorbis")) {
+			return Tags{}, ErrMalformed
+		}
+		if tags, err = parseVorbisComments(comment[7:]); err != nil {
+			return Tags{}, err
+		}
+		tags.Format = "ogg"
+	case len(identification) >= 12 && bytes.HasPrefix(identification, []byte("OpusHead")):
+		// Opus granule positions always count 48 kHz samples
+		sampleRate = 48000
+		preSkip = int64(binary.LittleEndian.Uint16(identification[10:12]))
+		comment, err := packets.next()
+		if err != nil || !bytes.HasPrefix(comment, []byte("OpusTags")) {
+			return Tags{}, ErrMalformed
+		}
+		if tags, err = parseVorbisComments(comment[8:]); err != nil {
+			return Tags{}, err
+		}
+		tags.Format = "opus"
+	default:
+		return Tags{}, ErrUnsupported
+	}
+
+	if granule, ok := lastOggGranule(r, size, packets.serial); ok && sampleRate > 0 && granule > preSkip {
+		tags.Duration = time.Duration(granule-preSkip) * time.Second / time.Duration(sampleRate)
+	}
+
+	return tags, nil
+}
+
+// lastOggGranule returns the granule position of the last page of the stream
+func lastOggGranule(r io.ReadSeeker, size int64, serial uint32) (int64, bool) {
+	start := size - oggTailWindow
+	if start < 0 {
+		start = 0
+	}
+	tail, err := readAt(r, start, int(size-start))
+	if err != nil {
+		return 0, false
+	}
+
+	for i := bytes.LastIndex(tail, []byte("OggS")); i >= 0; i = bytes.LastIndex(tail[:i], []byte("OggS")) {
+		if i+27 > len(tail) {
+			continue
+		}
+		if binary.LittleEndian.Uint32(tail[i+14:i+18]) != serial {
+			continue
+		}
+		granule := int64(binary.LittleEndian.Uint64(tail[i+6 : i+14]))
+		if granule >= 0 {
+			return granule, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,110 @@
+package metadata_test
+
+import (
+	"encoding/binary"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tushar27x/music-lib-api/metadata"
+)
+
+// oggPage builds a page from its segment table and body; the checksum is left empty since it isn't verified
+func oggPage(serial int, granule int64, table []byte, body []byte) []byte {
+	return join("OggS", byte(0), byte(0), binary.LittleEndian.AppendUint64(nil, uint64(granule)), le32(serial), le32(0), le32(0),
+		byte(len(table)), table, body)
+}
+
+// oggPacket builds a page holding exactly one packet
+func oggPacket(serial int, granule int64, packet []byte) []byte {
+	var table []byte
+	for n := len(packet); ; n -= 255 {
+		if n < 255 {
+			table = append(table, byte(n))
+			break
+		}
+		table = append(table, 255)
+	}
+	return oggPage(serial, granule, table, packet)
+}
+
+// vorbisIdentification builds the identification header of a stereo Vorbis stream
+func vorbisIdentification(sampleRate int) []byte {
+	return join("\x01vorbis", le32(0), byte(2), le32(sampleRate), make([]byte, 14))
+}
+
+// opusHead builds the identification header of a stereo Opus stream
+func opusHead(preSkip int) []byte {
+	return join("OpusHead", byte(1), byte(2), []byte{byte(preSkip), byte(preSkip >> 8)}, le32(48000), []byte{0, 0, 0})
+}
+
+func oggFixtures() []fixture {
+	longTitle := strings.Repeat("a", 300)
+	comment := join("\x03vorbis", vorbisComments("TITLE="+longTitle, "ARTIST=Nina Simone"), byte(1))
+	return []fixture{
+		{
+			name: "vorbis",
+			data: join(
+				oggPacket(1, 0, vorbisIdentification(44100)),
+				oggPacket(1, 0, join("\x03vorbis", vorbisComments("TITLE=Feeling Good", "ARTIST=Nina Simone", "DATE=1965"), byte(1))),
+				oggPacket(1, 441000, []byte{0}),
+			),
+			want: metadata.Tags{Format: "ogg", Title: "Feeling Good", Artist: "Nina Simone", Year: 1965, Duration: 10 * time.Second},
+		},
+		{
+			name: "opus",
+			data: join(
+				oggPacket(7, 0, opusHead(312)),
+				oggPacket(7, 0, join("OpusTags", vorbisComments("TITLE=Sinnerman", "TRACKNUMBER=4/10"))),
+				oggPacket(7, 3*48000+312, []byte{0}),
+			),
+			want: metadata.Tags{Format: "opus", Title: "Sinnerman", TrackNumber: 4, TrackTotal: 10, Duration: 3 * time.Second},
+		},
+		{
+			name: "packet across pages and another stream",
+			data: join(
+				oggPacket(1, 0, vorbisIdentification(44100)),
+				oggPage(1, -1, []byte{255}, comment[:255]),
+				oggPacket(2, 0, []byte("another stream")),
+				oggPage(1, 0, []byte{byte(len(comment) - 255)}, comment[255:]),
+				oggPacket(1, 88200, []byte{0}),
+				// The last page of the other stream doesn't count
+				oggPacket(2, 44100*60, []byte{0}),
+			),
+			want: metadata.Tags{Format: "ogg", Title: longTitle, Artist: "Nina Simone", Duration: 2 * time.Second},
+		},
+		{
+			name: "page longer than the file",
+			data: join(
+				oggPacket(1, 0, vorbisIdentification(44100)),
+				oggPage(1, 0, []byte{200}, []byte("\x03vorbis")),
+			),
+			err: metadata.ErrMalformed,
+		},
+		{
+			name: "more comments than the packet holds",
+			data: join(
+				oggPacket(1, 0, vorbisIdentification(44100)),
+				oggPacket(1, 0, join("\x03vorbis", le32(0), le32(1000))),
+			),
+			err: metadata.ErrMalformed,
+		},
+		{
+			name: "no comment packet",
+			data: join(
+				oggPacket(1, 0, vorbisIdentification(44100)),
+				oggPacket(1, 0, []byte("\x05vorbis")),
+			),
+			err: metadata.ErrMalformed,
+		},
+		{
+			name: "other codec",
+			data: oggPacket(1, 0, join("\x80theora", make([]byte, 34))),
+			err:  metadata.ErrUnsupported,
+		},
+	}
+}
+
+func TestReadOgg(t *testing.T) {
+	checkFixtures(t, oggFixtures())
+}
@@ -0,0 +1,226 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"strings"
+	"time"
+)
+
+// parseVorbisComments reads a Vorbis comment block as used by FLAC, Ogg Vorbis and Opus
+func parseVorbisComments(data []byte) (Tags, error) {
+	var tags Tags
+	reader := bytes.NewReader(data)
+
+	var vendorLength uint32
+	if err := binary.Read(reader, binary.LittleEndian, &vendorLength); err != nil {
+		return tags, ErrMalformed
+	}
+	if _, err := reader.Seek(int64(vendorLength), io.SeekCurrent); err != nil {
+		return tags, ErrMalformed
+	}
+
+	var count uint32
+	if err := binary.Read(reader, binary.LittleEndian, &count); err != nil {
+		return tags, ErrMalformed
+	}
+
+	for i := uint32(0); i < count; i++ {
+		var length uint32
+		if err := binary.Read(reader, binary.LittleEndian, &length); err != nil {
+			return tags, ErrMalformed
+		}
+		if int64(length) > int64(reader.Len()) {
+			return tags, ErrMalformed
+		}
+		comment := make([]byte, length)
+		if _, err := io.ReadFull(reader, comment); err != nil {
+			return tags, ErrMalformed
+		}
+
+		key, value, found := strings.Cut(string(comment), "=")
+		if !found {
+			continue
+		}
+		applyVorbisComment(&tags, strings.ToUpper(key), trimText(value))
+	}
+
+	return tags, nil
+}
+
+func applyVorbisComment(tags *Tags, key, value string) {
+	if value == "" {
+		return
+	}
+
+	// The first occurrence of a field wins
+	switch key {
+	case "TITLE":
+		if tags.Title == "" {
+			tags.Title = value
+		}
+	case "ARTIST":
+		if tags.Artist == "" {
+			tags.Artist = value
+		}
+	case "ALBUM":
+		if tags.Album == "" {
+			tags.Album = value
+		}
+	case "ALBUMARTIST", "ALBUM ARTIST":
+		if tags.AlbumArtist == "" {
+			tags.AlbumArtist = value
+		}
+	case "GENRE":
+		if tags.Genre == "" {
+			tags.Genre = value
+		}
+	case "DATE", "YEAR", "ORIGINALDATE":
+		if tags.Year == 0 {
+			tags.Year = parseYear(value)
+		}
+	case "TRACKNUMBER":
+		number, total := parsePosition(value)
+		tags.TrackNumber = number
+		if total > 0 {
+			tags.TrackTotal = total
+		}
+	case "TRACKTOTAL", "TOTALTRACKS":
+		tags.TrackTotal, _ = parsePosition(value)
+	case "DISCNUMBER":
+		number, total := parsePosition(value)
+		tags.DiscNumber = number
+		if total > 0 {
+			tags.DiscTotal = total
+		}
+	case "DISCTOTAL", "TOTALDISCS":
+		tags.DiscTotal, _ = parsePosition(value)
+	case "METADATA_BLOCK_PICTURE":
+		// A base64 encoded FLAC picture block
+		if block, err := base64.StdEncoding.DecodeString(value); err == nil {
+			if picture, ok := parseFLACPicture(block); ok {
+				tags.addPicture(picture)
+			}
+		}
+	case "COVERART":
+		// The older unofficial field holds the bare image
+		if data, err := base64.StdEncoding.DecodeString(value); err == nil {
+			tags.addPicture(Picture{Data: data})
+		}
+	}
+}
+
+// parseFLACPicture decodes a FLAC PICTURE metadata block
+func parseFLACPicture(block []byte) (Picture, bool) {
+	var picture Picture
+	field := func(n int) ([]byte, bool) {
+		if n < 0 || n > len(block) {
+			return nil, false
+		}
+		value := block[:n]
+		block = block[n:]
+		return value, true
+	}
+	length := func() (int, bool) {
+		value, ok := field(4)
+		if !ok {
+			return 0, false
+		}
+		return int(binary.BigEndian.Uint32(value)), true
+	}
+
+	pictureType, ok := length()
+	if !ok {
+		return picture, false
+	}
+	picture.Type = pictureType
+	mimeLength, ok := length()
+	if !ok {
+		return picture, false
+	}
+	mimeType, ok := field(mimeLength)
+	if !ok {
+		return picture, false
+	}
+	picture.MIMEType = string(mimeType)
+	descriptionLength, ok := length()
+	if !ok {
+		return picture, false
+	}
+	// Skip the description, then the width, height, colour depth and palette size
+	if _, ok := field(descriptionLength); !ok {
+		return picture, false
+	}
+	if _, ok := field(16); !ok {
+		return picture, false
+	}
+	dataLength, ok := length()
+	if !ok {
+		return picture, false
+	}
+	if picture.Data, ok = field(dataLength); !ok {
+		return picture, false
+	}
+	return picture, len(picture.Data) > 0
+}
+
+// readFLAC walks the FLAC metadata blocks starting at offset
+func readFLAC(r io.ReadSeeker, offset int64) (Tags, error) {
+	tags := Tags{Format: "flac"}
+	position := offset + 4
+
+	for {
+		header, err := readAt(r, position, 4)
+		if err != nil {
+			return Tags{}, ErrMalformed
+		}
+		last := header[0]&0x80 != 0
+		blockType := header[0] & 0x7F
+		length := int(header[1])<<16 | int(header[2])<<8 | int(header[3])
+		position += 4
+
+		switch blockType {
+		case 0: // STREAMINFO
+			block, err := readAt(r, position, length)
+			if err != nil || len(block) < 18 {
+				return Tags{}, ErrMalformed
+			}
+			sampleRate := int64(block[10])<<12 | int64(block[11])<<4 | int64(block[12])>>4
+			totalSamples := int64(block[13]&0x0F)<<32 | int64(binary.BigEndian.Uint32(block[14:18]))
+			if sampleRate > 0 {
+				tags.Duration = time.Duration(totalSamples) * time.Second / time.Duration(sampleRate)
+			}
+		case 4: // VORBIS_COMMENT
+			block, err := readAt(r, position, length)
+			if err != nil {
+				return Tags{}, ErrMalformed
+			}
+			comments, err := parseVorbisComments(block)
+			if err != nil {
+				return Tags{}, err
+			}
+			duration := tags.Duration
+			comments.merge(tags)
+			tags = comments
+			tags.Format = "flac"
+			tags.Duration = duration
+		case 6: // PICTURE
+			block, err := readAt(r, position, length)
+			if err != nil {
+				return Tags{}, ErrMalformed
+			}
+			if picture, ok := parseFLACPicture(block); ok {
+				tags.addPicture(picture)
+			}
+		}
+
+		position += int64(length)
+		if last {
+			break
+		}
+	}
+
+	return tags, nil
+}
@@ -0,0 +1,137 @@
+package metadata_test
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/tushar27x/music-lib-api/metadata"
+)
+
+// vorbisComments builds a Vorbis comment block
+func vorbisComments(comments ...string) []byte {
+	block := join(le32(4), "test", le32(len(comments)))
+	for _, comment := range comments {
+		block = join(block, le32(len(comment)), comment)
+	}
+	return block
+}
+
+// flacPicture builds a FLAC PICTURE block
+func flacPicture(pictureType int, mimeType, data string) []byte {
+	return join(be32(pictureType), be32(len(mimeType)), mimeType, be32(5), "cover", make([]byte, 16), be32(len(data)), data)
+}
+
+// streamInfo builds a FLAC STREAMINFO block of a 16 bit stereo stream
+func streamInfo(sampleRate, totalSamples int) []byte {
+	block := make([]byte, 34)
+	block[10] = byte(sampleRate >> 12)
+	block[11] = byte(sampleRate >> 4)
+	block[12] = byte(sampleRate<<4) | 1<<1
+	block[13] = 0xF0 | byte(totalSamples>>32&0x0F)
+	copy(block[14:18], be32(totalSamples))
+	return block
+}
+
+func flacBlock(last bool, blockType byte, data []byte) []byte {
+	if last {
+		blockType |= 0x80
+	}
+	n := len(data)
+	return join(blockType, []byte{byte(n >> 16), byte(n >> 8), byte(n)}, data)
+}
+
+func flacFixtures() []fixture {
+	flac := func(blocks ...any) []byte {
+		return join(append([]any{"fLaC"}, append(blocks, make([]byte, 64))...)...)
+	}
+	return []fixture{
+		{
+			name: "flac",
+			data: flac(
+				flacBlock(false, 0, streamInfo(44100, 441000)),
+				flacBlock(false, 4, vorbisComments(
+					"TITLE=So What",
+					"artist=Miles Davis",
+					"ALBUM=Kind of Blue",
+					"DATE=1959-08-17",
+					"TRACKNUMBER=1",
+					"TRACKTOTAL=5",
+					"DISCNUMBER=1/1",
+					"GENRE=Jazz",
+					// The first occurrence wins and comments without a value are ignored
+					"TITLE=Freddie Freeloader",
+					"NOVALUE",
+				)),
+				flacBlock(false, 1, make([]byte, 10)),
+				flacBlock(true, 6, flacPicture(metadata.PictureFrontCover, "image/png", "png-data")),
+			),
+			want: metadata.Tags{
+				Format: "flac", Title: "So What", Artist: "Miles Davis", Album: "Kind of Blue", Genre: "Jazz", Year: 1959,
+				TrackNumber: 1, TrackTotal: 5, DiscNumber: 1, DiscTotal: 1, Duration: 10 * time.Second,
+				Picture: &metadata.Picture{MIMEType: "image/png", Type: metadata.PictureFrontCover, Data: []byte("png-data")},
+			},
+		},
+		{
+			name: "ID3v2 before FLAC",
+			data: join(
+				id3Tag(3, 0, id3Frame(3, "TIT2", byte(0), "Ignored")),
+				flac(
+					flacBlock(false, 0, streamInfo(48000, 96000)),
+					flacBlock(true, 4, vorbisComments("TITLE=Blue in Green")),
+				),
+			),
+			want: metadata.Tags{Format: "flac", Title: "Blue in Green", Duration: 2 * time.Second},
+		},
+		{
+			name: "pictures in the comments",
+			data: flac(flacBlock(true, 4, vorbisComments(
+				"COVERART="+base64.StdEncoding.EncodeToString([]byte("bare-image")),
+				"METADATA_BLOCK_PICTURE="+base64.StdEncoding.EncodeToString(flacPicture(metadata.PictureFrontCover, "image/jpeg", "jpeg-data")),
+			))),
+			want: metadata.Tags{
+				Format:  "flac",
+				Picture: &metadata.Picture{MIMEType: "image/jpeg", Type: metadata.PictureFrontCover, Data: []byte("jpeg-data")},
+			},
+		},
+		{
+			name: "picture longer than its block",
+			data: flac(flacBlock(true, 6, join(be32(3), be32(9), "image/png", be32(0), make([]byte, 16), be32(1000), "png"))),
+			want: metadata.Tags{Format: "flac"},
+		},
+		{
+			name: "vendor longer than the block",
+			data: flac(flacBlock(true, 4, join(le32(1000), "test", le32(0)))),
+			err:  metadata.ErrMalformed,
+		},
+		{
+			name: "more comments than the block holds",
+			data: flac(flacBlock(true, 4, join(le32(0), le32(1000), le32(5), "A=bcd"))),
+			err:  metadata.ErrMalformed,
+		},
+		{
+			name: "comment longer than the block",
+			data: flac(flacBlock(true, 4, join(le32(0), le32(1), le32(0xFFFFFFFF), "TITLE=x"))),
+			err:  metadata.ErrMalformed,
+		},
+		{
+			name: "block longer than the file",
+			data: join("fLaC", flacBlock(false, 0, streamInfo(44100, 441000)), []byte{0x84, 0xFF, 0xFF, 0xFF}, "TITLE"),
+			err:  metadata.ErrMalformed,
+		},
+		{
+			name: "short stream info",
+			data: flac(flacBlock(true, 0, make([]byte, 10))),
+			err:  metadata.ErrMalformed,
+		},
+		{
+			name: "no last block",
+			data: join("fLaC", flacBlock(false, 0, streamInfo(44100, 441000)), make([]byte, 10)),
+			err:  metadata.ErrMalformed,
+		},
+	}
+}
+
+func TestReadFLAC(t *testing.T) {
+	checkFixtures(t, flacFixtures())
+}
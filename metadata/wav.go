@@ -0,0 +1,85 @@
+package metadata
+
+import (
+	"encoding/binary"
+	"io"
+	"time"
+)
+
+// readWAV reads the fmt and data chunks for the duration and a LIST/INFO chunk for tags
+func readWAV(r io.ReadSeeker, size int64) (Tags, error) {
+	tags := Tags{Format: "wav"}
+
+	var byteRate uint32
+	var dataSize int64
+	position := int64(12)
+
+	for position+8 <= size {
+		header, err := readAt(r, position, 8)
+		if err != nil {
+			return Tags{}, ErrMalformed
+		}
+		chunkId := string(header[0:4])
+		chunkSize := int64(binary.LittleEndian.Uint32(header[4:8]))
+		position += 8
+
+		switch chunkId {
+		case "fmt ":
+			chunk, err := readAt(r, position, int(chunkSize))
+			if err != nil || len(chunk) < 12 {
+				return Tags{}, ErrMalformed
+			}
+			byteRate = binary.LittleEndian.Uint32(chunk[8:12])
+		case "data":
+			dataSize = chunkSize
+			if position+dataSize > size {
+				dataSize = size - position
+			}
+		case "LIST":
+			chunk, err := readAt(r, position, int(chunkSize))
+			if err == nil && len(chunk) >= 4 && string(chunk[0:4]) == "INFO" {
+				parseRIFFInfo(&tags, chunk[4:])
+			}
+		}
+
+		// Chunks are padded to an even size
+		position += chunkSize + chunkSize%2
+	}
+
+	if byteRate > 0 && dataSize > 0 {
+		tags.Duration = time.Duration(dataSize) * time.Second / time.Duration(byteRate)
+	}
+	return tags, nil
+}
+
+func parseRIFFInfo(tags *Tags, data []byte) {
+	for len(data) >= 8 {
+		id := string(data[0:4])
+		size := int(binary.LittleEndian.Uint32(data[4:8]))
+		if 8+size > len(data) {
+			return
+		}
+		value := trimText(string(data[8 : 8+size]))
+
+		switch id {
+		case "INAM":
+			tags.Title = value
+		case "IART":
+			tags.Artist = value
+		case "IPRD":
+			tags.Album = value
+		case "IGNR":
+			tags.Genre = value
+		case "ICRD":
+			tags.Year = parseYear(value)
+		case "ITRK", "IPRT":
+			tags.TrackNumber, tags.TrackTotal = parsePosition(value)
+		}
+
+		next := 8 + size + size%2
+		if next > len(data) {
+			return
+		}
+		data = data[next:]
+	}
+}
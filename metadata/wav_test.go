@@ -0,0 +1,87 @@
+package metadata_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tushar27x/music-lib-api/metadata"
+)
+
+// riffChunk builds a chunk, padded to an even size
+func riffChunk(id string, payload ...any) []byte {
+	data := join(payload...)
+	chunk := join(id, le32(len(data)), data)
+	if len(data)%2 == 1 {
+		chunk = append(chunk, 0)
+	}
+	return chunk
+}
+
+func wav(chunks ...any) []byte {
+	body := join(chunks...)
+	return join("RIFF", le32(4+len(body)), "WAVE", body)
+}
+
+// wavFormat builds the fmt chunk of 8 kHz 8 bit mono PCM, 8000 bytes a second
+func wavFormat() []byte {
+	return riffChunk("fmt ", []byte{1, 0, 1, 0}, le32(8000), le32(8000), []byte{1, 0, 8, 0})
+}
+
+func wavFixtures() []fixture {
+	return []fixture{
+		{
+			name: "wav",
+			data: wav(
+				wavFormat(),
+				riffChunk("LIST", "INFO",
+					// Odd sized values are padded
+					riffChunk("INAM", "Clair de lune"),
+					riffChunk("IART", "Debussy"),
+					riffChunk("IPRD", "Suite bergamasque"),
+					riffChunk("ICRD", "1905"),
+					riffChunk("ITRK", "3/4"),
+					riffChunk("IGNR", "Classical"),
+				),
+				riffChunk("data", make([]byte, 16000)),
+			),
+			want: metadata.Tags{
+				Format: "wav", Title: "Clair de lune", Artist: "Debussy", Album: "Suite bergamasque", Year: 1905,
+				TrackNumber: 3, TrackTotal: 4, Genre: "Classical", Duration: 2 * time.Second,
+			},
+		},
+		{
+			name: "data longer than the file",
+			// Recordings that were cut off are measured by what is there
+			data: wav(wavFormat(), "data", le32(1000000), make([]byte, 8000)),
+			want: metadata.Tags{Format: "wav", Duration: time.Second},
+		},
+		{
+			name: "info value longer than the list",
+			data: wav(
+				wavFormat(),
+				riffChunk("LIST", "INFO", riffChunk("INAM", "Kept"), "IART", le32(1000), "Lost"),
+				riffChunk("data", make([]byte, 8000)),
+			),
+			want: metadata.Tags{Format: "wav", Title: "Kept", Duration: time.Second},
+		},
+		{
+			name: "list longer than the file",
+			data: wav(wavFormat(), riffChunk("data", make([]byte, 8000)), "LIST", le32(1000), "INFO"),
+			want: metadata.Tags{Format: "wav", Duration: time.Second},
+		},
+		{
+			name: "short format",
+			data: wav(riffChunk("fmt ", []byte{1, 0, 1, 0}), riffChunk("data", make([]byte, 8000))),
+			err:  metadata.ErrMalformed,
+		},
+		{
+			name: "format longer than the file",
+			data: wav("fmt ", le32(0xFFFFFFFF), []byte{1, 0, 1, 0}),
+			err:  metadata.ErrMalformed,
+		},
+	}
+}
+
+func TestReadWAV(t *testing.T) {
+	checkFixtures(t, wavFixtures())
+}
@@ -1,50 +1,83 @@
 package middlewares
 
 import (
+	"errors"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt"
-	"github.com/tushar27x/music-lib-api/config"
-	"github.com/tushar27x/music-lib-api/models"
+	"github.com/tushar27x/music-lib-api/services"
 )
 
-var jwtSecret = []byte(config.GetEnv("JWT_SECRET"))
+// AuthMiddleware authenticates requests with an access token. API keys are only accepted on routes
+// that name the resources they serve: the key needs the read scope of every resource for GET and
+// HEAD requests and the write scope otherwise.
+func AuthMiddleware(auth *services.AuthService, resources ...string) gin.HandlerFunc {
+	return authenticate(auth, false, resources)
+}
+
+// PasswordChangeMiddleware is AuthMiddleware that also lets through users who have to change
+// their password, so the password change endpoint stays reachable for them
+func PasswordChangeMiddleware(auth *services.AuthService) gin.HandlerFunc {
+	return authenticate(auth, true, nil)
+}
 
-func AuthMiddleware() gin.HandlerFunc {
+func authenticate(auth *services.AuthService, allowPasswordReset bool, resources []string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization Header missing"})
-			c.Abort()
-			return
+		// Scripts can send an API key in its own header or like a token
+		tokenString := c.GetHeader("X-API-Key")
+		if tokenString == "" {
+			authHeader := c.GetHeader("Authorization")
+			if authHeader == "" {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization Header missing"})
+				c.Abort()
+				return
+			}
+			tokenString = strings.TrimPrefix(authHeader, "Bearer ")
 		}
 
-		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			return jwtSecret, nil
-		})
-
-		if err != nil || !token.Valid {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invaid token"})
+		claims, err := auth.Authenticate(tokenString)
+		if errors.Is(err, services.ErrPasswordResetRequired) && allowPasswordReset {
+			err = nil
+		}
+		if err != nil {
+			switch {
+			case errors.Is(err, services.ErrUserNotFound):
+				c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			case errors.Is(err, services.ErrAccountSuspended):
+				c.JSON(http.StatusForbidden, gin.H{"error": "Account is suspended"})
+			case errors.Is(err, services.ErrPasswordResetRequired):
+				c.JSON(http.StatusForbidden, gin.H{"error": "Password change required, use PUT /api/auth/password"})
+			case errors.Is(err, services.ErrInvalidAPIKey):
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key"})
+			default:
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Invaid token"})
+			}
 			c.Abort()
 			return
 		}
 
-		claims := token.Claims.(jwt.MapClaims)
-		userId := uint(claims["user_id"].(float64))
-		role := string(claims["role"].(string))
-
-		var user models.User
-
-		if err := config.DB.First(&user, userId).Error; err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
-			return
+		if claims.APIKeyId != 0 {
+			if len(resources) == 0 {
+				c.JSON(http.StatusForbidden, gin.H{"error": "API keys cannot be used for this endpoint"})
+				c.Abort()
+				return
+			}
+			write := c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead
+			if !services.APIKeyAllows(claims.Scopes, write, resources...) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "API key is missing a required scope"})
+				c.Abort()
+				return
+			}
+			c.Set("apiKeyId", claims.APIKeyId)
+		} else {
+			c.Set("jti", claims.Id)
+			c.Set("tokenExpiresAt", time.Unix(claims.ExpiresAt, 0))
 		}
 
-		c.Set("userId", userId)
-		c.Set("role", role)
+		c.Set("userId", claims.UserId)
+		c.Set("role", claims.Role)
 		c.Next()
 	}
 
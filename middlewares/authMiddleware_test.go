@@ -0,0 +1,127 @@
+package middlewares_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tushar27x/music-lib-api/middlewares"
+	"github.com/tushar27x/music-lib-api/models"
+	"github.com/tushar27x/music-lib-api/repositories"
+	"github.com/tushar27x/music-lib-api/services"
+)
+
+func TestAuthMiddlewareScopesAPIKeys(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("JWT_SECRET", "test-secret")
+	store := repositories.NewMemoryStore()
+	tokens := services.NewTokenService(store)
+	apiKeys := services.NewAPIKeyService(store)
+	auth := services.NewAuthService(store, tokens, apiKeys, services.NewLoginThrottleService(store))
+
+	user := models.User{Name: "Ada", Email: "ada@example.com", Role: models.RoleArtist}
+	if err := store.Users().Create(&user); err != nil {
+		t.Fatal(err)
+	}
+	issued, err := tokens.IssueTokens(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newKey := func(scopes ...string) (models.APIKey, string) {
+		t.Helper()
+		key, rawKey, err := apiKeys.Create(user.ID, models.APIKeyCreateRequest{Name: "CI", Scopes: scopes})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return key, rawKey
+	}
+	_, readSongs := newKey(models.ScopeSongsRead)
+	_, writeSongs := newKey(models.ScopeSongsWrite)
+	_, readBoth := newKey(models.ScopeSongsRead, models.ScopeAlbumsRead)
+	revokedKey, revoked := newKey(models.ScopeSongsRead)
+	if _, err := apiKeys.Revoke(user.ID, revokedKey.ID); err != nil {
+		t.Fatal(err)
+	}
+	expiringKey, expired := newKey(models.ScopeSongsRead)
+	past := time.Now().Add(-time.Second)
+	expiringKey.ExpiresAt = &past
+	if err := store.APIKeys().Update(&expiringKey, "expires_at"); err != nil {
+		t.Fatal(err)
+	}
+
+	ok := func(c *gin.Context) {
+		if _, viaKey := c.Get("apiKeyId"); viaKey {
+			c.String(http.StatusOK, "key")
+			return
+		}
+		c.String(http.StatusOK, "token")
+	}
+	router := gin.New()
+	songs := middlewares.AuthMiddleware(auth, models.ResourceSongs)
+	router.GET("/songs", songs, ok)
+	router.HEAD("/songs", songs, ok)
+	router.POST("/songs", songs, ok)
+	router.DELETE("/songs", songs, ok)
+	router.GET("/search", middlewares.AuthMiddleware(auth, models.ResourceSongs, models.ResourceAlbums), ok)
+	router.GET("/account", middlewares.AuthMiddleware(auth), ok)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		header string
+		value  string
+		want   int
+		body   string
+	}{
+		{name: "no credentials", method: http.MethodGet, path: "/songs", want: http.StatusUnauthorized},
+		{name: "access token", method: http.MethodPost, path: "/songs", header: "Authorization", value: "Bearer " + issued.Token, want: http.StatusOK, body: "token"},
+		{name: "access token without resources", method: http.MethodGet, path: "/account", header: "Authorization", value: "Bearer " + issued.Token, want: http.StatusOK, body: "token"},
+		{name: "made-up token", method: http.MethodGet, path: "/songs", header: "Authorization", value: "Bearer nope", want: http.StatusUnauthorized},
+		{name: "read key reads", method: http.MethodGet, path: "/songs", header: "Authorization", value: "Bearer " + readSongs, want: http.StatusOK, body: "key"},
+		{name: "read key in its own header", method: http.MethodGet, path: "/songs", header: "X-API-Key", value: readSongs, want: http.StatusOK, body: "key"},
+		{name: "read key on HEAD", method: http.MethodHead, path: "/songs", header: "X-API-Key", value: readSongs, want: http.StatusOK},
+		{name: "read key writes", method: http.MethodPost, path: "/songs", header: "X-API-Key", value: readSongs, want: http.StatusForbidden},
+		{name: "read key deletes", method: http.MethodDelete, path: "/songs", header: "X-API-Key", value: readSongs, want: http.StatusForbidden},
+		{name: "write key writes", method: http.MethodPost, path: "/songs", header: "X-API-Key", value: writeSongs, want: http.StatusOK, body: "key"},
+		{name: "write key reads", method: http.MethodGet, path: "/songs", header: "X-API-Key", value: writeSongs, want: http.StatusForbidden},
+		{name: "key missing one of the resources", method: http.MethodGet, path: "/search", header: "X-API-Key", value: readSongs, want: http.StatusForbidden},
+		{name: "key with every resource", method: http.MethodGet, path: "/search", header: "X-API-Key", value: readBoth, want: http.StatusOK, body: "key"},
+		{name: "key on a route without resources", method: http.MethodGet, path: "/account", header: "X-API-Key", value: readBoth, want: http.StatusForbidden},
+		{name: "revoked key", method: http.MethodGet, path: "/songs", header: "X-API-Key", value: revoked, want: http.StatusUnauthorized},
+		{name: "expired key", method: http.MethodGet, path: "/songs", header: "X-API-Key", value: expired, want: http.StatusUnauthorized},
+		{name: "unknown key", method: http.MethodGet, path: "/songs", header: "X-API-Key", value: services.APIKeyPrefix + "made-up", want: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body.String())
+			}
+			if tt.body != "" && w.Body.String() != tt.body {
+				t.Errorf("authenticated by %q, want %q", w.Body.String(), tt.body)
+			}
+		})
+	}
+
+	// Keys stop working with their account
+	now := time.Now()
+	user.SuspendedAt = &now
+	if err := store.Users().Update(&user, "suspended_at"); err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/songs", nil)
+	req.Header.Set("X-API-Key", readSongs)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("key of a suspended account = %d, want 403", w.Code)
+	}
+}
@@ -0,0 +1,60 @@
+package middlewares
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tushar27x/music-lib-api/ratelimit"
+)
+
+// RateLimit answers 429 once a client has sent more than limit requests to the routes named
+// name. Clients are counted by user once AuthMiddleware has run and by IP address before that.
+// Every response carries RateLimit-* headers; with no store nothing is limited. When the store
+// fails the request is let through, so an outage of the counters does not take the API down.
+func RateLimit(store ratelimit.Store, name string, limit ratelimit.Limit) gin.HandlerFunc {
+	if store == nil {
+		return func(c *gin.Context) {
+			c.Next()
+		}
+	}
+
+	policy := limit.Policy()
+	return func(c *gin.Context) {
+		key := name + ":ip:" + c.ClientIP()
+		if userId, ok := c.Get("userId"); ok {
+			key = fmt.Sprintf("%s:user:%v", name, userId)
+		}
+
+		result, err := ratelimit.Allow(c.Request.Context(), store, key, limit, time.Now())
+		if err != nil {
+			log.Printf("Rate limiter unavailable, letting request through: %v", err)
+			c.Next()
+			return
+		}
+
+		reset := strconv.FormatInt(seconds(result.Reset), 10)
+		c.Header("RateLimit-Limit", strconv.FormatInt(limit.Requests, 10))
+		c.Header("RateLimit-Remaining", strconv.FormatInt(result.Remaining, 10))
+		c.Header("RateLimit-Reset", reset)
+		c.Header("RateLimit-Policy", policy)
+
+		if !result.Allowed {
+			c.Header("Retry-After", strconv.FormatInt(max(seconds(result.RetryAfter), 1), 10))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests, please try again later"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// seconds rounds d up to whole seconds as the headers count them
+func seconds(d time.Duration) int64 {
+	return int64(math.Ceil(d.Seconds()))
+}
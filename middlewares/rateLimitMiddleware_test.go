@@ -0,0 +1,61 @@
+package middlewares_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tushar27x/music-lib-api/middlewares"
+	"github.com/tushar27x/music-lib-api/ratelimit"
+)
+
+func TestRateLimitCountsByIPBeforeAuthAndByUserAfter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := ratelimit.NewMemoryStore()
+	// Stands in for AuthMiddleware, taking the user from a header
+	auth := func(c *gin.Context) {
+		userId, err := strconv.ParseUint(c.GetHeader("X-User"), 10, 64)
+		if err != nil {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		c.Set("userId", uint(userId))
+	}
+	router := gin.New()
+	router.GET("/",
+		middlewares.RateLimit(store, "ip", ratelimit.Limit{Requests: 3, Window: time.Minute}),
+		auth,
+		middlewares.RateLimit(store, "api", ratelimit.Limit{Requests: 2, Window: time.Minute}),
+		func(c *gin.Context) { c.Status(http.StatusOK) },
+	)
+	get := func(ip string, user string) int {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = ip + ":1234"
+		req.Header.Set("X-User", user)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	for i, want := range []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests} {
+		if got := get("10.0.0.1", "1"); got != want {
+			t.Errorf("request %d of user 1 = %d, want %d", i+1, got, want)
+		}
+	}
+	// The address has used up its 3 requests, whoever sends the next one, even without credentials
+	if got := get("10.0.0.1", "2"); got != http.StatusTooManyRequests {
+		t.Errorf("request of user 2 from the same address = %d, want 429", got)
+	}
+	if got := get("10.0.0.1", ""); got != http.StatusTooManyRequests {
+		t.Errorf("unauthenticated request from the same address = %d, want 429", got)
+	}
+	if got := get("10.0.0.2", "2"); got != http.StatusOK {
+		t.Errorf("request of user 2 from another address = %d, want 200", got)
+	}
+	if got := get("10.0.0.3", ""); got != http.StatusUnauthorized {
+		t.Errorf("unauthenticated request from another address = %d, want 401", got)
+	}
+}
@@ -0,0 +1,35 @@
+package middlewares
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tushar27x/music-lib-api/models"
+	"github.com/tushar27x/music-lib-api/services"
+)
+
+// StreamAuthMiddleware accepts either a signed stream URL (expires + signature
+// query parameters) or a regular bearer token or API key. Signed URLs let <audio src>
+// tags, which cannot send an Authorization header, play a song.
+func StreamAuthMiddleware(auth *services.AuthService) gin.HandlerFunc {
+	authMiddleware := AuthMiddleware(auth, models.ResourceSongs)
+
+	return func(c *gin.Context) {
+		signature := c.Query("signature")
+		if signature == "" {
+			authMiddleware(c)
+			return
+		}
+
+		songId, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil || !services.VerifyStreamSignature(uint(songId), c.Query("expires"), signature) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired stream URL"})
+			c.Abort()
+			return
+		}
+
+		c.Set("streamSongId", uint(songId))
+		c.Next()
+	}
+}
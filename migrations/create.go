@@ -0,0 +1,52 @@
+package migrations
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Dir is where the migration files live in the source tree
+const Dir = "migrations/sql"
+
+var nameCleaner = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Create writes an empty up and down file for the next version into the directory of every
+// dialect below dir and returns their paths. The files are embedded on the next build.
+func Create(dir string, name string) ([]string, error) {
+	name = strings.Trim(nameCleaner.ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if name == "" {
+		return nil, fmt.Errorf("migration name must contain letters or digits")
+	}
+
+	// Versions must line up across dialects, so the next one follows the highest anywhere
+	version := uint(1)
+	for _, dialect := range Dialects {
+		existing, err := Load(os.DirFS(filepath.Join(dir, dialect)), ".")
+		if err != nil {
+			return nil, err
+		}
+		if len(existing) > 0 && existing[len(existing)-1].Version >= version {
+			version = existing[len(existing)-1].Version + 1
+		}
+	}
+
+	header := "-- Migration " + strconv.FormatUint(uint64(version), 10) + ": " + strings.ReplaceAll(name, "_", " ") + "\n"
+	var created []string
+	for _, dialect := range Dialects {
+		base := filepath.Join(dir, dialect, fmt.Sprintf("%04d_%s", version, name))
+		for _, file := range []string{base + ".up.sql", base + ".down.sql"} {
+			if err := os.WriteFile(file, []byte(header), 0o644); err != nil {
+				for _, done := range created {
+					os.Remove(done)
+				}
+				return nil, err
+			}
+			created = append(created, file)
+		}
+	}
+	return created, nil
+}
@@ -0,0 +1,410 @@
+// Package migrations applies the numbered SQL files in sql/ to the database.
+//
+// Every change to the schema is a pair of files, NNNN_name.up.sql and NNNN_name.down.sql,
+// embedded into the binary. Each supported database has its own directory, sql/postgres and
+// sql/sqlite, with the same versions in both. Applied versions are recorded in the
+// schema_migrations table, and on Postgres an advisory lock makes sure only one process
+// migrates at a time.
+package migrations
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+//go:embed sql/postgres/*.sql sql/sqlite/*.sql
+var files embed.FS
+
+// lockKey identifies the advisory lock held while migrating; it spells "musiclib"
+const lockKey int64 = 0x6d757369636c6962
+
+// dialect holds what differs between the supported databases
+type dialect struct {
+	// lock and unlock take and release a lock shared by every process, empty when there is none
+	lock   string
+	unlock string
+	// schemaTable creates the schema_migrations table
+	schemaTable string
+	// foreignKeysOff and foreignKeysOn switch foreign key enforcement outside a transaction, and
+	// foreignKeyCheck lists violations; empty when migrations never need to switch it
+	foreignKeysOff  string
+	foreignKeysOn   string
+	foreignKeyCheck string
+}
+
+// dialects are keyed by gorm.Dialector.Name, which is also the directory of their migrations
+var dialects = map[string]dialect{
+	"postgres": {
+		lock:   "SELECT pg_advisory_lock(?)",
+		unlock: "SELECT pg_advisory_unlock(?)",
+		schemaTable: `CREATE TABLE IF NOT EXISTS schema_migrations (
+			version bigint PRIMARY KEY,
+			name text NOT NULL,
+			dirty boolean NOT NULL DEFAULT false,
+			applied_at timestamptz NOT NULL
+		)`,
+	},
+	// A SQLite database belongs to a single process, and its connection pool is limited
+	// to one connection, so there is nobody to race with
+	"sqlite": {
+		schemaTable: `CREATE TABLE IF NOT EXISTS schema_migrations (
+			version integer PRIMARY KEY,
+			name text NOT NULL,
+			dirty boolean NOT NULL DEFAULT false,
+			applied_at datetime NOT NULL
+		)`,
+		foreignKeysOff:  "PRAGMA foreign_keys = OFF",
+		foreignKeysOn:   "PRAGMA foreign_keys = ON",
+		foreignKeyCheck: "PRAGMA foreign_key_check",
+	},
+}
+
+// foreignKeysOffDirective starts the scripts that rebuild a table other tables refer to. SQLite
+// can't alter most of a table in place, and dropping or renaming a referenced table breaks or
+// rewrites the references while foreign keys are enforced, so such scripts run without them and
+// the foreign keys are checked before the transaction commits.
+const foreignKeysOffDirective = "-- migrate: foreign keys off"
+
+// Dialects lists the databases with migrations, matching the directories in sql/
+var Dialects = []string{"postgres", "sqlite"}
+
+var fileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+var (
+	// ErrDirty is returned when a migration was interrupted and the schema needs to be checked by hand
+	ErrDirty = errors.New("database schema is dirty")
+	// ErrPending is returned when migrations exist that have not been applied
+	ErrPending = errors.New("database schema is out of date")
+)
+
+// Migration is one numbered schema change
+type Migration struct {
+	Version uint
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus reports whether a migration has been applied
+type MigrationStatus struct {
+	Migration
+	Applied   bool
+	Dirty     bool
+	AppliedAt *time.Time
+	// Unknown is set for versions recorded in the database that this binary doesn't ship
+	Unknown bool
+}
+
+// schemaMigration is a row of the schema_migrations table
+type schemaMigration struct {
+	Version   uint
+	Name      string
+	Dirty     bool
+	AppliedAt time.Time
+}
+
+// Migrator applies and rolls back migrations on one database
+type Migrator struct {
+	db         *gorm.DB
+	dialect    dialect
+	migrations []Migration
+}
+
+// New returns a Migrator for db using the embedded migrations of its database
+func New(db *gorm.DB) (*Migrator, error) {
+	name := db.Dialector.Name()
+	dialect, ok := dialects[name]
+	if !ok {
+		return nil, fmt.Errorf("no migrations for %s databases", name)
+	}
+
+	migrations, err := Load(files, path.Join("sql", name))
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, dialect: dialect, migrations: migrations}, nil
+}
+
+// Load reads the migrations in dir of fsys, ordered by version
+func Load(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[uint]*Migration{}
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("unexpected migration file name %q", entry.Name())
+		}
+		version, _ := strconv.ParseUint(match[1], 10, 32)
+		content, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[uint(version)]
+		if !ok {
+			migration = &Migration{Version: uint(version), Name: match[2]}
+			byVersion[uint(version)] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, migration.Name, match[2])
+		}
+		if match[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s needs both an up and a down file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Latest returns the highest version shipped with the binary
+func (m *Migrator) Latest() uint {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Up applies pending migrations in order, at most steps of them when steps is positive
+func (m *Migrator) Up(steps int) ([]Migration, error) {
+	var applied []Migration
+	err := m.locked(func(conn *gorm.DB) error {
+		rows, err := appliedMigrations(conn)
+		if err != nil {
+			return err
+		}
+		if err := checkDirty(rows); err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := rows[migration.Version]; ok {
+				continue
+			}
+			if steps > 0 && len(applied) == steps {
+				break
+			}
+			if err := apply(conn, m.dialect, migration, true); err != nil {
+				return err
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down rolls back the most recently applied migrations, one when steps is not positive
+func (m *Migrator) Down(steps int) ([]Migration, error) {
+	if steps <= 0 {
+		steps = 1
+	}
+
+	var reverted []Migration
+	err := m.locked(func(conn *gorm.DB) error {
+		rows, err := appliedMigrations(conn)
+		if err != nil {
+			return err
+		}
+		if err := checkDirty(rows); err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			migration := m.migrations[i]
+			if _, ok := rows[migration.Version]; !ok {
+				continue
+			}
+			if err := apply(conn, m.dialect, migration, false); err != nil {
+				return err
+			}
+			reverted = append(reverted, migration)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Status lists every known migration and whether it has been applied
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	rows, err := appliedMigrations(m.db)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := MigrationStatus{Migration: migration}
+		if row, ok := rows[migration.Version]; ok {
+			appliedAt := row.AppliedAt
+			status.Applied = true
+			status.Dirty = row.Dirty
+			status.AppliedAt = &appliedAt
+			delete(rows, migration.Version)
+		}
+		statuses = append(statuses, status)
+	}
+
+	// Versions applied by a newer release
+	for _, row := range rows {
+		appliedAt := row.AppliedAt
+		statuses = append(statuses, MigrationStatus{
+			Migration: Migration{Version: row.Version, Name: row.Name},
+			Applied:   true,
+			Dirty:     row.Dirty,
+			AppliedAt: &appliedAt,
+			Unknown:   true,
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+// Check returns ErrDirty or ErrPending unless every shipped migration has been applied cleanly.
+// Versions from a newer release are tolerated so older replicas keep serving during a rollout.
+func (m *Migrator) Check() error {
+	statuses, err := m.Status()
+	if err != nil {
+		return err
+	}
+
+	var pending []uint
+	for _, status := range statuses {
+		if status.Dirty {
+			return fmt.Errorf("%w: migration %d_%s did not finish", ErrDirty, status.Version, status.Name)
+		}
+		if !status.Applied {
+			pending = append(pending, status.Version)
+		}
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%w: migrations %v have not been applied", ErrPending, pending)
+	}
+	return nil
+}
+
+// locked runs fn on a single connection holding the migration lock, creating the
+// schema_migrations table first. Other replicas wait for the lock instead of racing.
+func (m *Migrator) locked(fn func(conn *gorm.DB) error) error {
+	return m.db.Connection(func(conn *gorm.DB) error {
+		if m.dialect.lock != "" {
+			if err := conn.Exec(m.dialect.lock, lockKey).Error; err != nil {
+				return err
+			}
+			defer conn.Exec(m.dialect.unlock, lockKey)
+		}
+
+		if err := conn.Exec(m.dialect.schemaTable).Error; err != nil {
+			return err
+		}
+		return fn(conn)
+	})
+}
+
+// apply runs one migration up or down in a transaction. The version is marked dirty before
+// the transaction starts, so a process that dies halfway leaves a trace for Check to find.
+func apply(conn *gorm.DB, dialect dialect, migration Migration, up bool) error {
+	script := migration.Down
+	direction := "down"
+	if up {
+		script = migration.Up
+		direction = "up"
+	}
+
+	foreignKeysOff := dialect.foreignKeysOff != "" && strings.HasPrefix(script, foreignKeysOffDirective)
+	if foreignKeysOff {
+		if err := conn.Exec(dialect.foreignKeysOff).Error; err != nil {
+			return err
+		}
+		defer conn.Exec(dialect.foreignKeysOn)
+	}
+
+	var err error
+	if up {
+		err = conn.Exec("INSERT INTO schema_migrations (version, name, dirty, applied_at) VALUES (?, ?, true, ?)", migration.Version, migration.Name, time.Now()).Error
+	} else {
+		err = conn.Exec("UPDATE schema_migrations SET dirty = true WHERE version = ?", migration.Version).Error
+	}
+	if err != nil {
+		return err
+	}
+
+	err = conn.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(script).Error; err != nil {
+			return err
+		}
+		if foreignKeysOff {
+			var violations []map[string]interface{}
+			if err := tx.Raw(dialect.foreignKeyCheck).Scan(&violations).Error; err != nil {
+				return err
+			}
+			if len(violations) > 0 {
+				return fmt.Errorf("%d foreign key violations, the first in %v", len(violations), violations[0]["table"])
+			}
+		}
+		if up {
+			return tx.Exec("UPDATE schema_migrations SET dirty = false, applied_at = ? WHERE version = ?", time.Now(), migration.Version).Error
+		}
+		return tx.Exec("DELETE FROM schema_migrations WHERE version = ?", migration.Version).Error
+	})
+	if err == nil {
+		return nil
+	}
+
+	// The transaction rolled back, so the schema is exactly as before and the marker can go
+	if up {
+		conn.Exec("DELETE FROM schema_migrations WHERE version = ?", migration.Version)
+	} else {
+		conn.Exec("UPDATE schema_migrations SET dirty = false WHERE version = ?", migration.Version)
+	}
+	return fmt.Errorf("migration %d_%s %s failed: %w", migration.Version, migration.Name, direction, err)
+}
+
+// appliedMigrations returns the rows of schema_migrations by version; none when the table doesn't exist yet
+func appliedMigrations(db *gorm.DB) (map[uint]schemaMigration, error) {
+	rows := map[uint]schemaMigration{}
+	if !db.Migrator().HasTable("schema_migrations") {
+		return rows, nil
+	}
+
+	var list []schemaMigration
+	if err := db.Table("schema_migrations").Order("version ASC").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range list {
+		rows[row.Version] = row
+	}
+	return rows, nil
+}
+
+func checkDirty(rows map[uint]schemaMigration) error {
+	for _, row := range rows {
+		if row.Dirty {
+			return fmt.Errorf("%w: migration %d_%s did not finish; fix the schema by hand and clear the dirty flag in schema_migrations", ErrDirty, row.Version, row.Name)
+		}
+	}
+	return nil
+}
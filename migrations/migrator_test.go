@@ -0,0 +1,233 @@
+package migrations_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/tushar27x/music-lib-api/config"
+	"github.com/tushar27x/music-lib-api/migrations"
+	"github.com/tushar27x/music-lib-api/repositories"
+	"github.com/tushar27x/music-lib-api/search"
+	"github.com/tushar27x/music-lib-api/services"
+	"gorm.io/gorm"
+)
+
+// baselineSchema is what GORM's AutoMigrate created for the models of the first release
+const baselineSchema = `
+CREATE TABLE users (
+	id bigserial PRIMARY KEY,
+	created_at timestamptz,
+	updated_at timestamptz,
+	deleted_at timestamptz,
+	name text,
+	email text,
+	password text,
+	role text,
+	CONSTRAINT uni_users_email UNIQUE (email)
+);
+CREATE INDEX idx_users_deleted_at ON users (deleted_at);
+
+CREATE TABLE albums (
+	id bigserial PRIMARY KEY,
+	created_at timestamptz,
+	updated_at timestamptz,
+	deleted_at timestamptz,
+	title text,
+	artist text,
+	year bigint,
+	user_id bigint,
+	CONSTRAINT fk_users_albums FOREIGN KEY (user_id) REFERENCES users (id),
+	CONSTRAINT uni_albums_title UNIQUE (title)
+);
+CREATE INDEX idx_albums_deleted_at ON albums (deleted_at);
+
+CREATE TABLE songs (
+	id bigserial PRIMARY KEY,
+	created_at timestamptz,
+	updated_at timestamptz,
+	deleted_at timestamptz,
+	title text,
+	duration bigint,
+	album_id bigint,
+	user_id bigint,
+	CONSTRAINT fk_albums_songs FOREIGN KEY (album_id) REFERENCES albums (id),
+	CONSTRAINT fk_users_songs FOREIGN KEY (user_id) REFERENCES users (id)
+);
+CREATE INDEX idx_songs_deleted_at ON songs (deleted_at);
+
+CREATE TABLE playlists (
+	id bigserial PRIMARY KEY,
+	created_at timestamptz,
+	updated_at timestamptz,
+	deleted_at timestamptz,
+	name text,
+	user_id bigint
+);
+CREATE INDEX idx_playlists_deleted_at ON playlists (deleted_at);
+
+CREATE TABLE playlist_songs (
+	playlist_id bigint,
+	song_id bigint,
+	PRIMARY KEY (playlist_id, song_id),
+	CONSTRAINT fk_playlist_songs_playlist FOREIGN KEY (playlist_id) REFERENCES playlists (id),
+	CONSTRAINT fk_playlist_songs_song FOREIGN KEY (song_id) REFERENCES songs (id)
+);
+
+INSERT INTO users (created_at, updated_at, name, email, password, role)
+VALUES (now(), now(), 'Ada', 'ada@example.com', 'x', 'artist');
+INSERT INTO albums (created_at, updated_at, title, artist, year, user_id)
+VALUES (now(), now(), 'Blue Train', 'John Coltrane', 1957, 1);
+INSERT INTO songs (created_at, updated_at, title, duration, album_id, user_id)
+VALUES (now(), now(), 'Moment''s Notice', 550000, 1, 1), (now(), now(), 'Locomotion', 430000, 1, 1);
+INSERT INTO playlists (created_at, updated_at, name, user_id) VALUES (now(), now(), 'Hard bop', 1);
+INSERT INTO playlist_songs (playlist_id, song_id) VALUES (1, 2), (1, 1);
+`
+
+// TestPostgresAdoptsBaselineSchema migrates a database created by the first release's AutoMigrate.
+// It needs a Postgres database in TEST_POSTGRES_URI and works in a schema of its own.
+func TestPostgresAdoptsBaselineSchema(t *testing.T) {
+	uri := os.Getenv("TEST_POSTGRES_URI")
+	if uri == "" {
+		t.Skip("TEST_POSTGRES_URI is not set")
+	}
+
+	db, err := config.OpenDB(uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// search_path is per connection
+	sqlDB.SetMaxOpenConns(1)
+	schema := fmt.Sprintf("adopt_%d", time.Now().UnixNano())
+	if err := db.Exec("CREATE SCHEMA " + schema).Error; err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.Exec("SET search_path TO public")
+		db.Exec("DROP SCHEMA " + schema + " CASCADE")
+		sqlDB.Close()
+	})
+	if err := db.Exec("SET search_path TO " + schema + ", public").Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Exec(baselineSchema).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	migrator, err := migrations.New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(0); err != nil {
+		t.Fatalf("migrating the baseline schema: %v", err)
+	}
+	if err := migrator.Check(); err != nil {
+		t.Fatal(err)
+	}
+	checkAdopted(t, db)
+}
+
+// TestSQLiteMigratesUpAndDown rolls every migration back one at a time down to an empty database,
+// then applies them again one at a time
+func TestSQLiteMigratesUpAndDown(t *testing.T) {
+	db, err := config.OpenDB("sqlite::memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrator, err := migrations.New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	applied, err := migrator.Up(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := migrator.Check(); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := len(applied) - 1; i >= 0; i-- {
+		reverted, err := migrator.Down(1)
+		if err != nil {
+			t.Fatalf("reverting %d_%s: %v", applied[i].Version, applied[i].Name, err)
+		}
+		if len(reverted) != 1 || reverted[0].Version != applied[i].Version {
+			t.Fatalf("reverted %v, want %d_%s", reverted, applied[i].Version, applied[i].Name)
+		}
+	}
+	if tables := sqliteTables(t, db); len(tables) != 0 {
+		t.Errorf("tables left after reverting every migration: %v", tables)
+	}
+	if err := migrator.Check(); !errors.Is(err, migrations.ErrPending) {
+		t.Errorf("Check() = %v, want ErrPending", err)
+	}
+
+	for _, migration := range applied {
+		if _, err := migrator.Up(1); err != nil {
+			t.Fatalf("applying %d_%s again: %v", migration.Version, migration.Name, err)
+		}
+	}
+	if err := migrator.Check(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// sqliteTables lists the tables, indexes, triggers and views of a SQLite database other than the migrator's own
+func sqliteTables(t *testing.T, db *gorm.DB) []string {
+	t.Helper()
+	var names []string
+	err := db.Raw(`SELECT name FROM sqlite_master
+		WHERE name NOT LIKE 'sqlite_%' AND tbl_name <> 'schema_migrations'
+		ORDER BY name`).Scan(&names).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	return names
+}
+
+// checkAdopted runs queries on the columns added since the first release over the rows it left behind
+func checkAdopted(t *testing.T, db *gorm.DB) {
+	t.Helper()
+	store := repositories.NewGormStore(db)
+
+	playlist, err := store.Playlists().FindOwnedWithTracks(1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if playlist.Type != "manual" {
+		t.Errorf("playlist type = %q, want manual", playlist.Type)
+	}
+	var songIds []uint
+	for _, entry := range playlist.Entries {
+		songIds = append(songIds, entry.SongId)
+	}
+	if len(songIds) != 2 || songIds[0] != 1 || songIds[1] != 2 {
+		t.Errorf("playlist songs = %v, want [1 2]", songIds)
+	}
+
+	if err := store.Songs().RecordPlay(1, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	song, err := store.Songs().FindOwned(1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if song.PlayCount != 1 {
+		t.Errorf("play count = %d, want 1", song.PlayCount)
+	}
+
+	hits, total, err := services.NewSearchService(db).SearchSongs(1, search.Parse("locomotion"), services.SongSearchFilters{}, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 1 || len(hits) != 1 || hits[0].ID != 2 {
+		t.Errorf("search hits = %+v, want song 2", hits)
+	}
+}
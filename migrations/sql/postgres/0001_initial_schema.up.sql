@@ -0,0 +1,139 @@
+-- Tables as they were created by GORM's AutoMigrate. Databases that were set up that way
+-- already have the tables of the first release, which IF NOT EXISTS skips, so the columns
+-- added to songs and playlists since are added to them here.
+
+CREATE TABLE IF NOT EXISTS users (
+	id bigserial PRIMARY KEY,
+	created_at timestamptz,
+	updated_at timestamptz,
+	deleted_at timestamptz,
+	name text,
+	email text,
+	password text,
+	role text,
+	CONSTRAINT uni_users_email UNIQUE (email)
+);
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at);
+
+CREATE TABLE IF NOT EXISTS albums (
+	id bigserial PRIMARY KEY,
+	created_at timestamptz,
+	updated_at timestamptz,
+	deleted_at timestamptz,
+	title text,
+	artist text,
+	year bigint,
+	user_id bigint,
+	CONSTRAINT fk_users_albums FOREIGN KEY (user_id) REFERENCES users (id),
+	CONSTRAINT uni_albums_title UNIQUE (title)
+);
+CREATE INDEX IF NOT EXISTS idx_albums_deleted_at ON albums (deleted_at);
+
+CREATE TABLE IF NOT EXISTS songs (
+	id bigserial PRIMARY KEY,
+	created_at timestamptz,
+	updated_at timestamptz,
+	deleted_at timestamptz,
+	title text,
+	duration bigint,
+	artist text,
+	genre text,
+	year bigint,
+	track_number bigint,
+	disc_number bigint,
+	album_id bigint,
+	user_id bigint,
+	audio_key text,
+	audio_content_type text,
+	audio_size bigint,
+	audio_checksum text,
+	audio_uploaded_at timestamptz,
+	play_count bigint,
+	last_played_at timestamptz,
+	CONSTRAINT fk_albums_songs FOREIGN KEY (album_id) REFERENCES albums (id),
+	CONSTRAINT fk_users_songs FOREIGN KEY (user_id) REFERENCES users (id)
+);
+CREATE INDEX IF NOT EXISTS idx_songs_deleted_at ON songs (deleted_at);
+
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS artist text;
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS genre text;
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS year bigint;
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS track_number bigint;
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS disc_number bigint;
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS audio_key text;
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS audio_content_type text;
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS audio_size bigint;
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS audio_checksum text;
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS audio_uploaded_at timestamptz;
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS play_count bigint;
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS last_played_at timestamptz;
+-- Plays are counted with play_count + 1, which stays NULL on adopted rows
+UPDATE songs SET play_count = 0 WHERE play_count IS NULL;
+
+CREATE TABLE IF NOT EXISTS playlists (
+	id bigserial PRIMARY KEY,
+	created_at timestamptz,
+	updated_at timestamptz,
+	deleted_at timestamptz,
+	name text,
+	user_id bigint,
+	type text DEFAULT 'manual',
+	rules text
+);
+CREATE INDEX IF NOT EXISTS idx_playlists_deleted_at ON playlists (deleted_at);
+
+ALTER TABLE playlists ADD COLUMN IF NOT EXISTS type text DEFAULT 'manual';
+ALTER TABLE playlists ADD COLUMN IF NOT EXISTS rules text;
+
+CREATE TABLE IF NOT EXISTS playlist_entries (
+	id bigserial PRIMARY KEY,
+	created_at timestamptz,
+	playlist_id bigint,
+	song_id bigint,
+	position bigint,
+	CONSTRAINT fk_playlists_entries FOREIGN KEY (playlist_id) REFERENCES playlists (id),
+	CONSTRAINT fk_playlist_entries_song FOREIGN KEY (song_id) REFERENCES songs (id)
+);
+CREATE INDEX IF NOT EXISTS idx_playlist_entries_song_id ON playlist_entries (song_id);
+CREATE INDEX IF NOT EXISTS idx_playlist_entries_position ON playlist_entries (playlist_id, position);
+
+-- Earlier releases kept playlist songs in the playlist_songs join table, which had no order.
+-- Its rows become entries in song ID order, the order they were most likely added in, unless the
+-- playlist already has entries, and the table is dropped. It is created first so new databases go
+-- through the same steps.
+CREATE TABLE IF NOT EXISTS playlist_songs (
+	playlist_id bigint,
+	song_id bigint,
+	PRIMARY KEY (playlist_id, song_id)
+);
+
+INSERT INTO playlist_entries (created_at, playlist_id, song_id, position)
+SELECT playlists.created_at, playlist_songs.playlist_id, playlist_songs.song_id,
+	ROW_NUMBER() OVER (PARTITION BY playlist_songs.playlist_id ORDER BY playlist_songs.song_id) - 1
+FROM playlist_songs JOIN playlists ON playlists.id = playlist_songs.playlist_id
+WHERE NOT EXISTS (SELECT 1 FROM playlist_entries WHERE playlist_entries.playlist_id = playlist_songs.playlist_id);
+
+DROP TABLE playlist_songs;
+
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+	id bigserial PRIMARY KEY,
+	created_at timestamptz,
+	updated_at timestamptz,
+	user_id bigint,
+	token_hash text,
+	family_id text,
+	expires_at timestamptz,
+	revoked_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family_id ON refresh_tokens (family_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_refresh_tokens_token_hash ON refresh_tokens (token_hash);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens (user_id);
+
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+	id bigserial PRIMARY KEY,
+	created_at timestamptz,
+	jti text,
+	expires_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_revoked_tokens_expires_at ON revoked_tokens (expires_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_revoked_tokens_jti ON revoked_tokens (jti);
@@ -0,0 +1,13 @@
+DROP INDEX IF EXISTS idx_playlists_name_trgm;
+DROP INDEX IF EXISTS idx_playlists_search_vector;
+ALTER TABLE playlists DROP COLUMN IF EXISTS search_vector;
+
+DROP INDEX IF EXISTS idx_albums_artist_trgm;
+DROP INDEX IF EXISTS idx_albums_title_trgm;
+DROP INDEX IF EXISTS idx_albums_search_vector;
+ALTER TABLE albums DROP COLUMN IF EXISTS search_vector;
+
+DROP INDEX IF EXISTS idx_songs_artist_trgm;
+DROP INDEX IF EXISTS idx_songs_title_trgm;
+DROP INDEX IF EXISTS idx_songs_search_vector;
+ALTER TABLE songs DROP COLUMN IF EXISTS search_vector;
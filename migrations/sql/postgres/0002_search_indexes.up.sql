@@ -0,0 +1,27 @@
+-- Generated tsvector columns plus full-text and trigram indexes used by search
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+	setweight(to_tsvector('simple', coalesce(title, '')), 'A') ||
+	setweight(to_tsvector('simple', coalesce(artist, '')), 'B') ||
+	setweight(to_tsvector('simple', coalesce(genre, '')), 'C')
+) STORED;
+CREATE INDEX IF NOT EXISTS idx_songs_search_vector ON songs USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_songs_title_trgm ON songs USING GIN (title gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_songs_artist_trgm ON songs USING GIN (artist gin_trgm_ops);
+
+ALTER TABLE albums ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+	setweight(to_tsvector('simple', coalesce(title, '')), 'A') ||
+	setweight(to_tsvector('simple', coalesce(artist, '')), 'B') ||
+	setweight(to_tsvector('simple', coalesce(year::text, '')), 'C')
+) STORED;
+CREATE INDEX IF NOT EXISTS idx_albums_search_vector ON albums USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_albums_title_trgm ON albums USING GIN (title gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_albums_artist_trgm ON albums USING GIN (artist gin_trgm_ops);
+
+ALTER TABLE playlists ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+	setweight(to_tsvector('simple', coalesce(name, '')), 'A')
+) STORED;
+CREATE INDEX IF NOT EXISTS idx_playlists_search_vector ON playlists USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_playlists_name_trgm ON playlists USING GIN (name gin_trgm_ops);
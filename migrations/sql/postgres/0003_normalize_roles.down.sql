@@ -0,0 +1,2 @@
+-- The roles that were replaced are not recorded, so there is nothing to restore
+SELECT 1;
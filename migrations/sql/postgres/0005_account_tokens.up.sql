@@ -0,0 +1,17 @@
+-- Email verification and single-use tokens for verifying addresses and resetting passwords.
+-- Existing accounts start unverified and can ask for a new verification email.
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at timestamptz;
+
+CREATE TABLE IF NOT EXISTS account_tokens (
+	id bigserial PRIMARY KEY,
+	created_at timestamptz,
+	user_id bigint,
+	purpose text,
+	email text,
+	token_hash text,
+	expires_at timestamptz,
+	used_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_account_tokens_token_hash ON account_tokens (token_hash);
+CREATE INDEX IF NOT EXISTS idx_account_tokens_user_id ON account_tokens (user_id);
@@ -0,0 +1,24 @@
+-- Logins through external OpenID Connect providers. Accounts created by such a login have no password.
+
+CREATE TABLE IF NOT EXISTS user_identities (
+	id bigserial PRIMARY KEY,
+	created_at timestamptz,
+	user_id bigint,
+	provider text,
+	subject text,
+	email text
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_identities_provider_subject ON user_identities (provider, subject);
+CREATE INDEX IF NOT EXISTS idx_user_identities_user_id ON user_identities (user_id);
+
+CREATE TABLE IF NOT EXISTS oidc_logins (
+	id bigserial PRIMARY KEY,
+	created_at timestamptz,
+	provider text,
+	state_hash text,
+	code_verifier text,
+	nonce text,
+	expires_at timestamptz,
+	used_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_oidc_logins_state_hash ON oidc_logins (state_hash);
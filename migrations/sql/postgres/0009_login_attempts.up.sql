@@ -0,0 +1,14 @@
+-- Failed login counters per account and per IP address for backoff and lockouts
+
+CREATE TABLE IF NOT EXISTS login_attempts (
+	id bigserial PRIMARY KEY,
+	created_at timestamptz,
+	updated_at timestamptz,
+	kind text,
+	target text,
+	failures bigint,
+	last_failed_at timestamptz,
+	locked_until timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_login_attempts_kind_target ON login_attempts (kind, target);
+CREATE INDEX IF NOT EXISTS idx_login_attempts_locked_until ON login_attempts (locked_until);
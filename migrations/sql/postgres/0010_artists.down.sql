@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS song_credits;
+DROP TABLE IF EXISTS album_credits;
+DROP TABLE IF EXISTS artists;
@@ -0,0 +1,4 @@
+-- Fails while two albums share a title, which the per-user uniqueness allows
+
+DROP INDEX IF EXISTS idx_albums_user_artist_title_year;
+ALTER TABLE albums ADD CONSTRAINT uni_albums_title UNIQUE (title);
@@ -0,0 +1,5 @@
+-- Album titles are unique per user, artist and year, ignoring case, instead of across all users.
+-- Deleted albums don't take part, so an album can be recreated after it was deleted.
+
+ALTER TABLE albums DROP CONSTRAINT IF EXISTS uni_albums_title;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_albums_user_artist_title_year ON albums (user_id, lower(artist), lower(title), year) WHERE deleted_at IS NULL;
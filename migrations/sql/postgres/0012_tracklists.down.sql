@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_songs_album_disc_track;
+ALTER TABLE songs DROP COLUMN IF EXISTS disc_subtitle;
@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS idx_albums_genre_id;
+ALTER TABLE albums DROP COLUMN IF EXISTS genre_id;
+ALTER TABLE albums DROP COLUMN IF EXISTS genre;
+
+DROP INDEX IF EXISTS idx_songs_genre_id;
+ALTER TABLE songs DROP COLUMN IF EXISTS genre_id;
+
+DROP TABLE IF EXISTS tagged_albums;
+DROP TABLE IF EXISTS tagged_songs;
+DROP TABLE IF EXISTS tags;
+DROP TABLE IF EXISTS genres;
@@ -0,0 +1,63 @@
+-- Hierarchical genres and user tags for songs and albums. Every distinct genre text of a user's
+-- songs, ignoring case and surrounding spaces, becomes one top-level genre, and the genre text is
+-- set to that genre's name.
+
+CREATE TABLE IF NOT EXISTS genres (
+	id bigserial PRIMARY KEY,
+	created_at timestamptz,
+	updated_at timestamptz,
+	user_id bigint,
+	parent_id bigint,
+	name text,
+	CONSTRAINT fk_users_genres FOREIGN KEY (user_id) REFERENCES users (id),
+	CONSTRAINT fk_genres_parent FOREIGN KEY (parent_id) REFERENCES genres (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_genres_user_name ON genres (user_id, lower(name));
+CREATE INDEX IF NOT EXISTS idx_genres_parent_id ON genres (parent_id);
+
+CREATE TABLE IF NOT EXISTS tags (
+	id bigserial PRIMARY KEY,
+	created_at timestamptz,
+	updated_at timestamptz,
+	user_id bigint,
+	name text,
+	CONSTRAINT fk_users_tags FOREIGN KEY (user_id) REFERENCES users (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tags_user_name ON tags (user_id, lower(name));
+
+CREATE TABLE IF NOT EXISTS tagged_songs (
+	song_id bigint,
+	tag_id bigint,
+	PRIMARY KEY (song_id, tag_id),
+	CONSTRAINT fk_songs_tags FOREIGN KEY (song_id) REFERENCES songs (id),
+	CONSTRAINT fk_tagged_songs_tag FOREIGN KEY (tag_id) REFERENCES tags (id)
+);
+CREATE INDEX IF NOT EXISTS idx_tagged_songs_tag_id ON tagged_songs (tag_id);
+
+CREATE TABLE IF NOT EXISTS tagged_albums (
+	album_id bigint,
+	tag_id bigint,
+	PRIMARY KEY (album_id, tag_id),
+	CONSTRAINT fk_albums_tags FOREIGN KEY (album_id) REFERENCES albums (id),
+	CONSTRAINT fk_tagged_albums_tag FOREIGN KEY (tag_id) REFERENCES tags (id)
+);
+CREATE INDEX IF NOT EXISTS idx_tagged_albums_tag_id ON tagged_albums (tag_id);
+
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS genre_id bigint REFERENCES genres (id);
+CREATE INDEX IF NOT EXISTS idx_songs_genre_id ON songs (genre_id);
+
+ALTER TABLE albums ADD COLUMN IF NOT EXISTS genre text;
+ALTER TABLE albums ADD COLUMN IF NOT EXISTS genre_id bigint REFERENCES genres (id);
+CREATE INDEX IF NOT EXISTS idx_albums_genre_id ON albums (genre_id);
+
+INSERT INTO genres (created_at, updated_at, user_id, name)
+SELECT MIN(created_at), MIN(created_at), user_id, MIN(TRIM(genre))
+FROM songs
+WHERE deleted_at IS NULL AND TRIM(genre) <> ''
+GROUP BY user_id, LOWER(TRIM(genre));
+
+UPDATE songs SET genre_id = (SELECT genres.id FROM genres WHERE genres.user_id = songs.user_id AND LOWER(genres.name) = LOWER(TRIM(songs.genre)))
+WHERE deleted_at IS NULL AND TRIM(genre) <> '';
+
+UPDATE songs SET genre = (SELECT genres.name FROM genres WHERE genres.id = songs.genre_id)
+WHERE genre_id IS NOT NULL;
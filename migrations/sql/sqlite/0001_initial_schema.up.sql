@@ -0,0 +1,102 @@
+-- The same tables as the Postgres migration, in SQLite types. SQLite databases were never created
+-- by AutoMigrate, so there is nothing to adopt.
+
+CREATE TABLE IF NOT EXISTS users (
+	id integer PRIMARY KEY AUTOINCREMENT,
+	created_at datetime,
+	updated_at datetime,
+	deleted_at datetime,
+	name text,
+	email text,
+	password text,
+	role text,
+	CONSTRAINT uni_users_email UNIQUE (email)
+);
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at);
+
+CREATE TABLE IF NOT EXISTS albums (
+	id integer PRIMARY KEY AUTOINCREMENT,
+	created_at datetime,
+	updated_at datetime,
+	deleted_at datetime,
+	title text,
+	artist text,
+	year integer,
+	user_id integer,
+	CONSTRAINT fk_users_albums FOREIGN KEY (user_id) REFERENCES users (id),
+	CONSTRAINT uni_albums_title UNIQUE (title)
+);
+CREATE INDEX IF NOT EXISTS idx_albums_deleted_at ON albums (deleted_at);
+
+CREATE TABLE IF NOT EXISTS songs (
+	id integer PRIMARY KEY AUTOINCREMENT,
+	created_at datetime,
+	updated_at datetime,
+	deleted_at datetime,
+	title text,
+	duration integer,
+	artist text,
+	genre text,
+	year integer,
+	track_number integer,
+	disc_number integer,
+	album_id integer,
+	user_id integer,
+	audio_key text,
+	audio_content_type text,
+	audio_size integer,
+	audio_checksum text,
+	audio_uploaded_at datetime,
+	play_count integer,
+	last_played_at datetime,
+	CONSTRAINT fk_albums_songs FOREIGN KEY (album_id) REFERENCES albums (id),
+	CONSTRAINT fk_users_songs FOREIGN KEY (user_id) REFERENCES users (id)
+);
+CREATE INDEX IF NOT EXISTS idx_songs_deleted_at ON songs (deleted_at);
+
+CREATE TABLE IF NOT EXISTS playlists (
+	id integer PRIMARY KEY AUTOINCREMENT,
+	created_at datetime,
+	updated_at datetime,
+	deleted_at datetime,
+	name text,
+	user_id integer,
+	type text DEFAULT 'manual',
+	rules text
+);
+CREATE INDEX IF NOT EXISTS idx_playlists_deleted_at ON playlists (deleted_at);
+
+CREATE TABLE IF NOT EXISTS playlist_entries (
+	id integer PRIMARY KEY AUTOINCREMENT,
+	created_at datetime,
+	playlist_id integer,
+	song_id integer,
+	position integer,
+	CONSTRAINT fk_playlists_entries FOREIGN KEY (playlist_id) REFERENCES playlists (id),
+	CONSTRAINT fk_playlist_entries_song FOREIGN KEY (song_id) REFERENCES songs (id)
+);
+CREATE INDEX IF NOT EXISTS idx_playlist_entries_song_id ON playlist_entries (song_id);
+CREATE INDEX IF NOT EXISTS idx_playlist_entries_position ON playlist_entries (playlist_id, position);
+
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+	id integer PRIMARY KEY AUTOINCREMENT,
+	created_at datetime,
+	updated_at datetime,
+	user_id integer,
+	token_hash text,
+	family_id text,
+	expires_at datetime,
+	revoked_at datetime
+);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family_id ON refresh_tokens (family_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_refresh_tokens_token_hash ON refresh_tokens (token_hash);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens (user_id);
+
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+	id integer PRIMARY KEY AUTOINCREMENT,
+	created_at datetime,
+	jti text,
+	expires_at datetime
+);
+CREATE INDEX IF NOT EXISTS idx_revoked_tokens_expires_at ON revoked_tokens (expires_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_revoked_tokens_jti ON revoked_tokens (jti);
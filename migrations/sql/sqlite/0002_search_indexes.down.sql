@@ -0,0 +1,14 @@
+DROP TRIGGER IF EXISTS playlists_fts_update;
+DROP TRIGGER IF EXISTS playlists_fts_delete;
+DROP TRIGGER IF EXISTS playlists_fts_insert;
+DROP TABLE IF EXISTS playlists_fts;
+
+DROP TRIGGER IF EXISTS albums_fts_update;
+DROP TRIGGER IF EXISTS albums_fts_delete;
+DROP TRIGGER IF EXISTS albums_fts_insert;
+DROP TABLE IF EXISTS albums_fts;
+
+DROP TRIGGER IF EXISTS songs_fts_update;
+DROP TRIGGER IF EXISTS songs_fts_delete;
+DROP TRIGGER IF EXISTS songs_fts_insert;
+DROP TABLE IF EXISTS songs_fts;
@@ -0,0 +1,41 @@
+-- FTS5 indexes standing in for the Postgres search_vector columns. They use the tables
+-- as external content and are kept in sync by triggers.
+
+CREATE VIRTUAL TABLE songs_fts USING fts5(title, artist, genre, content = 'songs', content_rowid = 'id');
+INSERT INTO songs_fts (rowid, title, artist, genre) SELECT id, title, artist, genre FROM songs;
+CREATE TRIGGER songs_fts_insert AFTER INSERT ON songs BEGIN
+	INSERT INTO songs_fts (rowid, title, artist, genre) VALUES (new.id, new.title, new.artist, new.genre);
+END;
+CREATE TRIGGER songs_fts_delete AFTER DELETE ON songs BEGIN
+	INSERT INTO songs_fts (songs_fts, rowid, title, artist, genre) VALUES ('delete', old.id, old.title, old.artist, old.genre);
+END;
+CREATE TRIGGER songs_fts_update AFTER UPDATE OF title, artist, genre ON songs BEGIN
+	INSERT INTO songs_fts (songs_fts, rowid, title, artist, genre) VALUES ('delete', old.id, old.title, old.artist, old.genre);
+	INSERT INTO songs_fts (rowid, title, artist, genre) VALUES (new.id, new.title, new.artist, new.genre);
+END;
+
+CREATE VIRTUAL TABLE albums_fts USING fts5(title, artist, year, content = 'albums', content_rowid = 'id');
+INSERT INTO albums_fts (rowid, title, artist, year) SELECT id, title, artist, year FROM albums;
+CREATE TRIGGER albums_fts_insert AFTER INSERT ON albums BEGIN
+	INSERT INTO albums_fts (rowid, title, artist, year) VALUES (new.id, new.title, new.artist, new.year);
+END;
+CREATE TRIGGER albums_fts_delete AFTER DELETE ON albums BEGIN
+	INSERT INTO albums_fts (albums_fts, rowid, title, artist, year) VALUES ('delete', old.id, old.title, old.artist, old.year);
+END;
+CREATE TRIGGER albums_fts_update AFTER UPDATE OF title, artist, year ON albums BEGIN
+	INSERT INTO albums_fts (albums_fts, rowid, title, artist, year) VALUES ('delete', old.id, old.title, old.artist, old.year);
+	INSERT INTO albums_fts (rowid, title, artist, year) VALUES (new.id, new.title, new.artist, new.year);
+END;
+
+CREATE VIRTUAL TABLE playlists_fts USING fts5(name, content = 'playlists', content_rowid = 'id');
+INSERT INTO playlists_fts (rowid, name) SELECT id, name FROM playlists;
+CREATE TRIGGER playlists_fts_insert AFTER INSERT ON playlists BEGIN
+	INSERT INTO playlists_fts (rowid, name) VALUES (new.id, new.name);
+END;
+CREATE TRIGGER playlists_fts_delete AFTER DELETE ON playlists BEGIN
+	INSERT INTO playlists_fts (playlists_fts, rowid, name) VALUES ('delete', old.id, old.name);
+END;
+CREATE TRIGGER playlists_fts_update AFTER UPDATE OF name ON playlists BEGIN
+	INSERT INTO playlists_fts (playlists_fts, rowid, name) VALUES ('delete', old.id, old.name);
+	INSERT INTO playlists_fts (rowid, name) VALUES (new.id, new.name);
+END;
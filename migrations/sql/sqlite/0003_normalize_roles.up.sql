@@ -0,0 +1,3 @@
+-- Roles used to be free text chosen at registration, and only "artist" meant anything.
+-- Admin and moderator are now assigned by an admin, so self-chosen ones are reset to listener.
+UPDATE users SET role = 'listener' WHERE role IS NULL OR role NOT IN ('listener', 'artist');
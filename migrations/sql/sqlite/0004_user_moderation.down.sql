@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS audit_logs;
+ALTER TABLE users DROP COLUMN password_reset_required;
+ALTER TABLE users DROP COLUMN suspension_reason;
+ALTER TABLE users DROP COLUMN suspended_at;
@@ -0,0 +1,19 @@
+-- Account suspension, forced password resets and the audit trail of admin actions
+
+ALTER TABLE users ADD COLUMN suspended_at datetime;
+ALTER TABLE users ADD COLUMN suspension_reason text;
+ALTER TABLE users ADD COLUMN password_reset_required boolean NOT NULL DEFAULT false;
+
+CREATE TABLE IF NOT EXISTS audit_logs (
+	id integer PRIMARY KEY AUTOINCREMENT,
+	created_at datetime,
+	actor_id integer,
+	action text,
+	target_type text,
+	target_id integer,
+	details text
+);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_created_at ON audit_logs (created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_actor_id ON audit_logs (actor_id);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_action ON audit_logs (action);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_target ON audit_logs (target_type, target_id);
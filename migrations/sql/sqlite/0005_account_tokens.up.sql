@@ -0,0 +1,17 @@
+-- Email verification and single-use tokens for verifying addresses and resetting passwords.
+-- Existing accounts start unverified and can ask for a new verification email.
+
+ALTER TABLE users ADD COLUMN email_verified_at datetime;
+
+CREATE TABLE IF NOT EXISTS account_tokens (
+	id integer PRIMARY KEY AUTOINCREMENT,
+	created_at datetime,
+	user_id integer,
+	purpose text,
+	email text,
+	token_hash text,
+	expires_at datetime,
+	used_at datetime
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_account_tokens_token_hash ON account_tokens (token_hash);
+CREATE INDEX IF NOT EXISTS idx_account_tokens_user_id ON account_tokens (user_id);
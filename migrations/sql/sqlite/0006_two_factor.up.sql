@@ -0,0 +1,14 @@
+-- TOTP two-factor authentication and its recovery codes
+
+ALTER TABLE users ADD COLUMN totp_secret text;
+ALTER TABLE users ADD COLUMN totp_enabled_at datetime;
+ALTER TABLE users ADD COLUMN totp_last_step integer NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS recovery_codes (
+	id integer PRIMARY KEY AUTOINCREMENT,
+	created_at datetime,
+	user_id integer,
+	code_hash text,
+	used_at datetime
+);
+CREATE INDEX IF NOT EXISTS idx_recovery_codes_user_id ON recovery_codes (user_id);
@@ -0,0 +1,17 @@
+-- Personal API keys for scripts and CI
+
+CREATE TABLE IF NOT EXISTS api_keys (
+	id integer PRIMARY KEY AUTOINCREMENT,
+	created_at datetime,
+	updated_at datetime,
+	user_id integer,
+	name text,
+	prefix text,
+	key_hash text,
+	scopes text,
+	expires_at datetime,
+	last_used_at datetime,
+	revoked_at datetime
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_api_keys_key_hash ON api_keys (key_hash);
+CREATE INDEX IF NOT EXISTS idx_api_keys_user_id ON api_keys (user_id);
@@ -0,0 +1,24 @@
+-- Logins through external OpenID Connect providers. Accounts created by such a login have no password.
+
+CREATE TABLE IF NOT EXISTS user_identities (
+	id integer PRIMARY KEY AUTOINCREMENT,
+	created_at datetime,
+	user_id integer,
+	provider text,
+	subject text,
+	email text
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_identities_provider_subject ON user_identities (provider, subject);
+CREATE INDEX IF NOT EXISTS idx_user_identities_user_id ON user_identities (user_id);
+
+CREATE TABLE IF NOT EXISTS oidc_logins (
+	id integer PRIMARY KEY AUTOINCREMENT,
+	created_at datetime,
+	provider text,
+	state_hash text,
+	code_verifier text,
+	nonce text,
+	expires_at datetime,
+	used_at datetime
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_oidc_logins_state_hash ON oidc_logins (state_hash);
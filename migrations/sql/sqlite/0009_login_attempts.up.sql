@@ -0,0 +1,14 @@
+-- Failed login counters per account and per IP address for backoff and lockouts
+
+CREATE TABLE IF NOT EXISTS login_attempts (
+	id integer PRIMARY KEY AUTOINCREMENT,
+	created_at datetime,
+	updated_at datetime,
+	kind text,
+	target text,
+	failures integer,
+	last_failed_at datetime,
+	locked_until datetime
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_login_attempts_kind_target ON login_attempts (kind, target);
+CREATE INDEX IF NOT EXISTS idx_login_attempts_locked_until ON login_attempts (locked_until);
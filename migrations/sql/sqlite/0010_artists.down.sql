@@ -0,0 +1,8 @@
+DROP TRIGGER IF EXISTS artists_fts_update;
+DROP TRIGGER IF EXISTS artists_fts_delete;
+DROP TRIGGER IF EXISTS artists_fts_insert;
+DROP TABLE IF EXISTS artists_fts;
+
+DROP TABLE IF EXISTS song_credits;
+DROP TABLE IF EXISTS album_credits;
+DROP TABLE IF EXISTS artists;
@@ -0,0 +1,74 @@
+-- Artists with album and song credits. Every distinct artist text of a user's albums and songs,
+-- ignoring case and surrounding spaces, becomes one artist credited as primary artist, and the
+-- artist text is set to that artist's name.
+
+CREATE TABLE IF NOT EXISTS artists (
+	id integer PRIMARY KEY AUTOINCREMENT,
+	created_at datetime,
+	updated_at datetime,
+	user_id integer,
+	name text,
+	CONSTRAINT fk_users_artists FOREIGN KEY (user_id) REFERENCES users (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_artists_user_name ON artists (user_id, lower(name));
+
+CREATE TABLE IF NOT EXISTS album_credits (
+	album_id integer,
+	artist_id integer,
+	role text,
+	position integer,
+	PRIMARY KEY (album_id, artist_id, role),
+	CONSTRAINT fk_albums_credits FOREIGN KEY (album_id) REFERENCES albums (id),
+	CONSTRAINT fk_album_credits_artist FOREIGN KEY (artist_id) REFERENCES artists (id)
+);
+CREATE INDEX IF NOT EXISTS idx_album_credits_artist_id ON album_credits (artist_id);
+
+CREATE TABLE IF NOT EXISTS song_credits (
+	song_id integer,
+	artist_id integer,
+	role text,
+	position integer,
+	PRIMARY KEY (song_id, artist_id, role),
+	CONSTRAINT fk_songs_credits FOREIGN KEY (song_id) REFERENCES songs (id),
+	CONSTRAINT fk_song_credits_artist FOREIGN KEY (artist_id) REFERENCES artists (id)
+);
+CREATE INDEX IF NOT EXISTS idx_song_credits_artist_id ON song_credits (artist_id);
+
+INSERT INTO artists (created_at, updated_at, user_id, name)
+SELECT MIN(created_at), MIN(created_at), user_id, MIN(TRIM(artist))
+FROM (
+	SELECT created_at, user_id, artist FROM albums WHERE deleted_at IS NULL
+	UNION ALL
+	SELECT created_at, user_id, artist FROM songs WHERE deleted_at IS NULL
+) AS named
+WHERE TRIM(artist) <> ''
+GROUP BY user_id, LOWER(TRIM(artist));
+
+INSERT INTO album_credits (album_id, artist_id, role, position)
+SELECT albums.id, artists.id, 'primary', 0
+FROM albums JOIN artists ON artists.user_id = albums.user_id AND LOWER(artists.name) = LOWER(TRIM(albums.artist))
+WHERE albums.deleted_at IS NULL;
+
+INSERT INTO song_credits (song_id, artist_id, role, position)
+SELECT songs.id, artists.id, 'primary', 0
+FROM songs JOIN artists ON artists.user_id = songs.user_id AND LOWER(artists.name) = LOWER(TRIM(songs.artist))
+WHERE songs.deleted_at IS NULL;
+
+UPDATE albums SET artist = (SELECT artists.name FROM album_credits JOIN artists ON artists.id = album_credits.artist_id WHERE album_credits.album_id = albums.id)
+WHERE id IN (SELECT album_id FROM album_credits);
+
+UPDATE songs SET artist = (SELECT artists.name FROM song_credits JOIN artists ON artists.id = song_credits.artist_id WHERE song_credits.song_id = songs.id)
+WHERE id IN (SELECT song_id FROM song_credits);
+
+CREATE VIRTUAL TABLE artists_fts USING fts5(name, content = 'artists', content_rowid = 'id');
+INSERT INTO artists_fts (rowid, name) SELECT id, name FROM artists;
+CREATE TRIGGER artists_fts_insert AFTER INSERT ON artists BEGIN
+	INSERT INTO artists_fts (rowid, name) VALUES (new.id, new.name);
+END;
+CREATE TRIGGER artists_fts_delete AFTER DELETE ON artists BEGIN
+	INSERT INTO artists_fts (artists_fts, rowid, name) VALUES ('delete', old.id, old.name);
+END;
+CREATE TRIGGER artists_fts_update AFTER UPDATE OF name ON artists BEGIN
+	INSERT INTO artists_fts (artists_fts, rowid, name) VALUES ('delete', old.id, old.name);
+	INSERT INTO artists_fts (rowid, name) VALUES (new.id, new.name);
+END;
@@ -0,0 +1,35 @@
+-- migrate: foreign keys off
+-- Album titles are unique per user, artist and year, ignoring case, instead of across all users.
+-- Deleted albums don't take part, so an album can be recreated after it was deleted.
+
+-- SQLite can't drop a table constraint, so albums is rebuilt and the rows are copied with their
+-- IDs. The FTS5 index keeps its content, but the triggers and the index go with the old table.
+CREATE TABLE albums_new (
+	id integer PRIMARY KEY AUTOINCREMENT,
+	created_at datetime,
+	updated_at datetime,
+	deleted_at datetime,
+	title text,
+	artist text,
+	year integer,
+	user_id integer,
+	CONSTRAINT fk_users_albums FOREIGN KEY (user_id) REFERENCES users (id)
+);
+INSERT INTO albums_new (id, created_at, updated_at, deleted_at, title, artist, year, user_id)
+SELECT id, created_at, updated_at, deleted_at, title, artist, year, user_id FROM albums;
+DROP TABLE albums;
+ALTER TABLE albums_new RENAME TO albums;
+CREATE INDEX IF NOT EXISTS idx_albums_deleted_at ON albums (deleted_at);
+
+CREATE TRIGGER albums_fts_insert AFTER INSERT ON albums BEGIN
+	INSERT INTO albums_fts (rowid, title, artist, year) VALUES (new.id, new.title, new.artist, new.year);
+END;
+CREATE TRIGGER albums_fts_delete AFTER DELETE ON albums BEGIN
+	INSERT INTO albums_fts (albums_fts, rowid, title, artist, year) VALUES ('delete', old.id, old.title, old.artist, old.year);
+END;
+CREATE TRIGGER albums_fts_update AFTER UPDATE OF title, artist, year ON albums BEGIN
+	INSERT INTO albums_fts (albums_fts, rowid, title, artist, year) VALUES ('delete', old.id, old.title, old.artist, old.year);
+	INSERT INTO albums_fts (rowid, title, artist, year) VALUES (new.id, new.title, new.artist, new.year);
+END;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_albums_user_artist_title_year ON albums (user_id, lower(artist), lower(title), year) WHERE deleted_at IS NULL;
@@ -0,0 +1,21 @@
+-- Disc subtitles and unique track positions within an album. Numbered songs on an album without
+-- a disc number go on disc 1, and songs sharing a place keep it in ID order while the others lose
+-- their track number, so they show up at the end of the tracklist to be renumbered.
+
+ALTER TABLE songs ADD COLUMN disc_subtitle text;
+
+UPDATE songs SET disc_number = 1
+WHERE album_id IS NOT NULL AND track_number > 0 AND (disc_number IS NULL OR disc_number = 0);
+
+UPDATE songs SET track_number = 0
+WHERE id IN (
+	SELECT id FROM (
+		SELECT id, ROW_NUMBER() OVER (PARTITION BY album_id, disc_number, track_number ORDER BY id) AS n
+		FROM songs
+		WHERE deleted_at IS NULL AND album_id IS NOT NULL AND track_number > 0
+	) AS placed
+	WHERE n > 1
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_songs_album_disc_track ON songs (album_id, disc_number, track_number)
+WHERE deleted_at IS NULL AND album_id IS NOT NULL AND track_number > 0;
@@ -0,0 +1,9 @@
+ALTER TABLE playlists DROP COLUMN cover_updated_at;
+ALTER TABLE playlists DROP COLUMN cover_checksum;
+ALTER TABLE playlists DROP COLUMN cover_content_type;
+ALTER TABLE playlists DROP COLUMN cover_key;
+
+ALTER TABLE albums DROP COLUMN cover_updated_at;
+ALTER TABLE albums DROP COLUMN cover_checksum;
+ALTER TABLE albums DROP COLUMN cover_content_type;
+ALTER TABLE albums DROP COLUMN cover_key;
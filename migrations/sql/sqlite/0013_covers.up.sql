@@ -0,0 +1,11 @@
+-- Cover images of albums and playlists; thumbnails are stored next to the image under derived keys
+
+ALTER TABLE albums ADD COLUMN cover_key text;
+ALTER TABLE albums ADD COLUMN cover_content_type text;
+ALTER TABLE albums ADD COLUMN cover_checksum text;
+ALTER TABLE albums ADD COLUMN cover_updated_at datetime;
+
+ALTER TABLE playlists ADD COLUMN cover_key text;
+ALTER TABLE playlists ADD COLUMN cover_content_type text;
+ALTER TABLE playlists ADD COLUMN cover_checksum text;
+ALTER TABLE playlists ADD COLUMN cover_updated_at datetime;
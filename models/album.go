@@ -17,16 +17,32 @@ type Album struct {
 	UpdatedAt time.Time `json:"updated_at" example:"2023-01-01T00:00:00Z"`
 	// @Description When the album was deleted (soft delete)
 	DeletedAt gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
-	// @Description Album title
-	Title string `json:"title" gorm:"unique" example:"Dark Side of the Moon"`
-	// @Description Album artist
+	// @Description Album title; a user has one album per artist, title and year, ignoring case
+	Title string `json:"title" example:"Dark Side of the Moon"`
+	// @Description Album artist as displayed: the names of the primary artists in the credits
 	Artist string `json:"artist" example:"Pink Floyd"`
 	// @Description Release year
 	Year int `json:"year" example:"1973"`
+	// @Description Album genre as displayed: the name of the genre it belongs to
+	Genre string `json:"genre,omitempty" example:"Rock"`
+	// @Description ID of the genre the album belongs to
+	GenreId *uint `json:"genre_id,omitempty" example:"1"`
 	// @Description User ID who owns the album
 	UserId uint `json:"user_id" example:"1"`
+	// @Description Storage key of the cover image
+	CoverKey string `json:"-"`
+	// @Description Content type of the uploaded cover image
+	CoverContentType string `json:"cover_content_type,omitempty" example:"image/jpeg"`
+	// @Description SHA-256 checksum of the uploaded cover image
+	CoverChecksum string `json:"cover_checksum,omitempty" example:"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"`
+	// @Description When the cover image was uploaded
+	CoverUpdatedAt *time.Time `json:"cover_updated_at,omitempty" example:"2023-01-01T00:00:00Z"`
 	// @Description Songs in the album
 	Songs []Song `json:"songs,omitempty" gorm:"foreignKey:AlbumId"`
+	// @Description Artists credited on the album; the artist name is credited as primary artist when no primary artist is given
+	Credits []ArtistCredit `json:"credits,omitempty" gorm:"-"`
+	// @Description Names of the user's tags on the album; when creating, tags the user doesn't have yet are created
+	Tags []string `json:"tags,omitempty" gorm:"-"`
 }
 
 // AlbumResponse represents the album data returned in API responses
@@ -40,14 +56,28 @@ type AlbumResponse struct {
 	UpdatedAt time.Time `json:"updated_at" example:"2023-01-01T00:00:00Z"`
 	// @Description Album title
 	Title string `json:"title" example:"Dark Side of the Moon"`
-	// @Description Album artist
+	// @Description Album artist as displayed: the names of the primary artists in the credits
 	Artist string `json:"artist" example:"Pink Floyd"`
 	// @Description Release year
 	Year int `json:"year" example:"1973"`
+	// @Description Album genre as displayed: the name of the genre it belongs to
+	Genre string `json:"genre,omitempty" example:"Rock"`
+	// @Description ID of the genre the album belongs to
+	GenreId *uint `json:"genre_id,omitempty" example:"1"`
 	// @Description User ID who owns the album
 	UserId uint `json:"user_id" example:"1"`
+	// @Description Content type of the uploaded cover image
+	CoverContentType string `json:"cover_content_type,omitempty" example:"image/jpeg"`
+	// @Description SHA-256 checksum of the uploaded cover image
+	CoverChecksum string `json:"cover_checksum,omitempty" example:"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"`
+	// @Description When the cover image was uploaded
+	CoverUpdatedAt *time.Time `json:"cover_updated_at,omitempty" example:"2023-01-01T00:00:00Z"`
 	// @Description Songs in the album
 	Songs []SongResponse `json:"songs,omitempty"`
+	// @Description Artists credited on the album
+	Credits []ArtistCredit `json:"credits,omitempty"`
+	// @Description Names of the user's tags on the album
+	Tags []string `json:"tags,omitempty"`
 }
 
 // AlbumCreateRequest represents the album creation request payload
@@ -55,8 +85,23 @@ type AlbumResponse struct {
 type AlbumCreateRequest struct {
 	// @Description Album title
 	Title string `json:"title" binding:"required" example:"Dark Side of the Moon"`
-	// @Description Album artist
-	Artist string `json:"artist" binding:"required" example:"Pink Floyd"`
+	// @Description Album artist, credited as primary artist unless credits name one
+	Artist string `json:"artist" binding:"required_without=Credits" example:"Pink Floyd"`
 	// @Description Release year
 	Year int `json:"year" binding:"required" example:"1973"`
+	// @Description Album genre; a genre of that name is created if the user has none
+	Genre string `json:"genre,omitempty" example:"Rock"`
+	// @Description ID of a genre of the user, taking precedence over genre
+	GenreId *uint `json:"genre_id,omitempty" example:"1"`
+	// @Description Artists credited on the album, replacing the current credits; when omitted only the primary artist follows artist
+	Credits []ArtistCredit `json:"credits,omitempty"`
+	// @Description Names of tags, replacing the current tags; tags the user doesn't have yet are created. The tags are kept when omitted.
+	Tags []string `json:"tags,omitempty" binding:"omitempty,dive,max=100" example:"road trip,summer"`
+}
+
+// AlbumMergeRequest names the album a duplicate album is merged into
+// @Description Album merge request model
+type AlbumMergeRequest struct {
+	// @Description ID of the album that receives the songs and credits of the merged album
+	TargetAlbumId uint `json:"target_album_id" binding:"required" example:"2"`
 }
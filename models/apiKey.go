@@ -0,0 +1,109 @@
+package models
+
+import (
+	"time"
+)
+
+// Resources API keys can be scoped to. Each has a read scope, for GET and HEAD requests, and a
+// write scope for everything else, e.g. songs:read and songs:write.
+const (
+	ResourceSongs     = "songs"
+	ResourceAlbums    = "albums"
+	ResourcePlaylists = "playlists"
+	ResourceArtists   = "artists"
+	ResourceGenres    = "genres"
+	ResourceTags      = "tags"
+)
+
+// Scopes an API key can be given
+const (
+	ScopeSongsRead      = "songs:read"
+	ScopeSongsWrite     = "songs:write"
+	ScopeAlbumsRead     = "albums:read"
+	ScopeAlbumsWrite    = "albums:write"
+	ScopePlaylistsRead  = "playlists:read"
+	ScopePlaylistsWrite = "playlists:write"
+	ScopeArtistsRead    = "artists:read"
+	ScopeArtistsWrite   = "artists:write"
+	ScopeGenresRead     = "genres:read"
+	ScopeGenresWrite    = "genres:write"
+	ScopeTagsRead       = "tags:read"
+	ScopeTagsWrite      = "tags:write"
+)
+
+// APIKeyScopes lists every scope
+var APIKeyScopes = []string{
+	ScopeSongsRead, ScopeSongsWrite,
+	ScopeAlbumsRead, ScopeAlbumsWrite,
+	ScopePlaylistsRead, ScopePlaylistsWrite,
+	ScopeArtistsRead, ScopeArtistsWrite,
+	ScopeGenresRead, ScopeGenresWrite,
+	ScopeTagsRead, ScopeTagsWrite,
+}
+
+// APIKey represents a personal API key for scripts and CI
+// @Description API key model (only the hash of the key is stored)
+type APIKey struct {
+	// @Description Unique identifier for the API key
+	ID uint `json:"id" gorm:"primarykey" example:"1"`
+	// @Description When the API key was created
+	CreatedAt time.Time `json:"created_at" example:"2023-01-01T00:00:00Z"`
+	// @Description When the API key was last updated
+	UpdatedAt time.Time `json:"updated_at" example:"2023-01-01T00:00:00Z"`
+	// @Description User ID the API key belongs to
+	UserId uint `json:"user_id" gorm:"index" example:"1"`
+	// @Description Name given by the user
+	Name string `json:"name" example:"Nightly backup"`
+	// @Description Start of the key, to tell keys apart
+	Prefix string `json:"prefix" example:"mlk_Jx4Tq9Pa"`
+	// @Description SHA-256 hash of the key
+	KeyHash string `json:"-" gorm:"uniqueIndex"`
+	// @Description Space separated scopes
+	Scopes string `json:"scopes" example:"songs:read playlists:write"`
+	// @Description When the API key stops working; empty for never
+	ExpiresAt *time.Time `json:"expires_at,omitempty" example:"2024-01-01T00:00:00Z"`
+	// @Description When the API key was last used, to the minute
+	LastUsedAt *time.Time `json:"last_used_at,omitempty" example:"2023-01-02T00:00:00Z"`
+	// @Description When the API key was revoked
+	RevokedAt *time.Time `json:"revoked_at,omitempty" example:"2023-01-03T00:00:00Z"`
+}
+
+// APIKeyCreateRequest represents the request to create an API key
+// @Description API key creation request model
+type APIKeyCreateRequest struct {
+	// @Description Name to recognise the key by
+	Name string `json:"name" binding:"required,max=100" example:"Nightly backup"`
+	// @Description Scopes to grant: songs, albums, playlists, artists, genres or tags with :read or :write
+	Scopes []string `json:"scopes" binding:"required,min=1,dive,oneof=songs:read songs:write albums:read albums:write playlists:read playlists:write artists:read artists:write genres:read genres:write tags:read tags:write" example:"songs:read,playlists:write"`
+	// @Description When the key stops working; omit for a key that does not expire
+	ExpiresAt *time.Time `json:"expires_at,omitempty" example:"2024-01-01T00:00:00Z"`
+}
+
+// APIKeyResponse represents an API key without its secret
+// @Description API key response model
+type APIKeyResponse struct {
+	// @Description Unique identifier for the API key
+	ID uint `json:"id" example:"1"`
+	// @Description When the API key was created
+	CreatedAt time.Time `json:"created_at" example:"2023-01-01T00:00:00Z"`
+	// @Description Name given by the user
+	Name string `json:"name" example:"Nightly backup"`
+	// @Description Start of the key, to tell keys apart
+	Prefix string `json:"prefix" example:"mlk_Jx4Tq9Pa"`
+	// @Description Granted scopes
+	Scopes []string `json:"scopes" example:"songs:read,playlists:write"`
+	// @Description When the API key stops working; empty for never
+	ExpiresAt *time.Time `json:"expires_at,omitempty" example:"2024-01-01T00:00:00Z"`
+	// @Description When the API key was last used, to the minute
+	LastUsedAt *time.Time `json:"last_used_at,omitempty" example:"2023-01-02T00:00:00Z"`
+	// @Description When the API key was revoked
+	RevokedAt *time.Time `json:"revoked_at,omitempty" example:"2023-01-03T00:00:00Z"`
+}
+
+// APIKeyCreateResponse represents a new API key, the only time the key itself is returned
+// @Description API key creation response model
+type APIKeyCreateResponse struct {
+	APIKeyResponse
+	// @Description The key; send it in the X-API-Key header or as a Bearer token
+	Key string `json:"key" example:"mlk_Jx4Tq9Pa..."`
+}
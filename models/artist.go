@@ -0,0 +1,71 @@
+package models
+
+import (
+	"time"
+)
+
+// Roles an artist can be credited with on an album or song
+const (
+	CreditPrimary  = "primary"
+	CreditFeatured = "featured"
+	CreditComposer = "composer"
+	CreditProducer = "producer"
+)
+
+// CreditRoles lists every credit role
+var CreditRoles = []string{CreditPrimary, CreditFeatured, CreditComposer, CreditProducer}
+
+// Artist represents a performer, composer or producer in a user's library
+// @Description Artist model
+type Artist struct {
+	// @Description Unique identifier for the artist
+	ID uint `json:"id" gorm:"primarykey" example:"1"`
+	// @Description When the artist was created
+	CreatedAt time.Time `json:"created_at" example:"2023-01-01T00:00:00Z"`
+	// @Description When the artist was last updated
+	UpdatedAt time.Time `json:"updated_at" example:"2023-01-01T00:00:00Z"`
+	// @Description Artist name, unique per user ignoring case
+	Name string `json:"name" example:"Pink Floyd"`
+	// @Description User ID who owns the artist
+	UserId uint `json:"user_id" gorm:"index" example:"1"`
+}
+
+// ArtistCreateRequest represents the artist creation and rename request payload
+// @Description Artist creation request model
+type ArtistCreateRequest struct {
+	// @Description Artist name
+	Name string `json:"name" binding:"required,max=200" example:"Pink Floyd"`
+}
+
+// ArtistCredit names an artist credited on an album or song and the role they had
+// @Description Artist credit
+type ArtistCredit struct {
+	// @Description Artist ID; when creating or updating, either artist_id or name is given
+	ArtistId uint `json:"artist_id,omitempty" example:"1"`
+	// @Description Artist name; when creating or updating, an artist of that name is created if the user has none
+	Name string `json:"name,omitempty" example:"Pink Floyd"`
+	// @Description Role of the artist: primary, featured, composer or producer
+	Role string `json:"role" example:"primary"`
+}
+
+// AlbumCredit stores an ArtistCredit of an album
+type AlbumCredit struct {
+	AlbumId  uint   `gorm:"primaryKey;autoIncrement:false"`
+	ArtistId uint   `gorm:"primaryKey;autoIncrement:false"`
+	Role     string `gorm:"primaryKey"`
+	// Position keeps the credits in the order they were given
+	Position int
+	// Name is read from the artist
+	Name string `gorm:"->"`
+}
+
+// SongCredit stores an ArtistCredit of a song
+type SongCredit struct {
+	SongId   uint   `gorm:"primaryKey;autoIncrement:false"`
+	ArtistId uint   `gorm:"primaryKey;autoIncrement:false"`
+	Role     string `gorm:"primaryKey"`
+	// Position keeps the credits in the order they were given
+	Position int
+	// Name is read from the artist
+	Name string `gorm:"->"`
+}
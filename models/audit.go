@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+)
+
+// Actions recorded in the audit trail
+const (
+	AuditUserRoleChanged         = "user.role_changed"
+	AuditUserSuspended           = "user.suspended"
+	AuditUserUnsuspended         = "user.unsuspended"
+	AuditUserPasswordResetForced = "user.password_reset_forced"
+	AuditUserTwoFactorReset      = "user.two_factor_reset"
+	AuditUserLibraryViewed       = "user.library_viewed"
+	AuditRecordRemoved           = "record.removed"
+	AuditRecordRestored          = "record.restored"
+	AuditAPIKeyRevoked           = "api_key.revoked"
+	AuditLoginUnlocked           = "login.unlocked"
+)
+
+// Kinds of records an audit entry can point at
+const (
+	AuditTargetUser         = "user"
+	AuditTargetSong         = "song"
+	AuditTargetAlbum        = "album"
+	AuditTargetPlaylist     = "playlist"
+	AuditTargetAPIKey       = "api_key"
+	AuditTargetLoginAttempt = "login_attempt"
+)
+
+// AuditLog represents one administrative action
+// @Description Audit trail entry; entries are only ever added
+type AuditLog struct {
+	// @Description Unique identifier for the entry
+	ID uint `json:"id" gorm:"primarykey" example:"1"`
+	// @Description When the action was taken
+	CreatedAt time.Time `json:"created_at" gorm:"index" example:"2023-01-01T00:00:00Z"`
+	// @Description User who took the action; empty for the command line
+	ActorId *uint `json:"actor_id,omitempty" gorm:"index" example:"1"`
+	// @Description What was done
+	Action string `json:"action" gorm:"index" example:"user.suspended"`
+	// @Description Kind of record acted on
+	TargetType string `json:"target_type" example:"user"`
+	// @Description ID of the record acted on
+	TargetId uint `json:"target_id" example:"42"`
+	// @Description Human readable details, such as the old and new role
+	Details string `json:"details,omitempty" example:"Spam uploads"`
+}
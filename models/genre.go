@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+)
+
+// Genre is a node of a user's genre taxonomy; a genre without a parent is a top-level genre
+// @Description Genre model
+type Genre struct {
+	// @Description Unique identifier for the genre
+	ID uint `json:"id" gorm:"primarykey" example:"2"`
+	// @Description When the genre was created
+	CreatedAt time.Time `json:"created_at" example:"2023-01-01T00:00:00Z"`
+	// @Description When the genre was last updated
+	UpdatedAt time.Time `json:"updated_at" example:"2023-01-01T00:00:00Z"`
+	// @Description Genre name, unique per user ignoring case
+	Name string `json:"name" example:"Progressive Rock"`
+	// @Description ID of the parent genre; omitted for a top-level genre
+	ParentId *uint `json:"parent_id,omitempty" example:"1"`
+	// @Description User ID who owns the genre
+	UserId uint `json:"user_id" gorm:"index" example:"1"`
+}
+
+// GenreNode is a genre with its subgenres
+// @Description Genre tree node
+type GenreNode struct {
+	Genre
+	// @Description Subgenres in name order
+	Children []GenreNode `json:"children"`
+}
+
+// GenreCreateRequest represents the genre creation and update request payload
+// @Description Genre creation request model
+type GenreCreateRequest struct {
+	// @Description Genre name
+	Name string `json:"name" binding:"required,max=100" example:"Progressive Rock"`
+	// @Description ID of the parent genre; omit for a top-level genre
+	ParentId *uint `json:"parent_id,omitempty" example:"1"`
+}
+
+// GenreAssignRequest names the songs and albums a genre is given to or taken from
+// @Description Genre assignment request model
+type GenreAssignRequest struct {
+	// @Description IDs of songs of the user
+	SongIds []uint `json:"song_ids,omitempty" example:"1,2"`
+	// @Description IDs of albums of the user
+	AlbumIds []uint `json:"album_ids,omitempty" example:"1"`
+}
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"time"
+)
+
+// UserIdentity links a user to their account at an external OpenID Connect provider
+// @Description External identity model
+type UserIdentity struct {
+	// @Description Unique identifier for the identity
+	ID uint `json:"id" gorm:"primarykey" example:"1"`
+	// @Description When the identity was linked
+	CreatedAt time.Time `json:"created_at" example:"2023-01-01T00:00:00Z"`
+	// @Description User ID the identity belongs to
+	UserId uint `json:"user_id" gorm:"index" example:"1"`
+	// @Description Name of the provider as configured in OIDC_PROVIDERS
+	Provider string `json:"provider" example:"corp"`
+	// @Description Subject (sub claim) identifying the account at the provider
+	Subject string `json:"subject" example:"248289761001"`
+	// @Description Email address the provider reported when the identity was linked
+	Email string `json:"email" example:"john@example.com"`
+}
+
+// OIDCLogin represents a login at an external provider that was started but has not come back yet
+// @Description OpenID Connect login state (only the hash of the state is stored)
+type OIDCLogin struct {
+	// @Description Unique identifier for the login
+	ID uint `json:"id" gorm:"primarykey" example:"1"`
+	// @Description When the login was started
+	CreatedAt time.Time `json:"created_at" example:"2023-01-01T00:00:00Z"`
+	// @Description Name of the provider
+	Provider string `json:"provider" example:"corp"`
+	// @Description SHA-256 hash of the state parameter
+	StateHash string `json:"-" gorm:"uniqueIndex"`
+	// @Description PKCE code verifier sent with the authorization code
+	CodeVerifier string `json:"-"`
+	// @Description Nonce the ID token has to carry
+	Nonce string `json:"-"`
+	// @Description When the login expires
+	ExpiresAt time.Time `json:"expires_at" example:"2023-01-01T00:10:00Z"`
+	// @Description When the callback used the login
+	UsedAt *time.Time `json:"used_at,omitempty" example:"2023-01-01T00:01:00Z"`
+}
+
+// TableName keeps GORM from splitting the acronym into o_id_c_logins
+func (OIDCLogin) TableName() string {
+	return "oidc_logins"
+}
+
+// OIDCProviderResponse represents an identity provider users can log in with
+// @Description Identity provider model
+type OIDCProviderResponse struct {
+	// @Description Name of the provider used in the login URLs
+	Name string `json:"name" example:"corp"`
+	// @Description Name to show on the login button
+	DisplayName string `json:"display_name" example:"Corporate SSO"`
+}
+
+// OIDCAuthorizeResponse represents a started login at an identity provider
+// @Description Where to send the browser to log in at the provider
+type OIDCAuthorizeResponse struct {
+	// @Description Authorization URL of the provider, including state, nonce and PKCE challenge
+	AuthorizationURL string `json:"authorization_url" example:"https://sso.example.com/authorize?client_id=music&state=..."`
+	// @Description State the provider sends back to the redirect URL; keep it to check the callback
+	State string `json:"state" example:"Jw2k...9c"`
+	// @Description Seconds until the login has to be finished
+	ExpiresIn int64 `json:"expires_in" example:"600"`
+}
+
+// OIDCCallbackRequest represents what the provider sent back to the redirect URL
+// @Description OpenID Connect callback request model
+type OIDCCallbackRequest struct {
+	// @Description Authorization code from the provider
+	Code string `json:"code" binding:"required" example:"SplxlOBeZQQYbYS6WxSbIA"`
+	// @Description State from the provider, as returned by the authorize endpoint
+	State string `json:"state" binding:"required" example:"Jw2k...9c"`
+}
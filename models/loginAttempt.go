@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+)
+
+// What failed logins are counted for
+const (
+	LoginAttemptAccount = "account"
+	LoginAttemptIP      = "ip"
+)
+
+// LoginAttempt counts the recent failed logins of one account or one IP address
+// @Description Failed login counter; an entry with locked_until in the future is a lockout
+type LoginAttempt struct {
+	// @Description Unique identifier for the entry
+	ID uint `json:"id" gorm:"primarykey" example:"1"`
+	// @Description When the first counted failure happened
+	CreatedAt time.Time `json:"created_at" example:"2023-01-01T00:00:00Z"`
+	// @Description When the entry was last updated
+	UpdatedAt time.Time `json:"updated_at" example:"2023-01-01T00:05:00Z"`
+	// @Description What the failures are counted for (account or ip)
+	Kind string `json:"kind" example:"account"`
+	// @Description Email address (lowercased) or IP address the failures are counted for
+	Target string `json:"target" example:"john@example.com"`
+	// @Description Failed attempts since the counter was last reset
+	Failures int `json:"failures" example:"10"`
+	// @Description When the last failure happened
+	LastFailedAt time.Time `json:"last_failed_at" example:"2023-01-01T00:05:00Z"`
+	// @Description Until when logins are refused
+	LockedUntil *time.Time `json:"locked_until,omitempty" example:"2023-01-01T00:20:00Z"`
+}
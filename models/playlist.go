@@ -21,8 +21,55 @@ type Playlist struct {
 	Name string `json:"name" example:"My Favorite Songs"`
 	// @Description User ID who owns the playlist
 	UserId uint `json:"userId" example:"1"`
-	// @Description Songs in the playlist
-	Songs []Song `json:"songs,omitempty" gorm:"many2many:playlist_songs"`
+	// @Description Playlist type: manual or smart
+	Type string `json:"type" gorm:"default:manual" example:"manual"`
+	// @Description Storage key of the cover image
+	CoverKey string `json:"-"`
+	// @Description Content type of the uploaded cover image
+	CoverContentType string `json:"cover_content_type,omitempty" example:"image/jpeg"`
+	// @Description SHA-256 checksum of the uploaded cover image
+	CoverChecksum string `json:"cover_checksum,omitempty" example:"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"`
+	// @Description When the cover image was uploaded
+	CoverUpdatedAt *time.Time `json:"cover_updated_at,omitempty" example:"2023-01-01T00:00:00Z"`
+	// @Description Rule set of a smart playlist
+	Rules *SmartPlaylistRules `json:"rules,omitempty" gorm:"serializer:json"`
+	// @Description Ordered playlist entries; the same song may appear more than once
+	Entries []PlaylistEntry `json:"entries,omitempty" gorm:"foreignKey:PlaylistId"`
+	// @Description Songs matching the rules of a smart playlist; manual playlists list their songs in entries
+	Songs []Song `json:"songs,omitempty" gorm:"-"`
+}
+
+// Tracks returns the songs of the playlist in playlist order: the loaded entries of a manual
+// playlist or the evaluated songs of a smart playlist
+func (p *Playlist) Tracks() []Song {
+	if p.Type == PlaylistTypeSmart {
+		return p.Songs
+	}
+	songs := make([]Song, 0, len(p.Entries))
+	for _, entry := range p.Entries {
+		// Entries of soft-deleted songs have no song loaded
+		if entry.Song != nil && entry.Song.ID != 0 {
+			songs = append(songs, *entry.Song)
+		}
+	}
+	return songs
+}
+
+// PlaylistEntry represents one position in a playlist
+// @Description Playlist entry model linking a song to a position in a playlist
+type PlaylistEntry struct {
+	// @Description Unique identifier for the entry
+	ID uint `json:"id" gorm:"primarykey" example:"1"`
+	// @Description When the entry was added
+	CreatedAt time.Time `json:"created_at" example:"2023-01-01T00:00:00Z"`
+	// @Description Playlist the entry belongs to
+	PlaylistId uint `json:"playlist_id" gorm:"index:idx_playlist_entries_position,priority:1" example:"1"`
+	// @Description Song at this position
+	SongId uint `json:"song_id" gorm:"index" example:"1"`
+	// @Description Zero-based position in the playlist
+	Position int `json:"position" gorm:"index:idx_playlist_entries_position,priority:2" example:"0"`
+	// @Description The song at this position
+	Song *Song `json:"song,omitempty" gorm:"foreignKey:SongId"`
 }
 
 // PlaylistResponse represents the playlist data returned in API responses
@@ -38,15 +85,62 @@ type PlaylistResponse struct {
 	Name string `json:"name" example:"My Favorite Songs"`
 	// @Description User ID who owns the playlist
 	UserId uint `json:"userId" example:"1"`
-	// @Description Songs in the playlist
+	// @Description Playlist type: manual or smart
+	Type string `json:"type" example:"manual"`
+	// @Description Content type of the uploaded cover image
+	CoverContentType string `json:"cover_content_type,omitempty" example:"image/jpeg"`
+	// @Description SHA-256 checksum of the uploaded cover image
+	CoverChecksum string `json:"cover_checksum,omitempty" example:"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"`
+	// @Description When the cover image was uploaded
+	CoverUpdatedAt *time.Time `json:"cover_updated_at,omitempty" example:"2023-01-01T00:00:00Z"`
+	// @Description Rule set of a smart playlist
+	Rules *SmartPlaylistRules `json:"rules,omitempty"`
+	// @Description Ordered playlist entries
+	Entries []PlaylistEntryResponse `json:"entries,omitempty"`
+	// @Description Songs matching the rules of a smart playlist; manual playlists list their songs in entries
 	Songs []SongResponse `json:"songs,omitempty"`
 }
 
+// PlaylistEntryResponse represents a playlist entry returned in API responses
+// @Description Playlist entry response model
+type PlaylistEntryResponse struct {
+	// @Description Unique identifier for the entry
+	ID uint `json:"id" example:"1"`
+	// @Description Song at this position
+	SongId uint `json:"song_id" example:"1"`
+	// @Description Zero-based position in the playlist
+	Position int `json:"position" example:"0"`
+	// @Description The song at this position
+	Song *SongResponse `json:"song,omitempty"`
+}
+
 // PlaylistCreateRequest represents the playlist creation request payload
 // @Description Playlist creation request model
 type PlaylistCreateRequest struct {
 	// @Description Playlist name
 	Name string `json:"name" binding:"required" example:"My Favorite Songs"`
-	// @Description Array of song IDs to include in the playlist
+	// @Description Array of song IDs to include in the playlist, in order (manual playlists only)
 	SongIds []uint `json:"song_ids,omitempty" example:"[1,2,3]"`
+	// @Description Playlist type: manual (default) or smart
+	Type string `json:"type,omitempty" binding:"omitempty,oneof=manual smart" example:"manual"`
+	// @Description Rule set for smart playlists
+	Rules *SmartPlaylistRules `json:"rules,omitempty"`
+}
+
+// PlaylistTracksAddRequest represents the request to insert songs into a playlist
+// @Description Track insert request model
+type PlaylistTracksAddRequest struct {
+	// @Description Song IDs to insert, in order; duplicates are allowed
+	SongIds []uint `json:"song_ids" binding:"required,min=1" example:"[4,5]"`
+	// @Description Zero-based position to insert at; appended to the end when omitted
+	Position *int `json:"position,omitempty" binding:"omitempty,min=0" example:"2"`
+}
+
+// PlaylistTrackMoveRequest represents the request to move an entry within a playlist
+// @Description Track move request model
+type PlaylistTrackMoveRequest struct {
+	// @Description Entry to move
+	EntryId uint `json:"entry_id" binding:"required" example:"7"`
+	// @Description Zero-based target position
+	Position *int `json:"position" binding:"required,min=0" example:"0"`
 }
@@ -0,0 +1,37 @@
+package models
+
+// PlaylistImportEntry describes how one entry of an imported playlist file was resolved
+// @Description Imported playlist entry and the song it was matched to
+type PlaylistImportEntry struct {
+	// @Description Zero-based position of the entry in the file
+	Index int `json:"index" example:"0"`
+	// @Description Title read from the file (or derived from the file name)
+	Title string `json:"title,omitempty" example:"Money"`
+	// @Description Artist read from the file
+	Artist string `json:"artist,omitempty" example:"Pink Floyd"`
+	// @Description Duration read from the file in milliseconds
+	Duration uint `json:"duration,omitempty" example:"382000"`
+	// @Description Location (path or URL) read from the file
+	Location string `json:"location,omitempty" example:"C:\\Music\\Pink Floyd - Money.mp3"`
+	// @Description Song the entry was added as, if any
+	SongId *uint `json:"song_id,omitempty" example:"1"`
+	// @Description How the song was found: stream_url, file_path or title
+	MatchedBy string `json:"matched_by,omitempty" example:"title"`
+	// @Description Match confidence between 0 and 1
+	Score float64 `json:"score,omitempty" example:"0.93"`
+	// @Description Songs that matched equally well, for ambiguous entries
+	Candidates []uint `json:"candidates,omitempty" example:"[1,7]"`
+}
+
+// PlaylistImportReport summarises how the entries of an imported playlist file were matched
+// @Description Playlist import report
+type PlaylistImportReport struct {
+	// @Description Number of entries in the file
+	Total int `json:"total" example:"12"`
+	// @Description Entries matched to exactly one song
+	Matched []PlaylistImportEntry `json:"matched"`
+	// @Description Entries that matched several songs equally well
+	Ambiguous []PlaylistImportEntry `json:"ambiguous"`
+	// @Description Entries with no matching song
+	Unmatched []PlaylistImportEntry `json:"unmatched"`
+}
@@ -0,0 +1,31 @@
+package models
+
+// Roles a user can have, from least to most privileged
+const (
+	RoleListener  = "listener"
+	RoleArtist    = "artist"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+// Roles lists every role
+var Roles = []string{RoleListener, RoleArtist, RoleModerator, RoleAdmin}
+
+// Permissions checked by the API; services.RolePermissions decides which roles hold them
+const (
+	// PermissionManageLibrary allows managing one's own songs and playlists
+	PermissionManageLibrary = "library:manage"
+	// PermissionManageAlbums allows creating, changing and deleting one's own albums
+	PermissionManageAlbums = "albums:manage"
+	// PermissionModerateContent allows acting on other users' content
+	PermissionModerateContent = "content:moderate"
+	// PermissionManageUsers allows managing accounts, including assigning roles
+	PermissionManageUsers = "users:manage"
+)
+
+// UserRoleUpdateRequest represents the request to change a user's role
+// @Description Role change request model
+type UserRoleUpdateRequest struct {
+	// @Description New role of the user
+	Role string `json:"role" binding:"required,oneof=listener artist moderator admin" example:"moderator"`
+}
@@ -0,0 +1,76 @@
+package models
+
+// SongSearchResult is a song matched by a search with its relevance
+// @Description Song search hit
+type SongSearchResult struct {
+	Song
+	// @Description Relevance score; higher is better
+	Rank float64 `json:"rank" example:"0.42"`
+	// @Description HTML-escaped matching text with the matched words wrapped in <mark> tags
+	Snippet string `json:"snippet,omitempty" example:"<mark>Bohemian</mark> Rhapsody · Queen"`
+}
+
+// AlbumSearchResult is an album matched by a search with its relevance
+// @Description Album search hit
+type AlbumSearchResult struct {
+	Album
+	// @Description Relevance score; higher is better
+	Rank float64 `json:"rank" example:"0.42"`
+	// @Description HTML-escaped matching text with the matched words wrapped in <mark> tags
+	Snippet string `json:"snippet,omitempty" example:"<mark>Dark</mark> Side of the Moon · Pink Floyd"`
+}
+
+// PlaylistSearchResult is a playlist matched by a search with its relevance
+// @Description Playlist search hit
+type PlaylistSearchResult struct {
+	Playlist
+	// @Description Relevance score; higher is better
+	Rank float64 `json:"rank" example:"0.42"`
+	// @Description HTML-escaped matching text with the matched words wrapped in <mark> tags
+	Snippet string `json:"snippet,omitempty" example:"My <mark>Favorite</mark> Songs"`
+}
+
+// ArtistSearchResult is an artist matched by a search with its relevance
+// @Description Artist search hit
+type ArtistSearchResult struct {
+	Artist
+	// @Description Relevance score; higher is better
+	Rank float64 `json:"rank" example:"0.42"`
+	// @Description HTML-escaped matching text with the matched words wrapped in <mark> tags
+	Snippet string `json:"snippet,omitempty" example:"<mark>Pink</mark> Floyd"`
+}
+
+// SearchFacetValue is one value of a facet with the number of matching songs
+// @Description Facet value and count
+type SearchFacetValue struct {
+	// @Description Value to pass back as a filter (year, album ID or duration bucket)
+	Value string `json:"value" example:"1975"`
+	// @Description Human readable label
+	Label string `json:"label" example:"1975"`
+	// @Description Number of matching songs with this value
+	Count int64 `json:"count" example:"3"`
+}
+
+// SearchFacets are aggregations over the songs matching a search
+// @Description Song facets for filter sidebars
+type SearchFacets struct {
+	// @Description Songs per release year
+	Year []SearchFacetValue `json:"year"`
+	// @Description Songs per album
+	Album []SearchFacetValue `json:"album"`
+	// @Description Songs per duration bucket
+	Duration []SearchFacetValue `json:"duration"`
+}
+
+// SearchResponse is the result of a search across songs, albums and playlists
+// @Description Unified search response
+type SearchResponse struct {
+	// @Description The search query
+	Query string `json:"query" example:"bohemian"`
+	// @Description Total number of matches per type
+	Counts map[string]int64 `json:"counts"`
+	// @Description Ranked hits per type (songs, albums, playlists)
+	Results map[string]interface{} `json:"results"`
+	// @Description Facets over the matching songs
+	Facets *SearchFacets `json:"facets,omitempty"`
+}
@@ -0,0 +1,48 @@
+package models
+
+// Playlist types
+const (
+	PlaylistTypeManual = "manual"
+	PlaylistTypeSmart  = "smart"
+)
+
+// SmartPlaylistRules is the stored definition of a smart playlist
+// @Description Rule set evaluated against the user's songs every time the playlist is read
+type SmartPlaylistRules struct {
+	SmartRuleGroup
+	// @Description Sort field, prefixed with "-" for descending order (title, artist, duration, year, album_year, added, play_count, random)
+	Sort string `json:"sort,omitempty" example:"-play_count"`
+	// @Description Maximum number of songs (0 for no limit)
+	Limit int `json:"limit,omitempty" example:"50"`
+}
+
+// SmartRuleGroup combines rules and nested groups with AND ("all") or OR ("any")
+// @Description Group of smart playlist rules
+type SmartRuleGroup struct {
+	// @Description How the rules are combined: all (AND) or any (OR)
+	Match string `json:"match" example:"all"`
+	// @Description Rules in this group
+	Rules []SmartRule `json:"rules,omitempty"`
+	// @Description Nested rule groups
+	Groups []SmartRuleGroup `json:"groups,omitempty"`
+}
+
+// SmartRule is a single condition on a song field
+// @Description Smart playlist rule
+type SmartRule struct {
+	// @Description Song field: title, artist, genre, album, duration, year, album_year, play_count or added
+	Field string `json:"field" example:"title"`
+	// @Description Operator: contains, not_contains, equals, not_equals, starts_with, ends_with, gt, gte, lt, lte, between, in_last_days, not_in_last_days
+	Operator string `json:"operator" example:"contains"`
+	// @Description Value to compare with; a two element array for between
+	Value interface{} `json:"value" swaggertype:"string" example:"love"`
+}
+
+// ValidationError describes a problem with one field of a request
+// @Description Field-level validation error
+type ValidationError struct {
+	// @Description Path of the invalid field
+	Field string `json:"field" example:"rules[0].operator"`
+	// @Description What is wrong with it
+	Message string `json:"message" example:"operator gt is not supported for text fields"`
+}
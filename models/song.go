@@ -21,10 +21,42 @@ type Song struct {
 	Title string `json:"title" example:"Bohemian Rhapsody"`
 	// @Description Song duration in milliseconds
 	Duration uint `json:"duration" example:"157467"`
+	// @Description Song artist as displayed: the names of the primary artists in the credits
+	Artist string `json:"artist,omitempty" example:"Queen"`
+	// @Description Song genre as displayed: the name of the genre it belongs to
+	Genre string `json:"genre,omitempty" example:"Rock"`
+	// @Description ID of the genre the song belongs to
+	GenreId *uint `json:"genre_id,omitempty" example:"1"`
+	// @Description Release year
+	Year int `json:"year,omitempty" example:"1975"`
+	// @Description Track number on the album, unique per disc within the album
+	TrackNumber uint `json:"track_number,omitempty" example:"11"`
+	// @Description Disc number on the album; 1 when the song has a track number on an album but no disc number
+	DiscNumber uint `json:"disc_number,omitempty" example:"1"`
+	// @Description Subtitle of the disc, e.g. "Live at Wembley"
+	DiscSubtitle string `json:"disc_subtitle,omitempty" example:"Bonus Tracks"`
 	// @Description Optional album ID the song belongs to
 	AlbumId *uint `json:"album_id,omitempty" example:"1"`
 	// @Description User ID who owns the song
 	UserId uint `json:"user_id" example:"1"`
+	// @Description Storage key of the uploaded audio file
+	AudioKey string `json:"-"`
+	// @Description Content type of the uploaded audio file
+	AudioContentType string `json:"audio_content_type,omitempty" example:"audio/mpeg"`
+	// @Description Size of the uploaded audio file in bytes
+	AudioSize int64 `json:"audio_size,omitempty" example:"5242880"`
+	// @Description SHA-256 checksum of the uploaded audio file
+	AudioChecksum string `json:"audio_checksum,omitempty" example:"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"`
+	// @Description When the audio file was uploaded
+	AudioUploadedAt *time.Time `json:"audio_uploaded_at,omitempty" example:"2023-01-01T00:00:00Z"`
+	// @Description How many times the song has been played
+	PlayCount uint `json:"play_count" example:"12"`
+	// @Description When the song was last played
+	LastPlayedAt *time.Time `json:"last_played_at,omitempty" example:"2023-01-01T00:00:00Z"`
+	// @Description Artists credited on the song; the artist name is credited as primary artist when no primary artist is given
+	Credits []ArtistCredit `json:"credits,omitempty" gorm:"-"`
+	// @Description Names of the user's tags on the song; when creating, tags the user doesn't have yet are created
+	Tags []string `json:"tags,omitempty" gorm:"-"`
 }
 
 // SongResponse represents the song data returned in API responses
@@ -40,10 +72,40 @@ type SongResponse struct {
 	Title string `json:"title" example:"Bohemian Rhapsody"`
 	// @Description Song duration in milliseconds
 	Duration uint `json:"duration" example:"175000"`
+	// @Description Song artist as displayed: the names of the primary artists in the credits
+	Artist string `json:"artist,omitempty" example:"Queen"`
+	// @Description Song genre as displayed: the name of the genre it belongs to
+	Genre string `json:"genre,omitempty" example:"Rock"`
+	// @Description ID of the genre the song belongs to
+	GenreId *uint `json:"genre_id,omitempty" example:"1"`
+	// @Description Release year
+	Year int `json:"year,omitempty" example:"1975"`
+	// @Description Track number on the album, unique per disc within the album
+	TrackNumber uint `json:"track_number,omitempty" example:"11"`
+	// @Description Disc number on the album; 1 when the song has a track number on an album but no disc number
+	DiscNumber uint `json:"disc_number,omitempty" example:"1"`
+	// @Description Subtitle of the disc, e.g. "Live at Wembley"
+	DiscSubtitle string `json:"disc_subtitle,omitempty" example:"Bonus Tracks"`
 	// @Description Optional album ID the song belongs to
 	AlbumId *uint `json:"album_id,omitempty" example:"1"`
 	// @Description User ID who owns the song
 	UserId uint `json:"user_id" example:"1"`
+	// @Description Content type of the uploaded audio file
+	AudioContentType string `json:"audio_content_type,omitempty" example:"audio/mpeg"`
+	// @Description Size of the uploaded audio file in bytes
+	AudioSize int64 `json:"audio_size,omitempty" example:"5242880"`
+	// @Description SHA-256 checksum of the uploaded audio file
+	AudioChecksum string `json:"audio_checksum,omitempty" example:"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"`
+	// @Description When the audio file was uploaded
+	AudioUploadedAt *time.Time `json:"audio_uploaded_at,omitempty" example:"2023-01-01T00:00:00Z"`
+	// @Description How many times the song has been played
+	PlayCount uint `json:"play_count" example:"12"`
+	// @Description When the song was last played
+	LastPlayedAt *time.Time `json:"last_played_at,omitempty" example:"2023-01-01T00:00:00Z"`
+	// @Description Artists credited on the song
+	Credits []ArtistCredit `json:"credits,omitempty"`
+	// @Description Names of the user's tags on the song
+	Tags []string `json:"tags,omitempty"`
 }
 
 // SongCreateRequest represents the song creation request payload
@@ -53,6 +115,97 @@ type SongCreateRequest struct {
 	Title string `json:"title" binding:"required" example:"Bohemian Rhapsody"`
 	// @Description Song duration in millseconds
 	Duration uint `json:"duration" binding:"required" example:"175000"`
+	// @Description Song artist, credited as primary artist unless credits name one
+	Artist string `json:"artist,omitempty" example:"Queen"`
+	// @Description Song genre; a genre of that name is created if the user has none
+	Genre string `json:"genre,omitempty" example:"Rock"`
+	// @Description ID of a genre of the user, taking precedence over genre
+	GenreId *uint `json:"genre_id,omitempty" example:"1"`
+	// @Description Release year
+	Year int `json:"year,omitempty" example:"1975"`
+	// @Description Track number on the album, unique per disc within the album
+	TrackNumber uint `json:"track_number,omitempty" example:"11"`
+	// @Description Disc number on the album; 1 when the song has a track number on an album but no disc number
+	DiscNumber uint `json:"disc_number,omitempty" example:"1"`
+	// @Description Subtitle of the disc, e.g. "Live at Wembley"
+	DiscSubtitle string `json:"disc_subtitle,omitempty" example:"Bonus Tracks"`
 	// @Description Optional album ID the song belongs to
 	AlbumId *uint `json:"album_id,omitempty" example:"1"`
+	// @Description Artists credited on the song, replacing the current credits; when omitted only the primary artist follows artist
+	Credits []ArtistCredit `json:"credits,omitempty"`
+	// @Description Names of tags, replacing the current tags; tags the user doesn't have yet are created. The tags are kept when omitted.
+	Tags []string `json:"tags,omitempty" binding:"omitempty,dive,max=100" example:"road trip,summer"`
+}
+
+// SongTags represents the metadata extracted from a song's audio file
+// @Description Tags read from ID3, Vorbis comment or MP4 metadata
+type SongTags struct {
+	// @Description Detected container format
+	Format string `json:"format" example:"mp3"`
+	// @Description Track title
+	Title string `json:"title,omitempty" example:"Bohemian Rhapsody"`
+	// @Description Track artist
+	Artist string `json:"artist,omitempty" example:"Queen"`
+	// @Description Album title
+	Album string `json:"album,omitempty" example:"A Night at the Opera"`
+	// @Description Album artist
+	AlbumArtist string `json:"album_artist,omitempty" example:"Queen"`
+	// @Description Genre
+	Genre string `json:"genre,omitempty" example:"Rock"`
+	// @Description Release year
+	Year int `json:"year,omitempty" example:"1975"`
+	// @Description Track number
+	TrackNumber uint `json:"track_number,omitempty" example:"11"`
+	// @Description Total tracks on the disc
+	TrackTotal uint `json:"track_total,omitempty" example:"12"`
+	// @Description Disc number
+	DiscNumber uint `json:"disc_number,omitempty" example:"1"`
+	// @Description Total discs
+	DiscTotal uint `json:"disc_total,omitempty" example:"1"`
+	// @Description Real duration in milliseconds
+	Duration uint `json:"duration,omitempty" example:"354320"`
+	// @Description Whether the file embeds cover art
+	HasPicture bool `json:"has_picture" example:"true"`
+}
+
+// SongTagsPreview shows what applying the extracted tags would change
+// @Description Tag preview model
+type SongTagsPreview struct {
+	// @Description Tags extracted from the audio file
+	Tags SongTags `json:"tags"`
+	// @Description Song fields that would change, keyed by field name
+	Changes map[string]interface{} `json:"changes"`
+	// @Description What would happen to the album: link (existing album), create or none
+	AlbumAction string `json:"album_action" example:"link"`
+	// @Description Matching album owned by the user, if any
+	AlbumId *uint `json:"album_id,omitempty" example:"1"`
+}
+
+// SongTagsApplyRequest represents the request to commit extracted tags to a song
+// @Description Tag apply request model
+type SongTagsApplyRequest struct {
+	// @Description Fields to apply (title, artist, genre, year, track_number, disc_number, duration); all when omitted
+	Fields []string `json:"fields,omitempty" example:"title,duration"`
+	// @Description Album handling: link to a matching album, create it when missing, or leave the album alone
+	Album string `json:"album,omitempty" binding:"omitempty,oneof=link create none" example:"create"`
+}
+
+// TracklistEntry places a song of an album in the tracklist
+// @Description Tracklist entry model
+type TracklistEntry struct {
+	// @Description ID of a song on the album
+	SongId uint `json:"song_id" binding:"required" example:"1"`
+	// @Description Disc number; the disc of the previous entry, or 1 for the first, when omitted
+	DiscNumber uint `json:"disc_number,omitempty" example:"1"`
+	// @Description Track number; one after the previous entry on the same disc when omitted
+	TrackNumber uint `json:"track_number,omitempty" example:"1"`
+	// @Description Disc subtitle; the current one is kept when omitted
+	DiscSubtitle *string `json:"disc_subtitle,omitempty" example:"Bonus Tracks"`
+}
+
+// TracklistRequest represents the request to renumber and reorder every song on an album
+// @Description Tracklist request model
+type TracklistRequest struct {
+	// @Description Every song on the album exactly once, in tracklist order
+	Tracks []TracklistEntry `json:"tracks" binding:"required,dive"`
 }
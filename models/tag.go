@@ -0,0 +1,71 @@
+package models
+
+import (
+	"time"
+)
+
+// Tag is a free-form label a user puts on songs and albums, unlike the tags read from audio files
+// @Description Tag model
+type Tag struct {
+	// @Description Unique identifier for the tag
+	ID uint `json:"id" gorm:"primarykey" example:"1"`
+	// @Description When the tag was created
+	CreatedAt time.Time `json:"created_at" example:"2023-01-01T00:00:00Z"`
+	// @Description When the tag was last updated
+	UpdatedAt time.Time `json:"updated_at" example:"2023-01-01T00:00:00Z"`
+	// @Description Tag name, unique per user ignoring case
+	Name string `json:"name" example:"road trip"`
+	// @Description User ID who owns the tag
+	UserId uint `json:"user_id" gorm:"index" example:"1"`
+}
+
+// TagUsage is a tag with how many songs and albums carry it
+// @Description Tag with usage counts
+type TagUsage struct {
+	Tag
+	// @Description Number of songs with the tag, not counting deleted ones
+	SongCount int64 `json:"song_count" example:"12"`
+	// @Description Number of albums with the tag, not counting deleted ones
+	AlbumCount int64 `json:"album_count" example:"2"`
+}
+
+// TagCreateRequest represents the tag creation and rename request payload
+// @Description Tag creation request model
+type TagCreateRequest struct {
+	// @Description Tag name
+	Name string `json:"name" binding:"required,max=100" example:"road trip"`
+}
+
+// TagApplyRequest names the tags put on or taken off songs and albums
+// @Description Tag apply request model
+type TagApplyRequest struct {
+	// @Description Tag names; when tagging, tags the user doesn't have yet are created
+	Tags []string `json:"tags" binding:"required,min=1,dive,required,max=100" example:"road trip,summer"`
+	// @Description IDs of songs of the user
+	SongIds []uint `json:"song_ids,omitempty" example:"1,2"`
+	// @Description IDs of albums of the user
+	AlbumIds []uint `json:"album_ids,omitempty" example:"1"`
+}
+
+// TagMergeRequest names the tag another tag is merged into
+// @Description Tag merge request model
+type TagMergeRequest struct {
+	// @Description ID of the tag that takes over the songs and albums of the merged tag
+	TargetTagId uint `json:"target_tag_id" binding:"required" example:"2"`
+}
+
+// TaggedSong stores a tag on a song
+type TaggedSong struct {
+	SongId uint `gorm:"primaryKey;autoIncrement:false"`
+	TagId  uint `gorm:"primaryKey;autoIncrement:false"`
+	// Name is read from the tag
+	Name string `gorm:"->"`
+}
+
+// TaggedAlbum stores a tag on an album
+type TaggedAlbum struct {
+	AlbumId uint `gorm:"primaryKey;autoIncrement:false"`
+	TagId   uint `gorm:"primaryKey;autoIncrement:false"`
+	// Name is read from the tag
+	Name string `gorm:"->"`
+}
@@ -0,0 +1,116 @@
+package models
+
+import (
+	"time"
+)
+
+// RefreshToken represents a long-lived token used to obtain new access tokens
+// @Description Refresh token model (only the hash of the token is stored)
+type RefreshToken struct {
+	// @Description Unique identifier for the refresh token
+	ID uint `json:"id" gorm:"primarykey" example:"1"`
+	// @Description When the refresh token was issued
+	CreatedAt time.Time `json:"created_at" example:"2023-01-01T00:00:00Z"`
+	// @Description When the refresh token was last updated
+	UpdatedAt time.Time `json:"updated_at" example:"2023-01-01T00:00:00Z"`
+	// @Description User ID the refresh token belongs to
+	UserId uint `json:"user_id" gorm:"index" example:"1"`
+	// @Description SHA-256 hash of the refresh token
+	TokenHash string `json:"-" gorm:"uniqueIndex"`
+	// @Description Token family shared by every token rotated from the same login
+	FamilyId string `json:"family_id" gorm:"index" example:"6f1c2a9e0b7d4c3a"`
+	// @Description When the refresh token expires
+	ExpiresAt time.Time `json:"expires_at" example:"2023-01-31T00:00:00Z"`
+	// @Description When the refresh token was revoked or rotated
+	RevokedAt *time.Time `json:"revoked_at,omitempty" example:"2023-01-02T00:00:00Z"`
+}
+
+// RevokedToken represents an access token that was revoked before it expired
+// @Description Access token denylist entry keyed by the token's jti claim
+type RevokedToken struct {
+	// @Description Unique identifier for the denylist entry
+	ID uint `json:"id" gorm:"primarykey" example:"1"`
+	// @Description When the access token was revoked
+	CreatedAt time.Time `json:"created_at" example:"2023-01-01T00:00:00Z"`
+	// @Description JWT ID of the revoked access token
+	Jti string `json:"jti" gorm:"uniqueIndex" example:"9b1deb4d3b7d4bad9bdd2b0d7b3dcb6d"`
+	// @Description When the revoked access token would have expired
+	ExpiresAt time.Time `json:"expires_at" gorm:"index" example:"2023-01-01T00:15:00Z"`
+}
+
+// Purposes of account tokens
+const (
+	AccountTokenVerifyEmail   = "verify_email"
+	AccountTokenResetPassword = "reset_password"
+)
+
+// AccountToken represents a single-use token sent by email to verify an address or reset a password
+// @Description Account token model (only the hash of the token is stored)
+type AccountToken struct {
+	// @Description Unique identifier for the account token
+	ID uint `json:"id" gorm:"primarykey" example:"1"`
+	// @Description When the account token was issued
+	CreatedAt time.Time `json:"created_at" example:"2023-01-01T00:00:00Z"`
+	// @Description User ID the account token belongs to
+	UserId uint `json:"user_id" gorm:"index" example:"1"`
+	// @Description What the token is for (verify_email or reset_password)
+	Purpose string `json:"purpose" example:"verify_email"`
+	// @Description Email address the token was sent to
+	Email string `json:"email" example:"john@example.com"`
+	// @Description SHA-256 hash of the token
+	TokenHash string `json:"-" gorm:"uniqueIndex"`
+	// @Description When the account token expires
+	ExpiresAt time.Time `json:"expires_at" example:"2023-01-01T01:00:00Z"`
+	// @Description When the account token was used or superseded
+	UsedAt *time.Time `json:"used_at,omitempty" example:"2023-01-01T00:10:00Z"`
+}
+
+// TokenResponse represents the tokens returned after login or refresh
+// @Description Token pair response model
+type TokenResponse struct {
+	// @Description Short-lived access token (kept as "token" for existing clients)
+	Token string `json:"token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	// @Description Rotating refresh token
+	RefreshToken string `json:"refresh_token" example:"q3X9...Zk"`
+	// @Description Token type
+	TokenType string `json:"token_type" example:"Bearer"`
+	// @Description Access token lifetime in seconds
+	ExpiresIn int64 `json:"expires_in" example:"900"`
+}
+
+// RefreshTokenRequest represents the refresh request payload
+// @Description Refresh request model
+type RefreshTokenRequest struct {
+	// @Description Refresh token issued by login or a previous refresh
+	RefreshToken string `json:"refresh_token" binding:"required" example:"q3X9...Zk"`
+}
+
+// LogoutRequest represents the logout request payload
+// @Description Logout request model
+type LogoutRequest struct {
+	// @Description Refresh token to revoke; when omitted every refresh token of the user is revoked
+	RefreshToken string `json:"refresh_token,omitempty" example:"q3X9...Zk"`
+}
+
+// EmailVerificationRequest represents the request to verify an email address
+// @Description Email verification request model
+type EmailVerificationRequest struct {
+	// @Description Token from the verification email
+	Token string `json:"token" binding:"required" example:"Jx4...Qw"`
+}
+
+// EmailRequest represents a request naming an account by its email address
+// @Description Request model for resending the verification email and for forgotten passwords
+type EmailRequest struct {
+	// @Description Email address of the account
+	Email string `json:"email" binding:"required,email" example:"john@example.com"`
+}
+
+// PasswordResetRequest represents the request to set a new password with a reset token
+// @Description Password reset request model
+type PasswordResetRequest struct {
+	// @Description Token from the password reset email
+	Token string `json:"token" binding:"required" example:"Jx4...Qw"`
+	// @Description New password
+	NewPassword string `json:"new_password" binding:"required,min=6" example:"n3w-password"`
+}
@@ -0,0 +1,85 @@
+package models
+
+import (
+	"time"
+)
+
+// RecoveryCode represents a single-use code that replaces a TOTP code when the authenticator is lost
+// @Description Recovery code model (only the hash of the code is stored)
+type RecoveryCode struct {
+	// @Description Unique identifier for the recovery code
+	ID uint `json:"id" gorm:"primarykey" example:"1"`
+	// @Description When the recovery code was generated
+	CreatedAt time.Time `json:"created_at" example:"2023-01-01T00:00:00Z"`
+	// @Description User ID the recovery code belongs to
+	UserId uint `json:"user_id" gorm:"index" example:"1"`
+	// @Description SHA-256 hash of the recovery code
+	CodeHash string `json:"-"`
+	// @Description When the recovery code was used
+	UsedAt *time.Time `json:"used_at,omitempty" example:"2023-01-02T00:00:00Z"`
+}
+
+// TwoFactorSetupResponse represents a new TOTP secret waiting to be confirmed
+// @Description TOTP enrolment response model
+type TwoFactorSetupResponse struct {
+	// @Description Base32 TOTP secret for entering into an authenticator app by hand
+	Secret string `json:"secret" example:"JBSWY3DPEHPK3PXP"`
+	// @Description otpauth:// URI carrying the secret
+	OTPAuthURL string `json:"otpauth_url" example:"otpauth://totp/Music%20Library:john@example.com?issuer=Music+Library&secret=JBSWY3DPEHPK3PXP"`
+	// @Description QR code of the otpauth:// URI as a PNG data URI, usable as an image source
+	QRCode string `json:"qr_code" example:"data:image/png;base64,iVBORw0KGgo..."`
+}
+
+// TwoFactorStatusResponse represents the two-factor state of the authenticated user
+// @Description Two-factor status response model
+type TwoFactorStatusResponse struct {
+	// @Description Whether login needs a code from an authenticator app
+	Enabled bool `json:"enabled" example:"true"`
+	// @Description When two-factor authentication was confirmed
+	EnabledAt *time.Time `json:"enabled_at,omitempty" example:"2023-01-01T00:10:00Z"`
+	// @Description How many unused recovery codes are left
+	RecoveryCodesRemaining int64 `json:"recovery_codes_remaining" example:"10"`
+}
+
+// TwoFactorCodeRequest represents a request carrying a code from the authenticator app
+// @Description Two-factor code request model
+type TwoFactorCodeRequest struct {
+	// @Description Six digit TOTP code, or a recovery code where allowed
+	Code string `json:"code" binding:"required" example:"123456"`
+}
+
+// TwoFactorDisableRequest represents the request to turn two-factor authentication off
+// @Description Two-factor disable request model
+type TwoFactorDisableRequest struct {
+	// @Description Password of the account
+	Password string `json:"password" binding:"required" example:"password123"`
+	// @Description Six digit TOTP code or a recovery code
+	Code string `json:"code" binding:"required" example:"123456"`
+}
+
+// RecoveryCodesResponse represents newly generated recovery codes, which are only shown once
+// @Description Recovery codes response model
+type RecoveryCodesResponse struct {
+	// @Description Single-use recovery codes
+	RecoveryCodes []string `json:"recovery_codes" example:"k3m9x-2pq7d,8vn4c-w6hrt"`
+}
+
+// MFAChallengeResponse represents the first step of a login to an account with two-factor authentication
+// @Description MFA challenge response model
+type MFAChallengeResponse struct {
+	// @Description Always true; the login is finished by POST /api/auth/2fa/verify
+	MFARequired bool `json:"mfa_required" example:"true"`
+	// @Description Short-lived single-use challenge token
+	MFAToken string `json:"mfa_token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	// @Description Challenge token lifetime in seconds
+	ExpiresIn int64 `json:"expires_in" example:"300"`
+}
+
+// MFAVerifyRequest represents the second step of a login to an account with two-factor authentication
+// @Description MFA verification request model
+type MFAVerifyRequest struct {
+	// @Description Challenge token returned by login
+	MFAToken string `json:"mfa_token" binding:"required" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	// @Description Six digit TOTP code or a recovery code
+	Code string `json:"code" binding:"required" example:"123456"`
+}
@@ -3,7 +3,6 @@ package models
 import (
 	"time"
 
-	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
@@ -22,7 +21,7 @@ type User struct {
 	Name string `json:"name" example:"John Doe"`
 	// @Description User's email address (unique)
 	Email string `json:"email" gorm:"unique" example:"john@example.com"`
-	// @Description User's password (hashed)
+	// @Description User's password (hashed); empty for accounts created by an identity provider login
 	Password string `json:"password" example:"password123"`
 	// @Description User's albums
 	Albums []Album `json:"albums,omitempty" gorm:"foreignKey:UserId"`
@@ -30,6 +29,20 @@ type User struct {
 	Songs []Song `json:"songs,omitempty" gorm:"foreignKey:UserId"`
 	// @Description User's role in the system
 	Role string `json:"role" example:"artist"`
+	// @Description When an admin suspended the account; suspended users cannot log in or use their tokens
+	SuspendedAt *time.Time `json:"suspended_at,omitempty" example:"2023-01-02T00:00:00Z"`
+	// @Description Why the account was suspended
+	SuspensionReason string `json:"suspension_reason,omitempty" example:"Spam uploads"`
+	// @Description Whether the user must change their password before doing anything else
+	PasswordResetRequired bool `json:"password_reset_required" example:"false"`
+	// @Description When the user confirmed their email address
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty" example:"2023-01-01T00:10:00Z"`
+	// @Description TOTP secret, set once two-factor authentication is being set up
+	TOTPSecret string `json:"-"`
+	// @Description When two-factor authentication was confirmed; login needs a code from then on
+	TOTPEnabledAt *time.Time `json:"totp_enabled_at,omitempty" example:"2023-01-01T00:10:00Z"`
+	// @Description Last TOTP time step accepted, so a code cannot be used twice
+	TOTPLastStep int64 `json:"-"`
 }
 
 // UserResponse represents the user data returned in API responses
@@ -47,6 +60,16 @@ type UserResponse struct {
 	Email string `json:"email" example:"john@example.com"`
 	// @Description User's role in the system
 	Role string `json:"role" example:"artist"`
+	// @Description When an admin suspended the account
+	SuspendedAt *time.Time `json:"suspended_at,omitempty" example:"2023-01-02T00:00:00Z"`
+	// @Description Why the account was suspended
+	SuspensionReason string `json:"suspension_reason,omitempty" example:"Spam uploads"`
+	// @Description Whether the user must change their password before doing anything else
+	PasswordResetRequired bool `json:"password_reset_required" example:"false"`
+	// @Description When the user confirmed their email address
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty" example:"2023-01-01T00:10:00Z"`
+	// @Description Whether login needs a code from an authenticator app
+	TwoFactorEnabled bool `json:"two_factor_enabled" example:"false"`
 	// @Description User's albums
 	Albums []AlbumResponse `json:"albums,omitempty"`
 	// @Description User's songs
@@ -71,15 +94,42 @@ type UserRegisterRequest struct {
 	Email string `json:"email" binding:"required,email" example:"john@example.com"`
 	// @Description User's password
 	Password string `json:"password" binding:"required,min=6" example:"password123"`
-	// @Description User's role in the system
-	Role string `json:"role" binding:"required" example:"artist"`
+	// @Description Role to register with; moderators and admins are appointed by an admin (default listener)
+	Role string `json:"role,omitempty" binding:"omitempty,oneof=listener artist" example:"artist"`
+}
+
+// PasswordChangeRequest represents the request to change one's own password
+// @Description Password change request model
+type PasswordChangeRequest struct {
+	// @Description Password currently in use
+	CurrentPassword string `json:"current_password" binding:"required" example:"password123"`
+	// @Description New password
+	NewPassword string `json:"new_password" binding:"required,min=6" example:"n3w-password"`
+}
+
+// UserSuspendRequest represents the request to suspend a user
+// @Description Suspension request model
+type UserSuspendRequest struct {
+	// @Description Why the account is suspended
+	Reason string `json:"reason" binding:"required,max=500" example:"Spam uploads"`
+}
+
+// RecordRemoveRequest represents the request to remove another user's song, album or playlist
+// @Description Removal request model
+type RecordRemoveRequest struct {
+	// @Description Why the record is removed
+	Reason string `json:"reason" binding:"required,max=500" example:"Copyright claim"`
 }
 
-func (u *User) BeforeCreate(tx *gorm.DB) (err error) {
-	hashed, err := bcrypt.GenerateFromPassword([]byte(u.Password), bcrypt.DefaultCost)
-	if err != nil {
-		return err
-	}
-	u.Password = string(hashed)
-	return nil
+// UserLibraryResponse represents everything a user owns
+// @Description A user's library as seen by an admin
+type UserLibraryResponse struct {
+	// @Description Owner of the library
+	User UserResponse `json:"user"`
+	// @Description User's songs
+	Songs []Song `json:"songs"`
+	// @Description User's albums with their songs
+	Albums []Album `json:"albums"`
+	// @Description User's playlists with their tracks
+	Playlists []Playlist `json:"playlists"`
 }
@@ -0,0 +1,96 @@
+package playlistfile
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// parseM3U reads plain and extended M3U; locations without #EXTINF become entries without details
+func parseM3U(text string) Playlist {
+	var playlist Playlist
+	var pending *Entry
+
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, "#EXTINF:"):
+			entry := parseExtInf(strings.TrimPrefix(line, "#EXTINF:"))
+			pending = &entry
+		case strings.HasPrefix(line, "#PLAYLIST:"):
+			playlist.Title = strings.TrimSpace(strings.TrimPrefix(line, "#PLAYLIST:"))
+		case strings.HasPrefix(line, "#EXTALB:"):
+			if pending != nil {
+				pending.Album = strings.TrimSpace(strings.TrimPrefix(line, "#EXTALB:"))
+			}
+		case strings.HasPrefix(line, "#"):
+			continue
+		default:
+			entry := Entry{}
+			if pending != nil {
+				entry = *pending
+				pending = nil
+			}
+			entry.Location = line
+			playlist.Entries = append(playlist.Entries, entry)
+		}
+	}
+
+	return playlist
+}
+
+// parseExtInf reads "duration [attributes],Artist - Title", or just "Artist - Title" from writers that leave out the duration
+func parseExtInf(value string) Entry {
+	info, display, found := strings.Cut(value, ",")
+	if _, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64); !found && err != nil {
+		info, display = "", value
+	}
+
+	var entry Entry
+	if fields := strings.Fields(info); len(fields) > 0 {
+		if length, err := strconv.ParseInt(fields[0], 10, 64); err == nil {
+			entry.Duration = seconds(length)
+		}
+	}
+	entry.Artist, entry.Title = SplitArtistTitle(display)
+	return entry
+}
+
+func writeM3U(w io.Writer, playlist Playlist) error {
+	out := bufio.NewWriter(w)
+	fmt.Fprintln(out, "#EXTM3U")
+	if playlist.Title != "" {
+		fmt.Fprintf(out, "#PLAYLIST:%s\n", singleLine(playlist.Title))
+	}
+
+	for _, entry := range playlist.Entries {
+		length := int64(-1)
+		if entry.Duration > 0 {
+			length = int64(entry.Duration.Seconds() + 0.5)
+		}
+		fmt.Fprintf(out, "#EXTINF:%d,%s\n", length, singleLine(displayName(entry)))
+		if entry.Album != "" {
+			fmt.Fprintf(out, "#EXTALB:%s\n", singleLine(entry.Album))
+		}
+		fmt.Fprintln(out, singleLine(entry.Location))
+	}
+
+	return out.Flush()
+}
+
+// displayName joins artist and title the way players show them
+func displayName(entry Entry) string {
+	if entry.Artist == "" {
+		return entry.Title
+	}
+	return entry.Artist + " - " + entry.Title
+}
+
+// singleLine keeps user supplied text from breaking line based formats
+func singleLine(value string) string {
+	return strings.Join(strings.Fields(value), " ")
+}
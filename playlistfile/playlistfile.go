@@ -0,0 +1,141 @@
+// Package playlistfile reads and writes M3U/M3U8, PLS and XSPF playlist files.
+package playlistfile
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"path"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var (
+	// ErrUnknownFormat is returned for formats other than m3u, m3u8, pls and xspf
+	ErrUnknownFormat = errors.New("unknown playlist format")
+	// ErrMalformed is returned when a file claims a format but cannot be parsed
+	ErrMalformed = errors.New("malformed playlist file")
+)
+
+// Supported formats
+const (
+	FormatM3U  = "m3u"
+	FormatM3U8 = "m3u8"
+	FormatPLS  = "pls"
+	FormatXSPF = "xspf"
+)
+
+// Entry is one track of a playlist file; any field may be empty
+type Entry struct {
+	Title    string
+	Artist   string
+	Album    string
+	Duration time.Duration
+	Location string
+}
+
+// Playlist is the content of a playlist file
+type Playlist struct {
+	Title   string
+	Entries []Entry
+}
+
+// NormalizeFormat validates a format name, accepting an optional leading dot and any case
+func NormalizeFormat(format string) (string, error) {
+	format = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(format), "."))
+	switch format {
+	case FormatM3U, FormatM3U8, FormatPLS, FormatXSPF:
+		return format, nil
+	}
+	return "", ErrUnknownFormat
+}
+
+// Detect works out the format from the file name, falling back to the content
+func Detect(filename string, data []byte) (string, error) {
+	if format, err := NormalizeFormat(path.Ext(filename)); err == nil {
+		return format, nil
+	}
+
+	head := bytes.TrimSpace(bytes.TrimPrefix(data, utf8BOM))
+	switch {
+	case bytes.HasPrefix(head, []byte("#EXTM3U")):
+		return FormatM3U8, nil
+	case bytes.HasPrefix(bytes.ToLower(head), []byte("[playlist]")):
+		return FormatPLS, nil
+	case bytes.HasPrefix(head, []byte("<?xml")) || bytes.HasPrefix(head, []byte("<playlist")):
+		return FormatXSPF, nil
+	}
+	return "", ErrUnknownFormat
+}
+
+// Parse reads a playlist file in the given format
+func Parse(format string, data []byte) (Playlist, error) {
+	switch format {
+	case FormatM3U, FormatM3U8:
+		return parseM3U(decodeText(data)), nil
+	case FormatPLS:
+		return parsePLS(decodeText(data))
+	case FormatXSPF:
+		return parseXSPF(data)
+	}
+	return Playlist{}, ErrUnknownFormat
+}
+
+// Write encodes the playlist in the given format
+func Write(w io.Writer, format string, playlist Playlist) error {
+	switch format {
+	case FormatM3U, FormatM3U8:
+		return writeM3U(w, playlist)
+	case FormatPLS:
+		return writePLS(w, playlist)
+	case FormatXSPF:
+		return writeXSPF(w, playlist)
+	}
+	return ErrUnknownFormat
+}
+
+// ContentType returns the MIME type used when serving a playlist file
+func ContentType(format string) string {
+	switch format {
+	case FormatM3U, FormatM3U8:
+		return "audio/x-mpegurl; charset=utf-8"
+	case FormatPLS:
+		return "audio/x-scpls; charset=utf-8"
+	case FormatXSPF:
+		return "application/xspf+xml; charset=utf-8"
+	}
+	return "application/octet-stream"
+}
+
+// SplitArtistTitle splits the common "Artist - Title" display form
+func SplitArtistTitle(display string) (string, string) {
+	if artist, title, found := strings.Cut(display, " - "); found {
+		return strings.TrimSpace(artist), strings.TrimSpace(title)
+	}
+	return "", strings.TrimSpace(display)
+}
+
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// decodeText strips a UTF-8 BOM and treats invalid UTF-8 as Latin-1, the usual encoding of plain .m3u and .pls files
+func decodeText(data []byte) string {
+	data = bytes.TrimPrefix(data, utf8BOM)
+	if utf8.Valid(data) {
+		return string(data)
+	}
+
+	runes := make([]rune, len(data))
+	for i, b := range data {
+		runes[i] = rune(b)
+	}
+	return string(runes)
+}
+
+// seconds converts a duration in seconds, where negative means unknown
+func seconds(value int64) time.Duration {
+	if value <= 0 {
+		return 0
+	}
+	return time.Duration(value) * time.Second
+}
@@ -0,0 +1,244 @@
+package playlistfile_test
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tushar27x/music-lib-api/playlistfile"
+)
+
+var locations = []string{
+	"Music/Daft Punk/One More Time.mp3",
+	"../shared/Around the World.flac",
+	"/home/ada/Music/Digital Love.ogg",
+	`C:\Music\Aerodynamic.mp3`,
+	"https://music.example.com/songs/7/stream?token=a&expires=2",
+}
+
+func TestRoundTrip(t *testing.T) {
+	playlist := playlistfile.Playlist{
+		Title: `Rock & Roll <Live> "2001"`,
+		Entries: []playlistfile.Entry{
+			{Title: "One More Time", Artist: "Daft Punk", Album: "Discovery", Duration: 5*time.Minute + 20*time.Second, Location: locations[0]},
+			{Title: "Around the World", Artist: "Daft Punk", Duration: 7 * time.Minute, Location: locations[1]},
+			// Entries without an artist or a known duration
+			{Title: "Digital Love", Location: locations[2]},
+			{Title: "Tom & Jerry's <Theme>", Artist: "AC/DC", Location: locations[3]},
+			{Title: "Señorita", Artist: "Zoë", Duration: time.Second, Location: locations[4]},
+		},
+	}
+
+	for _, format := range []string{playlistfile.FormatM3U, playlistfile.FormatM3U8, playlistfile.FormatPLS, playlistfile.FormatXSPF} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := playlistfile.Write(&buf, format, playlist); err != nil {
+				t.Fatal(err)
+			}
+			detected, err := playlistfile.Detect("", buf.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if format == playlistfile.FormatM3U {
+				// Plain and extended M3U are told apart by the extension only
+				detected = format
+			}
+			if detected != format {
+				t.Errorf("detected %q, want %q", detected, format)
+			}
+			got, err := playlistfile.Parse(format, buf.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want := playlist
+			switch format {
+			case playlistfile.FormatPLS:
+				// PLS has no playlist title or albums
+				want = playlistfile.Playlist{Entries: append([]playlistfile.Entry(nil), playlist.Entries...)}
+				for i := range want.Entries {
+					want.Entries[i].Album = ""
+				}
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("round trip = %+v\nwant %+v\nfile:\n%s", got, want, buf.String())
+			}
+		})
+	}
+}
+
+func TestWriteXSPFEscapesText(t *testing.T) {
+	var buf bytes.Buffer
+	err := playlistfile.Write(&buf, playlistfile.FormatXSPF, playlistfile.Playlist{
+		Title:   `Rock & Roll <Live>`,
+		Entries: []playlistfile.Entry{{Title: "</title><script>", Location: locations[4]}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, raw := range []string{"Rock & Roll", "<Live>", "</title><script>", "token=a&expires"} {
+		if strings.Contains(out, raw) {
+			t.Errorf("output contains %q unescaped:\n%s", raw, out)
+		}
+	}
+	for _, escaped := range []string{"Rock &amp; Roll &lt;Live&gt;", "token=a&amp;expires"} {
+		if !strings.Contains(out, escaped) {
+			t.Errorf("output lacks %q:\n%s", escaped, out)
+		}
+	}
+}
+
+func TestWriteKeepsLineFormatsOnOneLine(t *testing.T) {
+	playlist := playlistfile.Playlist{
+		Title:   "Line\nbreak",
+		Entries: []playlistfile.Entry{{Title: "Title\r\n#EXTINF:1,Injected", Location: "a.mp3\nb.mp3"}},
+	}
+	for _, format := range []string{playlistfile.FormatM3U8, playlistfile.FormatPLS} {
+		var buf bytes.Buffer
+		if err := playlistfile.Write(&buf, format, playlist); err != nil {
+			t.Fatal(err)
+		}
+		got, err := playlistfile.Parse(format, buf.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got.Entries) != 1 || got.Entries[0].Location != "a.mp3 b.mp3" {
+			t.Errorf("%s: entries = %+v, want one entry at %q", format, got.Entries, "a.mp3 b.mp3")
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	bom := "\xEF\xBB\xBF"
+	tests := []struct {
+		name   string
+		format string
+		data   string
+		want   playlistfile.Playlist
+		err    error
+	}{
+		{
+			name:   "m3u with a BOM and CRLF",
+			format: playlistfile.FormatM3U8,
+			data:   bom + "#EXTM3U\r\n#PLAYLIST:Road trip\r\n#EXTINF:320,Daft Punk - One More Time\r\n#EXTALB:Discovery\r\nMusic/One More Time.mp3\r\n",
+			want: playlistfile.Playlist{Title: "Road trip", Entries: []playlistfile.Entry{
+				{Title: "One More Time", Artist: "Daft Punk", Album: "Discovery", Duration: 320 * time.Second, Location: "Music/One More Time.mp3"},
+			}},
+		},
+		{
+			name:   "EXTINF without a duration",
+			format: playlistfile.FormatM3U8,
+			data: "#EXTM3U\n" +
+				"#EXTINF:,Daft Punk - Around the World\n/music/a.mp3\n" +
+				"#EXTINF:-1,Digital Love\n/music/b.mp3\n" +
+				"#EXTINF:Aerodynamic\n/music/c.mp3\n" +
+				"#EXTINF:0 tvg-id=\"x\",Voyager\n/music/d.mp3\n",
+			want: playlistfile.Playlist{Entries: []playlistfile.Entry{
+				{Title: "Around the World", Artist: "Daft Punk", Location: "/music/a.mp3"},
+				{Title: "Digital Love", Location: "/music/b.mp3"},
+				{Title: "Aerodynamic", Location: "/music/c.mp3"},
+				{Title: "Voyager", Location: "/music/d.mp3"},
+			}},
+		},
+		{
+			name:   "plain m3u in Latin-1",
+			format: playlistfile.FormatM3U,
+			data:   "# comment\n\nMusic\\Se\xf1orita.mp3\r\n#EXTALB:ignored without EXTINF\nhttp://example.com/a.mp3",
+			want: playlistfile.Playlist{Entries: []playlistfile.Entry{
+				{Location: `Music\Señorita.mp3`},
+				{Location: "http://example.com/a.mp3"},
+			}},
+		},
+		{
+			name:   "pls without NumberOfEntries",
+			format: playlistfile.FormatPLS,
+			data:   bom + "[Playlist]\r\nFile2=../b.mp3\r\nfile1=/music/a.mp3\r\nTitle1=Daft Punk - One More Time\r\nLength1=320\r\nTitle3=No file\r\nLength2=-1\r\n",
+			want: playlistfile.Playlist{Entries: []playlistfile.Entry{
+				{Title: "One More Time", Artist: "Daft Punk", Duration: 320 * time.Second, Location: "/music/a.mp3"},
+				{Location: "../b.mp3"},
+			}},
+		},
+		{
+			name:   "pls with other sections",
+			format: playlistfile.FormatPLS,
+			data:   "[other]\nFile1=skipped.mp3\n[playlist]\n; comment\nFile1=a.mp3\nTitle1=A = B\nNumberOfEntries=1\nVersion=2\n",
+			want: playlistfile.Playlist{Entries: []playlistfile.Entry{
+				{Title: "A = B", Location: "a.mp3"},
+			}},
+		},
+		{
+			name:   "pls without a playlist section",
+			format: playlistfile.FormatPLS,
+			data:   "File1=a.mp3\n",
+			err:    playlistfile.ErrMalformed,
+		},
+		{
+			name:   "xspf with a BOM",
+			format: playlistfile.FormatXSPF,
+			data: bom + `<?xml version="1.0" encoding="UTF-8"?>` + "\r\n" +
+				`<playlist version="1" xmlns="http://xspf.org/ns/0/"><title>Rock &amp; Roll</title><trackList>` +
+				`<track><location>file:///music/a%20b.mp3</location><location>ignored.mp3</location><title> Tom &amp; Jerry </title><creator>AC/DC</creator><duration>1500</duration></track>` +
+				`<track><title>No location</title></track>` +
+				`</trackList></playlist>`,
+			want: playlistfile.Playlist{Title: "Rock & Roll", Entries: []playlistfile.Entry{
+				{Title: "Tom & Jerry", Artist: "AC/DC", Duration: 1500 * time.Millisecond, Location: "file:///music/a%20b.mp3"},
+				{Title: "No location"},
+			}},
+		},
+		{
+			name:   "xspf in another charset",
+			format: playlistfile.FormatXSPF,
+			data:   `<?xml version="1.0" encoding="ISO-8859-1"?><playlist><trackList><track><location>a.mp3</location></track></trackList></playlist>`,
+			want:   playlistfile.Playlist{Entries: []playlistfile.Entry{{Location: "a.mp3"}}},
+		},
+		{
+			name:   "broken xspf",
+			format: playlistfile.FormatXSPF,
+			data:   `<playlist><trackList><track><title>Rock & Roll</title></track></trackList></playlist>`,
+			err:    playlistfile.ErrMalformed,
+		},
+		{
+			name:   "unknown format",
+			format: "wpl",
+			data:   "<smil/>",
+			err:    playlistfile.ErrUnknownFormat,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := playlistfile.Parse(tt.format, []byte(tt.data))
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("error = %v, want %v", err, tt.err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("playlist = %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		filename string
+		data     string
+		want     string
+		err      error
+	}{
+		{"Road Trip.M3U", "", playlistfile.FormatM3U, nil},
+		{"list.pls", "#EXTM3U", playlistfile.FormatPLS, nil},
+		{"upload", "\xEF\xBB\xBF#EXTM3U\r\n", playlistfile.FormatM3U8, nil},
+		{"upload", "\r\n[playlist]\r\n", playlistfile.FormatPLS, nil},
+		{"upload", "\xEF\xBB\xBF<?xml version=\"1.0\"?><playlist/>", playlistfile.FormatXSPF, nil},
+		{"upload.txt", "/music/a.mp3", "", playlistfile.ErrUnknownFormat},
+	}
+	for _, tt := range tests {
+		got, err := playlistfile.Detect(tt.filename, []byte(tt.data))
+		if got != tt.want || !errors.Is(err, tt.err) {
+			t.Errorf("Detect(%q, %q) = %q, %v, want %q, %v", tt.filename, tt.data, got, err, tt.want, tt.err)
+		}
+	}
+}
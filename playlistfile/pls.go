@@ -0,0 +1,109 @@
+package playlistfile
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// parsePLS reads the [playlist] section of a PLS file; FileN, TitleN and LengthN keys are grouped by N
+func parsePLS(text string) (Playlist, error) {
+	entries := map[int]*Entry{}
+	inPlaylist := false
+	sawSection := false
+
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, ";") || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			inPlaylist = strings.EqualFold(line, "[playlist]")
+			sawSection = sawSection || inPlaylist
+			continue
+		}
+		if !inPlaylist {
+			continue
+		}
+
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		var field string
+		for _, prefix := range []string{"file", "title", "length"} {
+			if strings.HasPrefix(key, prefix) {
+				field = prefix
+				break
+			}
+		}
+		if field == "" {
+			continue
+		}
+		index, err := strconv.Atoi(strings.TrimPrefix(key, field))
+		if err != nil {
+			continue
+		}
+
+		entry := entries[index]
+		if entry == nil {
+			entry = &Entry{}
+			entries[index] = entry
+		}
+		switch field {
+		case "file":
+			entry.Location = value
+		case "title":
+			entry.Artist, entry.Title = SplitArtistTitle(value)
+		case "length":
+			if length, err := strconv.ParseInt(value, 10, 64); err == nil {
+				entry.Duration = seconds(length)
+			}
+		}
+	}
+
+	if !sawSection {
+		return Playlist{}, ErrMalformed
+	}
+
+	indexes := make([]int, 0, len(entries))
+	for index, entry := range entries {
+		// A title without a file is not a playable entry
+		if entry.Location != "" {
+			indexes = append(indexes, index)
+		}
+	}
+	sort.Ints(indexes)
+
+	var playlist Playlist
+	for _, index := range indexes {
+		playlist.Entries = append(playlist.Entries, *entries[index])
+	}
+	return playlist, nil
+}
+
+func writePLS(w io.Writer, playlist Playlist) error {
+	out := bufio.NewWriter(w)
+	fmt.Fprintln(out, "[playlist]")
+
+	for i, entry := range playlist.Entries {
+		n := i + 1
+		length := int64(-1)
+		if entry.Duration > 0 {
+			length = int64(entry.Duration.Seconds() + 0.5)
+		}
+		fmt.Fprintf(out, "File%d=%s\n", n, singleLine(entry.Location))
+		fmt.Fprintf(out, "Title%d=%s\n", n, singleLine(displayName(entry)))
+		fmt.Fprintf(out, "Length%d=%d\n", n, length)
+	}
+
+	fmt.Fprintf(out, "NumberOfEntries=%d\n", len(playlist.Entries))
+	fmt.Fprintln(out, "Version=2")
+	return out.Flush()
+}
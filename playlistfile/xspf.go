@@ -0,0 +1,89 @@
+package playlistfile
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"time"
+)
+
+const xspfNamespace = "http://xspf.org/ns/0/"
+
+type xspfPlaylist struct {
+	XMLName xml.Name    `xml:"playlist"`
+	Xmlns   string      `xml:"xmlns,attr,omitempty"`
+	Version string      `xml:"version,attr,omitempty"`
+	Title   string      `xml:"title,omitempty"`
+	Tracks  []xspfTrack `xml:"trackList>track"`
+}
+
+type xspfTrack struct {
+	Locations []string `xml:"location"`
+	Title     string   `xml:"title,omitempty"`
+	Creator   string   `xml:"creator,omitempty"`
+	Album     string   `xml:"album,omitempty"`
+	// Duration is in milliseconds
+	Duration int64 `xml:"duration,omitempty"`
+}
+
+func parseXSPF(data []byte) (Playlist, error) {
+	var doc xspfPlaylist
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	// Declared charsets other than UTF-8 are rare; read them as-is rather than failing
+	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+	if err := decoder.Decode(&doc); err != nil {
+		return Playlist{}, ErrMalformed
+	}
+
+	playlist := Playlist{Title: strings.TrimSpace(doc.Title)}
+	for _, track := range doc.Tracks {
+		entry := Entry{
+			Title:  strings.TrimSpace(track.Title),
+			Artist: strings.TrimSpace(track.Creator),
+			Album:  strings.TrimSpace(track.Album),
+		}
+		if track.Duration > 0 {
+			entry.Duration = time.Duration(track.Duration) * time.Millisecond
+		}
+		if len(track.Locations) > 0 {
+			entry.Location = strings.TrimSpace(track.Locations[0])
+		}
+		playlist.Entries = append(playlist.Entries, entry)
+	}
+	return playlist, nil
+}
+
+func writeXSPF(w io.Writer, playlist Playlist) error {
+	doc := xspfPlaylist{
+		Xmlns:   xspfNamespace,
+		Version: "1",
+		Title:   playlist.Title,
+		Tracks:  make([]xspfTrack, 0, len(playlist.Entries)),
+	}
+	for _, entry := range playlist.Entries {
+		track := xspfTrack{
+			Title:    entry.Title,
+			Creator:  entry.Artist,
+			Album:    entry.Album,
+			Duration: entry.Duration.Milliseconds(),
+		}
+		if entry.Location != "" {
+			track.Locations = []string{entry.Location}
+		}
+		doc.Tracks = append(doc.Tracks, track)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
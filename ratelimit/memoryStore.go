@@ -0,0 +1,65 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often counters of clients that went quiet are dropped
+const sweepInterval = time.Minute
+
+// MemoryStore keeps the counters in the process, so every instance limits on its own
+type MemoryStore struct {
+	mu        sync.Mutex
+	counters  map[string]memoryCounter
+	lastSweep time.Time
+}
+
+type memoryCounter struct {
+	window   time.Duration
+	index    int64
+	current  int64
+	previous int64
+}
+
+// NewMemoryStore returns an empty in-memory counter driver
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{counters: make(map[string]memoryCounter)}
+}
+
+func (s *MemoryStore) Increment(ctx context.Context, key string, window time.Duration, now time.Time) (int64, int64, error) {
+	index := windowIndex(window, now)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.lastSweep) > sweepInterval {
+		s.sweep(now)
+	}
+
+	counter := s.counters[key]
+	switch {
+	case counter.window == window && counter.index == index:
+	case counter.window == window && counter.index == index-1:
+		counter.previous, counter.current = counter.current, 0
+	default:
+		counter.previous, counter.current = 0, 0
+	}
+	counter.window = window
+	counter.index = index
+	counter.current++
+	s.counters[key] = counter
+
+	return counter.current, counter.previous, nil
+}
+
+// sweep drops the counters that no longer affect any limit
+func (s *MemoryStore) sweep(now time.Time) {
+	for key, counter := range s.counters {
+		if counter.index < windowIndex(counter.window, now)-1 {
+			delete(s.counters, key)
+		}
+	}
+	s.lastSweep = now
+}
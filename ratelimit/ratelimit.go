@@ -0,0 +1,151 @@
+// Package ratelimit counts requests per client and tells when a client has sent too many.
+//
+// Requests are counted in fixed windows, and a limit is checked against a sliding window
+// estimated from the current and the previous one: the previous count is weighted by how much
+// of it still overlaps the last Window. This smooths out the bursts fixed windows allow at
+// their edges while only keeping two counters per client. The counters live in the driver
+// selected by RATE_LIMIT_DRIVER: in memory for a single instance, or in Redis (or a
+// compatible server such as Valkey or KeyDB) when several instances share the limits.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/tushar27x/music-lib-api/config"
+)
+
+// Store is implemented by every counter driver
+type Store interface {
+	// Increment counts a request for key in the window containing now and returns the counts
+	// of that window and of the one before it
+	Increment(ctx context.Context, key string, window time.Duration, now time.Time) (current int64, previous int64, err error)
+}
+
+// Counters is the counter driver selected by RATE_LIMIT_DRIVER, or nil when rate limiting is off
+var Counters Store
+
+// InitRateLimiter configures Counters from the environment
+func InitRateLimiter() {
+	var err error
+	driver := strings.ToLower(config.GetEnv("RATE_LIMIT_DRIVER"))
+
+	switch driver {
+	case "", "memory":
+		driver = "memory"
+		Counters = NewMemoryStore()
+	case "redis":
+		Counters, err = NewRedisStore(config.GetEnv("REDIS_URL"))
+	case "none":
+		log.Printf("Rate limiting is disabled")
+		return
+	default:
+		log.Fatalf("❌ Unknown RATE_LIMIT_DRIVER %q (expected memory, redis or none)", driver)
+	}
+
+	if err != nil {
+		log.Fatalf("❌ Error configuring %s rate limiter: %s", driver, err)
+	}
+
+	log.Printf("Using %s rate limiter", driver)
+}
+
+// Limit allows Requests per Window
+type Limit struct {
+	Requests int64
+	Window   time.Duration
+}
+
+// DefaultLimit returns the limit of authenticated API requests per user (RATE_LIMIT, default 100,
+// per RATE_LIMIT_WINDOW, default 1m)
+func DefaultLimit() Limit {
+	return Limit{
+		Requests: config.GetEnvInt64("RATE_LIMIT", 100),
+		Window:   config.GetEnvDuration("RATE_LIMIT_WINDOW", time.Minute),
+	}
+}
+
+// IPLimit returns the limit of API requests per IP address, checked before the request is
+// authenticated (RATE_LIMIT_IP, default 300, per RATE_LIMIT_IP_WINDOW, default 1m). It is higher
+// than DefaultLimit since several users can share an address.
+func IPLimit() Limit {
+	return Limit{
+		Requests: config.GetEnvInt64("RATE_LIMIT_IP", 300),
+		Window:   config.GetEnvDuration("RATE_LIMIT_IP_WINDOW", time.Minute),
+	}
+}
+
+// AuthLimit returns the stricter limit of the authentication endpoints per IP address
+// (RATE_LIMIT_AUTH, default 20, per RATE_LIMIT_AUTH_WINDOW, default 1m)
+func AuthLimit() Limit {
+	return Limit{
+		Requests: config.GetEnvInt64("RATE_LIMIT_AUTH", 20),
+		Window:   config.GetEnvDuration("RATE_LIMIT_AUTH_WINDOW", time.Minute),
+	}
+}
+
+// Policy describes the limit for the RateLimit-Policy header, e.g. "100;w=60"
+func (l Limit) Policy() string {
+	return fmt.Sprintf("%d;w=%d", l.Requests, int64(max(l.Window.Round(time.Second), time.Second)/time.Second))
+}
+
+// Result is the outcome of counting one request
+type Result struct {
+	Allowed bool
+	// Remaining is how many more requests are allowed right now
+	Remaining int64
+	// Reset is how long until the current window ends
+	Reset time.Duration
+	// RetryAfter is how long a client that was turned away has to wait for its next request
+	// to be allowed
+	RetryAfter time.Duration
+}
+
+// Allow counts a request for key and checks it against limit
+func Allow(ctx context.Context, store Store, key string, limit Limit, now time.Time) (Result, error) {
+	current, previous, err := store.Increment(ctx, key, limit.Window, now)
+	if err != nil {
+		return Result{}, err
+	}
+
+	elapsed := time.Duration(now.UnixNano() % int64(limit.Window))
+	weight := float64(limit.Window-elapsed) / float64(limit.Window)
+	used := int64(float64(previous)*weight) + current
+
+	result := Result{
+		Allowed:   used <= limit.Requests,
+		Remaining: max(limit.Requests-used, 0),
+		Reset:     limit.Window - elapsed,
+	}
+	if !result.Allowed {
+		result.RetryAfter = retryAfter(limit, current, previous, elapsed)
+	}
+	return result, nil
+}
+
+// retryAfter returns how long until the previous window weighs little enough for one more request.
+// If the current window is full itself, that is in the next window, where it becomes the previous one.
+func retryAfter(limit Limit, current int64, previous int64, elapsed time.Duration) time.Duration {
+	if current+1 > limit.Requests {
+		return limit.Window - elapsed + overlapUntil(limit, current, 1)
+	}
+	return max(overlapUntil(limit, previous, current+1)-elapsed, 0)
+}
+
+// overlapUntil returns how far into a window the previous count has to fade before current fits
+func overlapUntil(limit Limit, previous int64, current int64) time.Duration {
+	if previous == 0 || current > limit.Requests {
+		return limit.Window
+	}
+	fraction := 1 - float64(limit.Requests-current)/float64(previous)
+	return time.Duration(math.Ceil(max(fraction, 0) * float64(limit.Window)))
+}
+
+// windowIndex numbers the windows since the Unix epoch, so every instance agrees on them
+func windowIndex(window time.Duration, now time.Time) int64 {
+	return now.UnixNano() / int64(window)
+}
@@ -0,0 +1,254 @@
+package ratelimit
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// redisTimeout bounds dialing and every command when the request context has no earlier deadline
+	redisTimeout = 2 * time.Second
+	// redisIdleConns is how many connections are kept open between commands
+	redisIdleConns = 16
+	// redisKeyPrefix keeps the counters apart from other data in a shared server
+	redisKeyPrefix = "ratelimit:"
+)
+
+// incrementScript counts a request in KEYS[1], lets the window expire after ARGV[1] milliseconds
+// and returns the count together with the one in KEYS[2], the window before
+const incrementScript = `
+local current = redis.call('INCR', KEYS[1])
+if current == 1 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+local previous = tonumber(redis.call('GET', KEYS[2]) or '0')
+return {current, previous}
+`
+
+// RedisError is an error reply from the server
+type RedisError string
+
+func (e RedisError) Error() string {
+	return "redis: " + string(e)
+}
+
+// RedisStore keeps the counters in a Redis-compatible server, so every instance shares them.
+// It speaks just enough of the RESP protocol to run the counting script.
+type RedisStore struct {
+	addr     string
+	username string
+	password string
+	db       int
+	tls      *tls.Config
+	idle     chan *redisConn
+}
+
+type redisConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+// NewRedisStore parses a URL such as redis://:password@localhost:6379/0 (rediss:// for TLS)
+func NewRedisStore(rawURL string) (*RedisStore, error) {
+	if rawURL == "" {
+		return nil, errors.New("REDIS_URL is required")
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid REDIS_URL: %w", err)
+	}
+
+	store := &RedisStore{addr: u.Host, idle: make(chan *redisConn, redisIdleConns)}
+	switch u.Scheme {
+	case "redis":
+	case "rediss":
+		store.tls = &tls.Config{ServerName: u.Hostname(), MinVersion: tls.VersionTLS12}
+	default:
+		return nil, fmt.Errorf("invalid REDIS_URL scheme %q (expected redis or rediss)", u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("invalid REDIS_URL %q", rawURL)
+	}
+	if u.Port() == "" {
+		store.addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		store.username = u.User.Username()
+		store.password, _ = u.User.Password()
+	}
+	if db := strings.Trim(u.Path, "/"); db != "" {
+		if store.db, err = strconv.Atoi(db); err != nil || store.db < 0 {
+			return nil, fmt.Errorf("invalid REDIS_URL database %q", db)
+		}
+	}
+
+	return store, nil
+}
+
+func (s *RedisStore) Increment(ctx context.Context, key string, window time.Duration, now time.Time) (int64, int64, error) {
+	index := windowIndex(window, now)
+	// The hash tag keeps both windows of a key in the same slot of a cluster
+	currentKey := fmt.Sprintf("%s{%s}:%d", redisKeyPrefix, key, index)
+	previousKey := fmt.Sprintf("%s{%s}:%d", redisKeyPrefix, key, index-1)
+	// The window has to outlive itself by one more, while it is the previous one
+	ttl := strconv.FormatInt((2 * window).Milliseconds(), 10)
+
+	reply, err := s.do(ctx, "EVAL", incrementScript, "2", currentKey, previousKey, ttl)
+	if err != nil {
+		return 0, 0, err
+	}
+	counts, ok := reply.([]interface{})
+	if !ok || len(counts) != 2 {
+		return 0, 0, fmt.Errorf("redis: unexpected reply %v", reply)
+	}
+	current, ok1 := counts[0].(int64)
+	previous, ok2 := counts[1].(int64)
+	if !ok1 || !ok2 {
+		return 0, 0, fmt.Errorf("redis: unexpected reply %v", reply)
+	}
+	return current, previous, nil
+}
+
+// do sends one command and reads its reply. Connections are reused unless something other than
+// an error reply went wrong, since the stream may be out of step then.
+func (s *RedisStore) do(ctx context.Context, args ...string) (interface{}, error) {
+	conn, err := s.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := conn.command(ctx, args...)
+	var redisErr RedisError
+	if err != nil && !errors.As(err, &redisErr) {
+		conn.Close()
+		return nil, err
+	}
+
+	select {
+	case s.idle <- conn:
+	default:
+		conn.Close()
+	}
+	return reply, err
+}
+
+// conn takes an idle connection or opens a new one
+func (s *RedisStore) conn(ctx context.Context) (*redisConn, error) {
+	select {
+	case conn := <-s.idle:
+		return conn, nil
+	default:
+	}
+
+	dialer := &net.Dialer{Timeout: redisTimeout}
+	var raw net.Conn
+	var err error
+	if s.tls != nil {
+		raw, err = (&tls.Dialer{NetDialer: dialer, Config: s.tls}).DialContext(ctx, "tcp", s.addr)
+	} else {
+		raw, err = dialer.DialContext(ctx, "tcp", s.addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	conn := &redisConn{Conn: raw, r: bufio.NewReader(raw)}
+
+	if s.password != "" {
+		args := []string{"AUTH", s.password}
+		if s.username != "" {
+			args = []string{"AUTH", s.username, s.password}
+		}
+		if _, err := conn.command(ctx, args...); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if s.db != 0 {
+		if _, err := conn.command(ctx, "SELECT", strconv.Itoa(s.db)); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+func (c *redisConn) command(ctx context.Context, args ...string) (interface{}, error) {
+	deadline := time.Now().Add(redisTimeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	if err := c.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(c, b.String()); err != nil {
+		return nil, err
+	}
+	return c.readReply()
+}
+
+// readReply reads one RESP2 reply: strings, integers and nil bulk strings become string, int64
+// and nil, arrays become []interface{} and error replies a RedisError
+func (c *redisConn) readReply() (interface{}, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("redis: empty reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, RedisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(c.r, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:size]), nil
+	case '*':
+		count, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if count < 0 {
+			return nil, nil
+		}
+		items := make([]interface{}, count)
+		for i := range items {
+			if items[i], err = c.readReply(); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("redis: unexpected reply %q", line)
+	}
+}
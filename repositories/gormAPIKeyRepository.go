@@ -0,0 +1,36 @@
+package repositories
+
+import (
+	"github.com/tushar27x/music-lib-api/models"
+	"gorm.io/gorm"
+)
+
+type gormAPIKeyRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormAPIKeyRepository) Create(key *models.APIKey) error {
+	return duplicate(r.db.Create(key).Error)
+}
+
+func (r *gormAPIKeyRepository) FindByHash(keyHash string) (models.APIKey, error) {
+	var key models.APIKey
+	err := r.db.Where("key_hash = ?", keyHash).First(&key).Error
+	return key, notFound(err)
+}
+
+func (r *gormAPIKeyRepository) FindOwned(userId uint, id uint) (models.APIKey, error) {
+	var key models.APIKey
+	err := r.db.Where("id = ? AND user_id = ?", id, userId).First(&key).Error
+	return key, notFound(err)
+}
+
+func (r *gormAPIKeyRepository) FindByUser(userId uint) ([]models.APIKey, error) {
+	keys := []models.APIKey{}
+	err := r.db.Where("user_id = ?", userId).Order("created_at DESC, id DESC").Find(&keys).Error
+	return keys, err
+}
+
+func (r *gormAPIKeyRepository) Update(key *models.APIKey, columns ...string) error {
+	return r.db.Model(key).Select(columns).Updates(key).Error
+}
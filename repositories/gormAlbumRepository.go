@@ -0,0 +1,91 @@
+package repositories
+
+import (
+	"github.com/tushar27x/music-lib-api/models"
+	"gorm.io/gorm"
+)
+
+type gormAlbumRepository struct {
+	db *gorm.DB
+}
+
+// withUserSongs preloads only the songs of the album owner, in tracklist order
+func withUserSongs(userId uint) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Preload("Songs", func(db *gorm.DB) *gorm.DB {
+			return db.Where("user_id = ?", userId).Order(tracklistOrder)
+		})
+	}
+}
+
+// tracklistOrder sorts songs by disc and track, with the songs without a track number last
+const tracklistOrder = "track_number = 0, disc_number, track_number, id"
+
+func (r *gormAlbumRepository) Create(album *models.Album) error {
+	return duplicate(r.db.Create(album).Error)
+}
+
+func (r *gormAlbumRepository) FindByID(id uint) (models.Album, error) {
+	var album models.Album
+	err := r.db.First(&album, id).Error
+	return album, notFound(err)
+}
+
+func (r *gormAlbumRepository) FindOwned(userId uint, id uint) (models.Album, error) {
+	var album models.Album
+	err := r.db.Where("id = ? AND user_id = ?", id, userId).First(&album).Error
+	return album, notFound(err)
+}
+
+func (r *gormAlbumRepository) FindOwnedWithSongs(userId uint, id uint) (models.Album, error) {
+	var album models.Album
+	err := r.db.Scopes(withUserSongs(userId)).
+		Where("id = ? AND user_id = ?", id, userId).
+		First(&album).Error
+	return album, notFound(err)
+}
+
+func (r *gormAlbumRepository) FindByUser(userId uint) ([]models.Album, error) {
+	var albums []models.Album
+	err := r.db.Scopes(withUserSongs(userId)).Where("user_id = ?", userId).Find(&albums).Error
+	return albums, err
+}
+
+func (r *gormAlbumRepository) FindByTitle(userId uint, title string) ([]models.Album, error) {
+	var albums []models.Album
+	err := r.db.Where("user_id = ? AND LOWER(title) = LOWER(?)", userId, title).Find(&albums).Error
+	return albums, err
+}
+
+func (r *gormAlbumRepository) FindByArtist(userId uint, artistId uint, role string) ([]models.Album, error) {
+	albums := []models.Album{}
+	credits := r.db.Model(&models.AlbumCredit{}).Select("album_id").Where("artist_id = ?", artistId)
+	if role != "" {
+		credits = credits.Where("role = ?", role)
+	}
+	err := r.db.Scopes(withUserSongs(userId)).
+		Where("user_id = ? AND id IN (?)", userId, credits).
+		Order("year, id").
+		Find(&albums).Error
+	return albums, err
+}
+
+func (r *gormAlbumRepository) Update(album *models.Album, columns ...string) error {
+	return duplicate(r.db.Model(album).Select(columns).Updates(album).Error)
+}
+
+func (r *gormAlbumRepository) Delete(album *models.Album) error {
+	return r.db.Delete(album).Error
+}
+
+func (r *gormAlbumRepository) FindDeleted(userId uint, limit int, offset int) ([]models.Album, int64, error) {
+	albums := []models.Album{}
+	total, err := findDeleted(r.db, &models.Album{}, &albums, userId, limit, offset)
+	return albums, total, err
+}
+
+func (r *gormAlbumRepository) Restore(id uint) (models.Album, error) {
+	var album models.Album
+	err := restore(r.db, &album, id)
+	return album, err
+}
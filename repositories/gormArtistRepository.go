@@ -0,0 +1,111 @@
+package repositories
+
+import (
+	"github.com/tushar27x/music-lib-api/models"
+	"gorm.io/gorm"
+)
+
+type gormArtistRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormArtistRepository) Create(artist *models.Artist) error {
+	return duplicate(r.db.Create(artist).Error)
+}
+
+func (r *gormArtistRepository) FindOwned(userId uint, id uint) (models.Artist, error) {
+	var artist models.Artist
+	err := r.db.Where("id = ? AND user_id = ?", id, userId).First(&artist).Error
+	return artist, notFound(err)
+}
+
+func (r *gormArtistRepository) FindByName(userId uint, name string) (models.Artist, error) {
+	var artist models.Artist
+	err := r.db.Where("user_id = ? AND LOWER(name) = LOWER(?)", userId, name).First(&artist).Error
+	return artist, notFound(err)
+}
+
+func (r *gormArtistRepository) FindByUser(userId uint) ([]models.Artist, error) {
+	artists := []models.Artist{}
+	err := r.db.Where("user_id = ?", userId).Order("LOWER(name), id").Find(&artists).Error
+	return artists, err
+}
+
+func (r *gormArtistRepository) Update(artist *models.Artist, columns ...string) error {
+	return duplicate(r.db.Model(artist).Select(columns).Updates(artist).Error)
+}
+
+func (r *gormArtistRepository) Delete(artist *models.Artist) error {
+	// Deleted albums and songs keep their credits for when they are restored, so those go first
+	if err := r.db.Where("artist_id = ?", artist.ID).Delete(&models.AlbumCredit{}).Error; err != nil {
+		return err
+	}
+	if err := r.db.Where("artist_id = ?", artist.ID).Delete(&models.SongCredit{}).Error; err != nil {
+		return err
+	}
+	return r.db.Delete(artist).Error
+}
+
+func (r *gormArtistRepository) CountCredits(artistId uint) (int64, error) {
+	var albums, songs int64
+	err := r.db.Model(&models.AlbumCredit{}).
+		Joins("JOIN albums ON albums.id = album_credits.album_id AND albums.deleted_at IS NULL").
+		Where("album_credits.artist_id = ?", artistId).
+		Count(&albums).Error
+	if err != nil {
+		return 0, err
+	}
+	err = r.db.Model(&models.SongCredit{}).
+		Joins("JOIN songs ON songs.id = song_credits.song_id AND songs.deleted_at IS NULL").
+		Where("song_credits.artist_id = ?", artistId).
+		Count(&songs).Error
+	return albums + songs, err
+}
+
+func (r *gormArtistRepository) FindAlbumCredits(albumIds []uint) ([]models.AlbumCredit, error) {
+	credits := []models.AlbumCredit{}
+	if len(albumIds) == 0 {
+		return credits, nil
+	}
+	err := r.db.Model(&models.AlbumCredit{}).
+		Select("album_credits.*, artists.name AS name").
+		Joins("JOIN artists ON artists.id = album_credits.artist_id").
+		Where("album_credits.album_id IN ?", albumIds).
+		Order("album_credits.album_id, album_credits.position").
+		Find(&credits).Error
+	return credits, err
+}
+
+func (r *gormArtistRepository) SetAlbumCredits(albumId uint, credits []models.AlbumCredit) error {
+	if err := r.db.Where("album_id = ?", albumId).Delete(&models.AlbumCredit{}).Error; err != nil {
+		return err
+	}
+	if len(credits) == 0 {
+		return nil
+	}
+	return duplicate(r.db.Create(&credits).Error)
+}
+
+func (r *gormArtistRepository) FindSongCredits(songIds []uint) ([]models.SongCredit, error) {
+	credits := []models.SongCredit{}
+	if len(songIds) == 0 {
+		return credits, nil
+	}
+	err := r.db.Model(&models.SongCredit{}).
+		Select("song_credits.*, artists.name AS name").
+		Joins("JOIN artists ON artists.id = song_credits.artist_id").
+		Where("song_credits.song_id IN ?", songIds).
+		Order("song_credits.song_id, song_credits.position").
+		Find(&credits).Error
+	return credits, err
+}
+
+func (r *gormArtistRepository) SetSongCredits(songId uint, credits []models.SongCredit) error {
+	if err := r.db.Where("song_id = ?", songId).Delete(&models.SongCredit{}).Error; err != nil {
+		return err
+	}
+	if len(credits) == 0 {
+		return nil
+	}
+	return duplicate(r.db.Create(&credits).Error)
+}
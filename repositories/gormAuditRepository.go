@@ -0,0 +1,41 @@
+package repositories
+
+import (
+	"github.com/tushar27x/music-lib-api/models"
+	"gorm.io/gorm"
+)
+
+type gormAuditRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormAuditRepository) Create(entry *models.AuditLog) error {
+	return r.db.Create(entry).Error
+}
+
+func (r *gormAuditRepository) List(filter AuditFilter, limit int, offset int) ([]models.AuditLog, int64, error) {
+	matching := func() *gorm.DB {
+		query := r.db.Model(&models.AuditLog{})
+		if filter.ActorId != nil {
+			query = query.Where("actor_id = ?", *filter.ActorId)
+		}
+		if filter.Action != "" {
+			query = query.Where("action = ?", filter.Action)
+		}
+		if filter.TargetType != "" {
+			query = query.Where("target_type = ?", filter.TargetType)
+		}
+		if filter.TargetId != nil {
+			query = query.Where("target_id = ?", *filter.TargetId)
+		}
+		return query
+	}
+
+	var total int64
+	if err := matching().Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	entries := []models.AuditLog{}
+	err := matching().Order("id DESC").Limit(limit).Offset(offset).Find(&entries).Error
+	return entries, total, err
+}
@@ -0,0 +1,67 @@
+package repositories
+
+import (
+	"github.com/tushar27x/music-lib-api/models"
+	"gorm.io/gorm"
+)
+
+type gormGenreRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormGenreRepository) Create(genre *models.Genre) error {
+	return duplicate(r.db.Create(genre).Error)
+}
+
+func (r *gormGenreRepository) FindOwned(userId uint, id uint) (models.Genre, error) {
+	var genre models.Genre
+	err := r.db.Where("id = ? AND user_id = ?", id, userId).First(&genre).Error
+	return genre, notFound(err)
+}
+
+func (r *gormGenreRepository) FindByName(userId uint, name string) (models.Genre, error) {
+	var genre models.Genre
+	err := r.db.Where("user_id = ? AND LOWER(name) = LOWER(?)", userId, name).First(&genre).Error
+	return genre, notFound(err)
+}
+
+func (r *gormGenreRepository) FindByUser(userId uint) ([]models.Genre, error) {
+	genres := []models.Genre{}
+	err := r.db.Where("user_id = ?", userId).Order("LOWER(name), id").Find(&genres).Error
+	return genres, err
+}
+
+func (r *gormGenreRepository) Update(genre *models.Genre, columns ...string) error {
+	return duplicate(r.db.Model(genre).Select(columns).Updates(genre).Error)
+}
+
+func (r *gormGenreRepository) Delete(genre *models.Genre) error {
+	// Deleted albums and songs come back without the genre when they are restored
+	cleared := map[string]interface{}{"genre": "", "genre_id": nil}
+	if err := r.db.Unscoped().Model(&models.Album{}).Where("genre_id = ?", genre.ID).UpdateColumns(cleared).Error; err != nil {
+		return err
+	}
+	if err := r.db.Unscoped().Model(&models.Song{}).Where("genre_id = ?", genre.ID).UpdateColumns(cleared).Error; err != nil {
+		return err
+	}
+	return r.db.Delete(genre).Error
+}
+
+func (r *gormGenreRepository) CountUses(genreId uint) (int64, error) {
+	var subgenres, albums, songs int64
+	if err := r.db.Model(&models.Genre{}).Where("parent_id = ?", genreId).Count(&subgenres).Error; err != nil {
+		return 0, err
+	}
+	if err := r.db.Model(&models.Album{}).Where("genre_id = ?", genreId).Count(&albums).Error; err != nil {
+		return 0, err
+	}
+	err := r.db.Model(&models.Song{}).Where("genre_id = ?", genreId).Count(&songs).Error
+	return subgenres + albums + songs, err
+}
+
+func (r *gormGenreRepository) RenameUses(genreId uint, name string) error {
+	if err := r.db.Unscoped().Model(&models.Album{}).Where("genre_id = ?", genreId).Update("genre", name).Error; err != nil {
+		return err
+	}
+	return r.db.Unscoped().Model(&models.Song{}).Where("genre_id = ?", genreId).Update("genre", name).Error
+}
@@ -0,0 +1,46 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/tushar27x/music-lib-api/models"
+	"gorm.io/gorm"
+)
+
+type gormIdentityRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormIdentityRepository) Create(identity *models.UserIdentity) error {
+	return duplicate(r.db.Create(identity).Error)
+}
+
+func (r *gormIdentityRepository) FindBySubject(provider string, subject string) (models.UserIdentity, error) {
+	var identity models.UserIdentity
+	err := r.db.Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error
+	return identity, notFound(err)
+}
+
+func (r *gormIdentityRepository) FindByUser(userId uint) ([]models.UserIdentity, error) {
+	identities := []models.UserIdentity{}
+	err := r.db.Where("user_id = ?", userId).Order("id").Find(&identities).Error
+	return identities, err
+}
+
+func (r *gormIdentityRepository) CreateLogin(login *models.OIDCLogin) error {
+	return duplicate(r.db.Create(login).Error)
+}
+
+func (r *gormIdentityRepository) FindLogin(stateHash string) (models.OIDCLogin, error) {
+	var login models.OIDCLogin
+	err := r.db.Where("state_hash = ?", stateHash).First(&login).Error
+	return login, notFound(err)
+}
+
+func (r *gormIdentityRepository) UseLogin(id uint, at time.Time) (bool, error) {
+	// The used_at guard lets only one callback finish the login
+	result := r.db.Model(&models.OIDCLogin{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", at)
+	return result.RowsAffected > 0, result.Error
+}
@@ -0,0 +1,59 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/tushar27x/music-lib-api/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type gormLoginAttemptRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormLoginAttemptRepository) Find(kind string, target string) (models.LoginAttempt, error) {
+	var attempt models.LoginAttempt
+	err := r.db.Where("kind = ? AND target = ?", kind, target).First(&attempt).Error
+	return attempt, notFound(err)
+}
+
+func (r *gormLoginAttemptRepository) Lock(kind string, target string) (models.LoginAttempt, error) {
+	var attempt models.LoginAttempt
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("kind = ? AND target = ?", kind, target).
+		First(&attempt).Error
+	return attempt, notFound(err)
+}
+
+func (r *gormLoginAttemptRepository) FindByID(id uint) (models.LoginAttempt, error) {
+	var attempt models.LoginAttempt
+	err := r.db.First(&attempt, id).Error
+	return attempt, notFound(err)
+}
+
+func (r *gormLoginAttemptRepository) Save(attempt *models.LoginAttempt) error {
+	return duplicate(r.db.Save(attempt).Error)
+}
+
+func (r *gormLoginAttemptRepository) Delete(id uint) error {
+	return r.db.Delete(&models.LoginAttempt{}, id).Error
+}
+
+func (r *gormLoginAttemptRepository) ListLocked(kind string, now time.Time, limit int, offset int) ([]models.LoginAttempt, int64, error) {
+	locked := func() *gorm.DB {
+		query := r.db.Model(&models.LoginAttempt{}).Where("locked_until > ?", now)
+		if kind != "" {
+			query = query.Where("kind = ?", kind)
+		}
+		return query
+	}
+
+	var total int64
+	if err := locked().Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	attempts := []models.LoginAttempt{}
+	err := locked().Order("locked_until DESC, id DESC").Limit(limit).Offset(offset).Find(&attempts).Error
+	return attempts, total, err
+}
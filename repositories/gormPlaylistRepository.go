@@ -0,0 +1,131 @@
+package repositories
+
+import (
+	"github.com/tushar27x/music-lib-api/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type gormPlaylistRepository struct {
+	db *gorm.DB
+}
+
+// WithTracks preloads playlist entries in playlist order together with their songs
+func WithTracks(db *gorm.DB) *gorm.DB {
+	return db.Preload("Entries", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position ASC")
+	}).Preload("Entries.Song")
+}
+
+func (r *gormPlaylistRepository) Create(playlist *models.Playlist) error {
+	return r.db.Create(playlist).Error
+}
+
+func (r *gormPlaylistRepository) FindByID(id uint) (models.Playlist, error) {
+	var playlist models.Playlist
+	err := r.db.Where("id = ?", id).First(&playlist).Error
+	return playlist, notFound(err)
+}
+
+func (r *gormPlaylistRepository) FindOwned(userId uint, id uint) (models.Playlist, error) {
+	var playlist models.Playlist
+	err := r.db.Where("id = ? AND user_id = ?", id, userId).First(&playlist).Error
+	return playlist, notFound(err)
+}
+
+func (r *gormPlaylistRepository) FindOwnedWithTracks(userId uint, id uint) (models.Playlist, error) {
+	var playlist models.Playlist
+	err := r.db.Scopes(WithTracks).Where("id = ? AND user_id = ?", id, userId).First(&playlist).Error
+	return playlist, notFound(err)
+}
+
+func (r *gormPlaylistRepository) FindByUser(userId uint) ([]models.Playlist, error) {
+	var playlists []models.Playlist
+	err := r.db.Scopes(WithTracks).Where("user_id = ?", userId).Find(&playlists).Error
+	return playlists, err
+}
+
+func (r *gormPlaylistRepository) LockOwned(userId uint, id uint) (models.Playlist, error) {
+	var playlist models.Playlist
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND user_id = ?", id, userId).
+		First(&playlist).Error
+	return playlist, notFound(err)
+}
+
+func (r *gormPlaylistRepository) Update(playlist *models.Playlist, columns ...string) error {
+	return r.db.Model(playlist).Select(columns).Updates(playlist).Error
+}
+
+func (r *gormPlaylistRepository) Delete(playlist *models.Playlist) error {
+	return r.db.Delete(playlist).Error
+}
+
+func (r *gormPlaylistRepository) FindEntry(playlistId uint, entryId uint) (models.PlaylistEntry, error) {
+	var entry models.PlaylistEntry
+	err := r.db.Where("id = ? AND playlist_id = ?", entryId, playlistId).First(&entry).Error
+	return entry, notFound(err)
+}
+
+func (r *gormPlaylistRepository) FindEntries(playlistId uint) ([]models.PlaylistEntry, error) {
+	var entries []models.PlaylistEntry
+	err := r.db.Where("playlist_id = ?", playlistId).Order("position ASC, id ASC").Find(&entries).Error
+	return entries, err
+}
+
+func (r *gormPlaylistRepository) CountEntries(playlistId uint) (int, error) {
+	var count int64
+	err := r.db.Model(&models.PlaylistEntry{}).Where("playlist_id = ?", playlistId).Count(&count).Error
+	return int(count), err
+}
+
+func (r *gormPlaylistRepository) CreateEntries(entries []models.PlaylistEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	return r.db.Create(&entries).Error
+}
+
+func (r *gormPlaylistRepository) ShiftEntries(playlistId uint, from int, to int, delta int) error {
+	query := r.db.Model(&models.PlaylistEntry{}).Where("playlist_id = ? AND position >= ?", playlistId, from)
+	if to >= 0 {
+		query = query.Where("position < ?", to)
+	}
+	return query.Update("position", gorm.Expr("position + ?", delta)).Error
+}
+
+func (r *gormPlaylistRepository) SetEntryPosition(entry *models.PlaylistEntry, position int) error {
+	return r.db.Model(entry).Update("position", position).Error
+}
+
+func (r *gormPlaylistRepository) DeleteEntry(entry models.PlaylistEntry) error {
+	return r.db.Delete(&entry).Error
+}
+
+func (r *gormPlaylistRepository) DeleteEntries(playlistId uint) error {
+	return r.db.Where("playlist_id = ?", playlistId).Delete(&models.PlaylistEntry{}).Error
+}
+
+func (r *gormPlaylistRepository) FindPlaylistIdsWithSong(songId uint) ([]uint, error) {
+	var playlistIds []uint
+	err := r.db.Model(&models.PlaylistEntry{}).
+		Where("song_id = ?", songId).
+		Distinct().Pluck("playlist_id", &playlistIds).Error
+	return playlistIds, err
+}
+
+func (r *gormPlaylistRepository) DeleteSongEntries(songId uint) error {
+	return r.db.Where("song_id = ?", songId).Delete(&models.PlaylistEntry{}).Error
+}
+
+func (r *gormPlaylistRepository) FindDeleted(userId uint, limit int, offset int) ([]models.Playlist, int64, error) {
+	playlists := []models.Playlist{}
+	total, err := findDeleted(r.db, &models.Playlist{}, &playlists, userId, limit, offset)
+	return playlists, total, err
+}
+
+func (r *gormPlaylistRepository) Restore(id uint) (models.Playlist, error) {
+	var playlist models.Playlist
+	err := restore(r.db, &playlist, id)
+	return playlist, err
+}
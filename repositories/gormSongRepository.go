@@ -0,0 +1,108 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/tushar27x/music-lib-api/models"
+	"gorm.io/gorm"
+)
+
+type gormSongRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormSongRepository) Create(song *models.Song) error {
+	return duplicate(r.db.Create(song).Error)
+}
+
+func (r *gormSongRepository) FindByID(id uint) (models.Song, error) {
+	var song models.Song
+	err := r.db.Where("id = ?", id).First(&song).Error
+	return song, notFound(err)
+}
+
+func (r *gormSongRepository) FindOwned(userId uint, id uint) (models.Song, error) {
+	var song models.Song
+	err := r.db.Where("id = ? AND user_id = ?", id, userId).First(&song).Error
+	return song, notFound(err)
+}
+
+func (r *gormSongRepository) FindByUser(userId uint) ([]models.Song, error) {
+	var songs []models.Song
+	err := r.db.Where("user_id = ?", userId).Find(&songs).Error
+	return songs, err
+}
+
+func (r *gormSongRepository) FindByArtist(userId uint, artistId uint, role string) ([]models.Song, error) {
+	songs := []models.Song{}
+	credits := r.db.Model(&models.SongCredit{}).Select("song_id").Where("artist_id = ?", artistId)
+	if role != "" {
+		credits = credits.Where("role = ?", role)
+	}
+	err := r.db.Where("user_id = ? AND id IN (?)", userId, credits).Order("id").Find(&songs).Error
+	return songs, err
+}
+
+func (r *gormSongRepository) CountOwned(userId uint, ids []uint) (int, error) {
+	var count int64
+	err := r.db.Model(&models.Song{}).Where("id IN ? AND user_id = ?", ids, userId).Count(&count).Error
+	return int(count), err
+}
+
+func (r *gormSongRepository) FindMatching(userId uint, rules models.SmartPlaylistRules, now time.Time) ([]models.Song, error) {
+	songs := []models.Song{}
+
+	query := r.db.Model(&models.Song{}).
+		Select("songs.*").
+		Joins("LEFT JOIN albums ON albums.id = songs.album_id AND albums.deleted_at IS NULL").
+		Where("songs.user_id = ?", userId)
+
+	if condition, args := smartGroupSQL(rules.SmartRuleGroup, now); condition != "" {
+		query = query.Where(condition, args...)
+	}
+
+	query = applySmartSort(query, rules.Sort)
+	if rules.Limit > 0 {
+		query = query.Limit(rules.Limit)
+	}
+
+	err := query.Find(&songs).Error
+	return songs, err
+}
+
+func (r *gormSongRepository) Update(song *models.Song, columns ...string) error {
+	return duplicate(r.db.Model(song).Select(columns).Updates(song).Error)
+}
+
+func (r *gormSongRepository) RecordPlay(id uint, at time.Time) error {
+	return r.db.Model(&models.Song{}).
+		Where("id = ?", id).
+		UpdateColumns(map[string]interface{}{
+			"play_count":     gorm.Expr("play_count + 1"),
+			"last_played_at": at,
+		}).Error
+}
+
+func (r *gormSongRepository) Delete(song *models.Song) error {
+	return r.db.Delete(song).Error
+}
+
+func (r *gormSongRepository) DeleteByAlbum(userId uint, albumId uint) error {
+	return r.db.Where("album_id = ? AND user_id = ?", albumId, userId).Delete(&models.Song{}).Error
+}
+
+func (r *gormSongRepository) MoveToAlbum(userId uint, albumId uint, targetId uint) error {
+	return duplicate(r.db.Model(&models.Song{}).Where("album_id = ? AND user_id = ?", albumId, userId).Update("album_id", targetId).Error)
+}
+
+func (r *gormSongRepository) FindDeleted(userId uint, limit int, offset int) ([]models.Song, int64, error) {
+	songs := []models.Song{}
+	total, err := findDeleted(r.db, &models.Song{}, &songs, userId, limit, offset)
+	return songs, total, err
+}
+
+func (r *gormSongRepository) Restore(id uint) (models.Song, error) {
+	var song models.Song
+	err := restore(r.db, &song, id)
+	return song, err
+}
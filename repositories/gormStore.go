@@ -0,0 +1,115 @@
+package repositories
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// gormStore keeps every repository on one database handle, which is a transaction inside Transaction
+type gormStore struct {
+	db *gorm.DB
+}
+
+// NewGormStore returns repositories backed by the database
+func NewGormStore(db *gorm.DB) Store {
+	return &gormStore{db: db}
+}
+
+func (s *gormStore) Songs() SongRepository {
+	return &gormSongRepository{db: s.db}
+}
+
+func (s *gormStore) Albums() AlbumRepository {
+	return &gormAlbumRepository{db: s.db}
+}
+
+func (s *gormStore) Artists() ArtistRepository {
+	return &gormArtistRepository{db: s.db}
+}
+
+func (s *gormStore) Genres() GenreRepository {
+	return &gormGenreRepository{db: s.db}
+}
+
+func (s *gormStore) Tags() TagRepository {
+	return &gormTagRepository{db: s.db}
+}
+
+func (s *gormStore) Playlists() PlaylistRepository {
+	return &gormPlaylistRepository{db: s.db}
+}
+
+func (s *gormStore) Users() UserRepository {
+	return &gormUserRepository{db: s.db}
+}
+
+func (s *gormStore) Tokens() TokenRepository {
+	return &gormTokenRepository{db: s.db}
+}
+
+func (s *gormStore) Audit() AuditRepository {
+	return &gormAuditRepository{db: s.db}
+}
+
+func (s *gormStore) APIKeys() APIKeyRepository {
+	return &gormAPIKeyRepository{db: s.db}
+}
+
+func (s *gormStore) Identities() IdentityRepository {
+	return &gormIdentityRepository{db: s.db}
+}
+
+func (s *gormStore) LoginAttempts() LoginAttemptRepository {
+	return &gormLoginAttemptRepository{db: s.db}
+}
+
+func (s *gormStore) Transaction(fn func(tx Store) error) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&gormStore{db: tx})
+	})
+}
+
+// notFound translates GORM's missing record error into ErrNotFound
+func notFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// duplicate translates unique constraint violations into ErrDuplicate. The drivers report them
+// as gorm.ErrDuplicatedKey because config.OpenDB turns on TranslateError.
+func duplicate(err error) error {
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return ErrDuplicate
+	}
+	return err
+}
+
+// findDeleted loads soft-deleted rows of model into dest, most recently deleted first, of one
+// user or of everyone when userId is 0, and counts how many there are
+func findDeleted(db *gorm.DB, model interface{}, dest interface{}, userId uint, limit int, offset int) (int64, error) {
+	deleted := func() *gorm.DB {
+		query := db.Unscoped().Model(model).Where("deleted_at IS NOT NULL")
+		if userId != 0 {
+			query = query.Where("user_id = ?", userId)
+		}
+		return query
+	}
+
+	var total int64
+	if err := deleted().Count(&total).Error; err != nil {
+		return 0, err
+	}
+	err := deleted().Order("deleted_at DESC, id DESC").Limit(limit).Offset(offset).Find(dest).Error
+	return total, err
+}
+
+// restore loads the soft-deleted row with the id into dest and clears its deleted_at
+func restore(db *gorm.DB, dest interface{}, id uint) error {
+	if err := db.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).First(dest).Error; err != nil {
+		return notFound(err)
+	}
+	return duplicate(db.Unscoped().Model(dest).Update("deleted_at", nil).Error)
+}
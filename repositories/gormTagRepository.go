@@ -0,0 +1,136 @@
+package repositories
+
+import (
+	"github.com/tushar27x/music-lib-api/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type gormTagRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormTagRepository) Create(tag *models.Tag) error {
+	return duplicate(r.db.Create(tag).Error)
+}
+
+func (r *gormTagRepository) FindOwned(userId uint, id uint) (models.Tag, error) {
+	var tag models.Tag
+	err := r.db.Where("id = ? AND user_id = ?", id, userId).First(&tag).Error
+	return tag, notFound(err)
+}
+
+func (r *gormTagRepository) FindByName(userId uint, name string) (models.Tag, error) {
+	var tag models.Tag
+	err := r.db.Where("user_id = ? AND LOWER(name) = LOWER(?)", userId, name).First(&tag).Error
+	return tag, notFound(err)
+}
+
+func (r *gormTagRepository) FindUsage(userId uint) ([]models.TagUsage, error) {
+	tags := []models.TagUsage{}
+	songs := r.db.Model(&models.TaggedSong{}).
+		Select("COUNT(*)").
+		Joins("JOIN songs ON songs.id = tagged_songs.song_id AND songs.deleted_at IS NULL").
+		Where("tagged_songs.tag_id = tags.id")
+	albums := r.db.Model(&models.TaggedAlbum{}).
+		Select("COUNT(*)").
+		Joins("JOIN albums ON albums.id = tagged_albums.album_id AND albums.deleted_at IS NULL").
+		Where("tagged_albums.tag_id = tags.id")
+	err := r.db.Model(&models.Tag{}).
+		Select("tags.*, (?) AS song_count, (?) AS album_count", songs, albums).
+		Where("tags.user_id = ?", userId).
+		Order("LOWER(tags.name), tags.id").
+		Scan(&tags).Error
+	return tags, err
+}
+
+func (r *gormTagRepository) Update(tag *models.Tag, columns ...string) error {
+	return duplicate(r.db.Model(tag).Select(columns).Updates(tag).Error)
+}
+
+func (r *gormTagRepository) Delete(tag *models.Tag) error {
+	// Deleted albums and songs keep their tags for when they are restored, so those go first
+	if err := r.db.Where("tag_id = ?", tag.ID).Delete(&models.TaggedAlbum{}).Error; err != nil {
+		return err
+	}
+	if err := r.db.Where("tag_id = ?", tag.ID).Delete(&models.TaggedSong{}).Error; err != nil {
+		return err
+	}
+	return r.db.Delete(tag).Error
+}
+
+func (r *gormTagRepository) MoveTaggings(fromId uint, toId uint) error {
+	err := r.db.Exec(`INSERT INTO tagged_songs (song_id, tag_id)
+		SELECT song_id, ? FROM tagged_songs
+		WHERE tag_id = ? AND song_id NOT IN (SELECT song_id FROM tagged_songs WHERE tag_id = ?)`, toId, fromId, toId).Error
+	if err != nil {
+		return err
+	}
+	return r.db.Exec(`INSERT INTO tagged_albums (album_id, tag_id)
+		SELECT album_id, ? FROM tagged_albums
+		WHERE tag_id = ? AND album_id NOT IN (SELECT album_id FROM tagged_albums WHERE tag_id = ?)`, toId, fromId, toId).Error
+}
+
+func (r *gormTagRepository) FindSongTags(songIds []uint) ([]models.TaggedSong, error) {
+	tags := []models.TaggedSong{}
+	if len(songIds) == 0 {
+		return tags, nil
+	}
+	err := r.db.Model(&models.TaggedSong{}).
+		Select("tagged_songs.*, tags.name AS name").
+		Joins("JOIN tags ON tags.id = tagged_songs.tag_id").
+		Where("tagged_songs.song_id IN ?", songIds).
+		Order("tagged_songs.song_id, LOWER(tags.name)").
+		Find(&tags).Error
+	return tags, err
+}
+
+func (r *gormTagRepository) TagSongs(tagId uint, songIds []uint) error {
+	if len(songIds) == 0 {
+		return nil
+	}
+	tagged := make([]models.TaggedSong, len(songIds))
+	for i, songId := range songIds {
+		tagged[i] = models.TaggedSong{SongId: songId, TagId: tagId}
+	}
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&tagged).Error
+}
+
+func (r *gormTagRepository) UntagSongs(tagId uint, songIds []uint) error {
+	if len(songIds) == 0 {
+		return nil
+	}
+	return r.db.Where("tag_id = ? AND song_id IN ?", tagId, songIds).Delete(&models.TaggedSong{}).Error
+}
+
+func (r *gormTagRepository) FindAlbumTags(albumIds []uint) ([]models.TaggedAlbum, error) {
+	tags := []models.TaggedAlbum{}
+	if len(albumIds) == 0 {
+		return tags, nil
+	}
+	err := r.db.Model(&models.TaggedAlbum{}).
+		Select("tagged_albums.*, tags.name AS name").
+		Joins("JOIN tags ON tags.id = tagged_albums.tag_id").
+		Where("tagged_albums.album_id IN ?", albumIds).
+		Order("tagged_albums.album_id, LOWER(tags.name)").
+		Find(&tags).Error
+	return tags, err
+}
+
+func (r *gormTagRepository) TagAlbums(tagId uint, albumIds []uint) error {
+	if len(albumIds) == 0 {
+		return nil
+	}
+	tagged := make([]models.TaggedAlbum, len(albumIds))
+	for i, albumId := range albumIds {
+		tagged[i] = models.TaggedAlbum{AlbumId: albumId, TagId: tagId}
+	}
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&tagged).Error
+}
+
+func (r *gormTagRepository) UntagAlbums(tagId uint, albumIds []uint) error {
+	if len(albumIds) == 0 {
+		return nil
+	}
+	return r.db.Where("tag_id = ? AND album_id IN ?", tagId, albumIds).Delete(&models.TaggedAlbum{}).Error
+}
@@ -0,0 +1,114 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/tushar27x/music-lib-api/models"
+	"gorm.io/gorm"
+)
+
+type gormTokenRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormTokenRepository) CreateRefreshToken(token *models.RefreshToken) error {
+	return duplicate(r.db.Create(token).Error)
+}
+
+func (r *gormTokenRepository) FindRefreshToken(tokenHash string) (models.RefreshToken, error) {
+	var token models.RefreshToken
+	err := r.db.Where("token_hash = ?", tokenHash).First(&token).Error
+	return token, notFound(err)
+}
+
+func (r *gormTokenRepository) ConsumeRefreshToken(id uint, at time.Time) (bool, error) {
+	// The revoked_at guard makes concurrent refreshes of the same token lose the race
+	result := r.db.Model(&models.RefreshToken{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", at)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *gormTokenRepository) RevokeFamily(familyId string, at time.Time) error {
+	return r.db.Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyId).
+		Update("revoked_at", at).Error
+}
+
+func (r *gormTokenRepository) RevokeUserRefreshTokens(userId uint, at time.Time) error {
+	return r.db.Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userId).
+		Update("revoked_at", at).Error
+}
+
+func (r *gormTokenRepository) IsAccessTokenRevoked(jti string) (bool, error) {
+	var count int64
+	if err := r.db.Model(&models.RevokedToken{}).Where("jti = ?", jti).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (r *gormTokenRepository) RevokeAccessToken(jti string, expiresAt time.Time, now time.Time) error {
+	// Entries for tokens that have expired anyway are no longer needed
+	if err := r.db.Where("expires_at < ?", now).Delete(&models.RevokedToken{}).Error; err != nil {
+		return err
+	}
+
+	revoked, err := r.IsAccessTokenRevoked(jti)
+	if err != nil || revoked {
+		return err
+	}
+
+	return r.db.Create(&models.RevokedToken{Jti: jti, ExpiresAt: expiresAt}).Error
+}
+
+func (r *gormTokenRepository) CreateAccountToken(token *models.AccountToken) error {
+	return duplicate(r.db.Create(token).Error)
+}
+
+func (r *gormTokenRepository) FindAccountToken(purpose string, tokenHash string) (models.AccountToken, error) {
+	var token models.AccountToken
+	err := r.db.Where("purpose = ? AND token_hash = ?", purpose, tokenHash).First(&token).Error
+	return token, notFound(err)
+}
+
+func (r *gormTokenRepository) UseAccountToken(id uint, at time.Time) (bool, error) {
+	// Like ConsumeRefreshToken, the used_at guard lets only one request use the token
+	result := r.db.Model(&models.AccountToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", at)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *gormTokenRepository) ExpireAccountTokens(userId uint, purpose string, at time.Time) error {
+	return r.db.Model(&models.AccountToken{}).
+		Where("user_id = ? AND purpose = ? AND used_at IS NULL", userId, purpose).
+		Update("used_at", at).Error
+}
+
+func (r *gormTokenRepository) ReplaceRecoveryCodes(userId uint, codes []models.RecoveryCode) error {
+	if err := r.db.Where("user_id = ?", userId).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return err
+	}
+	if len(codes) == 0 {
+		return nil
+	}
+	for i := range codes {
+		codes[i].UserId = userId
+	}
+	return r.db.Create(&codes).Error
+}
+
+func (r *gormTokenRepository) UseRecoveryCode(userId uint, codeHash string, at time.Time) (bool, error) {
+	result := r.db.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userId, codeHash).
+		Update("used_at", at)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *gormTokenRepository) CountRecoveryCodes(userId uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.RecoveryCode{}).Where("user_id = ? AND used_at IS NULL", userId).Count(&count).Error
+	return count, err
+}
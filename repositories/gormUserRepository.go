@@ -0,0 +1,69 @@
+package repositories
+
+import (
+	"strings"
+
+	"github.com/tushar27x/music-lib-api/models"
+	"gorm.io/gorm"
+)
+
+type gormUserRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormUserRepository) Create(user *models.User) error {
+	return duplicate(r.db.Create(user).Error)
+}
+
+func (r *gormUserRepository) FindByID(id uint) (models.User, error) {
+	var user models.User
+	err := r.db.First(&user, id).Error
+	return user, notFound(err)
+}
+
+func (r *gormUserRepository) FindByEmail(email string) (models.User, error) {
+	var user models.User
+	err := r.db.Where("email = ?", email).First(&user).Error
+	return user, notFound(err)
+}
+
+func (r *gormUserRepository) Update(user *models.User, columns ...string) error {
+	return duplicate(r.db.Model(user).Select(columns).Updates(user).Error)
+}
+
+func (r *gormUserRepository) UseTOTPStep(id uint, step int64) (bool, error) {
+	// The guard lets only one of the requests carrying the same code use it
+	result := r.db.Model(&models.User{}).
+		Where("id = ? AND totp_last_step < ?", id, step).
+		Update("totp_last_step", step)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *gormUserRepository) List(filter UserFilter, limit int, offset int) ([]models.User, int64, error) {
+	matching := func() *gorm.DB {
+		query := r.db.Model(&models.User{})
+		if filter.Query != "" {
+			pattern := "%" + strings.ToLower(filter.Query) + "%"
+			query = query.Where("LOWER(name) LIKE ? OR LOWER(email) LIKE ?", pattern, pattern)
+		}
+		if filter.Role != "" {
+			query = query.Where("role = ?", filter.Role)
+		}
+		if filter.Suspended != nil {
+			if *filter.Suspended {
+				query = query.Where("suspended_at IS NOT NULL")
+			} else {
+				query = query.Where("suspended_at IS NULL")
+			}
+		}
+		return query
+	}
+
+	var total int64
+	if err := matching().Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	users := []models.User{}
+	err := matching().Order("id ASC").Limit(limit).Offset(offset).Find(&users).Error
+	return users, total, err
+}
@@ -0,0 +1,87 @@
+package repositories
+
+import (
+	"sort"
+	"time"
+
+	"github.com/tushar27x/music-lib-api/models"
+)
+
+type memoryAPIKeyRepository struct {
+	store *MemoryStore
+}
+
+func (r *memoryAPIKeyRepository) Create(key *models.APIKey) error {
+	return r.store.write(func(data *memoryData) error {
+		for _, existing := range data.apiKeys {
+			if existing.KeyHash == key.KeyHash {
+				return duplicateError("api_keys", "key_hash")
+			}
+		}
+		now := time.Now()
+		key.ID = data.nextId()
+		key.CreatedAt, key.UpdatedAt = now, now
+		data.apiKeys[key.ID] = *key
+		return nil
+	})
+}
+
+func (r *memoryAPIKeyRepository) FindByHash(keyHash string) (models.APIKey, error) {
+	var key models.APIKey
+	err := r.store.read(func(data *memoryData) error {
+		for _, found := range data.apiKeys {
+			if found.KeyHash == keyHash {
+				key = found
+				return nil
+			}
+		}
+		return ErrNotFound
+	})
+	return key, err
+}
+
+func (r *memoryAPIKeyRepository) FindOwned(userId uint, id uint) (models.APIKey, error) {
+	var key models.APIKey
+	err := r.store.read(func(data *memoryData) error {
+		found, ok := data.apiKeys[id]
+		if !ok || found.UserId != userId {
+			return ErrNotFound
+		}
+		key = found
+		return nil
+	})
+	return key, err
+}
+
+func (r *memoryAPIKeyRepository) FindByUser(userId uint) ([]models.APIKey, error) {
+	keys := []models.APIKey{}
+	err := r.store.read(func(data *memoryData) error {
+		for _, key := range data.apiKeys {
+			if key.UserId == userId {
+				keys = append(keys, key)
+			}
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if !keys[i].CreatedAt.Equal(keys[j].CreatedAt) {
+				return keys[i].CreatedAt.After(keys[j].CreatedAt)
+			}
+			return keys[i].ID > keys[j].ID
+		})
+		return nil
+	})
+	return keys, err
+}
+
+func (r *memoryAPIKeyRepository) Update(key *models.APIKey, columns ...string) error {
+	return r.store.write(func(data *memoryData) error {
+		stored, ok := data.apiKeys[key.ID]
+		if !ok {
+			return nil
+		}
+		assignColumns(&stored, key, columns)
+		key.UpdatedAt = time.Now()
+		stored.UpdatedAt = key.UpdatedAt
+		data.apiKeys[key.ID] = stored
+		return nil
+	})
+}
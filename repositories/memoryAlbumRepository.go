@@ -0,0 +1,215 @@
+package repositories
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tushar27x/music-lib-api/models"
+	"gorm.io/gorm"
+)
+
+type memoryAlbumRepository struct {
+	store *MemoryStore
+}
+
+func (r *memoryAlbumRepository) Create(album *models.Album) error {
+	return r.store.write(func(data *memoryData) error {
+		if albumExists(data, *album) {
+			return duplicateError("albums", "user_id, artist, title, year")
+		}
+		now := time.Now()
+		album.ID = data.nextId()
+		album.CreatedAt, album.UpdatedAt = now, now
+		stored := *album
+		stored.Songs, stored.Credits, stored.Tags = nil, nil, nil
+		data.albums[album.ID] = stored
+		return nil
+	})
+}
+
+func (r *memoryAlbumRepository) FindByID(id uint) (models.Album, error) {
+	var album models.Album
+	err := r.store.read(func(data *memoryData) error {
+		found, ok := data.albums[id]
+		if !ok {
+			return ErrNotFound
+		}
+		album = found
+		return nil
+	})
+	return album, err
+}
+
+func (r *memoryAlbumRepository) FindOwned(userId uint, id uint) (models.Album, error) {
+	album, err := r.FindByID(id)
+	if err == nil && album.UserId != userId {
+		return models.Album{}, ErrNotFound
+	}
+	return album, err
+}
+
+func (r *memoryAlbumRepository) FindOwnedWithSongs(userId uint, id uint) (models.Album, error) {
+	var album models.Album
+	err := r.store.read(func(data *memoryData) error {
+		found, ok := data.albums[id]
+		if !ok || found.UserId != userId {
+			return ErrNotFound
+		}
+		album = withAlbumSongs(data, found)
+		return nil
+	})
+	return album, err
+}
+
+func (r *memoryAlbumRepository) FindByUser(userId uint) ([]models.Album, error) {
+	albums := []models.Album{}
+	err := r.store.read(func(data *memoryData) error {
+		for _, album := range data.albums {
+			if album.UserId == userId {
+				albums = append(albums, withAlbumSongs(data, album))
+			}
+		}
+		return nil
+	})
+	sort.Slice(albums, func(i, j int) bool { return albums[i].ID < albums[j].ID })
+	return albums, err
+}
+
+func (r *memoryAlbumRepository) FindByTitle(userId uint, title string) ([]models.Album, error) {
+	albums := []models.Album{}
+	err := r.store.read(func(data *memoryData) error {
+		for _, album := range data.albums {
+			if album.UserId == userId && strings.EqualFold(album.Title, title) {
+				albums = append(albums, album)
+			}
+		}
+		return nil
+	})
+	sort.Slice(albums, func(i, j int) bool { return albums[i].ID < albums[j].ID })
+	return albums, err
+}
+
+func (r *memoryAlbumRepository) FindByArtist(userId uint, artistId uint, role string) ([]models.Album, error) {
+	albums := []models.Album{}
+	err := r.store.read(func(data *memoryData) error {
+		for _, album := range data.albums {
+			if album.UserId != userId {
+				continue
+			}
+			if slices.ContainsFunc(data.albumCredits[album.ID], func(credit models.AlbumCredit) bool {
+				return credit.ArtistId == artistId && (role == "" || credit.Role == role)
+			}) {
+				albums = append(albums, withAlbumSongs(data, album))
+			}
+		}
+		return nil
+	})
+	sort.Slice(albums, func(i, j int) bool {
+		if albums[i].Year != albums[j].Year {
+			return albums[i].Year < albums[j].Year
+		}
+		return albums[i].ID < albums[j].ID
+	})
+	return albums, err
+}
+
+func (r *memoryAlbumRepository) Update(album *models.Album, columns ...string) error {
+	return r.store.write(func(data *memoryData) error {
+		stored, ok := data.albums[album.ID]
+		if !ok {
+			return nil
+		}
+		assignColumns(&stored, album, columns)
+		if albumExists(data, stored) {
+			return duplicateError("albums", "user_id, artist, title, year")
+		}
+		album.UpdatedAt = time.Now()
+		stored.UpdatedAt = album.UpdatedAt
+		data.albums[album.ID] = stored
+		return nil
+	})
+}
+
+func (r *memoryAlbumRepository) Delete(album *models.Album) error {
+	return r.store.write(func(data *memoryData) error {
+		stored, ok := data.albums[album.ID]
+		if !ok {
+			return nil
+		}
+		delete(data.albums, album.ID)
+		stored.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+		data.deletedAlbums[album.ID] = stored
+		return nil
+	})
+}
+
+// albumExists reports whether another live album of the owner has the same artist, title and year, ignoring case
+func albumExists(data *memoryData, album models.Album) bool {
+	for id, existing := range data.albums {
+		if id != album.ID && existing.UserId == album.UserId && existing.Year == album.Year &&
+			strings.EqualFold(existing.Artist, album.Artist) && strings.EqualFold(existing.Title, album.Title) {
+			return true
+		}
+	}
+	return false
+}
+
+// withAlbumSongs attaches the songs of the album owner to the album in tracklist order
+func withAlbumSongs(data *memoryData, album models.Album) models.Album {
+	album.Songs = []models.Song{}
+	for _, song := range userSongs(data, album.UserId) {
+		if song.AlbumId != nil && *song.AlbumId == album.ID {
+			album.Songs = append(album.Songs, song)
+		}
+	}
+	// Like tracklistOrder: by disc and track, songs without a track number last
+	sort.SliceStable(album.Songs, func(i, j int) bool {
+		a, b := album.Songs[i], album.Songs[j]
+		if (a.TrackNumber == 0) != (b.TrackNumber == 0) {
+			return b.TrackNumber == 0
+		}
+		if a.DiscNumber != b.DiscNumber {
+			return a.DiscNumber < b.DiscNumber
+		}
+		return a.TrackNumber < b.TrackNumber
+	})
+	return album
+}
+
+func (r *memoryAlbumRepository) FindDeleted(userId uint, limit int, offset int) ([]models.Album, int64, error) {
+	albums := []models.Album{}
+	err := r.store.read(func(data *memoryData) error {
+		for _, album := range data.deletedAlbums {
+			if userId == 0 || album.UserId == userId {
+				albums = append(albums, album)
+			}
+		}
+		return nil
+	})
+	sort.Slice(albums, func(i, j int) bool {
+		return deletedLater(albums[i].DeletedAt, albums[i].ID, albums[j].DeletedAt, albums[j].ID)
+	})
+	start, end := pageBounds(len(albums), limit, offset)
+	return albums[start:end], int64(len(albums)), err
+}
+
+func (r *memoryAlbumRepository) Restore(id uint) (models.Album, error) {
+	var album models.Album
+	err := r.store.write(func(data *memoryData) error {
+		found, ok := data.deletedAlbums[id]
+		if !ok {
+			return ErrNotFound
+		}
+		if albumExists(data, found) {
+			return duplicateError("albums", "user_id, artist, title, year")
+		}
+		delete(data.deletedAlbums, id)
+		found.DeletedAt = gorm.DeletedAt{}
+		data.albums[id] = found
+		album = found
+		return nil
+	})
+	return album, err
+}
@@ -0,0 +1,207 @@
+package repositories
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tushar27x/music-lib-api/models"
+)
+
+type memoryArtistRepository struct {
+	store *MemoryStore
+}
+
+func (r *memoryArtistRepository) Create(artist *models.Artist) error {
+	return r.store.write(func(data *memoryData) error {
+		if artistNameTaken(data, artist.UserId, artist.Name, 0) {
+			return duplicateError("artists", "name")
+		}
+		now := time.Now()
+		artist.ID = data.nextId()
+		artist.CreatedAt, artist.UpdatedAt = now, now
+		data.artists[artist.ID] = *artist
+		return nil
+	})
+}
+
+func (r *memoryArtistRepository) FindOwned(userId uint, id uint) (models.Artist, error) {
+	var artist models.Artist
+	err := r.store.read(func(data *memoryData) error {
+		found, ok := data.artists[id]
+		if !ok || found.UserId != userId {
+			return ErrNotFound
+		}
+		artist = found
+		return nil
+	})
+	return artist, err
+}
+
+func (r *memoryArtistRepository) FindByName(userId uint, name string) (models.Artist, error) {
+	var artist models.Artist
+	err := r.store.read(func(data *memoryData) error {
+		for _, found := range data.artists {
+			if found.UserId == userId && strings.EqualFold(found.Name, name) {
+				artist = found
+				return nil
+			}
+		}
+		return ErrNotFound
+	})
+	return artist, err
+}
+
+func (r *memoryArtistRepository) FindByUser(userId uint) ([]models.Artist, error) {
+	artists := []models.Artist{}
+	err := r.store.read(func(data *memoryData) error {
+		for _, artist := range data.artists {
+			if artist.UserId == userId {
+				artists = append(artists, artist)
+			}
+		}
+		return nil
+	})
+	sort.Slice(artists, func(i, j int) bool {
+		a, b := strings.ToLower(artists[i].Name), strings.ToLower(artists[j].Name)
+		if a != b {
+			return a < b
+		}
+		return artists[i].ID < artists[j].ID
+	})
+	return artists, err
+}
+
+func (r *memoryArtistRepository) Update(artist *models.Artist, columns ...string) error {
+	return r.store.write(func(data *memoryData) error {
+		stored, ok := data.artists[artist.ID]
+		if !ok {
+			return nil
+		}
+		assignColumns(&stored, artist, columns)
+		if artistNameTaken(data, stored.UserId, stored.Name, stored.ID) {
+			return duplicateError("artists", "name")
+		}
+		artist.UpdatedAt = time.Now()
+		stored.UpdatedAt = artist.UpdatedAt
+		data.artists[artist.ID] = stored
+		return nil
+	})
+}
+
+func (r *memoryArtistRepository) Delete(artist *models.Artist) error {
+	return r.store.write(func(data *memoryData) error {
+		delete(data.artists, artist.ID)
+		for albumId, credits := range data.albumCredits {
+			data.albumCredits[albumId] = slices.DeleteFunc(slices.Clone(credits), func(credit models.AlbumCredit) bool {
+				return credit.ArtistId == artist.ID
+			})
+		}
+		for songId, credits := range data.songCredits {
+			data.songCredits[songId] = slices.DeleteFunc(slices.Clone(credits), func(credit models.SongCredit) bool {
+				return credit.ArtistId == artist.ID
+			})
+		}
+		return nil
+	})
+}
+
+func (r *memoryArtistRepository) CountCredits(artistId uint) (int64, error) {
+	var count int64
+	err := r.store.read(func(data *memoryData) error {
+		for albumId, credits := range data.albumCredits {
+			if _, live := data.albums[albumId]; !live {
+				continue
+			}
+			for _, credit := range credits {
+				if credit.ArtistId == artistId {
+					count++
+				}
+			}
+		}
+		for songId, credits := range data.songCredits {
+			if _, live := data.songs[songId]; !live {
+				continue
+			}
+			for _, credit := range credits {
+				if credit.ArtistId == artistId {
+					count++
+				}
+			}
+		}
+		return nil
+	})
+	return count, err
+}
+
+func (r *memoryArtistRepository) FindAlbumCredits(albumIds []uint) ([]models.AlbumCredit, error) {
+	credits := []models.AlbumCredit{}
+	err := r.store.read(func(data *memoryData) error {
+		for _, albumId := range sortedIds(albumIds) {
+			for _, credit := range data.albumCredits[albumId] {
+				credit.Name = data.artists[credit.ArtistId].Name
+				credits = append(credits, credit)
+			}
+		}
+		return nil
+	})
+	return credits, err
+}
+
+func (r *memoryArtistRepository) SetAlbumCredits(albumId uint, credits []models.AlbumCredit) error {
+	return r.store.write(func(data *memoryData) error {
+		stored := make([]models.AlbumCredit, len(credits))
+		for i, credit := range credits {
+			credit.Name = ""
+			stored[i] = credit
+		}
+		sort.SliceStable(stored, func(i, j int) bool { return stored[i].Position < stored[j].Position })
+		data.albumCredits[albumId] = stored
+		return nil
+	})
+}
+
+func (r *memoryArtistRepository) FindSongCredits(songIds []uint) ([]models.SongCredit, error) {
+	credits := []models.SongCredit{}
+	err := r.store.read(func(data *memoryData) error {
+		for _, songId := range sortedIds(songIds) {
+			for _, credit := range data.songCredits[songId] {
+				credit.Name = data.artists[credit.ArtistId].Name
+				credits = append(credits, credit)
+			}
+		}
+		return nil
+	})
+	return credits, err
+}
+
+func (r *memoryArtistRepository) SetSongCredits(songId uint, credits []models.SongCredit) error {
+	return r.store.write(func(data *memoryData) error {
+		stored := make([]models.SongCredit, len(credits))
+		for i, credit := range credits {
+			credit.Name = ""
+			stored[i] = credit
+		}
+		sort.SliceStable(stored, func(i, j int) bool { return stored[i].Position < stored[j].Position })
+		data.songCredits[songId] = stored
+		return nil
+	})
+}
+
+// artistNameTaken reports whether another artist of the user has the name, ignoring case like the unique index
+func artistNameTaken(data *memoryData, userId uint, name string, exceptId uint) bool {
+	for id, existing := range data.artists {
+		if id != exceptId && existing.UserId == userId && strings.EqualFold(existing.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedIds returns the distinct IDs in ascending order, like the ORDER BY of the database
+func sortedIds(ids []uint) []uint {
+	sorted := slices.Clone(ids)
+	slices.Sort(sorted)
+	return slices.Compact(sorted)
+}
@@ -0,0 +1,46 @@
+package repositories
+
+import (
+	"sort"
+	"time"
+
+	"github.com/tushar27x/music-lib-api/models"
+)
+
+type memoryAuditRepository struct {
+	store *MemoryStore
+}
+
+func (r *memoryAuditRepository) Create(entry *models.AuditLog) error {
+	return r.store.write(func(data *memoryData) error {
+		entry.ID = data.nextId()
+		entry.CreatedAt = time.Now()
+		data.auditLogs[entry.ID] = *entry
+		return nil
+	})
+}
+
+func (r *memoryAuditRepository) List(filter AuditFilter, limit int, offset int) ([]models.AuditLog, int64, error) {
+	entries := []models.AuditLog{}
+	err := r.store.read(func(data *memoryData) error {
+		for _, entry := range data.auditLogs {
+			if filter.ActorId != nil && (entry.ActorId == nil || *entry.ActorId != *filter.ActorId) {
+				continue
+			}
+			if filter.Action != "" && entry.Action != filter.Action {
+				continue
+			}
+			if filter.TargetType != "" && entry.TargetType != filter.TargetType {
+				continue
+			}
+			if filter.TargetId != nil && entry.TargetId != *filter.TargetId {
+				continue
+			}
+			entries = append(entries, entry)
+		}
+		return nil
+	})
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ID > entries[j].ID })
+	start, end := pageBounds(len(entries), limit, offset)
+	return entries[start:end], int64(len(entries)), err
+}
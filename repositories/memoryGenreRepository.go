@@ -0,0 +1,165 @@
+package repositories
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tushar27x/music-lib-api/models"
+)
+
+type memoryGenreRepository struct {
+	store *MemoryStore
+}
+
+func (r *memoryGenreRepository) Create(genre *models.Genre) error {
+	return r.store.write(func(data *memoryData) error {
+		if genreNameTaken(data, genre.UserId, genre.Name, 0) {
+			return duplicateError("genres", "name")
+		}
+		now := time.Now()
+		genre.ID = data.nextId()
+		genre.CreatedAt, genre.UpdatedAt = now, now
+		data.genres[genre.ID] = *genre
+		return nil
+	})
+}
+
+func (r *memoryGenreRepository) FindOwned(userId uint, id uint) (models.Genre, error) {
+	var genre models.Genre
+	err := r.store.read(func(data *memoryData) error {
+		found, ok := data.genres[id]
+		if !ok || found.UserId != userId {
+			return ErrNotFound
+		}
+		genre = found
+		return nil
+	})
+	return genre, err
+}
+
+func (r *memoryGenreRepository) FindByName(userId uint, name string) (models.Genre, error) {
+	var genre models.Genre
+	err := r.store.read(func(data *memoryData) error {
+		for _, found := range data.genres {
+			if found.UserId == userId && strings.EqualFold(found.Name, name) {
+				genre = found
+				return nil
+			}
+		}
+		return ErrNotFound
+	})
+	return genre, err
+}
+
+func (r *memoryGenreRepository) FindByUser(userId uint) ([]models.Genre, error) {
+	genres := []models.Genre{}
+	err := r.store.read(func(data *memoryData) error {
+		for _, genre := range data.genres {
+			if genre.UserId == userId {
+				genres = append(genres, genre)
+			}
+		}
+		return nil
+	})
+	sort.Slice(genres, func(i, j int) bool {
+		a, b := strings.ToLower(genres[i].Name), strings.ToLower(genres[j].Name)
+		if a != b {
+			return a < b
+		}
+		return genres[i].ID < genres[j].ID
+	})
+	return genres, err
+}
+
+func (r *memoryGenreRepository) Update(genre *models.Genre, columns ...string) error {
+	return r.store.write(func(data *memoryData) error {
+		stored, ok := data.genres[genre.ID]
+		if !ok {
+			return nil
+		}
+		assignColumns(&stored, genre, columns)
+		if genreNameTaken(data, stored.UserId, stored.Name, stored.ID) {
+			return duplicateError("genres", "name")
+		}
+		genre.UpdatedAt = time.Now()
+		stored.UpdatedAt = genre.UpdatedAt
+		data.genres[genre.ID] = stored
+		return nil
+	})
+}
+
+func (r *memoryGenreRepository) Delete(genre *models.Genre) error {
+	return r.store.write(func(data *memoryData) error {
+		for id, album := range data.deletedAlbums {
+			if album.GenreId != nil && *album.GenreId == genre.ID {
+				album.Genre, album.GenreId = "", nil
+				data.deletedAlbums[id] = album
+			}
+		}
+		for id, song := range data.deletedSongs {
+			if song.GenreId != nil && *song.GenreId == genre.ID {
+				song.Genre, song.GenreId = "", nil
+				data.deletedSongs[id] = song
+			}
+		}
+		delete(data.genres, genre.ID)
+		return nil
+	})
+}
+
+func (r *memoryGenreRepository) CountUses(genreId uint) (int64, error) {
+	var count int64
+	err := r.store.read(func(data *memoryData) error {
+		for _, genre := range data.genres {
+			if genre.ParentId != nil && *genre.ParentId == genreId {
+				count++
+			}
+		}
+		for _, album := range data.albums {
+			if album.GenreId != nil && *album.GenreId == genreId {
+				count++
+			}
+		}
+		for _, song := range data.songs {
+			if song.GenreId != nil && *song.GenreId == genreId {
+				count++
+			}
+		}
+		return nil
+	})
+	return count, err
+}
+
+func (r *memoryGenreRepository) RenameUses(genreId uint, name string) error {
+	return r.store.write(func(data *memoryData) error {
+		now := time.Now()
+		for _, albums := range []map[uint]models.Album{data.albums, data.deletedAlbums} {
+			for id, album := range albums {
+				if album.GenreId != nil && *album.GenreId == genreId {
+					album.Genre, album.UpdatedAt = name, now
+					albums[id] = album
+				}
+			}
+		}
+		for _, songs := range []map[uint]models.Song{data.songs, data.deletedSongs} {
+			for id, song := range songs {
+				if song.GenreId != nil && *song.GenreId == genreId {
+					song.Genre, song.UpdatedAt = name, now
+					songs[id] = song
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// genreNameTaken reports whether another genre of the user has the name, ignoring case like the unique index
+func genreNameTaken(data *memoryData, userId uint, name string, exceptId uint) bool {
+	for id, existing := range data.genres {
+		if id != exceptId && existing.UserId == userId && strings.EqualFold(existing.Name, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,97 @@
+package repositories
+
+import (
+	"sort"
+	"time"
+
+	"github.com/tushar27x/music-lib-api/models"
+)
+
+type memoryIdentityRepository struct {
+	store *MemoryStore
+}
+
+func (r *memoryIdentityRepository) Create(identity *models.UserIdentity) error {
+	return r.store.write(func(data *memoryData) error {
+		for _, existing := range data.identities {
+			if existing.Provider == identity.Provider && existing.Subject == identity.Subject {
+				return duplicateError("user_identities", "subject")
+			}
+		}
+		identity.ID = data.nextId()
+		identity.CreatedAt = time.Now()
+		data.identities[identity.ID] = *identity
+		return nil
+	})
+}
+
+func (r *memoryIdentityRepository) FindBySubject(provider string, subject string) (models.UserIdentity, error) {
+	var identity models.UserIdentity
+	err := r.store.read(func(data *memoryData) error {
+		for _, found := range data.identities {
+			if found.Provider == provider && found.Subject == subject {
+				identity = found
+				return nil
+			}
+		}
+		return ErrNotFound
+	})
+	return identity, err
+}
+
+func (r *memoryIdentityRepository) FindByUser(userId uint) ([]models.UserIdentity, error) {
+	identities := []models.UserIdentity{}
+	err := r.store.read(func(data *memoryData) error {
+		for _, identity := range data.identities {
+			if identity.UserId == userId {
+				identities = append(identities, identity)
+			}
+		}
+		sort.Slice(identities, func(i, j int) bool { return identities[i].ID < identities[j].ID })
+		return nil
+	})
+	return identities, err
+}
+
+func (r *memoryIdentityRepository) CreateLogin(login *models.OIDCLogin) error {
+	return r.store.write(func(data *memoryData) error {
+		for _, existing := range data.oidcLogins {
+			if existing.StateHash == login.StateHash {
+				return duplicateError("oidc_logins", "state_hash")
+			}
+		}
+		login.ID = data.nextId()
+		login.CreatedAt = time.Now()
+		data.oidcLogins[login.ID] = *login
+		return nil
+	})
+}
+
+func (r *memoryIdentityRepository) FindLogin(stateHash string) (models.OIDCLogin, error) {
+	var login models.OIDCLogin
+	err := r.store.read(func(data *memoryData) error {
+		for _, found := range data.oidcLogins {
+			if found.StateHash == stateHash {
+				login = found
+				return nil
+			}
+		}
+		return ErrNotFound
+	})
+	return login, err
+}
+
+func (r *memoryIdentityRepository) UseLogin(id uint, at time.Time) (bool, error) {
+	used := false
+	err := r.store.write(func(data *memoryData) error {
+		login, ok := data.oidcLogins[id]
+		if !ok || login.UsedAt != nil {
+			return nil
+		}
+		login.UsedAt = &at
+		data.oidcLogins[id] = login
+		used = true
+		return nil
+	})
+	return used, err
+}
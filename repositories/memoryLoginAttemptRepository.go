@@ -0,0 +1,93 @@
+package repositories
+
+import (
+	"sort"
+	"time"
+
+	"github.com/tushar27x/music-lib-api/models"
+)
+
+type memoryLoginAttemptRepository struct {
+	store *MemoryStore
+}
+
+func (r *memoryLoginAttemptRepository) Find(kind string, target string) (models.LoginAttempt, error) {
+	var attempt models.LoginAttempt
+	err := r.store.read(func(data *memoryData) error {
+		for _, found := range data.loginAttempts {
+			if found.Kind == kind && found.Target == target {
+				attempt = found
+				return nil
+			}
+		}
+		return ErrNotFound
+	})
+	return attempt, err
+}
+
+// Lock needs no lock of its own: transactions already hold the whole store
+func (r *memoryLoginAttemptRepository) Lock(kind string, target string) (models.LoginAttempt, error) {
+	return r.Find(kind, target)
+}
+
+func (r *memoryLoginAttemptRepository) FindByID(id uint) (models.LoginAttempt, error) {
+	var attempt models.LoginAttempt
+	err := r.store.read(func(data *memoryData) error {
+		found, ok := data.loginAttempts[id]
+		if !ok {
+			return ErrNotFound
+		}
+		attempt = found
+		return nil
+	})
+	return attempt, err
+}
+
+func (r *memoryLoginAttemptRepository) Save(attempt *models.LoginAttempt) error {
+	return r.store.write(func(data *memoryData) error {
+		for _, existing := range data.loginAttempts {
+			if existing.ID != attempt.ID && existing.Kind == attempt.Kind && existing.Target == attempt.Target {
+				return duplicateError("login_attempts", "target")
+			}
+		}
+		now := time.Now()
+		if attempt.ID == 0 {
+			attempt.ID = data.nextId()
+			attempt.CreatedAt = now
+		}
+		attempt.UpdatedAt = now
+		data.loginAttempts[attempt.ID] = *attempt
+		return nil
+	})
+}
+
+func (r *memoryLoginAttemptRepository) Delete(id uint) error {
+	return r.store.write(func(data *memoryData) error {
+		delete(data.loginAttempts, id)
+		return nil
+	})
+}
+
+func (r *memoryLoginAttemptRepository) ListLocked(kind string, now time.Time, limit int, offset int) ([]models.LoginAttempt, int64, error) {
+	attempts := []models.LoginAttempt{}
+	err := r.store.read(func(data *memoryData) error {
+		for _, attempt := range data.loginAttempts {
+			if attempt.LockedUntil == nil || !attempt.LockedUntil.After(now) {
+				continue
+			}
+			if kind != "" && attempt.Kind != kind {
+				continue
+			}
+			attempts = append(attempts, attempt)
+		}
+		return nil
+	})
+	sort.Slice(attempts, func(i, j int) bool {
+		if !attempts[i].LockedUntil.Equal(*attempts[j].LockedUntil) {
+			return attempts[i].LockedUntil.After(*attempts[j].LockedUntil)
+		}
+		return attempts[i].ID > attempts[j].ID
+	})
+	start, end := pageBounds(len(attempts), limit, offset)
+	return attempts[start:end], int64(len(attempts)), err
+}
@@ -0,0 +1,277 @@
+package repositories
+
+import (
+	"sort"
+	"time"
+
+	"github.com/tushar27x/music-lib-api/models"
+	"gorm.io/gorm"
+)
+
+type memoryPlaylistRepository struct {
+	store *MemoryStore
+}
+
+func (r *memoryPlaylistRepository) Create(playlist *models.Playlist) error {
+	return r.store.write(func(data *memoryData) error {
+		now := time.Now()
+		playlist.ID = data.nextId()
+		playlist.CreatedAt, playlist.UpdatedAt = now, now
+		if playlist.Type == "" {
+			playlist.Type = models.PlaylistTypeManual
+		}
+		stored := *playlist
+		stored.Entries, stored.Songs = nil, nil
+		data.playlists[playlist.ID] = stored
+		return nil
+	})
+}
+
+func (r *memoryPlaylistRepository) FindByID(id uint) (models.Playlist, error) {
+	var playlist models.Playlist
+	err := r.store.read(func(data *memoryData) error {
+		found, ok := data.playlists[id]
+		if !ok {
+			return ErrNotFound
+		}
+		playlist = found
+		return nil
+	})
+	return playlist, err
+}
+
+func (r *memoryPlaylistRepository) FindOwned(userId uint, id uint) (models.Playlist, error) {
+	playlist, err := r.FindByID(id)
+	if err == nil && playlist.UserId != userId {
+		return models.Playlist{}, ErrNotFound
+	}
+	return playlist, err
+}
+
+func (r *memoryPlaylistRepository) FindOwnedWithTracks(userId uint, id uint) (models.Playlist, error) {
+	var playlist models.Playlist
+	err := r.store.read(func(data *memoryData) error {
+		found, ok := data.playlists[id]
+		if !ok || found.UserId != userId {
+			return ErrNotFound
+		}
+		playlist = withTracks(data, found)
+		return nil
+	})
+	return playlist, err
+}
+
+func (r *memoryPlaylistRepository) FindByUser(userId uint) ([]models.Playlist, error) {
+	playlists := []models.Playlist{}
+	err := r.store.read(func(data *memoryData) error {
+		for _, playlist := range data.playlists {
+			if playlist.UserId == userId {
+				playlists = append(playlists, withTracks(data, playlist))
+			}
+		}
+		return nil
+	})
+	sort.Slice(playlists, func(i, j int) bool { return playlists[i].ID < playlists[j].ID })
+	return playlists, err
+}
+
+// LockOwned needs no lock of its own: transactions already hold the whole store
+func (r *memoryPlaylistRepository) LockOwned(userId uint, id uint) (models.Playlist, error) {
+	return r.FindOwned(userId, id)
+}
+
+func (r *memoryPlaylistRepository) Update(playlist *models.Playlist, columns ...string) error {
+	return r.store.write(func(data *memoryData) error {
+		stored, ok := data.playlists[playlist.ID]
+		if !ok {
+			return nil
+		}
+		assignColumns(&stored, playlist, columns)
+		playlist.UpdatedAt = time.Now()
+		stored.UpdatedAt = playlist.UpdatedAt
+		data.playlists[playlist.ID] = stored
+		return nil
+	})
+}
+
+func (r *memoryPlaylistRepository) Delete(playlist *models.Playlist) error {
+	return r.store.write(func(data *memoryData) error {
+		stored, ok := data.playlists[playlist.ID]
+		if !ok {
+			return nil
+		}
+		delete(data.playlists, playlist.ID)
+		stored.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+		data.deletedPlaylists[playlist.ID] = stored
+		return nil
+	})
+}
+
+func (r *memoryPlaylistRepository) FindEntry(playlistId uint, entryId uint) (models.PlaylistEntry, error) {
+	var entry models.PlaylistEntry
+	err := r.store.read(func(data *memoryData) error {
+		found, ok := data.entries[entryId]
+		if !ok || found.PlaylistId != playlistId {
+			return ErrNotFound
+		}
+		entry = found
+		return nil
+	})
+	return entry, err
+}
+
+func (r *memoryPlaylistRepository) FindEntries(playlistId uint) ([]models.PlaylistEntry, error) {
+	var entries []models.PlaylistEntry
+	err := r.store.read(func(data *memoryData) error {
+		entries = playlistEntries(data, playlistId)
+		return nil
+	})
+	return entries, err
+}
+
+func (r *memoryPlaylistRepository) CountEntries(playlistId uint) (int, error) {
+	entries, err := r.FindEntries(playlistId)
+	return len(entries), err
+}
+
+func (r *memoryPlaylistRepository) CreateEntries(entries []models.PlaylistEntry) error {
+	return r.store.write(func(data *memoryData) error {
+		now := time.Now()
+		for i := range entries {
+			entries[i].ID = data.nextId()
+			entries[i].CreatedAt = now
+			stored := entries[i]
+			stored.Song = nil
+			data.entries[stored.ID] = stored
+		}
+		return nil
+	})
+}
+
+func (r *memoryPlaylistRepository) ShiftEntries(playlistId uint, from int, to int, delta int) error {
+	return r.store.write(func(data *memoryData) error {
+		for id, entry := range data.entries {
+			if entry.PlaylistId == playlistId && entry.Position >= from && (to < 0 || entry.Position < to) {
+				entry.Position += delta
+				data.entries[id] = entry
+			}
+		}
+		return nil
+	})
+}
+
+func (r *memoryPlaylistRepository) SetEntryPosition(entry *models.PlaylistEntry, position int) error {
+	return r.store.write(func(data *memoryData) error {
+		entry.Position = position
+		if stored, ok := data.entries[entry.ID]; ok {
+			stored.Position = position
+			data.entries[entry.ID] = stored
+		}
+		return nil
+	})
+}
+
+func (r *memoryPlaylistRepository) DeleteEntry(entry models.PlaylistEntry) error {
+	return r.store.write(func(data *memoryData) error {
+		delete(data.entries, entry.ID)
+		return nil
+	})
+}
+
+func (r *memoryPlaylistRepository) DeleteEntries(playlistId uint) error {
+	return r.store.write(func(data *memoryData) error {
+		for id, entry := range data.entries {
+			if entry.PlaylistId == playlistId {
+				delete(data.entries, id)
+			}
+		}
+		return nil
+	})
+}
+
+func (r *memoryPlaylistRepository) FindPlaylistIdsWithSong(songId uint) ([]uint, error) {
+	var playlistIds []uint
+	err := r.store.read(func(data *memoryData) error {
+		seen := map[uint]bool{}
+		for _, entry := range data.entries {
+			if entry.SongId == songId && !seen[entry.PlaylistId] {
+				seen[entry.PlaylistId] = true
+				playlistIds = append(playlistIds, entry.PlaylistId)
+			}
+		}
+		return nil
+	})
+	return playlistIds, err
+}
+
+func (r *memoryPlaylistRepository) DeleteSongEntries(songId uint) error {
+	return r.store.write(func(data *memoryData) error {
+		for id, entry := range data.entries {
+			if entry.SongId == songId {
+				delete(data.entries, id)
+			}
+		}
+		return nil
+	})
+}
+
+// playlistEntries returns the entries of a playlist ordered like FindEntries on the database
+func playlistEntries(data *memoryData, playlistId uint) []models.PlaylistEntry {
+	var entries []models.PlaylistEntry
+	for _, entry := range data.entries {
+		if entry.PlaylistId == playlistId {
+			entries = append(entries, entry)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Position != entries[j].Position {
+			return entries[i].Position < entries[j].Position
+		}
+		return entries[i].ID < entries[j].ID
+	})
+	return entries
+}
+
+// withTracks attaches the ordered entries and their songs, as WithTracks does on the database
+func withTracks(data *memoryData, playlist models.Playlist) models.Playlist {
+	playlist.Entries = playlistEntries(data, playlist.ID)
+	for i := range playlist.Entries {
+		if song, ok := data.songs[playlist.Entries[i].SongId]; ok {
+			playlist.Entries[i].Song = &song
+		}
+	}
+	return playlist
+}
+
+func (r *memoryPlaylistRepository) FindDeleted(userId uint, limit int, offset int) ([]models.Playlist, int64, error) {
+	playlists := []models.Playlist{}
+	err := r.store.read(func(data *memoryData) error {
+		for _, playlist := range data.deletedPlaylists {
+			if userId == 0 || playlist.UserId == userId {
+				playlists = append(playlists, playlist)
+			}
+		}
+		return nil
+	})
+	sort.Slice(playlists, func(i, j int) bool {
+		return deletedLater(playlists[i].DeletedAt, playlists[i].ID, playlists[j].DeletedAt, playlists[j].ID)
+	})
+	start, end := pageBounds(len(playlists), limit, offset)
+	return playlists[start:end], int64(len(playlists)), err
+}
+
+func (r *memoryPlaylistRepository) Restore(id uint) (models.Playlist, error) {
+	var playlist models.Playlist
+	err := r.store.write(func(data *memoryData) error {
+		found, ok := data.deletedPlaylists[id]
+		if !ok {
+			return ErrNotFound
+		}
+		delete(data.deletedPlaylists, id)
+		found.DeletedAt = gorm.DeletedAt{}
+		data.playlists[id] = found
+		playlist = found
+		return nil
+	})
+	return playlist, err
+}
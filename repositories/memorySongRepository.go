@@ -0,0 +1,249 @@
+package repositories
+
+import (
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/tushar27x/music-lib-api/models"
+	"gorm.io/gorm"
+)
+
+type memorySongRepository struct {
+	store *MemoryStore
+}
+
+func (r *memorySongRepository) Create(song *models.Song) error {
+	return r.store.write(func(data *memoryData) error {
+		if trackTaken(data, *song) {
+			return duplicateError("songs", "album_id, disc_number, track_number")
+		}
+		now := time.Now()
+		song.ID = data.nextId()
+		song.CreatedAt, song.UpdatedAt = now, now
+		stored := *song
+		stored.Credits, stored.Tags = nil, nil
+		data.songs[song.ID] = stored
+		return nil
+	})
+}
+
+func (r *memorySongRepository) FindByID(id uint) (models.Song, error) {
+	var song models.Song
+	err := r.store.read(func(data *memoryData) error {
+		found, ok := data.songs[id]
+		if !ok {
+			return ErrNotFound
+		}
+		song = found
+		return nil
+	})
+	return song, err
+}
+
+func (r *memorySongRepository) FindOwned(userId uint, id uint) (models.Song, error) {
+	song, err := r.FindByID(id)
+	if err == nil && song.UserId != userId {
+		return models.Song{}, ErrNotFound
+	}
+	return song, err
+}
+
+func (r *memorySongRepository) FindByUser(userId uint) ([]models.Song, error) {
+	var songs []models.Song
+	err := r.store.read(func(data *memoryData) error {
+		songs = userSongs(data, userId)
+		return nil
+	})
+	return songs, err
+}
+
+func (r *memorySongRepository) FindByArtist(userId uint, artistId uint, role string) ([]models.Song, error) {
+	songs := []models.Song{}
+	err := r.store.read(func(data *memoryData) error {
+		for _, song := range userSongs(data, userId) {
+			if slices.ContainsFunc(data.songCredits[song.ID], func(credit models.SongCredit) bool {
+				return credit.ArtistId == artistId && (role == "" || credit.Role == role)
+			}) {
+				songs = append(songs, song)
+			}
+		}
+		return nil
+	})
+	return songs, err
+}
+
+func (r *memorySongRepository) CountOwned(userId uint, ids []uint) (int, error) {
+	count := 0
+	err := r.store.read(func(data *memoryData) error {
+		for _, id := range ids {
+			if song, ok := data.songs[id]; ok && song.UserId == userId {
+				count++
+			}
+		}
+		return nil
+	})
+	return count, err
+}
+
+func (r *memorySongRepository) FindMatching(userId uint, rules models.SmartPlaylistRules, now time.Time) ([]models.Song, error) {
+	songs := []models.Song{}
+	err := r.store.read(func(data *memoryData) error {
+		for _, song := range userSongs(data, userId) {
+			var album *models.Album
+			if song.AlbumId != nil {
+				if found, ok := data.albums[*song.AlbumId]; ok {
+					album = &found
+				}
+			}
+			if matchSmartGroup(rules.SmartRuleGroup, song, album, now) {
+				songs = append(songs, song)
+			}
+		}
+		sortSmartSongs(songs, data.albums, rules.Sort)
+		return nil
+	})
+	if rules.Limit > 0 && len(songs) > rules.Limit {
+		songs = songs[:rules.Limit]
+	}
+	return songs, err
+}
+
+func (r *memorySongRepository) Update(song *models.Song, columns ...string) error {
+	return r.store.write(func(data *memoryData) error {
+		stored, ok := data.songs[song.ID]
+		if !ok {
+			return nil
+		}
+		assignColumns(&stored, song, columns)
+		if trackTaken(data, stored) {
+			return duplicateError("songs", "album_id, disc_number, track_number")
+		}
+		song.UpdatedAt = time.Now()
+		stored.UpdatedAt = song.UpdatedAt
+		data.songs[song.ID] = stored
+		return nil
+	})
+}
+
+func (r *memorySongRepository) RecordPlay(id uint, at time.Time) error {
+	return r.store.write(func(data *memoryData) error {
+		song, ok := data.songs[id]
+		if !ok {
+			return nil
+		}
+		song.PlayCount++
+		song.LastPlayedAt = &at
+		data.songs[id] = song
+		return nil
+	})
+}
+
+func (r *memorySongRepository) Delete(song *models.Song) error {
+	return r.store.write(func(data *memoryData) error {
+		if stored, ok := data.songs[song.ID]; ok {
+			trashSong(data, stored, time.Now())
+		}
+		return nil
+	})
+}
+
+func (r *memorySongRepository) DeleteByAlbum(userId uint, albumId uint) error {
+	return r.store.write(func(data *memoryData) error {
+		for id, song := range data.songs {
+			if song.UserId == userId && song.AlbumId != nil && *song.AlbumId == albumId {
+				trashSong(data, data.songs[id], time.Now())
+			}
+		}
+		return nil
+	})
+}
+
+func (r *memorySongRepository) MoveToAlbum(userId uint, albumId uint, targetId uint) error {
+	return r.store.write(func(data *memoryData) error {
+		now := time.Now()
+		for id, song := range data.songs {
+			if song.UserId == userId && song.AlbumId != nil && *song.AlbumId == albumId {
+				target := targetId
+				song.AlbumId = &target
+				song.UpdatedAt = now
+				data.songs[id] = song
+			}
+		}
+		for _, song := range data.songs {
+			if trackTaken(data, song) {
+				return duplicateError("songs", "album_id, disc_number, track_number")
+			}
+		}
+		return nil
+	})
+}
+
+// userSongs returns the songs of a user in ID order, like an unordered query on Postgres usually does
+func userSongs(data *memoryData, userId uint) []models.Song {
+	songs := []models.Song{}
+	for _, song := range data.songs {
+		if song.UserId == userId {
+			songs = append(songs, song)
+		}
+	}
+	sort.Slice(songs, func(i, j int) bool { return songs[i].ID < songs[j].ID })
+	return songs
+}
+
+func (r *memorySongRepository) FindDeleted(userId uint, limit int, offset int) ([]models.Song, int64, error) {
+	songs := []models.Song{}
+	err := r.store.read(func(data *memoryData) error {
+		for _, song := range data.deletedSongs {
+			if userId == 0 || song.UserId == userId {
+				songs = append(songs, song)
+			}
+		}
+		return nil
+	})
+	sort.Slice(songs, func(i, j int) bool {
+		return deletedLater(songs[i].DeletedAt, songs[i].ID, songs[j].DeletedAt, songs[j].ID)
+	})
+	start, end := pageBounds(len(songs), limit, offset)
+	return songs[start:end], int64(len(songs)), err
+}
+
+func (r *memorySongRepository) Restore(id uint) (models.Song, error) {
+	var song models.Song
+	err := r.store.write(func(data *memoryData) error {
+		found, ok := data.deletedSongs[id]
+		if !ok {
+			return ErrNotFound
+		}
+		if trackTaken(data, found) {
+			return duplicateError("songs", "album_id, disc_number, track_number")
+		}
+		delete(data.deletedSongs, id)
+		found.DeletedAt = gorm.DeletedAt{}
+		data.songs[id] = found
+		song = found
+		return nil
+	})
+	return song, err
+}
+
+// trackTaken reports whether another live song has the disc and track number of the song on its album
+func trackTaken(data *memoryData, song models.Song) bool {
+	if song.AlbumId == nil || song.TrackNumber == 0 {
+		return false
+	}
+	for id, existing := range data.songs {
+		if id != song.ID && existing.AlbumId != nil && *existing.AlbumId == *song.AlbumId &&
+			existing.DiscNumber == song.DiscNumber && existing.TrackNumber == song.TrackNumber {
+			return true
+		}
+	}
+	return false
+}
+
+// trashSong moves a song to the deleted songs
+func trashSong(data *memoryData, song models.Song, at time.Time) {
+	delete(data.songs, song.ID)
+	song.DeletedAt = gorm.DeletedAt{Time: at, Valid: true}
+	data.deletedSongs[song.ID] = song
+}
@@ -0,0 +1,230 @@
+package repositories
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"sync"
+
+	"github.com/tushar27x/music-lib-api/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// memoryData holds every record of a MemoryStore. Deleted songs, albums and playlists move to
+// their own maps so they can be restored, like soft deletes in the database; other records are simply removed.
+// Credits and tags are kept per album and song ID, and their slices are only ever replaced so clones can share them.
+type memoryData struct {
+	lastId           uint
+	songs            map[uint]models.Song
+	albums           map[uint]models.Album
+	artists          map[uint]models.Artist
+	albumCredits     map[uint][]models.AlbumCredit
+	songCredits      map[uint][]models.SongCredit
+	genres           map[uint]models.Genre
+	tags             map[uint]models.Tag
+	albumTags        map[uint][]models.TaggedAlbum
+	songTags         map[uint][]models.TaggedSong
+	playlists        map[uint]models.Playlist
+	deletedSongs     map[uint]models.Song
+	deletedAlbums    map[uint]models.Album
+	deletedPlaylists map[uint]models.Playlist
+	entries          map[uint]models.PlaylistEntry
+	users            map[uint]models.User
+	refreshTokens    map[uint]models.RefreshToken
+	revokedTokens    map[string]models.RevokedToken
+	accountTokens    map[uint]models.AccountToken
+	recoveryCodes    map[uint]models.RecoveryCode
+	apiKeys          map[uint]models.APIKey
+	identities       map[uint]models.UserIdentity
+	oidcLogins       map[uint]models.OIDCLogin
+	loginAttempts    map[uint]models.LoginAttempt
+	auditLogs        map[uint]models.AuditLog
+}
+
+func (d *memoryData) clone() *memoryData {
+	return &memoryData{
+		lastId:           d.lastId,
+		songs:            maps.Clone(d.songs),
+		albums:           maps.Clone(d.albums),
+		artists:          maps.Clone(d.artists),
+		albumCredits:     maps.Clone(d.albumCredits),
+		songCredits:      maps.Clone(d.songCredits),
+		genres:           maps.Clone(d.genres),
+		tags:             maps.Clone(d.tags),
+		albumTags:        maps.Clone(d.albumTags),
+		songTags:         maps.Clone(d.songTags),
+		playlists:        maps.Clone(d.playlists),
+		deletedSongs:     maps.Clone(d.deletedSongs),
+		deletedAlbums:    maps.Clone(d.deletedAlbums),
+		deletedPlaylists: maps.Clone(d.deletedPlaylists),
+		entries:          maps.Clone(d.entries),
+		users:            maps.Clone(d.users),
+		refreshTokens:    maps.Clone(d.refreshTokens),
+		revokedTokens:    maps.Clone(d.revokedTokens),
+		accountTokens:    maps.Clone(d.accountTokens),
+		recoveryCodes:    maps.Clone(d.recoveryCodes),
+		apiKeys:          maps.Clone(d.apiKeys),
+		identities:       maps.Clone(d.identities),
+		oidcLogins:       maps.Clone(d.oidcLogins),
+		loginAttempts:    maps.Clone(d.loginAttempts),
+		auditLogs:        maps.Clone(d.auditLogs),
+	}
+}
+
+// nextId hands out IDs from one sequence shared by every table
+func (d *memoryData) nextId() uint {
+	d.lastId++
+	return d.lastId
+}
+
+// MemoryStore keeps every repository in maps. It is safe for concurrent use;
+// transactions hold the store exclusively and restore a snapshot when they fail.
+type MemoryStore struct {
+	mu   *sync.Mutex
+	data **memoryData
+	// inTx is set on the store handed to a transaction, which already holds mu
+	inTx bool
+}
+
+// NewMemoryStore returns an empty in-memory store for tests and local experiments
+func NewMemoryStore() *MemoryStore {
+	data := &memoryData{
+		songs:            map[uint]models.Song{},
+		albums:           map[uint]models.Album{},
+		artists:          map[uint]models.Artist{},
+		albumCredits:     map[uint][]models.AlbumCredit{},
+		songCredits:      map[uint][]models.SongCredit{},
+		genres:           map[uint]models.Genre{},
+		tags:             map[uint]models.Tag{},
+		albumTags:        map[uint][]models.TaggedAlbum{},
+		songTags:         map[uint][]models.TaggedSong{},
+		playlists:        map[uint]models.Playlist{},
+		deletedSongs:     map[uint]models.Song{},
+		deletedAlbums:    map[uint]models.Album{},
+		deletedPlaylists: map[uint]models.Playlist{},
+		entries:          map[uint]models.PlaylistEntry{},
+		users:            map[uint]models.User{},
+		refreshTokens:    map[uint]models.RefreshToken{},
+		revokedTokens:    map[string]models.RevokedToken{},
+		accountTokens:    map[uint]models.AccountToken{},
+		recoveryCodes:    map[uint]models.RecoveryCode{},
+		apiKeys:          map[uint]models.APIKey{},
+		identities:       map[uint]models.UserIdentity{},
+		oidcLogins:       map[uint]models.OIDCLogin{},
+		loginAttempts:    map[uint]models.LoginAttempt{},
+		auditLogs:        map[uint]models.AuditLog{},
+	}
+	return &MemoryStore{mu: &sync.Mutex{}, data: &data}
+}
+
+func (s *MemoryStore) Songs() SongRepository {
+	return &memorySongRepository{store: s}
+}
+
+func (s *MemoryStore) Albums() AlbumRepository {
+	return &memoryAlbumRepository{store: s}
+}
+
+func (s *MemoryStore) Artists() ArtistRepository {
+	return &memoryArtistRepository{store: s}
+}
+
+func (s *MemoryStore) Genres() GenreRepository {
+	return &memoryGenreRepository{store: s}
+}
+
+func (s *MemoryStore) Tags() TagRepository {
+	return &memoryTagRepository{store: s}
+}
+
+func (s *MemoryStore) Playlists() PlaylistRepository {
+	return &memoryPlaylistRepository{store: s}
+}
+
+func (s *MemoryStore) Users() UserRepository {
+	return &memoryUserRepository{store: s}
+}
+
+func (s *MemoryStore) Tokens() TokenRepository {
+	return &memoryTokenRepository{store: s}
+}
+
+func (s *MemoryStore) Audit() AuditRepository {
+	return &memoryAuditRepository{store: s}
+}
+
+func (s *MemoryStore) APIKeys() APIKeyRepository {
+	return &memoryAPIKeyRepository{store: s}
+}
+
+func (s *MemoryStore) Identities() IdentityRepository {
+	return &memoryIdentityRepository{store: s}
+}
+
+func (s *MemoryStore) LoginAttempts() LoginAttemptRepository {
+	return &memoryLoginAttemptRepository{store: s}
+}
+
+func (s *MemoryStore) Transaction(fn func(tx Store) error) error {
+	if !s.inTx {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+	}
+
+	// Nested transactions behave like savepoints
+	snapshot := (*s.data).clone()
+	if err := fn(&MemoryStore{mu: s.mu, data: s.data, inTx: true}); err != nil {
+		*s.data = snapshot
+		return err
+	}
+	return nil
+}
+
+// read runs fn with the current data, holding the lock unless a transaction already does
+func (s *MemoryStore) read(fn func(data *memoryData) error) error {
+	if !s.inTx {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+	}
+	return fn(*s.data)
+}
+
+// write is read for changes; a failing fn leaves the data as it was
+func (s *MemoryStore) write(fn func(data *memoryData) error) error {
+	return s.Transaction(func(tx Store) error {
+		return fn(*tx.(*MemoryStore).data)
+	})
+}
+
+// assignColumns copies the fields behind the named columns from src to dst, both pointers to the same struct type
+func assignColumns(dst interface{}, src interface{}, columns []string) {
+	naming := schema.NamingStrategy{}
+	target := reflect.ValueOf(dst).Elem()
+	source := reflect.ValueOf(src).Elem()
+	for i := 0; i < target.NumField(); i++ {
+		if slices.Contains(columns, naming.ColumnName("", target.Type().Field(i).Name)) {
+			target.Field(i).Set(source.Field(i))
+		}
+	}
+}
+
+// duplicateError mimics the unique constraint violations of the database
+func duplicateError(table string, column string) error {
+	return fmt.Errorf("%w: unique constraint on %s.%s", ErrDuplicate, table, column)
+}
+
+// pageBounds returns the slice bounds of a page of limit items starting at offset out of length
+func pageBounds(length int, limit int, offset int) (int, int) {
+	start := min(offset, length)
+	return start, min(start+limit, length)
+}
+
+// deletedLater orders records by when they were deleted, most recent first, then by descending ID
+func deletedLater(a gorm.DeletedAt, aId uint, b gorm.DeletedAt, bId uint) bool {
+	if !a.Time.Equal(b.Time) {
+		return a.Time.After(b.Time)
+	}
+	return aId > bId
+}
@@ -0,0 +1,246 @@
+package repositories
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tushar27x/music-lib-api/models"
+)
+
+type memoryTagRepository struct {
+	store *MemoryStore
+}
+
+func (r *memoryTagRepository) Create(tag *models.Tag) error {
+	return r.store.write(func(data *memoryData) error {
+		if tagNameTaken(data, tag.UserId, tag.Name, 0) {
+			return duplicateError("tags", "name")
+		}
+		now := time.Now()
+		tag.ID = data.nextId()
+		tag.CreatedAt, tag.UpdatedAt = now, now
+		data.tags[tag.ID] = *tag
+		return nil
+	})
+}
+
+func (r *memoryTagRepository) FindOwned(userId uint, id uint) (models.Tag, error) {
+	var tag models.Tag
+	err := r.store.read(func(data *memoryData) error {
+		found, ok := data.tags[id]
+		if !ok || found.UserId != userId {
+			return ErrNotFound
+		}
+		tag = found
+		return nil
+	})
+	return tag, err
+}
+
+func (r *memoryTagRepository) FindByName(userId uint, name string) (models.Tag, error) {
+	var tag models.Tag
+	err := r.store.read(func(data *memoryData) error {
+		for _, found := range data.tags {
+			if found.UserId == userId && strings.EqualFold(found.Name, name) {
+				tag = found
+				return nil
+			}
+		}
+		return ErrNotFound
+	})
+	return tag, err
+}
+
+func (r *memoryTagRepository) FindUsage(userId uint) ([]models.TagUsage, error) {
+	tags := []models.TagUsage{}
+	err := r.store.read(func(data *memoryData) error {
+		byTag := map[uint]*models.TagUsage{}
+		for _, tag := range data.tags {
+			if tag.UserId == userId {
+				tags = append(tags, models.TagUsage{Tag: tag})
+			}
+		}
+		for i := range tags {
+			byTag[tags[i].ID] = &tags[i]
+		}
+		for songId, tagged := range data.songTags {
+			if _, live := data.songs[songId]; !live {
+				continue
+			}
+			for _, tag := range tagged {
+				if usage, ok := byTag[tag.TagId]; ok {
+					usage.SongCount++
+				}
+			}
+		}
+		for albumId, tagged := range data.albumTags {
+			if _, live := data.albums[albumId]; !live {
+				continue
+			}
+			for _, tag := range tagged {
+				if usage, ok := byTag[tag.TagId]; ok {
+					usage.AlbumCount++
+				}
+			}
+		}
+		return nil
+	})
+	sort.Slice(tags, func(i, j int) bool {
+		a, b := strings.ToLower(tags[i].Name), strings.ToLower(tags[j].Name)
+		if a != b {
+			return a < b
+		}
+		return tags[i].ID < tags[j].ID
+	})
+	return tags, err
+}
+
+func (r *memoryTagRepository) Update(tag *models.Tag, columns ...string) error {
+	return r.store.write(func(data *memoryData) error {
+		stored, ok := data.tags[tag.ID]
+		if !ok {
+			return nil
+		}
+		assignColumns(&stored, tag, columns)
+		if tagNameTaken(data, stored.UserId, stored.Name, stored.ID) {
+			return duplicateError("tags", "name")
+		}
+		tag.UpdatedAt = time.Now()
+		stored.UpdatedAt = tag.UpdatedAt
+		data.tags[tag.ID] = stored
+		return nil
+	})
+}
+
+func (r *memoryTagRepository) Delete(tag *models.Tag) error {
+	return r.store.write(func(data *memoryData) error {
+		delete(data.tags, tag.ID)
+		for albumId, tagged := range data.albumTags {
+			data.albumTags[albumId] = slices.DeleteFunc(slices.Clone(tagged), func(stored models.TaggedAlbum) bool {
+				return stored.TagId == tag.ID
+			})
+		}
+		for songId, tagged := range data.songTags {
+			data.songTags[songId] = slices.DeleteFunc(slices.Clone(tagged), func(stored models.TaggedSong) bool {
+				return stored.TagId == tag.ID
+			})
+		}
+		return nil
+	})
+}
+
+func (r *memoryTagRepository) MoveTaggings(fromId uint, toId uint) error {
+	return r.store.write(func(data *memoryData) error {
+		for songId, tagged := range data.songTags {
+			if hasSongTag(tagged, fromId) && !hasSongTag(tagged, toId) {
+				data.songTags[songId] = append(slices.Clone(tagged), models.TaggedSong{SongId: songId, TagId: toId})
+			}
+		}
+		for albumId, tagged := range data.albumTags {
+			if hasAlbumTag(tagged, fromId) && !hasAlbumTag(tagged, toId) {
+				data.albumTags[albumId] = append(slices.Clone(tagged), models.TaggedAlbum{AlbumId: albumId, TagId: toId})
+			}
+		}
+		return nil
+	})
+}
+
+func (r *memoryTagRepository) FindSongTags(songIds []uint) ([]models.TaggedSong, error) {
+	tags := []models.TaggedSong{}
+	err := r.store.read(func(data *memoryData) error {
+		for _, songId := range sortedIds(songIds) {
+			tagged := slices.Clone(data.songTags[songId])
+			for i := range tagged {
+				tagged[i].Name = data.tags[tagged[i].TagId].Name
+			}
+			sort.SliceStable(tagged, func(i, j int) bool {
+				return strings.ToLower(tagged[i].Name) < strings.ToLower(tagged[j].Name)
+			})
+			tags = append(tags, tagged...)
+		}
+		return nil
+	})
+	return tags, err
+}
+
+func (r *memoryTagRepository) TagSongs(tagId uint, songIds []uint) error {
+	return r.store.write(func(data *memoryData) error {
+		for _, songId := range songIds {
+			if tagged := data.songTags[songId]; !hasSongTag(tagged, tagId) {
+				data.songTags[songId] = append(slices.Clone(tagged), models.TaggedSong{SongId: songId, TagId: tagId})
+			}
+		}
+		return nil
+	})
+}
+
+func (r *memoryTagRepository) UntagSongs(tagId uint, songIds []uint) error {
+	return r.store.write(func(data *memoryData) error {
+		for _, songId := range songIds {
+			data.songTags[songId] = slices.DeleteFunc(slices.Clone(data.songTags[songId]), func(stored models.TaggedSong) bool {
+				return stored.TagId == tagId
+			})
+		}
+		return nil
+	})
+}
+
+func (r *memoryTagRepository) FindAlbumTags(albumIds []uint) ([]models.TaggedAlbum, error) {
+	tags := []models.TaggedAlbum{}
+	err := r.store.read(func(data *memoryData) error {
+		for _, albumId := range sortedIds(albumIds) {
+			tagged := slices.Clone(data.albumTags[albumId])
+			for i := range tagged {
+				tagged[i].Name = data.tags[tagged[i].TagId].Name
+			}
+			sort.SliceStable(tagged, func(i, j int) bool {
+				return strings.ToLower(tagged[i].Name) < strings.ToLower(tagged[j].Name)
+			})
+			tags = append(tags, tagged...)
+		}
+		return nil
+	})
+	return tags, err
+}
+
+func (r *memoryTagRepository) TagAlbums(tagId uint, albumIds []uint) error {
+	return r.store.write(func(data *memoryData) error {
+		for _, albumId := range albumIds {
+			if tagged := data.albumTags[albumId]; !hasAlbumTag(tagged, tagId) {
+				data.albumTags[albumId] = append(slices.Clone(tagged), models.TaggedAlbum{AlbumId: albumId, TagId: tagId})
+			}
+		}
+		return nil
+	})
+}
+
+func (r *memoryTagRepository) UntagAlbums(tagId uint, albumIds []uint) error {
+	return r.store.write(func(data *memoryData) error {
+		for _, albumId := range albumIds {
+			data.albumTags[albumId] = slices.DeleteFunc(slices.Clone(data.albumTags[albumId]), func(stored models.TaggedAlbum) bool {
+				return stored.TagId == tagId
+			})
+		}
+		return nil
+	})
+}
+
+// tagNameTaken reports whether another tag of the user has the name, ignoring case like the unique index
+func tagNameTaken(data *memoryData, userId uint, name string, exceptId uint) bool {
+	for id, existing := range data.tags {
+		if id != exceptId && existing.UserId == userId && strings.EqualFold(existing.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasSongTag(tagged []models.TaggedSong, tagId uint) bool {
+	return slices.ContainsFunc(tagged, func(stored models.TaggedSong) bool { return stored.TagId == tagId })
+}
+
+func hasAlbumTag(tagged []models.TaggedAlbum, tagId uint) bool {
+	return slices.ContainsFunc(tagged, func(stored models.TaggedAlbum) bool { return stored.TagId == tagId })
+}
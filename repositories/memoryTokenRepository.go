@@ -0,0 +1,205 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/tushar27x/music-lib-api/models"
+)
+
+type memoryTokenRepository struct {
+	store *MemoryStore
+}
+
+func (r *memoryTokenRepository) CreateRefreshToken(token *models.RefreshToken) error {
+	return r.store.write(func(data *memoryData) error {
+		for _, existing := range data.refreshTokens {
+			if existing.TokenHash == token.TokenHash {
+				return duplicateError("refresh_tokens", "token_hash")
+			}
+		}
+		now := time.Now()
+		token.ID = data.nextId()
+		token.CreatedAt, token.UpdatedAt = now, now
+		data.refreshTokens[token.ID] = *token
+		return nil
+	})
+}
+
+func (r *memoryTokenRepository) FindRefreshToken(tokenHash string) (models.RefreshToken, error) {
+	var token models.RefreshToken
+	err := r.store.read(func(data *memoryData) error {
+		for _, found := range data.refreshTokens {
+			if found.TokenHash == tokenHash {
+				token = found
+				return nil
+			}
+		}
+		return ErrNotFound
+	})
+	return token, err
+}
+
+func (r *memoryTokenRepository) ConsumeRefreshToken(id uint, at time.Time) (bool, error) {
+	consumed := false
+	err := r.store.write(func(data *memoryData) error {
+		token, ok := data.refreshTokens[id]
+		if !ok || token.RevokedAt != nil {
+			return nil
+		}
+		token.RevokedAt = &at
+		data.refreshTokens[id] = token
+		consumed = true
+		return nil
+	})
+	return consumed, err
+}
+
+func (r *memoryTokenRepository) RevokeFamily(familyId string, at time.Time) error {
+	return r.revokeWhere(at, func(token models.RefreshToken) bool {
+		return token.FamilyId == familyId
+	})
+}
+
+func (r *memoryTokenRepository) RevokeUserRefreshTokens(userId uint, at time.Time) error {
+	return r.revokeWhere(at, func(token models.RefreshToken) bool {
+		return token.UserId == userId
+	})
+}
+
+func (r *memoryTokenRepository) IsAccessTokenRevoked(jti string) (bool, error) {
+	revoked := false
+	err := r.store.read(func(data *memoryData) error {
+		_, revoked = data.revokedTokens[jti]
+		return nil
+	})
+	return revoked, err
+}
+
+func (r *memoryTokenRepository) RevokeAccessToken(jti string, expiresAt time.Time, now time.Time) error {
+	return r.store.write(func(data *memoryData) error {
+		for key, entry := range data.revokedTokens {
+			if entry.ExpiresAt.Before(now) {
+				delete(data.revokedTokens, key)
+			}
+		}
+		if _, ok := data.revokedTokens[jti]; !ok {
+			data.revokedTokens[jti] = models.RevokedToken{ID: data.nextId(), CreatedAt: now, Jti: jti, ExpiresAt: expiresAt}
+		}
+		return nil
+	})
+}
+
+func (r *memoryTokenRepository) CreateAccountToken(token *models.AccountToken) error {
+	return r.store.write(func(data *memoryData) error {
+		for _, existing := range data.accountTokens {
+			if existing.TokenHash == token.TokenHash {
+				return duplicateError("account_tokens", "token_hash")
+			}
+		}
+		token.ID = data.nextId()
+		token.CreatedAt = time.Now()
+		data.accountTokens[token.ID] = *token
+		return nil
+	})
+}
+
+func (r *memoryTokenRepository) FindAccountToken(purpose string, tokenHash string) (models.AccountToken, error) {
+	var token models.AccountToken
+	err := r.store.read(func(data *memoryData) error {
+		for _, found := range data.accountTokens {
+			if found.Purpose == purpose && found.TokenHash == tokenHash {
+				token = found
+				return nil
+			}
+		}
+		return ErrNotFound
+	})
+	return token, err
+}
+
+func (r *memoryTokenRepository) UseAccountToken(id uint, at time.Time) (bool, error) {
+	used := false
+	err := r.store.write(func(data *memoryData) error {
+		token, ok := data.accountTokens[id]
+		if !ok || token.UsedAt != nil {
+			return nil
+		}
+		token.UsedAt = &at
+		data.accountTokens[id] = token
+		used = true
+		return nil
+	})
+	return used, err
+}
+
+func (r *memoryTokenRepository) ExpireAccountTokens(userId uint, purpose string, at time.Time) error {
+	return r.store.write(func(data *memoryData) error {
+		for id, token := range data.accountTokens {
+			if token.UsedAt == nil && token.UserId == userId && token.Purpose == purpose {
+				token.UsedAt = &at
+				data.accountTokens[id] = token
+			}
+		}
+		return nil
+	})
+}
+
+func (r *memoryTokenRepository) ReplaceRecoveryCodes(userId uint, codes []models.RecoveryCode) error {
+	return r.store.write(func(data *memoryData) error {
+		for id, code := range data.recoveryCodes {
+			if code.UserId == userId {
+				delete(data.recoveryCodes, id)
+			}
+		}
+		now := time.Now()
+		for i := range codes {
+			codes[i].ID = data.nextId()
+			codes[i].CreatedAt = now
+			codes[i].UserId = userId
+			data.recoveryCodes[codes[i].ID] = codes[i]
+		}
+		return nil
+	})
+}
+
+func (r *memoryTokenRepository) UseRecoveryCode(userId uint, codeHash string, at time.Time) (bool, error) {
+	used := false
+	err := r.store.write(func(data *memoryData) error {
+		for id, code := range data.recoveryCodes {
+			if code.UserId == userId && code.CodeHash == codeHash && code.UsedAt == nil {
+				code.UsedAt = &at
+				data.recoveryCodes[id] = code
+				used = true
+				return nil
+			}
+		}
+		return nil
+	})
+	return used, err
+}
+
+func (r *memoryTokenRepository) CountRecoveryCodes(userId uint) (int64, error) {
+	var count int64
+	err := r.store.read(func(data *memoryData) error {
+		for _, code := range data.recoveryCodes {
+			if code.UserId == userId && code.UsedAt == nil {
+				count++
+			}
+		}
+		return nil
+	})
+	return count, err
+}
+
+// revokeWhere revokes the active refresh tokens selected by match
+func (r *memoryTokenRepository) revokeWhere(at time.Time, match func(token models.RefreshToken) bool) error {
+	return r.store.write(func(data *memoryData) error {
+		for id, token := range data.refreshTokens {
+			if token.RevokedAt == nil && match(token) {
+				token.RevokedAt = &at
+				data.refreshTokens[id] = token
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,115 @@
+package repositories
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tushar27x/music-lib-api/models"
+)
+
+type memoryUserRepository struct {
+	store *MemoryStore
+}
+
+func (r *memoryUserRepository) Create(user *models.User) error {
+	return r.store.write(func(data *memoryData) error {
+		for _, existing := range data.users {
+			if existing.Email == user.Email {
+				return duplicateError("users", "email")
+			}
+		}
+		now := time.Now()
+		user.ID = data.nextId()
+		user.CreatedAt, user.UpdatedAt = now, now
+		stored := *user
+		stored.Albums, stored.Songs = nil, nil
+		data.users[user.ID] = stored
+		return nil
+	})
+}
+
+func (r *memoryUserRepository) FindByID(id uint) (models.User, error) {
+	var user models.User
+	err := r.store.read(func(data *memoryData) error {
+		found, ok := data.users[id]
+		if !ok {
+			return ErrNotFound
+		}
+		user = found
+		return nil
+	})
+	return user, err
+}
+
+func (r *memoryUserRepository) FindByEmail(email string) (models.User, error) {
+	var user models.User
+	err := r.store.read(func(data *memoryData) error {
+		for _, found := range data.users {
+			if found.Email == email {
+				user = found
+				return nil
+			}
+		}
+		return ErrNotFound
+	})
+	return user, err
+}
+
+func (r *memoryUserRepository) UseTOTPStep(id uint, step int64) (bool, error) {
+	used := false
+	err := r.store.write(func(data *memoryData) error {
+		user, ok := data.users[id]
+		if !ok || user.TOTPLastStep >= step {
+			return nil
+		}
+		user.TOTPLastStep = step
+		data.users[id] = user
+		used = true
+		return nil
+	})
+	return used, err
+}
+
+func (r *memoryUserRepository) Update(user *models.User, columns ...string) error {
+	return r.store.write(func(data *memoryData) error {
+		stored, ok := data.users[user.ID]
+		if !ok {
+			return nil
+		}
+		assignColumns(&stored, user, columns)
+		for id, existing := range data.users {
+			if id != user.ID && existing.Email == stored.Email {
+				return duplicateError("users", "email")
+			}
+		}
+		user.UpdatedAt = time.Now()
+		stored.UpdatedAt = user.UpdatedAt
+		stored.Albums, stored.Songs = nil, nil
+		data.users[user.ID] = stored
+		return nil
+	})
+}
+
+func (r *memoryUserRepository) List(filter UserFilter, limit int, offset int) ([]models.User, int64, error) {
+	users := []models.User{}
+	query := strings.ToLower(filter.Query)
+	err := r.store.read(func(data *memoryData) error {
+		for _, user := range data.users {
+			if query != "" && !strings.Contains(strings.ToLower(user.Name), query) && !strings.Contains(strings.ToLower(user.Email), query) {
+				continue
+			}
+			if filter.Role != "" && user.Role != filter.Role {
+				continue
+			}
+			if filter.Suspended != nil && *filter.Suspended != (user.SuspendedAt != nil) {
+				continue
+			}
+			users = append(users, user)
+		}
+		return nil
+	})
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	start, end := pageBounds(len(users), limit, offset)
+	return users[start:end], int64(len(users)), err
+}
@@ -0,0 +1,313 @@
+// Package repositories hides how songs, albums, artists, genres, tags, playlists, users, tokens, API keys, external identities, failed logins and the audit trail are stored.
+//
+// Services only talk to the interfaces in this file. NewGormStore backs them with the
+// database, Postgres or SQLite, and NewMemoryStore with plain maps, so the rules built on
+// top of them can be exercised without a database at all.
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	"github.com/tushar27x/music-lib-api/models"
+)
+
+var (
+	// ErrNotFound is returned when a record does not exist or belongs to someone else
+	ErrNotFound = errors.New("record not found")
+	// ErrDuplicate is returned when a write would break a unique constraint, such as two users with one email
+	ErrDuplicate = errors.New("record already exists")
+)
+
+// Store gives access to every repository and runs work atomically
+type Store interface {
+	Songs() SongRepository
+	Albums() AlbumRepository
+	Artists() ArtistRepository
+	Genres() GenreRepository
+	Tags() TagRepository
+	Playlists() PlaylistRepository
+	Users() UserRepository
+	Tokens() TokenRepository
+	Audit() AuditRepository
+	APIKeys() APIKeyRepository
+	Identities() IdentityRepository
+	LoginAttempts() LoginAttemptRepository
+	// Transaction runs fn with repositories that share one transaction; returning an error rolls everything back
+	Transaction(fn func(tx Store) error) error
+}
+
+// SongRepository stores songs
+type SongRepository interface {
+	Create(song *models.Song) error
+	// FindByID returns a song whoever owns it
+	FindByID(id uint) (models.Song, error)
+	// FindOwned returns a song of the user
+	FindOwned(userId uint, id uint) (models.Song, error)
+	FindByUser(userId uint) ([]models.Song, error)
+	// CountOwned counts how many of the given distinct song IDs belong to the user
+	CountOwned(userId uint, ids []uint) (int, error)
+	// FindMatching returns the user's songs matching validated smart playlist rules, sorted and limited by them
+	FindMatching(userId uint, rules models.SmartPlaylistRules, now time.Time) ([]models.Song, error)
+	// Update writes the given columns of the song
+	Update(song *models.Song, columns ...string) error
+	// RecordPlay increments the play count of a song and stamps when it was last played
+	RecordPlay(id uint, at time.Time) error
+	Delete(song *models.Song) error
+	// FindByArtist returns the user's songs crediting the artist, in any role or in the given one
+	FindByArtist(userId uint, artistId uint, role string) ([]models.Song, error)
+	// DeleteByAlbum deletes the user's songs on an album
+	DeleteByAlbum(userId uint, albumId uint) error
+	// MoveToAlbum moves the user's songs on an album to another album
+	MoveToAlbum(userId uint, albumId uint, targetId uint) error
+	// FindDeleted returns deleted songs, most recently deleted first, of one user or of everyone when userId is 0
+	FindDeleted(userId uint, limit int, offset int) ([]models.Song, int64, error)
+	// Restore undeletes a song; it returns ErrNotFound unless the song exists and is deleted
+	Restore(id uint) (models.Song, error)
+}
+
+// AlbumRepository stores albums
+type AlbumRepository interface {
+	Create(album *models.Album) error
+	// FindByID returns an album whoever owns it, without its songs
+	FindByID(id uint) (models.Album, error)
+	// FindOwned returns an album of the user without its songs
+	FindOwned(userId uint, id uint) (models.Album, error)
+	// FindOwnedWithSongs returns an album of the user together with the user's songs on it
+	FindOwnedWithSongs(userId uint, id uint) (models.Album, error)
+	// FindByUser returns the user's albums together with the user's songs on them
+	FindByUser(userId uint) ([]models.Album, error)
+	// FindByTitle returns the user's albums with the given title, ignoring case
+	FindByTitle(userId uint, title string) ([]models.Album, error)
+	// FindByArtist returns the user's albums crediting the artist, in any role or in the given one,
+	// by year, together with the user's songs on them
+	FindByArtist(userId uint, artistId uint, role string) ([]models.Album, error)
+	// Update writes the given columns of the album
+	Update(album *models.Album, columns ...string) error
+	Delete(album *models.Album) error
+	// FindDeleted returns deleted albums, most recently deleted first, of one user or of everyone when userId is 0
+	FindDeleted(userId uint, limit int, offset int) ([]models.Album, int64, error)
+	// Restore undeletes an album without its songs; it returns ErrNotFound unless the album exists and is deleted
+	Restore(id uint) (models.Album, error)
+}
+
+// ArtistRepository stores artists and their credits on albums and songs
+type ArtistRepository interface {
+	Create(artist *models.Artist) error
+	// FindOwned returns an artist of the user
+	FindOwned(userId uint, id uint) (models.Artist, error)
+	// FindByName returns the user's artist with the name, ignoring case
+	FindByName(userId uint, name string) (models.Artist, error)
+	// FindByUser returns the user's artists in name order
+	FindByUser(userId uint) ([]models.Artist, error)
+	// Update writes the given columns of the artist
+	Update(artist *models.Artist, columns ...string) error
+	// Delete removes the artist together with all its credits
+	Delete(artist *models.Artist) error
+	// CountCredits counts the albums and songs, not counting deleted ones, that credit the artist
+	CountCredits(artistId uint) (int64, error)
+	// FindAlbumCredits returns the credits of the albums with their artist names, in position order
+	FindAlbumCredits(albumIds []uint) ([]models.AlbumCredit, error)
+	// SetAlbumCredits replaces the credits of an album
+	SetAlbumCredits(albumId uint, credits []models.AlbumCredit) error
+	// FindSongCredits returns the credits of the songs with their artist names, in position order
+	FindSongCredits(songIds []uint) ([]models.SongCredit, error)
+	// SetSongCredits replaces the credits of a song
+	SetSongCredits(songId uint, credits []models.SongCredit) error
+}
+
+// GenreRepository stores the genre taxonomies of users
+type GenreRepository interface {
+	Create(genre *models.Genre) error
+	// FindOwned returns a genre of the user
+	FindOwned(userId uint, id uint) (models.Genre, error)
+	// FindByName returns the user's genre with the name, ignoring case
+	FindByName(userId uint, name string) (models.Genre, error)
+	// FindByUser returns the user's genres in name order
+	FindByUser(userId uint) ([]models.Genre, error)
+	// Update writes the given columns of the genre
+	Update(genre *models.Genre, columns ...string) error
+	// Delete removes the genre, taking it off deleted albums and songs
+	Delete(genre *models.Genre) error
+	// CountUses counts the subgenres of the genre and the albums and songs in it, not counting deleted ones
+	CountUses(genreId uint) (int64, error)
+	// RenameUses sets the genre text of every album and song in the genre, deleted ones included
+	RenameUses(genreId uint, name string) error
+}
+
+// TagRepository stores the tags of users and which songs and albums carry them
+type TagRepository interface {
+	Create(tag *models.Tag) error
+	// FindOwned returns a tag of the user
+	FindOwned(userId uint, id uint) (models.Tag, error)
+	// FindByName returns the user's tag with the name, ignoring case
+	FindByName(userId uint, name string) (models.Tag, error)
+	// FindUsage returns the user's tags in name order with how many songs and albums, not counting
+	// deleted ones, carry each
+	FindUsage(userId uint) ([]models.TagUsage, error)
+	// Update writes the given columns of the tag
+	Update(tag *models.Tag, columns ...string) error
+	// Delete removes the tag from every song and album and then the tag itself
+	Delete(tag *models.Tag) error
+	// MoveTaggings puts the tag with toId on every song and album carrying the tag with fromId
+	MoveTaggings(fromId uint, toId uint) error
+	// FindSongTags returns the tags of the songs with their names, in name order
+	FindSongTags(songIds []uint) ([]models.TaggedSong, error)
+	// TagSongs puts the tag on the songs that don't carry it yet
+	TagSongs(tagId uint, songIds []uint) error
+	// UntagSongs takes the tag off the songs
+	UntagSongs(tagId uint, songIds []uint) error
+	// FindAlbumTags returns the tags of the albums with their names, in name order
+	FindAlbumTags(albumIds []uint) ([]models.TaggedAlbum, error)
+	// TagAlbums puts the tag on the albums that don't carry it yet
+	TagAlbums(tagId uint, albumIds []uint) error
+	// UntagAlbums takes the tag off the albums
+	UntagAlbums(tagId uint, albumIds []uint) error
+}
+
+// PlaylistRepository stores playlists and their ordered entries
+type PlaylistRepository interface {
+	Create(playlist *models.Playlist) error
+	// FindByID returns a playlist whoever owns it, without its entries
+	FindByID(id uint) (models.Playlist, error)
+	// FindOwned returns a playlist of the user without its entries
+	FindOwned(userId uint, id uint) (models.Playlist, error)
+	// FindOwnedWithTracks returns a playlist of the user with its entries and songs in playlist order
+	FindOwnedWithTracks(userId uint, id uint) (models.Playlist, error)
+	// FindByUser returns the user's playlists with their entries and songs in playlist order
+	FindByUser(userId uint) ([]models.Playlist, error)
+	// LockOwned returns a playlist of the user and locks it until the transaction ends so edits are serialised
+	LockOwned(userId uint, id uint) (models.Playlist, error)
+	// Update writes the given columns of the playlist
+	Update(playlist *models.Playlist, columns ...string) error
+	Delete(playlist *models.Playlist) error
+	// FindDeleted returns deleted playlists, most recently deleted first, of one user or of everyone when userId is 0
+	FindDeleted(userId uint, limit int, offset int) ([]models.Playlist, int64, error)
+	// Restore undeletes a playlist; its entries were removed on delete and stay gone.
+	// It returns ErrNotFound unless the playlist exists and is deleted.
+	Restore(id uint) (models.Playlist, error)
+
+	FindEntry(playlistId uint, entryId uint) (models.PlaylistEntry, error)
+	// FindEntries returns the entries of a playlist in position order
+	FindEntries(playlistId uint) ([]models.PlaylistEntry, error)
+	CountEntries(playlistId uint) (int, error)
+	CreateEntries(entries []models.PlaylistEntry) error
+	// ShiftEntries adds delta to the position of the entries at positions from up to, but not including, to;
+	// a negative to shifts every entry from the start position on
+	ShiftEntries(playlistId uint, from int, to int, delta int) error
+	SetEntryPosition(entry *models.PlaylistEntry, position int) error
+	DeleteEntry(entry models.PlaylistEntry) error
+	// DeleteEntries removes every entry of a playlist
+	DeleteEntries(playlistId uint) error
+	// FindPlaylistIdsWithSong returns the playlists that contain the song
+	FindPlaylistIdsWithSong(songId uint) ([]uint, error)
+	// DeleteSongEntries removes the song from every playlist without renumbering
+	DeleteSongEntries(songId uint) error
+}
+
+// UserRepository stores user accounts
+type UserRepository interface {
+	Create(user *models.User) error
+	FindByID(id uint) (models.User, error)
+	FindByEmail(email string) (models.User, error)
+	// Update writes the given columns of the user
+	Update(user *models.User, columns ...string) error
+	// UseTOTPStep records step as the user's last used TOTP time step; it reports false when the
+	// last used step is already at or past it
+	UseTOTPStep(id uint, step int64) (bool, error)
+	// List returns the users matching filter in ID order and how many match in total
+	List(filter UserFilter, limit int, offset int) ([]models.User, int64, error)
+}
+
+// UserFilter narrows a user listing; zero values match everyone
+type UserFilter struct {
+	// Query matches part of the name or email, ignoring case
+	Query     string
+	Role      string
+	Suspended *bool
+}
+
+// APIKeyRepository stores personal API keys
+type APIKeyRepository interface {
+	Create(key *models.APIKey) error
+	FindByHash(keyHash string) (models.APIKey, error)
+	// FindOwned returns an API key of the user
+	FindOwned(userId uint, id uint) (models.APIKey, error)
+	// FindByUser returns the user's API keys, newest first
+	FindByUser(userId uint) ([]models.APIKey, error)
+	// Update writes the given columns of the API key
+	Update(key *models.APIKey, columns ...string) error
+}
+
+// IdentityRepository stores the links between users and external identity providers and the
+// logins at those providers that are in progress
+type IdentityRepository interface {
+	Create(identity *models.UserIdentity) error
+	// FindBySubject returns the identity with the subject at the provider
+	FindBySubject(provider string, subject string) (models.UserIdentity, error)
+	// FindByUser returns the user's identities in the order they were linked
+	FindByUser(userId uint) ([]models.UserIdentity, error)
+	CreateLogin(login *models.OIDCLogin) error
+	FindLogin(stateHash string) (models.OIDCLogin, error)
+	// UseLogin marks an unused login as used; it reports false when it was already used
+	UseLogin(id uint, at time.Time) (bool, error)
+}
+
+// LoginAttemptRepository stores the failed login counters of accounts and IP addresses
+type LoginAttemptRepository interface {
+	// Find returns the counter of the email address or IP address
+	Find(kind string, target string) (models.LoginAttempt, error)
+	// Lock returns the counter of the email address or IP address and locks it until the
+	// transaction ends so concurrent failures are counted one after another
+	Lock(kind string, target string) (models.LoginAttempt, error)
+	FindByID(id uint) (models.LoginAttempt, error)
+	// Save creates the counter or writes all its columns
+	Save(attempt *models.LoginAttempt) error
+	Delete(id uint) error
+	// ListLocked returns the counters locked at now, of one kind or of both when kind is empty,
+	// the longest lockouts first, and how many there are
+	ListLocked(kind string, now time.Time, limit int, offset int) ([]models.LoginAttempt, int64, error)
+}
+
+// TokenRepository stores refresh tokens, the access token denylist, the single-use account tokens sent by email
+// and two-factor recovery codes
+type TokenRepository interface {
+	CreateRefreshToken(token *models.RefreshToken) error
+	FindRefreshToken(tokenHash string) (models.RefreshToken, error)
+	// ConsumeRefreshToken revokes an active refresh token; it reports false when the token was already revoked
+	ConsumeRefreshToken(id uint, at time.Time) (bool, error)
+	// RevokeFamily revokes every active refresh token of a token family
+	RevokeFamily(familyId string, at time.Time) error
+	// RevokeUserRefreshTokens revokes every active refresh token of a user
+	RevokeUserRefreshTokens(userId uint, at time.Time) error
+	IsAccessTokenRevoked(jti string) (bool, error)
+	// RevokeAccessToken adds an access token to the denylist once, dropping entries for tokens that expired before now
+	RevokeAccessToken(jti string, expiresAt time.Time, now time.Time) error
+	CreateAccountToken(token *models.AccountToken) error
+	FindAccountToken(purpose string, tokenHash string) (models.AccountToken, error)
+	// UseAccountToken marks an unused account token as used; it reports false when it was already used
+	UseAccountToken(id uint, at time.Time) (bool, error)
+	// ExpireAccountTokens marks every unused account token of a user with the purpose as used
+	ExpireAccountTokens(userId uint, purpose string, at time.Time) error
+	// ReplaceRecoveryCodes deletes the recovery codes of a user and stores codes instead
+	ReplaceRecoveryCodes(userId uint, codes []models.RecoveryCode) error
+	// UseRecoveryCode marks an unused recovery code of the user as used; it reports false when there is none
+	UseRecoveryCode(userId uint, codeHash string, at time.Time) (bool, error)
+	// CountRecoveryCodes counts the unused recovery codes of a user
+	CountRecoveryCodes(userId uint) (int64, error)
+}
+
+// AuditRepository stores the audit trail of administrative actions
+type AuditRepository interface {
+	Create(entry *models.AuditLog) error
+	// List returns the entries matching filter, newest first, and how many match in total
+	List(filter AuditFilter, limit int, offset int) ([]models.AuditLog, int64, error)
+}
+
+// AuditFilter narrows the audit trail; zero values match every entry
+type AuditFilter struct {
+	ActorId    *uint
+	Action     string
+	TargetType string
+	TargetId   *uint
+}
@@ -0,0 +1,342 @@
+package repositories
+
+import (
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tushar27x/music-lib-api/models"
+	"gorm.io/gorm"
+)
+
+// Rules reaching a repository have been validated by the service layer, so unknown
+// fields and operators never match anything rather than being reported.
+
+// smartColumns maps rule fields to columns; only these columns ever reach SQL
+var smartColumns = map[string]string{
+	"title":      "songs.title",
+	"artist":     "songs.artist",
+	"genre":      "songs.genre",
+	"album":      "albums.title",
+	"duration":   "songs.duration",
+	"year":       "songs.year",
+	"album_year": "albums.year",
+	"play_count": "songs.play_count",
+	"added":      "songs.created_at",
+}
+
+var smartSortColumns = map[string]string{
+	"title":      "songs.title",
+	"artist":     "songs.artist",
+	"duration":   "songs.duration",
+	"year":       "songs.year",
+	"album_year": "albums.year",
+	"added":      "songs.created_at",
+	"play_count": "songs.play_count",
+}
+
+// smartGroupSQL turns a rule group into a parenthesised SQL condition
+func smartGroupSQL(group models.SmartRuleGroup, now time.Time) (string, []interface{}) {
+	var parts []string
+	var args []interface{}
+
+	for _, rule := range group.Rules {
+		condition, ruleArgs := smartRuleSQL(rule, now)
+		parts = append(parts, condition)
+		args = append(args, ruleArgs...)
+	}
+	for _, child := range group.Groups {
+		condition, childArgs := smartGroupSQL(child, now)
+		if condition == "" {
+			continue
+		}
+		parts = append(parts, condition)
+		args = append(args, childArgs...)
+	}
+
+	if len(parts) == 0 {
+		return "", nil
+	}
+	joiner := " AND "
+	if group.Match == "any" {
+		joiner = " OR "
+	}
+	return "(" + strings.Join(parts, joiner) + ")", args
+}
+
+func smartRuleSQL(rule models.SmartRule, now time.Time) (string, []interface{}) {
+	column, ok := smartColumns[rule.Field]
+	if !ok {
+		return "1 = 0", nil
+	}
+
+	switch rule.Operator {
+	case "contains":
+		return "LOWER(" + column + ") LIKE LOWER(?) ESCAPE '\\'", []interface{}{"%" + escapeLike(ruleText(rule)) + "%"}
+	case "not_contains":
+		return "(" + column + " IS NULL OR LOWER(" + column + ") NOT LIKE LOWER(?) ESCAPE '\\')", []interface{}{"%" + escapeLike(ruleText(rule)) + "%"}
+	case "starts_with":
+		return "LOWER(" + column + ") LIKE LOWER(?) ESCAPE '\\'", []interface{}{escapeLike(ruleText(rule)) + "%"}
+	case "ends_with":
+		return "LOWER(" + column + ") LIKE LOWER(?) ESCAPE '\\'", []interface{}{"%" + escapeLike(ruleText(rule))}
+	case "equals":
+		if text, ok := rule.Value.(string); ok {
+			return "LOWER(" + column + ") = LOWER(?)", []interface{}{text}
+		}
+		return column + " = ?", []interface{}{rule.Value}
+	case "not_equals":
+		if text, ok := rule.Value.(string); ok {
+			return "(" + column + " IS NULL OR LOWER(" + column + ") <> LOWER(?))", []interface{}{text}
+		}
+		return "(" + column + " IS NULL OR " + column + " <> ?)", []interface{}{rule.Value}
+	case "gt":
+		return column + " > ?", []interface{}{rule.Value}
+	case "gte":
+		return column + " >= ?", []interface{}{rule.Value}
+	case "lt":
+		return column + " < ?", []interface{}{rule.Value}
+	case "lte":
+		return column + " <= ?", []interface{}{rule.Value}
+	case "between":
+		if bounds, ok := ruleBounds(rule); ok {
+			return column + " BETWEEN ? AND ?", []interface{}{bounds[0], bounds[1]}
+		}
+	case "in_last_days":
+		return column + " >= ?", []interface{}{now.Add(-daysDuration(rule.Value))}
+	case "not_in_last_days":
+		return column + " < ?", []interface{}{now.Add(-daysDuration(rule.Value))}
+	}
+
+	// Match nothing rather than everything
+	return "1 = 0", nil
+}
+
+func applySmartSort(query *gorm.DB, sort string) *gorm.DB {
+	if sort == "random" {
+		return query.Order("RANDOM()")
+	}
+
+	direction := "ASC"
+	if strings.HasPrefix(sort, "-") {
+		direction = "DESC"
+		sort = sort[1:]
+	}
+	column, ok := smartSortColumns[sort]
+	if !ok {
+		return query.Order("songs.id ASC")
+	}
+	// NULLs sort as the largest values, as they do in Postgres; SQLite takes them for the smallest
+	return query.Order(column + " IS NULL " + direction).Order(column + " " + direction).Order("songs.id ASC")
+}
+
+// smartValue is what a rule compares against for one song; albums are nil for songs without one
+func smartValue(field string, song models.Song, album *models.Album) (interface{}, bool) {
+	switch field {
+	case "title":
+		return song.Title, true
+	case "artist":
+		return song.Artist, true
+	case "genre":
+		return song.Genre, true
+	case "duration":
+		return float64(song.Duration), true
+	case "year":
+		return float64(song.Year), true
+	case "play_count":
+		return float64(song.PlayCount), true
+	case "added":
+		return song.CreatedAt, true
+	case "album":
+		if album == nil {
+			return nil, false
+		}
+		return album.Title, true
+	case "album_year":
+		if album == nil {
+			return nil, false
+		}
+		return float64(album.Year), true
+	}
+	return nil, false
+}
+
+// matchSmartGroup evaluates a rule group in memory with the same semantics as smartGroupSQL
+func matchSmartGroup(group models.SmartRuleGroup, song models.Song, album *models.Album, now time.Time) bool {
+	if len(group.Rules) == 0 && len(group.Groups) == 0 {
+		return true
+	}
+
+	matchAny := group.Match == "any"
+	for _, rule := range group.Rules {
+		if matchSmartRule(rule, song, album, now) == matchAny {
+			return matchAny
+		}
+	}
+	for _, child := range group.Groups {
+		if len(child.Rules) == 0 && len(child.Groups) == 0 {
+			continue
+		}
+		if matchSmartGroup(child, song, album, now) == matchAny {
+			return matchAny
+		}
+	}
+	return !matchAny
+}
+
+// matchSmartRule mirrors smartRuleSQL; like SQL, a missing value only satisfies the negative operators
+func matchSmartRule(rule models.SmartRule, song models.Song, album *models.Album, now time.Time) bool {
+	value, present := smartValue(rule.Field, song, album)
+
+	switch rule.Operator {
+	case "not_contains", "not_equals":
+		if !present {
+			return true
+		}
+	}
+	if !present {
+		return false
+	}
+
+	switch current := value.(type) {
+	case string:
+		text := strings.ToLower(current)
+		target := strings.ToLower(ruleText(rule))
+		switch rule.Operator {
+		case "contains":
+			return strings.Contains(text, target)
+		case "not_contains":
+			return !strings.Contains(text, target)
+		case "starts_with":
+			return strings.HasPrefix(text, target)
+		case "ends_with":
+			return strings.HasSuffix(text, target)
+		case "equals":
+			return text == target
+		case "not_equals":
+			return text != target
+		}
+	case float64:
+		if rule.Operator == "between" {
+			bounds, ok := ruleBounds(rule)
+			return ok && current >= bounds[0] && current <= bounds[1]
+		}
+		target, ok := rule.Value.(float64)
+		if !ok {
+			return false
+		}
+		switch rule.Operator {
+		case "equals":
+			return current == target
+		case "not_equals":
+			return current != target
+		case "gt":
+			return current > target
+		case "gte":
+			return current >= target
+		case "lt":
+			return current < target
+		case "lte":
+			return current <= target
+		}
+	case time.Time:
+		since := now.Add(-daysDuration(rule.Value))
+		switch rule.Operator {
+		case "in_last_days":
+			return !current.Before(since)
+		case "not_in_last_days":
+			return current.Before(since)
+		}
+	}
+	return false
+}
+
+// sortSmartSongs orders songs in memory like applySmartSort
+func sortSmartSongs(songs []models.Song, albums map[uint]models.Album, order string) {
+	if order == "random" {
+		rand.Shuffle(len(songs), func(i, j int) { songs[i], songs[j] = songs[j], songs[i] })
+		return
+	}
+
+	descending := strings.HasPrefix(order, "-")
+	field := strings.TrimPrefix(order, "-")
+	if _, ok := smartSortColumns[field]; !ok {
+		sort.SliceStable(songs, func(i, j int) bool { return songs[i].ID < songs[j].ID })
+		return
+	}
+
+	value := func(song models.Song) interface{} {
+		var album *models.Album
+		if song.AlbumId != nil {
+			if found, ok := albums[*song.AlbumId]; ok {
+				album = &found
+			}
+		}
+		v, _ := smartValue(field, song, album)
+		return v
+	}
+	sort.SliceStable(songs, func(i, j int) bool {
+		cmp := compareSmartValues(value(songs[i]), value(songs[j]))
+		if cmp == 0 {
+			return songs[i].ID < songs[j].ID
+		}
+		if descending {
+			return cmp > 0
+		}
+		return cmp < 0
+	})
+}
+
+// compareSmartValues compares two field values; missing values compare greater, as NULLs do in Postgres
+func compareSmartValues(a interface{}, b interface{}) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	}
+
+	switch av := a.(type) {
+	case string:
+		return strings.Compare(av, b.(string))
+	case float64:
+		bv := b.(float64)
+		switch {
+		case av < bv:
+			return -1
+		case av > bv:
+			return 1
+		}
+	case time.Time:
+		return av.Compare(b.(time.Time))
+	}
+	return 0
+}
+
+func ruleText(rule models.SmartRule) string {
+	text, _ := rule.Value.(string)
+	return text
+}
+
+func ruleBounds(rule models.SmartRule) ([2]float64, bool) {
+	values, ok := rule.Value.([]interface{})
+	if !ok || len(values) != 2 {
+		return [2]float64{}, false
+	}
+	min, minOk := values[0].(float64)
+	max, maxOk := values[1].(float64)
+	return [2]float64{min, max}, minOk && maxOk
+}
+
+func daysDuration(value interface{}) time.Duration {
+	days, _ := value.(float64)
+	return time.Duration(days * float64(24*time.Hour))
+}
+
+// escapeLike escapes the LIKE wildcards in user input
+func escapeLike(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return replacer.Replace(value)
+}
@@ -0,0 +1,146 @@
+package repositories_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tushar27x/music-lib-api/config"
+	"github.com/tushar27x/music-lib-api/migrations"
+	"github.com/tushar27x/music-lib-api/models"
+	"github.com/tushar27x/music-lib-api/repositories"
+)
+
+// stores returns an empty in-memory store and a store on a fully migrated in-memory SQLite
+// database, so the same test can check that both behave alike
+func stores(t *testing.T) map[string]repositories.Store {
+	t.Helper()
+	db, err := config.OpenDB("sqlite::memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrator, err := migrations.New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(0); err != nil {
+		t.Fatal(err)
+	}
+	return map[string]repositories.Store{
+		"memory": repositories.NewMemoryStore(),
+		"sqlite": repositories.NewGormStore(db),
+	}
+}
+
+// createUser adds a user to the store and returns its ID
+func createUser(t *testing.T, store repositories.Store, email string) uint {
+	t.Helper()
+	user := models.User{Name: "Ada", Email: email, Role: "artist"}
+	if err := store.Users().Create(&user); err != nil {
+		t.Fatal(err)
+	}
+	return user.ID
+}
+
+func TestFindMatching(t *testing.T) {
+	rule := func(field string, operator string, value interface{}) models.SmartRule {
+		return models.SmartRule{Field: field, Operator: operator, Value: value}
+	}
+	tests := []struct {
+		name   string
+		rules  models.SmartPlaylistRules
+		titles []string
+	}{
+		{
+			name:   "contains ignores case",
+			rules:  models.SmartPlaylistRules{SmartRuleGroup: models.SmartRuleGroup{Match: "all", Rules: []models.SmartRule{rule("title", "contains", "LOVE")}}},
+			titles: []string{"Love of My Life", "Crazy Little Thing Called Love", "100% Pure Love"},
+		},
+		{
+			name:   "contains escapes wildcards",
+			rules:  models.SmartPlaylistRules{SmartRuleGroup: models.SmartRuleGroup{Match: "all", Rules: []models.SmartRule{rule("title", "contains", "100%")}}},
+			titles: []string{"100% Pure Love"},
+		},
+		{
+			name: "album fields",
+			rules: models.SmartPlaylistRules{SmartRuleGroup: models.SmartRuleGroup{Match: "all", Rules: []models.SmartRule{
+				rule("album", "equals", "a night at the opera"),
+				rule("album_year", "between", []interface{}{1970.0, 1979.0}),
+			}}},
+			titles: []string{"Bohemian Rhapsody", "Love of My Life"},
+		},
+		{
+			name: "nested any group",
+			rules: models.SmartPlaylistRules{SmartRuleGroup: models.SmartRuleGroup{
+				Match: "all",
+				Rules: []models.SmartRule{rule("artist", "not_equals", "Crystal Waters")},
+				Groups: []models.SmartRuleGroup{{Match: "any", Rules: []models.SmartRule{
+					rule("genre", "equals", "rock"),
+					rule("year", "gte", 1979.0),
+				}}},
+			}},
+			titles: []string{"Bohemian Rhapsody", "Love of My Life", "Crazy Little Thing Called Love"},
+		},
+		{
+			name:   "not_contains keeps empty columns",
+			rules:  models.SmartPlaylistRules{SmartRuleGroup: models.SmartRuleGroup{Match: "all", Rules: []models.SmartRule{rule("genre", "not_contains", "rock")}}},
+			titles: []string{"Crazy Little Thing Called Love", "100% Pure Love"},
+		},
+		{
+			name:   "added recently",
+			rules:  models.SmartPlaylistRules{SmartRuleGroup: models.SmartRuleGroup{Match: "all", Rules: []models.SmartRule{rule("added", "not_in_last_days", 1.0)}}},
+			titles: nil,
+		},
+		{
+			name: "sorted and limited",
+			rules: models.SmartPlaylistRules{
+				SmartRuleGroup: models.SmartRuleGroup{Match: "all", Rules: []models.SmartRule{rule("play_count", "gt", 0.0)}},
+				Sort:           "-play_count",
+				Limit:          2,
+			},
+			titles: []string{"Love of My Life", "Bohemian Rhapsody"},
+		},
+		{
+			name:   "songs without an album sort last",
+			rules:  models.SmartPlaylistRules{SmartRuleGroup: models.SmartRuleGroup{Match: "all"}, Sort: "album_year"},
+			titles: []string{"Bohemian Rhapsody", "Love of My Life", "Crazy Little Thing Called Love", "100% Pure Love"},
+		},
+	}
+
+	for name, store := range stores(t) {
+		userId := createUser(t, store, "ada@example.com")
+		otherId := createUser(t, store, "grace@example.com")
+		opera := models.Album{Title: "A Night at the Opera", Artist: "Queen", Year: 1975, UserId: userId}
+		if err := store.Albums().Create(&opera); err != nil {
+			t.Fatal(err)
+		}
+		songs := []models.Song{
+			{Title: "Bohemian Rhapsody", Artist: "Queen", Genre: "Rock", Year: 1975, AlbumId: &opera.ID, PlayCount: 3, UserId: userId},
+			{Title: "Love of My Life", Artist: "Queen", Genre: "Rock", Year: 1975, AlbumId: &opera.ID, PlayCount: 7, UserId: userId},
+			{Title: "Crazy Little Thing Called Love", Artist: "Queen", Year: 1979, UserId: userId},
+			{Title: "100% Pure Love", Artist: "Crystal Waters", Year: 1994, PlayCount: 1, UserId: userId},
+			{Title: "Love Will Tear Us Apart", Artist: "Joy Division", Genre: "Rock", Year: 1980, PlayCount: 9, UserId: otherId},
+		}
+		for i := range songs {
+			if err := store.Songs().Create(&songs[i]); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		for _, tt := range tests {
+			t.Run(name+"/"+tt.name, func(t *testing.T) {
+				matching, err := store.Songs().FindMatching(userId, tt.rules, time.Now())
+				if err != nil {
+					t.Fatal(err)
+				}
+				var titles []string
+				for _, song := range matching {
+					titles = append(titles, song.Title)
+				}
+				if strings.Join(titles, "|") != strings.Join(tt.titles, "|") {
+					t.Errorf("songs = %q, want %q", titles, tt.titles)
+				}
+			})
+		}
+	}
+}
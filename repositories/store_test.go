@@ -0,0 +1,162 @@
+package repositories_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/tushar27x/music-lib-api/models"
+	"github.com/tushar27x/music-lib-api/repositories"
+)
+
+func TestSoftDeleteAndRestore(t *testing.T) {
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			userId := createUser(t, store, "ada@example.com")
+			song := models.Song{Title: "Bohemian Rhapsody", UserId: userId}
+			if err := store.Songs().Create(&song); err != nil {
+				t.Fatal(err)
+			}
+			if err := store.Songs().Delete(&song); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := store.Songs().FindOwned(userId, song.ID); !errors.Is(err, repositories.ErrNotFound) {
+				t.Errorf("FindOwned of a deleted song = %v, want ErrNotFound", err)
+			}
+			deleted, total, err := store.Songs().FindDeleted(userId, 10, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if total != 1 || len(deleted) != 1 || deleted[0].ID != song.ID || !deleted[0].DeletedAt.Valid {
+				t.Errorf("deleted songs = %+v (total %d), want song %d", deleted, total, song.ID)
+			}
+
+			restored, err := store.Songs().Restore(song.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if restored.ID != song.ID || restored.DeletedAt.Valid {
+				t.Errorf("restored song = %+v, want song %d without deleted_at", restored, song.ID)
+			}
+			if _, err := store.Songs().FindOwned(userId, song.ID); err != nil {
+				t.Errorf("FindOwned of a restored song = %v", err)
+			}
+			if _, err := store.Songs().Restore(song.ID); !errors.Is(err, repositories.ErrNotFound) {
+				t.Errorf("restoring a song that isn't deleted = %v, want ErrNotFound", err)
+			}
+		})
+	}
+}
+
+func TestRestoreKeepsAlbumsUnique(t *testing.T) {
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			userId := createUser(t, store, "ada@example.com")
+			album := models.Album{Title: "A Night at the Opera", Artist: "Queen", Year: 1975, UserId: userId}
+			if err := store.Albums().Create(&album); err != nil {
+				t.Fatal(err)
+			}
+			if err := store.Albums().Delete(&album); err != nil {
+				t.Fatal(err)
+			}
+
+			// Deleted albums don't count, until they are restored
+			again := models.Album{Title: "A Night at the Opera", Artist: "Queen", Year: 1975, UserId: userId}
+			if err := store.Albums().Create(&again); err != nil {
+				t.Fatalf("recreating a deleted album = %v", err)
+			}
+			if _, err := store.Albums().Restore(album.ID); !errors.Is(err, repositories.ErrDuplicate) {
+				t.Errorf("restoring a recreated album = %v, want ErrDuplicate", err)
+			}
+		})
+	}
+}
+
+func TestUniqueConstraints(t *testing.T) {
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			userId := createUser(t, store, "ada@example.com")
+
+			user := models.User{Name: "Ada", Email: "ada@example.com"}
+			if err := store.Users().Create(&user); !errors.Is(err, repositories.ErrDuplicate) {
+				t.Errorf("second user with an email = %v, want ErrDuplicate", err)
+			}
+
+			album := models.Album{Title: "A Night at the Opera", Artist: "Queen", Year: 1975, UserId: userId}
+			if err := store.Albums().Create(&album); err != nil {
+				t.Fatal(err)
+			}
+			first := models.Song{Title: "Death on Two Legs", AlbumId: &album.ID, DiscNumber: 1, TrackNumber: 1, UserId: userId}
+			if err := store.Songs().Create(&first); err != nil {
+				t.Fatal(err)
+			}
+			clash := models.Song{Title: "Lazing on a Sunday Afternoon", AlbumId: &album.ID, DiscNumber: 1, TrackNumber: 1, UserId: userId}
+			if err := store.Songs().Create(&clash); !errors.Is(err, repositories.ErrDuplicate) {
+				t.Errorf("second song with a track number = %v, want ErrDuplicate", err)
+			}
+			clash.TrackNumber = 2
+			if err := store.Songs().Create(&clash); err != nil {
+				t.Fatal(err)
+			}
+			clash.TrackNumber = 1
+			if err := store.Songs().Update(&clash, "track_number"); !errors.Is(err, repositories.ErrDuplicate) {
+				t.Errorf("moving a song onto a taken track number = %v, want ErrDuplicate", err)
+			}
+
+			tag := models.Tag{Name: "Favourites", UserId: userId}
+			if err := store.Tags().Create(&tag); err != nil {
+				t.Fatal(err)
+			}
+			tag = models.Tag{Name: "favourites", UserId: userId}
+			if err := store.Tags().Create(&tag); !errors.Is(err, repositories.ErrDuplicate) {
+				t.Errorf("second tag with a name in another case = %v, want ErrDuplicate", err)
+			}
+		})
+	}
+}
+
+func TestNestedTransactionsRollBackLikeSavepoints(t *testing.T) {
+	errRollback := errors.New("roll back")
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			err := store.Transaction(func(tx repositories.Store) error {
+				createUser(t, tx, "ada@example.com")
+				err := tx.Transaction(func(tx repositories.Store) error {
+					createUser(t, tx, "grace@example.com")
+					return errRollback
+				})
+				if !errors.Is(err, errRollback) {
+					t.Errorf("inner transaction = %v, want its own error", err)
+				}
+				createUser(t, tx, "edsger@example.com")
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = store.Transaction(func(tx repositories.Store) error {
+				createUser(t, tx, "barbara@example.com")
+				return errRollback
+			})
+			if !errors.Is(err, errRollback) {
+				t.Errorf("transaction = %v, want its own error", err)
+			}
+
+			for email, kept := range map[string]bool{
+				"ada@example.com":     true,
+				"grace@example.com":   false,
+				"edsger@example.com":  true,
+				"barbara@example.com": false,
+			} {
+				_, err := store.Users().FindByEmail(email)
+				if kept && err != nil {
+					t.Errorf("user %s was rolled back: %v", email, err)
+				}
+				if !kept && !errors.Is(err, repositories.ErrNotFound) {
+					t.Errorf("user %s = %v, want ErrNotFound", email, err)
+				}
+			}
+		})
+	}
+}
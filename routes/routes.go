@@ -5,10 +5,79 @@ import (
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 	"github.com/tushar27x/music-lib-api/controllers"
+	"github.com/tushar27x/music-lib-api/mailer"
 	"github.com/tushar27x/music-lib-api/middlewares"
+	"github.com/tushar27x/music-lib-api/models"
+	"github.com/tushar27x/music-lib-api/ratelimit"
+	"github.com/tushar27x/music-lib-api/repositories"
+	"github.com/tushar27x/music-lib-api/services"
+	"github.com/tushar27x/music-lib-api/storage"
 )
 
-func RegisterRoutes(router *gin.Engine) {
+// Handlers holds the controllers and the authentication service the routes are wired to
+type Handlers struct {
+	Auth      *services.AuthService
+	Users     *controllers.AuthController
+	TwoFactor *controllers.TwoFactorController
+	APIKeys   *controllers.APIKeyController
+	OIDC      *controllers.OIDCController
+	Albums    *controllers.AlbumController
+	Songs     *controllers.SongController
+	Artists   *controllers.ArtistController
+	Genres    *controllers.GenreController
+	Tags      *controllers.TagController
+	Playlists *controllers.PlaylistController
+	Search    *controllers.SearchController
+	Admin     *controllers.AdminController
+	// RateLimits holds the request counters; nil turns rate limiting off
+	RateLimits ratelimit.Store
+}
+
+// NewHandlers builds the services on top of store, searcher, blobs and mail and the controllers using them;
+// requests are counted in limits
+func NewHandlers(store repositories.Store, searcher *services.SearchService, blobs storage.Storage, mail mailer.Mailer, limits ratelimit.Store) Handlers {
+	tokens := services.NewTokenService(store)
+	apiKeys := services.NewAPIKeyService(store)
+	throttle := services.NewLoginThrottleService(store)
+	auth := services.NewAuthService(store, tokens, apiKeys, throttle)
+	accounts := services.NewAccountService(store, mail)
+	covers := services.NewCoverService(store, blobs)
+
+	return Handlers{
+		Auth:       auth,
+		Users:      controllers.NewAuthController(auth, tokens, accounts),
+		TwoFactor:  controllers.NewTwoFactorController(services.NewTwoFactorService(store, tokens, throttle)),
+		APIKeys:    controllers.NewAPIKeyController(apiKeys),
+		OIDC:       controllers.NewOIDCController(services.NewOIDCService(store, tokens, services.OIDCProvidersFromEnv())),
+		Albums:     controllers.NewAlbumController(services.NewAlbumService(store, blobs), covers, searcher),
+		Songs:      controllers.NewSongController(services.NewSongService(store, blobs), covers, searcher),
+		Artists:    controllers.NewArtistController(services.NewArtistService(store), searcher),
+		Genres:     controllers.NewGenreController(services.NewGenreService(store)),
+		Tags:       controllers.NewTagController(services.NewTagService(store)),
+		Playlists:  controllers.NewPlaylistController(services.NewPlaylistService(store), covers, searcher),
+		Search:     controllers.NewSearchController(searcher),
+		Admin:      controllers.NewAdminController(services.NewUserService(store), services.NewModerationService(store), services.NewAuditService(store), apiKeys, throttle),
+		RateLimits: limits,
+	}
+}
+
+func RegisterRoutes(router *gin.Engine, h Handlers) {
+	// authMiddleware only accepts access tokens; the library groups also take API keys with their scopes
+	authMiddleware := middlewares.AuthMiddleware(h.Auth)
+	streamAuthMiddleware := middlewares.StreamAuthMiddleware(h.Auth)
+	manageLibrary := middlewares.RequirePermission(models.PermissionManageLibrary)
+	manageAlbums := middlewares.RequirePermission(models.PermissionManageAlbums)
+	// Accounts are managed by admins; other users' content is moderated by moderators too
+	manageUsers := middlewares.RequirePermission(models.PermissionManageUsers)
+	moderateContent := middlewares.RequirePermission(models.PermissionModerateContent)
+	// Authentication endpoints are counted per IP address under a stricter limit against guessing and
+	// sign-up floods; the rest of the API is counted per IP address before authentication, so floods of
+	// bad tokens and keys are cut off before they are checked, and per user after it. Streaming is left
+	// out since players send many range requests for a single song.
+	authLimit := middlewares.RateLimit(h.RateLimits, "auth", ratelimit.AuthLimit())
+	ipLimit := middlewares.RateLimit(h.RateLimits, "ip", ratelimit.IPLimit())
+	apiLimit := middlewares.RateLimit(h.RateLimits, "api", ratelimit.DefaultLimit())
+
 	// Swagger documentation
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
@@ -19,42 +88,167 @@ func RegisterRoutes(router *gin.Engine) {
 		})
 
 		auth := api.Group("/auth")
+		auth.Use(authLimit)
 		{
-			auth.POST("/register", controllers.Register)
-			auth.POST("/login", controllers.Login)
+			auth.POST("/register", h.Users.Register)
+			auth.POST("/login", h.Users.Login)
+			auth.POST("/refresh", h.Users.Refresh)
+			auth.POST("/logout", authMiddleware, h.Users.Logout)
+			auth.PUT("/password", middlewares.PasswordChangeMiddleware(h.Auth), h.Users.ChangePassword)
+			auth.POST("/verify-email", h.Users.VerifyEmail)
+			auth.POST("/verify-email/resend", h.Users.ResendVerification)
+			auth.POST("/forgot-password", h.Users.ForgotPassword)
+			auth.POST("/reset-password", h.Users.ResetPassword)
+
+			// The second step of logging in happens before there is an access token
+			auth.POST("/2fa/verify", h.TwoFactor.Verify)
+			twoFactor := auth.Group("/2fa")
+			twoFactor.Use(authMiddleware)
+			{
+				twoFactor.GET("", h.TwoFactor.Status)
+				twoFactor.POST("/setup", h.TwoFactor.Setup)
+				twoFactor.POST("/confirm", h.TwoFactor.Confirm)
+				twoFactor.POST("/disable", h.TwoFactor.Disable)
+				twoFactor.POST("/recovery-codes", h.TwoFactor.RegenerateRecoveryCodes)
+			}
+
+			apiKeys := auth.Group("/api-keys")
+			apiKeys.Use(authMiddleware)
+			{
+				apiKeys.GET("", h.APIKeys.ListAPIKeys)
+				apiKeys.POST("", h.APIKeys.CreateAPIKey)
+				apiKeys.DELETE("/:id", h.APIKeys.RevokeAPIKey)
+			}
+
+			// Single sign-on through external identity providers
+			auth.GET("/oidc/providers", h.OIDC.Providers)
+			auth.POST("/oidc/:provider/authorize", h.OIDC.Authorize)
+			auth.POST("/oidc/:provider/callback", h.OIDC.Callback)
+			auth.GET("/identities", authMiddleware, h.OIDC.Identities)
 		}
 
+		// Searches songs, albums and playlists in one call
+		api.GET("/search", ipLimit, middlewares.AuthMiddleware(h.Auth, models.ResourceSongs, models.ResourceAlbums, models.ResourcePlaylists), apiLimit, h.Search.Search)
+
 		albums := api.Group("/albums")
-		albums.Use(middlewares.AuthMiddleware())
+		albums.Use(ipLimit, middlewares.AuthMiddleware(h.Auth, models.ResourceAlbums), apiLimit)
 		{
-			albums.GET("/", controllers.GetAlbums)
-			albums.GET("/search", controllers.SearchAlbums)
-			albums.GET("/:id", controllers.GetAlbumByID)
-			albums.POST("/", controllers.CreateAlbum)
-			albums.PUT("/:id", controllers.UpdateAlbum)
-			albums.DELETE("/:id", controllers.DeleteAlbum)
+			albums.GET("/", h.Albums.GetAlbums)
+			albums.GET("/search", h.Albums.SearchAlbums)
+			albums.GET("/:id", h.Albums.GetAlbumByID)
+			albums.POST("/", manageAlbums, h.Albums.CreateAlbum)
+			albums.PUT("/:id", manageAlbums, h.Albums.UpdateAlbum)
+			albums.DELETE("/:id", manageAlbums, h.Albums.DeleteAlbum)
+			albums.POST("/:id/merge", manageAlbums, h.Albums.MergeAlbum)
+			albums.PUT("/:id/tracklist", manageAlbums, h.Albums.UpdateTracklist)
+			albums.GET("/:id/cover", h.Albums.GetAlbumCover)
+			albums.POST("/:id/cover", manageAlbums, h.Albums.UploadAlbumCover)
+			albums.DELETE("/:id/cover", manageAlbums, h.Albums.DeleteAlbumCover)
+			albums.POST("/:id/cover/extract", manageAlbums, h.Albums.ExtractAlbumCover)
 		}
 
 		songs := api.Group("/songs")
-		songs.Use(middlewares.AuthMiddleware())
+		songs.Use(ipLimit, middlewares.AuthMiddleware(h.Auth, models.ResourceSongs), apiLimit, manageLibrary)
+		{
+			songs.GET("/", h.Songs.GetSongs)
+			songs.GET("/search", h.Songs.SearchSongs)
+			songs.GET("/:id", h.Songs.GetSongByID)
+			songs.POST("/", h.Songs.AddSong)
+			songs.PUT("/:id", h.Songs.UpdateSong)
+			songs.DELETE("/:id", h.Songs.DeleteSong)
+			songs.POST("/:id/audio", h.Songs.UploadSongAudio)
+			songs.GET("/:id/stream-url", h.Songs.GetSongStreamURL)
+			songs.GET("/:id/tags", h.Songs.GetSongTags)
+			songs.POST("/:id/tags", h.Songs.ApplySongTags)
+		}
+
+		// Streaming also accepts signed URLs, so it sits outside the authenticated group
+		api.GET("/songs/:id/stream", streamAuthMiddleware, h.Songs.StreamSong)
+		api.HEAD("/songs/:id/stream", streamAuthMiddleware, h.Songs.StreamSong)
+
+		artists := api.Group("/artists")
+		artists.Use(ipLimit, middlewares.AuthMiddleware(h.Auth, models.ResourceArtists), apiLimit, manageLibrary)
+		{
+			artists.GET("/", h.Artists.GetArtists)
+			artists.GET("/search", h.Artists.SearchArtists)
+			artists.GET("/:id", h.Artists.GetArtistByID)
+			artists.POST("/", h.Artists.CreateArtist)
+			artists.PUT("/:id", h.Artists.UpdateArtist)
+			artists.DELETE("/:id", h.Artists.DeleteArtist)
+		}
+
+		// The listings also read albums or songs, so API keys need those scopes too
+		api.GET("/artists/:id/albums", ipLimit, middlewares.AuthMiddleware(h.Auth, models.ResourceArtists, models.ResourceAlbums), apiLimit, manageLibrary, h.Artists.GetArtistAlbums)
+		api.GET("/artists/:id/songs", ipLimit, middlewares.AuthMiddleware(h.Auth, models.ResourceArtists, models.ResourceSongs), apiLimit, manageLibrary, h.Artists.GetArtistSongs)
+
+		genres := api.Group("/genres")
+		genres.Use(ipLimit, middlewares.AuthMiddleware(h.Auth, models.ResourceGenres), apiLimit, manageLibrary)
+		{
+			genres.GET("/", h.Genres.GetGenres)
+			genres.GET("/tree", h.Genres.GetGenreTree)
+			genres.GET("/:id", h.Genres.GetGenreByID)
+			genres.POST("/", h.Genres.CreateGenre)
+			genres.PUT("/:id", h.Genres.UpdateGenre)
+			genres.DELETE("/:id", h.Genres.DeleteGenre)
+		}
+
+		tags := api.Group("/tags")
+		tags.Use(ipLimit, middlewares.AuthMiddleware(h.Auth, models.ResourceTags), apiLimit, manageLibrary)
 		{
-			songs.GET("/", controllers.GetSongs)
-			songs.GET("/search", controllers.SearchSongs)
-			songs.GET("/:id", controllers.GetSongByID)
-			songs.POST("/", controllers.AddSong)
-			songs.PUT("/:id", controllers.UpdateSong)
-			songs.DELETE("/:id", controllers.DeleteSong)
+			tags.GET("/", h.Tags.GetTags)
+			tags.POST("/", h.Tags.CreateTag)
+			tags.PUT("/:id", h.Tags.UpdateTag)
+			tags.DELETE("/:id", h.Tags.DeleteTag)
+			tags.POST("/:id/merge", h.Tags.MergeTag)
 		}
 
+		// Bulk changes also write songs and albums, so API keys need those scopes too
+		genreItems := middlewares.AuthMiddleware(h.Auth, models.ResourceGenres, models.ResourceSongs, models.ResourceAlbums)
+		tagItems := middlewares.AuthMiddleware(h.Auth, models.ResourceTags, models.ResourceSongs, models.ResourceAlbums)
+		api.POST("/genres/:id/assign", ipLimit, genreItems, apiLimit, manageLibrary, h.Genres.AssignGenre)
+		api.POST("/genres/:id/unassign", ipLimit, genreItems, apiLimit, manageLibrary, h.Genres.UnassignGenre)
+		api.POST("/tags/apply", ipLimit, tagItems, apiLimit, manageLibrary, h.Tags.ApplyTags)
+		api.POST("/tags/remove", ipLimit, tagItems, apiLimit, manageLibrary, h.Tags.RemoveTags)
+
 		playlists := api.Group("/playlists")
-		playlists.Use(middlewares.AuthMiddleware())
-		{
-			playlists.GET("/", controllers.GetPlayList)
-			playlists.GET("/search", controllers.SearchPlaylists)
-			playlists.GET("/:id", controllers.GetPlayListById)
-			playlists.POST("/", controllers.AddPlaylist)
-			playlists.PUT("/:id", controllers.UpdatePlaylist)
-			playlists.DELETE("/:id", controllers.DeletePlaylist)
+		playlists.Use(ipLimit, middlewares.AuthMiddleware(h.Auth, models.ResourcePlaylists), apiLimit, manageLibrary)
+		{
+			playlists.GET("/", h.Playlists.GetPlayList)
+			playlists.GET("/search", h.Playlists.SearchPlaylists)
+			playlists.POST("/import", h.Playlists.ImportPlaylist)
+			playlists.GET("/:id", h.Playlists.GetPlayListById)
+			playlists.POST("/", h.Playlists.AddPlaylist)
+			playlists.PUT("/:id", h.Playlists.UpdatePlaylist)
+			playlists.DELETE("/:id", h.Playlists.DeletePlaylist)
+			playlists.POST("/:id/tracks", h.Playlists.AddPlaylistTracks)
+			playlists.DELETE("/:id/tracks/:entryId", h.Playlists.RemovePlaylistTrack)
+			playlists.PATCH("/:id/tracks/move", h.Playlists.MovePlaylistTrack)
+			playlists.GET("/:id/export", h.Playlists.ExportPlaylist)
+			playlists.GET("/:id/cover", h.Playlists.GetPlaylistCover)
+			playlists.POST("/:id/cover", h.Playlists.UploadPlaylistCover)
+			playlists.DELETE("/:id/cover", h.Playlists.DeletePlaylistCover)
+		}
+
+		admin := api.Group("/admin")
+		admin.Use(ipLimit, authMiddleware, apiLimit)
+		{
+			admin.GET("/users", manageUsers, h.Admin.ListUsers)
+			admin.GET("/users/:id", manageUsers, h.Admin.GetUser)
+			admin.PUT("/users/:id/role", manageUsers, h.Admin.UpdateUserRole)
+			admin.POST("/users/:id/suspend", manageUsers, h.Admin.SuspendUser)
+			admin.POST("/users/:id/unsuspend", manageUsers, h.Admin.UnsuspendUser)
+			admin.POST("/users/:id/password-reset", manageUsers, h.Admin.ForcePasswordReset)
+			admin.POST("/users/:id/2fa/reset", manageUsers, h.Admin.ResetTwoFactor)
+			admin.GET("/users/:id/api-keys", manageUsers, h.Admin.ListUserAPIKeys)
+			admin.DELETE("/users/:id/api-keys/:keyId", manageUsers, h.Admin.RevokeUserAPIKey)
+			admin.GET("/lockouts", manageUsers, h.Admin.ListLockouts)
+			admin.DELETE("/lockouts/:id", manageUsers, h.Admin.Unlock)
+
+			admin.GET("/users/:id/library", moderateContent, h.Admin.GetUserLibrary)
+			admin.DELETE("/content/:type/:id", moderateContent, h.Admin.RemoveRecord)
+			admin.GET("/deleted/:type", moderateContent, h.Admin.ListDeleted)
+			admin.POST("/deleted/:type/:id/restore", moderateContent, h.Admin.RestoreDeleted)
+			admin.GET("/audit", moderateContent, h.Admin.ListAudit)
 		}
 	}
 }
@@ -0,0 +1,188 @@
+// Package search parses the user facing search syntax into PostgreSQL text search and SQLite FTS5 queries.
+//
+// Supported syntax:
+//
+//	love song        both words (AND)
+//	"love song"      the exact phrase
+//	lov*             words starting with "lov"
+//	-live            excludes matches containing "live" (also -"live at")
+//	love OR peace    either word
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Query is a parsed search query
+type Query struct {
+	// TSQuery is the query for to_tsquery('simple', ...); empty when nothing searchable was given
+	TSQuery string
+	// Text is the positive search words, used for typo tolerant trigram matching
+	Text string
+	// Negated is a tsquery matching any excluded term, empty when nothing is excluded
+	Negated string
+	// FTS is the query in SQLite FTS5 syntax without the excluded terms, which FTS5 can't negate on
+	// their own; empty when only exclusions were given
+	FTS string
+	// NegatedFTS is an FTS5 query matching any excluded term, empty when nothing is excluded
+	NegatedFTS string
+}
+
+// Empty reports whether the query has nothing to search for
+func (q Query) Empty() bool {
+	return q.TSQuery == ""
+}
+
+type term struct {
+	expression string
+	// fts is the same term in FTS5 syntax
+	fts   string
+	words []string
+}
+
+// Parse turns user input into a Query. It never fails: characters that are not
+// part of the syntax are dropped, so the output is always a valid tsquery.
+func Parse(input string) Query {
+	var groups [][]term
+	var textWords, negatedTerms, negatedFTS []string
+	joinNext := false
+
+	runes := []rune(input)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		if unicode.IsSpace(r) {
+			i++
+			continue
+		}
+
+		if r == '|' {
+			joinNext = len(groups) > 0
+			i++
+			continue
+		}
+
+		negated := false
+		if r == '-' || r == '!' {
+			negated = true
+			i++
+			if i >= len(runes) {
+				break
+			}
+		}
+
+		var current term
+		if runes[i] == '"' {
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+			current = phraseTerm(string(runes[i+1 : end]))
+			i = end + 1
+		} else {
+			end := i
+			for end < len(runes) && !unicode.IsSpace(runes[end]) && runes[end] != '"' && runes[end] != '|' {
+				end++
+			}
+			raw := string(runes[i:end])
+			i = end
+
+			if raw == "OR" && !negated {
+				joinNext = len(groups) > 0
+				continue
+			}
+			current = wordTerm(raw)
+		}
+
+		if current.expression == "" {
+			continue
+		}
+		if negated {
+			current.expression = "!" + current.expression
+			negatedTerms = append(negatedTerms, strings.TrimPrefix(current.expression, "!"))
+			negatedFTS = append(negatedFTS, current.fts)
+			current.fts = ""
+		} else {
+			textWords = append(textWords, current.words...)
+		}
+
+		if joinNext {
+			groups[len(groups)-1] = append(groups[len(groups)-1], current)
+			joinNext = false
+		} else {
+			groups = append(groups, []term{current})
+		}
+	}
+
+	clauses := make([]string, 0, len(groups))
+	ftsClauses := make([]string, 0, len(groups))
+	for _, group := range groups {
+		expressions := make([]string, len(group))
+		var ftsExpressions []string
+		for i, t := range group {
+			expressions[i] = t.expression
+			if t.fts != "" {
+				ftsExpressions = append(ftsExpressions, t.fts)
+			}
+		}
+		clauses = append(clauses, joinGroup(expressions, " | "))
+		if len(ftsExpressions) > 0 {
+			ftsClauses = append(ftsClauses, joinGroup(ftsExpressions, " OR "))
+		}
+	}
+
+	return Query{
+		TSQuery:    strings.Join(clauses, " & "),
+		Text:       strings.Join(textWords, " "),
+		Negated:    strings.Join(negatedTerms, " | "),
+		FTS:        strings.Join(ftsClauses, " AND "),
+		NegatedFTS: strings.Join(negatedFTS, " OR "),
+	}
+}
+
+// joinGroup joins the alternatives of one group, parenthesised when there are several
+func joinGroup(expressions []string, operator string) string {
+	if len(expressions) == 1 {
+		return expressions[0]
+	}
+	return "(" + strings.Join(expressions, operator) + ")"
+}
+
+// wordTerm handles a bare word, which may expand to several lexemes ("rock'n'roll") and may end in *
+func wordTerm(raw string) term {
+	prefix := strings.HasSuffix(raw, "*")
+	words := lexemes(raw)
+	if len(words) == 0 {
+		return term{}
+	}
+
+	expression := strings.Join(words, " <-> ")
+	// Lexemes are letters and digits only, so quoting them is always safe in FTS5
+	fts := `"` + strings.Join(words, " ") + `"`
+	if prefix {
+		expression += ":*"
+		fts += "*"
+	}
+	if len(words) > 1 {
+		expression = "(" + expression + ")"
+	}
+	return term{expression: expression, fts: fts, words: words}
+}
+
+func phraseTerm(raw string) term {
+	words := lexemes(raw)
+	switch len(words) {
+	case 0:
+		return term{}
+	case 1:
+		return term{expression: words[0], fts: `"` + words[0] + `"`, words: words}
+	}
+	return term{expression: "(" + strings.Join(words, " <-> ") + ")", fts: `"` + strings.Join(words, " ") + `"`, words: words}
+}
+
+// lexemes lowercases the input and splits it on anything that isn't a letter or digit
+func lexemes(raw string) []string {
+	return strings.FieldsFunc(strings.ToLower(raw), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
@@ -0,0 +1,48 @@
+package search
+
+// WordSimilarity mirrors word_similarity from Postgres' pg_trgm for databases without it: the greatest
+// similarity between the trigrams of query and any continuous run of the trigrams of text, where
+// similarity is the number of shared trigrams over the size of their union.
+func WordSimilarity(query string, text string) float64 {
+	wanted := map[string]bool{}
+	for _, trigram := range trigrams(query) {
+		wanted[trigram] = true
+	}
+	if len(wanted) == 0 {
+		return 0
+	}
+
+	sequence := trigrams(text)
+	best := 0.0
+	for start := range sequence {
+		seen := map[string]bool{}
+		shared, extra := 0, 0
+		for _, trigram := range sequence[start:] {
+			if !seen[trigram] {
+				seen[trigram] = true
+				if wanted[trigram] {
+					shared++
+				} else {
+					extra++
+				}
+			}
+			if score := float64(shared) / float64(len(wanted)+extra); score > best {
+				best = score
+			}
+		}
+	}
+	return best
+}
+
+// trigrams lists the trigrams of every word in order, each word padded like pg_trgm does
+// with two spaces in front and one behind
+func trigrams(value string) []string {
+	var list []string
+	for _, word := range lexemes(value) {
+		padded := []rune("  " + word + " ")
+		for i := 0; i+3 <= len(padded); i++ {
+			list = append(list, string(padded[i:i+3]))
+		}
+	}
+	return list
+}
@@ -0,0 +1,294 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tushar27x/music-lib-api/config"
+	"github.com/tushar27x/music-lib-api/mailer"
+	"github.com/tushar27x/music-lib-api/models"
+	"github.com/tushar27x/music-lib-api/repositories"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	// ErrInvalidAccountToken is returned when a verification or reset token is unknown, used or expired
+	ErrInvalidAccountToken = errors.New("invalid or expired token")
+	// ErrEmailNotVerified is returned on login when REQUIRE_EMAIL_VERIFICATION is on and the address is unverified
+	ErrEmailNotVerified = errors.New("email address is not verified")
+)
+
+// EmailVerificationTTL returns how long verification links stay valid (EMAIL_VERIFICATION_TTL, default 48h)
+func EmailVerificationTTL() time.Duration {
+	return config.GetEnvDuration("EMAIL_VERIFICATION_TTL", 48*time.Hour)
+}
+
+// PasswordResetTTL returns how long password reset links stay valid (PASSWORD_RESET_TTL, default 1h)
+func PasswordResetTTL() time.Duration {
+	return config.GetEnvDuration("PASSWORD_RESET_TTL", time.Hour)
+}
+
+// RequireEmailVerification reports whether login is refused until the email address is verified
+func RequireEmailVerification() bool {
+	return config.GetEnv("REQUIRE_EMAIL_VERIFICATION") == "true"
+}
+
+// MailTimeout returns how long sending one email may take (MAIL_TIMEOUT, default 30s)
+func MailTimeout() time.Duration {
+	return config.GetEnvDuration("MAIL_TIMEOUT", 30*time.Second)
+}
+
+// AppURL returns the address of the web app the links in emails point to (APP_URL, default http://localhost:3000)
+func AppURL() string {
+	appURL := config.GetEnv("APP_URL")
+	if appURL == "" {
+		appURL = "http://localhost:3000"
+	}
+	return strings.TrimRight(appURL, "/")
+}
+
+// AccountService verifies email addresses and resets forgotten passwords with single-use
+// tokens sent by email. Only the hash of a token is stored. Emails are sent in the background, so
+// requests neither wait for the mail server nor reveal through their timing whether one was sent.
+type AccountService struct {
+	store   repositories.Store
+	mail    mailer.Mailer
+	sending sync.WaitGroup
+}
+
+// NewAccountService returns an AccountService keeping its tokens in store and sending them with mail
+func NewAccountService(store repositories.Store, mail mailer.Mailer) *AccountService {
+	return &AccountService{store: store, mail: mail}
+}
+
+// SendVerification emails the user a link to verify their address in the background. Earlier
+// verification links of the user stop working.
+func (s *AccountService) SendVerification(user models.User) {
+	s.background(fmt.Sprintf("verification email to user %d", user.ID), func(ctx context.Context) error {
+		return s.sendVerification(ctx, user)
+	})
+}
+
+// ResendVerification sends a new verification link to the account with the email in the
+// background. Unknown and already verified addresses are ignored, so the response does not
+// reveal which accounts exist.
+func (s *AccountService) ResendVerification(email string) {
+	s.background("verification email", func(ctx context.Context) error {
+		user, err := s.store.Users().FindByEmail(email)
+		if errors.Is(err, repositories.ErrNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if user.EmailVerifiedAt != nil {
+			return nil
+		}
+		return s.sendVerification(ctx, user)
+	})
+}
+
+func (s *AccountService) sendVerification(ctx context.Context, user models.User) error {
+	return s.sendLink(ctx, user, models.AccountTokenVerifyEmail, mailer.TemplateVerifyEmail, "/verify-email", EmailVerificationTTL())
+}
+
+// VerifyEmail uses a verification token and marks the address it was sent to as verified.
+// The token is rejected if the user has changed their address since.
+func (s *AccountService) VerifyEmail(rawToken string) (models.User, error) {
+	var user models.User
+	err := s.store.Transaction(func(tx repositories.Store) error {
+		token, err := useAccountToken(tx, models.AccountTokenVerifyEmail, rawToken)
+		if err != nil {
+			return err
+		}
+		user, err = accountTokenUser(tx, token)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if user.EmailVerifiedAt == nil {
+			user.EmailVerifiedAt = &now
+			if err := tx.Users().Update(&user, "email_verified_at"); err != nil {
+				return err
+			}
+		}
+		return tx.Tokens().ExpireAccountTokens(user.ID, models.AccountTokenVerifyEmail, now)
+	})
+	return user, err
+}
+
+// ForgotPassword emails a password reset link to the account with the email in the background.
+// Unknown addresses are ignored, so the response does not reveal which accounts exist.
+func (s *AccountService) ForgotPassword(email string) {
+	s.background("password reset email", func(ctx context.Context) error {
+		user, err := s.store.Users().FindByEmail(email)
+		if errors.Is(err, repositories.ErrNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return s.sendLink(ctx, user, models.AccountTokenResetPassword, mailer.TemplateResetPassword, "/reset-password", PasswordResetTTL())
+	})
+}
+
+// Wait blocks until the emails being sent in the background are done
+func (s *AccountService) Wait() {
+	s.sending.Wait()
+}
+
+// background runs send on its own with a context ending after MailTimeout and logs its failure
+func (s *AccountService) background(what string, send func(ctx context.Context) error) {
+	s.sending.Add(1)
+	go func() {
+		defer s.sending.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), MailTimeout())
+		defer cancel()
+		if err := send(ctx); err != nil {
+			log.Printf("Failed to send %s: %v", what, err)
+		}
+	}()
+}
+
+// ResetPassword uses a reset token to set a new password. A required password change is
+// cleared, every refresh token of the user is revoked, failed logins are forgotten so a
+// locked out user can log in right away, and since the link proves the user reads the
+// mailbox, an unverified address is verified too. Access tokens are not tracked per user,
+// so the ones already issued stay valid until they expire after ACCESS_TOKEN_TTL.
+func (s *AccountService) ResetPassword(rawToken string, password string) (models.User, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	var user models.User
+	err = s.store.Transaction(func(tx repositories.Store) error {
+		token, err := useAccountToken(tx, models.AccountTokenResetPassword, rawToken)
+		if err != nil {
+			return err
+		}
+		user, err = accountTokenUser(tx, token)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		user.Password = string(hashed)
+		user.PasswordResetRequired = false
+		if user.EmailVerifiedAt == nil {
+			user.EmailVerifiedAt = &now
+		}
+		if err := tx.Users().Update(&user, "password", "password_reset_required", "email_verified_at"); err != nil {
+			return err
+		}
+		if err := tx.Tokens().ExpireAccountTokens(user.ID, models.AccountTokenResetPassword, now); err != nil {
+			return err
+		}
+		if err := tx.Tokens().RevokeUserRefreshTokens(user.ID, now); err != nil {
+			return err
+		}
+		return forgetLoginFailures(tx, user.Email)
+	})
+	return user, err
+}
+
+// sendLink issues a token for purpose, replacing the user's unused ones, and emails it as a
+// link to path in the web app
+func (s *AccountService) sendLink(ctx context.Context, user models.User, purpose string, template string, path string, ttl time.Duration) error {
+	rawToken, err := randomString(32)
+	if err != nil {
+		return err
+	}
+
+	err = s.store.Transaction(func(tx repositories.Store) error {
+		now := time.Now()
+		if err := tx.Tokens().ExpireAccountTokens(user.ID, purpose, now); err != nil {
+			return err
+		}
+		return tx.Tokens().CreateAccountToken(&models.AccountToken{
+			UserId:    user.ID,
+			Purpose:   purpose,
+			Email:     user.Email,
+			TokenHash: hashToken(rawToken),
+			ExpiresAt: now.Add(ttl),
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	msg, err := mailer.Render(template, user.Email, mailer.LinkData{
+		Name:      user.Name,
+		Email:     user.Email,
+		Link:      AppURL() + path + "?token=" + url.QueryEscape(rawToken),
+		ExpiresIn: durationWords(ttl),
+	})
+	if err != nil {
+		return err
+	}
+	return s.mail.Send(ctx, msg)
+}
+
+// useAccountToken marks the token as used if it is valid for purpose
+func useAccountToken(tx repositories.Store, purpose string, rawToken string) (models.AccountToken, error) {
+	token, err := tx.Tokens().FindAccountToken(purpose, hashToken(rawToken))
+	if err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			return token, ErrInvalidAccountToken
+		}
+		return token, err
+	}
+	if token.UsedAt != nil || time.Now().After(token.ExpiresAt) {
+		return token, ErrInvalidAccountToken
+	}
+
+	used, err := tx.Tokens().UseAccountToken(token.ID, time.Now())
+	if err != nil {
+		return token, err
+	}
+	if !used {
+		return token, ErrInvalidAccountToken
+	}
+	return token, nil
+}
+
+// accountTokenUser loads the owner of a token, which must still have the address the token was sent to
+func accountTokenUser(tx repositories.Store, token models.AccountToken) (models.User, error) {
+	user, err := tx.Users().FindByID(token.UserId)
+	if err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			return user, ErrInvalidAccountToken
+		}
+		return user, err
+	}
+	if !strings.EqualFold(user.Email, token.Email) {
+		return user, ErrInvalidAccountToken
+	}
+	return user, nil
+}
+
+// durationWords spells out a link lifetime for emails, e.g. "2 days" or "1 hour"
+func durationWords(d time.Duration) string {
+	plural := func(n int64, unit string) string {
+		if n == 1 {
+			return "1 " + unit
+		}
+		return fmt.Sprintf("%d %ss", n, unit)
+	}
+
+	switch {
+	case d >= 24*time.Hour && d%(24*time.Hour) == 0:
+		return plural(int64(d/(24*time.Hour)), "day")
+	case d >= time.Hour && d%time.Hour == 0:
+		return plural(int64(d/time.Hour), "hour")
+	default:
+		return plural(int64(d.Round(time.Minute)/time.Minute), "minute")
+	}
+}
@@ -0,0 +1,151 @@
+package services_test
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tushar27x/music-lib-api/mailer"
+	"github.com/tushar27x/music-lib-api/models"
+	"github.com/tushar27x/music-lib-api/repositories"
+	"github.com/tushar27x/music-lib-api/services"
+)
+
+// slowMailer holds every message until release is closed or the context of the send ends
+type slowMailer struct {
+	release chan struct{}
+	mu      sync.Mutex
+	sent    []mailer.Message
+}
+
+func (m *slowMailer) Send(ctx context.Context, msg mailer.Message) error {
+	select {
+	case <-m.release:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func (m *slowMailer) messages() []mailer.Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.sent
+}
+
+func TestAccountEmailsAreSentInTheBackground(t *testing.T) {
+	store := repositories.NewMemoryStore()
+	user := models.User{Name: "Ada", Email: "ada@example.com", Role: models.RoleListener}
+	if err := store.Users().Create(&user); err != nil {
+		t.Fatal(err)
+	}
+	mail := &slowMailer{release: make(chan struct{})}
+	accounts := services.NewAccountService(store, mail)
+
+	// Neither call waits for the mail server, whether or not the address has an account
+	accounts.ForgotPassword("ada@example.com")
+	accounts.ForgotPassword("nobody@example.com")
+	accounts.SendVerification(user)
+	if sent := mail.messages(); len(sent) != 0 {
+		t.Fatalf("sent %d emails before the mail server answered", len(sent))
+	}
+
+	close(mail.release)
+	accounts.Wait()
+	sent := mail.messages()
+	if len(sent) != 2 {
+		t.Fatalf("sent %d emails, want 2", len(sent))
+	}
+	links := map[string]bool{}
+	for _, msg := range sent {
+		if msg.To != "ada@example.com" {
+			t.Errorf("email sent to %q, want ada@example.com", msg.To)
+		}
+		for _, path := range []string{"/reset-password?token=", "/verify-email?token="} {
+			if strings.Contains(msg.Text, path) {
+				links[path] = true
+			}
+		}
+	}
+	if len(links) != 2 {
+		t.Errorf("links sent = %v, want a reset and a verification link", links)
+	}
+}
+
+func TestAccountEmailsTimeOut(t *testing.T) {
+	t.Setenv("MAIL_TIMEOUT", "10ms")
+	store := repositories.NewMemoryStore()
+	user := models.User{Name: "Ada", Email: "ada@example.com", Role: models.RoleListener}
+	if err := store.Users().Create(&user); err != nil {
+		t.Fatal(err)
+	}
+	mail := &slowMailer{release: make(chan struct{})}
+	accounts := services.NewAccountService(store, mail)
+
+	accounts.ForgotPassword("ada@example.com")
+	done := make(chan struct{})
+	go func() {
+		accounts.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("sending did not give up after MAIL_TIMEOUT")
+	}
+	if sent := mail.messages(); len(sent) != 0 {
+		t.Errorf("sent %d emails, want none", len(sent))
+	}
+}
+
+func TestResetPasswordLiftsTheLockout(t *testing.T) {
+	throttlePolicy(t)
+	auth, _, store := authServices(t)
+	throttle := services.NewLoginThrottleService(store)
+	if _, err := auth.Register(models.UserRegisterRequest{Name: "Ada", Email: "ada@example.com", Password: "correct horse"}); err != nil {
+		t.Fatal(err)
+	}
+	session, _, err := auth.Login("ada@example.com", "correct horse", "10.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 6 {
+		if err := throttle.RecordFailure("ada@example.com", "10.0.0.1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := lockedFor(t, throttle, "ada@example.com"); got == 0 {
+		t.Fatal("not locked after 6 failures")
+	}
+
+	mail := &slowMailer{release: make(chan struct{})}
+	close(mail.release)
+	accounts := services.NewAccountService(store, mail)
+	accounts.ForgotPassword("ada@example.com")
+	accounts.Wait()
+	sent := mail.messages()
+	if len(sent) != 1 {
+		t.Fatalf("sent %d emails, want 1", len(sent))
+	}
+	_, rawToken, _ := strings.Cut(sent[0].Text, "token=")
+	rawToken, _ = url.QueryUnescape(strings.Fields(rawToken)[0])
+	if _, err := accounts.ResetPassword(rawToken, "battery staple"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := lockedFor(t, throttle, "ada@example.com"); got != 0 {
+		t.Errorf("locked for %v after a password reset, want 0", got)
+	}
+	if _, _, err := auth.Login("ada@example.com", "battery staple", "10.0.0.1"); err != nil {
+		t.Errorf("login with the new password = %v, want nil", err)
+	}
+	if _, err := services.NewTokenService(store).RefreshTokens(session.RefreshToken); err == nil {
+		t.Error("refresh token from before the reset still works")
+	}
+}
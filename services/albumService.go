@@ -0,0 +1,383 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/tushar27x/music-lib-api/models"
+	"github.com/tushar27x/music-lib-api/repositories"
+	"github.com/tushar27x/music-lib-api/storage"
+)
+
+var (
+	// ErrAlbumExists is returned when the user already has an album with the same artist, title and year
+	ErrAlbumExists = errors.New("album already exists")
+	// ErrMergeSameAlbum is returned when an album is merged into itself
+	ErrMergeSameAlbum = errors.New("cannot merge an album into itself")
+	// ErrTracklistSongs is returned for a tracklist that doesn't list every song on the album exactly once
+	ErrTracklistSongs = errors.New("tracklist must list every song on the album exactly once")
+	// ErrTracklistPositions is returned for a tracklist placing two songs at the same disc and track number
+	ErrTracklistPositions = errors.New("tracklist places two songs at the same disc and track number")
+)
+
+// AlbumService holds the rules for a user's albums; only roles with models.PermissionManageAlbums may change them
+type AlbumService struct {
+	store repositories.Store
+	blobs storage.Storage
+}
+
+// NewAlbumService returns an AlbumService keeping albums in store and their covers in blobs
+func NewAlbumService(store repositories.Store, blobs storage.Storage) *AlbumService {
+	return &AlbumService{store: store, blobs: blobs}
+}
+
+// Create adds an album owned by the user, credits its artists and puts it in its genre with its tags
+func (s *AlbumService) Create(userId uint, role string, album *models.Album) error {
+	if !HasPermission(role, models.PermissionManageAlbums) {
+		return ErrPermissionDenied
+	}
+	album.UserId = userId
+
+	return s.store.Transaction(func(tx repositories.Store) error {
+		credits, err := resolveCredits(tx, userId, album.Artist, album.Credits, nil)
+		if err != nil {
+			return err
+		}
+		album.Artist = creditedArtist(credits)
+		genre, err := resolveGenre(tx, userId, album.GenreId, album.Genre)
+		if err != nil {
+			return err
+		}
+		album.GenreId, album.Genre = genreFields(genre)
+
+		err = tx.Albums().Create(album)
+		if errors.Is(err, repositories.ErrDuplicate) {
+			return ErrAlbumExists
+		}
+		if err != nil {
+			return err
+		}
+
+		album.Credits = credits
+		if err := saveAlbumCredits(tx, album.ID, credits); err != nil {
+			return err
+		}
+		if album.Tags != nil {
+			album.Tags, err = replaceAlbumTags(tx, userId, album.ID, album.Tags)
+		}
+		return err
+	})
+}
+
+// List returns the user's albums with their songs, credits and tags
+func (s *AlbumService) List(userId uint) ([]models.Album, error) {
+	albums, err := s.store.Albums().FindByUser(userId)
+	if err != nil {
+		return nil, err
+	}
+	if err := attachAlbumCredits(s.store, albums); err != nil {
+		return nil, err
+	}
+	return albums, attachAlbumTags(s.store, albums)
+}
+
+// Get returns an album of the user with its songs, credits and tags
+func (s *AlbumService) Get(userId uint, albumId uint) (models.Album, error) {
+	album, err := s.store.Albums().FindOwnedWithSongs(userId, albumId)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return album, ErrAlbumNotFound
+	}
+	if err != nil {
+		return album, err
+	}
+
+	if album.Credits, err = albumCredits(s.store, album.ID); err != nil {
+		return album, err
+	}
+	albums := []models.Album{album}
+	err = attachAlbumTags(s.store, albums)
+	return albums[0], err
+}
+
+// Update replaces the title, artist, year, genre and, when given, the credits and tags of an album of the user
+func (s *AlbumService) Update(userId uint, role string, albumId uint, input models.AlbumCreateRequest) (models.Album, error) {
+	if !HasPermission(role, models.PermissionManageAlbums) {
+		return models.Album{}, ErrPermissionDenied
+	}
+
+	var album models.Album
+	err := s.store.Transaction(func(tx repositories.Store) error {
+		found, err := tx.Albums().FindOwned(userId, albumId)
+		if errors.Is(err, repositories.ErrNotFound) {
+			return ErrAlbumNotFound
+		}
+		if err != nil {
+			return err
+		}
+		album = found
+
+		current, err := albumCredits(tx, album.ID)
+		if err != nil {
+			return err
+		}
+		credits, err := resolveCredits(tx, userId, input.Artist, input.Credits, current)
+		if err != nil {
+			return err
+		}
+		genre, err := resolveGenre(tx, userId, input.GenreId, input.Genre)
+		if err != nil {
+			return err
+		}
+
+		album.Title = input.Title
+		album.Artist = creditedArtist(credits)
+		album.Year = input.Year
+		album.GenreId, album.Genre = genreFields(genre)
+
+		err = tx.Albums().Update(&album, "title", "artist", "year", "genre", "genre_id")
+		if errors.Is(err, repositories.ErrDuplicate) {
+			return ErrAlbumExists
+		}
+		if err != nil {
+			return err
+		}
+
+		album.Credits = credits
+		if err := saveAlbumCredits(tx, album.ID, credits); err != nil {
+			return err
+		}
+		if input.Tags != nil {
+			album.Tags, err = replaceAlbumTags(tx, userId, album.ID, input.Tags)
+			return err
+		}
+		albums := []models.Album{album}
+		err = attachAlbumTags(tx, albums)
+		album.Tags = albums[0].Tags
+		return err
+	})
+	return album, err
+}
+
+// Delete removes an album of the user together with all of its songs
+func (s *AlbumService) Delete(userId uint, role string, albumId uint) error {
+	if !HasPermission(role, models.PermissionManageAlbums) {
+		return ErrPermissionDenied
+	}
+
+	return s.store.Transaction(func(tx repositories.Store) error {
+		album, err := tx.Albums().FindOwned(userId, albumId)
+		if errors.Is(err, repositories.ErrNotFound) {
+			return ErrAlbumNotFound
+		}
+		if err != nil {
+			return err
+		}
+		return deleteAlbum(tx, album)
+	})
+}
+
+// deleteAlbum deletes an album together with its owner's songs on it
+func deleteAlbum(tx repositories.Store, album models.Album) error {
+	if err := tx.Songs().DeleteByAlbum(album.UserId, album.ID); err != nil {
+		return err
+	}
+	return tx.Albums().Delete(&album)
+}
+
+// Merge moves the songs of a duplicate album of the user into the target album, adds the credits
+// and tags the target lacks, and its genre and cover when the target has none, and deletes the
+// duplicate along with a cover the target didn't take. Moved songs whose disc and track number is
+// taken on the target lose their track number. It returns the target album with its songs, credits and tags.
+func (s *AlbumService) Merge(ctx context.Context, userId uint, role string, albumId uint, targetId uint) (models.Album, error) {
+	if !HasPermission(role, models.PermissionManageAlbums) {
+		return models.Album{}, ErrPermissionDenied
+	}
+	if albumId == targetId {
+		return models.Album{}, ErrMergeSameAlbum
+	}
+
+	// The cover the duplicate leaves behind is only deleted once the merge is committed
+	var orphanedCover string
+	err := s.store.Transaction(func(tx repositories.Store) error {
+		album, err := tx.Albums().FindOwnedWithSongs(userId, albumId)
+		if errors.Is(err, repositories.ErrNotFound) {
+			return ErrAlbumNotFound
+		}
+		if err != nil {
+			return err
+		}
+		target, err := tx.Albums().FindOwnedWithSongs(userId, targetId)
+		if errors.Is(err, repositories.ErrNotFound) {
+			return ErrAlbumNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		credits, err := albumCredits(tx, target.ID)
+		if err != nil {
+			return err
+		}
+		merged, err := albumCredits(tx, album.ID)
+		if err != nil {
+			return err
+		}
+		updated := target
+		updated.Songs = nil
+		var columns []string
+		added := false
+		for _, credit := range merged {
+			if !slices.Contains(credits, credit) {
+				credits = append(credits, credit)
+				added = true
+			}
+		}
+		if added {
+			if err := saveAlbumCredits(tx, target.ID, credits); err != nil {
+				return err
+			}
+			updated.Artist = creditedArtist(credits)
+			columns = append(columns, "artist")
+		}
+
+		tagged, err := tx.Tags().FindAlbumTags([]uint{album.ID})
+		if err != nil {
+			return err
+		}
+		for _, tag := range tagged {
+			if err := tx.Tags().TagAlbums(tag.TagId, []uint{target.ID}); err != nil {
+				return err
+			}
+		}
+		if target.GenreId == nil && album.GenreId != nil {
+			updated.GenreId, updated.Genre = album.GenreId, album.Genre
+			columns = append(columns, "genre", "genre_id")
+		}
+		if album.CoverKey != "" {
+			if target.CoverKey == "" {
+				updated.CoverKey, updated.CoverContentType = album.CoverKey, album.CoverContentType
+				updated.CoverChecksum, updated.CoverUpdatedAt = album.CoverChecksum, album.CoverUpdatedAt
+				columns = append(columns, coverColumns...)
+			} else {
+				orphanedCover = album.CoverKey
+			}
+		}
+		if len(columns) > 0 {
+			if err := tx.Albums().Update(&updated, columns...); err != nil {
+				return err
+			}
+		}
+
+		taken := map[[2]uint]bool{}
+		for _, song := range target.Songs {
+			taken[[2]uint{song.DiscNumber, song.TrackNumber}] = true
+		}
+		for _, song := range album.Songs {
+			if song.TrackNumber > 0 && taken[[2]uint{song.DiscNumber, song.TrackNumber}] {
+				song.TrackNumber = 0
+				if err := tx.Songs().Update(&song, "track_number"); err != nil {
+					return err
+				}
+			}
+		}
+
+		if err := tx.Songs().MoveToAlbum(userId, album.ID, target.ID); err != nil {
+			return err
+		}
+
+		// The deleted duplicate no longer points at the cover, whether the target took it or it goes
+		album.Songs = nil
+		album.CoverKey, album.CoverContentType, album.CoverChecksum, album.CoverUpdatedAt = "", "", "", nil
+		if err := tx.Albums().Update(&album, coverColumns...); err != nil {
+			return err
+		}
+		return tx.Albums().Delete(&album)
+	})
+	if err != nil {
+		return models.Album{}, err
+	}
+	if orphanedCover != "" {
+		deleteCover(ctx, s.blobs, orphanedCover)
+	}
+	return s.Get(userId, targetId)
+}
+
+// Tracklist renumbers and reorders every song on an album of the user at once. Entries without a
+// disc number stay on the disc of the previous entry and entries without a track number follow the
+// previous entry on their disc. It returns the album with its songs and credits.
+func (s *AlbumService) Tracklist(userId uint, role string, albumId uint, input models.TracklistRequest) (models.Album, error) {
+	if !HasPermission(role, models.PermissionManageAlbums) {
+		return models.Album{}, ErrPermissionDenied
+	}
+
+	err := s.store.Transaction(func(tx repositories.Store) error {
+		album, err := tx.Albums().FindOwnedWithSongs(userId, albumId)
+		if errors.Is(err, repositories.ErrNotFound) {
+			return ErrAlbumNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		songs := map[uint]models.Song{}
+		for _, song := range album.Songs {
+			songs[song.ID] = song
+		}
+		if len(input.Tracks) != len(songs) {
+			return ErrTracklistSongs
+		}
+
+		placed := make([]models.Song, 0, len(input.Tracks))
+		taken := map[[2]uint]bool{}
+		last := map[uint]uint{}
+		disc := uint(1)
+		for _, entry := range input.Tracks {
+			song, ok := songs[entry.SongId]
+			if !ok {
+				return ErrTracklistSongs
+			}
+			delete(songs, entry.SongId)
+
+			if entry.DiscNumber != 0 {
+				disc = entry.DiscNumber
+			}
+			track := entry.TrackNumber
+			if track == 0 {
+				track = last[disc] + 1
+			}
+			if taken[[2]uint{disc, track}] {
+				return ErrTracklistPositions
+			}
+			taken[[2]uint{disc, track}] = true
+			last[disc] = track
+
+			song.DiscNumber, song.TrackNumber = disc, track
+			if entry.DiscSubtitle != nil {
+				song.DiscSubtitle = *entry.DiscSubtitle
+			}
+			placed = append(placed, song)
+		}
+
+		// Songs swapping places would collide halfway, so every track number is cleared first
+		for _, song := range album.Songs {
+			if song.TrackNumber == 0 {
+				continue
+			}
+			song.TrackNumber = 0
+			if err := tx.Songs().Update(&song, "track_number"); err != nil {
+				return err
+			}
+		}
+		for i := range placed {
+			if err := tx.Songs().Update(&placed[i], "disc_number", "track_number", "disc_subtitle"); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return models.Album{}, err
+	}
+	return s.Get(userId, albumId)
+}
@@ -0,0 +1,124 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/tushar27x/music-lib-api/artwork"
+	"github.com/tushar27x/music-lib-api/models"
+	"github.com/tushar27x/music-lib-api/repositories"
+	"github.com/tushar27x/music-lib-api/services"
+	"github.com/tushar27x/music-lib-api/storage"
+)
+
+// putCover stores a cover and its thumbnails under key and points the album at them
+func putCover(t *testing.T, store repositories.Store, blobs storage.Storage, album *models.Album, key string) []string {
+	t.Helper()
+	keys := []string{key}
+	for _, size := range artwork.Sizes {
+		keys = append(keys, fmt.Sprintf("%s-%d.jpg", strings.TrimSuffix(key, ".jpg"), size))
+	}
+	for _, key := range keys {
+		if err := blobs.Put(context.Background(), key, strings.NewReader("jpeg"), 4, "image/jpeg"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	album.CoverKey, album.CoverContentType = key, "image/jpeg"
+	if err := store.Albums().Update(album, "cover_key", "cover_content_type"); err != nil {
+		t.Fatal(err)
+	}
+	return keys
+}
+
+// blobExists opens and closes an object, returning the error for a missing one
+func blobExists(blobs storage.Storage, key string) error {
+	r, err := blobs.Get(context.Background(), key)
+	if err != nil {
+		return err
+	}
+	return r.Close()
+}
+
+func TestMergeAlbums(t *testing.T) {
+	store := repositories.NewMemoryStore()
+	blobs, err := storage.NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	userId, _ := createSongs(t, store, "ada@example.com")
+	albums := services.NewAlbumService(store, blobs)
+
+	target := models.Album{Title: "Under Pressure", Artist: "Queen", Year: 1981}
+	duplicate := models.Album{Title: "Under Pressure", Artist: "David Bowie", Year: 1981}
+	for _, album := range []*models.Album{&target, &duplicate} {
+		if err := albums.Create(userId, models.RoleArtist, album); err != nil {
+			t.Fatal(err)
+		}
+	}
+	kept := putCover(t, store, blobs, &target, "covers/target.jpg")
+	orphaned := putCover(t, store, blobs, &duplicate, "covers/duplicate.jpg")
+
+	merged, err := albums.Merge(context.Background(), userId, models.RoleArtist, duplicate.ID, target.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if merged.Artist != "Queen, David Bowie" {
+		t.Errorf("merged artist = %q, want %q", merged.Artist, "Queen, David Bowie")
+	}
+	stored, err := store.Albums().FindOwned(userId, target.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Artist != "Queen, David Bowie" || stored.CoverKey != "covers/target.jpg" {
+		t.Errorf("stored album = %q with cover %q, want Queen, David Bowie with covers/target.jpg", stored.Artist, stored.CoverKey)
+	}
+	if _, err := albums.Get(userId, duplicate.ID); !errors.Is(err, services.ErrAlbumNotFound) {
+		t.Errorf("merged album = %v, want ErrAlbumNotFound", err)
+	}
+
+	for _, key := range kept {
+		if err := blobExists(blobs, key); err != nil {
+			t.Errorf("target cover %s: %v", key, err)
+		}
+	}
+	for _, key := range orphaned {
+		if err := blobExists(blobs, key); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("duplicate cover %s = %v, want ErrNotFound", key, err)
+		}
+	}
+}
+
+func TestMergeAlbumsMovesTheCoverToATargetWithout(t *testing.T) {
+	store := repositories.NewMemoryStore()
+	blobs, err := storage.NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	userId, _ := createSongs(t, store, "ada@example.com")
+	albums := services.NewAlbumService(store, blobs)
+
+	target := models.Album{Title: "Heroes", Artist: "David Bowie", Year: 1977}
+	duplicate := models.Album{Title: "\"Heroes\"", Artist: "David Bowie", Year: 1977}
+	for _, album := range []*models.Album{&target, &duplicate} {
+		if err := albums.Create(userId, models.RoleArtist, album); err != nil {
+			t.Fatal(err)
+		}
+	}
+	moved := putCover(t, store, blobs, &duplicate, "covers/heroes.jpg")
+
+	merged, err := albums.Merge(context.Background(), userId, models.RoleArtist, duplicate.ID, target.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if merged.Artist != "David Bowie" || merged.CoverKey != "covers/heroes.jpg" {
+		t.Errorf("merged album = %q with cover %q, want David Bowie with covers/heroes.jpg", merged.Artist, merged.CoverKey)
+	}
+	for _, key := range moved {
+		if err := blobExists(blobs, key); err != nil {
+			t.Errorf("moved cover %s: %v", key, err)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package services
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/tushar27x/music-lib-api/models"
+	"github.com/tushar27x/music-lib-api/repositories"
+)
+
+var (
+	// ErrAPIKeyNotFound is returned when the user has no API key with the ID
+	ErrAPIKeyNotFound = errors.New("API key not found")
+	// ErrInvalidAPIKey is returned when an API key is unknown, revoked or expired
+	ErrInvalidAPIKey = errors.New("invalid API key")
+	// ErrInvalidScope is returned when an API key is created with a scope that does not exist
+	ErrInvalidScope = errors.New("invalid scope")
+	// ErrAPIKeyExpired is returned when an API key is created with an expiry in the past
+	ErrAPIKeyExpired = errors.New("expiry must be in the future")
+)
+
+// APIKeyPrefix starts every API key, which tells keys and access tokens apart in the Authorization header
+const APIKeyPrefix = "mlk_"
+
+// apiKeyUsageResolution is how precisely last use is recorded; coarser stamps save a write on most requests
+const apiKeyUsageResolution = time.Minute
+
+// APIKeyService manages the personal API keys scripts and CI authenticate with
+type APIKeyService struct {
+	store repositories.Store
+}
+
+// NewAPIKeyService returns an APIKeyService backed by store
+func NewAPIKeyService(store repositories.Store) *APIKeyService {
+	return &APIKeyService{store: store}
+}
+
+// Create generates an API key for the user. The key itself is returned only here; only its
+// hash is stored.
+func (s *APIKeyService) Create(userId uint, input models.APIKeyCreateRequest) (models.APIKey, string, error) {
+	var scopes []string
+	for _, scope := range models.APIKeyScopes {
+		if slices.Contains(input.Scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	for _, scope := range input.Scopes {
+		if !slices.Contains(models.APIKeyScopes, scope) {
+			return models.APIKey{}, "", ErrInvalidScope
+		}
+	}
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(time.Now()) {
+		return models.APIKey{}, "", ErrAPIKeyExpired
+	}
+
+	secret, err := randomString(32)
+	if err != nil {
+		return models.APIKey{}, "", err
+	}
+	rawKey := APIKeyPrefix + secret
+
+	key := models.APIKey{
+		UserId:    userId,
+		Name:      input.Name,
+		Prefix:    rawKey[:len(APIKeyPrefix)+8],
+		KeyHash:   hashToken(rawKey),
+		Scopes:    strings.Join(scopes, " "),
+		ExpiresAt: input.ExpiresAt,
+	}
+	if err := s.store.APIKeys().Create(&key); err != nil {
+		return models.APIKey{}, "", err
+	}
+	return key, rawKey, nil
+}
+
+// List returns the user's API keys, newest first, including revoked and expired ones
+func (s *APIKeyService) List(userId uint) ([]models.APIKey, error) {
+	return s.store.APIKeys().FindByUser(userId)
+}
+
+// Revoke stops one of the user's API keys from working. Revoking a revoked key changes nothing.
+func (s *APIKeyService) Revoke(userId uint, keyId uint) (models.APIKey, error) {
+	var key models.APIKey
+	err := s.store.Transaction(func(tx repositories.Store) error {
+		var err error
+		key, err = revokeAPIKey(tx, userId, keyId)
+		return err
+	})
+	return key, err
+}
+
+// RevokeForUser revokes an API key of any user on behalf of an admin and records it in the audit trail
+func (s *APIKeyService) RevokeForUser(actorId uint, userId uint, keyId uint) (models.APIKey, error) {
+	var key models.APIKey
+	err := s.store.Transaction(func(tx repositories.Store) error {
+		var err error
+		if key, err = revokeAPIKey(tx, userId, keyId); err != nil {
+			return err
+		}
+		return recordAudit(tx, actorId, models.AuditAPIKeyRevoked, models.AuditTargetAPIKey, key.ID, key.Prefix+" ("+key.Name+")")
+	})
+	return key, err
+}
+
+// Authenticate checks an API key and returns it. Its last use is recorded.
+func (s *APIKeyService) Authenticate(rawKey string) (models.APIKey, error) {
+	key, err := s.store.APIKeys().FindByHash(hashToken(rawKey))
+	if err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			return key, ErrInvalidAPIKey
+		}
+		return key, err
+	}
+
+	now := time.Now()
+	if key.RevokedAt != nil || (key.ExpiresAt != nil && now.After(*key.ExpiresAt)) {
+		return key, ErrInvalidAPIKey
+	}
+
+	used := now.Truncate(apiKeyUsageResolution)
+	if key.LastUsedAt == nil || key.LastUsedAt.Before(used) {
+		key.LastUsedAt = &used
+		if err := s.store.APIKeys().Update(&key, "last_used_at"); err != nil {
+			return key, err
+		}
+	}
+	return key, nil
+}
+
+// APIKeyAllows reports whether scopes grant reading, or writing when write is set, every resource
+func APIKeyAllows(scopes []string, write bool, resources ...string) bool {
+	access := ":read"
+	if write {
+		access = ":write"
+	}
+	for _, resource := range resources {
+		if !slices.Contains(scopes, resource+access) {
+			return false
+		}
+	}
+	return true
+}
+
+func revokeAPIKey(tx repositories.Store, userId uint, keyId uint) (models.APIKey, error) {
+	key, err := tx.APIKeys().FindOwned(userId, keyId)
+	if err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			return key, ErrAPIKeyNotFound
+		}
+		return key, err
+	}
+	if key.RevokedAt != nil {
+		return key, nil
+	}
+
+	now := time.Now()
+	key.RevokedAt = &now
+	return key, tx.APIKeys().Update(&key, "revoked_at")
+}
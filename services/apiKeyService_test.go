@@ -0,0 +1,173 @@
+package services_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tushar27x/music-lib-api/models"
+	"github.com/tushar27x/music-lib-api/repositories"
+	"github.com/tushar27x/music-lib-api/services"
+)
+
+func TestCreateAPIKey(t *testing.T) {
+	store := repositories.NewMemoryStore()
+	apiKeys := services.NewAPIKeyService(store)
+
+	key, rawKey, err := apiKeys.Create(1, models.APIKeyCreateRequest{
+		Name:   "Nightly backup",
+		Scopes: []string{models.ScopePlaylistsWrite, models.ScopeSongsRead},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(rawKey, services.APIKeyPrefix) || !strings.HasPrefix(rawKey, key.Prefix) || len(key.Prefix) != len(services.APIKeyPrefix)+8 {
+		t.Errorf("key %q with prefix %q, want mlk_ and the first 8 characters of the secret", rawKey, key.Prefix)
+	}
+	stored, err := store.APIKeys().FindOwned(1, key.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.KeyHash == "" || strings.Contains(stored.KeyHash, strings.TrimPrefix(rawKey, services.APIKeyPrefix)) {
+		t.Errorf("stored hash %q, want a hash that doesn't contain the key", stored.KeyHash)
+	}
+	// Scopes are stored in the order of models.APIKeyScopes
+	if stored.Scopes != "songs:read playlists:write" {
+		t.Errorf("scopes = %q, want songs:read playlists:write", stored.Scopes)
+	}
+
+	if _, _, err := apiKeys.Create(1, models.APIKeyCreateRequest{Name: "x", Scopes: []string{"users:write"}}); !errors.Is(err, services.ErrInvalidScope) {
+		t.Errorf("unknown scope = %v, want ErrInvalidScope", err)
+	}
+	past := time.Now().Add(-time.Hour)
+	if _, _, err := apiKeys.Create(1, models.APIKeyCreateRequest{Name: "x", Scopes: []string{models.ScopeSongsRead}, ExpiresAt: &past}); !errors.Is(err, services.ErrAPIKeyExpired) {
+		t.Errorf("expiry in the past = %v, want ErrAPIKeyExpired", err)
+	}
+}
+
+func TestAuthenticateAPIKey(t *testing.T) {
+	store := repositories.NewMemoryStore()
+	apiKeys := services.NewAPIKeyService(store)
+	create := func() (models.APIKey, string) {
+		t.Helper()
+		key, rawKey, err := apiKeys.Create(1, models.APIKeyCreateRequest{Name: "CI", Scopes: []string{models.ScopeSongsRead}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return key, rawKey
+	}
+
+	key, rawKey := create()
+	authenticated, err := apiKeys.Authenticate(rawKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if authenticated.ID != key.ID {
+		t.Errorf("authenticated key %d, want %d", authenticated.ID, key.ID)
+	}
+	for _, wrong := range []string{services.APIKeyPrefix + "made-up", rawKey + "x", strings.ToUpper(rawKey)} {
+		if _, err := apiKeys.Authenticate(wrong); !errors.Is(err, services.ErrInvalidAPIKey) {
+			t.Errorf("key %q = %v, want ErrInvalidAPIKey", wrong, err)
+		}
+	}
+
+	if _, err := apiKeys.Revoke(2, key.ID); !errors.Is(err, services.ErrAPIKeyNotFound) {
+		t.Errorf("revoking another user's key = %v, want ErrAPIKeyNotFound", err)
+	}
+	if _, err := apiKeys.Revoke(1, key.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := apiKeys.Authenticate(rawKey); !errors.Is(err, services.ErrInvalidAPIKey) {
+		t.Errorf("revoked key = %v, want ErrInvalidAPIKey", err)
+	}
+
+	expiring, expiringRaw := create()
+	expired := time.Now().Add(-time.Second)
+	expiring.ExpiresAt = &expired
+	if err := store.APIKeys().Update(&expiring, "expires_at"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := apiKeys.Authenticate(expiringRaw); !errors.Is(err, services.ErrInvalidAPIKey) {
+		t.Errorf("expired key = %v, want ErrInvalidAPIKey", err)
+	}
+}
+
+func TestAPIKeyLastUseIsRecordedByTheMinute(t *testing.T) {
+	store := repositories.NewMemoryStore()
+	apiKeys := services.NewAPIKeyService(store)
+	key, rawKey, err := apiKeys.Create(1, models.APIKeyCreateRequest{Name: "CI", Scopes: []string{models.ScopeSongsRead}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lastUsed := func() time.Time {
+		t.Helper()
+		stored, err := store.APIKeys().FindOwned(1, key.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stored.LastUsedAt == nil {
+			t.Fatal("last use not recorded")
+		}
+		return *stored.LastUsedAt
+	}
+	setLastUsed := func(at time.Time) {
+		t.Helper()
+		key.LastUsedAt = &at
+		if err := store.APIKeys().Update(&key, "last_used_at"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Stay clear of a minute boundary so every use below falls in the same minute
+	if left := time.Until(time.Now().Truncate(time.Minute).Add(time.Minute)); left < 5*time.Second {
+		time.Sleep(left)
+	}
+	minute := time.Now().Truncate(time.Minute)
+	if _, err := apiKeys.Authenticate(rawKey); err != nil {
+		t.Fatal(err)
+	}
+	if got := lastUsed(); !got.Equal(minute) {
+		t.Errorf("last used = %v, want %v", got, minute)
+	}
+
+	// A stamp within the current minute is left alone; this one could not have come from Authenticate
+	withinMinute := minute.Add(30 * time.Second)
+	setLastUsed(withinMinute)
+	if _, err := apiKeys.Authenticate(rawKey); err != nil {
+		t.Fatal(err)
+	}
+	if got := lastUsed(); !got.Equal(withinMinute) {
+		t.Errorf("last used = %v, want it left at %v", got, withinMinute)
+	}
+
+	setLastUsed(minute.Add(-2 * time.Minute))
+	if _, err := apiKeys.Authenticate(rawKey); err != nil {
+		t.Fatal(err)
+	}
+	if got := lastUsed(); !got.Equal(minute) {
+		t.Errorf("last used = %v, want it moved to %v", got, minute)
+	}
+}
+
+func TestAPIKeyAllows(t *testing.T) {
+	scopes := []string{models.ScopeSongsRead, models.ScopeSongsWrite, models.ScopeAlbumsRead}
+	tests := []struct {
+		write     bool
+		resources []string
+		want      bool
+	}{
+		{false, []string{models.ResourceSongs}, true},
+		{true, []string{models.ResourceSongs}, true},
+		{false, []string{models.ResourceAlbums}, true},
+		{true, []string{models.ResourceAlbums}, false},
+		{false, []string{models.ResourceSongs, models.ResourceAlbums}, true},
+		{true, []string{models.ResourceSongs, models.ResourceAlbums}, false},
+		{false, []string{models.ResourcePlaylists}, false},
+	}
+	for _, tt := range tests {
+		if got := services.APIKeyAllows(scopes, tt.write, tt.resources...); got != tt.want {
+			t.Errorf("APIKeyAllows(write %v, %v) = %v, want %v", tt.write, tt.resources, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,344 @@
+package services
+
+import (
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/tushar27x/music-lib-api/models"
+	"github.com/tushar27x/music-lib-api/repositories"
+)
+
+var (
+	// ErrArtistNotFound is returned when an artist does not exist or belongs to someone else
+	ErrArtistNotFound = errors.New("artist not found")
+	// ErrArtistNameTaken is returned when the user already has an artist of that name
+	ErrArtistNameTaken = errors.New("artist name is already taken")
+	// ErrArtistNameRequired is returned for an artist name that is empty once trimmed
+	ErrArtistNameRequired = errors.New("artist name is required")
+	// ErrArtistInUse is returned when deleting an artist that albums or songs still credit
+	ErrArtistInUse = errors.New("artist is still credited")
+	// ErrInvalidCredit is returned for a credit without an artist ID or name
+	ErrInvalidCredit = errors.New("credits need an artist_id or a name")
+	// ErrInvalidCreditRole is returned for a credit role that does not exist
+	ErrInvalidCreditRole = errors.New("credit role must be primary, featured, composer or producer")
+)
+
+// ArtistService holds the rules for a user's artists and the credits of their albums and songs.
+// The artist text of albums and songs is kept as the names of their primary artists, so existing
+// search, smart playlists and exports keep working on it.
+type ArtistService struct {
+	store repositories.Store
+}
+
+// NewArtistService returns an ArtistService keeping artists in store
+func NewArtistService(store repositories.Store) *ArtistService {
+	return &ArtistService{store: store}
+}
+
+// Create adds an artist to the user's library
+func (s *ArtistService) Create(userId uint, input models.ArtistCreateRequest) (models.Artist, error) {
+	artist := models.Artist{Name: strings.TrimSpace(input.Name), UserId: userId}
+	if artist.Name == "" {
+		return artist, ErrArtistNameRequired
+	}
+
+	err := s.store.Artists().Create(&artist)
+	if errors.Is(err, repositories.ErrDuplicate) {
+		return artist, ErrArtistNameTaken
+	}
+	return artist, err
+}
+
+// List returns the user's artists in name order
+func (s *ArtistService) List(userId uint) ([]models.Artist, error) {
+	return s.store.Artists().FindByUser(userId)
+}
+
+// Get returns an artist of the user
+func (s *ArtistService) Get(userId uint, artistId uint) (models.Artist, error) {
+	artist, err := s.store.Artists().FindOwned(userId, artistId)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return artist, ErrArtistNotFound
+	}
+	return artist, err
+}
+
+// Update renames an artist of the user and the artist text of the albums and songs crediting
+// them as primary artist
+func (s *ArtistService) Update(userId uint, artistId uint, input models.ArtistCreateRequest) (models.Artist, error) {
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		return models.Artist{}, ErrArtistNameRequired
+	}
+
+	var artist models.Artist
+	err := s.store.Transaction(func(tx repositories.Store) error {
+		found, err := tx.Artists().FindOwned(userId, artistId)
+		if errors.Is(err, repositories.ErrNotFound) {
+			return ErrArtistNotFound
+		}
+		if err != nil {
+			return err
+		}
+		artist = found
+
+		artist.Name = name
+		err = tx.Artists().Update(&artist, "name")
+		if errors.Is(err, repositories.ErrDuplicate) {
+			return ErrArtistNameTaken
+		}
+		if err != nil {
+			return err
+		}
+
+		albums, err := tx.Albums().FindByArtist(userId, artist.ID, models.CreditPrimary)
+		if err != nil {
+			return err
+		}
+		for _, album := range albums {
+			credits, err := albumCredits(tx, album.ID)
+			if err != nil {
+				return err
+			}
+			album.Songs = nil
+			album.Artist = creditedArtist(credits)
+			if err := tx.Albums().Update(&album, "artist"); err != nil {
+				return err
+			}
+		}
+
+		songs, err := tx.Songs().FindByArtist(userId, artist.ID, models.CreditPrimary)
+		if err != nil {
+			return err
+		}
+		for _, song := range songs {
+			credits, err := songCredits(tx, song.ID)
+			if err != nil {
+				return err
+			}
+			song.Artist = creditedArtist(credits)
+			if err := tx.Songs().Update(&song, "artist"); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return artist, err
+}
+
+// Delete removes an artist of the user that no album or song credits any more. Credits on deleted
+// albums and songs go with the artist.
+func (s *ArtistService) Delete(userId uint, artistId uint) error {
+	return s.store.Transaction(func(tx repositories.Store) error {
+		artist, err := tx.Artists().FindOwned(userId, artistId)
+		if errors.Is(err, repositories.ErrNotFound) {
+			return ErrArtistNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		credited, err := tx.Artists().CountCredits(artist.ID)
+		if err != nil {
+			return err
+		}
+		if credited > 0 {
+			return ErrArtistInUse
+		}
+		return tx.Artists().Delete(&artist)
+	})
+}
+
+// Albums returns the user's albums crediting the artist, in any role or in the given one, by year
+func (s *ArtistService) Albums(userId uint, artistId uint, role string) ([]models.Album, error) {
+	if role != "" && !slices.Contains(models.CreditRoles, role) {
+		return nil, ErrInvalidCreditRole
+	}
+	if _, err := s.Get(userId, artistId); err != nil {
+		return nil, err
+	}
+
+	albums, err := s.store.Albums().FindByArtist(userId, artistId, role)
+	if err != nil {
+		return nil, err
+	}
+	if err := attachAlbumCredits(s.store, albums); err != nil {
+		return nil, err
+	}
+	return albums, attachAlbumTags(s.store, albums)
+}
+
+// Songs returns the user's songs crediting the artist, in any role or in the given one
+func (s *ArtistService) Songs(userId uint, artistId uint, role string) ([]models.Song, error) {
+	if role != "" && !slices.Contains(models.CreditRoles, role) {
+		return nil, ErrInvalidCreditRole
+	}
+	if _, err := s.Get(userId, artistId); err != nil {
+		return nil, err
+	}
+
+	songs, err := s.store.Songs().FindByArtist(userId, artistId, role)
+	if err != nil {
+		return nil, err
+	}
+	if err := attachSongCredits(s.store, songs); err != nil {
+		return nil, err
+	}
+	return songs, attachSongTags(s.store, songs)
+}
+
+// resolveCredits returns the credits to store for an album or song. Requested credits replace the
+// current ones; when none are requested, the current credits in other roles than primary are kept.
+// Unless a primary artist is requested, the artist text is credited as primary artist. Artists are
+// given by ID or by name, and an artist named for the first time is created, so names differing
+// only in case end up as one artist.
+func resolveCredits(tx repositories.Store, userId uint, artist string, requested []models.ArtistCredit, current []models.ArtistCredit) ([]models.ArtistCredit, error) {
+	if requested == nil {
+		for _, credit := range current {
+			if credit.Role != models.CreditPrimary {
+				requested = append(requested, credit)
+			}
+		}
+	}
+
+	hasPrimary := slices.ContainsFunc(requested, func(credit models.ArtistCredit) bool {
+		return credit.Role == models.CreditPrimary
+	})
+	if name := strings.TrimSpace(artist); !hasPrimary && name != "" {
+		requested = append([]models.ArtistCredit{{Name: name, Role: models.CreditPrimary}}, requested...)
+	}
+
+	credits := []models.ArtistCredit{}
+	for _, credit := range requested {
+		if !slices.Contains(models.CreditRoles, credit.Role) {
+			return nil, ErrInvalidCreditRole
+		}
+
+		var found models.Artist
+		var err error
+		switch name := strings.TrimSpace(credit.Name); {
+		case credit.ArtistId != 0:
+			found, err = tx.Artists().FindOwned(userId, credit.ArtistId)
+			if errors.Is(err, repositories.ErrNotFound) {
+				return nil, ErrArtistNotFound
+			}
+		case name != "":
+			found, err = findOrCreateArtist(tx, userId, name)
+		default:
+			return nil, ErrInvalidCredit
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		resolved := models.ArtistCredit{ArtistId: found.ID, Name: found.Name, Role: credit.Role}
+		if !slices.Contains(credits, resolved) {
+			credits = append(credits, resolved)
+		}
+	}
+	return credits, nil
+}
+
+// findOrCreateArtist returns the user's artist with the name, ignoring case, creating it when there is none
+func findOrCreateArtist(tx repositories.Store, userId uint, name string) (models.Artist, error) {
+	artist, err := tx.Artists().FindByName(userId, name)
+	if !errors.Is(err, repositories.ErrNotFound) {
+		return artist, err
+	}
+
+	artist = models.Artist{Name: name, UserId: userId}
+	err = tx.Artists().Create(&artist)
+	// Another request created the artist first
+	if errors.Is(err, repositories.ErrDuplicate) {
+		return artist, ErrArtistNameTaken
+	}
+	return artist, err
+}
+
+// creditedArtist returns the artist text of credits: the names of the primary artists
+func creditedArtist(credits []models.ArtistCredit) string {
+	var names []string
+	for _, credit := range credits {
+		if credit.Role == models.CreditPrimary {
+			names = append(names, credit.Name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+func albumCredits(store repositories.Store, albumId uint) ([]models.ArtistCredit, error) {
+	stored, err := store.Artists().FindAlbumCredits([]uint{albumId})
+	credits := make([]models.ArtistCredit, len(stored))
+	for i, credit := range stored {
+		credits[i] = models.ArtistCredit{ArtistId: credit.ArtistId, Name: credit.Name, Role: credit.Role}
+	}
+	return credits, err
+}
+
+func saveAlbumCredits(tx repositories.Store, albumId uint, credits []models.ArtistCredit) error {
+	stored := make([]models.AlbumCredit, len(credits))
+	for i, credit := range credits {
+		stored[i] = models.AlbumCredit{AlbumId: albumId, ArtistId: credit.ArtistId, Role: credit.Role, Position: i}
+	}
+	return tx.Artists().SetAlbumCredits(albumId, stored)
+}
+
+// attachAlbumCredits fills in the credits of albums
+func attachAlbumCredits(store repositories.Store, albums []models.Album) error {
+	ids := make([]uint, len(albums))
+	for i, album := range albums {
+		ids[i] = album.ID
+	}
+	stored, err := store.Artists().FindAlbumCredits(ids)
+	if err != nil {
+		return err
+	}
+
+	byAlbum := map[uint][]models.ArtistCredit{}
+	for _, credit := range stored {
+		byAlbum[credit.AlbumId] = append(byAlbum[credit.AlbumId], models.ArtistCredit{ArtistId: credit.ArtistId, Name: credit.Name, Role: credit.Role})
+	}
+	for i := range albums {
+		albums[i].Credits = byAlbum[albums[i].ID]
+	}
+	return nil
+}
+
+func songCredits(store repositories.Store, songId uint) ([]models.ArtistCredit, error) {
+	stored, err := store.Artists().FindSongCredits([]uint{songId})
+	credits := make([]models.ArtistCredit, len(stored))
+	for i, credit := range stored {
+		credits[i] = models.ArtistCredit{ArtistId: credit.ArtistId, Name: credit.Name, Role: credit.Role}
+	}
+	return credits, err
+}
+
+func saveSongCredits(tx repositories.Store, songId uint, credits []models.ArtistCredit) error {
+	stored := make([]models.SongCredit, len(credits))
+	for i, credit := range credits {
+		stored[i] = models.SongCredit{SongId: songId, ArtistId: credit.ArtistId, Role: credit.Role, Position: i}
+	}
+	return tx.Artists().SetSongCredits(songId, stored)
+}
+
+// attachSongCredits fills in the credits of songs
+func attachSongCredits(store repositories.Store, songs []models.Song) error {
+	ids := make([]uint, len(songs))
+	for i, song := range songs {
+		ids[i] = song.ID
+	}
+	stored, err := store.Artists().FindSongCredits(ids)
+	if err != nil {
+		return err
+	}
+
+	bySong := map[uint][]models.ArtistCredit{}
+	for _, credit := range stored {
+		bySong[credit.SongId] = append(bySong[credit.SongId], models.ArtistCredit{ArtistId: credit.ArtistId, Name: credit.Name, Role: credit.Role})
+	}
+	for i := range songs {
+		songs[i].Credits = bySong[songs[i].ID]
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/tushar27x/music-lib-api/models"
+)
+
+// ErrNotAudio is returned when an uploaded file is not a recognised audio format
+var ErrNotAudio = errors.New("file is not a supported audio format")
+
+// sniffLength is how many leading bytes are inspected to detect the format
+const sniffLength = 64
+
+// audioFormat describes a supported audio container
+type audioFormat struct {
+	ContentType string
+	Extension   string
+}
+
+// DetectAudioFormat identifies an audio container from its magic bytes
+func DetectAudioFormat(header []byte) (audioFormat, bool) {
+	switch {
+	case bytes.HasPrefix(header, []byte("ID3")):
+		return audioFormat{"audio/mpeg", ".mp3"}, true
+	case bytes.HasPrefix(header, []byte("fLaC")):
+		return audioFormat{"audio/flac", ".flac"}, true
+	case bytes.HasPrefix(header, []byte("OggS")):
+		if bytes.Contains(header, []byte("OpusHead")) {
+			return audioFormat{"audio/opus", ".opus"}, true
+		}
+		return audioFormat{"audio/ogg", ".ogg"}, true
+	case len(header) >= 12 && bytes.Equal(header[0:4], []byte("RIFF")) && bytes.Equal(header[8:12], []byte("WAVE")):
+		return audioFormat{"audio/wav", ".wav"}, true
+	case len(header) >= 12 && bytes.Equal(header[0:4], []byte("FORM")) &&
+		(bytes.Equal(header[8:12], []byte("AIFF")) || bytes.Equal(header[8:12], []byte("AIFC"))):
+		return audioFormat{"audio/aiff", ".aiff"}, true
+	case len(header) >= 12 && bytes.Equal(header[4:8], []byte("ftyp")):
+		// MP4 family; only accept brands used for audio
+		switch string(header[8:12]) {
+		case "M4A ", "M4B ", "M4P ", "mp42", "isom", "dash":
+			return audioFormat{"audio/mp4", ".m4a"}, true
+		}
+	case len(header) >= 4 && bytes.Equal(header[0:4], []byte{0x1A, 0x45, 0xDF, 0xA3}):
+		return audioFormat{"audio/webm", ".webm"}, true
+	case len(header) >= 2 && header[0] == 0xFF && header[1]&0xF6 == 0xF0:
+		// ADTS AAC sync word (layer bits are always 00)
+		return audioFormat{"audio/aac", ".aac"}, true
+	case len(header) >= 2 && header[0] == 0xFF && header[1]&0xE0 == 0xE0 && header[1]&0x06 != 0:
+		// MPEG audio frame sync without an ID3 tag
+		return audioFormat{"audio/mpeg", ".mp3"}, true
+	}
+	return audioFormat{}, false
+}
+
+// StoreAudio validates an uploaded file, writes it to blob storage and records it on the song
+func (s *SongService) StoreAudio(ctx context.Context, song *models.Song, file io.ReadSeeker, size int64) error {
+	header := make([]byte, sniffLength)
+	n, err := io.ReadFull(file, header)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		if errors.Is(err, io.EOF) {
+			return ErrNotAudio
+		}
+		return err
+	}
+
+	format, ok := DetectAudioFormat(header[:n])
+	if !ok {
+		return ErrNotAudio
+	}
+
+	// Hash the whole file first so the checksum can be part of the storage key
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, file); err != nil {
+		return err
+	}
+	checksum := hex.EncodeToString(hasher.Sum(nil))
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	key := fmt.Sprintf("audio/%d/%d/%s%s", song.UserId, song.ID, checksum, format.Extension)
+	if err := s.blobs.Put(ctx, key, file, size, format.ContentType); err != nil {
+		return err
+	}
+
+	previousKey := song.AudioKey
+	now := time.Now()
+	song.AudioKey = key
+	song.AudioContentType = format.ContentType
+	song.AudioSize = size
+	song.AudioChecksum = checksum
+	song.AudioUploadedAt = &now
+	if err := s.store.Songs().Update(song, "audio_key", "audio_content_type", "audio_size", "audio_checksum", "audio_uploaded_at"); err != nil {
+		// Don't leave an orphaned object behind
+		if key != previousKey {
+			s.blobs.Delete(ctx, key)
+		}
+		return err
+	}
+
+	// The old file is no longer referenced once the song points at the new one
+	if previousKey != "" && previousKey != key {
+		if err := s.blobs.Delete(ctx, previousKey); err != nil {
+			log.Printf("Warning: failed to delete replaced audio %s: %v", previousKey, err)
+		}
+	}
+
+	return nil
+}
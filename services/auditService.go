@@ -0,0 +1,36 @@
+package services
+
+import (
+	"github.com/tushar27x/music-lib-api/models"
+	"github.com/tushar27x/music-lib-api/repositories"
+)
+
+// AuditService reads the audit trail of administrative actions
+type AuditService struct {
+	store repositories.Store
+}
+
+// NewAuditService returns an AuditService backed by store
+func NewAuditService(store repositories.Store) *AuditService {
+	return &AuditService{store: store}
+}
+
+// List returns the entries matching filter, newest first, and how many match in total
+func (s *AuditService) List(filter repositories.AuditFilter, limit int, offset int) ([]models.AuditLog, int64, error) {
+	return s.store.Audit().List(filter, limit, offset)
+}
+
+// recordAudit adds an entry to the audit trail, normally in the transaction of the action itself.
+// An actorId of 0 stands for the command line.
+func recordAudit(tx repositories.Store, actorId uint, action string, targetType string, targetId uint, details string) error {
+	entry := models.AuditLog{
+		Action:     action,
+		TargetType: targetType,
+		TargetId:   targetId,
+		Details:    details,
+	}
+	if actorId != 0 {
+		entry.ActorId = &actorId
+	}
+	return tx.Audit().Create(&entry)
+}
@@ -0,0 +1,209 @@
+package services
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tushar27x/music-lib-api/models"
+	"github.com/tushar27x/music-lib-api/repositories"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	// ErrUserNotFound is returned when no account matches; login reports ErrInvalidCredentials instead
+	ErrUserNotFound = errors.New("user not found")
+	// ErrInvalidCredentials is returned when the password does not match the account
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	// ErrEmailTaken is returned when registering an email that already has an account
+	ErrEmailTaken = errors.New("email is already registered")
+	// ErrRoleNotAllowed is returned when registering with a role that only an admin can assign
+	ErrRoleNotAllowed = errors.New("role cannot be chosen at registration")
+	// ErrAccountSuspended is returned when a suspended user logs in or uses a token
+	ErrAccountSuspended = errors.New("account is suspended")
+	// ErrPasswordResetRequired is returned when an admin requires the user to change their password first
+	ErrPasswordResetRequired = errors.New("password change required")
+)
+
+// dummyPasswordHash is checked against when there is no password to check, so a login for an
+// unknown email takes as long as one with a wrong password
+var dummyPasswordHash = sync.OnceValue(func() []byte {
+	hashed, _ := bcrypt.GenerateFromPassword([]byte("not a real password"), bcrypt.DefaultCost)
+	return hashed
+})
+
+// AuthService registers users, logs them in and authenticates their requests
+type AuthService struct {
+	store    repositories.Store
+	tokens   *TokenService
+	apiKeys  *APIKeyService
+	throttle *LoginThrottleService
+}
+
+// NewAuthService returns an AuthService reading users from store, issuing tokens with tokens,
+// accepting the API keys of apiKeys and slowing down failed logins with throttle
+func NewAuthService(store repositories.Store, tokens *TokenService, apiKeys *APIKeyService, throttle *LoginThrottleService) *AuthService {
+	return &AuthService{store: store, tokens: tokens, apiKeys: apiKeys, throttle: throttle}
+}
+
+// selfServiceRoles are the roles anyone may register with
+var selfServiceRoles = []string{models.RoleListener, models.RoleArtist}
+
+// Register hashes the user's password and creates the account. Only listener and artist can be
+// chosen; every other role is assigned by an admin.
+func (s *AuthService) Register(input models.UserRegisterRequest) (models.User, error) {
+	role := input.Role
+	if role == "" {
+		role = models.RoleListener
+	}
+	if !slices.Contains(selfServiceRoles, role) {
+		return models.User{}, ErrRoleNotAllowed
+	}
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	user := models.User{
+		Name:     input.Name,
+		Email:    input.Email,
+		Password: string(hashed),
+		Role:     role,
+	}
+	err = s.store.Users().Create(&user)
+	if errors.Is(err, repositories.ErrDuplicate) {
+		return user, ErrEmailTaken
+	}
+	return user, err
+}
+
+// Login checks the credentials and starts a new session. For accounts with two-factor
+// authentication no tokens are issued; a challenge is returned instead, which
+// TwoFactorService.Verify exchanges for tokens together with a code.
+//
+// Unknown emails and wrong passwords both return ErrInvalidCredentials after the same amount of
+// work and count as failures of the email and of ip. While either is locked out, a
+// *LoginLockedError is returned without checking the password.
+func (s *AuthService) Login(email string, password string, ip string) (models.TokenResponse, *models.MFAChallengeResponse, error) {
+	if err := s.throttle.Check(email, ip); err != nil {
+		return models.TokenResponse{}, nil, err
+	}
+
+	user, err := s.store.Users().FindByEmail(email)
+	if err != nil && !errors.Is(err, repositories.ErrNotFound) {
+		return models.TokenResponse{}, nil, err
+	}
+
+	// Accounts without a password, created by an identity provider login, get the same treatment
+	known := err == nil && user.Password != ""
+	hashed := dummyPasswordHash()
+	if known {
+		hashed = []byte(user.Password)
+	}
+	if bcrypt.CompareHashAndPassword(hashed, []byte(password)) != nil || !known {
+		if err := s.throttle.RecordFailure(email, ip); err != nil {
+			return models.TokenResponse{}, nil, err
+		}
+		return models.TokenResponse{}, nil, ErrInvalidCredentials
+	}
+
+	// With two-factor authentication the failures are forgotten once the code is right too
+	if user.TOTPEnabledAt == nil {
+		if err := s.throttle.RecordSuccess(email); err != nil {
+			return models.TokenResponse{}, nil, err
+		}
+	}
+	if user.SuspendedAt != nil {
+		return models.TokenResponse{}, nil, ErrAccountSuspended
+	}
+	if RequireEmailVerification() && user.EmailVerifiedAt == nil {
+		return models.TokenResponse{}, nil, ErrEmailNotVerified
+	}
+
+	return startSession(s.tokens, user)
+}
+
+// ChangePassword replaces the user's password after checking the current one and clears a
+// required reset. Every refresh token of the user is revoked and a new session is started.
+func (s *AuthService) ChangePassword(userId uint, current string, next string) (models.TokenResponse, error) {
+	user, err := s.store.Users().FindByID(userId)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return models.TokenResponse{}, ErrUserNotFound
+	}
+	if err != nil {
+		return models.TokenResponse{}, err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(current)); err != nil {
+		return models.TokenResponse{}, ErrInvalidCredentials
+	}
+	hashed, err := bcrypt.GenerateFromPassword([]byte(next), bcrypt.DefaultCost)
+	if err != nil {
+		return models.TokenResponse{}, err
+	}
+
+	err = s.store.Transaction(func(tx repositories.Store) error {
+		user.Password = string(hashed)
+		user.PasswordResetRequired = false
+		if err := tx.Users().Update(&user, "password", "password_reset_required"); err != nil {
+			return err
+		}
+		return tx.Tokens().RevokeUserRefreshTokens(user.ID, time.Now())
+	})
+	if err != nil {
+		return models.TokenResponse{}, err
+	}
+	return s.tokens.IssueTokens(user)
+}
+
+// Authenticate verifies an access token or an API key and makes sure its user still exists and
+// is not suspended. The role comes from the account rather than the token, so role changes apply
+// to tokens that were already issued. When the user has to change their password, the claims are
+// returned together with ErrPasswordResetRequired.
+func (s *AuthService) Authenticate(tokenString string) (*AccessClaims, error) {
+	var claims *AccessClaims
+	if strings.HasPrefix(tokenString, APIKeyPrefix) {
+		key, err := s.apiKeys.Authenticate(tokenString)
+		if err != nil {
+			return nil, err
+		}
+		claims = &AccessClaims{UserId: key.UserId, APIKeyId: key.ID, Scopes: strings.Fields(key.Scopes)}
+	} else {
+		var err error
+		if claims, err = s.tokens.ParseAccessToken(tokenString); err != nil {
+			return nil, err
+		}
+	}
+
+	user, err := s.store.Users().FindByID(claims.UserId)
+	if err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	if user.SuspendedAt != nil {
+		return nil, ErrAccountSuspended
+	}
+
+	claims.Role = user.Role
+	if user.PasswordResetRequired {
+		return claims, ErrPasswordResetRequired
+	}
+	return claims, nil
+}
+
+// startSession issues tokens to a user who proved who they are, or a challenge for a second
+// factor when the account has two-factor authentication
+func startSession(tokens *TokenService, user models.User) (models.TokenResponse, *models.MFAChallengeResponse, error) {
+	if user.TOTPEnabledAt != nil {
+		challenge, err := tokens.IssueMFAChallenge(user)
+		return models.TokenResponse{}, &challenge, err
+	}
+
+	issued, err := tokens.IssueTokens(user)
+	return issued, nil, err
+}
@@ -0,0 +1,491 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/tushar27x/music-lib-api/artwork"
+	"github.com/tushar27x/music-lib-api/config"
+	"github.com/tushar27x/music-lib-api/metadata"
+	"github.com/tushar27x/music-lib-api/models"
+	"github.com/tushar27x/music-lib-api/repositories"
+	"github.com/tushar27x/music-lib-api/storage"
+)
+
+var (
+	// ErrNotImage is returned when an uploaded cover is not a JPEG, PNG or WebP image
+	ErrNotImage = errors.New("file is not a JPEG, PNG or WebP image")
+	// ErrImageTooLarge is returned when an uploaded cover is wider or taller than artwork.MaxDimension
+	ErrImageTooLarge = fmt.Errorf("image is larger than %dx%d pixels", artwork.MaxDimension, artwork.MaxDimension)
+	// ErrNoCover is returned when an album or playlist has no cover to show or remove
+	ErrNoCover = errors.New("no cover image")
+	// ErrNoEmbeddedCover is returned when no audio file on an album embeds usable cover art
+	ErrNoEmbeddedCover = errors.New("no embedded cover art found")
+	// ErrSongNotOnAlbum is returned when cover art is taken from a song that is not on the album
+	ErrSongNotOnAlbum = errors.New("song is not on this album")
+)
+
+// coverColumns are the columns recording the cover of an album or playlist
+var coverColumns = []string{"cover_key", "cover_content_type", "cover_checksum", "cover_updated_at"}
+
+// mosaicTiles is how many album covers make up the mosaic of a playlist
+const mosaicTiles = 4
+
+// MaxCoverSize returns the largest cover upload in bytes (MAX_COVER_SIZE, default 10 MB)
+func MaxCoverSize() int64 {
+	return config.GetEnvInt64("MAX_COVER_SIZE", 10<<20)
+}
+
+// CoverService stores the cover images of albums and playlists with their thumbnails
+type CoverService struct {
+	store repositories.Store
+	blobs storage.Storage
+}
+
+// NewCoverService returns a CoverService recording covers in store and keeping the images in blobs
+func NewCoverService(store repositories.Store, blobs storage.Storage) *CoverService {
+	return &CoverService{store: store, blobs: blobs}
+}
+
+// Cover is a cover image ready to be served; the image is only loaded, or rendered, when read
+type Cover struct {
+	ContentType string
+	ETag        string
+	// ModTime is zero for playlist mosaics, whose age says nothing about the covers in them
+	ModTime time.Time
+	read    func() ([]byte, error)
+}
+
+// Read loads the image
+func (c Cover) Read() ([]byte, error) {
+	return c.read()
+}
+
+// storedCover is an image written by storeCover
+type storedCover struct {
+	key         string
+	contentType string
+	checksum    string
+	uploadedAt  time.Time
+}
+
+// SetAlbumCover replaces the cover of an album of the user with an uploaded image
+func (s *CoverService) SetAlbumCover(ctx context.Context, userId uint, role string, albumId uint, data []byte) error {
+	if !HasPermission(role, models.PermissionManageAlbums) {
+		return ErrPermissionDenied
+	}
+	album, err := s.ownedAlbum(userId, albumId)
+	if err != nil {
+		return err
+	}
+	return s.setAlbumCover(ctx, &album, data)
+}
+
+// DeleteAlbumCover removes the cover of an album of the user
+func (s *CoverService) DeleteAlbumCover(ctx context.Context, userId uint, role string, albumId uint) error {
+	if !HasPermission(role, models.PermissionManageAlbums) {
+		return ErrPermissionDenied
+	}
+	album, err := s.ownedAlbum(userId, albumId)
+	if err != nil {
+		return err
+	}
+	if album.CoverKey == "" {
+		return ErrNoCover
+	}
+
+	previousKey := album.CoverKey
+	album.CoverKey, album.CoverContentType, album.CoverChecksum, album.CoverUpdatedAt = "", "", "", nil
+	if err := s.store.Albums().Update(&album, coverColumns...); err != nil {
+		return err
+	}
+	s.deleteCover(ctx, previousKey)
+	return nil
+}
+
+// AlbumCover returns the cover of an album of the user, or its thumbnail of the given size unless size is 0
+func (s *CoverService) AlbumCover(ctx context.Context, userId uint, albumId uint, size int) (Cover, error) {
+	album, err := s.ownedAlbum(userId, albumId)
+	if err != nil {
+		return Cover{}, err
+	}
+	if album.CoverKey == "" {
+		return Cover{}, ErrNoCover
+	}
+	return s.cover(ctx, album.CoverKey, album.CoverContentType, album.CoverChecksum, album.CoverUpdatedAt, size), nil
+}
+
+// ExtractAlbumCover makes the cover art embedded in the audio of a song the cover of its album.
+// Without a song the songs of the album are tried in tracklist order.
+func (s *CoverService) ExtractAlbumCover(ctx context.Context, userId uint, role string, albumId uint, songId uint) error {
+	if !HasPermission(role, models.PermissionManageAlbums) {
+		return ErrPermissionDenied
+	}
+	album, err := s.store.Albums().FindOwnedWithSongs(userId, albumId)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return ErrAlbumNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	songs := album.Songs
+	if songId != 0 {
+		index := -1
+		for i, song := range songs {
+			if song.ID == songId {
+				index = i
+			}
+		}
+		if index < 0 {
+			return ErrSongNotOnAlbum
+		}
+		songs = songs[index : index+1]
+	}
+
+	for _, song := range songs {
+		picture, err := s.embeddedPicture(ctx, song)
+		if err != nil {
+			return err
+		}
+		if picture == nil {
+			continue
+		}
+		err = s.setAlbumCover(ctx, &album, picture.Data)
+		// Pictures in formats we don't take, like GIF or BMP, are passed over
+		if songId == 0 && (errors.Is(err, ErrNotImage) || errors.Is(err, ErrImageTooLarge)) {
+			continue
+		}
+		return err
+	}
+	return ErrNoEmbeddedCover
+}
+
+// CoverFromAudio gives the album of a song the cover art embedded in its audio when the album has
+// no cover yet, and reports whether it did
+func (s *CoverService) CoverFromAudio(ctx context.Context, userId uint, role string, song models.Song) (bool, error) {
+	if song.AlbumId == nil || song.AudioKey == "" || !HasPermission(role, models.PermissionManageAlbums) {
+		return false, nil
+	}
+	album, err := s.ownedAlbum(userId, *song.AlbumId)
+	if errors.Is(err, ErrAlbumNotFound) {
+		return false, nil
+	}
+	if err != nil || album.CoverKey != "" {
+		return false, err
+	}
+
+	picture, err := s.embeddedPicture(ctx, song)
+	if err != nil || picture == nil {
+		return false, err
+	}
+	err = s.setAlbumCover(ctx, &album, picture.Data)
+	if errors.Is(err, ErrNotImage) || errors.Is(err, ErrImageTooLarge) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// SetPlaylistCover replaces the cover of a playlist of the user with an uploaded image
+func (s *CoverService) SetPlaylistCover(ctx context.Context, userId uint, playlistId uint, data []byte) error {
+	playlist, err := s.ownedPlaylist(userId, playlistId)
+	if err != nil {
+		return err
+	}
+
+	stored, err := s.storeCover(ctx, fmt.Sprintf("covers/playlists/%d/%d", playlist.UserId, playlist.ID), data)
+	if err != nil {
+		return err
+	}
+
+	previousKey := playlist.CoverKey
+	playlist.CoverKey, playlist.CoverContentType, playlist.CoverChecksum, playlist.CoverUpdatedAt = stored.key, stored.contentType, stored.checksum, &stored.uploadedAt
+	if err := s.store.Playlists().Update(&playlist, coverColumns...); err != nil {
+		// Don't leave orphaned objects behind
+		if stored.key != previousKey {
+			s.deleteCover(ctx, stored.key)
+		}
+		return err
+	}
+	if previousKey != "" && previousKey != stored.key {
+		s.deleteCover(ctx, previousKey)
+	}
+	return nil
+}
+
+// DeletePlaylistCover removes the uploaded cover of a playlist of the user, which then shows a mosaic again
+func (s *CoverService) DeletePlaylistCover(ctx context.Context, userId uint, playlistId uint) error {
+	playlist, err := s.ownedPlaylist(userId, playlistId)
+	if err != nil {
+		return err
+	}
+	if playlist.CoverKey == "" {
+		return ErrNoCover
+	}
+
+	previousKey := playlist.CoverKey
+	playlist.CoverKey, playlist.CoverContentType, playlist.CoverChecksum, playlist.CoverUpdatedAt = "", "", "", nil
+	if err := s.store.Playlists().Update(&playlist, coverColumns...); err != nil {
+		return err
+	}
+	s.deleteCover(ctx, previousKey)
+	return nil
+}
+
+// PlaylistCover returns the uploaded cover of a loaded playlist. Without one it is a mosaic of the covers
+// of the first four albums on the playlist, or the cover of the first album when fewer have one.
+func (s *CoverService) PlaylistCover(ctx context.Context, playlist models.Playlist, size int) (Cover, error) {
+	if playlist.CoverKey != "" {
+		return s.cover(ctx, playlist.CoverKey, playlist.CoverContentType, playlist.CoverChecksum, playlist.CoverUpdatedAt, size), nil
+	}
+
+	albums, err := s.coveredAlbums(playlist)
+	if err != nil {
+		return Cover{}, err
+	}
+	if len(albums) == 0 {
+		return Cover{}, ErrNoCover
+	}
+	if len(albums) < mosaicTiles {
+		first := albums[0]
+		return s.cover(ctx, first.CoverKey, first.CoverContentType, first.CoverChecksum, first.CoverUpdatedAt, size), nil
+	}
+
+	if size == 0 {
+		size = artwork.Sizes[len(artwork.Sizes)-1]
+	}
+	tileSize := artwork.Sizes[len(artwork.Sizes)-1]
+	for _, candidate := range artwork.Sizes {
+		if candidate >= size/2 {
+			tileSize = candidate
+			break
+		}
+	}
+
+	// The mosaic changes with the covers in it, so they make up its ETag
+	hasher := sha256.New()
+	for _, album := range albums {
+		fmt.Fprintf(hasher, "%s\n", album.CoverChecksum)
+	}
+	fmt.Fprintf(hasher, "%d", size)
+
+	return Cover{
+		ContentType: artwork.JPEG.ContentType,
+		ETag:        "mosaic-" + hex.EncodeToString(hasher.Sum(nil)),
+		read: func() ([]byte, error) {
+			tiles := make([]image.Image, 0, len(albums))
+			for _, album := range albums {
+				data, err := s.readThumbnail(ctx, album.CoverKey, tileSize)
+				if err != nil {
+					return nil, err
+				}
+				tile, _, err := artwork.Decode(data)
+				if err != nil {
+					return nil, err
+				}
+				tiles = append(tiles, tile)
+			}
+			return artwork.Encode(artwork.Mosaic(tiles, size))
+		},
+	}, nil
+}
+
+// coveredAlbums returns the first albums on the playlist that have a cover, in playlist order
+func (s *CoverService) coveredAlbums(playlist models.Playlist) ([]models.Album, error) {
+	var albums []models.Album
+	seen := map[uint]bool{}
+	for _, song := range playlist.Tracks() {
+		if song.AlbumId == nil || seen[*song.AlbumId] {
+			continue
+		}
+		seen[*song.AlbumId] = true
+
+		album, err := s.store.Albums().FindOwned(playlist.UserId, *song.AlbumId)
+		if errors.Is(err, repositories.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if album.CoverKey != "" {
+			albums = append(albums, album)
+			if len(albums) == mosaicTiles {
+				break
+			}
+		}
+	}
+	return albums, nil
+}
+
+func (s *CoverService) setAlbumCover(ctx context.Context, album *models.Album, data []byte) error {
+	stored, err := s.storeCover(ctx, fmt.Sprintf("covers/albums/%d/%d", album.UserId, album.ID), data)
+	if err != nil {
+		return err
+	}
+
+	previousKey := album.CoverKey
+	album.CoverKey, album.CoverContentType, album.CoverChecksum, album.CoverUpdatedAt = stored.key, stored.contentType, stored.checksum, &stored.uploadedAt
+	if err := s.store.Albums().Update(album, coverColumns...); err != nil {
+		// Don't leave orphaned objects behind
+		if stored.key != previousKey {
+			s.deleteCover(ctx, stored.key)
+		}
+		return err
+	}
+	if previousKey != "" && previousKey != stored.key {
+		s.deleteCover(ctx, previousKey)
+	}
+	return nil
+}
+
+// storeCover checks that data is an image we can decode, then writes it below prefix together with
+// a JPEG thumbnail for each of artwork.Sizes
+func (s *CoverService) storeCover(ctx context.Context, prefix string, data []byte) (storedCover, error) {
+	img, format, err := artwork.Decode(data)
+	if errors.Is(err, artwork.ErrTooLarge) {
+		return storedCover{}, ErrImageTooLarge
+	}
+	if err != nil {
+		return storedCover{}, ErrNotImage
+	}
+
+	sum := sha256.Sum256(data)
+	stored := storedCover{
+		contentType: format.ContentType,
+		checksum:    hex.EncodeToString(sum[:]),
+		uploadedAt:  time.Now(),
+	}
+	stored.key = fmt.Sprintf("%s/%s%s", prefix, stored.checksum, format.Extension)
+
+	if err := s.blobs.Put(ctx, stored.key, bytes.NewReader(data), int64(len(data)), format.ContentType); err != nil {
+		return storedCover{}, err
+	}
+	for _, size := range artwork.Sizes {
+		thumbnail, err := artwork.Encode(artwork.Thumbnail(img, size))
+		if err == nil {
+			err = s.blobs.Put(ctx, thumbnailKey(stored.key, size), bytes.NewReader(thumbnail), int64(len(thumbnail)), artwork.JPEG.ContentType)
+		}
+		if err != nil {
+			s.deleteCover(ctx, stored.key)
+			return storedCover{}, err
+		}
+	}
+	return stored, nil
+}
+
+// cover describes a stored cover, or its thumbnail of the given size unless size is 0
+func (s *CoverService) cover(ctx context.Context, key string, contentType string, checksum string, uploadedAt *time.Time, size int) Cover {
+	cover := Cover{ContentType: contentType, ETag: checksum}
+	if uploadedAt != nil {
+		cover.ModTime = *uploadedAt
+	}
+	if size == 0 {
+		cover.read = func() ([]byte, error) { return s.readBlob(ctx, key) }
+		return cover
+	}
+
+	cover.ContentType = artwork.JPEG.ContentType
+	cover.ETag = fmt.Sprintf("%s-%d", checksum, size)
+	cover.read = func() ([]byte, error) { return s.readThumbnail(ctx, key, size) }
+	return cover
+}
+
+// readThumbnail loads a thumbnail of the cover stored under key, rendering and storing it
+// if it is missing, e.g. because it is of a size added after the cover was uploaded
+func (s *CoverService) readThumbnail(ctx context.Context, key string, size int) ([]byte, error) {
+	data, err := s.readBlob(ctx, thumbnailKey(key, size))
+	if !errors.Is(err, storage.ErrNotFound) {
+		return data, err
+	}
+
+	original, err := s.readBlob(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	img, _, err := artwork.Decode(original)
+	if err != nil {
+		return nil, err
+	}
+	if data, err = artwork.Encode(artwork.Thumbnail(img, size)); err != nil {
+		return nil, err
+	}
+	if err := s.blobs.Put(ctx, thumbnailKey(key, size), bytes.NewReader(data), int64(len(data)), artwork.JPEG.ContentType); err != nil {
+		log.Printf("Warning: failed to store thumbnail of %s: %v", key, err)
+	}
+	return data, nil
+}
+
+func (s *CoverService) readBlob(ctx context.Context, key string) ([]byte, error) {
+	body, err := s.blobs.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return io.ReadAll(body)
+}
+
+func (s *CoverService) deleteCover(ctx context.Context, key string) {
+	deleteCover(ctx, s.blobs, key)
+}
+
+// deleteCover removes a cover and its thumbnails from blobs; failures only leave unreferenced objects behind
+func deleteCover(ctx context.Context, blobs storage.Storage, key string) {
+	keys := []string{key}
+	for _, size := range artwork.Sizes {
+		keys = append(keys, thumbnailKey(key, size))
+	}
+	for _, key := range keys {
+		if err := blobs.Delete(ctx, key); err != nil {
+			log.Printf("Warning: failed to delete cover %s: %v", key, err)
+		}
+	}
+}
+
+// embeddedPicture reads the cover art embedded in the audio of a song, if any
+func (s *CoverService) embeddedPicture(ctx context.Context, song models.Song) (*metadata.Picture, error) {
+	if song.AudioKey == "" {
+		return nil, nil
+	}
+	reader := storage.NewRangeReader(ctx, s.blobs, song.AudioKey, song.AudioSize)
+	defer reader.Close()
+
+	tags, err := metadata.Read(reader, song.AudioSize)
+	if errors.Is(err, metadata.ErrUnsupported) || errors.Is(err, metadata.ErrMalformed) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return tags.Picture, nil
+}
+
+func (s *CoverService) ownedAlbum(userId uint, albumId uint) (models.Album, error) {
+	album, err := s.store.Albums().FindOwned(userId, albumId)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return album, ErrAlbumNotFound
+	}
+	return album, err
+}
+
+func (s *CoverService) ownedPlaylist(userId uint, playlistId uint) (models.Playlist, error) {
+	playlist, err := s.store.Playlists().FindOwned(userId, playlistId)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return playlist, ErrPlaylistNotFound
+	}
+	return playlist, err
+}
+
+// thumbnailKey returns the key of the thumbnail of the given size next to the cover stored under key
+func thumbnailKey(key string, size int) string {
+	base := key[:strings.LastIndex(key, ".")]
+	return fmt.Sprintf("%s-%d%s", base, size, artwork.JPEG.Extension)
+}
@@ -0,0 +1,340 @@
+package services
+
+import (
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/tushar27x/music-lib-api/models"
+	"github.com/tushar27x/music-lib-api/repositories"
+)
+
+var (
+	// ErrGenreNotFound is returned when a genre does not exist or belongs to someone else
+	ErrGenreNotFound = errors.New("genre not found")
+	// ErrGenreNameTaken is returned when the user already has a genre of that name
+	ErrGenreNameTaken = errors.New("genre name is already taken")
+	// ErrGenreNameRequired is returned for a genre name that is empty once trimmed
+	ErrGenreNameRequired = errors.New("genre name is required")
+	// ErrGenreInUse is returned when deleting a genre that has subgenres or that albums or songs are still in
+	ErrGenreInUse = errors.New("genre still has subgenres, albums or songs")
+	// ErrParentGenreNotFound is returned when the parent of a genre does not exist or belongs to someone else
+	ErrParentGenreNotFound = errors.New("parent genre not found")
+	// ErrGenreCycle is returned when a genre would become its own ancestor
+	ErrGenreCycle = errors.New("a genre can't be moved under itself or one of its subgenres")
+	// ErrNoLibraryItems is returned for a bulk change that names no songs and no albums
+	ErrNoLibraryItems = errors.New("song_ids or album_ids is required")
+)
+
+// GenreService holds the rules for a user's genre taxonomy. Every album and song is in at most one
+// genre, and a genre covers its subgenres when searching. The genre text of albums and songs is kept
+// as the name of their genre, so existing search, smart playlists and exports keep working on it.
+type GenreService struct {
+	store repositories.Store
+}
+
+// NewGenreService returns a GenreService keeping genres in store
+func NewGenreService(store repositories.Store) *GenreService {
+	return &GenreService{store: store}
+}
+
+// Create adds a genre to the user's taxonomy, under a parent genre when one is given
+func (s *GenreService) Create(userId uint, input models.GenreCreateRequest) (models.Genre, error) {
+	genre := models.Genre{Name: strings.TrimSpace(input.Name), ParentId: input.ParentId, UserId: userId}
+	if genre.Name == "" {
+		return genre, ErrGenreNameRequired
+	}
+	if genre.ParentId != nil {
+		if _, err := s.store.Genres().FindOwned(userId, *genre.ParentId); err != nil {
+			if errors.Is(err, repositories.ErrNotFound) {
+				return genre, ErrParentGenreNotFound
+			}
+			return genre, err
+		}
+	}
+
+	err := s.store.Genres().Create(&genre)
+	if errors.Is(err, repositories.ErrDuplicate) {
+		return genre, ErrGenreNameTaken
+	}
+	return genre, err
+}
+
+// List returns the user's genres in name order
+func (s *GenreService) List(userId uint) ([]models.Genre, error) {
+	return s.store.Genres().FindByUser(userId)
+}
+
+// Tree returns the user's top-level genres with their subgenres, each level in name order
+func (s *GenreService) Tree(userId uint) ([]models.GenreNode, error) {
+	genres, err := s.store.Genres().FindByUser(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	// Top-level genres are kept under 0, which no genre has as ID
+	children := map[uint][]models.Genre{}
+	for _, genre := range genres {
+		var parentId uint
+		if genre.ParentId != nil {
+			parentId = *genre.ParentId
+		}
+		children[parentId] = append(children[parentId], genre)
+	}
+
+	var build func(parentId uint) []models.GenreNode
+	build = func(parentId uint) []models.GenreNode {
+		nodes := []models.GenreNode{}
+		for _, genre := range children[parentId] {
+			nodes = append(nodes, models.GenreNode{Genre: genre, Children: build(genre.ID)})
+		}
+		return nodes
+	}
+	return build(0), nil
+}
+
+// Get returns a genre of the user
+func (s *GenreService) Get(userId uint, genreId uint) (models.Genre, error) {
+	genre, err := s.store.Genres().FindOwned(userId, genreId)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return genre, ErrGenreNotFound
+	}
+	return genre, err
+}
+
+// Update renames a genre of the user and moves it under another parent, or to the top level when
+// no parent is given. Albums and songs in the genre show the new name.
+func (s *GenreService) Update(userId uint, genreId uint, input models.GenreCreateRequest) (models.Genre, error) {
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		return models.Genre{}, ErrGenreNameRequired
+	}
+
+	var genre models.Genre
+	err := s.store.Transaction(func(tx repositories.Store) error {
+		found, err := tx.Genres().FindOwned(userId, genreId)
+		if errors.Is(err, repositories.ErrNotFound) {
+			return ErrGenreNotFound
+		}
+		if err != nil {
+			return err
+		}
+		genre = found
+
+		if input.ParentId != nil {
+			if err := checkGenreParent(tx, userId, genre.ID, *input.ParentId); err != nil {
+				return err
+			}
+		}
+
+		renamed := genre.Name != name
+		genre.Name = name
+		genre.ParentId = input.ParentId
+		err = tx.Genres().Update(&genre, "name", "parent_id")
+		if errors.Is(err, repositories.ErrDuplicate) {
+			return ErrGenreNameTaken
+		}
+		if err != nil || !renamed {
+			return err
+		}
+		return tx.Genres().RenameUses(genre.ID, genre.Name)
+	})
+	return genre, err
+}
+
+// Delete removes a genre of the user without subgenres that no album or song is in any more.
+// Deleted albums and songs in the genre lose it.
+func (s *GenreService) Delete(userId uint, genreId uint) error {
+	return s.store.Transaction(func(tx repositories.Store) error {
+		genre, err := tx.Genres().FindOwned(userId, genreId)
+		if errors.Is(err, repositories.ErrNotFound) {
+			return ErrGenreNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		uses, err := tx.Genres().CountUses(genre.ID)
+		if err != nil {
+			return err
+		}
+		if uses > 0 {
+			return ErrGenreInUse
+		}
+		return tx.Genres().Delete(&genre)
+	})
+}
+
+// Assign puts songs and albums of the user in a genre, taking them out of the genre they were in.
+// Changing albums needs the permission to manage albums.
+func (s *GenreService) Assign(userId uint, role string, genreId uint, input models.GenreAssignRequest) error {
+	return s.store.Transaction(func(tx repositories.Store) error {
+		genre, err := tx.Genres().FindOwned(userId, genreId)
+		if errors.Is(err, repositories.ErrNotFound) {
+			return ErrGenreNotFound
+		}
+		if err != nil {
+			return err
+		}
+		return setLibraryGenre(tx, userId, role, input.SongIds, input.AlbumIds, &genre, false)
+	})
+}
+
+// Unassign takes songs and albums of the user out of a genre; those in another genre are left alone.
+// Changing albums needs the permission to manage albums.
+func (s *GenreService) Unassign(userId uint, role string, genreId uint, input models.GenreAssignRequest) error {
+	return s.store.Transaction(func(tx repositories.Store) error {
+		genre, err := tx.Genres().FindOwned(userId, genreId)
+		if errors.Is(err, repositories.ErrNotFound) {
+			return ErrGenreNotFound
+		}
+		if err != nil {
+			return err
+		}
+		return setLibraryGenre(tx, userId, role, input.SongIds, input.AlbumIds, &genre, true)
+	})
+}
+
+// setLibraryGenre puts the songs and albums in the genre or, with remove, takes those in it out
+func setLibraryGenre(tx repositories.Store, userId uint, role string, songIds []uint, albumIds []uint, genre *models.Genre, remove bool) error {
+	songs, albums, err := libraryItems(tx, userId, role, songIds, albumIds)
+	if err != nil {
+		return err
+	}
+
+	genreId, name := genreFields(genre)
+	if remove {
+		genreId, name = genreFields(nil)
+	}
+	for _, song := range songs {
+		// Assigning skips those already in the genre and removing skips those outside it
+		if inGenre := song.GenreId != nil && *song.GenreId == genre.ID; inGenre != remove {
+			continue
+		}
+		song.GenreId, song.Genre = genreId, name
+		if err := tx.Songs().Update(&song, "genre", "genre_id"); err != nil {
+			return err
+		}
+	}
+	for _, album := range albums {
+		if inGenre := album.GenreId != nil && *album.GenreId == genre.ID; inGenre != remove {
+			continue
+		}
+		album.GenreId, album.Genre = genreId, name
+		if err := tx.Albums().Update(&album, "genre", "genre_id"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// libraryItems loads the distinct songs and albums of the user named by a bulk change. Changing
+// albums needs the permission to manage albums.
+func libraryItems(tx repositories.Store, userId uint, role string, songIds []uint, albumIds []uint) ([]models.Song, []models.Album, error) {
+	if len(songIds) == 0 && len(albumIds) == 0 {
+		return nil, nil, ErrNoLibraryItems
+	}
+	if len(albumIds) > 0 && !HasPermission(role, models.PermissionManageAlbums) {
+		return nil, nil, ErrPermissionDenied
+	}
+
+	var songs []models.Song
+	for _, songId := range distinctIds(songIds) {
+		song, err := tx.Songs().FindOwned(userId, songId)
+		if errors.Is(err, repositories.ErrNotFound) {
+			return nil, nil, ErrSongNotFound
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		songs = append(songs, song)
+	}
+
+	var albums []models.Album
+	for _, albumId := range distinctIds(albumIds) {
+		album, err := tx.Albums().FindOwned(userId, albumId)
+		if errors.Is(err, repositories.ErrNotFound) {
+			return nil, nil, ErrAlbumNotFound
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		albums = append(albums, album)
+	}
+	return songs, albums, nil
+}
+
+// checkGenreParent checks that parentId is a genre of the user other than genreId and its subgenres
+func checkGenreParent(tx repositories.Store, userId uint, genreId uint, parentId uint) error {
+	seen := map[uint]bool{}
+	for id := &parentId; id != nil; {
+		if *id == genreId {
+			return ErrGenreCycle
+		}
+		// The taxonomy has no cycles, but don't loop forever should one slip in
+		if seen[*id] {
+			return nil
+		}
+		seen[*id] = true
+
+		parent, err := tx.Genres().FindOwned(userId, *id)
+		if errors.Is(err, repositories.ErrNotFound) {
+			return ErrParentGenreNotFound
+		}
+		if err != nil {
+			return err
+		}
+		id = parent.ParentId
+	}
+	return nil
+}
+
+// resolveGenre returns the genre of an album or song: the user's genre with genreId when given,
+// otherwise the genre named name, which is created at the top level if the user has none. It
+// returns nil when neither is given.
+func resolveGenre(tx repositories.Store, userId uint, genreId *uint, name string) (*models.Genre, error) {
+	if genreId != nil {
+		genre, err := tx.Genres().FindOwned(userId, *genreId)
+		if errors.Is(err, repositories.ErrNotFound) {
+			return nil, ErrGenreNotFound
+		}
+		return &genre, err
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, nil
+	}
+	genre, err := tx.Genres().FindByName(userId, name)
+	if !errors.Is(err, repositories.ErrNotFound) {
+		return &genre, err
+	}
+
+	genre = models.Genre{Name: name, UserId: userId}
+	err = tx.Genres().Create(&genre)
+	// Another request created the genre first
+	if errors.Is(err, repositories.ErrDuplicate) {
+		return nil, ErrGenreNameTaken
+	}
+	return &genre, err
+}
+
+// genreFields returns the genre ID and genre text of an album or song in the genre
+func genreFields(genre *models.Genre) (*uint, string) {
+	if genre == nil {
+		return nil, ""
+	}
+	id := genre.ID
+	return &id, genre.Name
+}
+
+// distinctIds returns the IDs without repeats, in the order they were first given
+func distinctIds(ids []uint) []uint {
+	distinct := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if !slices.Contains(distinct, id) {
+			distinct = append(distinct, id)
+		}
+	}
+	return distinct
+}
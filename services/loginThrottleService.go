@@ -0,0 +1,213 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/tushar27x/music-lib-api/config"
+	"github.com/tushar27x/music-lib-api/models"
+	"github.com/tushar27x/music-lib-api/repositories"
+)
+
+var (
+	// ErrTooManyLoginAttempts is returned while an account or IP address is locked out after failed logins
+	ErrTooManyLoginAttempts = errors.New("too many failed login attempts")
+	// ErrLockoutNotFound is returned when unlocking a login counter that does not exist
+	ErrLockoutNotFound = errors.New("lockout not found")
+)
+
+// LoginLockedError is ErrTooManyLoginAttempts together with the time logins are accepted again
+type LoginLockedError struct {
+	Until time.Time
+}
+
+func (e *LoginLockedError) Error() string {
+	return ErrTooManyLoginAttempts.Error()
+}
+
+func (e *LoginLockedError) Unwrap() error {
+	return ErrTooManyLoginAttempts
+}
+
+// LoginBackoffBase returns the first backoff delay, which doubles with every further failure (LOGIN_BACKOFF_BASE, default 1s)
+func LoginBackoffBase() time.Duration {
+	return config.GetEnvDuration("LOGIN_BACKOFF_BASE", time.Second)
+}
+
+// LoginLockoutDuration returns how long a lockout lasts and how long failures are remembered (LOGIN_LOCKOUT_DURATION, default 15m)
+func LoginLockoutDuration() time.Duration {
+	return config.GetEnvDuration("LOGIN_LOCKOUT_DURATION", 15*time.Minute)
+}
+
+// loginPolicy says after how many failures logins are slowed down and after how many they are locked out
+type loginPolicy struct {
+	backoffAfter int64
+	lockoutAfter int64
+}
+
+// loginPolicyFor returns the policy of accounts (LOGIN_BACKOFF_AFTER, default 3, and LOGIN_LOCKOUT_AFTER,
+// default 10) or of IP addresses, which many users may share (LOGIN_IP_BACKOFF_AFTER, default 10, and
+// LOGIN_IP_LOCKOUT_AFTER, default 50)
+func loginPolicyFor(kind string) loginPolicy {
+	if kind == models.LoginAttemptIP {
+		return loginPolicy{
+			backoffAfter: config.GetEnvInt64("LOGIN_IP_BACKOFF_AFTER", 10),
+			lockoutAfter: config.GetEnvInt64("LOGIN_IP_LOCKOUT_AFTER", 50),
+		}
+	}
+	return loginPolicy{
+		backoffAfter: config.GetEnvInt64("LOGIN_BACKOFF_AFTER", 3),
+		lockoutAfter: config.GetEnvInt64("LOGIN_LOCKOUT_AFTER", 10),
+	}
+}
+
+// lockedUntil returns when logins are accepted again after the given number of failures, or nil
+// when they are not slowed down yet
+func (p loginPolicy) lockedUntil(failures int64, now time.Time) *time.Time {
+	lockout := LoginLockoutDuration()
+	var delay time.Duration
+	switch {
+	case failures >= p.lockoutAfter:
+		delay = lockout
+	case failures >= p.backoffAfter:
+		// Capping the shift keeps the delay from overflowing before it is capped by the lockout
+		delay = min(LoginBackoffBase()<<min(failures-p.backoffAfter, 30), lockout)
+	default:
+		return nil
+	}
+	until := now.Add(delay)
+	return &until
+}
+
+// LoginThrottleService counts failed logins per account and per IP address. After a few failures
+// every further attempt has to wait twice as long as the one before, and after more of them logins
+// are locked out for a while. Accounts are counted by email address, so unknown addresses are
+// throttled exactly like registered ones.
+type LoginThrottleService struct {
+	store repositories.Store
+}
+
+// NewLoginThrottleService returns a LoginThrottleService keeping its counters in store
+func NewLoginThrottleService(store repositories.Store) *LoginThrottleService {
+	return &LoginThrottleService{store: store}
+}
+
+// Check returns a *LoginLockedError while the email or the IP address is locked
+func (s *LoginThrottleService) Check(email string, ip string) error {
+	now := time.Now()
+	var until time.Time
+	for kind, target := range loginTargets(email, ip) {
+		attempt, err := s.store.LoginAttempts().Find(kind, target)
+		if errors.Is(err, repositories.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if attempt.LockedUntil != nil && attempt.LockedUntil.After(now) && attempt.LockedUntil.After(until) {
+			until = *attempt.LockedUntil
+		}
+	}
+	if !until.IsZero() {
+		return &LoginLockedError{Until: until}
+	}
+	return nil
+}
+
+// RecordFailure counts a failed login for the email and the IP address. Failures older than
+// LoginLockoutDuration are forgotten.
+func (s *LoginThrottleService) RecordFailure(email string, ip string) error {
+	now := time.Now()
+	for kind, target := range loginTargets(email, ip) {
+		err := s.store.Transaction(func(tx repositories.Store) error {
+			return recordLoginFailure(tx, kind, target, now)
+		})
+		// A concurrent first failure created the counter, so count on it
+		if errors.Is(err, repositories.ErrDuplicate) {
+			err = s.store.Transaction(func(tx repositories.Store) error {
+				return recordLoginFailure(tx, kind, target, now)
+			})
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RecordSuccess forgets the failures of the email after a login. Failures of the IP address are
+// kept, so logging in to one's own account does not buy more guesses at others.
+func (s *LoginThrottleService) RecordSuccess(email string) error {
+	return forgetLoginFailures(s.store, email)
+}
+
+// ListLocked returns the accounts and IP addresses that are locked right now
+func (s *LoginThrottleService) ListLocked(kind string, limit int, offset int) ([]models.LoginAttempt, int64, error) {
+	return s.store.LoginAttempts().ListLocked(kind, time.Now(), limit, offset)
+}
+
+// Unlock clears a lockout and the failures behind it
+func (s *LoginThrottleService) Unlock(actorId uint, id uint) (models.LoginAttempt, error) {
+	var attempt models.LoginAttempt
+	err := s.store.Transaction(func(tx repositories.Store) error {
+		found, err := tx.LoginAttempts().FindByID(id)
+		if errors.Is(err, repositories.ErrNotFound) {
+			return ErrLockoutNotFound
+		}
+		if err != nil {
+			return err
+		}
+		attempt = found
+
+		if err := tx.LoginAttempts().Delete(attempt.ID); err != nil {
+			return err
+		}
+		return recordAudit(tx, actorId, models.AuditLoginUnlocked, models.AuditTargetLoginAttempt, attempt.ID, attempt.Kind+" "+attempt.Target)
+	})
+	return attempt, err
+}
+
+// forgetLoginFailures deletes the failure counter of the email, which also lifts its lockout
+func forgetLoginFailures(store repositories.Store, email string) error {
+	attempt, err := store.LoginAttempts().Find(models.LoginAttemptAccount, normalizeLoginEmail(email))
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return store.LoginAttempts().Delete(attempt.ID)
+}
+
+// recordLoginFailure counts one failure on the locked counter, so failures at the same time are all counted
+func recordLoginFailure(tx repositories.Store, kind string, target string, now time.Time) error {
+	attempt, err := tx.LoginAttempts().Lock(kind, target)
+	if errors.Is(err, repositories.ErrNotFound) {
+		attempt = models.LoginAttempt{Kind: kind, Target: target}
+	} else if err != nil {
+		return err
+	}
+
+	locked := attempt.LockedUntil != nil && attempt.LockedUntil.After(now)
+	if !locked && now.Sub(attempt.LastFailedAt) > LoginLockoutDuration() {
+		attempt.Failures = 0
+	}
+	attempt.Failures++
+	attempt.LastFailedAt = now
+	attempt.LockedUntil = loginPolicyFor(kind).lockedUntil(int64(attempt.Failures), now)
+	return tx.LoginAttempts().Save(&attempt)
+}
+
+// loginTargets returns what a login is counted for; an empty IP address is not counted
+func loginTargets(email string, ip string) map[string]string {
+	targets := map[string]string{models.LoginAttemptAccount: normalizeLoginEmail(email)}
+	if ip != "" {
+		targets[models.LoginAttemptIP] = ip
+	}
+	return targets
+}
+
+func normalizeLoginEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,185 @@
+package services_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tushar27x/music-lib-api/config"
+	"github.com/tushar27x/music-lib-api/migrations"
+	"github.com/tushar27x/music-lib-api/models"
+	"github.com/tushar27x/music-lib-api/repositories"
+	"github.com/tushar27x/music-lib-api/services"
+)
+
+// throttlePolicy sets the account policy the throttle tests count against
+func throttlePolicy(t *testing.T) {
+	t.Setenv("LOGIN_BACKOFF_BASE", "1s")
+	t.Setenv("LOGIN_BACKOFF_AFTER", "3")
+	t.Setenv("LOGIN_LOCKOUT_AFTER", "6")
+	t.Setenv("LOGIN_LOCKOUT_DURATION", "15m")
+}
+
+// lockedFor returns how much longer the email is locked, or 0 when it is not
+func lockedFor(t *testing.T, throttle *services.LoginThrottleService, email string) time.Duration {
+	t.Helper()
+	err := throttle.Check(email, "")
+	var locked *services.LoginLockedError
+	if errors.As(err, &locked) {
+		return time.Until(locked.Until)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return 0
+}
+
+func TestLoginThrottleBacksOffAndLocksOut(t *testing.T) {
+	throttlePolicy(t)
+	throttle := services.NewLoginThrottleService(repositories.NewMemoryStore())
+
+	// Failures below LOGIN_BACKOFF_AFTER don't slow logins down; then the delay doubles up to the lockout
+	for failures, want := range []time.Duration{0, 0, time.Second, 2 * time.Second, 4 * time.Second, 15 * time.Minute, 15 * time.Minute} {
+		if err := throttle.RecordFailure("Ada@Example.com ", "10.0.0.1"); err != nil {
+			t.Fatal(err)
+		}
+		got := lockedFor(t, throttle, "ada@example.com")
+		if got > want || got < want-time.Second {
+			t.Errorf("after %d failures locked for %v, want %v", failures+1, got, want)
+		}
+	}
+
+	// The IP address has its own, higher thresholds
+	if err := throttle.Check("grace@example.com", "10.0.0.1"); err != nil {
+		t.Errorf("other account from the same IP address = %v, want nil", err)
+	}
+
+	if err := throttle.RecordSuccess("ada@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if got := lockedFor(t, throttle, "ada@example.com"); got != 0 {
+		t.Errorf("locked for %v after a successful login, want 0", got)
+	}
+}
+
+func TestLoginThrottleForgetsOldFailures(t *testing.T) {
+	throttlePolicy(t)
+	store := repositories.NewMemoryStore()
+	throttle := services.NewLoginThrottleService(store)
+	for range 6 {
+		if err := throttle.RecordFailure("ada@example.com", ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Move the lockout into the past, as if LOGIN_LOCKOUT_DURATION had gone by
+	attempt, err := store.LoginAttempts().Find(models.LoginAttemptAccount, "ada@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired := time.Now().Add(-time.Minute)
+	attempt.LastFailedAt = expired.Add(-15 * time.Minute)
+	attempt.LockedUntil = &expired
+	if err := store.LoginAttempts().Save(&attempt); err != nil {
+		t.Fatal(err)
+	}
+	if got := lockedFor(t, throttle, "ada@example.com"); got != 0 {
+		t.Fatalf("locked for %v after the lockout ended, want 0", got)
+	}
+
+	if err := throttle.RecordFailure("ada@example.com", ""); err != nil {
+		t.Fatal(err)
+	}
+	attempt, err = store.LoginAttempts().Find(models.LoginAttemptAccount, "ada@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attempt.Failures != 1 || attempt.LockedUntil != nil {
+		t.Errorf("counter after the lockout = %d failures locked until %v, want 1 and not locked", attempt.Failures, attempt.LockedUntil)
+	}
+}
+
+func TestLoginThrottleCountsParallelFailures(t *testing.T) {
+	throttlePolicy(t)
+	for name, store := range throttleStores(t) {
+		t.Run(name, func(t *testing.T) {
+			throttle := services.NewLoginThrottleService(store)
+			const guesses = 20
+			var wg sync.WaitGroup
+			errs := make(chan error, guesses)
+			for range guesses {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					errs <- throttle.RecordFailure("ada@example.com", "10.0.0.1")
+				}()
+			}
+			wg.Wait()
+			close(errs)
+			for err := range errs {
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			for kind, target := range map[string]string{models.LoginAttemptAccount: "ada@example.com", models.LoginAttemptIP: "10.0.0.1"} {
+				attempt, err := store.LoginAttempts().Find(kind, target)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if attempt.Failures != guesses {
+					t.Errorf("%s counted %d failures, want %d", kind, attempt.Failures, guesses)
+				}
+			}
+		})
+	}
+}
+
+// throttleStores returns the stores the parallel failures are counted on: memory, SQLite and,
+// when TEST_POSTGRES_URI is a postgres:// URL, a schema of its own in that database, where
+// transactions really run at the same time
+func throttleStores(t *testing.T) map[string]repositories.Store {
+	t.Helper()
+	stores := map[string]repositories.Store{
+		"memory": repositories.NewMemoryStore(),
+		"sqlite": repositories.NewGormStore(openSQLite(t)),
+	}
+
+	uri := os.Getenv("TEST_POSTGRES_URI")
+	if !strings.HasPrefix(uri, "postgres://") && !strings.HasPrefix(uri, "postgresql://") {
+		return stores
+	}
+	admin, err := config.OpenDB(uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema := fmt.Sprintf("throttle_%d", time.Now().UnixNano())
+	if err := admin.Exec("CREATE SCHEMA " + schema).Error; err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		admin.Exec("DROP SCHEMA " + schema + " CASCADE")
+	})
+
+	separator := "?"
+	if strings.Contains(uri, "?") {
+		separator = "&"
+	}
+	db, err := config.OpenDB(uri + separator + "search_path=" + schema + ",public")
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrator, err := migrations.New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(0); err != nil {
+		t.Fatal(err)
+	}
+	stores["postgres"] = repositories.NewGormStore(db)
+	return stores
+}
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"errors"
+
+	"github.com/tushar27x/music-lib-api/models"
+	"github.com/tushar27x/music-lib-api/repositories"
+)
+
+// Kinds of records moderators can remove, list when deleted and restore
+const (
+	RecordTypeSongs     = "songs"
+	RecordTypeAlbums    = "albums"
+	RecordTypePlaylists = "playlists"
+)
+
+// RecordTypes lists every kind of record that can be removed and restored
+var RecordTypes = []string{RecordTypeSongs, RecordTypeAlbums, RecordTypePlaylists}
+
+var (
+	// ErrUnknownRecordType is returned for a record type other than RecordTypes
+	ErrUnknownRecordType = errors.New("unknown record type")
+	// ErrRecordNotFound is returned when removing a record that does not exist or is already deleted
+	ErrRecordNotFound = errors.New("no record with this ID")
+	// ErrRecordNotDeleted is returned when restoring a record that does not exist or is not deleted
+	ErrRecordNotDeleted = errors.New("no deleted record with this ID")
+	// ErrRestoreConflict is returned when a live record already takes the place of the deleted one
+	ErrRestoreConflict = errors.New("a live record conflicts with the deleted one")
+)
+
+// UserLibrary is everything a user owns
+type UserLibrary struct {
+	User      models.User
+	Songs     []models.Song
+	Albums    []models.Album
+	Playlists []models.Playlist
+}
+
+// ModerationService lets moderators look into any user's library, remove content and undo deletions
+type ModerationService struct {
+	store repositories.Store
+}
+
+// NewModerationService returns a ModerationService backed by store
+func NewModerationService(store repositories.Store) *ModerationService {
+	return &ModerationService{store: store}
+}
+
+// Library returns everything a user owns. Looking is recorded in the audit trail like any other moderation action.
+func (s *ModerationService) Library(actorId uint, userId uint) (UserLibrary, error) {
+	var library UserLibrary
+	err := s.store.Transaction(func(tx repositories.Store) error {
+		user, err := tx.Users().FindByID(userId)
+		if err != nil {
+			if errors.Is(err, repositories.ErrNotFound) {
+				return ErrUserNotFound
+			}
+			return err
+		}
+
+		songs, err := tx.Songs().FindByUser(userId)
+		if err != nil {
+			return err
+		}
+		albums, err := tx.Albums().FindByUser(userId)
+		if err != nil {
+			return err
+		}
+		playlists, err := tx.Playlists().FindByUser(userId)
+		if err != nil {
+			return err
+		}
+
+		library = UserLibrary{User: user, Songs: songs, Albums: albums, Playlists: playlists}
+		return recordAudit(tx, actorId, models.AuditUserLibraryViewed, models.AuditTargetUser, userId, "")
+	})
+	return library, err
+}
+
+// ListDeleted returns deleted records of one type, most recently deleted first, of one user or
+// of everyone when userId is 0, and how many there are in total
+func (s *ModerationService) ListDeleted(recordType string, userId uint, limit int, offset int) (interface{}, int64, error) {
+	switch recordType {
+	case RecordTypeSongs:
+		return s.store.Songs().FindDeleted(userId, limit, offset)
+	case RecordTypeAlbums:
+		return s.store.Albums().FindDeleted(userId, limit, offset)
+	case RecordTypePlaylists:
+		return s.store.Playlists().FindDeleted(userId, limit, offset)
+	}
+	return nil, 0, ErrUnknownRecordType
+}
+
+// Remove deletes a song, album or playlist of any user the way its owner would, and records
+// why in the audit trail. The record can be restored like any other deleted one.
+func (s *ModerationService) Remove(actorId uint, recordType string, id uint, reason string) error {
+	return s.store.Transaction(func(tx repositories.Store) error {
+		var err error
+		var targetType string
+		switch recordType {
+		case RecordTypeSongs:
+			targetType = models.AuditTargetSong
+			var song models.Song
+			if song, err = tx.Songs().FindByID(id); err == nil {
+				err = deleteSong(tx, song)
+			}
+		case RecordTypeAlbums:
+			targetType = models.AuditTargetAlbum
+			var album models.Album
+			if album, err = tx.Albums().FindByID(id); err == nil {
+				err = deleteAlbum(tx, album)
+			}
+		case RecordTypePlaylists:
+			targetType = models.AuditTargetPlaylist
+			var playlist models.Playlist
+			if playlist, err = tx.Playlists().FindByID(id); err == nil {
+				err = deletePlaylist(tx, playlist)
+			}
+		default:
+			return ErrUnknownRecordType
+		}
+		if errors.Is(err, repositories.ErrNotFound) {
+			return ErrRecordNotFound
+		}
+		if err != nil {
+			return err
+		}
+		return recordAudit(tx, actorId, models.AuditRecordRemoved, targetType, id, reason)
+	})
+}
+
+// Restore undeletes one record. Albums come back without their songs, which are restored one by
+// one, and playlists come back empty because their entries are removed on delete.
+func (s *ModerationService) Restore(actorId uint, recordType string, id uint) (interface{}, error) {
+	var restored interface{}
+	err := s.store.Transaction(func(tx repositories.Store) error {
+		var err error
+		var targetType string
+		switch recordType {
+		case RecordTypeSongs:
+			targetType = models.AuditTargetSong
+			restored, err = tx.Songs().Restore(id)
+		case RecordTypeAlbums:
+			targetType = models.AuditTargetAlbum
+			restored, err = tx.Albums().Restore(id)
+		case RecordTypePlaylists:
+			targetType = models.AuditTargetPlaylist
+			restored, err = tx.Playlists().Restore(id)
+		default:
+			return ErrUnknownRecordType
+		}
+		if errors.Is(err, repositories.ErrNotFound) {
+			return ErrRecordNotDeleted
+		}
+		if errors.Is(err, repositories.ErrDuplicate) {
+			return ErrRestoreConflict
+		}
+		if err != nil {
+			return err
+		}
+		return recordAudit(tx, actorId, models.AuditRecordRestored, targetType, id, "")
+	})
+	return restored, err
+}
@@ -0,0 +1,70 @@
+package services_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/tushar27x/music-lib-api/models"
+	"github.com/tushar27x/music-lib-api/repositories"
+	"github.com/tushar27x/music-lib-api/services"
+)
+
+func TestModeratorRemovesAndRestoresAnotherUsersSong(t *testing.T) {
+	store := repositories.NewMemoryStore()
+	ownerId, songIds := createSongs(t, store, "ada@example.com", "Blue Train", "Locomotion")
+	moderatorId, _ := createSongs(t, store, "grace@example.com")
+	playlists := services.NewPlaylistService(store)
+	playlist, err := playlists.Create(ownerId, models.PlaylistCreateRequest{Name: "Hard bop", SongIds: songIds})
+	if err != nil {
+		t.Fatal(err)
+	}
+	moderation := services.NewModerationService(store)
+
+	if err := moderation.Remove(moderatorId, services.RecordTypeSongs, songIds[0], "Copyright claim"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Songs().FindOwned(ownerId, songIds[0]); !errors.Is(err, repositories.ErrNotFound) {
+		t.Errorf("removed song = %v, want ErrNotFound", err)
+	}
+	if got := trackTitles(t, playlists, ownerId, playlist.ID); len(got) != 1 || got[0] != "Locomotion" {
+		t.Errorf("playlist tracks after the removal = %q, want [Locomotion]", got)
+	}
+	entries, _, err := store.Audit().List(repositories.AuditFilter{Action: models.AuditRecordRemoved}, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].TargetId != songIds[0] || entries[0].Details != "Copyright claim" ||
+		entries[0].ActorId == nil || *entries[0].ActorId != moderatorId {
+		t.Errorf("audit entries = %+v, want the removal by %d", entries, moderatorId)
+	}
+
+	if err := moderation.Remove(moderatorId, services.RecordTypeSongs, songIds[0], "Again"); !errors.Is(err, services.ErrRecordNotFound) {
+		t.Errorf("removing a deleted song = %v, want ErrRecordNotFound", err)
+	}
+	if err := moderation.Remove(moderatorId, "users", ownerId, "Spam"); !errors.Is(err, services.ErrUnknownRecordType) {
+		t.Errorf("removing a user = %v, want ErrUnknownRecordType", err)
+	}
+
+	if _, err := moderation.Restore(moderatorId, services.RecordTypeSongs, songIds[0]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Songs().FindOwned(ownerId, songIds[0]); err != nil {
+		t.Errorf("restored song = %v", err)
+	}
+}
+
+func TestModerationPermissions(t *testing.T) {
+	for role, moderates := range map[string]bool{
+		models.RoleListener:  false,
+		models.RoleArtist:    false,
+		models.RoleModerator: true,
+		models.RoleAdmin:     true,
+	} {
+		if got := services.HasPermission(role, models.PermissionModerateContent); got != moderates {
+			t.Errorf("%s moderates content = %v, want %v", role, got, moderates)
+		}
+		if got := services.HasPermission(role, models.PermissionManageUsers); got != (role == models.RoleAdmin) {
+			t.Errorf("%s manages users = %v", role, got)
+		}
+	}
+}
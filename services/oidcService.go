@@ -0,0 +1,354 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/tushar27x/music-lib-api/config"
+	"github.com/tushar27x/music-lib-api/models"
+	"github.com/tushar27x/music-lib-api/repositories"
+	"golang.org/x/oauth2"
+)
+
+var (
+	// ErrOIDCProviderNotFound is returned for a provider that is not configured
+	ErrOIDCProviderNotFound = errors.New("identity provider not found")
+	// ErrOIDCProviderUnavailable is returned when the discovery document of a provider cannot be loaded
+	ErrOIDCProviderUnavailable = errors.New("identity provider is unavailable")
+	// ErrInvalidOIDCState is returned when a callback does not belong to a login in progress
+	ErrInvalidOIDCState = errors.New("invalid or expired login state")
+	// ErrOIDCLoginFailed is returned when the code exchange fails or the ID token does not verify
+	ErrOIDCLoginFailed = errors.New("identity provider login failed")
+	// ErrOIDCEmailNotVerified is returned when a new identity comes without an email address the provider verified
+	ErrOIDCEmailNotVerified = errors.New("identity provider did not report a verified email address")
+	// ErrOIDCAccountNotVerified is returned when a new identity matches an account whose address was never verified,
+	// which could have been registered by someone else to take over the identity
+	ErrOIDCAccountNotVerified = errors.New("an account with this email exists but its address is not verified")
+)
+
+// oidcProviderName keeps provider names usable in URLs and environment variable names
+var oidcProviderName = regexp.MustCompile(`^[a-z0-9-]+$`)
+
+// OIDCLoginTTL returns how long a started login waits for its callback (OIDC_LOGIN_TTL, default 10m)
+func OIDCLoginTTL() time.Duration {
+	return config.GetEnvDuration("OIDC_LOGIN_TTL", 10*time.Minute)
+}
+
+// OIDCProvider is an OpenID Connect provider users can log in with
+type OIDCProvider struct {
+	Name         string
+	DisplayName  string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	// RedirectURL is where the provider sends the browser back to; the page there posts the code and state to the callback endpoint
+	RedirectURL string
+	Scopes      []string
+	// DefaultRole is given to accounts created by a first login
+	DefaultRole string
+	// TrustEmail treats every email address from the provider as verified, for providers that do not send email_verified
+	TrustEmail bool
+
+	mu         sync.Mutex
+	discovered *oidc.Provider
+}
+
+// OIDCProvidersFromEnv reads the providers listed in OIDC_PROVIDERS. Each provider NAME is set up
+// with OIDC_<NAME>_ISSUER, OIDC_<NAME>_CLIENT_ID and optionally OIDC_<NAME>_CLIENT_SECRET,
+// OIDC_<NAME>_REDIRECT_URL, OIDC_<NAME>_SCOPES, OIDC_<NAME>_DISPLAY_NAME, OIDC_<NAME>_DEFAULT_ROLE
+// and OIDC_<NAME>_TRUST_EMAIL. Providers with missing settings are skipped with a warning.
+func OIDCProvidersFromEnv() []*OIDCProvider {
+	var providers []*OIDCProvider
+	for _, name := range strings.Split(config.GetEnv("OIDC_PROVIDERS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if !oidcProviderName.MatchString(name) {
+			log.Printf("Warning: invalid OIDC provider name %q, skipping it", name)
+			continue
+		}
+
+		prefix := "OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		setting := func(key string, fallback string) string {
+			if value := strings.TrimSpace(config.GetEnv(prefix + key)); value != "" {
+				return value
+			}
+			return fallback
+		}
+
+		provider := &OIDCProvider{
+			Name:         name,
+			DisplayName:  setting("DISPLAY_NAME", name),
+			Issuer:       setting("ISSUER", ""),
+			ClientID:     setting("CLIENT_ID", ""),
+			ClientSecret: setting("CLIENT_SECRET", ""),
+			RedirectURL:  setting("REDIRECT_URL", AppURL()+"/oidc/"+name+"/callback"),
+			Scopes:       strings.Fields(setting("SCOPES", "openid email profile")),
+			DefaultRole:  setting("DEFAULT_ROLE", config.GetEnv("OIDC_DEFAULT_ROLE")),
+			TrustEmail:   setting("TRUST_EMAIL", "false") == "true",
+		}
+		if provider.Issuer == "" || provider.ClientID == "" {
+			log.Printf("Warning: OIDC provider %s needs %sISSUER and %sCLIENT_ID, skipping it", name, prefix, prefix)
+			continue
+		}
+		if !slices.Contains(provider.Scopes, oidc.ScopeOpenID) {
+			provider.Scopes = append([]string{oidc.ScopeOpenID}, provider.Scopes...)
+		}
+		if provider.DefaultRole == "" {
+			provider.DefaultRole = models.RoleListener
+		}
+		if !IsRole(provider.DefaultRole) {
+			log.Printf("Warning: invalid default role %q for OIDC provider %s, using %s", provider.DefaultRole, name, models.RoleListener)
+			provider.DefaultRole = models.RoleListener
+		}
+		providers = append(providers, provider)
+	}
+	return providers
+}
+
+// discover loads the discovery document of the provider on first use, so the API starts even
+// when a provider is down
+func (p *OIDCProvider) discover(ctx context.Context) (*oidc.Provider, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.discovered == nil {
+		discovered, err := oidc.NewProvider(ctx, p.Issuer)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrOIDCProviderUnavailable, err)
+		}
+		p.discovered = discovered
+	}
+	return p.discovered, nil
+}
+
+// oauth2Config returns the discovered provider and the OAuth2 client settings for it
+func (p *OIDCProvider) oauth2Config(ctx context.Context) (*oidc.Provider, oauth2.Config, error) {
+	discovered, err := p.discover(ctx)
+	if err != nil {
+		return nil, oauth2.Config{}, err
+	}
+	return discovered, oauth2.Config{
+		ClientID:     p.ClientID,
+		ClientSecret: p.ClientSecret,
+		Endpoint:     discovered.Endpoint(),
+		RedirectURL:  p.RedirectURL,
+		Scopes:       p.Scopes,
+	}, nil
+}
+
+// oidcClaims are the ID token claims used to link and create accounts
+type oidcClaims struct {
+	Email string `json:"email"`
+	// EmailVerified is a boolean, but some providers send it as a string
+	EmailVerified interface{} `json:"email_verified"`
+	Name          string      `json:"name"`
+}
+
+func (c oidcClaims) emailVerified() bool {
+	return c.EmailVerified == true || c.EmailVerified == "true"
+}
+
+// OIDCService logs users in with external OpenID Connect providers using the authorization code
+// flow with PKCE. Identities are linked to the account with the same verified email address, and
+// an account is created on the first login of anyone else.
+type OIDCService struct {
+	store     repositories.Store
+	tokens    *TokenService
+	providers []*OIDCProvider
+}
+
+// NewOIDCService returns an OIDCService for providers, keeping identities in store and issuing tokens with tokens
+func NewOIDCService(store repositories.Store, tokens *TokenService, providers []*OIDCProvider) *OIDCService {
+	return &OIDCService{store: store, tokens: tokens, providers: providers}
+}
+
+// Providers lists the configured providers
+func (s *OIDCService) Providers() []models.OIDCProviderResponse {
+	providers := []models.OIDCProviderResponse{}
+	for _, provider := range s.providers {
+		providers = append(providers, models.OIDCProviderResponse{Name: provider.Name, DisplayName: provider.DisplayName})
+	}
+	return providers
+}
+
+// Identities lists the identities linked to a user
+func (s *OIDCService) Identities(userId uint) ([]models.UserIdentity, error) {
+	return s.store.Identities().FindByUser(userId)
+}
+
+// Authorize starts a login at the provider. The state, nonce and PKCE code verifier are kept
+// until the callback; only the hash of the state is stored.
+func (s *OIDCService) Authorize(ctx context.Context, name string) (models.OIDCAuthorizeResponse, error) {
+	provider, err := s.provider(name)
+	if err != nil {
+		return models.OIDCAuthorizeResponse{}, err
+	}
+	_, oauthConfig, err := provider.oauth2Config(ctx)
+	if err != nil {
+		return models.OIDCAuthorizeResponse{}, err
+	}
+
+	state, err := randomString(32)
+	if err != nil {
+		return models.OIDCAuthorizeResponse{}, err
+	}
+	nonce, err := randomString(16)
+	if err != nil {
+		return models.OIDCAuthorizeResponse{}, err
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	ttl := OIDCLoginTTL()
+	login := models.OIDCLogin{
+		Provider:     provider.Name,
+		StateHash:    hashToken(state),
+		CodeVerifier: verifier,
+		Nonce:        nonce,
+		ExpiresAt:    time.Now().Add(ttl),
+	}
+	if err := s.store.Identities().CreateLogin(&login); err != nil {
+		return models.OIDCAuthorizeResponse{}, err
+	}
+
+	return models.OIDCAuthorizeResponse{
+		AuthorizationURL: oauthConfig.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier)),
+		State:            state,
+		ExpiresIn:        int64(ttl.Seconds()),
+	}, nil
+}
+
+// Callback finishes a login with the code and state the provider sent back. The code is exchanged
+// together with the PKCE code verifier and the ID token is verified, including its nonce. Like
+// AuthService.Login, it returns a challenge instead of tokens for accounts with two-factor
+// authentication.
+func (s *OIDCService) Callback(ctx context.Context, name string, code string, state string) (models.TokenResponse, *models.MFAChallengeResponse, error) {
+	provider, err := s.provider(name)
+	if err != nil {
+		return models.TokenResponse{}, nil, err
+	}
+
+	login, err := s.store.Identities().FindLogin(hashToken(state))
+	if errors.Is(err, repositories.ErrNotFound) {
+		return models.TokenResponse{}, nil, ErrInvalidOIDCState
+	}
+	if err != nil {
+		return models.TokenResponse{}, nil, err
+	}
+	now := time.Now()
+	if login.Provider != provider.Name || login.UsedAt != nil || now.After(login.ExpiresAt) {
+		return models.TokenResponse{}, nil, ErrInvalidOIDCState
+	}
+	used, err := s.store.Identities().UseLogin(login.ID, now)
+	if err != nil {
+		return models.TokenResponse{}, nil, err
+	}
+	if !used {
+		return models.TokenResponse{}, nil, ErrInvalidOIDCState
+	}
+
+	discovered, oauthConfig, err := provider.oauth2Config(ctx)
+	if err != nil {
+		return models.TokenResponse{}, nil, err
+	}
+	token, err := oauthConfig.Exchange(ctx, code, oauth2.VerifierOption(login.CodeVerifier))
+	if err != nil {
+		return models.TokenResponse{}, nil, fmt.Errorf("%w: exchanging the code: %v", ErrOIDCLoginFailed, err)
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return models.TokenResponse{}, nil, fmt.Errorf("%w: no id_token in the token response", ErrOIDCLoginFailed)
+	}
+	idToken, err := discovered.Verifier(&oidc.Config{ClientID: provider.ClientID}).Verify(ctx, rawIDToken)
+	if err != nil {
+		return models.TokenResponse{}, nil, fmt.Errorf("%w: %v", ErrOIDCLoginFailed, err)
+	}
+	if idToken.Nonce != login.Nonce {
+		return models.TokenResponse{}, nil, fmt.Errorf("%w: nonce mismatch", ErrOIDCLoginFailed)
+	}
+	var claims oidcClaims
+	if err := idToken.Claims(&claims); err != nil {
+		return models.TokenResponse{}, nil, fmt.Errorf("%w: %v", ErrOIDCLoginFailed, err)
+	}
+
+	user, err := s.resolveUser(provider, idToken.Subject, claims)
+	if err != nil {
+		return models.TokenResponse{}, nil, err
+	}
+	if user.SuspendedAt != nil {
+		return models.TokenResponse{}, nil, ErrAccountSuspended
+	}
+	return startSession(s.tokens, user)
+}
+
+// resolveUser returns the account an identity is linked to. A new identity is linked to the
+// account with the same email address, or a new account without a password is created for it.
+// Either needs an address the provider verified.
+func (s *OIDCService) resolveUser(provider *OIDCProvider, subject string, claims oidcClaims) (models.User, error) {
+	var user models.User
+	err := s.store.Transaction(func(tx repositories.Store) error {
+		identity, err := tx.Identities().FindBySubject(provider.Name, subject)
+		if err == nil {
+			user, err = findUser(tx, identity.UserId)
+			return err
+		}
+		if !errors.Is(err, repositories.ErrNotFound) {
+			return err
+		}
+
+		email := strings.TrimSpace(claims.Email)
+		if email == "" || !(provider.TrustEmail || claims.emailVerified()) {
+			return ErrOIDCEmailNotVerified
+		}
+
+		user, err = tx.Users().FindByEmail(email)
+		switch {
+		case err == nil:
+			if user.EmailVerifiedAt == nil {
+				return ErrOIDCAccountNotVerified
+			}
+		case errors.Is(err, repositories.ErrNotFound):
+			now := time.Now()
+			user = models.User{
+				Name:            claims.Name,
+				Email:           email,
+				Role:            provider.DefaultRole,
+				EmailVerifiedAt: &now,
+			}
+			if user.Name == "" {
+				user.Name, _, _ = strings.Cut(email, "@")
+			}
+			if err := tx.Users().Create(&user); err != nil {
+				return err
+			}
+		default:
+			return err
+		}
+
+		return tx.Identities().Create(&models.UserIdentity{
+			UserId:   user.ID,
+			Provider: provider.Name,
+			Subject:  subject,
+			Email:    email,
+		})
+	})
+	return user, err
+}
+
+func (s *OIDCService) provider(name string) (*OIDCProvider, error) {
+	for _, provider := range s.providers {
+		if provider.Name == name {
+			return provider, nil
+		}
+	}
+	return nil, ErrOIDCProviderNotFound
+}
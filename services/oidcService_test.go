@@ -0,0 +1,264 @@
+package services_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/tushar27x/music-lib-api/models"
+	"github.com/tushar27x/music-lib-api/repositories"
+	"github.com/tushar27x/music-lib-api/services"
+)
+
+// mockIssuer is an OpenID Connect provider serving discovery, JWKS and token endpoints. The
+// authorization step is skipped: grant hands out a code for an authorization URL directly.
+type mockIssuer struct {
+	t      *testing.T
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu     sync.Mutex
+	grants map[string]mockGrant
+}
+
+// mockGrant is what the issuer remembers about a code until it is exchanged
+type mockGrant struct {
+	challenge string
+	claims    jwt.MapClaims
+}
+
+func newMockIssuer(t *testing.T) *mockIssuer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer := &mockIssuer{t: t, key: key, grants: map[string]mockGrant{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"issuer":                                issuer.server.URL,
+			"authorization_endpoint":                issuer.server.URL + "/authorize",
+			"token_endpoint":                        issuer.server.URL + "/token",
+			"jwks_uri":                              issuer.server.URL + "/jwks",
+			"response_types_supported":              []string{"code"},
+			"subject_types_supported":               []string{"public"},
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "test",
+			"alg": "RS256",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", issuer.token)
+	issuer.server = httptest.NewServer(mux)
+	t.Cleanup(issuer.server.Close)
+	return issuer
+}
+
+// grant checks that an authorization URL asks for a PKCE code and returns a code for it whose
+// ID token carries the claims, with the nonce of the URL unless the claims have one
+func (m *mockIssuer) grant(authorizationURL string, claims jwt.MapClaims) string {
+	m.t.Helper()
+	parsed, err := url.Parse(authorizationURL)
+	if err != nil {
+		m.t.Fatal(err)
+	}
+	query := parsed.Query()
+	if query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		m.t.Fatalf("authorization URL %s does not use PKCE", authorizationURL)
+	}
+	if query.Get("nonce") == "" || query.Get("state") == "" {
+		m.t.Fatalf("authorization URL %s has no nonce or state", authorizationURL)
+	}
+	if _, ok := claims["nonce"]; !ok {
+		claims["nonce"] = query.Get("nonce")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	code := "code-" + query.Get("state")
+	m.grants[code] = mockGrant{challenge: query.Get("code_challenge"), claims: claims}
+	return code
+}
+
+// token exchanges a code once, and only together with the code verifier of its challenge
+func (m *mockIssuer) token(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	grant, ok := m.grants[r.FormValue("code")]
+	delete(m.grants, r.FormValue("code"))
+	m.mu.Unlock()
+
+	verifier := sha256.Sum256([]byte(r.FormValue("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(verifier[:]) != grant.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	claims := jwt.MapClaims{
+		"iss": m.server.URL,
+		"aud": "music-lib",
+		"iat": time.Now().Unix(),
+		"exp": time.Now().Add(time.Minute).Unix(),
+	}
+	for name, value := range grant.claims {
+		claims[name] = value
+	}
+	idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	idToken.Header["kid"] = "test"
+	signed, err := idToken.SignedString(m.key)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": "access",
+		"token_type":   "Bearer",
+		"expires_in":   60,
+		"id_token":     signed,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// newOIDCService returns an OIDCService on a memory store with the mock issuer as provider "mock"
+func newOIDCService(t *testing.T, issuer *mockIssuer) (*services.OIDCService, repositories.Store) {
+	t.Setenv("JWT_SECRET", "test-secret")
+	store := repositories.NewMemoryStore()
+	provider := &services.OIDCProvider{
+		Name:        "mock",
+		Issuer:      issuer.server.URL,
+		ClientID:    "music-lib",
+		RedirectURL: "http://localhost/oidc/mock/callback",
+		Scopes:      []string{"openid", "email", "profile"},
+		DefaultRole: models.RoleListener,
+	}
+	return services.NewOIDCService(store, services.NewTokenService(store), []*services.OIDCProvider{provider}), store
+}
+
+// login starts a login and comes back with a code for the claims; it returns the code and state
+func login(t *testing.T, oidc *services.OIDCService, issuer *mockIssuer, claims jwt.MapClaims) (string, string) {
+	t.Helper()
+	authorization, err := oidc.Authorize(context.Background(), "mock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return issuer.grant(authorization.AuthorizationURL, claims), authorization.State
+}
+
+func TestOIDCLogin(t *testing.T) {
+	issuer := newMockIssuer(t)
+	oidc, store := newOIDCService(t, issuer)
+	ctx := context.Background()
+	claims := func() jwt.MapClaims {
+		return jwt.MapClaims{"sub": "ada-1", "email": "ada@example.com", "email_verified": true, "name": "Ada"}
+	}
+
+	code, state := login(t, oidc, issuer, claims())
+	tokens, challenge, err := oidc.Callback(ctx, "mock", code, state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if challenge != nil || tokens.Token == "" {
+		t.Fatalf("callback = %+v, %+v, want tokens", tokens, challenge)
+	}
+	user, err := store.Users().FindByEmail("ada@example.com")
+	if err != nil {
+		t.Fatalf("the first login created no account: %v", err)
+	}
+	if user.Name != "Ada" || user.Role != models.RoleListener || user.EmailVerifiedAt == nil || user.Password != "" {
+		t.Errorf("created account = %+v", user)
+	}
+
+	// The identity is found by subject from then on, whatever the email says
+	next := claims()
+	next["email"], next["email_verified"] = "", false
+	code, state = login(t, oidc, issuer, next)
+	if _, _, err := oidc.Callback(ctx, "mock", code, state); err != nil {
+		t.Errorf("second login = %v", err)
+	}
+	identities, err := oidc.Identities(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(identities) != 1 || identities[0].Subject != "ada-1" {
+		t.Errorf("identities = %+v, want one for ada-1", identities)
+	}
+}
+
+func TestOIDCCallbackRejects(t *testing.T) {
+	issuer := newMockIssuer(t)
+	oidc, store := newOIDCService(t, issuer)
+	ctx := context.Background()
+
+	unverified := models.User{Name: "Grace", Email: "grace@example.com", Password: "hash", Role: models.RoleListener}
+	if err := store.Users().Create(&unverified); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("reused state", func(t *testing.T) {
+		code, state := login(t, oidc, issuer, jwt.MapClaims{"sub": "ada-1", "email": "ada@example.com", "email_verified": true})
+		if _, _, err := oidc.Callback(ctx, "mock", code, state); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := oidc.Callback(ctx, "mock", code, state); !errors.Is(err, services.ErrInvalidOIDCState) {
+			t.Errorf("second callback with a state = %v, want ErrInvalidOIDCState", err)
+		}
+	})
+
+	t.Run("unknown state", func(t *testing.T) {
+		code, _ := login(t, oidc, issuer, jwt.MapClaims{"sub": "ada-1"})
+		if _, _, err := oidc.Callback(ctx, "mock", code, "forged"); !errors.Is(err, services.ErrInvalidOIDCState) {
+			t.Errorf("callback with a forged state = %v, want ErrInvalidOIDCState", err)
+		}
+	})
+
+	t.Run("wrong nonce", func(t *testing.T) {
+		code, state := login(t, oidc, issuer, jwt.MapClaims{"sub": "ada-1", "nonce": "replayed"})
+		if _, _, err := oidc.Callback(ctx, "mock", code, state); !errors.Is(err, services.ErrOIDCLoginFailed) {
+			t.Errorf("ID token with another nonce = %v, want ErrOIDCLoginFailed", err)
+		}
+	})
+
+	t.Run("unverified email", func(t *testing.T) {
+		code, state := login(t, oidc, issuer, jwt.MapClaims{"sub": "eve-1", "email": "eve@example.com", "email_verified": false})
+		if _, _, err := oidc.Callback(ctx, "mock", code, state); !errors.Is(err, services.ErrOIDCEmailNotVerified) {
+			t.Errorf("login with an unverified email = %v, want ErrOIDCEmailNotVerified", err)
+		}
+		if _, err := store.Users().FindByEmail("eve@example.com"); !errors.Is(err, repositories.ErrNotFound) {
+			t.Errorf("an account was created for an unverified email: %v", err)
+		}
+	})
+
+	t.Run("account with an unverified email", func(t *testing.T) {
+		code, state := login(t, oidc, issuer, jwt.MapClaims{"sub": "grace-1", "email": "grace@example.com", "email_verified": true})
+		if _, _, err := oidc.Callback(ctx, "mock", code, state); !errors.Is(err, services.ErrOIDCAccountNotVerified) {
+			t.Errorf("linking an account with an unverified email = %v, want ErrOIDCAccountNotVerified", err)
+		}
+		if identities, _ := oidc.Identities(unverified.ID); len(identities) != 0 {
+			t.Errorf("identities linked to the unverified account = %+v", identities)
+		}
+	})
+}
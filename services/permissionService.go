@@ -0,0 +1,42 @@
+package services
+
+import (
+	"errors"
+	"slices"
+
+	"github.com/tushar27x/music-lib-api/models"
+)
+
+// ErrPermissionDenied is returned when the role of the user lacks a permission
+var ErrPermissionDenied = errors.New("permission denied")
+
+// RolePermissions is the permission matrix: every permission each role holds
+var RolePermissions = map[string][]string{
+	models.RoleListener: {
+		models.PermissionManageLibrary,
+	},
+	models.RoleArtist: {
+		models.PermissionManageLibrary,
+		models.PermissionManageAlbums,
+	},
+	models.RoleModerator: {
+		models.PermissionManageLibrary,
+		models.PermissionModerateContent,
+	},
+	models.RoleAdmin: {
+		models.PermissionManageLibrary,
+		models.PermissionManageAlbums,
+		models.PermissionModerateContent,
+		models.PermissionManageUsers,
+	},
+}
+
+// HasPermission reports whether the role holds the permission; unknown roles hold none
+func HasPermission(role string, permission string) bool {
+	return slices.Contains(RolePermissions[role], permission)
+}
+
+// IsRole reports whether role is one of models.Roles
+func IsRole(role string) bool {
+	return slices.Contains(models.Roles, role)
+}
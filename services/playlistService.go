@@ -0,0 +1,363 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/tushar27x/music-lib-api/models"
+	"github.com/tushar27x/music-lib-api/repositories"
+)
+
+var (
+	// ErrPlaylistNotFound is returned when a playlist does not exist or belongs to someone else
+	ErrPlaylistNotFound = errors.New("playlist not found")
+	// ErrEntryNotFound is returned when a playlist entry does not exist in the playlist
+	ErrEntryNotFound = errors.New("playlist entry not found")
+	// ErrSongsNotOwned is returned when some of the given songs don't belong to the user
+	ErrSongsNotOwned = errors.New("some songs were not found in your library")
+	// ErrInvalidPosition is returned when a position is outside the playlist
+	ErrInvalidPosition = errors.New("position is out of range")
+	// ErrSmartPlaylist is returned when tracks of a smart playlist are edited by hand
+	ErrSmartPlaylist = errors.New("tracks of a smart playlist are chosen by its rules")
+	// ErrRulesNotAllowed is returned when rules are given for a manual playlist
+	ErrRulesNotAllowed = errors.New("rules are only allowed on smart playlists")
+	// ErrPlaylistTypeChanged is returned when an update tries to turn a manual playlist into a smart one or back
+	ErrPlaylistTypeChanged = errors.New("playlist type cannot be changed")
+)
+
+// PlaylistService holds the rules for a user's manual and smart playlists
+type PlaylistService struct {
+	store repositories.Store
+}
+
+// NewPlaylistService returns a PlaylistService keeping playlists in store
+func NewPlaylistService(store repositories.Store) *PlaylistService {
+	return &PlaylistService{store: store}
+}
+
+// Create makes a manual playlist with the given songs in order, or a smart playlist from its rules
+func (s *PlaylistService) Create(userId uint, input models.PlaylistCreateRequest) (models.Playlist, error) {
+	playlist := models.Playlist{
+		Name:   input.Name,
+		UserId: userId,
+		Type:   models.PlaylistTypeManual,
+	}
+
+	if input.Type == models.PlaylistTypeSmart {
+		if len(input.SongIds) > 0 {
+			return playlist, ErrSmartPlaylist
+		}
+		if errs := ValidateSmartRules(input.Rules); len(errs) > 0 {
+			return playlist, &SmartRulesError{Fields: errs}
+		}
+		playlist.Type = models.PlaylistTypeSmart
+		playlist.Rules = input.Rules
+	} else if input.Rules != nil {
+		return playlist, ErrRulesNotAllowed
+	}
+
+	err := s.store.Transaction(func(tx repositories.Store) error {
+		if err := tx.Playlists().Create(&playlist); err != nil {
+			return err
+		}
+		return replaceTracks(tx, userId, playlist.ID, input.SongIds)
+	})
+	if err != nil {
+		return playlist, err
+	}
+
+	return s.Load(userId, playlist.ID)
+}
+
+// List returns the user's playlists with their stored tracks
+func (s *PlaylistService) List(userId uint) ([]models.Playlist, error) {
+	return s.store.Playlists().FindByUser(userId)
+}
+
+// Load returns a playlist of the user with its ordered tracks; smart playlists are evaluated on every load
+func (s *PlaylistService) Load(userId uint, playlistId uint) (models.Playlist, error) {
+	playlist, err := s.store.Playlists().FindOwnedWithTracks(userId, playlistId)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return playlist, ErrPlaylistNotFound
+	}
+	if err != nil {
+		return playlist, err
+	}
+
+	if playlist.Type == models.PlaylistTypeSmart {
+		playlist.Songs, err = s.evaluate(userId, playlist.Rules)
+	}
+	return playlist, err
+}
+
+// Update renames a playlist and, depending on its type, replaces its songs or its rules
+func (s *PlaylistService) Update(userId uint, playlistId uint, input models.PlaylistCreateRequest) (models.Playlist, error) {
+	err := s.store.Transaction(func(tx repositories.Store) error {
+		playlist, err := tx.Playlists().FindOwned(userId, playlistId)
+		if errors.Is(err, repositories.ErrNotFound) {
+			return ErrPlaylistNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		if input.Type != "" && input.Type != playlist.Type {
+			return ErrPlaylistTypeChanged
+		}
+
+		columns := []string{"name"}
+		if playlist.Type == models.PlaylistTypeSmart {
+			if len(input.SongIds) > 0 {
+				return ErrSmartPlaylist
+			}
+			if input.Rules != nil {
+				if errs := ValidateSmartRules(input.Rules); len(errs) > 0 {
+					return &SmartRulesError{Fields: errs}
+				}
+				columns = append(columns, "rules")
+			}
+		} else if input.Rules != nil {
+			return ErrRulesNotAllowed
+		}
+
+		playlist.Name = input.Name
+		playlist.Rules = input.Rules
+		if err := tx.Playlists().Update(&playlist, columns...); err != nil {
+			return err
+		}
+
+		// When song IDs are given, the playlist's tracks are replaced in that order
+		if len(input.SongIds) > 0 {
+			return replaceTracks(tx, userId, playlist.ID, input.SongIds)
+		}
+		return nil
+	})
+	if err != nil {
+		return models.Playlist{}, err
+	}
+
+	return s.Load(userId, playlistId)
+}
+
+// Delete removes a playlist of the user; its songs stay in the library
+func (s *PlaylistService) Delete(userId uint, playlistId uint) error {
+	return s.store.Transaction(func(tx repositories.Store) error {
+		playlist, err := tx.Playlists().FindOwned(userId, playlistId)
+		if errors.Is(err, repositories.ErrNotFound) {
+			return ErrPlaylistNotFound
+		}
+		if err != nil {
+			return err
+		}
+		return deletePlaylist(tx, playlist)
+	})
+}
+
+// deletePlaylist deletes a playlist and its entries; the songs stay in the library
+func deletePlaylist(tx repositories.Store, playlist models.Playlist) error {
+	if err := tx.Playlists().DeleteEntries(playlist.ID); err != nil {
+		return err
+	}
+	return tx.Playlists().Delete(&playlist)
+}
+
+// InsertTracks inserts songs into a playlist at position, or at the end when position is nil
+func (s *PlaylistService) InsertTracks(userId uint, playlistId uint, songIds []uint, position *int) error {
+	return s.store.Transaction(func(tx repositories.Store) error {
+		playlist, err := lockPlaylist(tx, userId, playlistId)
+		if err != nil {
+			return err
+		}
+		if err := checkSongsOwned(tx, userId, songIds); err != nil {
+			return err
+		}
+
+		count, err := tx.Playlists().CountEntries(playlist.ID)
+		if err != nil {
+			return err
+		}
+
+		at := count
+		if position != nil {
+			if *position > count {
+				return ErrInvalidPosition
+			}
+			at = *position
+		}
+
+		// Make room for the new entries
+		if at < count {
+			if err := tx.Playlists().ShiftEntries(playlist.ID, at, -1, len(songIds)); err != nil {
+				return err
+			}
+		}
+
+		return createEntries(tx, playlist.ID, songIds, at)
+	})
+}
+
+// RemoveTrack removes one entry from a playlist and closes the gap it leaves
+func (s *PlaylistService) RemoveTrack(userId uint, playlistId uint, entryId uint) error {
+	return s.store.Transaction(func(tx repositories.Store) error {
+		playlist, err := lockPlaylist(tx, userId, playlistId)
+		if err != nil {
+			return err
+		}
+
+		entry, err := findEntry(tx, playlist.ID, entryId)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Playlists().DeleteEntry(entry); err != nil {
+			return err
+		}
+		return tx.Playlists().ShiftEntries(playlist.ID, entry.Position+1, -1, -1)
+	})
+}
+
+// MoveTrack moves an entry to a new position, shifting the entries in between
+func (s *PlaylistService) MoveTrack(userId uint, playlistId uint, entryId uint, to int) error {
+	return s.store.Transaction(func(tx repositories.Store) error {
+		playlist, err := lockPlaylist(tx, userId, playlistId)
+		if err != nil {
+			return err
+		}
+
+		entry, err := findEntry(tx, playlist.ID, entryId)
+		if err != nil {
+			return err
+		}
+
+		count, err := tx.Playlists().CountEntries(playlist.ID)
+		if err != nil {
+			return err
+		}
+		if to >= count {
+			return ErrInvalidPosition
+		}
+
+		from := entry.Position
+		switch {
+		case to == from:
+			return nil
+		case to < from:
+			err = tx.Playlists().ShiftEntries(playlist.ID, to, from, 1)
+		default:
+			err = tx.Playlists().ShiftEntries(playlist.ID, from+1, to+1, -1)
+		}
+		if err != nil {
+			return err
+		}
+
+		return tx.Playlists().SetEntryPosition(&entry, to)
+	})
+}
+
+// evaluate runs a smart playlist's rules against the user's songs
+func (s *PlaylistService) evaluate(userId uint, rules *models.SmartPlaylistRules) ([]models.Song, error) {
+	if rules == nil {
+		return []models.Song{}, nil
+	}
+	return s.store.Songs().FindMatching(userId, *rules, time.Now())
+}
+
+// replaceTracks replaces every entry of a playlist with the given songs, in order
+func replaceTracks(tx repositories.Store, userId uint, playlistId uint, songIds []uint) error {
+	if err := checkSongsOwned(tx, userId, songIds); err != nil {
+		return err
+	}
+	if err := tx.Playlists().DeleteEntries(playlistId); err != nil {
+		return err
+	}
+	return createEntries(tx, playlistId, songIds, 0)
+}
+
+// removeSongFromPlaylists deletes every entry of a song and renumbers the affected playlists
+func removeSongFromPlaylists(tx repositories.Store, songId uint) error {
+	playlistIds, err := tx.Playlists().FindPlaylistIdsWithSong(songId)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Playlists().DeleteSongEntries(songId); err != nil {
+		return err
+	}
+
+	for _, playlistId := range playlistIds {
+		if err := renumberEntries(tx, playlistId); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// renumberEntries rewrites positions as 0..n-1 keeping the current order
+func renumberEntries(tx repositories.Store, playlistId uint) error {
+	entries, err := tx.Playlists().FindEntries(playlistId)
+	if err != nil {
+		return err
+	}
+	for i := range entries {
+		if entries[i].Position == i {
+			continue
+		}
+		if err := tx.Playlists().SetEntryPosition(&entries[i], i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lockPlaylist loads the user's manual playlist and locks it so concurrent edits are serialised
+func lockPlaylist(tx repositories.Store, userId uint, playlistId uint) (models.Playlist, error) {
+	playlist, err := tx.Playlists().LockOwned(userId, playlistId)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return playlist, ErrPlaylistNotFound
+	}
+	if err == nil && playlist.Type == models.PlaylistTypeSmart {
+		return playlist, ErrSmartPlaylist
+	}
+	return playlist, err
+}
+
+func findEntry(tx repositories.Store, playlistId uint, entryId uint) (models.PlaylistEntry, error) {
+	entry, err := tx.Playlists().FindEntry(playlistId, entryId)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return entry, ErrEntryNotFound
+	}
+	return entry, err
+}
+
+// checkSongsOwned makes sure every distinct song ID belongs to the user
+func checkSongsOwned(tx repositories.Store, userId uint, songIds []uint) error {
+	distinct := map[uint]bool{}
+	for _, id := range songIds {
+		distinct[id] = true
+	}
+	if len(distinct) == 0 {
+		return nil
+	}
+
+	ids := make([]uint, 0, len(distinct))
+	for id := range distinct {
+		ids = append(ids, id)
+	}
+
+	count, err := tx.Songs().CountOwned(userId, ids)
+	if err != nil {
+		return err
+	}
+	if count != len(ids) {
+		return ErrSongsNotOwned
+	}
+	return nil
+}
+
+func createEntries(tx repositories.Store, playlistId uint, songIds []uint, start int) error {
+	entries := make([]models.PlaylistEntry, len(songIds))
+	for i, songId := range songIds {
+		entries[i] = models.PlaylistEntry{PlaylistId: playlistId, SongId: songId, Position: start + i}
+	}
+	return tx.Playlists().CreateEntries(entries)
+}
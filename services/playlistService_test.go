@@ -0,0 +1,123 @@
+package services_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/tushar27x/music-lib-api/models"
+	"github.com/tushar27x/music-lib-api/repositories"
+	"github.com/tushar27x/music-lib-api/services"
+)
+
+// createSongs adds a user with songs of the given titles to the store
+func createSongs(t *testing.T, store repositories.Store, email string, titles ...string) (uint, []uint) {
+	t.Helper()
+	user := models.User{Name: "Ada", Email: email, Role: "artist"}
+	if err := store.Users().Create(&user); err != nil {
+		t.Fatal(err)
+	}
+	var ids []uint
+	for _, title := range titles {
+		song := models.Song{Title: title, UserId: user.ID}
+		if err := store.Songs().Create(&song); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, song.ID)
+	}
+	return user.ID, ids
+}
+
+// trackTitles loads a playlist and returns the titles of its tracks, checking their positions run from 0
+func trackTitles(t *testing.T, playlists *services.PlaylistService, userId uint, playlistId uint) []string {
+	t.Helper()
+	playlist, err := playlists.Load(userId, playlistId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, entry := range playlist.Entries {
+		if entry.Position != i {
+			t.Errorf("entry %d is at position %d, want %d", entry.ID, entry.Position, i)
+		}
+	}
+	var titles []string
+	for _, song := range playlist.Tracks() {
+		titles = append(titles, song.Title)
+	}
+	return titles
+}
+
+func TestPlaylistTrackEditing(t *testing.T) {
+	store := repositories.NewMemoryStore()
+	userId, songIds := createSongs(t, store, "ada@example.com", "Blue Train", "Moment's Notice", "Locomotion")
+	_, otherIds := createSongs(t, store, "grace@example.com", "So What")
+	playlists := services.NewPlaylistService(store)
+
+	playlist, err := playlists.Create(userId, models.PlaylistCreateRequest{Name: "Hard bop", SongIds: songIds[:2]})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first := 0
+	if err := playlists.InsertTracks(userId, playlist.ID, songIds[2:], &first); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"Locomotion", "Blue Train", "Moment's Notice"}
+	if got := trackTitles(t, playlists, userId, playlist.ID); !slices.Equal(got, want) {
+		t.Fatalf("after inserting tracks = %q, want %q", got, want)
+	}
+
+	loaded, err := playlists.Load(userId, playlist.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := playlists.MoveTrack(userId, playlist.ID, loaded.Entries[0].ID, 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := playlists.RemoveTrack(userId, playlist.ID, loaded.Entries[1].ID); err != nil {
+		t.Fatal(err)
+	}
+	want = []string{"Moment's Notice", "Locomotion"}
+	if got := trackTitles(t, playlists, userId, playlist.ID); !slices.Equal(got, want) {
+		t.Fatalf("after moving and removing tracks = %q, want %q", got, want)
+	}
+
+	// A song of someone else rolls the whole insert back
+	err = playlists.InsertTracks(userId, playlist.ID, []uint{songIds[0], otherIds[0]}, nil)
+	if !errors.Is(err, services.ErrSongsNotOwned) {
+		t.Errorf("inserting another user's song = %v, want ErrSongsNotOwned", err)
+	}
+	if got := trackTitles(t, playlists, userId, playlist.ID); !slices.Equal(got, want) {
+		t.Errorf("after a failed insert = %q, want %q", got, want)
+	}
+
+	if err := playlists.MoveTrack(userId, playlist.ID, loaded.Entries[2].ID, 2); !errors.Is(err, services.ErrInvalidPosition) {
+		t.Errorf("moving past the end = %v, want ErrInvalidPosition", err)
+	}
+}
+
+func TestSmartPlaylistTracks(t *testing.T) {
+	store := repositories.NewMemoryStore()
+	userId, _ := createSongs(t, store, "ada@example.com", "Blue Train", "Moment's Notice", "Locomotion")
+	playlists := services.NewPlaylistService(store)
+
+	rules := &models.SmartPlaylistRules{SmartRuleGroup: models.SmartRuleGroup{
+		Match: "all",
+		Rules: []models.SmartRule{{Field: "title", Operator: "contains", Value: "o"}},
+	}}
+	playlist, err := playlists.Create(userId, models.PlaylistCreateRequest{Name: "With an o", Type: models.PlaylistTypeSmart, Rules: rules})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(playlist.Entries) != 0 {
+		t.Errorf("smart playlist entries = %+v, want none", playlist.Entries)
+	}
+	want := []string{"Moment's Notice", "Locomotion"}
+	if got := trackTitles(t, playlists, userId, playlist.ID); !slices.Equal(got, want) {
+		t.Errorf("smart playlist tracks = %q, want %q", got, want)
+	}
+
+	if err := playlists.InsertTracks(userId, playlist.ID, []uint{1}, nil); !errors.Is(err, services.ErrSmartPlaylist) {
+		t.Errorf("inserting into a smart playlist = %v, want ErrSmartPlaylist", err)
+	}
+}
@@ -0,0 +1,299 @@
+package services
+
+import (
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/tushar27x/music-lib-api/models"
+	"github.com/tushar27x/music-lib-api/playlistfile"
+	"github.com/tushar27x/music-lib-api/repositories"
+)
+
+// Thresholds for matching imported entries by title
+const (
+	// minMatchScore is the lowest score that counts as a match at all
+	minMatchScore = 0.75
+	// ambiguityMargin is how far ahead the best candidate must be to win outright
+	ambiguityMargin = 0.1
+)
+
+var (
+	streamPathPattern  = regexp.MustCompile(`/api/songs/(\d+)/stream`)
+	trackPrefixPattern = regexp.MustCompile(`^\d{1,3}\s*(?:[-._]\s*)?`)
+	bracketPattern     = regexp.MustCompile(`\s*[\(\[][^\)\]]*[\)\]]`)
+)
+
+// ExportPlaylist converts a loaded playlist into a playlist file; songs with audio get a stream URL
+// below baseURL signed for ExportURLTTL so desktop players can open them without a token
+func ExportPlaylist(playlist models.Playlist, baseURL string) playlistfile.Playlist {
+	file := playlistfile.Playlist{Title: playlist.Name}
+	expiresAt := time.Now().Add(ExportURLTTL())
+
+	for _, song := range playlist.Tracks() {
+		location := baseURL + "/api/songs/" + strconv.FormatUint(uint64(song.ID), 10) + "/stream"
+		if song.AudioKey != "" {
+			location = baseURL + SignStreamURL(song.ID, expiresAt)
+		}
+		file.Entries = append(file.Entries, playlistfile.Entry{
+			Title:    song.Title,
+			Artist:   song.Artist,
+			Duration: time.Duration(song.Duration) * time.Millisecond,
+			Location: location,
+		})
+	}
+	return file
+}
+
+// Import matches the entries of a playlist file against the user's songs and creates a manual
+// playlist from the matches. Ambiguous entries are only added when addAmbiguous is set, using the best candidate.
+func (s *PlaylistService) Import(userId uint, name string, file playlistfile.Playlist, addAmbiguous bool) (models.Playlist, models.PlaylistImportReport, error) {
+	report := models.PlaylistImportReport{
+		Total:     len(file.Entries),
+		Matched:   []models.PlaylistImportEntry{},
+		Ambiguous: []models.PlaylistImportEntry{},
+		Unmatched: []models.PlaylistImportEntry{},
+	}
+
+	songs, err := s.store.Songs().FindByUser(userId)
+	if err != nil {
+		return models.Playlist{}, report, err
+	}
+	matcher := newSongMatcher(songs)
+
+	var songIds []uint
+	for i, entry := range file.Entries {
+		result := matcher.match(i, entry)
+		switch {
+		case result.SongId != nil && len(result.Candidates) == 0:
+			report.Matched = append(report.Matched, result)
+			songIds = append(songIds, *result.SongId)
+		case len(result.Candidates) > 0:
+			if addAmbiguous {
+				songIds = append(songIds, *result.SongId)
+			} else {
+				result.SongId = nil
+			}
+			report.Ambiguous = append(report.Ambiguous, result)
+		default:
+			report.Unmatched = append(report.Unmatched, result)
+		}
+	}
+
+	playlist := models.Playlist{
+		Name:   name,
+		UserId: userId,
+		Type:   models.PlaylistTypeManual,
+	}
+	err = s.store.Transaction(func(tx repositories.Store) error {
+		if err := tx.Playlists().Create(&playlist); err != nil {
+			return err
+		}
+		return replaceTracks(tx, userId, playlist.ID, songIds)
+	})
+	if err != nil {
+		return playlist, report, err
+	}
+
+	playlist, err = s.Load(userId, playlist.ID)
+	return playlist, report, err
+}
+
+type matchCandidate struct {
+	song  *models.Song
+	score float64
+}
+
+// songMatcher resolves playlist file entries to songs of one user
+type songMatcher struct {
+	songs  []models.Song
+	byId   map[uint]*models.Song
+	byFile map[string]*models.Song
+}
+
+func newSongMatcher(songs []models.Song) *songMatcher {
+	matcher := &songMatcher{
+		songs:  songs,
+		byId:   map[uint]*models.Song{},
+		byFile: map[string]*models.Song{},
+	}
+	for i := range songs {
+		song := &songs[i]
+		matcher.byId[song.ID] = song
+		if song.AudioKey != "" {
+			// Stored files are named after their checksum, so the base name identifies the audio
+			matcher.byFile[strings.ToLower(path.Base(song.AudioKey))] = song
+		}
+	}
+	return matcher
+}
+
+func (m *songMatcher) match(index int, entry playlistfile.Entry) models.PlaylistImportEntry {
+	result := models.PlaylistImportEntry{
+		Index:    index,
+		Title:    entry.Title,
+		Artist:   entry.Artist,
+		Duration: uint(entry.Duration.Milliseconds()),
+		Location: entry.Location,
+	}
+
+	location := locationPath(entry.Location)
+
+	// Files exported by this API point at the stream endpoint of the song
+	if groups := streamPathPattern.FindStringSubmatch(location); groups != nil {
+		if id, err := strconv.ParseUint(groups[1], 10, 64); err == nil {
+			if song, ok := m.byId[uint(id)]; ok {
+				return matchedEntry(result, song, "stream_url", 1)
+			}
+		}
+	}
+
+	if location != "" {
+		if song, ok := m.byFile[strings.ToLower(path.Base(location))]; ok {
+			return matchedEntry(result, song, "file_path", 1)
+		}
+	}
+
+	// Fall back to the file name when the playlist carries no titles
+	if result.Title == "" && location != "" {
+		name := strings.TrimSuffix(path.Base(location), path.Ext(location))
+		name = trackPrefixPattern.ReplaceAllString(name, "")
+		result.Artist, result.Title = playlistfile.SplitArtistTitle(strings.ReplaceAll(name, "_", " "))
+	}
+	if result.Title == "" {
+		return result
+	}
+
+	candidates := m.rank(result.Title, result.Artist, entry.Duration)
+	if len(candidates) == 0 {
+		return result
+	}
+
+	best := candidates[0]
+	result = matchedEntry(result, best.song, "title", best.score)
+	if len(candidates) > 1 && best.score-candidates[1].score < ambiguityMargin {
+		for _, candidate := range candidates {
+			if best.score-candidate.score < ambiguityMargin {
+				result.Candidates = append(result.Candidates, candidate.song.ID)
+			}
+		}
+	}
+	return result
+}
+
+// rank scores every song against the entry and returns the candidates above minMatchScore, best first
+func (m *songMatcher) rank(title string, artist string, duration time.Duration) []matchCandidate {
+	var candidates []matchCandidate
+	for i := range m.songs {
+		song := &m.songs[i]
+
+		score := titleSimilarity(title, song.Title)
+		if score < minMatchScore-0.15 {
+			continue
+		}
+		if artist != "" && song.Artist != "" {
+			score = 0.75*score + 0.25*titleSimilarity(artist, song.Artist)
+		}
+		if duration > 0 && song.Duration > 0 {
+			difference := duration - time.Duration(song.Duration)*time.Millisecond
+			if difference < 0 {
+				difference = -difference
+			}
+			switch {
+			case difference <= 3*time.Second:
+				score += 0.1
+			case difference > 15*time.Second:
+				score -= 0.2
+			}
+		}
+		if score >= minMatchScore {
+			candidates = append(candidates, matchCandidate{song: song, score: score})
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].score > candidates[j].score
+	})
+	return candidates
+}
+
+func matchedEntry(result models.PlaylistImportEntry, song *models.Song, matchedBy string, score float64) models.PlaylistImportEntry {
+	id := song.ID
+	result.SongId = &id
+	result.MatchedBy = matchedBy
+	// Duration bonuses can push the ranking score above 1; the report shows a confidence
+	result.Score = float64(int(min(score, 1)*100+0.5)) / 100
+	return result
+}
+
+// locationPath turns file URIs and URLs into a plain, slash separated path
+func locationPath(location string) string {
+	location = strings.TrimSpace(location)
+	if parsed, err := url.Parse(location); err == nil && parsed.Scheme != "" && len(parsed.Scheme) > 1 {
+		return parsed.Path
+	}
+	// Windows paths, including drive letters that look like a URL scheme
+	return strings.ReplaceAll(location, `\`, "/")
+}
+
+// titleSimilarity compares two titles ignoring case, punctuation and bracketed suffixes such as "(Remastered)"
+func titleSimilarity(a string, b string) float64 {
+	score := similarity(normalizeTitle(a), normalizeTitle(b))
+	// Ignoring the brackets costs a little so an exact title still wins over a remix or live version
+	stripped := similarity(normalizeTitle(bracketPattern.ReplaceAllString(a, "")), normalizeTitle(bracketPattern.ReplaceAllString(b, ""))) - 0.05
+	return max(score, stripped)
+}
+
+func normalizeTitle(value string) string {
+	value = strings.ReplaceAll(strings.ToLower(value), "&", " and ")
+	var builder strings.Builder
+	space := false
+	for _, r := range value {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if space && builder.Len() > 0 {
+				builder.WriteByte(' ')
+			}
+			builder.WriteRune(r)
+			space = false
+		} else {
+			space = true
+		}
+	}
+	return builder.String()
+}
+
+// similarity is one minus the normalised Levenshtein distance of the two strings
+func similarity(a string, b string) float64 {
+	if a == b {
+		return 1
+	}
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) == 0 || len(rb) == 0 {
+		return 0
+	}
+
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+
+	longest := max(len(ra), len(rb))
+	return 1 - float64(previous[len(rb)])/float64(longest)
+}
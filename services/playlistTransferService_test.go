@@ -0,0 +1,44 @@
+package services_test
+
+import (
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/tushar27x/music-lib-api/models"
+	"github.com/tushar27x/music-lib-api/services"
+)
+
+func TestExportPlaylistLinksBelowThePublicBaseURL(t *testing.T) {
+	t.Setenv("PUBLIC_BASE_URL", "https://music.example.com/")
+	t.Setenv("STREAM_URL_TTL", "24h")
+	t.Setenv("EXPORT_URL_TTL", "10m")
+	playlist := models.Playlist{Name: "Hard bop", Entries: []models.PlaylistEntry{
+		{Song: &models.Song{ID: 1, Title: "Moment's Notice", AudioKey: "audio/1.flac"}},
+		{Song: &models.Song{ID: 2, Title: "Locomotion"}},
+	}}
+
+	file := services.ExportPlaylist(playlist, services.PublicBaseURL())
+	if len(file.Entries) != 2 {
+		t.Fatalf("exported %d entries, want 2", len(file.Entries))
+	}
+	if got := file.Entries[1].Location; got != "https://music.example.com/api/songs/2/stream" {
+		t.Errorf("location without audio = %q", got)
+	}
+
+	signed, err := url.Parse(file.Entries[0].Location)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signed.Scheme != "https" || signed.Host != "music.example.com" || signed.Path != "/api/songs/1/stream" {
+		t.Errorf("signed location = %q, want it below https://music.example.com", file.Entries[0].Location)
+	}
+	expires, err := strconv.ParseInt(signed.Query().Get("expires"), 10, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lifetime := time.Until(time.Unix(expires, 0)); lifetime > 10*time.Minute || lifetime < 9*time.Minute {
+		t.Errorf("signed URL valid for %v, want EXPORT_URL_TTL of 10m", lifetime)
+	}
+}
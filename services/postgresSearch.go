@@ -0,0 +1,64 @@
+package services
+
+import (
+	"strings"
+
+	"github.com/tushar27x/music-lib-api/search"
+	"gorm.io/gorm"
+)
+
+// postgresSearch matches the generated search_vector columns with tsqueries and tolerates
+// typos with pg_trgm. The parsed tsquery is joined in as search_query for ranking and highlighting.
+type postgresSearch struct{}
+
+func (postgresSearch) contains(column string) string {
+	return column + " ILIKE ?"
+}
+
+func (postgresSearch) match(base *gorm.DB, target searchTarget, q search.Query) *gorm.DB {
+	vector := target.table + ".search_vector"
+	query := base.Joins("CROSS JOIN to_tsquery('simple', ?) AS search_query", q.TSQuery)
+
+	// Full-text matches, plus near misses on the fuzzy columns for typos
+	conditions := []string{vector + " @@ search_query"}
+	var args []interface{}
+	if q.Text != "" {
+		for _, column := range target.fuzzy {
+			conditions = append(conditions, "? <% "+column)
+			args = append(args, q.Text)
+		}
+	}
+	query = query.Where("("+strings.Join(conditions, " OR ")+")", args...)
+
+	// Fuzzy matches bypass the tsquery, so excluded terms are checked again
+	if q.Negated != "" {
+		query = query.Where("NOT ("+vector+" @@ to_tsquery('simple', ?))", q.Negated)
+	}
+	return query
+}
+
+func (postgresSearch) ranked(query *gorm.DB, target searchTarget, q search.Query) *gorm.DB {
+	rank := "ts_rank_cd(" + target.table + ".search_vector, search_query)"
+	var rankArgs []interface{}
+	if q.Text != "" {
+		similarities := make([]string, len(target.fuzzy))
+		for i, column := range target.fuzzy {
+			similarities[i] = "word_similarity(?, " + column + ")"
+			rankArgs = append(rankArgs, q.Text)
+		}
+		rank += " + 0.5 * GREATEST(" + strings.Join(similarities, ", ") + ")"
+	}
+
+	columns := make([]string, len(target.document))
+	for i, column := range target.document {
+		columns[i] = target.table + "." + column
+		if i > 0 {
+			columns[i] = "NULLIF(" + columns[i] + ", '')"
+		}
+	}
+	document := "concat_ws(' · ', " + strings.Join(columns, ", ") + ")"
+	headline := "ts_headline('simple', " + document + ", search_query, 'StartSel=' || chr(2) || ', StopSel=' || chr(3) || ', HighlightAll=true')"
+	snippet := markedSnippet(headline, "chr(2)", "chr(3)")
+
+	return query.Select(target.table+".id AS id, "+rank+" AS rank, "+snippet+" AS snippet", rankArgs...)
+}
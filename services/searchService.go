@@ -0,0 +1,355 @@
+package services
+
+import (
+	"strings"
+
+	"github.com/tushar27x/music-lib-api/models"
+	"github.com/tushar27x/music-lib-api/repositories"
+	"github.com/tushar27x/music-lib-api/search"
+	"gorm.io/gorm"
+)
+
+// SearchService runs ranked full-text searches. It queries the database directly because ranking,
+// snippets and facets rely on the text search of the database rather than on the repositories;
+// the search columns and indexes come from the 0002_search_indexes and 0010_artists migrations.
+type SearchService struct {
+	db      *gorm.DB
+	dialect searchDialect
+}
+
+// NewSearchService returns a SearchService querying db with Postgres text search, or FTS5 on SQLite
+func NewSearchService(db *gorm.DB) *SearchService {
+	var dialect searchDialect = postgresSearch{}
+	if db.Dialector.Name() == "sqlite" {
+		dialect = sqliteSearch{}
+	}
+	return &SearchService{db: db, dialect: dialect}
+}
+
+// searchDialect writes the parts of a search that depend on the database
+type searchDialect interface {
+	// contains is a case-insensitive substring condition on column taking one escaped LIKE pattern
+	contains(column string) string
+	// match narrows a model query to the rows matching a non-empty query
+	match(base *gorm.DB, target searchTarget, q search.Query) *gorm.DB
+	// ranked selects the id, rank and snippet of every row of a matched query
+	ranked(query *gorm.DB, target searchTarget, q search.Query) *gorm.DB
+}
+
+// SongSearchFilters narrow a song search independently of the query text
+type SongSearchFilters struct {
+	Title       string
+	AlbumId     *int
+	Year        *int
+	MinDuration *int
+	MaxDuration *int
+	Categories  CategoryFilters
+}
+
+// AlbumSearchFilters narrow an album search independently of the query text
+type AlbumSearchFilters struct {
+	Id         *int
+	Title      string
+	Artist     string
+	Year       *int
+	Categories CategoryFilters
+}
+
+// CategoryFilters narrow a search to genres and tags by name, ignoring case. A genre also matches
+// its subgenres. Rows must match every genre and tag unless MatchAny is set, when one is enough.
+type CategoryFilters struct {
+	Genres   []string
+	Tags     []string
+	MatchAny bool
+}
+
+// searchTarget describes how one table is searched
+type searchTarget struct {
+	table string
+	// fuzzy columns are compared with trigram word similarity to tolerate typos
+	fuzzy []string
+	// document lists the columns snippets are cut from, joined with " · "; they come first
+	// in the full-text index, in the same order
+	document []string
+}
+
+var (
+	songSearch = searchTarget{
+		table:    "songs",
+		fuzzy:    []string{"songs.title", "songs.artist"},
+		document: []string{"title", "artist", "genre"},
+	}
+	albumSearch = searchTarget{
+		table:    "albums",
+		fuzzy:    []string{"albums.title", "albums.artist"},
+		document: []string{"title", "artist"},
+	}
+	artistSearch = searchTarget{
+		table:    "artists",
+		fuzzy:    []string{"artists.name"},
+		document: []string{"name"},
+	}
+	playlistSearch = searchTarget{
+		table:    "playlists",
+		fuzzy:    []string{"playlists.name"},
+		document: []string{"name"},
+	}
+)
+
+// markedSnippet HTML-escapes a snippet whose matches are delimited by the start and stop expressions,
+// control characters that survive escaping, and then turns them into <mark> tags. Highlighting
+// escaped text instead would match inside the entities.
+func markedSnippet(snippet string, start string, stop string) string {
+	for _, entity := range [][2]string{{"&", "&amp;"}, {"<", "&lt;"}, {">", "&gt;"}, {`"`, "&quot;"}, {"'", "&#39;"}} {
+		snippet = "replace(" + snippet + ", '" + strings.ReplaceAll(entity[0], "'", "''") + "', '" + entity[1] + "')"
+	}
+	return "replace(replace(" + snippet + ", " + start + ", '<mark>'), " + stop + ", '</mark>')"
+}
+
+// searchHit is one ranked row before the full record is loaded
+type searchHit struct {
+	ID      uint
+	Rank    float64
+	Snippet string
+}
+
+// SearchSongs runs a ranked full-text search over the user's songs
+func (s *SearchService) SearchSongs(userId uint, q search.Query, filters SongSearchFilters, limit int, offset int) ([]models.SongSearchResult, int64, error) {
+	base := s.songSearchBase(userId, filters)
+
+	hits, total, err := s.rankedSearch(base, songSearch, q, limit, offset)
+	if err != nil || len(hits) == 0 {
+		return []models.SongSearchResult{}, total, err
+	}
+
+	var songs []models.Song
+	if err := s.db.Where("id IN ?", hitIds(hits)).Find(&songs).Error; err != nil {
+		return nil, total, err
+	}
+	byId := map[uint]models.Song{}
+	for _, song := range songs {
+		byId[song.ID] = song
+	}
+
+	results := make([]models.SongSearchResult, 0, len(hits))
+	for _, hit := range hits {
+		if song, ok := byId[hit.ID]; ok {
+			results = append(results, models.SongSearchResult{Song: song, Rank: hit.Rank, Snippet: hit.Snippet})
+		}
+	}
+	return results, total, nil
+}
+
+// songSearchBase is the user's songs narrowed by the field filters
+func (s *SearchService) songSearchBase(userId uint, filters SongSearchFilters) *gorm.DB {
+	base := s.db.Model(&models.Song{}).Where("songs.user_id = ?", userId)
+	if filters.Title != "" {
+		base = base.Where(s.dialect.contains("songs.title"), "%"+escapeLike(filters.Title)+"%")
+	}
+	if filters.AlbumId != nil {
+		base = base.Where("songs.album_id = ?", *filters.AlbumId)
+	}
+	if filters.Year != nil {
+		base = base.Where("songs.year = ?", *filters.Year)
+	}
+	if filters.MinDuration != nil {
+		base = base.Where("songs.duration >= ?", *filters.MinDuration)
+	}
+	if filters.MaxDuration != nil {
+		base = base.Where("songs.duration <= ?", *filters.MaxDuration)
+	}
+	if condition, args := filters.Categories.condition("songs", userId); condition != "" {
+		base = base.Where(condition, args...)
+	}
+	return base
+}
+
+// SearchAlbums runs a ranked full-text search over the user's albums
+func (s *SearchService) SearchAlbums(userId uint, q search.Query, filters AlbumSearchFilters, limit int, offset int) ([]models.AlbumSearchResult, int64, error) {
+	base := s.albumSearchBase(userId, filters)
+
+	hits, total, err := s.rankedSearch(base, albumSearch, q, limit, offset)
+	if err != nil || len(hits) == 0 {
+		return []models.AlbumSearchResult{}, total, err
+	}
+
+	var albums []models.Album
+	if err := s.db.Preload("Songs", func(db *gorm.DB) *gorm.DB {
+		return db.Where("user_id = ?", userId)
+	}).Where("id IN ?", hitIds(hits)).Find(&albums).Error; err != nil {
+		return nil, total, err
+	}
+	byId := map[uint]models.Album{}
+	for _, album := range albums {
+		byId[album.ID] = album
+	}
+
+	results := make([]models.AlbumSearchResult, 0, len(hits))
+	for _, hit := range hits {
+		if album, ok := byId[hit.ID]; ok {
+			results = append(results, models.AlbumSearchResult{Album: album, Rank: hit.Rank, Snippet: hit.Snippet})
+		}
+	}
+	return results, total, nil
+}
+
+// albumSearchBase is the user's albums narrowed by the field filters
+func (s *SearchService) albumSearchBase(userId uint, filters AlbumSearchFilters) *gorm.DB {
+	base := s.db.Model(&models.Album{}).Where("albums.user_id = ?", userId)
+	if filters.Id != nil {
+		base = base.Where("albums.id = ?", *filters.Id)
+	}
+	if filters.Title != "" {
+		base = base.Where(s.dialect.contains("albums.title"), "%"+escapeLike(filters.Title)+"%")
+	}
+	if filters.Artist != "" {
+		base = base.Where(s.dialect.contains("albums.artist"), "%"+escapeLike(filters.Artist)+"%")
+	}
+	if filters.Year != nil {
+		base = base.Where("albums.year = ?", *filters.Year)
+	}
+	if condition, args := filters.Categories.condition("albums", userId); condition != "" {
+		base = base.Where(condition, args...)
+	}
+	return base
+}
+
+// SearchArtists runs a ranked full-text search over the names of the user's artists
+func (s *SearchService) SearchArtists(userId uint, q search.Query, limit int, offset int) ([]models.ArtistSearchResult, int64, error) {
+	base := s.db.Model(&models.Artist{}).Where("artists.user_id = ?", userId)
+
+	hits, total, err := s.rankedSearch(base, artistSearch, q, limit, offset)
+	if err != nil || len(hits) == 0 {
+		return []models.ArtistSearchResult{}, total, err
+	}
+
+	var artists []models.Artist
+	if err := s.db.Where("id IN ?", hitIds(hits)).Find(&artists).Error; err != nil {
+		return nil, total, err
+	}
+	byId := map[uint]models.Artist{}
+	for _, artist := range artists {
+		byId[artist.ID] = artist
+	}
+
+	results := make([]models.ArtistSearchResult, 0, len(hits))
+	for _, hit := range hits {
+		if artist, ok := byId[hit.ID]; ok {
+			results = append(results, models.ArtistSearchResult{Artist: artist, Rank: hit.Rank, Snippet: hit.Snippet})
+		}
+	}
+	return results, total, nil
+}
+
+// SearchPlaylists runs a ranked full-text search over the user's playlist names
+func (s *SearchService) SearchPlaylists(userId uint, q search.Query, name string, limit int, offset int) ([]models.PlaylistSearchResult, int64, error) {
+	base := s.db.Model(&models.Playlist{}).Where("playlists.user_id = ?", userId)
+	if name != "" {
+		base = base.Where(s.dialect.contains("playlists.name"), "%"+escapeLike(name)+"%")
+	}
+
+	hits, total, err := s.rankedSearch(base, playlistSearch, q, limit, offset)
+	if err != nil || len(hits) == 0 {
+		return []models.PlaylistSearchResult{}, total, err
+	}
+
+	var playlists []models.Playlist
+	if err := s.db.Scopes(repositories.WithTracks).Where("id IN ?", hitIds(hits)).Find(&playlists).Error; err != nil {
+		return nil, total, err
+	}
+	byId := map[uint]models.Playlist{}
+	for _, playlist := range playlists {
+		byId[playlist.ID] = playlist
+	}
+
+	results := make([]models.PlaylistSearchResult, 0, len(hits))
+	for _, hit := range hits {
+		if playlist, ok := byId[hit.ID]; ok {
+			results = append(results, models.PlaylistSearchResult{Playlist: playlist, Rank: hit.Rank, Snippet: hit.Snippet})
+		}
+	}
+	return results, total, nil
+}
+
+// rankedSearch applies the query to an already filtered model query and returns one page of IDs,
+// best match first, with the total number of matches. Without a query everything is returned in ID order.
+func (s *SearchService) rankedSearch(base *gorm.DB, target searchTarget, q search.Query, limit int, offset int) ([]searchHit, int64, error) {
+	var hits []searchHit
+	var total int64
+
+	id := target.table + ".id"
+	query := s.matchQuery(base, target, q).Session(&gorm.Session{})
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if q.Empty() {
+		err := query.Select(id + " AS id").Order(id + " ASC").Limit(limit).Offset(offset).Scan(&hits).Error
+		return hits, total, err
+	}
+
+	err := s.dialect.ranked(query, target, q).
+		Order("rank DESC").
+		Order(id + " ASC").
+		Limit(limit).
+		Offset(offset).
+		Scan(&hits).Error
+	return hits, total, err
+}
+
+// matchQuery narrows a model query to the rows matching q
+func (s *SearchService) matchQuery(base *gorm.DB, target searchTarget, q search.Query) *gorm.DB {
+	if q.Empty() {
+		return base
+	}
+	return s.dialect.match(base, target, q)
+}
+
+// condition returns the SQL condition on table, songs or albums, matching the filters, or "" when
+// there are none. Genres are expanded to their subgenres with a recursive query.
+func (f CategoryFilters) condition(table string, userId uint) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	for _, genre := range f.Genres {
+		conditions = append(conditions, table+`.genre_id IN (
+			WITH RECURSIVE covered (id) AS (
+				SELECT id FROM genres WHERE user_id = ? AND LOWER(name) = LOWER(?)
+				UNION SELECT genres.id FROM genres JOIN covered ON genres.parent_id = covered.id
+			)
+			SELECT id FROM covered
+		)`)
+		args = append(args, userId, genre)
+	}
+
+	tagged, item := "tagged_"+table, strings.TrimSuffix(table, "s")+"_id"
+	for _, tag := range f.Tags {
+		conditions = append(conditions, `EXISTS (
+			SELECT 1 FROM `+tagged+` JOIN tags ON tags.id = `+tagged+`.tag_id
+			WHERE `+tagged+`.`+item+` = `+table+`.id AND tags.user_id = ? AND LOWER(tags.name) = LOWER(?)
+		)`)
+		args = append(args, userId, tag)
+	}
+
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	operator := " AND "
+	if f.MatchAny {
+		operator = " OR "
+	}
+	return "(" + strings.Join(conditions, operator) + ")", args
+}
+
+func hitIds(hits []searchHit) []uint {
+	ids := make([]uint, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.ID
+	}
+	return ids
+}
+
+// escapeLike escapes the LIKE wildcards in user input
+func escapeLike(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return replacer.Replace(value)
+}
@@ -0,0 +1,160 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tushar27x/music-lib-api/models"
+)
+
+// Limits that keep smart playlist definitions cheap to evaluate
+const (
+	maxSmartRules      = 50
+	maxSmartGroupDepth = 4
+	maxSmartLimit      = 1000
+)
+
+type smartFieldKind int
+
+const (
+	textField smartFieldKind = iota
+	numberField
+	dateField
+)
+
+// smartFields lists the rule fields and the kind of value each compares; the repositories map them to columns
+var smartFields = map[string]smartFieldKind{
+	"title":      textField,
+	"artist":     textField,
+	"genre":      textField,
+	"album":      textField,
+	"duration":   numberField,
+	"year":       numberField,
+	"album_year": numberField,
+	"play_count": numberField,
+	"added":      dateField,
+}
+
+var smartOperators = map[smartFieldKind][]string{
+	textField:   {"contains", "not_contains", "equals", "not_equals", "starts_with", "ends_with"},
+	numberField: {"equals", "not_equals", "gt", "gte", "lt", "lte", "between"},
+	dateField:   {"in_last_days", "not_in_last_days"},
+}
+
+var smartSorts = map[string]bool{
+	"title":      true,
+	"artist":     true,
+	"duration":   true,
+	"year":       true,
+	"album_year": true,
+	"added":      true,
+	"play_count": true,
+}
+
+// SmartRulesError carries one validation error per invalid field of a smart playlist definition
+type SmartRulesError struct {
+	Fields []models.ValidationError
+}
+
+func (e *SmartRulesError) Error() string {
+	return "invalid smart playlist rules"
+}
+
+// ValidateSmartRules checks a smart playlist definition and returns one error per invalid field
+func ValidateSmartRules(rules *models.SmartPlaylistRules) []models.ValidationError {
+	if rules == nil {
+		return []models.ValidationError{{Field: "rules", Message: "rules are required for smart playlists"}}
+	}
+
+	var errs []models.ValidationError
+	count := 0
+	validateSmartGroup(rules.SmartRuleGroup, "rules", 1, &count, &errs)
+
+	if count > maxSmartRules {
+		errs = append(errs, models.ValidationError{Field: "rules", Message: fmt.Sprintf("at most %d rules are allowed", maxSmartRules)})
+	}
+
+	sort := strings.TrimPrefix(rules.Sort, "-")
+	if rules.Sort != "" && !smartSorts[sort] && rules.Sort != "random" {
+		errs = append(errs, models.ValidationError{Field: "rules.sort", Message: fmt.Sprintf("unknown sort field %q", rules.Sort)})
+	}
+	if rules.Limit < 0 || rules.Limit > maxSmartLimit {
+		errs = append(errs, models.ValidationError{Field: "rules.limit", Message: fmt.Sprintf("limit must be between 0 and %d", maxSmartLimit)})
+	}
+
+	return errs
+}
+
+func validateSmartGroup(group models.SmartRuleGroup, path string, depth int, count *int, errs *[]models.ValidationError) {
+	if group.Match != "all" && group.Match != "any" {
+		*errs = append(*errs, models.ValidationError{Field: path + ".match", Message: "match must be all or any"})
+	}
+	if depth > maxSmartGroupDepth {
+		*errs = append(*errs, models.ValidationError{Field: path, Message: fmt.Sprintf("groups can be nested at most %d levels deep", maxSmartGroupDepth)})
+		return
+	}
+	if depth > 1 && len(group.Rules) == 0 && len(group.Groups) == 0 {
+		*errs = append(*errs, models.ValidationError{Field: path, Message: "group must contain at least one rule"})
+	}
+
+	for i, rule := range group.Rules {
+		*count++
+		rulePath := fmt.Sprintf("%s.rules[%d]", path, i)
+		if message, field := validateSmartRule(rule); message != "" {
+			*errs = append(*errs, models.ValidationError{Field: rulePath + "." + field, Message: message})
+		}
+	}
+	for i, child := range group.Groups {
+		validateSmartGroup(child, fmt.Sprintf("%s.groups[%d]", path, i), depth+1, count, errs)
+	}
+}
+
+// validateSmartRule returns an error message and the offending rule attribute
+func validateSmartRule(rule models.SmartRule) (string, string) {
+	kind, ok := smartFields[rule.Field]
+	if !ok {
+		return fmt.Sprintf("unknown field %q", rule.Field), "field"
+	}
+
+	supported := false
+	for _, operator := range smartOperators[kind] {
+		if operator == rule.Operator {
+			supported = true
+			break
+		}
+	}
+	if !supported {
+		return fmt.Sprintf("operator %q is not supported for field %s", rule.Operator, rule.Field), "operator"
+	}
+
+	switch {
+	case rule.Operator == "between":
+		values, ok := rule.Value.([]interface{})
+		if !ok || len(values) != 2 {
+			return "between needs a [min, max] array", "value"
+		}
+		min, minOk := values[0].(float64)
+		max, maxOk := values[1].(float64)
+		if !minOk || !maxOk {
+			return "between bounds must be numbers", "value"
+		}
+		if min > max {
+			return "between minimum is greater than the maximum", "value"
+		}
+	case kind == textField:
+		text, ok := rule.Value.(string)
+		if !ok || text == "" {
+			return "value must be a non-empty string", "value"
+		}
+	default:
+		number, ok := rule.Value.(float64)
+		if !ok {
+			return "value must be a number", "value"
+		}
+		if kind == dateField && number <= 0 {
+			return "number of days must be positive", "value"
+		}
+	}
+
+	return "", ""
+}
@@ -0,0 +1,229 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"github.com/tushar27x/music-lib-api/models"
+	"github.com/tushar27x/music-lib-api/repositories"
+	"github.com/tushar27x/music-lib-api/storage"
+)
+
+var (
+	// ErrSongNotFound is returned when a song does not exist or belongs to someone else
+	ErrSongNotFound = errors.New("song not found")
+	// ErrAlbumNotFound is returned when a referenced album does not exist
+	ErrAlbumNotFound = errors.New("album not found")
+	// ErrAlbumNotOwned is returned when a song is put on another user's album
+	ErrAlbumNotOwned = errors.New("you don't own this album")
+	// ErrTrackPositionTaken is returned when another song on the album has the same disc and track number
+	ErrTrackPositionTaken = errors.New("another song on the album has this disc and track number")
+)
+
+// SongService holds the rules for a user's songs and their audio files
+type SongService struct {
+	store repositories.Store
+	blobs storage.Storage
+}
+
+// NewSongService returns a SongService keeping songs in store and audio files in blobs
+func NewSongService(store repositories.Store, blobs storage.Storage) *SongService {
+	return &SongService{store: store, blobs: blobs}
+}
+
+// Create adds a song to the user's library, credits its artists and puts it in its genre with its
+// tags; an album, when given, must be the user's
+func (s *SongService) Create(userId uint, song *models.Song) error {
+	if song.AlbumId != nil {
+		album, err := s.store.Albums().FindByID(*song.AlbumId)
+		if errors.Is(err, repositories.ErrNotFound) {
+			return ErrAlbumNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if album.UserId != userId {
+			return ErrAlbumNotOwned
+		}
+	}
+
+	song.UserId = userId
+	return s.store.Transaction(func(tx repositories.Store) error {
+		credits, err := resolveCredits(tx, userId, song.Artist, song.Credits, nil)
+		if err != nil {
+			return err
+		}
+		song.Artist = creditedArtist(credits)
+
+		genre, err := resolveGenre(tx, userId, song.GenreId, song.Genre)
+		if err != nil {
+			return err
+		}
+		song.GenreId, song.Genre = genreFields(genre)
+
+		if err := placeTrack(tx, song); err != nil {
+			return err
+		}
+		err = tx.Songs().Create(song)
+		if errors.Is(err, repositories.ErrDuplicate) {
+			return ErrTrackPositionTaken
+		}
+		if err != nil {
+			return err
+		}
+		song.Credits = credits
+		if err := saveSongCredits(tx, song.ID, credits); err != nil {
+			return err
+		}
+		if song.Tags != nil {
+			song.Tags, err = replaceSongTags(tx, userId, song.ID, song.Tags)
+		}
+		return err
+	})
+}
+
+// List returns every song of the user with its credits and tags
+func (s *SongService) List(userId uint) ([]models.Song, error) {
+	songs, err := s.store.Songs().FindByUser(userId)
+	if err != nil {
+		return nil, err
+	}
+	if err := attachSongCredits(s.store, songs); err != nil {
+		return nil, err
+	}
+	return songs, attachSongTags(s.store, songs)
+}
+
+// Get returns a song of the user with its credits and tags
+func (s *SongService) Get(userId uint, songId uint) (models.Song, error) {
+	song, err := s.store.Songs().FindOwned(userId, songId)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return song, ErrSongNotFound
+	}
+	if err != nil {
+		return song, err
+	}
+
+	if song.Credits, err = songCredits(s.store, song.ID); err != nil {
+		return song, err
+	}
+	songs := []models.Song{song}
+	err = attachSongTags(s.store, songs)
+	return songs[0], err
+}
+
+// GetByID returns a song whoever owns it, for requests already authorised by a signed URL
+func (s *SongService) GetByID(songId uint) (models.Song, error) {
+	song, err := s.store.Songs().FindByID(songId)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return song, ErrSongNotFound
+	}
+	return song, err
+}
+
+// Update replaces the editable fields, the genre and, when given, the credits and tags of a song of the user
+func (s *SongService) Update(userId uint, songId uint, input models.SongCreateRequest) (models.Song, error) {
+	song, err := s.Get(userId, songId)
+	if err != nil {
+		return song, err
+	}
+
+	if input.AlbumId != nil {
+		if _, err := s.store.Albums().FindOwned(userId, *input.AlbumId); err != nil {
+			if errors.Is(err, repositories.ErrNotFound) {
+				return song, ErrAlbumNotOwned
+			}
+			return song, err
+		}
+	}
+
+	err = s.store.Transaction(func(tx repositories.Store) error {
+		credits, err := resolveCredits(tx, userId, input.Artist, input.Credits, song.Credits)
+		if err != nil {
+			return err
+		}
+		genre, err := resolveGenre(tx, userId, input.GenreId, input.Genre)
+		if err != nil {
+			return err
+		}
+
+		song.Title = input.Title
+		song.Duration = input.Duration
+		song.Artist = creditedArtist(credits)
+		song.GenreId, song.Genre = genreFields(genre)
+		song.Year = input.Year
+		song.TrackNumber = input.TrackNumber
+		song.DiscNumber = input.DiscNumber
+		song.DiscSubtitle = input.DiscSubtitle
+		song.AlbumId = input.AlbumId
+
+		if err := placeTrack(tx, &song); err != nil {
+			return err
+		}
+		err = tx.Songs().Update(&song, "title", "duration", "artist", "genre", "genre_id", "year", "track_number", "disc_number", "disc_subtitle", "album_id")
+		if errors.Is(err, repositories.ErrDuplicate) {
+			return ErrTrackPositionTaken
+		}
+		if err != nil {
+			return err
+		}
+		song.Credits = credits
+		if err := saveSongCredits(tx, song.ID, credits); err != nil {
+			return err
+		}
+		if input.Tags != nil {
+			song.Tags, err = replaceSongTags(tx, userId, song.ID, input.Tags)
+		}
+		return err
+	})
+	return song, err
+}
+
+// placeTrack puts a numbered song of an album on disc 1 unless it has a disc number, and checks
+// that no other song of the album has its disc and track number
+func placeTrack(tx repositories.Store, song *models.Song) error {
+	if song.AlbumId == nil || song.TrackNumber == 0 {
+		return nil
+	}
+	if song.DiscNumber == 0 {
+		song.DiscNumber = 1
+	}
+
+	album, err := tx.Albums().FindOwnedWithSongs(song.UserId, *song.AlbumId)
+	if err != nil {
+		return err
+	}
+	for _, other := range album.Songs {
+		if other.ID != song.ID && other.DiscNumber == song.DiscNumber && other.TrackNumber == song.TrackNumber {
+			return ErrTrackPositionTaken
+		}
+	}
+	return nil
+}
+
+// Delete removes a song of the user and takes it out of every playlist
+func (s *SongService) Delete(userId uint, songId uint) error {
+	return s.store.Transaction(func(tx repositories.Store) error {
+		song, err := tx.Songs().FindOwned(userId, songId)
+		if errors.Is(err, repositories.ErrNotFound) {
+			return ErrSongNotFound
+		}
+		if err != nil {
+			return err
+		}
+		return deleteSong(tx, song)
+	})
+}
+
+// deleteSong deletes a song and takes it out of every playlist; the playlists keep existing
+func deleteSong(tx repositories.Store, song models.Song) error {
+	if err := removeSongFromPlaylists(tx, song.ID); err != nil {
+		return err
+	}
+	return tx.Songs().Delete(&song)
+}
+
+// OpenAudio returns a seekable reader over the song's audio file
+func (s *SongService) OpenAudio(ctx context.Context, song models.Song) *storage.RangeReader {
+	return storage.NewRangeReader(ctx, s.blobs, song.AudioKey, song.AudioSize)
+}
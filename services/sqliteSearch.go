@@ -0,0 +1,104 @@
+package services
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/tushar27x/music-lib-api/search"
+	"gorm.io/gorm"
+)
+
+// fuzzyThreshold is the word similarity a fuzzy column needs to match, the default of pg_trgm's <% operator
+const fuzzyThreshold = "0.6"
+
+// sqliteSearch matches the <table>_fts FTS5 indexes and tolerates typos with the word_similarity
+// function registered by config.OpenDB. FTS5 matches are joined in as search_match, carrying their
+// bm25 score and highlighted columns; rows found only by similarity have no search_match.
+type sqliteSearch struct{}
+
+func (sqliteSearch) contains(column string) string {
+	// LIKE ignores case in SQLite, and has no default escape character
+	return column + ` LIKE ? ESCAPE '\'`
+}
+
+func (sqliteSearch) match(base *gorm.DB, target searchTarget, q search.Query) *gorm.DB {
+	fts := target.table + "_fts"
+	id := target.table + ".id"
+	query := base
+
+	// A query of exclusions only matches every row without them, like its tsquery does
+	if q.FTS != "" {
+		highlights := make([]string, len(target.document))
+		for i := range target.document {
+			highlights[i] = "highlight(" + fts + ", " + strconv.Itoa(i) + ", char(2), char(3)) AS column_" + strconv.Itoa(i)
+		}
+		query = query.Joins(
+			"LEFT JOIN (SELECT rowid AS id, bm25("+fts+") AS score, "+strings.Join(highlights, ", ")+
+				" FROM "+fts+" WHERE "+fts+" MATCH ?) AS search_match ON search_match.id = "+id,
+			q.FTS,
+		)
+
+		// Full-text matches, plus near misses on the fuzzy columns for typos
+		conditions := []string{"search_match.id IS NOT NULL"}
+		var args []interface{}
+		if q.Text != "" {
+			for _, column := range target.fuzzy {
+				conditions = append(conditions, "word_similarity(?, "+column+") >= "+fuzzyThreshold)
+				args = append(args, q.Text)
+			}
+		}
+		query = query.Where("("+strings.Join(conditions, " OR ")+")", args...)
+	}
+
+	if q.NegatedFTS != "" {
+		query = query.Where(id+" NOT IN (SELECT rowid FROM "+fts+" WHERE "+fts+" MATCH ?)", q.NegatedFTS)
+	}
+	return query
+}
+
+func (sqliteSearch) ranked(query *gorm.DB, target searchTarget, q search.Query) *gorm.DB {
+	columns := make([]string, len(target.document))
+	for i, column := range target.document {
+		columns[i] = target.table + "." + column
+	}
+	document := sqliteDocument(columns)
+	if q.FTS == "" {
+		return query.Select(target.table + ".id AS id, 0 AS rank, " + markedSnippet(document, "char(2)", "char(3)") + " AS snippet")
+	}
+
+	// bm25 scores are negative, better matches being lower
+	rank := "COALESCE(-search_match.score, 0)"
+	var rankArgs []interface{}
+	if q.Text != "" {
+		similarities := make([]string, len(target.fuzzy))
+		for i, column := range target.fuzzy {
+			similarities[i] = "word_similarity(?, " + column + ")"
+			rankArgs = append(rankArgs, q.Text)
+		}
+		// With a single argument MAX would be the aggregate
+		similarity := similarities[0]
+		if len(similarities) > 1 {
+			similarity = "MAX(" + strings.Join(similarities, ", ") + ")"
+		}
+		rank += " + 0.5 * " + similarity
+	}
+
+	highlights := make([]string, len(target.document))
+	for i := range target.document {
+		highlights[i] = "search_match.column_" + strconv.Itoa(i)
+	}
+	// Rows found by similarity alone have nothing highlighted
+	snippet := markedSnippet("COALESCE("+sqliteDocument(highlights)+", "+document+")", "char(2)", "char(3)")
+
+	return query.Select(target.table+".id AS id, "+rank+" AS rank, "+snippet+" AS snippet", rankArgs...)
+}
+
+// sqliteDocument joins columns with " · " and skips the empty ones after the first, like the
+// concat_ws document in Postgres; it is NULL when the first column is
+func sqliteDocument(columns []string) string {
+	document := columns[0]
+	for _, column := range columns[1:] {
+		document += " || COALESCE(' · ' || NULLIF(" + column + ", ''), '')"
+	}
+	return document
+}
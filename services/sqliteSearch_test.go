@@ -0,0 +1,85 @@
+package services_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tushar27x/music-lib-api/config"
+	"github.com/tushar27x/music-lib-api/migrations"
+	"github.com/tushar27x/music-lib-api/models"
+	"github.com/tushar27x/music-lib-api/search"
+	"github.com/tushar27x/music-lib-api/services"
+	"gorm.io/gorm"
+)
+
+// openSQLite returns a fully migrated in-memory SQLite database
+func openSQLite(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := config.OpenDB("sqlite::memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrator, err := migrations.New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(0); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestSQLiteSongSearch(t *testing.T) {
+	db := openSQLite(t)
+	user := models.User{Name: "Ada", Email: "ada@example.com", Role: "artist"}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, song := range []models.Song{
+		{Title: "Bohemian Rhapsody", Artist: "Queen", Genre: "Rock"},
+		{Title: "Under Pressure", Artist: "Queen"},
+		{Title: "Stairway to Heaven", Artist: "Led Zeppelin"},
+		{Title: `<script>alert("Tom's")</script>`, Artist: "Mallory & Co"},
+	} {
+		song.UserId = user.ID
+		if err := db.Create(&song).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	searches := services.NewSearchService(db)
+
+	tests := []struct {
+		name    string
+		query   string
+		titles  []string
+		snippet string
+	}{
+		{name: "full text", query: "queen", titles: []string{"Under Pressure", "Bohemian Rhapsody"}, snippet: "<mark>Queen</mark>"},
+		{name: "typo", query: "rhapsodie", titles: []string{"Bohemian Rhapsody"}, snippet: "Bohemian Rhapsody · Queen · Rock"},
+		{name: "prefix", query: "stair*", titles: []string{"Stairway to Heaven"}, snippet: "<mark>Stairway</mark>"},
+		{name: "exclusion", query: "queen -pressure", titles: []string{"Bohemian Rhapsody"}},
+		{name: "escaped", query: "script", titles: []string{`<script>alert("Tom's")</script>`},
+			snippet: "&lt;<mark>script</mark>&gt;alert(&quot;Tom&#39;s&quot;)&lt;/<mark>script</mark>&gt; · Mallory &amp; Co"},
+		{name: "escaped typo", query: "malory", titles: []string{`<script>alert("Tom's")</script>`},
+			snippet: "&lt;script&gt;alert(&quot;Tom&#39;s&quot;)&lt;/script&gt; · Mallory &amp; Co"},
+		{name: "no match", query: "coltrane"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hits, total, err := searches.SearchSongs(user.ID, search.Parse(tt.query), services.SongSearchFilters{}, 10, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var titles []string
+			for _, hit := range hits {
+				titles = append(titles, hit.Title)
+			}
+			if total != int64(len(tt.titles)) || strings.Join(titles, "|") != strings.Join(tt.titles, "|") {
+				t.Fatalf("hits = %v (total %d), want %v", titles, total, tt.titles)
+			}
+			if tt.snippet != "" && !strings.Contains(hits[0].Snippet, tt.snippet) {
+				t.Errorf("snippet = %q, want it to contain %q", hits[0].Snippet, tt.snippet)
+			}
+		})
+	}
+}
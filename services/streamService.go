@@ -0,0 +1,75 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tushar27x/music-lib-api/config"
+)
+
+// StreamURLTTL returns how long signed stream URLs stay valid (STREAM_URL_TTL, default 1h)
+func StreamURLTTL() time.Duration {
+	return config.GetEnvDuration("STREAM_URL_TTL", time.Hour)
+}
+
+// ExportURLTTL returns how long the signed stream URLs in exported playlist files stay valid
+// (EXPORT_URL_TTL, default STREAM_URL_TTL). Anyone holding the file can play its songs until then.
+func ExportURLTTL() time.Duration {
+	return config.GetEnvDuration("EXPORT_URL_TTL", StreamURLTTL())
+}
+
+// PublicBaseURL returns the address clients reach the API at, which exported playlist files link
+// to (PUBLIC_BASE_URL, default http://localhost:PORT)
+func PublicBaseURL() string {
+	baseURL := config.GetEnv("PUBLIC_BASE_URL")
+	if baseURL == "" {
+		baseURL = "http://localhost:" + config.GetEnv("PORT")
+	}
+	return strings.TrimRight(baseURL, "/")
+}
+
+// SignStreamURL returns a stream path for the song that can be used without an
+// Authorization header until expiresAt
+func SignStreamURL(songId uint, expiresAt time.Time) string {
+	expires := expiresAt.Unix()
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(expires, 10))
+	query.Set("signature", streamSignature(songId, expires))
+	return fmt.Sprintf("/api/songs/%d/stream?%s", songId, query.Encode())
+}
+
+// VerifyStreamSignature checks a signature produced by SignStreamURL and that it has not expired
+func VerifyStreamSignature(songId uint, expires string, signature string) bool {
+	expiresUnix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > expiresUnix {
+		return false
+	}
+
+	expected := streamSignature(songId, expiresUnix)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
+
+func streamSignature(songId uint, expires int64) string {
+	mac := hmac.New(sha256.New, JWTSecret())
+	fmt.Fprintf(mac, "stream:%d:%d", songId, expires)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// IsPlaybackStart reports whether a request with this Range header starts playing from the beginning
+func IsPlaybackStart(rangeHeader string) bool {
+	if rangeHeader == "" {
+		return true
+	}
+	return strings.HasPrefix(strings.TrimSpace(rangeHeader), "bytes=0-")
+}
+
+// RecordPlay increments the play count of a song and stamps when it was last played
+func (s *SongService) RecordPlay(songId uint) error {
+	return s.store.Songs().RecordPlay(songId, time.Now())
+}
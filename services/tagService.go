@@ -0,0 +1,275 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/tushar27x/music-lib-api/metadata"
+	"github.com/tushar27x/music-lib-api/models"
+	"github.com/tushar27x/music-lib-api/repositories"
+)
+
+var (
+	// ErrNoAudio is returned when a song has no uploaded audio file
+	ErrNoAudio = errors.New("song has no audio file")
+	// ErrNoTags is returned when the audio format carries no tags we can read
+	ErrNoTags = errors.New("no readable tags in this audio format")
+	// ErrAlbumCreateForbidden is returned when a user who may not manage albums asks for one to be created
+	ErrAlbumCreateForbidden = errors.New("not allowed to create albums")
+)
+
+// TagFields are the song fields that can be filled from tags
+var TagFields = []string{"title", "artist", "genre", "year", "track_number", "disc_number", "duration"}
+
+// ExtractTags reads the tags of the audio file attached to the song
+func (s *SongService) ExtractTags(ctx context.Context, song models.Song) (models.SongTags, error) {
+	if song.AudioKey == "" {
+		return models.SongTags{}, ErrNoAudio
+	}
+
+	reader := s.OpenAudio(ctx, song)
+	defer reader.Close()
+
+	tags, err := metadata.Read(reader, song.AudioSize)
+	if err != nil {
+		if errors.Is(err, metadata.ErrUnsupported) {
+			return models.SongTags{}, ErrNoTags
+		}
+		return models.SongTags{}, err
+	}
+
+	return models.SongTags{
+		Format:      tags.Format,
+		Title:       tags.Title,
+		Artist:      tags.Artist,
+		Album:       tags.Album,
+		AlbumArtist: tags.AlbumArtist,
+		Genre:       tags.Genre,
+		Year:        tags.Year,
+		TrackNumber: uint(tags.TrackNumber),
+		TrackTotal:  uint(tags.TrackTotal),
+		DiscNumber:  uint(tags.DiscNumber),
+		DiscTotal:   uint(tags.DiscTotal),
+		Duration:    uint(tags.Duration.Milliseconds()),
+		HasPicture:  tags.Picture != nil,
+	}, nil
+}
+
+// PreviewTags works out which song fields the tags would change and how the album would be resolved
+func (s *SongService) PreviewTags(userId uint, song models.Song, tags models.SongTags) (models.SongTagsPreview, error) {
+	preview := models.SongTagsPreview{
+		Tags:        tags,
+		Changes:     tagChanges(song, tags, TagFields),
+		AlbumAction: "none",
+	}
+
+	if tags.Album == "" {
+		return preview, nil
+	}
+
+	album, err := findTaggedAlbum(s.store.Albums(), userId, tags)
+	if err != nil {
+		return preview, err
+	}
+	if album != nil {
+		preview.AlbumId = &album.ID
+		if song.AlbumId == nil || *song.AlbumId != album.ID {
+			preview.AlbumAction = "link"
+		}
+	} else {
+		preview.AlbumAction = "create"
+	}
+
+	return preview, nil
+}
+
+// ApplyTags writes the selected tag fields to the song and links or creates its album
+func (s *SongService) ApplyTags(userId uint, role string, song *models.Song, tags models.SongTags, input models.SongTagsApplyRequest) error {
+	fields := input.Fields
+	if len(fields) == 0 {
+		fields = TagFields
+	}
+	changes := tagChanges(*song, tags, fields)
+
+	return s.store.Transaction(func(tx repositories.Store) error {
+		updated := *song
+		columns := applyTagChanges(&updated, changes)
+
+		if tags.Album != "" && (input.Album == "link" || input.Album == "create") {
+			album, err := findTaggedAlbum(tx.Albums(), userId, tags)
+			if err != nil {
+				return err
+			}
+
+			if album == nil && input.Album == "create" {
+				if !HasPermission(role, models.PermissionManageAlbums) {
+					return ErrAlbumCreateForbidden
+				}
+				credits, err := resolveCredits(tx, userId, albumArtist(tags), nil, nil)
+				if err != nil {
+					return err
+				}
+				album = &models.Album{
+					Title:  tags.Album,
+					Artist: creditedArtist(credits),
+					Year:   tags.Year,
+					UserId: userId,
+				}
+				if err := tx.Albums().Create(album); err != nil {
+					return err
+				}
+				if err := saveAlbumCredits(tx, album.ID, credits); err != nil {
+					return err
+				}
+			}
+
+			if album != nil {
+				updated.AlbumId = &album.ID
+				columns = append(columns, "album_id")
+			}
+		}
+
+		// The tagged artist becomes the primary artist, keeping the other credits
+		var credits []models.ArtistCredit
+		if slices.Contains(columns, "artist") {
+			current, err := songCredits(tx, song.ID)
+			if err != nil {
+				return err
+			}
+			if credits, err = resolveCredits(tx, userId, updated.Artist, nil, current); err != nil {
+				return err
+			}
+			updated.Artist = creditedArtist(credits)
+		}
+
+		// The tagged genre becomes the user's genre of that name, which is created when missing
+		if slices.Contains(columns, "genre") {
+			genre, err := resolveGenre(tx, userId, nil, updated.Genre)
+			if err != nil {
+				return err
+			}
+			updated.GenreId, updated.Genre = genreFields(genre)
+			columns = append(columns, "genre_id")
+		}
+
+		if len(columns) == 0 {
+			return nil
+		}
+		discNumber := updated.DiscNumber
+		if err := placeTrack(tx, &updated); err != nil {
+			return err
+		}
+		if updated.DiscNumber != discNumber && !slices.Contains(columns, "disc_number") {
+			columns = append(columns, "disc_number")
+		}
+		err := tx.Songs().Update(&updated, columns...)
+		if errors.Is(err, repositories.ErrDuplicate) {
+			return ErrTrackPositionTaken
+		}
+		if err != nil {
+			return err
+		}
+		if credits != nil {
+			if err := saveSongCredits(tx, song.ID, credits); err != nil {
+				return err
+			}
+			updated.Credits = credits
+		}
+		*song = updated
+		return nil
+	})
+}
+
+// tagChanges returns the requested fields whose tag value is set and differs from the song
+func tagChanges(song models.Song, tags models.SongTags, fields []string) map[string]interface{} {
+	changes := map[string]interface{}{}
+	for _, field := range fields {
+		switch field {
+		case "title":
+			if tags.Title != "" && tags.Title != song.Title {
+				changes[field] = tags.Title
+			}
+		case "artist":
+			// Artist names are matched to artists ignoring case
+			if tags.Artist != "" && !strings.EqualFold(tags.Artist, song.Artist) {
+				changes[field] = tags.Artist
+			}
+		case "genre":
+			// Genre names are matched to genres ignoring case
+			if tags.Genre != "" && !strings.EqualFold(tags.Genre, song.Genre) {
+				changes[field] = tags.Genre
+			}
+		case "year":
+			if tags.Year != 0 && tags.Year != song.Year {
+				changes[field] = tags.Year
+			}
+		case "track_number":
+			if tags.TrackNumber != 0 && tags.TrackNumber != song.TrackNumber {
+				changes[field] = tags.TrackNumber
+			}
+		case "disc_number":
+			if tags.DiscNumber != 0 && tags.DiscNumber != song.DiscNumber {
+				changes[field] = tags.DiscNumber
+			}
+		case "duration":
+			if tags.Duration != 0 && tags.Duration != song.Duration {
+				changes[field] = tags.Duration
+			}
+		}
+	}
+	return changes
+}
+
+// applyTagChanges copies the changes returned by tagChanges onto the song and returns the changed columns
+func applyTagChanges(song *models.Song, changes map[string]interface{}) []string {
+	var columns []string
+	for _, field := range TagFields {
+		value, ok := changes[field]
+		if !ok {
+			continue
+		}
+		switch field {
+		case "title":
+			song.Title = value.(string)
+		case "artist":
+			song.Artist = value.(string)
+		case "genre":
+			song.Genre = value.(string)
+		case "year":
+			song.Year = value.(int)
+		case "track_number":
+			song.TrackNumber = value.(uint)
+		case "disc_number":
+			song.DiscNumber = value.(uint)
+		case "duration":
+			song.Duration = value.(uint)
+		}
+		columns = append(columns, field)
+	}
+	return columns
+}
+
+// findTaggedAlbum looks for an album of the user matching the tagged title, preferring a matching artist
+func findTaggedAlbum(repository repositories.AlbumRepository, userId uint, tags models.SongTags) (*models.Album, error) {
+	albums, err := repository.FindByTitle(userId, tags.Album)
+	if err != nil {
+		return nil, err
+	}
+
+	artist := albumArtist(tags)
+	for i := range albums {
+		if artist == "" || albums[i].Artist == "" || strings.EqualFold(albums[i].Artist, artist) {
+			return &albums[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func albumArtist(tags models.SongTags) string {
+	if tags.AlbumArtist != "" {
+		return tags.AlbumArtist
+	}
+	return tags.Artist
+}
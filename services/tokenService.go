@@ -0,0 +1,319 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/tushar27x/music-lib-api/config"
+	"github.com/tushar27x/music-lib-api/models"
+	"github.com/tushar27x/music-lib-api/repositories"
+)
+
+var (
+	// ErrInvalidToken is returned when an access token cannot be verified
+	ErrInvalidToken = errors.New("invalid token")
+	// ErrInvalidRefreshToken is returned when a refresh token is unknown or expired
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	// ErrRefreshTokenReused is returned when an already rotated refresh token is presented again
+	ErrRefreshTokenReused = errors.New("refresh token reuse detected")
+	// ErrInvalidMFAToken is returned when an MFA challenge token cannot be verified, has expired or was used
+	ErrInvalidMFAToken = errors.New("invalid MFA token")
+)
+
+// AccessClaims are the claims carried by every access token
+type AccessClaims struct {
+	UserId uint   `json:"user_id"`
+	Role   string `json:"role"`
+	// APIKeyId and Scopes are filled in when a request authenticates with an API key instead of a token
+	APIKeyId uint     `json:"-"`
+	Scopes   []string `json:"-"`
+	jwt.StandardClaims
+}
+
+// MFAClaims are the claims carried by MFA challenge tokens
+type MFAClaims struct {
+	UserId uint `json:"user_id"`
+	jwt.StandardClaims
+}
+
+// JWTSecret returns the signing key, read lazily so a .env loaded at startup is honoured
+func JWTSecret() []byte {
+	return []byte(config.GetEnv("JWT_SECRET"))
+}
+
+// AccessTokenTTL returns the lifetime of access tokens (ACCESS_TOKEN_TTL, default 15m)
+func AccessTokenTTL() time.Duration {
+	return config.GetEnvDuration("ACCESS_TOKEN_TTL", 15*time.Minute)
+}
+
+// RefreshTokenTTL returns the lifetime of refresh tokens (REFRESH_TOKEN_TTL, default 30 days)
+func RefreshTokenTTL() time.Duration {
+	return config.GetEnvDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour)
+}
+
+// MFAChallengeTTL returns the lifetime of MFA challenge tokens (MFA_CHALLENGE_TTL, default 5m)
+func MFAChallengeTTL() time.Duration {
+	return config.GetEnvDuration("MFA_CHALLENGE_TTL", 5*time.Minute)
+}
+
+// mfaSigningKey is derived from JWTSecret, so a challenge token never verifies as an access token
+func mfaSigningKey() []byte {
+	mac := hmac.New(sha256.New, JWTSecret())
+	mac.Write([]byte("mfa-challenge"))
+	return mac.Sum(nil)
+}
+
+// TokenService issues, rotates and revokes access and refresh tokens
+type TokenService struct {
+	store repositories.Store
+}
+
+// NewTokenService returns a TokenService keeping its tokens in store
+func NewTokenService(store repositories.Store) *TokenService {
+	return &TokenService{store: store}
+}
+
+// IssueTokens creates a new access token and a refresh token starting a new token family
+func (s *TokenService) IssueTokens(user models.User) (models.TokenResponse, error) {
+	familyId, err := randomString(16)
+	if err != nil {
+		return models.TokenResponse{}, err
+	}
+	return issueTokenPair(s.store.Tokens(), user, familyId)
+}
+
+// RefreshTokens rotates a refresh token: the presented token is consumed and a new
+// pair is issued in the same family. Presenting a consumed token revokes the whole family.
+func (s *TokenService) RefreshTokens(rawToken string) (models.TokenResponse, error) {
+	var response models.TokenResponse
+
+	err := s.store.Transaction(func(tx repositories.Store) error {
+		stored, err := tx.Tokens().FindRefreshToken(hashToken(rawToken))
+		if err != nil {
+			if errors.Is(err, repositories.ErrNotFound) {
+				return ErrInvalidRefreshToken
+			}
+			return err
+		}
+
+		if stored.RevokedAt != nil {
+			// Someone is replaying a token that was already rotated or revoked
+			return ErrRefreshTokenReused
+		}
+		if time.Now().After(stored.ExpiresAt) {
+			return ErrInvalidRefreshToken
+		}
+
+		// Consume the token; concurrent refreshes of the same token lose the race
+		consumed, err := tx.Tokens().ConsumeRefreshToken(stored.ID, time.Now())
+		if err != nil {
+			return err
+		}
+		if !consumed {
+			return ErrRefreshTokenReused
+		}
+
+		// Reload the user so role changes and suspensions are picked up on refresh
+		user, err := tx.Users().FindByID(stored.UserId)
+		if err != nil {
+			if errors.Is(err, repositories.ErrNotFound) {
+				return ErrInvalidRefreshToken
+			}
+			return err
+		}
+		if user.SuspendedAt != nil {
+			return ErrAccountSuspended
+		}
+
+		response, err = issueTokenPair(tx.Tokens(), user, stored.FamilyId)
+		return err
+	})
+
+	if errors.Is(err, ErrRefreshTokenReused) {
+		// Kill the whole family outside the rolled back transaction
+		if revokeErr := s.revokeFamilyByToken(rawToken); revokeErr != nil {
+			return response, fmt.Errorf("%w: %v", ErrRefreshTokenReused, revokeErr)
+		}
+	}
+
+	return response, err
+}
+
+// ParseAccessToken verifies an access token and checks it against the denylist
+func (s *TokenService) ParseAccessToken(tokenString string) (*AccessClaims, error) {
+	claims := &AccessClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+		}
+		return JWTSecret(), nil
+	})
+	if err != nil || !token.Valid || claims.Id == "" {
+		return nil, ErrInvalidToken
+	}
+
+	revoked, err := s.store.Tokens().IsAccessTokenRevoked(claims.Id)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, ErrInvalidToken
+	}
+
+	return claims, nil
+}
+
+// IssueMFAChallenge creates the token a user with two-factor authentication exchanges, together
+// with a code, for a token pair
+func (s *TokenService) IssueMFAChallenge(user models.User) (models.MFAChallengeResponse, error) {
+	jti, err := randomString(16)
+	if err != nil {
+		return models.MFAChallengeResponse{}, err
+	}
+
+	ttl := MFAChallengeTTL()
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, MFAClaims{
+		UserId: user.ID,
+		StandardClaims: jwt.StandardClaims{
+			Id:        jti,
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(ttl).Unix(),
+		},
+	})
+	signed, err := token.SignedString(mfaSigningKey())
+	if err != nil {
+		return models.MFAChallengeResponse{}, err
+	}
+
+	return models.MFAChallengeResponse{
+		MFARequired: true,
+		MFAToken:    signed,
+		ExpiresIn:   int64(ttl.Seconds()),
+	}, nil
+}
+
+// ParseMFAChallenge verifies an MFA challenge token and checks that it has not been used.
+// Challenge tokens share the access token denylist.
+func (s *TokenService) ParseMFAChallenge(tokenString string) (*MFAClaims, error) {
+	claims := &MFAClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+		}
+		return mfaSigningKey(), nil
+	})
+	if err != nil || !token.Valid || claims.Id == "" {
+		return nil, ErrInvalidMFAToken
+	}
+
+	revoked, err := s.store.Tokens().IsAccessTokenRevoked(claims.Id)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, ErrInvalidMFAToken
+	}
+
+	return claims, nil
+}
+
+// RevokeAccessToken adds an access token to the denylist until it would have expired
+func (s *TokenService) RevokeAccessToken(jti string, expiresAt time.Time) error {
+	return s.store.Tokens().RevokeAccessToken(jti, expiresAt, time.Now())
+}
+
+// RevokeRefreshToken revokes the family of the given refresh token if it belongs to the user
+func (s *TokenService) RevokeRefreshToken(userId uint, rawToken string) error {
+	stored, err := s.store.Tokens().FindRefreshToken(hashToken(rawToken))
+	if errors.Is(err, repositories.ErrNotFound) || (err == nil && stored.UserId != userId) {
+		return ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return err
+	}
+	return s.store.Tokens().RevokeFamily(stored.FamilyId, time.Now())
+}
+
+// RevokeUserRefreshTokens revokes every active refresh token of a user
+func (s *TokenService) RevokeUserRefreshTokens(userId uint) error {
+	return s.store.Tokens().RevokeUserRefreshTokens(userId, time.Now())
+}
+
+func (s *TokenService) revokeFamilyByToken(rawToken string) error {
+	stored, err := s.store.Tokens().FindRefreshToken(hashToken(rawToken))
+	if err != nil {
+		return err
+	}
+	return s.store.Tokens().RevokeFamily(stored.FamilyId, time.Now())
+}
+
+func issueTokenPair(tokens repositories.TokenRepository, user models.User, familyId string) (models.TokenResponse, error) {
+	ttl := AccessTokenTTL()
+	accessToken, err := signAccessToken(user, ttl)
+	if err != nil {
+		return models.TokenResponse{}, err
+	}
+
+	rawRefresh, err := randomString(32)
+	if err != nil {
+		return models.TokenResponse{}, err
+	}
+
+	refreshToken := models.RefreshToken{
+		UserId:    user.ID,
+		TokenHash: hashToken(rawRefresh),
+		FamilyId:  familyId,
+		ExpiresAt: time.Now().Add(RefreshTokenTTL()),
+	}
+	if err := tokens.CreateRefreshToken(&refreshToken); err != nil {
+		return models.TokenResponse{}, err
+	}
+
+	return models.TokenResponse{
+		Token:        accessToken,
+		RefreshToken: rawRefresh,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(ttl.Seconds()),
+	}, nil
+}
+
+func signAccessToken(user models.User, ttl time.Duration) (string, error) {
+	jti, err := randomString(16)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, AccessClaims{
+		UserId: user.ID,
+		Role:   user.Role,
+		StandardClaims: jwt.StandardClaims{
+			Id:        jti,
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(ttl).Unix(),
+		},
+	})
+
+	return token.SignedString(JWTSecret())
+}
+
+func hashToken(rawToken string) string {
+	sum := sha256.Sum256([]byte(rawToken))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomString(size int) (string, error) {
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
@@ -0,0 +1,166 @@
+package services_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/tushar27x/music-lib-api/models"
+	"github.com/tushar27x/music-lib-api/repositories"
+	"github.com/tushar27x/music-lib-api/services"
+)
+
+// tokenUser returns a token service on a fresh memory store and a user to issue tokens for
+func tokenUser(t *testing.T) (*services.TokenService, repositories.Store, models.User) {
+	t.Helper()
+	t.Setenv("JWT_SECRET", "test-secret")
+	store := repositories.NewMemoryStore()
+	user := models.User{Name: "Ada", Email: "ada@example.com", Role: models.RoleArtist}
+	if err := store.Users().Create(&user); err != nil {
+		t.Fatal(err)
+	}
+	return services.NewTokenService(store), store, user
+}
+
+func TestRefreshTokenRotation(t *testing.T) {
+	tokens, _, user := tokenUser(t)
+	first, err := tokens.IssueTokens(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := tokens.IssueTokens(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := tokens.RefreshTokens(first.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.RefreshToken == first.RefreshToken || second.Token == first.Token {
+		t.Error("refreshing returned the same tokens")
+	}
+	claims, err := tokens.ParseAccessToken(second.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.UserId != user.ID || claims.Role != models.RoleArtist {
+		t.Errorf("claims = %+v, want user %d as artist", claims, user.ID)
+	}
+
+	// Replaying the rotated token revokes its whole family, the token it was rotated into included
+	if _, err := tokens.RefreshTokens(first.RefreshToken); !errors.Is(err, services.ErrRefreshTokenReused) {
+		t.Errorf("replayed token = %v, want ErrRefreshTokenReused", err)
+	}
+	if _, err := tokens.RefreshTokens(second.RefreshToken); !errors.Is(err, services.ErrRefreshTokenReused) {
+		t.Errorf("token of the revoked family = %v, want ErrRefreshTokenReused", err)
+	}
+
+	// Other sessions have families of their own
+	if _, err := tokens.RefreshTokens(other.RefreshToken); err != nil {
+		t.Errorf("token of another family = %v, want nil", err)
+	}
+	if _, err := tokens.RefreshTokens("made-up"); !errors.Is(err, services.ErrInvalidRefreshToken) {
+		t.Errorf("unknown token = %v, want ErrInvalidRefreshToken", err)
+	}
+}
+
+func TestRefreshTokenChecksTheAccount(t *testing.T) {
+	tokens, store, user := tokenUser(t)
+	issued, err := tokens.IssueTokens(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Role changes are picked up on refresh, suspensions end the session
+	user.Role = models.RoleModerator
+	if err := store.Users().Update(&user, "role"); err != nil {
+		t.Fatal(err)
+	}
+	refreshed, err := tokens.RefreshTokens(issued.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := tokens.ParseAccessToken(refreshed.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Role != models.RoleModerator {
+		t.Errorf("role after refresh = %q, want moderator", claims.Role)
+	}
+
+	now := time.Now()
+	user.SuspendedAt = &now
+	if err := store.Users().Update(&user, "suspended_at"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tokens.RefreshTokens(refreshed.RefreshToken); !errors.Is(err, services.ErrAccountSuspended) {
+		t.Errorf("refresh of a suspended account = %v, want ErrAccountSuspended", err)
+	}
+}
+
+func TestExpiredTokens(t *testing.T) {
+	tokens, _, user := tokenUser(t)
+	t.Setenv("ACCESS_TOKEN_TTL", "1s")
+	t.Setenv("REFRESH_TOKEN_TTL", "1s")
+	issued, err := tokens.IssueTokens(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Token expiry has a resolution of seconds
+	time.Sleep(2100 * time.Millisecond)
+
+	if _, err := tokens.ParseAccessToken(issued.Token); !errors.Is(err, services.ErrInvalidToken) {
+		t.Errorf("expired access token = %v, want ErrInvalidToken", err)
+	}
+	if _, err := tokens.RefreshTokens(issued.RefreshToken); !errors.Is(err, services.ErrInvalidRefreshToken) {
+		t.Errorf("expired refresh token = %v, want ErrInvalidRefreshToken", err)
+	}
+}
+
+func TestAccessTokenDenylist(t *testing.T) {
+	tokens, _, user := tokenUser(t)
+	kept, err := tokens.IssueTokens(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	revoked, err := tokens.IssueTokens(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := tokens.ParseAccessToken(revoked.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tokens.RevokeAccessToken(claims.Id, time.Unix(claims.ExpiresAt, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tokens.ParseAccessToken(revoked.Token); !errors.Is(err, services.ErrInvalidToken) {
+		t.Errorf("denylisted token = %v, want ErrInvalidToken", err)
+	}
+	if _, err := tokens.ParseAccessToken(kept.Token); err != nil {
+		t.Errorf("other token = %v, want nil", err)
+	}
+}
+
+func TestAccessTokensAreSignedWithTheSecret(t *testing.T) {
+	tokens, _, user := tokenUser(t)
+	issued, err := tokens.IssueTokens(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	challenge, err := tokens.IssueMFAChallenge(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Challenge tokens are signed with a key of their own
+	if _, err := tokens.ParseAccessToken(challenge.MFAToken); !errors.Is(err, services.ErrInvalidToken) {
+		t.Errorf("challenge token as access token = %v, want ErrInvalidToken", err)
+	}
+	t.Setenv("JWT_SECRET", "another-secret")
+	if _, err := tokens.ParseAccessToken(issued.Token); !errors.Is(err, services.ErrInvalidToken) {
+		t.Errorf("token signed with another secret = %v, want ErrInvalidToken", err)
+	}
+}
@@ -0,0 +1,336 @@
+package services
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/base64"
+	"errors"
+	"image/png"
+	"strings"
+	"time"
+
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+	"github.com/tushar27x/music-lib-api/config"
+	"github.com/tushar27x/music-lib-api/models"
+	"github.com/tushar27x/music-lib-api/repositories"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	// ErrTwoFactorEnabled is returned when setting up two-factor authentication that is already on
+	ErrTwoFactorEnabled = errors.New("two-factor authentication is already enabled")
+	// ErrTwoFactorNotEnabled is returned when two-factor authentication is off
+	ErrTwoFactorNotEnabled = errors.New("two-factor authentication is not enabled")
+	// ErrTwoFactorNotSetUp is returned when confirming before a secret was generated
+	ErrTwoFactorNotSetUp = errors.New("two-factor authentication has not been set up")
+	// ErrInvalidTwoFactorCode is returned when a TOTP or recovery code does not match
+	ErrInvalidTwoFactorCode = errors.New("invalid two-factor code")
+)
+
+const (
+	// totpPeriod is the length of a TOTP time step in seconds, the one authenticator apps expect
+	totpPeriod = 30
+	// recoveryCodeCount is how many recovery codes are generated at a time
+	recoveryCodeCount = 10
+)
+
+// TOTPIssuer returns the name authenticator apps show for the account (TOTP_ISSUER, default Music Library)
+func TOTPIssuer() string {
+	issuer := config.GetEnv("TOTP_ISSUER")
+	if issuer == "" {
+		issuer = "Music Library"
+	}
+	return issuer
+}
+
+// TwoFactorService enrols users in TOTP two-factor authentication and finishes their logins
+type TwoFactorService struct {
+	store    repositories.Store
+	tokens   *TokenService
+	throttle *LoginThrottleService
+}
+
+// NewTwoFactorService returns a TwoFactorService keeping its state in store, issuing tokens with
+// tokens and counting wrong login codes with throttle
+func NewTwoFactorService(store repositories.Store, tokens *TokenService, throttle *LoginThrottleService) *TwoFactorService {
+	return &TwoFactorService{store: store, tokens: tokens, throttle: throttle}
+}
+
+// Status reports whether the user has two-factor authentication and how many recovery codes are left
+func (s *TwoFactorService) Status(userId uint) (models.TwoFactorStatusResponse, error) {
+	user, err := findUser(s.store, userId)
+	if err != nil {
+		return models.TwoFactorStatusResponse{}, err
+	}
+
+	status := models.TwoFactorStatusResponse{Enabled: user.TOTPEnabledAt != nil, EnabledAt: user.TOTPEnabledAt}
+	if status.Enabled {
+		status.RecoveryCodesRemaining, err = s.store.Tokens().CountRecoveryCodes(userId)
+	}
+	return status, err
+}
+
+// Setup generates a new TOTP secret for the user. It only takes effect once a code from it is
+// confirmed; setting up again before that replaces the secret.
+func (s *TwoFactorService) Setup(userId uint) (models.TwoFactorSetupResponse, error) {
+	var response models.TwoFactorSetupResponse
+	_, err := updateUser(s.store, userId, func(tx repositories.Store, user *models.User) error {
+		if user.TOTPEnabledAt != nil {
+			return ErrTwoFactorEnabled
+		}
+
+		key, err := totp.Generate(totp.GenerateOpts{
+			Issuer:      TOTPIssuer(),
+			AccountName: user.Email,
+			Period:      totpPeriod,
+			Digits:      otp.DigitsSix,
+			Algorithm:   otp.AlgorithmSHA1,
+		})
+		if err != nil {
+			return err
+		}
+		qrCode, err := qrCodeDataURI(key)
+		if err != nil {
+			return err
+		}
+
+		user.TOTPSecret = key.Secret()
+		user.TOTPLastStep = 0
+		if err := tx.Users().Update(user, "totp_secret", "totp_last_step"); err != nil {
+			return err
+		}
+		response = models.TwoFactorSetupResponse{Secret: key.Secret(), OTPAuthURL: key.URL(), QRCode: qrCode}
+		return nil
+	})
+	return response, err
+}
+
+// Confirm turns two-factor authentication on once the user proves their app produces codes for the
+// secret from Setup. It returns the recovery codes, which are not stored in readable form.
+func (s *TwoFactorService) Confirm(userId uint, code string) ([]string, error) {
+	var codes []string
+	_, err := updateUser(s.store, userId, func(tx repositories.Store, user *models.User) error {
+		if user.TOTPEnabledAt != nil {
+			return ErrTwoFactorEnabled
+		}
+		if user.TOTPSecret == "" {
+			return ErrTwoFactorNotSetUp
+		}
+
+		if err := useTOTP(tx, user, code); err != nil {
+			return err
+		}
+		now := time.Now()
+		user.TOTPEnabledAt = &now
+		if err := tx.Users().Update(user, "totp_enabled_at"); err != nil {
+			return err
+		}
+
+		var err error
+		codes, err = replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+	return codes, err
+}
+
+// Disable turns two-factor authentication off after checking the password and a TOTP or recovery code
+func (s *TwoFactorService) Disable(userId uint, password string, code string) error {
+	_, err := updateUser(s.store, userId, func(tx repositories.Store, user *models.User) error {
+		if user.TOTPEnabledAt == nil {
+			return ErrTwoFactorNotEnabled
+		}
+		if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+			return ErrInvalidCredentials
+		}
+		if err := verifyTwoFactorCode(tx, user, code); err != nil {
+			return err
+		}
+		return clearTwoFactor(tx, user)
+	})
+	return err
+}
+
+// RegenerateRecoveryCodes replaces the user's recovery codes after checking a TOTP code
+func (s *TwoFactorService) RegenerateRecoveryCodes(userId uint, code string) ([]string, error) {
+	var codes []string
+	_, err := updateUser(s.store, userId, func(tx repositories.Store, user *models.User) error {
+		if user.TOTPEnabledAt == nil {
+			return ErrTwoFactorNotEnabled
+		}
+
+		if err := useTOTP(tx, user, code); err != nil {
+			return err
+		}
+
+		var err error
+		codes, err = replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+	return codes, err
+}
+
+// Verify finishes a login: the challenge token from Login and a TOTP or recovery code are
+// exchanged for a token pair. The challenge token can only be exchanged once. Wrong codes count
+// as failed logins of the account and of ip, like wrong passwords.
+func (s *TwoFactorService) Verify(mfaToken string, code string, ip string) (models.TokenResponse, error) {
+	claims, err := s.tokens.ParseMFAChallenge(mfaToken)
+	if err != nil {
+		return models.TokenResponse{}, err
+	}
+
+	account, err := findUser(s.store, claims.UserId)
+	if errors.Is(err, ErrUserNotFound) {
+		return models.TokenResponse{}, ErrInvalidMFAToken
+	}
+	if err != nil {
+		return models.TokenResponse{}, err
+	}
+	if err := s.throttle.Check(account.Email, ip); err != nil {
+		return models.TokenResponse{}, err
+	}
+
+	user, err := updateUser(s.store, claims.UserId, func(tx repositories.Store, user *models.User) error {
+		if user.SuspendedAt != nil {
+			return ErrAccountSuspended
+		}
+		if user.TOTPEnabledAt == nil {
+			// Two-factor authentication was turned off after the challenge was issued
+			return ErrInvalidMFAToken
+		}
+		return verifyTwoFactorCode(tx, user, code)
+	})
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return models.TokenResponse{}, ErrInvalidMFAToken
+		}
+		if errors.Is(err, ErrInvalidTwoFactorCode) {
+			if err := s.throttle.RecordFailure(account.Email, ip); err != nil {
+				return models.TokenResponse{}, err
+			}
+		}
+		return models.TokenResponse{}, err
+	}
+
+	if err := s.throttle.RecordSuccess(user.Email); err != nil {
+		return models.TokenResponse{}, err
+	}
+	if err := s.tokens.RevokeAccessToken(claims.Id, time.Unix(claims.ExpiresAt, 0)); err != nil {
+		return models.TokenResponse{}, err
+	}
+	return s.tokens.IssueTokens(user)
+}
+
+// verifyTwoFactorCode accepts a TOTP code newer than the last one used, or an unused recovery code
+func verifyTwoFactorCode(tx repositories.Store, user *models.User, code string) error {
+	if err := useTOTP(tx, user, code); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		return err
+	}
+
+	used, err := tx.Tokens().UseRecoveryCode(user.ID, hashToken(normalizeRecoveryCode(code)), time.Now())
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidTwoFactorCode
+	}
+	return nil
+}
+
+// useTOTP accepts a TOTP code newer than the last one used and records its time step. The step is
+// only recorded if no other request has used it or a later one since the user was read, so two
+// requests carrying the same code cannot both succeed.
+func useTOTP(tx repositories.Store, user *models.User, code string) error {
+	step, ok := matchTOTP(user.TOTPSecret, code, user.TOTPLastStep, time.Now())
+	if !ok {
+		return ErrInvalidTwoFactorCode
+	}
+	used, err := tx.Users().UseTOTPStep(user.ID, step)
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidTwoFactorCode
+	}
+	user.TOTPLastStep = step
+	return nil
+}
+
+// clearTwoFactor removes the TOTP secret and recovery codes of a user
+func clearTwoFactor(tx repositories.Store, user *models.User) error {
+	user.TOTPSecret = ""
+	user.TOTPEnabledAt = nil
+	user.TOTPLastStep = 0
+	if err := tx.Users().Update(user, "totp_secret", "totp_enabled_at", "totp_last_step"); err != nil {
+		return err
+	}
+	return tx.Tokens().ReplaceRecoveryCodes(user.ID, nil)
+}
+
+// matchTOTP checks a code against the current time step and its neighbours, allowing for clock
+// drift, and returns the matching step. Steps up to lastStep are refused so a code works once.
+func matchTOTP(secret string, code string, lastStep int64, now time.Time) (int64, bool) {
+	code = strings.TrimSpace(code)
+	if secret == "" || len(code) != 6 {
+		return 0, false
+	}
+
+	current := now.Unix() / totpPeriod
+	for _, step := range []int64{current - 1, current, current + 1} {
+		if step <= lastStep {
+			continue
+		}
+		expected, err := totp.GenerateCodeCustom(secret, time.Unix(step*totpPeriod, 0), totp.ValidateOpts{
+			Period:    totpPeriod,
+			Digits:    otp.DigitsSix,
+			Algorithm: otp.AlgorithmSHA1,
+		})
+		if err == nil && subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// replaceRecoveryCodes generates a new set of recovery codes such as "k3m9x-2pq7d", stores their
+// hashes and returns them
+func replaceRecoveryCodes(tx repositories.Store, userId uint) ([]string, error) {
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+	codes := make([]string, 0, recoveryCodeCount)
+	stored := make([]models.RecoveryCode, 0, recoveryCodeCount)
+	for len(codes) < recoveryCodeCount {
+		buf := make([]byte, 7)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, err
+		}
+		raw := strings.ToLower(encoding.EncodeToString(buf))[:10]
+		codes = append(codes, raw[:5]+"-"+raw[5:])
+		stored = append(stored, models.RecoveryCode{UserId: userId, CodeHash: hashToken(raw)})
+	}
+
+	if err := tx.Tokens().ReplaceRecoveryCodes(userId, stored); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// normalizeRecoveryCode drops the separator and case so codes can be typed loosely
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+// qrCodeDataURI renders the otpauth:// URI of key as a PNG data URI
+func qrCodeDataURI(key *otp.Key) (string, error) {
+	img, err := key.Image(256, 256)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return "", err
+	}
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
@@ -0,0 +1,198 @@
+package services_test
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+	"github.com/tushar27x/music-lib-api/models"
+	"github.com/tushar27x/music-lib-api/repositories"
+	"github.com/tushar27x/music-lib-api/services"
+)
+
+// totpCode returns the code of secret for the time step steps away from the current one
+func totpCode(t *testing.T, secret string, steps int) string {
+	t.Helper()
+	code, err := totp.GenerateCodeCustom(secret, time.Now().Add(time.Duration(steps)*30*time.Second), totp.ValidateOpts{
+		Period:    30,
+		Digits:    otp.DigitsSix,
+		Algorithm: otp.AlgorithmSHA1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code
+}
+
+// authServices returns the services of a login on a fresh memory store
+func authServices(t *testing.T) (*services.AuthService, *services.TwoFactorService, repositories.Store) {
+	t.Helper()
+	t.Setenv("JWT_SECRET", "test-secret")
+	store := repositories.NewMemoryStore()
+	tokens := services.NewTokenService(store)
+	throttle := services.NewLoginThrottleService(store)
+	auth := services.NewAuthService(store, tokens, services.NewAPIKeyService(store), throttle)
+	return auth, services.NewTwoFactorService(store, tokens, throttle), store
+}
+
+// enrol registers a user and turns two-factor authentication on with the code of the previous
+// time step, leaving the current and the next one for the test; it returns the user, the secret
+// and the recovery codes
+func enrol(t *testing.T, auth *services.AuthService, twoFactor *services.TwoFactorService) (models.User, string, []string) {
+	t.Helper()
+	user, err := auth.Register(models.UserRegisterRequest{Name: "Ada", Email: "ada@example.com", Password: "correct horse"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	setup, err := twoFactor.Setup(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := twoFactor.Confirm(user.ID, "000000"); !errors.Is(err, services.ErrInvalidTwoFactorCode) {
+		t.Errorf("confirming a wrong code = %v, want ErrInvalidTwoFactorCode", err)
+	}
+	codes, err := twoFactor.Confirm(user.ID, totpCode(t, setup.Secret, -1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user, setup.Secret, codes
+}
+
+func TestTwoFactorCodesWorkOnce(t *testing.T) {
+	auth, twoFactor, _ := authServices(t)
+	user, secret, codes := enrol(t, auth, twoFactor)
+
+	if len(codes) != 10 {
+		t.Fatalf("got %d recovery codes, want 10", len(codes))
+	}
+	for _, code := range codes {
+		if !regexp.MustCompile(`^[a-z2-7]{5}-[a-z2-7]{5}$`).MatchString(code) {
+			t.Errorf("recovery code %q is not two groups of five", code)
+		}
+	}
+
+	// The code that confirmed the setup and codes of earlier steps are spent
+	for _, steps := range []int{-1, -2} {
+		if _, err := twoFactor.RegenerateRecoveryCodes(user.ID, totpCode(t, secret, steps)); !errors.Is(err, services.ErrInvalidTwoFactorCode) {
+			t.Errorf("code of step %d = %v, want ErrInvalidTwoFactorCode", steps, err)
+		}
+	}
+
+	current := totpCode(t, secret, 0)
+	if _, err := twoFactor.RegenerateRecoveryCodes(user.ID, current); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := twoFactor.RegenerateRecoveryCodes(user.ID, current); !errors.Is(err, services.ErrInvalidTwoFactorCode) {
+		t.Errorf("replayed code = %v, want ErrInvalidTwoFactorCode", err)
+	}
+}
+
+func TestTwoFactorStepIsUsedOnce(t *testing.T) {
+	for name, store := range map[string]repositories.Store{
+		"memory": repositories.NewMemoryStore(),
+		"sqlite": repositories.NewGormStore(openSQLite(t)),
+	} {
+		t.Run(name, func(t *testing.T) {
+			user := models.User{Name: "Ada", Email: "ada@example.com", Role: models.RoleListener}
+			if err := store.Users().Create(&user); err != nil {
+				t.Fatal(err)
+			}
+
+			// Two requests that read the user before either wrote it both try to use step 5
+			for _, tc := range []struct {
+				step int64
+				used bool
+			}{{5, true}, {5, false}, {4, false}, {6, true}} {
+				used, err := store.Users().UseTOTPStep(user.ID, tc.step)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if used != tc.used {
+					t.Errorf("using step %d = %v, want %v", tc.step, used, tc.used)
+				}
+			}
+			stored, err := store.Users().FindByID(user.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stored.TOTPLastStep != 6 {
+				t.Errorf("last step = %d, want 6", stored.TOTPLastStep)
+			}
+		})
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	auth, twoFactor, store := authServices(t)
+	user, _, codes := enrol(t, auth, twoFactor)
+
+	// Case, spaces and the separator don't matter
+	loose := "  " + strings.ToUpper(strings.Replace(codes[0], "-", " ", 1)) + " "
+	if err := twoFactor.Disable(user.ID, "correct horse", loose); err != nil {
+		t.Fatalf("disabling with %q: %v", loose, err)
+	}
+	status, err := twoFactor.Status(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Enabled {
+		t.Error("two-factor authentication still enabled after disabling")
+	}
+	if count, err := store.Tokens().CountRecoveryCodes(user.ID); err != nil || count != 0 {
+		t.Errorf("recovery codes left after disabling = %d (%v), want 0", count, err)
+	}
+}
+
+func TestTwoFactorLogin(t *testing.T) {
+	auth, twoFactor, _ := authServices(t)
+	user, secret, codes := enrol(t, auth, twoFactor)
+
+	login := func() string {
+		t.Helper()
+		tokens, challenge, err := auth.Login("ada@example.com", "correct horse", "10.0.0.1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if challenge == nil || !challenge.MFARequired || tokens.Token != "" {
+			t.Fatalf("login = %+v, %+v, want a challenge and no tokens", tokens, challenge)
+		}
+		return challenge.MFAToken
+	}
+
+	mfaToken := login()
+	if _, err := twoFactor.Verify(mfaToken, "000000", "10.0.0.1"); !errors.Is(err, services.ErrInvalidTwoFactorCode) {
+		t.Errorf("wrong code = %v, want ErrInvalidTwoFactorCode", err)
+	}
+	tokens, err := twoFactor.Verify(mfaToken, totpCode(t, secret, 0), "10.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tokens.Token == "" || tokens.RefreshToken == "" {
+		t.Errorf("tokens = %+v, want an access and a refresh token", tokens)
+	}
+	if _, err := twoFactor.Verify(mfaToken, codes[0], "10.0.0.1"); !errors.Is(err, services.ErrInvalidMFAToken) {
+		t.Errorf("reused challenge = %v, want ErrInvalidMFAToken", err)
+	}
+	if _, err := twoFactor.Verify("not-a-token", codes[0], "10.0.0.1"); !errors.Is(err, services.ErrInvalidMFAToken) {
+		t.Errorf("made-up challenge = %v, want ErrInvalidMFAToken", err)
+	}
+
+	// A recovery code finishes a login once
+	if _, err := twoFactor.Verify(login(), codes[1], "10.0.0.1"); err != nil {
+		t.Fatalf("recovery code: %v", err)
+	}
+	if _, err := twoFactor.Verify(login(), codes[1], "10.0.0.1"); !errors.Is(err, services.ErrInvalidTwoFactorCode) {
+		t.Errorf("used recovery code = %v, want ErrInvalidTwoFactorCode", err)
+	}
+	status, err := twoFactor.Status(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.RecoveryCodesRemaining != 9 {
+		t.Errorf("recovery codes remaining = %d, want 9", status.RecoveryCodesRemaining)
+	}
+}
@@ -0,0 +1,196 @@
+package services
+
+import (
+	"strconv"
+
+	"github.com/tushar27x/music-lib-api/models"
+	"github.com/tushar27x/music-lib-api/search"
+)
+
+// Types the unified search can return
+const (
+	SearchTypeSongs     = "songs"
+	SearchTypeAlbums    = "albums"
+	SearchTypePlaylists = "playlists"
+)
+
+// SearchTypes lists every type the unified search can return
+var SearchTypes = []string{SearchTypeSongs, SearchTypeAlbums, SearchTypePlaylists}
+
+// maxFacetValues caps the year and album facets
+const maxFacetValues = 20
+
+// DurationBucket is a range of song durations in milliseconds; Max is exclusive and 0 means unbounded
+type DurationBucket struct {
+	Key   string
+	Label string
+	Min   int
+	Max   int
+}
+
+// DurationBuckets are the duration facet values, shortest first
+var DurationBuckets = []DurationBucket{
+	{Key: "0-2", Label: "Under 2 minutes", Min: 0, Max: 2 * 60000},
+	{Key: "2-4", Label: "2 to 4 minutes", Min: 2 * 60000, Max: 4 * 60000},
+	{Key: "4-6", Label: "4 to 6 minutes", Min: 4 * 60000, Max: 6 * 60000},
+	{Key: "6-10", Label: "6 to 10 minutes", Min: 6 * 60000, Max: 10 * 60000},
+	{Key: "10-plus", Label: "Over 10 minutes", Min: 10 * 60000},
+}
+
+// FindDurationBucket looks up a duration bucket by key
+func FindDurationBucket(key string) (DurationBucket, bool) {
+	for _, bucket := range DurationBuckets {
+		if bucket.Key == key {
+			return bucket, true
+		}
+	}
+	return DurationBucket{}, false
+}
+
+// UnifiedSearchOptions select what a unified search returns and how songs are filtered
+type UnifiedSearchOptions struct {
+	Types    []string
+	Limit    int
+	Year     *int
+	AlbumId  *int
+	Duration *DurationBucket
+	Facets   bool
+}
+
+// UnifiedSearch searches the requested types in one call. Year and album filters also narrow
+// albums; facets are computed over songs, each ignoring its own filter so the other values stay selectable.
+func (s *SearchService) UnifiedSearch(userId uint, q search.Query, input string, options UnifiedSearchOptions) (models.SearchResponse, error) {
+	response := models.SearchResponse{
+		Query:   input,
+		Counts:  map[string]int64{},
+		Results: map[string]interface{}{},
+	}
+
+	songFilters := SongSearchFilters{AlbumId: options.AlbumId, Year: options.Year}
+	if options.Duration != nil {
+		songFilters.MinDuration, songFilters.MaxDuration = durationBounds(*options.Duration)
+	}
+
+	for _, searchType := range options.Types {
+		var results interface{}
+		var total int64
+		var err error
+
+		switch searchType {
+		case SearchTypeSongs:
+			results, total, err = s.SearchSongs(userId, q, songFilters, options.Limit, 0)
+		case SearchTypeAlbums:
+			results, total, err = s.SearchAlbums(userId, q, AlbumSearchFilters{Id: options.AlbumId, Year: options.Year}, options.Limit, 0)
+		case SearchTypePlaylists:
+			results, total, err = s.SearchPlaylists(userId, q, "", options.Limit, 0)
+		default:
+			continue
+		}
+		if err != nil {
+			return response, err
+		}
+		response.Results[searchType] = results
+		response.Counts[searchType] = total
+	}
+
+	if options.Facets {
+		facets, err := s.songFacets(userId, q, songFilters)
+		if err != nil {
+			return response, err
+		}
+		response.Facets = &facets
+	}
+
+	return response, nil
+}
+
+type facetRow struct {
+	Value string
+	Label string
+	Count int64
+}
+
+func (s *SearchService) songFacets(userId uint, q search.Query, filters SongSearchFilters) (models.SearchFacets, error) {
+	facets := models.SearchFacets{
+		Year:     []models.SearchFacetValue{},
+		Album:    []models.SearchFacetValue{},
+		Duration: []models.SearchFacetValue{},
+	}
+
+	var rows []facetRow
+
+	withoutYear := filters
+	withoutYear.Year = nil
+	if err := s.matchQuery(s.songSearchBase(userId, withoutYear), songSearch, q).
+		Select("songs.year AS value, COUNT(*) AS count").
+		Where("songs.year > 0").
+		Group("songs.year").
+		Order("count DESC, songs.year DESC").
+		Limit(maxFacetValues).
+		Scan(&rows).Error; err != nil {
+		return facets, err
+	}
+	for _, row := range rows {
+		facets.Year = append(facets.Year, models.SearchFacetValue{Value: row.Value, Label: row.Value, Count: row.Count})
+	}
+
+	rows = nil
+	withoutAlbum := filters
+	withoutAlbum.AlbumId = nil
+	if err := s.matchQuery(s.songSearchBase(userId, withoutAlbum), songSearch, q).
+		Joins("JOIN albums ON albums.id = songs.album_id AND albums.deleted_at IS NULL").
+		Select("albums.id AS value, albums.title AS label, COUNT(*) AS count").
+		Group("albums.id, albums.title").
+		Order("count DESC, albums.title ASC").
+		Limit(maxFacetValues).
+		Scan(&rows).Error; err != nil {
+		return facets, err
+	}
+	for _, row := range rows {
+		facets.Album = append(facets.Album, models.SearchFacetValue{Value: row.Value, Label: row.Label, Count: row.Count})
+	}
+
+	rows = nil
+	withoutDuration := filters
+	withoutDuration.MinDuration, withoutDuration.MaxDuration = nil, nil
+	if err := s.matchQuery(s.songSearchBase(userId, withoutDuration), songSearch, q).
+		Select(durationBucketSQL() + " AS value, COUNT(*) AS count").
+		Group("value").
+		Scan(&rows).Error; err != nil {
+		return facets, err
+	}
+	counts := map[string]int64{}
+	for _, row := range rows {
+		counts[row.Value] = row.Count
+	}
+	// Every bucket is listed, in order, so the sidebar doesn't jump around
+	for _, bucket := range DurationBuckets {
+		facets.Duration = append(facets.Duration, models.SearchFacetValue{Value: bucket.Key, Label: bucket.Label, Count: counts[bucket.Key]})
+	}
+
+	return facets, nil
+}
+
+// durationBucketSQL builds a CASE expression mapping songs.duration to its bucket key.
+// The keys are constants, so they are inlined as literals.
+func durationBucketSQL() string {
+	expression := "CASE"
+	for _, bucket := range DurationBuckets {
+		if bucket.Max == 0 {
+			expression += " ELSE '" + bucket.Key + "'"
+			continue
+		}
+		expression += " WHEN songs.duration < " + strconv.Itoa(bucket.Max) + " THEN '" + bucket.Key + "'"
+	}
+	return expression + " END"
+}
+
+// durationBounds converts a bucket into the inclusive filters used by song search
+func durationBounds(bucket DurationBucket) (*int, *int) {
+	min := bucket.Min
+	if bucket.Max == 0 {
+		return &min, nil
+	}
+	max := bucket.Max - 1
+	return &min, &max
+}
@@ -0,0 +1,181 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/tushar27x/music-lib-api/models"
+	"github.com/tushar27x/music-lib-api/repositories"
+)
+
+var (
+	// ErrInvalidRole is returned when a role is not one of models.Roles
+	ErrInvalidRole = errors.New("invalid role")
+	// ErrOwnRole is returned when an admin tries to change their own role, which could leave nobody able to assign roles
+	ErrOwnRole = errors.New("cannot change your own role")
+	// ErrOwnAccount is returned when an admin tries to suspend themselves
+	ErrOwnAccount = errors.New("cannot suspend your own account")
+	// ErrAlreadySuspended is returned when suspending a suspended account
+	ErrAlreadySuspended = errors.New("account is already suspended")
+	// ErrNotSuspended is returned when unsuspending an account that is not suspended
+	ErrNotSuspended = errors.New("account is not suspended")
+)
+
+// UserService manages user accounts on behalf of admins. Every change is written to the audit trail.
+type UserService struct {
+	store repositories.Store
+}
+
+// NewUserService returns a UserService backed by store
+func NewUserService(store repositories.Store) *UserService {
+	return &UserService{store: store}
+}
+
+// List returns the users matching filter in ID order and how many match in total
+func (s *UserService) List(filter repositories.UserFilter, limit int, offset int) ([]models.User, int64, error) {
+	return s.store.Users().List(filter, limit, offset)
+}
+
+// Get returns one user
+func (s *UserService) Get(userId uint) (models.User, error) {
+	return findUser(s.store, userId)
+}
+
+// ChangeRole gives a user another role. It applies to the user's next request, since
+// authentication reads the role from the account rather than from the token.
+func (s *UserService) ChangeRole(actorId uint, userId uint, role string) (models.User, error) {
+	if !IsRole(role) {
+		return models.User{}, ErrInvalidRole
+	}
+	if actorId == userId {
+		return models.User{}, ErrOwnRole
+	}
+
+	return updateUser(s.store, userId, func(tx repositories.Store, user *models.User) error {
+		details := user.Role + " -> " + role
+		user.Role = role
+		if err := tx.Users().Update(user, "role"); err != nil {
+			return err
+		}
+		return recordAudit(tx, actorId, models.AuditUserRoleChanged, models.AuditTargetUser, user.ID, details)
+	})
+}
+
+// SetRoleByEmail gives the account with the email another role. It is meant for the command
+// line, where the first admin has to be appointed without an admin to do it.
+func (s *UserService) SetRoleByEmail(email string, role string) (models.User, error) {
+	if !IsRole(role) {
+		return models.User{}, ErrInvalidRole
+	}
+
+	user, err := s.store.Users().FindByEmail(email)
+	if err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			return user, ErrUserNotFound
+		}
+		return user, err
+	}
+
+	err = s.store.Transaction(func(tx repositories.Store) error {
+		details := user.Role + " -> " + role
+		user.Role = role
+		if err := tx.Users().Update(&user, "role"); err != nil {
+			return err
+		}
+		return recordAudit(tx, 0, models.AuditUserRoleChanged, models.AuditTargetUser, user.ID, details)
+	})
+	return user, err
+}
+
+// Suspend locks a user out: logging in, refreshing and every request with an existing
+// access token are refused until the account is unsuspended
+func (s *UserService) Suspend(actorId uint, userId uint, reason string) (models.User, error) {
+	if actorId == userId {
+		return models.User{}, ErrOwnAccount
+	}
+
+	return updateUser(s.store, userId, func(tx repositories.Store, user *models.User) error {
+		if user.SuspendedAt != nil {
+			return ErrAlreadySuspended
+		}
+
+		now := time.Now()
+		user.SuspendedAt = &now
+		user.SuspensionReason = reason
+		if err := tx.Users().Update(user, "suspended_at", "suspension_reason"); err != nil {
+			return err
+		}
+		if err := tx.Tokens().RevokeUserRefreshTokens(user.ID, now); err != nil {
+			return err
+		}
+		return recordAudit(tx, actorId, models.AuditUserSuspended, models.AuditTargetUser, user.ID, reason)
+	})
+}
+
+// Unsuspend lets a suspended user log in again
+func (s *UserService) Unsuspend(actorId uint, userId uint) (models.User, error) {
+	return updateUser(s.store, userId, func(tx repositories.Store, user *models.User) error {
+		if user.SuspendedAt == nil {
+			return ErrNotSuspended
+		}
+
+		user.SuspendedAt = nil
+		user.SuspensionReason = ""
+		if err := tx.Users().Update(user, "suspended_at", "suspension_reason"); err != nil {
+			return err
+		}
+		return recordAudit(tx, actorId, models.AuditUserUnsuspended, models.AuditTargetUser, user.ID, "")
+	})
+}
+
+// ForcePasswordReset ends every session of the user and makes them change their password
+// before anything else they do is accepted
+func (s *UserService) ForcePasswordReset(actorId uint, userId uint) (models.User, error) {
+	return updateUser(s.store, userId, func(tx repositories.Store, user *models.User) error {
+		user.PasswordResetRequired = true
+		if err := tx.Users().Update(user, "password_reset_required"); err != nil {
+			return err
+		}
+		if err := tx.Tokens().RevokeUserRefreshTokens(user.ID, time.Now()); err != nil {
+			return err
+		}
+		return recordAudit(tx, actorId, models.AuditUserPasswordResetForced, models.AuditTargetUser, user.ID, "")
+	})
+}
+
+// ResetTwoFactor turns off two-factor authentication for a user who lost both their
+// authenticator and their recovery codes
+func (s *UserService) ResetTwoFactor(actorId uint, userId uint) (models.User, error) {
+	return updateUser(s.store, userId, func(tx repositories.Store, user *models.User) error {
+		if user.TOTPEnabledAt == nil {
+			return ErrTwoFactorNotEnabled
+		}
+		if err := clearTwoFactor(tx, user); err != nil {
+			return err
+		}
+		return recordAudit(tx, actorId, models.AuditUserTwoFactorReset, models.AuditTargetUser, user.ID, "")
+	})
+}
+
+// findUser loads a user, translating a missing account into ErrUserNotFound
+func findUser(store repositories.Store, userId uint) (models.User, error) {
+	user, err := store.Users().FindByID(userId)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return user, ErrUserNotFound
+	}
+	return user, err
+}
+
+// updateUser runs fn on a user in a transaction
+func updateUser(store repositories.Store, userId uint, fn func(tx repositories.Store, user *models.User) error) (models.User, error) {
+	var user models.User
+	err := store.Transaction(func(tx repositories.Store) error {
+		found, err := findUser(tx, userId)
+		if err != nil {
+			return err
+		}
+		user = found
+		return fn(tx, &user)
+	})
+	return user, err
+}
@@ -0,0 +1,338 @@
+package services
+
+import (
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/tushar27x/music-lib-api/models"
+	"github.com/tushar27x/music-lib-api/repositories"
+)
+
+var (
+	// ErrTagNotFound is returned when a tag does not exist or belongs to someone else
+	ErrTagNotFound = errors.New("tag not found")
+	// ErrTagNameTaken is returned when the user already has a tag of that name
+	ErrTagNameTaken = errors.New("tag name is already taken")
+	// ErrTagNameRequired is returned for a tag name that is empty once trimmed
+	ErrTagNameRequired = errors.New("tag name is required")
+	// ErrMergeSameTag is returned when a tag is merged into itself
+	ErrMergeSameTag = errors.New("a tag can't be merged into itself")
+)
+
+// TagService holds the rules for the tags users put on their songs and albums. These are free-form
+// labels of the user, not the tags read from audio files, which SongService handles.
+type TagService struct {
+	store repositories.Store
+}
+
+// NewTagService returns a TagService keeping tags in store
+func NewTagService(store repositories.Store) *TagService {
+	return &TagService{store: store}
+}
+
+// Create adds a tag to the user's library
+func (s *TagService) Create(userId uint, input models.TagCreateRequest) (models.Tag, error) {
+	tag := models.Tag{Name: strings.TrimSpace(input.Name), UserId: userId}
+	if tag.Name == "" {
+		return tag, ErrTagNameRequired
+	}
+
+	err := s.store.Tags().Create(&tag)
+	if errors.Is(err, repositories.ErrDuplicate) {
+		return tag, ErrTagNameTaken
+	}
+	return tag, err
+}
+
+// List returns the user's tags in name order with how many songs and albums carry each
+func (s *TagService) List(userId uint) ([]models.TagUsage, error) {
+	return s.store.Tags().FindUsage(userId)
+}
+
+// Update renames a tag of the user; to give it the name of another tag, merge them instead
+func (s *TagService) Update(userId uint, tagId uint, input models.TagCreateRequest) (models.Tag, error) {
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		return models.Tag{}, ErrTagNameRequired
+	}
+
+	tag, err := s.store.Tags().FindOwned(userId, tagId)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return tag, ErrTagNotFound
+	}
+	if err != nil {
+		return tag, err
+	}
+
+	tag.Name = name
+	err = s.store.Tags().Update(&tag, "name")
+	if errors.Is(err, repositories.ErrDuplicate) {
+		return tag, ErrTagNameTaken
+	}
+	return tag, err
+}
+
+// Delete takes a tag of the user off every song and album and removes it
+func (s *TagService) Delete(userId uint, tagId uint) error {
+	return s.store.Transaction(func(tx repositories.Store) error {
+		tag, err := tx.Tags().FindOwned(userId, tagId)
+		if errors.Is(err, repositories.ErrNotFound) {
+			return ErrTagNotFound
+		}
+		if err != nil {
+			return err
+		}
+		return tx.Tags().Delete(&tag)
+	})
+}
+
+// Merge puts the target tag on every song and album carrying a tag of the user and deletes the tag.
+// It returns the target tag.
+func (s *TagService) Merge(userId uint, tagId uint, targetId uint) (models.Tag, error) {
+	if tagId == targetId {
+		return models.Tag{}, ErrMergeSameTag
+	}
+
+	var target models.Tag
+	err := s.store.Transaction(func(tx repositories.Store) error {
+		tag, err := tx.Tags().FindOwned(userId, tagId)
+		if errors.Is(err, repositories.ErrNotFound) {
+			return ErrTagNotFound
+		}
+		if err != nil {
+			return err
+		}
+		target, err = tx.Tags().FindOwned(userId, targetId)
+		if errors.Is(err, repositories.ErrNotFound) {
+			return ErrTagNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Tags().MoveTaggings(tag.ID, target.ID); err != nil {
+			return err
+		}
+		return tx.Tags().Delete(&tag)
+	})
+	return target, err
+}
+
+// Tag puts tags on songs and albums of the user, creating the tags the user doesn't have yet, and
+// returns the tags. Tagging albums needs the permission to manage albums.
+func (s *TagService) Tag(userId uint, role string, input models.TagApplyRequest) ([]models.Tag, error) {
+	names := distinctTagNames(input.Tags)
+	if len(names) == 0 {
+		return nil, ErrTagNameRequired
+	}
+
+	var tags []models.Tag
+	err := s.store.Transaction(func(tx repositories.Store) error {
+		songs, albums, err := libraryItems(tx, userId, role, input.SongIds, input.AlbumIds)
+		if err != nil {
+			return err
+		}
+
+		for _, name := range names {
+			tag, err := findOrCreateTag(tx, userId, name)
+			if err != nil {
+				return err
+			}
+			if err := tx.Tags().TagSongs(tag.ID, songIds(songs)); err != nil {
+				return err
+			}
+			if err := tx.Tags().TagAlbums(tag.ID, albumIds(albums)); err != nil {
+				return err
+			}
+			tags = append(tags, tag)
+		}
+		return nil
+	})
+	return tags, err
+}
+
+// Untag takes tags off songs and albums of the user; names of tags the user doesn't have are
+// ignored. Untagging albums needs the permission to manage albums.
+func (s *TagService) Untag(userId uint, role string, input models.TagApplyRequest) error {
+	names := distinctTagNames(input.Tags)
+	if len(names) == 0 {
+		return ErrTagNameRequired
+	}
+
+	return s.store.Transaction(func(tx repositories.Store) error {
+		songs, albums, err := libraryItems(tx, userId, role, input.SongIds, input.AlbumIds)
+		if err != nil {
+			return err
+		}
+
+		for _, name := range names {
+			tag, err := tx.Tags().FindByName(userId, name)
+			if errors.Is(err, repositories.ErrNotFound) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if err := tx.Tags().UntagSongs(tag.ID, songIds(songs)); err != nil {
+				return err
+			}
+			if err := tx.Tags().UntagAlbums(tag.ID, albumIds(albums)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// findOrCreateTag returns the user's tag with the name, ignoring case, creating it when there is none
+func findOrCreateTag(tx repositories.Store, userId uint, name string) (models.Tag, error) {
+	tag, err := tx.Tags().FindByName(userId, name)
+	if !errors.Is(err, repositories.ErrNotFound) {
+		return tag, err
+	}
+
+	tag = models.Tag{Name: name, UserId: userId}
+	err = tx.Tags().Create(&tag)
+	// Another request created the tag first
+	if errors.Is(err, repositories.ErrDuplicate) {
+		return tag, ErrTagNameTaken
+	}
+	return tag, err
+}
+
+// distinctTagNames trims the names and drops empty ones and those repeating an earlier name, ignoring case
+func distinctTagNames(names []string) []string {
+	var distinct []string
+	seen := map[string]bool{}
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[strings.ToLower(name)] {
+			continue
+		}
+		seen[strings.ToLower(name)] = true
+		distinct = append(distinct, name)
+	}
+	return distinct
+}
+
+func songIds(songs []models.Song) []uint {
+	ids := make([]uint, len(songs))
+	for i, song := range songs {
+		ids[i] = song.ID
+	}
+	return ids
+}
+
+func albumIds(albums []models.Album) []uint {
+	ids := make([]uint, len(albums))
+	for i, album := range albums {
+		ids[i] = album.ID
+	}
+	return ids
+}
+
+// attachSongTags fills in the tag names of songs
+func attachSongTags(store repositories.Store, songs []models.Song) error {
+	tagged, err := store.Tags().FindSongTags(songIds(songs))
+	if err != nil {
+		return err
+	}
+
+	bySong := map[uint][]string{}
+	for _, tag := range tagged {
+		bySong[tag.SongId] = append(bySong[tag.SongId], tag.Name)
+	}
+	for i := range songs {
+		songs[i].Tags = bySong[songs[i].ID]
+	}
+	return nil
+}
+
+// attachAlbumTags fills in the tag names of albums
+func attachAlbumTags(store repositories.Store, albums []models.Album) error {
+	tagged, err := store.Tags().FindAlbumTags(albumIds(albums))
+	if err != nil {
+		return err
+	}
+
+	byAlbum := map[uint][]string{}
+	for _, tag := range tagged {
+		byAlbum[tag.AlbumId] = append(byAlbum[tag.AlbumId], tag.Name)
+	}
+	for i := range albums {
+		albums[i].Tags = byAlbum[albums[i].ID]
+	}
+	return nil
+}
+
+// replaceSongTags gives a song exactly the named tags, creating the tags the user doesn't have yet,
+// and returns the tag names in name order
+func replaceSongTags(tx repositories.Store, userId uint, songId uint, names []string) ([]string, error) {
+	current, err := tx.Tags().FindSongTags([]uint{songId})
+	if err != nil {
+		return nil, err
+	}
+
+	kept := map[uint]bool{}
+	tags := []string{}
+	for _, name := range distinctTagNames(names) {
+		tag, err := findOrCreateTag(tx, userId, name)
+		if err != nil {
+			return nil, err
+		}
+		if err := tx.Tags().TagSongs(tag.ID, []uint{songId}); err != nil {
+			return nil, err
+		}
+		kept[tag.ID] = true
+		tags = append(tags, tag.Name)
+	}
+	for _, tagged := range current {
+		if !kept[tagged.TagId] {
+			if err := tx.Tags().UntagSongs(tagged.TagId, []uint{songId}); err != nil {
+				return nil, err
+			}
+		}
+	}
+	sortTagNames(tags)
+	return tags, nil
+}
+
+// replaceAlbumTags gives an album exactly the named tags, creating the tags the user doesn't have yet,
+// and returns the tag names in name order
+func replaceAlbumTags(tx repositories.Store, userId uint, albumId uint, names []string) ([]string, error) {
+	current, err := tx.Tags().FindAlbumTags([]uint{albumId})
+	if err != nil {
+		return nil, err
+	}
+
+	kept := map[uint]bool{}
+	tags := []string{}
+	for _, name := range distinctTagNames(names) {
+		tag, err := findOrCreateTag(tx, userId, name)
+		if err != nil {
+			return nil, err
+		}
+		if err := tx.Tags().TagAlbums(tag.ID, []uint{albumId}); err != nil {
+			return nil, err
+		}
+		kept[tag.ID] = true
+		tags = append(tags, tag.Name)
+	}
+	for _, tagged := range current {
+		if !kept[tagged.TagId] {
+			if err := tx.Tags().UntagAlbums(tagged.TagId, []uint{albumId}); err != nil {
+				return nil, err
+			}
+		}
+	}
+	sortTagNames(tags)
+	return tags, nil
+}
+
+// sortTagNames sorts tag names ignoring case, the order they are read in
+func sortTagNames(names []string) {
+	slices.SortStableFunc(names, func(a, b string) int {
+		return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+	})
+}
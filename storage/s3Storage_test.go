@@ -0,0 +1,151 @@
+package storage_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tushar27x/music-lib-api/storage"
+)
+
+// s3Stub is a bucket served the way S3 answers path-style object requests
+type s3Stub struct {
+	bucket  string
+	mu      sync.Mutex
+	objects map[string][]byte
+	types   map[string]string
+}
+
+func (s *s3Stub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=key-id/") || !strings.Contains(auth, "/eu-west-1/s3/aws4_request") ||
+		r.Header.Get("X-Amz-Date") == "" || r.Header.Get("X-Amz-Content-Sha256") != "UNSIGNED-PAYLOAD" {
+		http.Error(w, "AccessDenied", http.StatusForbidden)
+		return
+	}
+	prefix := "/" + s.bucket + "/"
+	if !strings.HasPrefix(r.URL.Path, prefix) {
+		http.Error(w, "NoSuchBucket", http.StatusNotFound)
+		return
+	}
+	key := strings.TrimPrefix(r.URL.Path, prefix)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		body, err := io.ReadAll(r.Body)
+		if err != nil || int64(len(body)) != r.ContentLength {
+			http.Error(w, "IncompleteBody", http.StatusBadRequest)
+			return
+		}
+		s.objects[key] = body
+		s.types[key] = r.Header.Get("Content-Type")
+	case http.MethodGet:
+		body, ok := s.objects[key]
+		if !ok {
+			http.Error(w, "NoSuchKey", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", s.types[key])
+		// ServeContent answers Range headers with 206 Partial Content
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(body))
+	case http.MethodDelete:
+		// S3 answers deletes of missing keys with 204 too
+		delete(s.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "MethodNotAllowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func TestS3Storage(t *testing.T) {
+	stub := &s3Stub{bucket: "music", objects: map[string][]byte{}, types: map[string]string{}}
+	server := httptest.NewServer(stub)
+	defer server.Close()
+
+	s3, err := storage.NewS3Storage(storage.S3Config{
+		Endpoint:        server.URL,
+		Region:          "eu-west-1",
+		Bucket:          "music",
+		AccessKeyId:     "key-id",
+		SecretAccessKey: "secret",
+		UsePathStyle:    true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	readAll := func(r io.ReadCloser, err error) string {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer r.Close()
+		body, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(body)
+	}
+	key := "audio/1/Bohemian Rhapsody (Live).mp3"
+	content := "0123456789abcdef"
+
+	if err := s3.Put(ctx, key, strings.NewReader(content), int64(len(content)), "audio/mpeg"); err != nil {
+		t.Fatal(err)
+	}
+	if stub.types[key] != "audio/mpeg" {
+		t.Errorf("stored content type = %q, want audio/mpeg", stub.types[key])
+	}
+
+	if got := readAll(s3.Get(ctx, key)); got != content {
+		t.Errorf("Get = %q, want %q", got, content)
+	}
+	if got := readAll(s3.GetRange(ctx, key, 4, 6)); got != "456789" {
+		t.Errorf("GetRange(4, 6) = %q, want 456789", got)
+	}
+
+	if err := s3.Delete(ctx, key); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s3.Get(ctx, key); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("Get of a deleted key = %v, want ErrNotFound", err)
+	}
+	if _, err := s3.GetRange(ctx, "audio/1/missing.mp3", 0, 1); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("GetRange of a missing key = %v, want ErrNotFound", err)
+	}
+	if err := s3.Delete(ctx, key); err != nil {
+		t.Errorf("Delete of a missing key = %v", err)
+	}
+}
+
+func TestS3StorageReportsErrors(t *testing.T) {
+	stub := &s3Stub{bucket: "music", objects: map[string][]byte{}, types: map[string]string{}}
+	server := httptest.NewServer(stub)
+	defer server.Close()
+
+	s3, err := storage.NewS3Storage(storage.S3Config{
+		Endpoint:        server.URL,
+		Region:          "us-east-1",
+		Bucket:          "music",
+		AccessKeyId:     "key-id",
+		SecretAccessKey: "secret",
+		UsePathStyle:    true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The stub only accepts eu-west-1 signatures
+	err = s3.Put(context.Background(), "a.mp3", strings.NewReader("x"), 1, "")
+	if err == nil || errors.Is(err, storage.ErrNotFound) || !strings.Contains(err.Error(), "403") {
+		t.Errorf("Put with a rejected signature = %v, want a 403 error", err)
+	}
+}
@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"strings"
+
+	"github.com/tushar27x/music-lib-api/config"
+)
+
+// ErrNotFound is returned when an object does not exist in the store
+var ErrNotFound = errors.New("object not found")
+
+// Storage is implemented by every blob storage driver
+type Storage interface {
+	// Put stores size bytes read from r under key
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get opens the object stored under key
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// GetRange opens length bytes of the object stored under key starting at offset
+	GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error)
+	// Delete removes the object stored under key; deleting a missing object is not an error
+	Delete(ctx context.Context, key string) error
+}
+
+// Store is the storage driver selected by STORAGE_DRIVER
+var Store Storage
+
+// InitStorage configures Store from the environment
+func InitStorage() {
+	var err error
+	driver := strings.ToLower(config.GetEnv("STORAGE_DRIVER"))
+
+	switch driver {
+	case "", "local":
+		driver = "local"
+		root := config.GetEnv("STORAGE_LOCAL_PATH")
+		if root == "" {
+			root = "uploads"
+		}
+		Store, err = NewLocalStorage(root)
+	case "s3":
+		Store, err = NewS3Storage(S3Config{
+			Endpoint:        config.GetEnv("S3_ENDPOINT"),
+			Region:          config.GetEnv("S3_REGION"),
+			Bucket:          config.GetEnv("S3_BUCKET"),
+			AccessKeyId:     config.GetEnv("S3_ACCESS_KEY_ID"),
+			SecretAccessKey: config.GetEnv("S3_SECRET_ACCESS_KEY"),
+			UsePathStyle:    config.GetEnv("S3_USE_PATH_STYLE") != "false",
+		})
+	default:
+		log.Fatalf("❌ Unknown STORAGE_DRIVER %q (expected local or s3)", driver)
+	}
+
+	if err != nil {
+		log.Fatalf("❌ Error configuring %s storage: %s", driver, err)
+	}
+
+	log.Printf("Using %s blob storage", driver)
+}